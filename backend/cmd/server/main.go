@@ -13,6 +13,8 @@ import (
 	"harmony/internal/config"
 	"harmony/internal/database"
 	"harmony/internal/handlers"
+	"harmony/internal/models"
+	"harmony/internal/scanner"
 	"harmony/internal/services"
 	"harmony/internal/transcoder"
 )
@@ -48,9 +50,12 @@ func main() {
 	// Print configuration
 	cfg.Print()
 
-	// Initialize database
+	// Initialize database; track paths are stored relative to the media
+	// root when enabled
 	db, err := database.New(database.Config{
-		Path: cfg.DBPath,
+		Path:        cfg.DBPath,
+		TrackPaths:  models.NewTrackPaths(cfg.MediaRootID, cfg.MediaPath, cfg.RelativePaths),
+		PlainSearch: !cfg.SearchNormalize,
 	})
 	if err != nil {
 		slog.Error("failed to initialize database", "error", err)
@@ -64,6 +69,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Bring stored track paths in line with the configured path mode
+	if migrated, err := database.NewTrackRepository(db.DB).MigratePaths(context.Background()); err != nil {
+		slog.Error("failed to migrate track paths", "error", err)
+		os.Exit(1)
+	} else if migrated > 0 {
+		slog.Info("migrated track paths", "count", migrated, "relative", cfg.RelativePaths)
+	}
+
 	// Initialize Redis (optional - continue if not available)
 	var redis *database.RedisClient
 	redis, err = database.NewRedis(database.RedisConfig{
@@ -75,19 +88,36 @@ func main() {
 	}
 
 	// Initialize transcoder
+	maxBitrates, _ := cfg.MaxTranscodeBitrates()
+	lowChannels := 0
+	if cfg.TranscodeLowMono {
+		lowChannels = 1
+	}
 	trans, err := transcoder.New(transcoder.Config{
-		CacheDir:   cfg.CachePath,
-		MaxCacheGB: 10.0,
+		FFmpegPath:  cfg.FFmpegPath,
+		Optional:    true,
+		CacheDir:    cfg.CachePath,
+		MaxCacheGB:  10.0,
+		CacheTTL:    time.Duration(cfg.TranscodeCacheTTL) * time.Hour,
+		ContentKeys: cfg.TranscodeCacheKey == "content",
+		CapToSource: cfg.TranscodeCapSource,
+		MaxBitrates: maxBitrates,
+
+		MaxConcurrent: cfg.TranscodeConcurrent,
+		LowSampleRate: cfg.TranscodeLowRate,
+		LowChannels:   lowChannels,
 	})
 	if err != nil {
 		slog.Warn("transcoder not available", "error", err)
 		trans = nil
 	}
+	defer trans.Close()
 
 	// Create repositories
 	trackRepo := database.NewTrackRepository(db.DB)
 	albumRepo := database.NewAlbumRepository(db.DB)
 	artistRepo := database.NewArtistRepository(db.DB)
+	failureRepo := database.NewScanFailureRepository(db.DB)
 
 	// Initialize library service
 	libService := services.NewLibraryService(
@@ -96,14 +126,88 @@ func main() {
 		trackRepo,
 		albumRepo,
 		artistRepo,
+		failureRepo,
 	)
+	libService.SetTranscoder(trans)
+	libService.SetSettings(database.NewSettingsRepository(db.DB))
+	libService.StartMediaMonitor(time.Duration(cfg.MediaCheckInterval) * time.Second)
+	defer libService.Close()
+	// Thumbnail and stream limit settings were validated with the rest of the config
+	thumbnailMode, _ := scanner.ParseThumbnailMode(cfg.ThumbnailMode)
+	thumbnailPadColor, _ := scanner.ParseHexColor(cfg.ThumbnailPadColor)
+	streamLimits, _ := cfg.StreamLimits()
+	normalizeRules, err := scanner.ParseNormalizeRules(cfg.TagNormalizeRules)
+	if err != nil {
+		slog.Error("invalid TAG_NORMALIZE_RULES", "error", err)
+		os.Exit(1)
+	}
+
+	libService.SetOptions(services.LibraryOptions{
+		ArtworkFromVideo:    cfg.ArtworkFromVideo,
+		ArtworkMaxDimension: cfg.ArtworkMaxDimension,
+		ArtworkMaxPixels:    cfg.ArtworkMaxPixels,
+		ProbeDuringScan:     cfg.ProbeDuringScan,
+		AnalyzeAudio:        cfg.AnalyzeAudio,
+		UploadDir:           cfg.UploadDir,
+		MinAlbumTracks:      cfg.MinAlbumTracks,
+		PurgeTranscodes:     cfg.TranscodePurge,
+		EventBacklog:        cfg.ScanEventBacklog,
+		MaxScanFailures:     cfg.ScanFailureLimit,
+		ScanQueueDepth:      cfg.ScanQueueDepth,
+		HashFiles:           cfg.TranscodeCacheKey == "content",
+		ProgressInterval:    time.Duration(cfg.ProgressInterval) * time.Millisecond,
+		UnknownArtist:       cfg.UnknownArtist,
+		UnknownAlbum:        cfg.UnknownAlbum,
+		NormalizeRules:      normalizeRules,
+		ThumbnailMode:       thumbnailMode,
+		ThumbnailPadColor:   thumbnailPadColor,
+		FollowSymlinks:      cfg.FollowSymlinks,
+		DedupePaths:         cfg.DedupePaths,
+		MaxScanDepth:        cfg.ScanMaxDepth,
+		SingleTrackSingles:  cfg.SingleTrackSingles,
+		StatsCounters:       cfg.StatsCounters,
+		VerifyMissingFiles:  cfg.VerifyMissing,
+		InferGenres:         cfg.InferGenres,
+		ConsolidateArtists:  cfg.ConsolidateArtists,
+		AlbumNotesFromFiles: cfg.AlbumNotesFiles,
+	})
 
 	// Configure router
 	routerCfg := handlers.RouterConfig{
-		AllowedOrigins: []string{"*"}, // Allow all in container, restrict via reverse proxy
-		MediaRoot:      cfg.MediaPath,
-		CacheDir:       cfg.ArtworkPath,
-		BaseURL:        fmt.Sprintf("http://localhost:%d", cfg.Port),
+		AllowedOrigins:      []string{"*"}, // Allow all in container, restrict via reverse proxy
+		MediaRoot:           cfg.MediaPath,
+		CacheDir:            cfg.ArtworkPath,
+		BackupDir:           cfg.BackupPath,
+		BaseURL:             fmt.Sprintf("http://localhost:%d", cfg.Port),
+		ArtworkMaxDimension: cfg.ArtworkMaxDimension,
+		ArtworkMaxPixels:    cfg.ArtworkMaxPixels,
+		ThumbnailMode:       thumbnailMode,
+		ThumbnailPadColor:   thumbnailPadColor,
+		CompressionMinSize:  cfg.CompressionMinSize,
+		TimeFormat:          cfg.TimeFormat,
+		SortLocale:          cfg.SortLocale,
+		SearchTimeout:       time.Duration(cfg.SearchTimeout) * time.Second,
+		LibraryTimeout:      time.Duration(cfg.LibraryTimeout) * time.Second,
+		UploadMaxSize:       int64(cfg.UploadMaxSize) << 20,
+		MaxStreamsPerUser:   cfg.MaxStreamsPerUser,
+		UserStreamLimits:    streamLimits,
+		StreamBufferSize:    cfg.StreamBufferSize << 10,
+		MissingPlaceholder:  cfg.MissingPlaceholder,
+		MaxPlaylistTracks:   cfg.MaxPlaylistTracks,
+		PlayDedupeWindow:    time.Duration(cfg.PlayDedupeWindow) * time.Second,
+		ListCacheMaxAge:     time.Duration(cfg.ListCacheMaxAge) * time.Second,
+		AuthEnabled:         cfg.AuthEnabled,
+		JWTSecret:           cfg.JWTSecret,
+		AuthTokenTTL:        time.Duration(cfg.AuthTokenTTL) * time.Hour,
+		AuthRegistration:    cfg.AuthRegistration,
+		AdminToken:          cfg.AdminToken,
+
+		ArtworkArtistFallback: cfg.ArtworkArtistFallback,
+		PlaylistDefaultPublic: cfg.PlaylistDefaultPublic,
+		TrackArtwork:          cfg.TrackArtwork,
+		PublicOrigins:         cfg.PublicOrigins(),
+
+		RemoteArtworkPrivateHosts: cfg.RemoteArtworkPrivateHosts,
 	}
 
 	// Create router
@@ -127,6 +231,10 @@ func main() {
 		}
 	}()
 
+	// Scheduled scans, validated with the rest of the config
+	scanSchedule, _ := services.ParseScanSchedule(cfg.ScanSchedule)
+	libService.StartScanSchedule(scanSchedule)
+
 	// Auto-scan on startup if enabled
 	if cfg.ScanOnStartup {
 		slog.Info("starting initial library scan")
@@ -5,27 +5,102 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"harmony/internal/database"
+	"harmony/internal/services"
+	"harmony/internal/transcoder"
 )
 
 // Config holds all configuration values for the application
 type Config struct {
 	// Server settings
-	Port     int
-	LogLevel string
+	Port               int
+	LogLevel           string
+	CompressionMinSize int
+	TimeFormat         string
+	SortLocale         string
+	SearchNormalize    bool
+	SearchTimeout      int
+	LibraryTimeout     int
+	MaxStreamsPerUser  int
+	UserStreamLimits   string
+	StreamBufferSize   int
+	MissingPlaceholder string
+	MaxPlaylistTracks  int
+	PlayDedupeWindow   int
+	ListCacheMaxAge    int
+	PublicCORSOrigins  string
 
 	// Database settings
 	DBPath   string
 	RedisURL string
 
+	// Authentication
+	AuthEnabled      bool
+	JWTSecret        string
+	AuthTokenTTL     int
+	AuthRegistration bool
+	AdminToken       string
+
 	// Media settings
-	MediaPath   string
-	ArtworkPath string
-	CachePath   string
+	MediaPath           string
+	MediaRootID         string
+	MediaCheckInterval  int
+	RelativePaths       bool
+	ArtworkPath         string
+	CachePath           string
+	BackupPath          string
+	ArtworkMaxDimension int
+	ArtworkMaxPixels    int
+	ThumbnailMode       string
+	ThumbnailPadColor   string
+	UploadDir           string
+	UploadMaxSize       int
+	FFmpegPath          string
+	TranscodeCacheTTL   int
+	TranscodeCacheKey   string
+	TranscodePurge      bool
+	TranscodeCapSource  bool
+	TranscodeMaxBitrate string
+	TranscodeLowRate    int
+	TranscodeLowMono    bool
+	TranscodeConcurrent int
+	MinAlbumTracks      int
+	ScanEventBacklog    int
+	ScanFailureLimit    int
+	ScanQueueDepth      int
+	ScanMaxDepth        int
+	ProgressInterval    int
+	UnknownArtist       string
+	UnknownAlbum        string
+	TagNormalizeRules   string
+	SingleTrackSingles  bool
+	InferGenres         bool
+	ConsolidateArtists  bool
+	AlbumNotesFiles     bool
 
 	// Feature flags
-	ScanOnStartup bool
+	ScanOnStartup    bool
+	ScanSchedule     string
+	ArtworkFromVideo bool
+	ProbeDuringScan  bool
+	AnalyzeAudio     bool
+	FollowSymlinks   bool
+	DedupePaths      bool
+	StatsCounters    bool
+	VerifyMissing    bool
+
+	// Defaults for how the library is presented
+	ArtworkArtistFallback bool
+	PlaylistDefaultPublic bool
+	TrackArtwork          bool
+
+	// Remote artist images
+	RemoteArtworkPrivateHosts bool
 }
 
 // Default values
@@ -35,10 +110,33 @@ const (
 	DefaultDBPath      = "/data/harmony.db"
 	DefaultRedisURL    = "redis://localhost:6379"
 	DefaultMediaPath   = "/media"
+	DefaultMediaRootID = "media"
 	DefaultArtworkPath = "/app/artwork"
 	DefaultCachePath   = "/app/cache"
+	DefaultBackupPath  = "/data/backups"
+
+	DefaultArtworkMaxDimension = 8192
+	DefaultArtworkMaxPixels    = 4096 * 4096
+	DefaultCompressionMinSize  = 1024
+	DefaultTimeFormat          = "rfc3339"
+	DefaultSearchTimeout       = 5
+	DefaultLibraryTimeout      = 30
+	DefaultThumbnailMode       = "fit"
+	DefaultThumbnailPadColor   = "#000000"
+	DefaultUploadMaxSize       = 200
+	DefaultMediaCheckInterval  = 60
+	DefaultScanEventBacklog    = 16
+	DefaultScanFailureLimit    = 3
+	DefaultTranscodeCacheKey   = "path"
+	DefaultProgressInterval    = 250
+	DefaultAuthTokenTTL        = 168
+	DefaultPlayDedupeWindow    = 10
+	MinJWTSecretLength         = 32
+	MaxStreamBufferSize        = 16384
 )
 
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -49,7 +147,74 @@ func Load() (*Config, error) {
 		MediaPath:     getEnv("MEDIA_PATH", DefaultMediaPath),
 		ArtworkPath:   getEnv("ARTWORK_PATH", DefaultArtworkPath),
 		CachePath:     getEnv("CACHE_PATH", DefaultCachePath),
+		BackupPath:    getEnv("BACKUP_PATH", DefaultBackupPath),
 		ScanOnStartup: getEnvBool("SCAN_ON_STARTUP", false),
+		ScanSchedule:  getEnv("SCAN_SCHEDULE", ""),
+
+		AuthEnabled:      getEnvBool("AUTH_ENABLED", false),
+		JWTSecret:        getEnv("JWT_SECRET", ""),
+		AuthTokenTTL:     getEnvInt("AUTH_TOKEN_TTL", DefaultAuthTokenTTL),
+		AuthRegistration: getEnvBool("AUTH_REGISTRATION", true),
+		AdminToken:       getEnv("ADMIN_TOKEN", ""),
+
+		ArtworkMaxDimension: getEnvInt("ARTWORK_MAX_DIMENSION", DefaultArtworkMaxDimension),
+		ArtworkMaxPixels:    getEnvInt("ARTWORK_MAX_PIXELS", DefaultArtworkMaxPixels),
+		ArtworkFromVideo:    getEnvBool("ARTWORK_FROM_VIDEO", false),
+		CompressionMinSize:  getEnvInt("COMPRESSION_MIN_SIZE", DefaultCompressionMinSize),
+		ProbeDuringScan:     getEnvBool("PROBE_DURING_SCAN", false),
+		AnalyzeAudio:        getEnvBool("ANALYZE_AUDIO", false),
+		FollowSymlinks:      getEnvBool("FOLLOW_SYMLINKS", false),
+		DedupePaths:         getEnvBool("DEDUPE_PATHS", false),
+		StatsCounters:       getEnvBool("STATS_COUNTERS", true),
+		VerifyMissing:       getEnvBool("VERIFY_MISSING_FILES", false),
+		TimeFormat:          getEnv("TIME_FORMAT", DefaultTimeFormat),
+		SortLocale:          getEnv("SORT_LOCALE", ""),
+		SearchNormalize:     getEnvBool("SEARCH_NORMALIZE", true),
+		SearchTimeout:       getEnvInt("SEARCH_TIMEOUT", DefaultSearchTimeout),
+		LibraryTimeout:      getEnvInt("LIBRARY_TIMEOUT", DefaultLibraryTimeout),
+		MediaRootID:         getEnv("MEDIA_ROOT_ID", DefaultMediaRootID),
+		RelativePaths:       getEnvBool("RELATIVE_PATHS", false),
+		ThumbnailMode:       getEnv("THUMBNAIL_MODE", DefaultThumbnailMode),
+		ThumbnailPadColor:   getEnv("THUMBNAIL_PAD_COLOR", DefaultThumbnailPadColor),
+		UploadDir:           getEnv("UPLOAD_DIR", services.DefaultUploadDir),
+		UploadMaxSize:       getEnvInt("UPLOAD_MAX_SIZE", DefaultUploadMaxSize),
+		FFmpegPath:          getEnv("FFMPEG_PATH", ""),
+		TranscodeCacheTTL:   getEnvInt("TRANSCODE_CACHE_TTL", 0),
+		TranscodeCacheKey:   getEnv("TRANSCODE_CACHE_KEY", DefaultTranscodeCacheKey),
+		TranscodePurge:      getEnvBool("TRANSCODE_PURGE_ON_CHANGE", true),
+		TranscodeCapSource:  getEnvBool("TRANSCODE_CAP_TO_SOURCE", true),
+		TranscodeMaxBitrate: getEnv("TRANSCODE_MAX_BITRATE", ""),
+		TranscodeLowRate:    getEnvInt("TRANSCODE_LOW_SAMPLE_RATE", 0),
+		TranscodeLowMono:    getEnvBool("TRANSCODE_LOW_MONO", false),
+		TranscodeConcurrent: getEnvInt("TRANSCODE_MAX_CONCURRENT", 0),
+		MinAlbumTracks:      getEnvInt("MIN_ALBUM_TRACKS", 0),
+		SingleTrackSingles:  getEnvBool("SINGLE_TRACK_SINGLES", false),
+		InferGenres:         getEnvBool("INFER_GENRE_FROM_ARTIST", false),
+		ConsolidateArtists:  getEnvBool("CONSOLIDATE_ARTISTS", false),
+		AlbumNotesFiles:     getEnvBool("ALBUM_NOTES_FROM_FILES", false),
+		ScanEventBacklog:    getEnvInt("SCAN_EVENT_BACKLOG", DefaultScanEventBacklog),
+		ScanFailureLimit:    getEnvInt("SCAN_FAILURE_LIMIT", DefaultScanFailureLimit),
+		ScanQueueDepth:      getEnvInt("SCAN_QUEUE_DEPTH", 0),
+		ScanMaxDepth:        getEnvInt("SCAN_MAX_DEPTH", 0),
+		ProgressInterval:    getEnvInt("SCAN_PROGRESS_INTERVAL", DefaultProgressInterval),
+		UnknownArtist:       getEnv("UNKNOWN_ARTIST_NAME", services.DefaultUnknownArtist),
+		UnknownAlbum:        getEnv("UNKNOWN_ALBUM_NAME", services.DefaultUnknownAlbum),
+		TagNormalizeRules:   getEnv("TAG_NORMALIZE_RULES", ""),
+		MaxStreamsPerUser:   getEnvInt("MAX_STREAMS_PER_USER", 0),
+		MediaCheckInterval:  getEnvInt("MEDIA_CHECK_INTERVAL", DefaultMediaCheckInterval),
+		UserStreamLimits:    getEnv("USER_STREAM_LIMITS", ""),
+		StreamBufferSize:    getEnvInt("STREAM_BUFFER_SIZE", 0),
+		MissingPlaceholder:  getEnv("MISSING_FILE_PLACEHOLDER", ""),
+		MaxPlaylistTracks:   getEnvInt("MAX_PLAYLIST_TRACKS", 0),
+		PlayDedupeWindow:    getEnvInt("PLAY_DEDUPE_WINDOW", DefaultPlayDedupeWindow),
+		ListCacheMaxAge:     getEnvInt("LIST_CACHE_MAX_AGE", 0),
+		PublicCORSOrigins:   getEnv("PUBLIC_CORS_ORIGINS", ""),
+
+		ArtworkArtistFallback: getEnvBool("ARTWORK_ARTIST_FALLBACK", false),
+		PlaylistDefaultPublic: getEnvBool("PLAYLIST_DEFAULT_PUBLIC", false),
+		TrackArtwork:          getEnvBool("TRACK_ARTWORK", false),
+
+		RemoteArtworkPrivateHosts: getEnvBool("REMOTE_ARTWORK_PRIVATE_HOSTS", false),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -74,6 +239,34 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("invalid log level: %s (must be debug, info, warn, or error)", c.LogLevel))
 	}
 
+	// Validate time format
+	validTimeFormats := map[string]bool{"rfc3339": true, "rfc3339nano": true}
+	if !validTimeFormats[strings.ToLower(c.TimeFormat)] {
+		errs = append(errs, fmt.Sprintf("invalid TIME_FORMAT: %s (must be rfc3339 or rfc3339nano)", c.TimeFormat))
+	}
+	if c.SortLocale != "" && !database.IsSortLocale(c.SortLocale) {
+		errs = append(errs, fmt.Sprintf("invalid SORT_LOCALE: %s", c.SortLocale))
+	}
+
+	// Validate request timeouts (0 disables)
+	if c.SearchTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SEARCH_TIMEOUT: %d (must be 0 or more seconds)", c.SearchTimeout))
+	}
+	if c.LibraryTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("invalid LIBRARY_TIMEOUT: %d (must be 0 or more seconds)", c.LibraryTimeout))
+	}
+
+	// Tokens can't be signed securely without a long enough secret
+	if c.AuthEnabled && len(c.JWTSecret) < MinJWTSecretLength {
+		errs = append(errs, fmt.Sprintf("JWT_SECRET must be at least %d characters when AUTH_ENABLED is set", MinJWTSecretLength))
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < MinJWTSecretLength {
+		errs = append(errs, fmt.Sprintf("ADMIN_TOKEN must be at least %d characters", MinJWTSecretLength))
+	}
+	if c.AuthTokenTTL < 1 {
+		errs = append(errs, fmt.Sprintf("invalid AUTH_TOKEN_TTL: %d (must be at least 1 hour)", c.AuthTokenTTL))
+	}
+
 	// Validate required paths
 	if c.DBPath == "" {
 		errs = append(errs, "DB_PATH is required")
@@ -83,6 +276,127 @@ func (c *Config) Validate() error {
 		errs = append(errs, "MEDIA_PATH is required")
 	}
 
+	if c.MediaRootID == "" {
+		errs = append(errs, "MEDIA_ROOT_ID is required")
+	}
+
+	if c.ArtworkMaxDimension < 1 {
+		errs = append(errs, fmt.Sprintf("invalid ARTWORK_MAX_DIMENSION: %d (must be positive)", c.ArtworkMaxDimension))
+	}
+	if c.ArtworkMaxPixels < 1 {
+		errs = append(errs, fmt.Sprintf("invalid ARTWORK_MAX_PIXELS: %d (must be positive)", c.ArtworkMaxPixels))
+	}
+
+	// Validate thumbnail settings
+	validThumbnailModes := map[string]bool{"fit": true, "crop": true, "pad": true}
+	if !validThumbnailModes[strings.ToLower(c.ThumbnailMode)] {
+		errs = append(errs, fmt.Sprintf("invalid THUMBNAIL_MODE: %s (must be fit, crop, or pad)", c.ThumbnailMode))
+	}
+	if !hexColorPattern.MatchString(c.ThumbnailPadColor) {
+		errs = append(errs, fmt.Sprintf("invalid THUMBNAIL_PAD_COLOR: %s (must be #rrggbb)", c.ThumbnailPadColor))
+	}
+
+	if c.UploadMaxSize < 1 {
+		errs = append(errs, fmt.Sprintf("invalid UPLOAD_MAX_SIZE: %d (must be positive)", c.UploadMaxSize))
+	}
+
+	if c.TranscodeCacheTTL < 0 {
+		errs = append(errs, fmt.Sprintf("invalid TRANSCODE_CACHE_TTL: %d (must be 0 or more hours)", c.TranscodeCacheTTL))
+	}
+	if c.TranscodeCacheKey != "path" && c.TranscodeCacheKey != "content" {
+		errs = append(errs, fmt.Sprintf("invalid TRANSCODE_CACHE_KEY: %s (must be path or content)", c.TranscodeCacheKey))
+	}
+	if _, err := c.MaxTranscodeBitrates(); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid TRANSCODE_MAX_BITRATE: %v", err))
+	}
+	if c.TranscodeConcurrent < 0 {
+		errs = append(errs, fmt.Sprintf("invalid TRANSCODE_MAX_CONCURRENT: %d (must be 0 or more)", c.TranscodeConcurrent))
+	}
+	if c.TranscodeLowRate != 0 {
+		for _, profile := range transcoder.LowProfiles() {
+			if !transcoder.SupportsSampleRate(profile.Codec, c.TranscodeLowRate) {
+				errs = append(errs, fmt.Sprintf("invalid TRANSCODE_LOW_SAMPLE_RATE: %d (%s can't encode at it; try 22050)", c.TranscodeLowRate, profile.Codec))
+			}
+		}
+	}
+
+	if c.MediaCheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("invalid MEDIA_CHECK_INTERVAL: %d (must be 0 or more seconds)", c.MediaCheckInterval))
+	}
+
+	if c.MinAlbumTracks < 0 {
+		errs = append(errs, fmt.Sprintf("invalid MIN_ALBUM_TRACKS: %d (must be 0 or more)", c.MinAlbumTracks))
+	}
+
+	if c.ScanEventBacklog < 1 {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_EVENT_BACKLOG: %d (must be at least 1)", c.ScanEventBacklog))
+	}
+	if c.ScanFailureLimit < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_FAILURE_LIMIT: %d (must be 0 or more)", c.ScanFailureLimit))
+	}
+	if c.ScanQueueDepth < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_QUEUE_DEPTH: %d (must be 0 or more)", c.ScanQueueDepth))
+	}
+	if c.ScanMaxDepth < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_MAX_DEPTH: %d (must be 0 or more)", c.ScanMaxDepth))
+	}
+	if c.ProgressInterval < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_PROGRESS_INTERVAL: %d (must be 0 or more milliseconds)", c.ProgressInterval))
+	}
+
+	if c.MaxStreamsPerUser < 0 {
+		errs = append(errs, fmt.Sprintf("invalid MAX_STREAMS_PER_USER: %d (must be 0 or more)", c.MaxStreamsPerUser))
+	}
+	if _, err := c.StreamLimits(); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid USER_STREAM_LIMITS: %v", err))
+	}
+	if c.StreamBufferSize < 0 || c.StreamBufferSize > MaxStreamBufferSize {
+		errs = append(errs, fmt.Sprintf("invalid STREAM_BUFFER_SIZE: %d (must be between 0 and %d KB)", c.StreamBufferSize, MaxStreamBufferSize))
+	}
+	validPlaceholders := map[string]bool{"": true, "silence": true, "tone": true}
+	if !validPlaceholders[c.MissingPlaceholder] {
+		errs = append(errs, fmt.Sprintf("invalid MISSING_FILE_PLACEHOLDER: %s (must be silence or tone)", c.MissingPlaceholder))
+	}
+	if c.MaxPlaylistTracks < 0 {
+		errs = append(errs, fmt.Sprintf("invalid MAX_PLAYLIST_TRACKS: %d (must be 0 or more)", c.MaxPlaylistTracks))
+	}
+	if c.PlayDedupeWindow < 0 {
+		errs = append(errs, fmt.Sprintf("invalid PLAY_DEDUPE_WINDOW: %d (must be 0 or more seconds)", c.PlayDedupeWindow))
+	}
+	if c.ListCacheMaxAge < 0 {
+		errs = append(errs, fmt.Sprintf("invalid LIST_CACHE_MAX_AGE: %d (must be 0 or more seconds)", c.ListCacheMaxAge))
+	}
+	for _, origin := range c.PublicOrigins() {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Sprintf("invalid PUBLIC_CORS_ORIGINS: %s (must list * or http(s):// origins)", c.PublicCORSOrigins))
+			break
+		}
+	}
+
+	if strings.TrimSpace(c.UnknownArtist) == "" {
+		errs = append(errs, "invalid UNKNOWN_ARTIST_NAME: must not be empty")
+	}
+	if strings.TrimSpace(c.UnknownAlbum) == "" {
+		errs = append(errs, "invalid UNKNOWN_ALBUM_NAME: must not be empty")
+	}
+	validNormalizeRules := map[string]bool{"": true, "all": true, "trim": true, "case": true, "feat": true, "edition": true}
+	for _, rule := range strings.Split(c.TagNormalizeRules, ",") {
+		if !validNormalizeRules[strings.ToLower(strings.TrimSpace(rule))] {
+			errs = append(errs, fmt.Sprintf("invalid TAG_NORMALIZE_RULES: %s (must list trim, case, feat, edition, or all)", c.TagNormalizeRules))
+			break
+		}
+	}
+
+	if _, err := services.ParseScanSchedule(c.ScanSchedule); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid SCAN_SCHEDULE: %v", err))
+	}
+
+	// Uploads must land inside the media library
+	uploadDir := filepath.Clean(c.UploadDir)
+	if filepath.IsAbs(uploadDir) || uploadDir == ".." || strings.HasPrefix(uploadDir, "../") {
+		errs = append(errs, fmt.Sprintf("invalid UPLOAD_DIR: %s (must be relative to MEDIA_PATH)", c.UploadDir))
+	}
+
 	// Check if media path exists (warning only, might be mounted later in Docker)
 	if c.MediaPath != "" {
 		if info, err := os.Stat(c.MediaPath); err != nil {
@@ -118,17 +432,139 @@ func (c *Config) SlogLevel() slog.Level {
 	}
 }
 
+// StreamLimits parses USER_STREAM_LIMITS ("user=limit,...") into per-user overrides
+func (c *Config) StreamLimits() (map[string]int, error) {
+	limits := make(map[string]int)
+	if strings.TrimSpace(c.UserStreamLimits) == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(c.UserStreamLimits, ",") {
+		user, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		user = strings.TrimSpace(user)
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%q is not user=limit", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("%q has an invalid limit", entry)
+		}
+		limits[user] = limit
+	}
+
+	return limits, nil
+}
+
+// MaxTranscodeBitrates parses TRANSCODE_MAX_BITRATE ("format=kbps,...") into
+// per-format bitrate ceilings
+func (c *Config) MaxTranscodeBitrates() (map[string]int, error) {
+	ceilings := make(map[string]int)
+	if strings.TrimSpace(c.TranscodeMaxBitrate) == "" {
+		return ceilings, nil
+	}
+
+	for _, entry := range strings.Split(c.TranscodeMaxBitrate, ",") {
+		format, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		format = strings.ToLower(strings.TrimSpace(format))
+		if !ok || format == "" {
+			return nil, fmt.Errorf("%q is not format=kbps", entry)
+		}
+		kbps, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || kbps < 1 {
+			return nil, fmt.Errorf("%q has an invalid bitrate", entry)
+		}
+		ceilings[format] = kbps
+	}
+
+	return ceilings, nil
+}
+
+// PublicOrigins parses PUBLIC_CORS_ORIGINS into the origins allowed on the
+// public media routes; nil leaves them under the API's CORS policy
+func (c *Config) PublicOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.PublicCORSOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
 // Print logs the current configuration (with sensitive values masked)
 func (c *Config) Print() {
 	slog.Info("configuration loaded",
 		"port", c.Port,
 		"log_level", c.LogLevel,
+		"compression_min_size", c.CompressionMinSize,
+		"time_format", c.TimeFormat,
+		"sort_locale", c.SortLocale,
+		"search_normalize", c.SearchNormalize,
+		"search_timeout", c.SearchTimeout,
+		"library_timeout", c.LibraryTimeout,
+		"max_streams_per_user", c.MaxStreamsPerUser,
+		"media_check_interval", c.MediaCheckInterval,
+		"user_stream_limits", c.UserStreamLimits,
+		"stream_buffer_size", c.StreamBufferSize,
+		"missing_file_placeholder", c.MissingPlaceholder,
+		"max_playlist_tracks", c.MaxPlaylistTracks,
+		"play_dedupe_window", c.PlayDedupeWindow,
+		"list_cache_max_age", c.ListCacheMaxAge,
+		"public_cors_origins", c.PublicCORSOrigins,
 		"db_path", c.DBPath,
 		"redis_url", maskRedisURL(c.RedisURL),
+		"auth_enabled", c.AuthEnabled,
+		"jwt_secret_set", c.JWTSecret != "",
+		"auth_token_ttl", c.AuthTokenTTL,
+		"auth_registration", c.AuthRegistration,
+		"admin_token_set", c.AdminToken != "",
 		"media_path", c.MediaPath,
+		"media_root_id", c.MediaRootID,
+		"relative_paths", c.RelativePaths,
 		"artwork_path", c.ArtworkPath,
 		"cache_path", c.CachePath,
+		"backup_path", c.BackupPath,
+		"artwork_max_dimension", c.ArtworkMaxDimension,
+		"artwork_max_pixels", c.ArtworkMaxPixels,
+		"thumbnail_mode", c.ThumbnailMode,
+		"thumbnail_pad_color", c.ThumbnailPadColor,
+		"upload_dir", c.UploadDir,
+		"upload_max_size", c.UploadMaxSize,
+		"ffmpeg_path", c.FFmpegPath,
+		"transcode_cache_ttl", c.TranscodeCacheTTL,
+		"transcode_cache_key", c.TranscodeCacheKey,
+		"transcode_purge_on_change", c.TranscodePurge,
+		"transcode_cap_to_source", c.TranscodeCapSource,
+		"transcode_max_bitrate", c.TranscodeMaxBitrate,
+		"transcode_low_sample_rate", c.TranscodeLowRate,
+		"transcode_low_mono", c.TranscodeLowMono,
+		"transcode_max_concurrent", c.TranscodeConcurrent,
+		"min_album_tracks", c.MinAlbumTracks,
+		"single_track_singles", c.SingleTrackSingles,
+		"infer_genre_from_artist", c.InferGenres,
+		"consolidate_artists", c.ConsolidateArtists,
+		"album_notes_from_files", c.AlbumNotesFiles,
+		"scan_event_backlog", c.ScanEventBacklog,
+		"scan_failure_limit", c.ScanFailureLimit,
+		"scan_queue_depth", c.ScanQueueDepth,
+		"scan_max_depth", c.ScanMaxDepth,
+		"scan_progress_interval", c.ProgressInterval,
+		"unknown_artist_name", c.UnknownArtist,
+		"unknown_album_name", c.UnknownAlbum,
+		"tag_normalize_rules", c.TagNormalizeRules,
 		"scan_on_startup", c.ScanOnStartup,
+		"scan_schedule", c.ScanSchedule,
+		"artwork_from_video", c.ArtworkFromVideo,
+		"artwork_artist_fallback", c.ArtworkArtistFallback,
+		"probe_during_scan", c.ProbeDuringScan,
+		"analyze_audio", c.AnalyzeAudio,
+		"follow_symlinks", c.FollowSymlinks,
+		"dedupe_paths", c.DedupePaths,
+		"stats_counters", c.StatsCounters,
+		"verify_missing_files", c.VerifyMissing,
+		"playlist_default_public", c.PlaylistDefaultPublic,
+		"track_artwork", c.TrackArtwork,
+		"remote_artwork_private_hosts", c.RemoteArtworkPrivateHosts,
 	)
 }
 
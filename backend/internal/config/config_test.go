@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"defaults", nil, ""},
+		{"scan schedule", map[string]string{"SCAN_SCHEDULE": "03:00,15:30"}, ""},
+		{"invalid scan schedule", map[string]string{"SCAN_SCHEDULE": "nightly"}, "invalid SCAN_SCHEDULE"},
+		{"scan interval too short", map[string]string{"SCAN_SCHEDULE": "10s"}, "invalid SCAN_SCHEDULE"},
+		{"sort locale", map[string]string{"SORT_LOCALE": "de"}, ""},
+		{"unknown sort locale", map[string]string{"SORT_LOCALE": "xx"}, "invalid SORT_LOCALE"},
+		{"artwork pixel limit", map[string]string{"ARTWORK_MAX_PIXELS": "0"}, "invalid ARTWORK_MAX_PIXELS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MEDIA_PATH", t.TempDir())
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -11,7 +12,8 @@ import (
 )
 
 var (
-	ErrAlbumNotFound = errors.New("album not found")
+	ErrAlbumNotFound   = errors.New("album not found")
+	ErrTrackNotInAlbum = errors.New("track does not belong to album")
 )
 
 type AlbumRepository struct {
@@ -23,9 +25,11 @@ func NewAlbumRepository(db *gorm.DB) *AlbumRepository {
 }
 
 type AlbumFilter struct {
-	ArtistID string
-	Year     int
-	Query    string
+	ArtistID    string
+	Year        int
+	Query       string
+	AlbumType   string
+	ExcludeType string
 }
 
 type AlbumListOptions struct {
@@ -34,6 +38,8 @@ type AlbumListOptions struct {
 	Limit  int
 	SortBy string
 	Order  string
+	// Locale sorts names under its collation (see SortLocales)
+	Locale string
 }
 
 func (r *AlbumRepository) Create(ctx context.Context, album *models.Album) error {
@@ -58,12 +64,19 @@ func (r *AlbumRepository) FindByID(ctx context.Context, id string) (*models.Albu
 	return &album, nil
 }
 
+// albumTrackOrder sorts tracks with a manual album position first, in that
+// order, followed by the rest by disc and track number
+const albumTrackOrder = "CASE WHEN album_order > 0 THEN 0 ELSE 1 END, album_order ASC, disc_number ASC, track_number ASC"
+
+// albumTrackOrderDesc is albumTrackOrder reversed
+const albumTrackOrderDesc = "CASE WHEN album_order > 0 THEN 0 ELSE 1 END DESC, album_order DESC, disc_number DESC, track_number DESC"
+
 func (r *AlbumRepository) FindByIDWithTracks(ctx context.Context, id string) (*models.Album, error) {
 	var album models.Album
 	result := r.db.WithContext(ctx).
 		Preload("Artist").
 		Preload("Tracks", func(db *gorm.DB) *gorm.DB {
-			return db.Order("disc_number ASC, track_number ASC")
+			return db.Scopes(withoutLyrics).Order(albumTrackOrder)
 		}).
 		First(&album, "id = ?", id)
 
@@ -112,8 +125,13 @@ func (r *AlbumRepository) List(ctx context.Context, opts AlbumListOptions) ([]mo
 		query = query.Where("year = ?", opts.Filter.Year)
 	}
 	if opts.Filter.Query != "" {
-		searchQuery := "%" + opts.Filter.Query + "%"
-		query = query.Where("title LIKE ?", searchQuery)
+		query = query.Where(searchCondition(r.db, "title", opts.Filter.Query))
+	}
+	if opts.Filter.AlbumType != "" {
+		query = query.Where("album_type = ?", opts.Filter.AlbumType)
+	}
+	if opts.Filter.ExcludeType != "" {
+		query = query.Where("COALESCE(album_type, '') <> ?", opts.Filter.ExcludeType)
 	}
 
 	// Count total
@@ -141,6 +159,9 @@ func (r *AlbumRepository) List(ctx context.Context, opts AlbumListOptions) ([]mo
 	if opts.Order == "desc" {
 		order = "DESC"
 	}
+	if sortBy == "title" {
+		sortBy = collatedColumn(sortBy, opts.Locale)
+	}
 	query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
 
 	// Apply pagination
@@ -162,11 +183,10 @@ func (r *AlbumRepository) List(ctx context.Context, opts AlbumListOptions) ([]mo
 
 func (r *AlbumRepository) Search(ctx context.Context, query string, limit int) ([]models.Album, error) {
 	var albums []models.Album
-	searchQuery := "%" + query + "%"
 
 	err := r.db.WithContext(ctx).
 		Preload("Artist").
-		Where("title LIKE ?", searchQuery).
+		Where(searchCondition(r.db, "title", query)).
 		Limit(limit).
 		Find(&albums).Error
 
@@ -194,9 +214,11 @@ func (r *AlbumRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *AlbumRepository) GetRecentlyAdded(ctx context.Context, limit int) ([]models.Album, error) {
+// GetRecentlyAdded returns the most recently added albums matching filter,
+// newest first
+func (r *AlbumRepository) GetRecentlyAdded(ctx context.Context, limit int, filter AddedFilter) ([]models.Album, error) {
 	var albums []models.Album
-	err := r.db.WithContext(ctx).
+	err := filter.apply(r.db.WithContext(ctx)).
 		Preload("Artist").
 		Order("created_at DESC").
 		Limit(limit).
@@ -208,6 +230,32 @@ func (r *AlbumRepository) GetRecentlyAdded(ctx context.Context, limit int) ([]mo
 	return albums, nil
 }
 
+// AlbumAddedGroup is the set of albums added within one period
+type AlbumAddedGroup struct {
+	Start  time.Time
+	Albums []models.Album
+}
+
+// GetRecentlyAddedGrouped returns the most recently added albums matching
+// filter bucketed by the day or week they were added, newest period first
+func (r *AlbumRepository) GetRecentlyAddedGrouped(ctx context.Context, limit int, grouping DateGrouping, filter AddedFilter) ([]AlbumAddedGroup, error) {
+	albums, err := r.GetRecentlyAdded(ctx, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []AlbumAddedGroup
+	for _, album := range albums {
+		start := grouping.PeriodStart(album.CreatedAt)
+		if len(groups) == 0 || !groups[len(groups)-1].Start.Equal(start) {
+			groups = append(groups, AlbumAddedGroup{Start: start})
+		}
+		last := &groups[len(groups)-1]
+		last.Albums = append(last.Albums, album)
+	}
+	return groups, nil
+}
+
 func (r *AlbumRepository) GetRandom(ctx context.Context, limit int) ([]models.Album, error) {
 	var albums []models.Album
 	err := r.db.WithContext(ctx).
@@ -243,6 +291,173 @@ func (r *AlbumRepository) GetByArtist(ctx context.Context, artistID string) ([]m
 	return albums, nil
 }
 
+// AlbumTrackStats holds aggregate track figures for one album
+type AlbumTrackStats struct {
+	AlbumID      string
+	Title        string
+	ArtistID     string
+	ArtistName   string
+	AlbumType    string
+	TrackCount   int
+	Duration     int
+	LongestTrack int
+	ArtistCount  int
+}
+
+// GetTrackStats returns track count, total and longest duration and
+// distinct track artist count for every album that has tracks
+func (r *AlbumRepository) GetTrackStats(ctx context.Context) ([]AlbumTrackStats, error) {
+	var stats []AlbumTrackStats
+	err := r.db.WithContext(ctx).
+		Table("tracks").
+		Select(`tracks.album_id AS album_id, albums.title AS title, albums.artist_id AS artist_id, artists.name AS artist_name,
+			albums.album_type AS album_type, COUNT(*) AS track_count,
+			COALESCE(SUM(tracks.duration), 0) AS duration,
+			COALESCE(MAX(tracks.duration), 0) AS longest_track,
+			COUNT(DISTINCT tracks.artist_id) AS artist_count`).
+		Joins("JOIN albums ON albums.id = tracks.album_id").
+		Joins("LEFT JOIN artists ON artists.id = albums.artist_id").
+		Group("tracks.album_id, albums.title, albums.artist_id, artists.name, albums.album_type").
+		Scan(&stats).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("getting album track stats: %w", err)
+	}
+	return stats, nil
+}
+
+// UpdateType sets the release type of an album
+func (r *AlbumRepository) UpdateType(ctx context.Context, id, albumType string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where("id = ?", id).
+		UpdateColumn("album_type", albumType).Error
+
+	if err != nil {
+		return fmt.Errorf("updating album type: %w", err)
+	}
+	return nil
+}
+
+// RecountTotals sets the track and disc totals of every album to the largest
+// ones tagged on its tracks, touching only albums whose totals changed
+func (r *AlbumRepository) RecountTotals(ctx context.Context) error {
+	err := r.db.WithContext(ctx).Exec(`
+		UPDATE albums SET
+			total_tracks = COALESCE((SELECT MAX(total_tracks) FROM tracks WHERE tracks.album_id = albums.id), 0),
+			total_discs = COALESCE((SELECT MAX(total_discs) FROM tracks WHERE tracks.album_id = albums.id), 0)
+		WHERE total_tracks != COALESCE((SELECT MAX(total_tracks) FROM tracks WHERE tracks.album_id = albums.id), 0)
+			OR total_discs != COALESCE((SELECT MAX(total_discs) FROM tracks WHERE tracks.album_id = albums.id), 0)`).Error
+
+	if err != nil {
+		return fmt.Errorf("recounting album totals: %w", err)
+	}
+	return nil
+}
+
+// ListTitles returns the ID, title, edition and artist of every album
+func (r *AlbumRepository) ListTitles(ctx context.Context) ([]models.Album, error) {
+	var albums []models.Album
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Select("id, title, edition, artist_id").
+		Order("id").
+		Find(&albums).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("listing album titles: %w", err)
+	}
+	return albums, nil
+}
+
+// UpdateTitle sets the title and edition of an album
+func (r *AlbumRepository) UpdateTitle(ctx context.Context, id, title, edition string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"title": title, "edition": edition}).Error
+
+	if err != nil {
+		return fmt.Errorf("updating album title: %w", err)
+	}
+	return nil
+}
+
+// UpdateNotes sets the liner notes of an album as edited by a user, which
+// scans leave alone from then on, even when cleared
+func (r *AlbumRepository) UpdateNotes(ctx context.Context, id, notes string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"notes": notes, "notes_edited": true}).Error
+
+	if err != nil {
+		return fmt.Errorf("updating album notes: %w", err)
+	}
+	return nil
+}
+
+// FillNotes sets the liner notes of an album read from its files, unless it
+// has notes already or a user edited them. Reports whether they were set.
+func (r *AlbumRepository) FillNotes(ctx context.Context, id, notes string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where("id = ? AND notes = '' AND notes_edited = ?", id, false).
+		Update("notes", notes)
+
+	if result.Error != nil {
+		return false, fmt.Errorf("filling album notes: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetTrackOrder stores a manual track order for an album. trackIDs lists the
+// album's tracks in their new order; tracks left out follow them by disc and
+// track number, and an empty list restores the tagged order.
+func (r *AlbumRepository) SetTrackOrder(ctx context.Context, albumID string, trackIDs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(trackIDs) > 0 {
+			var count int64
+			if err := tx.Model(&models.Track{}).
+				Where("album_id = ? AND id IN ?", albumID, trackIDs).
+				Count(&count).Error; err != nil {
+				return fmt.Errorf("checking album tracks: %w", err)
+			}
+			if int(count) != len(trackIDs) {
+				return ErrTrackNotInAlbum
+			}
+		}
+
+		if err := tx.Model(&models.Track{}).
+			Where("album_id = ?", albumID).
+			UpdateColumn("album_order", 0).Error; err != nil {
+			return fmt.Errorf("clearing album track order: %w", err)
+		}
+		for i, trackID := range trackIDs {
+			if err := tx.Model(&models.Track{}).
+				Where("id = ?", trackID).
+				UpdateColumn("album_order", i+1).Error; err != nil {
+				return fmt.Errorf("updating album track order: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateCoverArt sets the cached cover art path of an album. Unlike Update
+// it never recreates an album that was deleted in the meantime.
+func (r *AlbumRepository) UpdateCoverArt(ctx context.Context, id, path string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where("id = ?", id).
+		UpdateColumn("cover_art_path", path).Error
+
+	if err != nil {
+		return fmt.Errorf("updating album cover art: %w", err)
+	}
+	return nil
+}
+
 // DeleteEmpty deletes albums that have no tracks
 func (r *AlbumRepository) DeleteEmpty(ctx context.Context) (int64, error) {
 	result := r.db.WithContext(ctx).Exec(`
@@ -254,3 +469,71 @@ func (r *AlbumRepository) DeleteEmpty(ctx context.Context) (int64, error) {
 	}
 	return result.RowsAffected, nil
 }
+
+// AlbumArtworkSource pairs an album with one of its files for artwork lookup
+type AlbumArtworkSource struct {
+	AlbumID  string
+	Title    string
+	FilePath string
+	RootID   string
+}
+
+// GetArtworkSources returns every album that has tracks along with the
+// absolute path of one of its files
+func (r *AlbumRepository) GetArtworkSources(ctx context.Context) ([]AlbumArtworkSource, error) {
+	var sources []AlbumArtworkSource
+	err := r.db.WithContext(ctx).
+		Table("albums").
+		Select("albums.id AS album_id, albums.title AS title, tracks.file_path AS file_path, tracks.root_id AS root_id").
+		Joins(`JOIN tracks ON tracks.id = (
+			SELECT id FROM tracks WHERE tracks.album_id = albums.id ORDER BY file_path LIMIT 1
+		)`).
+		Order("albums.title").
+		Scan(&sources).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("getting album artwork sources: %w", err)
+	}
+
+	paths := models.TrackPathsOf(r.db)
+	for i := range sources {
+		sources[i].FilePath = paths.Resolve(sources[i].FilePath, sources[i].RootID)
+	}
+	return sources, nil
+}
+
+// albumMissingArtist matches albums whose artist row no longer exists
+const albumMissingArtist = "artist_id IS NOT NULL AND artist_id <> '' AND artist_id NOT IN (SELECT id FROM artists)"
+
+// CountMissingArtist counts albums that reference an artist that no longer exists
+func (r *AlbumRepository) CountMissingArtist(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where(albumMissingArtist).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("counting albums with missing artist: %w", err)
+	}
+	return count, nil
+}
+
+// FixMissingArtist reassigns albums whose artist no longer exists to an
+// existing artist of one of their tracks, or clears the reference if there
+// is none. Returns the number of albums updated.
+func (r *AlbumRepository) FixMissingArtist(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Album{}).
+		Where(albumMissingArtist).
+		UpdateColumn("artist_id", gorm.Expr(`(
+			SELECT MIN(tracks.artist_id) FROM tracks
+			JOIN artists ON artists.id = tracks.artist_id
+			WHERE tracks.album_id = albums.id
+		)`))
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("fixing albums with missing artist: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecountTotals(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`UPDATE tracks SET total_tracks = 12, total_discs = 2 WHERE id = 't1'`,
+		`UPDATE tracks SET total_tracks = 10, total_discs = 1 WHERE id = 't2'`,
+		`UPDATE albums SET total_tracks = 5, total_discs = 1 WHERE id IN ('al2', 'al3')`,
+	)
+	repo := NewAlbumRepository(db.DB)
+	if err := repo.RecountTotals(context.Background()); err != nil {
+		t.Fatalf("RecountTotals: %v", err)
+	}
+
+	tests := []struct {
+		album       string
+		totalTracks int
+		totalDiscs  int
+	}{
+		{"al1", 12, 2},
+		{"al2", 0, 0}, // its tracks lost their totals
+		{"al3", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.album, func(t *testing.T) {
+			var totals struct{ TotalTracks, TotalDiscs int }
+			db.DB.Raw("SELECT total_tracks, total_discs FROM albums WHERE id = ?", tt.album).Scan(&totals)
+			if totals.TotalTracks != tt.totalTracks || totals.TotalDiscs != tt.totalDiscs {
+				t.Errorf("totals = %d tracks and %d discs, want %d and %d",
+					totals.TotalTracks, totals.TotalDiscs, tt.totalTracks, tt.totalDiscs)
+			}
+		})
+	}
+
+	// Recounting again changes nothing
+	var before int64
+	db.DB.Raw("SELECT version FROM library_version").Scan(&before)
+	if err := repo.RecountTotals(context.Background()); err != nil {
+		t.Fatalf("RecountTotals: %v", err)
+	}
+	var after int64
+	db.DB.Raw("SELECT version FROM library_version").Scan(&after)
+	if after != before {
+		t.Errorf("library version moved from %d to %d without any change", before, after)
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+// ArtistNameKey normalizes an artist name for matching variants of it:
+// whitespace is trimmed and collapsed, case is folded and a leading "The" is
+// dropped, so "the beatles" and "The  Beatles" share the key "beatles"
+func ArtistNameKey(name string) string {
+	key := cases.Fold().String(strings.Join(strings.Fields(name), " "))
+	if rest, ok := strings.CutPrefix(key, "the "); ok {
+		key = rest
+	}
+	return key
+}
+
+func (r *ArtistRepository) findByNameKey(ctx context.Context, key string) (*models.Artist, error) {
+	var artist models.Artist
+	result := r.db.WithContext(ctx).Where("name_key = ?", key).First(&artist)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrArtistNotFound
+		}
+		return nil, fmt.Errorf("finding artist by name key: %w", result.Error)
+	}
+	return &artist, nil
+}
+
+// FindOrCreateConsolidated is FindOrCreate matching names by ArtistNameKey,
+// so every variant of a name resolves to the artist first created with it
+func (r *ArtistRepository) FindOrCreateConsolidated(ctx context.Context, name string) (*models.Artist, error) {
+	key := ArtistNameKey(name)
+	artist, err := r.findByNameKey(ctx, key)
+	if err == nil {
+		return artist, nil
+	}
+	if !errors.Is(err, ErrArtistNotFound) {
+		return nil, err
+	}
+
+	newArtist := &models.Artist{
+		ID:      GenerateID(),
+		Name:    name,
+		NameKey: &key,
+	}
+	if err := r.Create(ctx, newArtist); err != nil {
+		// Another scan worker may have created the artist meanwhile
+		if artist, findErr := r.findByNameKey(ctx, key); findErr == nil {
+			return artist, nil
+		}
+		return nil, err
+	}
+	return newArtist, nil
+}
+
+// AssignNameKeys gives artists without a name key (created while
+// consolidation was off, or renamed since) their ArtistNameKey, oldest
+// first. Artists whose key another artist already holds are merged into
+// that artist. It returns how many artists were keyed and how many merged.
+func (r *ArtistRepository) AssignNameKeys(ctx context.Context) (assigned, merged int, err error) {
+	var keyed []models.Artist
+	if err := r.db.WithContext(ctx).
+		Select("id, name_key").
+		Where("name_key IS NOT NULL").
+		Find(&keyed).Error; err != nil {
+		return 0, 0, fmt.Errorf("listing artist name keys: %w", err)
+	}
+	keys := make(map[string]string, len(keyed)) // name key -> artist ID
+	for _, artist := range keyed {
+		keys[*artist.NameKey] = artist.ID
+	}
+
+	var artists []models.Artist
+	if err := r.db.WithContext(ctx).
+		Select("id, name").
+		Where("name_key IS NULL").
+		Order("created_at").
+		Find(&artists).Error; err != nil {
+		return 0, 0, fmt.Errorf("listing artists without name keys: %w", err)
+	}
+
+	for _, artist := range artists {
+		key := ArtistNameKey(artist.Name)
+		if intoID, ok := keys[key]; ok {
+			if err := r.mergeArtist(ctx, artist.ID, intoID); err != nil {
+				return assigned, merged, err
+			}
+			merged++
+			continue
+		}
+		if err := r.db.WithContext(ctx).
+			Model(&models.Artist{}).
+			Where("id = ?", artist.ID).
+			UpdateColumn("name_key", key).Error; err != nil {
+			return assigned, merged, fmt.Errorf("assigning artist name key: %w", err)
+		}
+		keys[key] = artist.ID
+		assigned++
+	}
+	return assigned, merged, nil
+}
+
+// mergeArtist moves the albums and tracks of artist fromID to artist intoID
+// and deletes fromID. Albums intoID has under the same title take the
+// tracks of fromID's album in its place.
+func (r *ArtistRepository) mergeArtist(ctx context.Context, fromID, intoID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var albums []models.Album
+		if err := tx.Select("id, title").Where("artist_id = ?", fromID).Find(&albums).Error; err != nil {
+			return fmt.Errorf("listing albums of duplicate artist: %w", err)
+		}
+		for _, album := range albums {
+			var target models.Album
+			err := tx.Select("id").Where("artist_id = ? AND title = ?", intoID, album.Title).First(&target).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Model(&models.Album{}).Where("id = ?", album.ID).
+					Update("artist_id", intoID).Error; err != nil {
+					return fmt.Errorf("moving album of duplicate artist: %w", err)
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("finding album to merge into: %w", err)
+			}
+			if err := tx.Model(&models.Track{}).Where("album_id = ?", album.ID).
+				Update("album_id", target.ID).Error; err != nil {
+				return fmt.Errorf("moving tracks of duplicate album: %w", err)
+			}
+			if err := tx.Delete(&models.Album{}, "id = ?", album.ID).Error; err != nil {
+				return fmt.Errorf("deleting duplicate album: %w", err)
+			}
+		}
+
+		if err := tx.Model(&models.Track{}).Where("artist_id = ?", fromID).
+			Update("artist_id", intoID).Error; err != nil {
+			return fmt.Errorf("moving tracks of duplicate artist: %w", err)
+		}
+		if err := tx.Delete(&models.Artist{}, "id = ?", fromID).Error; err != nil {
+			return fmt.Errorf("deleting duplicate artist: %w", err)
+		}
+		return nil
+	})
+}
@@ -32,6 +32,8 @@ type ArtistListOptions struct {
 	Limit  int
 	SortBy string
 	Order  string
+	// Locale sorts names under its collation (see SortLocales)
+	Locale string
 }
 
 func (r *ArtistRepository) Create(ctx context.Context, artist *models.Artist) error {
@@ -112,8 +114,7 @@ func (r *ArtistRepository) List(ctx context.Context, opts ArtistListOptions) ([]
 
 	// Apply filters
 	if opts.Filter.Query != "" {
-		searchQuery := "%" + opts.Filter.Query + "%"
-		query = query.Where("name LIKE ?", searchQuery)
+		query = query.Where(searchCondition(r.db, "name", opts.Filter.Query))
 	}
 
 	// Count total
@@ -139,6 +140,9 @@ func (r *ArtistRepository) List(ctx context.Context, opts ArtistListOptions) ([]
 	if opts.Order == "desc" {
 		order = "DESC"
 	}
+	if sortBy == "name" {
+		sortBy = collatedColumn(sortBy, opts.Locale)
+	}
 	query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
 
 	// Apply pagination
@@ -159,10 +163,9 @@ func (r *ArtistRepository) List(ctx context.Context, opts ArtistListOptions) ([]
 
 func (r *ArtistRepository) Search(ctx context.Context, query string, limit int) ([]models.Artist, error) {
 	var artists []models.Artist
-	searchQuery := "%" + query + "%"
 
 	err := r.db.WithContext(ctx).
-		Where("name LIKE ?", searchQuery).
+		Where(searchCondition(r.db, "name", query)).
 		Limit(limit).
 		Find(&artists).Error
 
@@ -201,6 +204,7 @@ func (r *ArtistRepository) Count(ctx context.Context) (int64, error) {
 func (r *ArtistRepository) GetPopularTracks(ctx context.Context, artistID string, limit int) ([]models.Track, error) {
 	var tracks []models.Track
 	err := r.db.WithContext(ctx).
+		Scopes(withoutLyrics).
 		Preload("Album").
 		Where("artist_id = ?", artistID).
 		Limit(limit).
@@ -212,11 +216,90 @@ func (r *ArtistRepository) GetPopularTracks(ctx context.Context, artistID string
 	return tracks, nil
 }
 
-// DeleteEmpty deletes artists that have no albums
+// GetAppearances returns albums by other artists that contain tracks by this artist
+func (r *ArtistRepository) GetAppearances(ctx context.Context, artistID string) ([]models.Album, error) {
+	var albums []models.Album
+	err := r.db.WithContext(ctx).
+		Preload("Artist").
+		Where("id IN (?)", r.db.Model(&models.Track{}).Select("album_id").Where("artist_id = ?", artistID)).
+		Where("artist_id <> ?", artistID).
+		Order("year ASC, title ASC").
+		Find(&albums).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("getting artist appearances: %w", err)
+	}
+	return albums, nil
+}
+
+// ListNames returns the ID and name of every artist
+func (r *ArtistRepository) ListNames(ctx context.Context) ([]models.Artist, error) {
+	var artists []models.Artist
+	err := r.db.WithContext(ctx).
+		Model(&models.Artist{}).
+		Select("id, name").
+		Order("id").
+		Find(&artists).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("listing artist names: %w", err)
+	}
+	return artists, nil
+}
+
+// ListWithoutImage returns the ID and name of artists with no image URL
+func (r *ArtistRepository) ListWithoutImage(ctx context.Context) ([]models.Artist, error) {
+	var artists []models.Artist
+	err := r.db.WithContext(ctx).
+		Model(&models.Artist{}).
+		Select("id, name").
+		Where("image_path IS NULL OR image_path = ''").
+		Order("id").
+		Find(&artists).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("listing artists without image: %w", err)
+	}
+	return artists, nil
+}
+
+// UpdateName renames an artist. Its name key is cleared for the next
+// consolidating scan to recompute.
+func (r *ArtistRepository) UpdateName(ctx context.Context, id, name string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Artist{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "name_key": nil}).Error
+
+	if err != nil {
+		return fmt.Errorf("updating artist name: %w", err)
+	}
+	return nil
+}
+
+// UpdateImagePath sets the remote image URL of an artist; an empty path
+// removes it
+func (r *ArtistRepository) UpdateImagePath(ctx context.Context, id, path string) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Artist{}).
+		Where("id = ?", id).
+		Update("image_path", path)
+
+	if result.Error != nil {
+		return fmt.Errorf("updating artist image: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrArtistNotFound
+	}
+	return nil
+}
+
+// DeleteEmpty deletes artists that have neither albums nor tracks
 func (r *ArtistRepository) DeleteEmpty(ctx context.Context) (int64, error) {
 	result := r.db.WithContext(ctx).Exec(`
 		DELETE FROM artists
 		WHERE id NOT IN (SELECT DISTINCT artist_id FROM albums WHERE artist_id IS NOT NULL)
+		AND id NOT IN (SELECT DISTINCT artist_id FROM tracks WHERE artist_id IS NOT NULL)
 	`)
 	if result.Error != nil {
 		return 0, fmt.Errorf("deleting empty artists: %w", result.Error)
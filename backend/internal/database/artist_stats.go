@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+// ArtistListener is a user and how many times they played an artist's tracks
+type ArtistListener struct {
+	UserID   string
+	Username string // empty when the user has no account
+	Plays    int64
+}
+
+// ArtistStats aggregates plays across the tracks credited to an artist
+type ArtistStats struct {
+	TotalPlays      int64
+	TotalSkips      int64
+	PlayedTracks    int64         // tracks played at least once
+	MostPlayedTrack *models.Track // nil until a track is played
+	TopListeners    []ArtistListener
+}
+
+// GetStats totals the play counts of an artist's tracks and finds the
+// listenerLimit users who played them most. Totals come from the tracks'
+// counters, which include plays from before the play history was kept;
+// listeners come from the history and count full listens only.
+func (r *ArtistRepository) GetStats(ctx context.Context, artistID string, listenerLimit int) (*ArtistStats, error) {
+	var totals struct {
+		TotalPlays   int64
+		TotalSkips   int64
+		PlayedTracks int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Select("COALESCE(SUM(play_count), 0) AS total_plays, "+
+			"COALESCE(SUM(skip_count), 0) AS total_skips, "+
+			"COUNT(CASE WHEN play_count > 0 THEN 1 END) AS played_tracks").
+		Where("artist_id = ?", artistID).
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("totaling artist plays: %w", err)
+	}
+	stats := ArtistStats{
+		TotalPlays:   totals.TotalPlays,
+		TotalSkips:   totals.TotalSkips,
+		PlayedTracks: totals.PlayedTracks,
+	}
+
+	var track models.Track
+	err := r.db.WithContext(ctx).
+		Preload("Album").
+		Where("artist_id = ? AND play_count > 0", artistID).
+		Order("play_count DESC, last_played_at DESC").
+		First(&track).Error
+	switch {
+	case err == nil:
+		stats.MostPlayedTrack = &track
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("finding most played track: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).
+		Table("plays").
+		Select("plays.user_id, COALESCE(users.username, '') AS username, COUNT(*) AS plays").
+		Joins("JOIN tracks ON tracks.id = plays.track_id").
+		Joins("LEFT JOIN users ON users.id = plays.user_id").
+		Where("tracks.artist_id = ? AND plays.completed = ? AND plays.user_id <> ''", artistID, true).
+		Group("plays.user_id, users.username").
+		Order("COUNT(*) DESC, plays.user_id").
+		Limit(listenerLimit).
+		Scan(&stats.TopListeners).Error; err != nil {
+		return nil, fmt.Errorf("finding top listeners: %w", err)
+	}
+
+	return &stats, nil
+}
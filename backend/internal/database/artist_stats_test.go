@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestArtistStats(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	// Two is moved to the compilation's artist so its plays belong to
+	// someone else. Cover ties One on plays but was played last.
+	execSQL(t, db,
+		`UPDATE tracks SET artist_id = 'ar2' WHERE id = 't2'`,
+		`UPDATE tracks SET play_count = 5, skip_count = 1, last_played_at = datetime('now', '-2 days') WHERE id = 't1'`,
+		`UPDATE tracks SET play_count = 3, skip_count = 4 WHERE id = 't2'`,
+		`UPDATE tracks SET play_count = 2, last_played_at = datetime('now', '-3 days') WHERE id = 't3'`,
+		`UPDATE tracks SET play_count = 5, last_played_at = datetime('now', '-1 day') WHERE id = 't4'`,
+		`INSERT INTO users (id, username, email, password_hash, created_at, updated_at) VALUES
+			('u1', 'alice', 'alice@example.com', 'x', datetime('now'), datetime('now')),
+			('u2', 'bob', 'bob@example.com', 'x', datetime('now'), datetime('now'))`,
+		// alice finished 3 of Band's tracks, bob 2 plus one he skipped
+		// and 3 of another artist's, a removed user 1 and an anonymous
+		// listener 1
+		`INSERT INTO plays (track_id, user_id, completed, played_at) VALUES
+			('t1', 'u1', 1, datetime('now')), ('t3', 'u1', 1, datetime('now')), ('t4', 'u1', 1, datetime('now')),
+			('t1', 'u2', 1, datetime('now')), ('t4', 'u2', 1, datetime('now')), ('t4', 'u2', 0, datetime('now')),
+			('t2', 'u2', 1, datetime('now')), ('t2', 'u2', 1, datetime('now')), ('t2', 'u2', 1, datetime('now')),
+			('t1', 'u3', 1, datetime('now')),
+			('t1', '', 1, datetime('now'))`,
+	)
+	repo := NewArtistRepository(db.DB)
+
+	tests := []struct {
+		name          string
+		artistID      string
+		listenerLimit int
+		want          ArtistStats
+		mostPlayed    string
+	}{
+		{"all listeners", "ar1", 10, ArtistStats{
+			TotalPlays:   12,
+			TotalSkips:   1,
+			PlayedTracks: 3,
+			TopListeners: []ArtistListener{{"u1", "alice", 3}, {"u2", "bob", 2}, {"u3", "", 1}},
+		}, "t4"},
+		{"top listener", "ar1", 1, ArtistStats{
+			TotalPlays:   12,
+			TotalSkips:   1,
+			PlayedTracks: 3,
+			TopListeners: []ArtistListener{{"u1", "alice", 3}},
+		}, "t4"},
+		{"other artist", "ar2", 10, ArtistStats{
+			TotalPlays:   3,
+			TotalSkips:   4,
+			PlayedTracks: 1,
+			TopListeners: []ArtistListener{{"u2", "bob", 3}},
+		}, "t2"},
+		{"no tracks", "missing", 10, ArtistStats{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := repo.GetStats(context.Background(), tt.artistID, tt.listenerLimit)
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+
+			mostPlayed := ""
+			if stats.MostPlayedTrack != nil {
+				mostPlayed = stats.MostPlayedTrack.ID
+				if stats.MostPlayedTrack.Album == nil {
+					t.Error("most played track has no album")
+				}
+			}
+			if mostPlayed != tt.mostPlayed {
+				t.Errorf("most played track = %q, want %q", mostPlayed, tt.mostPlayed)
+			}
+
+			got := *stats
+			got.MostPlayedTrack = nil
+			if len(got.TopListeners) == 0 {
+				got.TopListeners = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stats = %+v\nwant    %+v", got, tt.want)
+			}
+		})
+	}
+}
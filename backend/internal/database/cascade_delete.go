@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+var (
+	ErrAlbumHasTracks = errors.New("album has tracks")
+	ErrArtistHasMusic = errors.New("artist has albums or tracks")
+)
+
+// Deletion lists everything an album or artist deletion removed, so cached
+// artwork can be cleaned up after it
+type Deletion struct {
+	AlbumIDs []string
+	TrackIDs []string
+}
+
+// DeleteCascade deletes an album. With cascade its tracks are deleted too,
+// along with their playlist entries; without it an album that still has
+// tracks is refused with ErrAlbumHasTracks. Nothing is deleted on error.
+func (r *AlbumRepository) DeleteCascade(ctx context.Context, id string, cascade bool) (*Deletion, error) {
+	deletion := &Deletion{AlbumIDs: []string{id}}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Track{}).
+			Where("album_id = ?", id).
+			Pluck("id", &deletion.TrackIDs).Error; err != nil {
+			return fmt.Errorf("finding album tracks: %w", err)
+		}
+		if len(deletion.TrackIDs) > 0 && !cascade {
+			return ErrAlbumHasTracks
+		}
+		if err := deleteTracks(tx, deletion.TrackIDs); err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Album{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("deleting album: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlbumNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// DeleteCascade deletes an artist. With cascade their albums go too, with
+// every track on them, as do tracks credited to the artist on other albums;
+// without it an artist with albums or tracks is refused with
+// ErrArtistHasMusic. Nothing is deleted on error.
+func (r *ArtistRepository) DeleteCascade(ctx context.Context, id string, cascade bool) (*Deletion, error) {
+	deletion := &Deletion{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Album{}).
+			Where("artist_id = ?", id).
+			Pluck("id", &deletion.AlbumIDs).Error; err != nil {
+			return fmt.Errorf("finding artist albums: %w", err)
+		}
+		if err := tx.Model(&models.Track{}).
+			Where("artist_id = ? OR album_id IN (?)", id, tx.Model(&models.Album{}).Select("id").Where("artist_id = ?", id)).
+			Pluck("id", &deletion.TrackIDs).Error; err != nil {
+			return fmt.Errorf("finding artist tracks: %w", err)
+		}
+		if (len(deletion.AlbumIDs) > 0 || len(deletion.TrackIDs) > 0) && !cascade {
+			return ErrArtistHasMusic
+		}
+		if err := deleteTracks(tx, deletion.TrackIDs); err != nil {
+			return err
+		}
+		if len(deletion.AlbumIDs) > 0 {
+			if err := tx.Delete(&models.Album{}, "id IN ?", deletion.AlbumIDs).Error; err != nil {
+				return fmt.Errorf("deleting artist albums: %w", err)
+			}
+		}
+
+		result := tx.Delete(&models.Artist{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("deleting artist: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrArtistNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// deleteTracks deletes tracks and their playlist entries, renumbering the
+// playlists they were on. Plays and chapters go with the tracks.
+func deleteTracks(tx *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var playlistIDs []string
+	if err := tx.Model(&models.PlaylistTrack{}).
+		Distinct("playlist_id").
+		Where("track_id IN ?", ids).
+		Pluck("playlist_id", &playlistIDs).Error; err != nil {
+		return fmt.Errorf("finding playlists with deleted tracks: %w", err)
+	}
+	if err := tx.Delete(&models.PlaylistTrack{}, "track_id IN ?", ids).Error; err != nil {
+		return fmt.Errorf("deleting playlist entries: %w", err)
+	}
+	for _, playlistID := range playlistIDs {
+		if err := reorderPlaylistTracks(tx, playlistID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Delete(&models.Track{}, "id IN ?", ids).Error; err != nil {
+		return fmt.Errorf("deleting tracks: %w", err)
+	}
+	return nil
+}
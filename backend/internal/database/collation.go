@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// sqliteDriver is the SQLite driver with the sort locale collations and the
+// search_fold function registered
+const sqliteDriver = "sqlite3_collate"
+
+// SortLocales are the languages names and titles can be sorted by. Each is
+// registered on every connection as a collation named locale_<code>.
+var SortLocales = []language.Tag{
+	language.English,
+	language.German,
+	language.French,
+	language.Spanish,
+	language.Italian,
+	language.Dutch,
+	language.Portuguese,
+	language.Swedish,
+	language.Danish,
+	language.Norwegian,
+	language.Finnish,
+	language.Polish,
+	language.Czech,
+	language.Hungarian,
+	language.Turkish,
+	language.Russian,
+	language.Greek,
+	language.Japanese,
+	language.Chinese,
+	language.Korean,
+}
+
+var sortLocaleMatcher = language.NewMatcher(SortLocales)
+
+// lockedCollator serializes access to a collator, which isn't safe for
+// concurrent use
+type lockedCollator struct {
+	mu       sync.Mutex
+	collator *collate.Collator
+}
+
+func (c *lockedCollator) compare(a, b string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.collator.CompareString(a, b)
+}
+
+var (
+	collatorsOnce sync.Once
+	collators     map[string]*lockedCollator
+)
+
+// sortCollators returns the collator of every sort locale, keyed by code
+func sortCollators() map[string]*lockedCollator {
+	collatorsOnce.Do(func() {
+		collators = make(map[string]*lockedCollator, len(SortLocales))
+		for _, tag := range SortLocales {
+			collators[tag.String()] = &lockedCollator{
+				collator: collate.New(tag, collate.IgnoreCase),
+			}
+		}
+	})
+	return collators
+}
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for locale, collator := range sortCollators() {
+				if err := conn.RegisterCollation("locale_"+locale, collator.compare); err != nil {
+					return err
+				}
+			}
+			return conn.RegisterFunc("search_fold", FoldSearchText, true)
+		},
+	})
+}
+
+// IsSortLocale reports whether locale is one of SortLocales
+func IsSortLocale(locale string) bool {
+	_, ok := sortCollators()[locale]
+	return ok
+}
+
+// MatchSortLocale returns the sort locale best matching an Accept-Language
+// header, or fallback when the header is missing or nothing matches
+func MatchSortLocale(acceptLanguage, fallback string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return fallback
+	}
+
+	_, index, confidence := sortLocaleMatcher.Match(tags...)
+	if confidence == language.No {
+		return fallback
+	}
+	return SortLocales[index].String()
+}
+
+// collatedColumn returns a column for ORDER BY, compared under the locale's
+// collation. Without a locale SQLite's byte order is used.
+func collatedColumn(column, locale string) string {
+	if !IsSortLocale(locale) {
+		return column
+	}
+	return column + " COLLATE locale_" + locale
+}
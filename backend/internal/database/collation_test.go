@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSortLocaleCollation(t *testing.T) {
+	db := newTestDB(t)
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('a1', 'Zulu', datetime('now'), datetime('now')),
+			('a2', 'Ärtist', datetime('now'), datetime('now')),
+			('a3', 'Bravo', datetime('now'), datetime('now')),
+			('a4', 'Artist', datetime('now'), datetime('now'))`,
+	)
+	repo := NewArtistRepository(db.DB)
+
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"", []string{"Artist", "Bravo", "Zulu", "Ärtist"}},
+		{"de", []string{"Artist", "Ärtist", "Bravo", "Zulu"}},
+		{"xx", []string{"Artist", "Bravo", "Zulu", "Ärtist"}},
+	}
+	for _, tt := range tests {
+		t.Run("locale "+tt.locale, func(t *testing.T) {
+			artists, _, err := repo.List(context.Background(), ArtistListOptions{Locale: tt.locale})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var names []string
+			for _, artist := range artists {
+				names = append(names, artist.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSortLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"fr-CH, fr;q=0.9", "fr"},
+		{"tlh", "en"},
+		{"", "en"},
+		{"not a header;;", "en"},
+	}
+	for _, tt := range tests {
+		if got := MatchSortLocale(tt.header, "en"); got != tt.want {
+			t.Errorf("MatchSortLocale(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -21,6 +24,12 @@ type Config struct {
 	MaxOpenConn int
 	MaxIdleConn int
 	MaxLifetime time.Duration
+	// TrackPaths controls how track file paths are stored; nil stores them
+	// as they are
+	TrackPaths *models.TrackPaths
+	// PlainSearch matches searches with plain substring LIKE instead of
+	// ignoring accents, unicode forms and punctuation variants
+	PlainSearch bool
 }
 
 func DefaultConfig() Config {
@@ -37,7 +46,7 @@ func New(cfg Config) (*Database, error) {
 		Logger: logger.Default.LogMode(logger.Warn),
 	}
 
-	db, err := gorm.Open(sqlite.Open(cfg.Path), gormConfig)
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: sqliteDriver, DSN: cfg.Path}), gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -56,20 +65,87 @@ func New(cfg Config) (*Database, error) {
 
 	slog.Info("database connection established", "path", cfg.Path)
 
-	return &Database{DB: db}, nil
+	return &Database{DB: withPlainSearch(models.WithTrackPaths(db, cfg.TrackPaths), cfg.PlainSearch)}, nil
 }
 
 func (d *Database) Migrate() error {
 	slog.Info("running database migrations")
 
+	// File paths are no longer unique on their own since cue sheets split one
+	// file into several tracks; the composite index replaces this one
+	if d.DB.Migrator().HasIndex(&models.Track{}, "idx_tracks_file_path") {
+		if err := d.DB.Migrator().DropIndex(&models.Track{}, "idx_tracks_file_path"); err != nil {
+			return fmt.Errorf("dropping track file path index: %w", err)
+		}
+	}
+
 	if err := d.DB.AutoMigrate(models.AllModels()...); err != nil {
 		return fmt.Errorf("auto-migrating models: %w", err)
 	}
 
+	if err := d.migrateLibraryTotals(); err != nil {
+		return err
+	}
+	if err := d.migrateLibraryVersion(); err != nil {
+		return err
+	}
+	if err := d.migrateAdmin(); err != nil {
+		return err
+	}
+	if err := d.migratePlaylistOwners(); err != nil {
+		return err
+	}
+
 	slog.Info("database migrations completed")
 	return nil
 }
 
+// Backup writes a consistent snapshot of the database to destPath with
+// VACUUM INTO, which is safe while the database is in use, leaving out
+// backupExcludedSettings.
+func (d *Database) Backup(ctx context.Context, destPath string) error {
+	tempPath := destPath + ".tmp"
+	os.Remove(tempPath)
+	defer os.Remove(tempPath)
+
+	err := d.DB.WithContext(ctx).Exec("VACUUM INTO ?", tempPath).Error
+	if err == nil {
+		err = scrubBackup(ctx, tempPath)
+	}
+	if err != nil {
+		return fmt.Errorf("backing up database: %w", err)
+	}
+	if err := os.Rename(tempPath, destPath); err != nil {
+		return fmt.Errorf("moving backup into place: %w", err)
+	}
+	return nil
+}
+
+// backupExcludedSettings are the prefixes of settings keys left out of
+// backups. They hold third-party secrets, which a backup file handed
+// around shouldn't carry; users link those accounts again after a restore.
+var backupExcludedSettings = []string{models.SettingLastFMPrefix}
+
+// scrubBackup deletes backupExcludedSettings from the SQLite backup at path
+func scrubBackup(ctx context.Context, path string) error {
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	defer db.Close()
+
+	for _, prefix := range backupExcludedSettings {
+		if _, err := db.ExecContext(ctx, "DELETE FROM settings WHERE key LIKE ?", prefix+"%"); err != nil {
+			return fmt.Errorf("removing secrets from backup: %w", err)
+		}
+	}
+	// Rebuilding the file drops the deleted rows from its free pages too
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("compacting backup: %w", err)
+	}
+	return nil
+}
+
 func (d *Database) Close() error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
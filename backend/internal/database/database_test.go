@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`INSERT INTO settings (key, value, updated_at) VALUES
+			('lastfm:u1', '{"apiSecret":"very-secret-value"}', datetime('now')),
+			('theme', 'dark', datetime('now'))`,
+	)
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := db.Backup(context.Background(), dest); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if _, err := os.Stat(dest + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary backup file left behind")
+	}
+
+	backup, err := New(Config{Path: dest, MaxOpenConn: 1, MaxIdleConn: 1})
+	if err != nil {
+		t.Fatalf("opening backup: %v", err)
+	}
+	defer backup.Close()
+	var tracks int64
+	if err := backup.DB.Raw("SELECT COUNT(*) FROM tracks").Scan(&tracks).Error; err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if tracks != 4 {
+		t.Errorf("backup has %d tracks, want 4", tracks)
+	}
+
+	// Last.fm secrets are left out, down to the bytes of the file
+	var keys []string
+	backup.DB.Raw("SELECT key FROM settings ORDER BY key").Scan(&keys)
+	if len(keys) != 1 || keys[0] != "theme" {
+		t.Errorf("backup settings = %v, want [theme]", keys)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "very-secret-value") {
+		t.Errorf("backup file still contains the Last.fm secret")
+	}
+}
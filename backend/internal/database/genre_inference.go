@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+// artistDominantGenre selects the genre most of an artist's tagged tracks
+// share, breaking ties alphabetically
+const artistDominantGenre = `(
+	SELECT tagged.genre FROM tracks AS tagged
+	WHERE tagged.artist_id = tracks.artist_id AND tagged.genre IS NOT NULL AND TRIM(tagged.genre) <> ''
+	GROUP BY tagged.genre
+	ORDER BY COUNT(*) DESC, tagged.genre
+	LIMIT 1
+)`
+
+// InferGenresFromArtists sets the inferred genre of tracks without a genre
+// tag to the dominant genre of their artist's tagged tracks, and clears it
+// where there's no longer anything to infer. Tracks of the unknown artist
+// are left alone, since they don't share an actual artist. Only tagged
+// genres are counted, so inferred ones never feed back. It returns how many
+// tracks were given a new inferred genre.
+func (r *TrackRepository) InferGenresFromArtists(ctx context.Context, unknownArtist string) (int64, error) {
+	var inferred int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Track{}).
+			Where("tracks.genre IS NULL OR TRIM(tracks.genre) = ''").
+			Where("tracks.artist_id IS NOT NULL AND tracks.artist_id <> ''").
+			Where("tracks.artist_id NOT IN (SELECT id FROM artists WHERE name = ?)", unknownArtist).
+			Where("EXISTS "+artistDominantGenre).
+			Where("tracks.inferred_genre IS NOT "+artistDominantGenre).
+			Update("inferred_genre", gorm.Expr(artistDominantGenre))
+		if result.Error != nil {
+			return result.Error
+		}
+		inferred = result.RowsAffected
+
+		// Tagged since, or their artist has no tagged tracks left
+		return tx.Model(&models.Track{}).
+			Where("tracks.inferred_genre <> ''").
+			Where(`(tracks.genre IS NOT NULL AND TRIM(tracks.genre) <> '')
+				OR tracks.artist_id IN (SELECT id FROM artists WHERE name = ?)
+				OR NOT EXISTS `+artistDominantGenre, unknownArtist).
+			Update("inferred_genre", "").Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("inferring genres from artists: %w", err)
+	}
+	return inferred, nil
+}
+
+// ClearInferredGenres removes every inferred genre
+func (r *TrackRepository) ClearInferredGenres(ctx context.Context) error {
+	err := r.db.WithContext(ctx).Model(&models.Track{}).
+		Where("inferred_genre <> ''").
+		Update("inferred_genre", "").Error
+	if err != nil {
+		return fmt.Errorf("clearing inferred genres: %w", err)
+	}
+	return nil
+}
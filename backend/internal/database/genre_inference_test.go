@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInferGenresFromArtists(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('ar3', 'Unknown Artist', datetime('now'), datetime('now'))`,
+		`INSERT INTO tracks (id, title, duration, file_path, file_size, format, album_id, artist_id, genre, created_at, updated_at) VALUES
+			('t5', 'Demo', 100, '/a/5.mp3', 100, 'mp3', 'al1', 'ar1', '', datetime('now'), datetime('now')),
+			('t6', 'Lost', 100, '/a/6.mp3', 100, 'mp3', 'al1', 'ar3', '', datetime('now'), datetime('now')),
+			('t7', 'Found', 100, '/a/7.mp3', 100, 'mp3', 'al1', 'ar3', 'Rock', datetime('now'), datetime('now'))`,
+	)
+	repo := NewTrackRepository(db.DB)
+
+	// Steps run in order against the same tracks
+	tests := []struct {
+		name     string
+		setup    string
+		inferred int64
+		want     map[string]string // track ID to inferred genre
+	}{
+		{"dominant genre", "", 1, map[string]string{"t5": "Rock", "t6": "", "t1": ""}},
+		{"unchanged", "", 0, map[string]string{"t5": "Rock"}},
+		{"dominant genre changes", `UPDATE tracks SET genre = 'Jazz' WHERE id IN ('t1', 't3')`, 1, map[string]string{"t5": "Jazz"}},
+		{"tagged since", `UPDATE tracks SET genre = 'Blues' WHERE id = 't5'`, 0, map[string]string{"t5": ""}},
+		{"one tagged", `UPDATE tracks SET genre = '' WHERE id IN ('t1', 't2', 't3', 't4')`, 4, map[string]string{"t1": "Blues", "t5": ""}},
+		// Inferred genres don't count towards the dominant one
+		{"nothing tagged", `UPDATE tracks SET genre = '' WHERE id = 't5'`, 0, map[string]string{"t1": "", "t5": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != "" {
+				execSQL(t, db, tt.setup)
+			}
+			inferred, err := repo.InferGenresFromArtists(context.Background(), "Unknown Artist")
+			if err != nil {
+				t.Fatalf("InferGenresFromArtists: %v", err)
+			}
+			if inferred != tt.inferred {
+				t.Errorf("inferred %d genres, want %d", inferred, tt.inferred)
+			}
+			for id, want := range tt.want {
+				var got string
+				db.DB.Raw("SELECT inferred_genre FROM tracks WHERE id = ?", id).Scan(&got)
+				if got != want {
+					t.Errorf("track %s inferred genre = %q, want %q", id, got, want)
+				}
+			}
+		})
+	}
+
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidGrouping is returned for an unknown date grouping
+var ErrInvalidGrouping = errors.New("invalid grouping")
+
+// DateGrouping buckets records by the calendar period they were added in
+type DateGrouping string
+
+const (
+	GroupByDay  DateGrouping = "day"
+	GroupByWeek DateGrouping = "week"
+)
+
+// ParseDateGrouping validates a grouping name
+func ParseDateGrouping(s string) (DateGrouping, error) {
+	switch g := DateGrouping(s); g {
+	case GroupByDay, GroupByWeek:
+		return g, nil
+	}
+	return "", ErrInvalidGrouping
+}
+
+// AddedFilter narrows recently-added queries to one artist's tracks or
+// albums, or one album's tracks, and to those added within [After, Before).
+// Zero fields don't filter.
+type AddedFilter struct {
+	ArtistID string
+	AlbumID  string // tracks only
+	After    time.Time
+	Before   time.Time
+}
+
+// apply adds the filter's conditions to a query on tracks or albums
+func (f AddedFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.ArtistID != "" {
+		db = db.Where("artist_id = ?", f.ArtistID)
+	}
+	if f.AlbumID != "" {
+		db = db.Where("album_id = ?", f.AlbumID)
+	}
+	// Times are stored in the server's zone, and compared as text
+	if !f.After.IsZero() {
+		db = db.Where("created_at >= ?", f.After.Local())
+	}
+	if !f.Before.IsZero() {
+		db = db.Where("created_at < ?", f.Before.Local())
+	}
+	return db
+}
+
+// PeriodStart returns the start of the UTC day, or the Monday of the ISO
+// week, containing t
+func (g DateGrouping) PeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if g == GroupByWeek {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
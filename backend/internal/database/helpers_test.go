@@ -0,0 +1,58 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestDB opens and migrates a SQLite database in a temp directory. A
+// single connection keeps PRAGMA foreign_keys in effect for every query.
+func newTestDB(t *testing.T) *Database {
+	t.Helper()
+	db, err := New(Config{
+		Path:        filepath.Join(t.TempDir(), "harmony.db"),
+		MaxOpenConn: 1,
+		MaxIdleConn: 1,
+	})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	return db
+}
+
+// execSQL runs SQL statements against db
+func execSQL(t *testing.T, db *Database, statements ...string) {
+	t.Helper()
+	for _, stmt := range statements {
+		if err := db.DB.Exec(stmt).Error; err != nil {
+			t.Fatalf("executing %q: %v", stmt, err)
+		}
+	}
+}
+
+// seedLibrary adds two artists, three albums and four tracks:
+//
+//	ar1 "Band": al1 "First" (album, t1 and t2), al2 "Hit" (single, t3)
+//	ar2 "Various Artists": al3 "Comp" (compilation, t4 by ar1)
+func seedLibrary(t *testing.T, db *Database) {
+	t.Helper()
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('ar1', 'Band', datetime('now'), datetime('now')),
+			('ar2', 'Various Artists', datetime('now'), datetime('now'))`,
+		`INSERT INTO albums (id, title, year, album_type, artist_id, created_at, updated_at) VALUES
+			('al1', 'First', 2001, 'album', 'ar1', datetime('now'), datetime('now')),
+			('al2', 'Hit', 2003, 'single', 'ar1', datetime('now'), datetime('now')),
+			('al3', 'Comp', 2005, 'compilation', 'ar2', datetime('now'), datetime('now'))`,
+		`INSERT INTO tracks (id, title, duration, track_number, disc_number, file_path, file_size,
+			format, album_id, artist_id, genre, year, created_at, updated_at) VALUES
+			('t1', 'One', 200, 1, 1, '/a/1.mp3', 100, 'mp3', 'al1', 'ar1', 'Rock', 2001, datetime('now'), datetime('now')),
+			('t2', 'Two', 180, 2, 1, '/a/2.mp3', 100, 'mp3', 'al1', 'ar1', 'Jazz', 2001, datetime('now'), datetime('now')),
+			('t3', 'Hit', 210, 1, 1, '/a/3.mp3', 100, 'mp3', 'al2', 'ar1', 'Rock', 2003, datetime('now'), datetime('now')),
+			('t4', 'Cover', 240, 1, 1, '/a/4.mp3', 100, 'mp3', 'al3', 'ar1', 'Pop', 2005, datetime('now'), datetime('now'))`,
+	)
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"harmony/internal/models"
+)
+
+// Metadata fields a track can be missing
+const (
+	MetadataTitle  = "title"
+	MetadataArtist = "artist"
+	MetadataAlbum  = "album"
+	MetadataYear   = "year"
+	MetadataGenre  = "genre"
+)
+
+// MetadataFields lists the fields checked for incomplete metadata, in the
+// order they are reported
+var MetadataFields = []string{MetadataTitle, MetadataArtist, MetadataAlbum, MetadataYear, MetadataGenre}
+
+// IncompleteMetadataOptions selects tracks missing metadata
+type IncompleteMetadataOptions struct {
+	// Missing lists the fields to check; a track missing any of them
+	// matches. Empty checks all of MetadataFields.
+	Missing []string
+	// UnknownArtist and UnknownAlbum name the placeholder artist and album
+	// tracks without those tags are filed under
+	UnknownArtist string
+	UnknownAlbum  string
+	Page          int
+	Limit         int
+}
+
+// missingMetadataCondition returns the SQL condition matching tracks
+// missing field, with its arguments
+func missingMetadataCondition(field string, opts IncompleteMetadataOptions) (string, []interface{}) {
+	switch field {
+	case MetadataTitle:
+		return "tracks.title IS NULL OR TRIM(tracks.title) = ''", nil
+	case MetadataArtist:
+		return "tracks.artist_id IS NULL OR tracks.artist_id = '' OR tracks.artist_id IN (SELECT id FROM artists WHERE name = ?)",
+			[]interface{}{opts.UnknownArtist}
+	case MetadataAlbum:
+		return "tracks.album_id IS NULL OR tracks.album_id = '' OR tracks.album_id IN (SELECT id FROM albums WHERE title = ?)",
+			[]interface{}{opts.UnknownAlbum}
+	case MetadataYear:
+		return "tracks.year IS NULL OR tracks.year <= 0", nil
+	case MetadataGenre:
+		return "tracks.genre IS NULL OR TRIM(tracks.genre) = ''", nil
+	}
+	return "", nil
+}
+
+// ListIncompleteMetadata returns tracks missing any of the selected metadata
+// fields, ordered by file path so tracks of one folder are listed together
+func (r *TrackRepository) ListIncompleteMetadata(ctx context.Context, opts IncompleteMetadataOptions) ([]models.Track, int64, error) {
+	fields := opts.Missing
+	if len(fields) == 0 {
+		fields = MetadataFields
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, field := range fields {
+		condition, conditionArgs := missingMetadataCondition(field, opts)
+		if condition == "" {
+			return nil, 0, fmt.Errorf("unknown metadata field: %s", field)
+		}
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, conditionArgs...)
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Track{}).
+		Where(strings.Join(conditions, " OR "), args...)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting tracks with incomplete metadata: %w", err)
+	}
+
+	var tracks []models.Track
+	query = query.Order("tracks.file_path").Order("tracks.start_offset")
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+	if opts.Page > 0 && opts.Limit > 0 {
+		query = query.Offset((opts.Page - 1) * opts.Limit)
+	}
+	if err := query.Scopes(withoutLyrics).Preload("Album").Preload("Artist").Find(&tracks).Error; err != nil {
+		return nil, 0, fmt.Errorf("listing tracks with incomplete metadata: %w", err)
+	}
+
+	return tracks, total, nil
+}
+
+// MissingMetadata returns the fields of MetadataFields a loaded track is
+// missing. The track's Artist and Album must be preloaded.
+func MissingMetadata(track models.Track, unknownArtist, unknownAlbum string) []string {
+	var missing []string
+	if strings.TrimSpace(track.Title) == "" {
+		missing = append(missing, MetadataTitle)
+	}
+	if track.Artist == nil || track.Artist.Name == unknownArtist {
+		missing = append(missing, MetadataArtist)
+	}
+	if track.Album == nil || track.Album.Title == unknownAlbum {
+		missing = append(missing, MetadataAlbum)
+	}
+	if track.Year <= 0 {
+		missing = append(missing, MetadataYear)
+	}
+	if strings.TrimSpace(track.Genre) == "" {
+		missing = append(missing, MetadataGenre)
+	}
+	return missing
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestListIncompleteMetadata(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('ar3', 'Unknown Artist', datetime('now'), datetime('now'))`,
+		`INSERT INTO albums (id, title, artist_id, created_at, updated_at) VALUES
+			('al4', 'Unknown Album', 'ar3', datetime('now'), datetime('now'))`,
+		`INSERT INTO tracks (id, title, duration, file_path, file_size, format, album_id, artist_id, genre, year, created_at, updated_at) VALUES
+			('t5', '  ', 100, '/b/5.mp3', 100, 'mp3', 'al1', 'ar1', 'Rock', 2001, datetime('now'), datetime('now')),
+			('t6', 'Six', 100, '/b/6.mp3', 100, 'mp3', 'al1', 'ar3', 'Rock', 2001, datetime('now'), datetime('now')),
+			('t7', 'Seven', 100, '/b/7.mp3', 100, 'mp3', NULL, 'ar1', 'Rock', 2001, datetime('now'), datetime('now')),
+			('t8', 'Eight', 100, '/b/8.mp3', 100, 'mp3', 'al4', 'ar1', 'Rock', 0, datetime('now'), datetime('now')),
+			('t9', 'Nine', 100, '/b/9.mp3', 100, 'mp3', 'al1', 'ar1', '', NULL, datetime('now'), datetime('now'))`,
+	)
+	repo := NewTrackRepository(db.DB)
+	// What each incomplete track is missing, as MissingMetadata reports it
+	missing := map[string][]string{
+		"t5": {MetadataTitle},
+		"t6": {MetadataArtist},
+		"t7": {MetadataAlbum},
+		"t8": {MetadataAlbum, MetadataYear},
+		"t9": {MetadataYear, MetadataGenre},
+	}
+
+	tests := []struct {
+		name    string
+		missing []string
+		page    int
+		limit   int
+		ids     []string
+		total   int64
+		err     bool
+	}{
+		{"any field", nil, 0, 0, []string{"t5", "t6", "t7", "t8", "t9"}, 5, false},
+		{"title", []string{MetadataTitle}, 0, 0, []string{"t5"}, 1, false},
+		{"artist", []string{MetadataArtist}, 0, 0, []string{"t6"}, 1, false},
+		{"album", []string{MetadataAlbum}, 0, 0, []string{"t7", "t8"}, 2, false},
+		{"year", []string{MetadataYear}, 0, 0, []string{"t8", "t9"}, 2, false},
+		{"genre", []string{MetadataGenre}, 0, 0, []string{"t9"}, 1, false},
+		{"title or genre", []string{MetadataTitle, MetadataGenre}, 0, 0, []string{"t5", "t9"}, 2, false},
+		{"second page", nil, 2, 2, []string{"t7", "t8"}, 5, false},
+		{"unknown field", []string{"composer"}, 0, 0, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, total, err := repo.ListIncompleteMetadata(context.Background(), IncompleteMetadataOptions{
+				Missing:       tt.missing,
+				UnknownArtist: "Unknown Artist",
+				UnknownAlbum:  "Unknown Album",
+				Page:          tt.page,
+				Limit:         tt.limit,
+			})
+			if (err != nil) != tt.err {
+				t.Fatalf("ListIncompleteMetadata error = %v, want error %v", err, tt.err)
+			}
+			var ids []string
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+				got := MissingMetadata(track, "Unknown Artist", "Unknown Album")
+				if !slices.Equal(got, missing[track.ID]) {
+					t.Errorf("%s is missing %v, want %v", track.ID, got, missing[track.ID])
+				}
+			}
+			if !slices.Equal(ids, tt.ids) || total != tt.total {
+				t.Errorf("tracks = %v of %d, want %v of %d", ids, total, tt.ids, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+// looseTracks selects tracks that aren't on an album, which the catalog
+// lists under their own artist
+const looseTracks = "album_id IS NULL OR album_id = '' OR album_id NOT IN (SELECT id FROM albums)"
+
+// ListCatalog returns a page of artists, ordered by ID so pages stay stable
+// as names change, with their albums and the albums' tracks loaded. Tracks
+// that aren't on an album are loaded as the artist's own tracks.
+func (r *ArtistRepository) ListCatalog(ctx context.Context, page, limit int) ([]models.Artist, int64, error) {
+	var artists []models.Artist
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&models.Artist{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting artists: %w", err)
+	}
+
+	err := r.db.WithContext(ctx).
+		Preload("Albums", func(db *gorm.DB) *gorm.DB {
+			return db.Order("year, title, id")
+		}).
+		Preload("Albums.Tracks", func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(withoutLyrics).Order("disc_number, track_number, title, id")
+		}).
+		Preload("Tracks", func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(withoutLyrics).Where(looseTracks).Order("title, id")
+		}).
+		Order("id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&artists).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing catalog: %w", err)
+	}
+
+	return artists, total, nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+// libraryTotalsID is the primary key of the single library_totals row
+const libraryTotalsID = 1
+
+// libraryTotalsTriggers keep library_totals in step with every insert,
+// delete and size/duration change, including cascaded deletes
+var libraryTotalsTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS library_totals_track_insert AFTER INSERT ON tracks BEGIN
+		UPDATE library_totals SET track_count = track_count + 1,
+			total_duration = total_duration + NEW.duration,
+			total_size = total_size + NEW.file_size
+		WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_track_update AFTER UPDATE OF duration, file_size ON tracks BEGIN
+		UPDATE library_totals SET total_duration = total_duration - OLD.duration + NEW.duration,
+			total_size = total_size - OLD.file_size + NEW.file_size
+		WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_track_delete AFTER DELETE ON tracks BEGIN
+		UPDATE library_totals SET track_count = track_count - 1,
+			total_duration = total_duration - OLD.duration,
+			total_size = total_size - OLD.file_size
+		WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_album_insert AFTER INSERT ON albums BEGIN
+		UPDATE library_totals SET album_count = album_count + 1 WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_album_delete AFTER DELETE ON albums BEGIN
+		UPDATE library_totals SET album_count = album_count - 1 WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_artist_insert AFTER INSERT ON artists BEGIN
+		UPDATE library_totals SET artist_count = artist_count + 1 WHERE id = 1;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS library_totals_artist_delete AFTER DELETE ON artists BEGIN
+		UPDATE library_totals SET artist_count = artist_count - 1 WHERE id = 1;
+	END`,
+}
+
+// migrateLibraryTotals installs the totals triggers and rebuilds the totals,
+// which also fills them in for libraries created before they existed
+func (d *Database) migrateLibraryTotals() error {
+	for _, trigger := range libraryTotalsTriggers {
+		if err := d.DB.Exec(trigger).Error; err != nil {
+			return fmt.Errorf("creating library totals trigger: %w", err)
+		}
+	}
+	return recountLibraryTotals(d.DB)
+}
+
+// LibraryTotals returns the running library totals
+func (r *TrackRepository) LibraryTotals(ctx context.Context) (*models.LibraryTotals, error) {
+	var totals models.LibraryTotals
+	if err := r.db.WithContext(ctx).First(&totals, libraryTotalsID).Error; err != nil {
+		return nil, fmt.Errorf("getting library totals: %w", err)
+	}
+	return &totals, nil
+}
+
+// CountLibraryTotals computes the library totals from the tables themselves
+func (r *TrackRepository) CountLibraryTotals(ctx context.Context) (*models.LibraryTotals, error) {
+	return countLibraryTotals(r.db.WithContext(ctx))
+}
+
+// RecountLibraryTotals rebuilds the running totals from a full count,
+// correcting any drift
+func (r *TrackRepository) RecountLibraryTotals(ctx context.Context) error {
+	return recountLibraryTotals(r.db.WithContext(ctx))
+}
+
+func countLibraryTotals(db *gorm.DB) (*models.LibraryTotals, error) {
+	var totals models.LibraryTotals
+	err := db.Model(&models.Track{}).
+		Select("COUNT(*) AS track_count, COALESCE(SUM(duration), 0) AS total_duration, COALESCE(SUM(file_size), 0) AS total_size").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("counting tracks: %w", err)
+	}
+	totals.ID = libraryTotalsID
+
+	if err := db.Model(&models.Album{}).Count(&totals.AlbumCount).Error; err != nil {
+		return nil, fmt.Errorf("counting albums: %w", err)
+	}
+	if err := db.Model(&models.Artist{}).Count(&totals.ArtistCount).Error; err != nil {
+		return nil, fmt.Errorf("counting artists: %w", err)
+	}
+	return &totals, nil
+}
+
+func recountLibraryTotals(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		totals, err := countLibraryTotals(tx)
+		if err != nil {
+			return err
+		}
+		totals.RecountedAt = time.Now()
+
+		if err := tx.Save(totals).Error; err != nil {
+			return fmt.Errorf("saving library totals: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"harmony/internal/models"
+)
+
+func TestLibraryTotals(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTrackRepository(db.DB)
+	ctx := context.Background()
+
+	exec := func(statements ...string) func() {
+		return func() { execSQL(t, db, statements...) }
+	}
+
+	// Steps run in order against the same library
+	tests := []struct {
+		name string
+		run  func()
+		want models.LibraryTotals
+	}{
+		{"empty", func() {}, models.LibraryTotals{}},
+		{"seeded", func() { seedLibrary(t, db) }, models.LibraryTotals{
+			TrackCount: 4, AlbumCount: 3, ArtistCount: 2, TotalDuration: 830, TotalSize: 400}},
+		{"track added", exec(`INSERT INTO tracks (id, title, duration, file_path, file_size, format, album_id, artist_id, created_at, updated_at)
+			VALUES ('t5', 'Five', 100, '/a/5.mp3', 50, 'mp3', 'al2', 'ar1', datetime('now'), datetime('now'))`),
+			models.LibraryTotals{TrackCount: 5, AlbumCount: 3, ArtistCount: 2, TotalDuration: 930, TotalSize: 450}},
+		{"track rescanned", exec(`UPDATE tracks SET duration = 150, file_size = 70 WHERE id = 't5'`),
+			models.LibraryTotals{TrackCount: 5, AlbumCount: 3, ArtistCount: 2, TotalDuration: 980, TotalSize: 470}},
+		{"other columns changed", exec(`UPDATE tracks SET title = 'Renamed', play_count = 3 WHERE id = 't5'`),
+			models.LibraryTotals{TrackCount: 5, AlbumCount: 3, ArtistCount: 2, TotalDuration: 980, TotalSize: 470}},
+		{"track deleted", exec(`DELETE FROM tracks WHERE id = 't1'`),
+			models.LibraryTotals{TrackCount: 4, AlbumCount: 3, ArtistCount: 2, TotalDuration: 780, TotalSize: 370}},
+		{"artist and album added", exec(
+			`INSERT INTO artists (id, name, created_at, updated_at) VALUES ('ar3', 'New', datetime('now'), datetime('now'))`,
+			`INSERT INTO albums (id, title, artist_id, created_at, updated_at) VALUES ('al4', 'Debut', 'ar3', datetime('now'), datetime('now'))`),
+			models.LibraryTotals{TrackCount: 4, AlbumCount: 4, ArtistCount: 3, TotalDuration: 780, TotalSize: 370}},
+		{"artist and album deleted", exec(`DELETE FROM albums WHERE id = 'al4'`, `DELETE FROM artists WHERE id = 'ar3'`),
+			models.LibraryTotals{TrackCount: 4, AlbumCount: 3, ArtistCount: 2, TotalDuration: 780, TotalSize: 370}},
+		{"drift recounted", func() {
+			execSQL(t, db, `UPDATE library_totals SET track_count = 99, total_size = 0`)
+			if err := repo.RecountLibraryTotals(ctx); err != nil {
+				t.Fatalf("recounting: %v", err)
+			}
+		}, models.LibraryTotals{TrackCount: 4, AlbumCount: 3, ArtistCount: 2, TotalDuration: 780, TotalSize: 370}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run()
+
+			totals, err := repo.LibraryTotals(ctx)
+			if err != nil {
+				t.Fatalf("LibraryTotals: %v", err)
+			}
+			counted, err := repo.CountLibraryTotals(ctx)
+			if err != nil {
+				t.Fatalf("CountLibraryTotals: %v", err)
+			}
+			// Only the counts are compared, not when they were recounted
+			totals.RecountedAt, counted.RecountedAt = time.Time{}, time.Time{}
+			totals.ID, counted.ID = 0, 0
+			if *totals != tt.want {
+				t.Errorf("running totals = %+v, want %+v", *totals, tt.want)
+			}
+			if *counted != tt.want {
+				t.Errorf("full count = %+v, want %+v", *counted, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"harmony/internal/models"
+)
+
+// libraryVersionID is the primary key of the single library_version row
+const libraryVersionID = 1
+
+// versionedTables are the tables whose writes change the library version:
+// everything the catalog index and the list endpoints return
+var versionedTables = []string{"tracks", "albums", "artists", "playlists", "playlist_tracks", "settings"}
+
+// unlistedColumns are the columns of versioned tables that no list shows,
+// sorts or filters by, so updating only them leaves the version alone
+var unlistedColumns = map[string][]string{
+	"tracks": {"file_path", "root_id", "file_hash", "start_offset", "end_offset",
+		"channels", "folded_album", "lyrics", "analyzed_at"},
+	"albums":  {"notes", "notes_edited"},
+	"artists": {"name_key"},
+}
+
+// migrateLibraryVersion creates the version row and installs the triggers
+// bumping it. Triggers catch every write, including column updates that
+// leave updated_at alone and deletes that cascade. Update triggers are
+// recreated each time, so they cover columns added since.
+func (d *Database) migrateLibraryVersion() error {
+	err := d.DB.Exec(`INSERT OR IGNORE INTO library_version (id, version) VALUES (?, 0)`, libraryVersionID).Error
+	if err != nil {
+		return fmt.Errorf("creating library version: %w", err)
+	}
+
+	for _, table := range versionedTables {
+		columns, err := d.listedColumns(table)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			name := fmt.Sprintf("library_version_%s_%s", table, strings.ToLower(event))
+			on := event
+			if event == "UPDATE" {
+				if err := d.DB.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+					return fmt.Errorf("dropping library version trigger: %w", err)
+				}
+				on = "UPDATE OF " + strings.Join(columns, ", ")
+			}
+
+			trigger := fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s BEGIN
+				UPDATE library_version SET version = version + 1 WHERE id = %d;
+			END`, name, on, table, libraryVersionID)
+			if err := d.DB.Exec(trigger).Error; err != nil {
+				return fmt.Errorf("creating library version trigger: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// listedColumns returns the columns of table that lists show
+func (d *Database) listedColumns(table string) ([]string, error) {
+	columnTypes, err := d.DB.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s columns: %w", table, err)
+	}
+
+	var columns []string
+	for _, column := range columnTypes {
+		if !slices.Contains(unlistedColumns[table], column.Name()) {
+			columns = append(columns, column.Name())
+		}
+	}
+	return columns, nil
+}
+
+// LibraryVersion returns a counter that goes up whenever a track, album,
+// artist, playlist or setting is added, removed or updated
+func (r *TrackRepository) LibraryVersion(ctx context.Context) (int64, error) {
+	var version models.LibraryVersion
+	if err := r.db.WithContext(ctx).First(&version, libraryVersionID).Error; err != nil {
+		return 0, fmt.Errorf("getting library version: %w", err)
+	}
+	return version.Version, nil
+}
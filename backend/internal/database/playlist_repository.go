@@ -12,8 +12,9 @@ import (
 )
 
 var (
-	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrPlaylistNotFound   = errors.New("playlist not found")
 	ErrTrackNotInPlaylist = errors.New("track not in playlist")
+	ErrPlaylistFull       = errors.New("playlist track limit reached")
 )
 
 type PlaylistRepository struct {
@@ -36,6 +37,8 @@ type PlaylistListOptions struct {
 	Limit  int
 	SortBy string
 	Order  string
+	// Locale sorts names under its collation (see SortLocales)
+	Locale string
 }
 
 func (r *PlaylistRepository) Create(ctx context.Context, playlist *models.Playlist) error {
@@ -70,7 +73,7 @@ func (r *PlaylistRepository) FindByIDWithTracks(ctx context.Context, id string)
 		Preload("PlaylistTracks", func(db *gorm.DB) *gorm.DB {
 			return db.Order("position ASC")
 		}).
-		Preload("PlaylistTracks.Track").
+		Preload("PlaylistTracks.Track", withoutLyrics).
 		Preload("PlaylistTracks.Track.Album").
 		Preload("PlaylistTracks.Track.Artist").
 		First(&playlist, "id = ?", id)
@@ -113,8 +116,7 @@ func (r *PlaylistRepository) List(ctx context.Context, opts PlaylistListOptions)
 		query = query.Where("is_public = ?", *opts.Filter.IsPublic)
 	}
 	if opts.Filter.Query != "" {
-		searchQuery := "%" + opts.Filter.Query + "%"
-		query = query.Where("name LIKE ?", searchQuery)
+		query = query.Where(searchCondition(r.db, "name", opts.Filter.Query))
 	}
 
 	// Count total
@@ -131,6 +133,9 @@ func (r *PlaylistRepository) List(ctx context.Context, opts PlaylistListOptions)
 	if opts.Order == "desc" {
 		order = "DESC"
 	}
+	if sortBy == "name" {
+		sortBy = collatedColumn(sortBy, opts.Locale)
+	}
 	query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
 
 	// Apply pagination
@@ -185,33 +190,47 @@ func (r *PlaylistRepository) Delete(ctx context.Context, id string) error {
 	})
 }
 
-func (r *PlaylistRepository) AddTrack(ctx context.Context, playlistID, trackID string) error {
-	// Get current max position
-	var maxPosition int
-	r.db.WithContext(ctx).
-		Model(&models.PlaylistTrack{}).
-		Where("playlist_id = ?", playlistID).
-		Select("COALESCE(MAX(position), 0)").
-		Scan(&maxPosition)
+// AddTrack appends a track to a playlist. A positive limit caps the number
+// of tracks the playlist may hold; ErrPlaylistFull is returned once it's reached.
+func (r *PlaylistRepository) AddTrack(ctx context.Context, playlistID, trackID string, limit int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if limit > 0 {
+			var count int64
+			if err := tx.Model(&models.PlaylistTrack{}).
+				Where("playlist_id = ?", playlistID).
+				Count(&count).Error; err != nil {
+				return fmt.Errorf("counting playlist tracks: %w", err)
+			}
+			if count >= int64(limit) {
+				return ErrPlaylistFull
+			}
+		}
 
-	playlistTrack := &models.PlaylistTrack{
-		PlaylistID: playlistID,
-		TrackID:    trackID,
-		Position:   maxPosition + 1,
-		AddedAt:    time.Now(),
-	}
+		// Get current max position
+		var maxPosition int
+		tx.Model(&models.PlaylistTrack{}).
+			Where("playlist_id = ?", playlistID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&maxPosition)
+
+		playlistTrack := &models.PlaylistTrack{
+			PlaylistID: playlistID,
+			TrackID:    trackID,
+			Position:   maxPosition + 1,
+			AddedAt:    time.Now(),
+		}
 
-	if err := r.db.WithContext(ctx).Create(playlistTrack).Error; err != nil {
-		return fmt.Errorf("adding track to playlist: %w", err)
-	}
+		if err := tx.Create(playlistTrack).Error; err != nil {
+			return fmt.Errorf("adding track to playlist: %w", err)
+		}
 
-	// Update playlist's updated_at
-	r.db.WithContext(ctx).
-		Model(&models.Playlist{}).
-		Where("id = ?", playlistID).
-		Update("updated_at", time.Now())
+		// Update playlist's updated_at
+		tx.Model(&models.Playlist{}).
+			Where("id = ?", playlistID).
+			Update("updated_at", time.Now())
 
-	return nil
+		return nil
+	})
 }
 
 func (r *PlaylistRepository) RemoveTrack(ctx context.Context, playlistID, trackID string) error {
@@ -233,6 +252,74 @@ func (r *PlaylistRepository) RemoveTrack(ctx context.Context, playlistID, trackI
 	return nil
 }
 
+// MergeTracks appends the tracks of sourceID to targetID in their source
+// order, skipping tracks the target already has, in one transaction. Returns
+// the number of tracks added. A positive limit caps the size of the target;
+// merges that would exceed it fail with ErrPlaylistFull and add nothing.
+func (r *PlaylistRepository) MergeTracks(ctx context.Context, targetID, sourceID string, limit int) (int, error) {
+	var added int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var maxPosition int
+		if err := tx.Model(&models.PlaylistTrack{}).
+			Where("playlist_id = ?", targetID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&maxPosition).Error; err != nil {
+			return fmt.Errorf("getting playlist length: %w", err)
+		}
+
+		var trackIDs []string
+		if err := tx.Model(&models.PlaylistTrack{}).
+			Where("playlist_id = ?", sourceID).
+			Where("track_id NOT IN (?)", tx.Model(&models.PlaylistTrack{}).Select("track_id").Where("playlist_id = ?", targetID)).
+			Order("position ASC").
+			Pluck("track_id", &trackIDs).Error; err != nil {
+			return fmt.Errorf("getting source playlist tracks: %w", err)
+		}
+		if len(trackIDs) == 0 {
+			return nil
+		}
+
+		if limit > 0 {
+			var count int64
+			if err := tx.Model(&models.PlaylistTrack{}).
+				Where("playlist_id = ?", targetID).
+				Count(&count).Error; err != nil {
+				return fmt.Errorf("counting playlist tracks: %w", err)
+			}
+			if count+int64(len(trackIDs)) > int64(limit) {
+				return ErrPlaylistFull
+			}
+		}
+
+		now := time.Now()
+		entries := make([]models.PlaylistTrack, len(trackIDs))
+		for i, trackID := range trackIDs {
+			entries[i] = models.PlaylistTrack{
+				PlaylistID: targetID,
+				TrackID:    trackID,
+				Position:   maxPosition + i + 1,
+				AddedAt:    now,
+			}
+		}
+		if err := tx.CreateInBatches(entries, 100).Error; err != nil {
+			return fmt.Errorf("adding merged tracks: %w", err)
+		}
+
+		if err := tx.Model(&models.Playlist{}).
+			Where("id = ?", targetID).
+			Update("updated_at", now).Error; err != nil {
+			return fmt.Errorf("updating playlist: %w", err)
+		}
+
+		added = len(entries)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
 func (r *PlaylistRepository) ReorderTracks(ctx context.Context, playlistID string, trackIDs []string) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for i, trackID := range trackIDs {
@@ -247,8 +334,14 @@ func (r *PlaylistRepository) ReorderTracks(ctx context.Context, playlistID strin
 }
 
 func (r *PlaylistRepository) reorderTracks(ctx context.Context, playlistID string) error {
+	return reorderPlaylistTracks(r.db.WithContext(ctx), playlistID)
+}
+
+// reorderPlaylistTracks numbers a playlist's tracks from 1 again, closing
+// the gaps removed tracks left
+func reorderPlaylistTracks(db *gorm.DB, playlistID string) error {
 	var tracks []models.PlaylistTrack
-	if err := r.db.WithContext(ctx).
+	if err := db.
 		Where("playlist_id = ?", playlistID).
 		Order("position ASC").
 		Find(&tracks).Error; err != nil {
@@ -257,7 +350,7 @@ func (r *PlaylistRepository) reorderTracks(ctx context.Context, playlistID strin
 
 	for i, track := range tracks {
 		if track.Position != i+1 {
-			if err := r.db.WithContext(ctx).
+			if err := db.
 				Model(&models.PlaylistTrack{}).
 				Where("playlist_id = ? AND track_id = ?", playlistID, track.TrackID).
 				Update("position", i+1).Error; err != nil {
@@ -302,3 +395,100 @@ func (r *PlaylistRepository) HasTrack(ctx context.Context, playlistID, trackID s
 	}
 	return count > 0, nil
 }
+
+// orphanedPlaylistTrack matches playlist entries whose track or playlist is gone
+const orphanedPlaylistTrack = "track_id NOT IN (SELECT id FROM tracks) OR playlist_id NOT IN (SELECT id FROM playlists)"
+
+// CountOrphanedTracks counts playlist entries that reference a deleted track
+// or playlist
+func (r *PlaylistRepository) CountOrphanedTracks(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.PlaylistTrack{}).
+		Where(orphanedPlaylistTrack).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("counting orphaned playlist tracks: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOrphanedTracks removes playlist entries that reference a deleted
+// track or playlist and renumbers the affected playlists. Returns the number
+// of entries removed.
+func (r *PlaylistRepository) DeleteOrphanedTracks(ctx context.Context) (int64, error) {
+	var playlistIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.PlaylistTrack{}).
+		Where(orphanedPlaylistTrack).
+		Distinct("playlist_id").
+		Pluck("playlist_id", &playlistIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("finding orphaned playlist tracks: %w", err)
+	}
+
+	result := r.db.WithContext(ctx).
+		Where(orphanedPlaylistTrack).
+		Delete(&models.PlaylistTrack{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("deleting orphaned playlist tracks: %w", result.Error)
+	}
+
+	for _, playlistID := range playlistIDs {
+		if err := r.reorderTracks(ctx, playlistID); err != nil {
+			return result.RowsAffected, err
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// PlaylistStats holds aggregate figures over a playlist's tracks
+type PlaylistStats struct {
+	TrackCount     int
+	Duration       int
+	ArtistCount    int
+	AlbumCount     int
+	AverageBitrate float64
+	Genres         []GenreCount `gorm:"-"`
+}
+
+// GenreCount is how many tracks share a genre
+type GenreCount struct {
+	Genre string
+	Count int
+}
+
+// GetStats computes aggregate figures for a playlist. The average bitrate
+// only includes tracks with a known bitrate; tracks without a genre are left
+// out of the genre breakdown.
+func (r *PlaylistRepository) GetStats(ctx context.Context, playlistID string) (*PlaylistStats, error) {
+	var stats PlaylistStats
+	err := r.db.WithContext(ctx).
+		Table("playlist_tracks").
+		Select(`COUNT(*) AS track_count,
+			COALESCE(SUM(tracks.duration), 0) AS duration,
+			COUNT(DISTINCT NULLIF(tracks.artist_id, '')) AS artist_count,
+			COUNT(DISTINCT NULLIF(tracks.album_id, '')) AS album_count,
+			COALESCE(AVG(NULLIF(tracks.bitrate, 0)), 0) AS average_bitrate`).
+		Joins("JOIN tracks ON tracks.id = playlist_tracks.track_id").
+		Where("playlist_tracks.playlist_id = ?", playlistID).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("getting playlist stats: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).
+		Table("playlist_tracks").
+		Select("tracks.genre AS genre, COUNT(*) AS count").
+		Joins("JOIN tracks ON tracks.id = playlist_tracks.track_id").
+		Where("playlist_tracks.playlist_id = ? AND tracks.genre <> ''", playlistID).
+		Group("tracks.genre").
+		Order("count DESC, genre ASC").
+		Scan(&stats.Genres).Error
+	if err != nil {
+		return nil, fmt.Errorf("getting playlist genres: %w", err)
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMergeTracks(t *testing.T) {
+	tests := []struct {
+		name   string
+		target []string
+		source []string
+		limit  int
+		added  int
+		err    error
+		want   []string
+	}{
+		{"overlapping", []string{"t1", "t2"}, []string{"t3", "t2", "t4"}, 0, 2, nil, []string{"t1", "t2", "t3", "t4"}},
+		{"into an empty playlist", nil, []string{"t2", "t1"}, 0, 2, nil, []string{"t2", "t1"}},
+		{"nothing new", []string{"t1", "t2"}, []string{"t2"}, 0, 0, nil, []string{"t1", "t2"}},
+		{"from an empty playlist", []string{"t3"}, nil, 0, 0, nil, []string{"t3"}},
+		{"up to the limit", []string{"t1", "t2"}, []string{"t2", "t3", "t4"}, 4, 2, nil, []string{"t1", "t2", "t3", "t4"}},
+		{"past the limit", []string{"t1", "t2"}, []string{"t2", "t3", "t4"}, 3, 0, ErrPlaylistFull, []string{"t1", "t2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			seedLibrary(t, db)
+			execSQL(t, db, `INSERT INTO playlists (id, name, created_at, updated_at) VALUES
+				('target', 'Target', datetime('now'), datetime('now')),
+				('source', 'Source', datetime('now'), datetime('now'))`)
+			for playlistID, trackIDs := range map[string][]string{"target": tt.target, "source": tt.source} {
+				for i, trackID := range trackIDs {
+					execSQL(t, db, fmt.Sprintf(`INSERT INTO playlist_tracks (playlist_id, track_id, position, added_at)
+						VALUES ('%s', '%s', %d, datetime('now'))`, playlistID, trackID, i+1))
+				}
+			}
+
+			added, err := NewPlaylistRepository(db.DB).MergeTracks(context.Background(), "target", "source", tt.limit)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("MergeTracks error = %v, want %v", err, tt.err)
+			}
+			if added != tt.added {
+				t.Errorf("added %d tracks, want %d", added, tt.added)
+			}
+
+			var entries []struct {
+				TrackID  string
+				Position int
+			}
+			db.DB.Raw("SELECT track_id, position FROM playlist_tracks WHERE playlist_id = 'target' ORDER BY position").Scan(&entries)
+			var got []string
+			for i, entry := range entries {
+				got = append(got, entry.TrackID)
+				if entry.Position != i+1 {
+					t.Errorf("%s at position %d, want %d", entry.TrackID, entry.Position, i+1)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("target tracks = %v, want %v", got, tt.want)
+			}
+
+			// The source is left as it was
+			var source []string
+			db.DB.Raw("SELECT track_id FROM playlist_tracks WHERE playlist_id = 'source' ORDER BY position").Scan(&source)
+			if !slices.Equal(source, tt.source) {
+				t.Errorf("source tracks = %v, want %v", source, tt.source)
+			}
+		})
+	}
+}
@@ -68,6 +68,7 @@ const (
 	KeyPrefixAlbumArt    = "art:"
 	KeyPrefixSearch      = "search:"
 	KeyPrefixLibraryStats = "library:stats"
+	KeyPrefixAudioInfo    = "audioinfo:"
 )
 
 // TTL durations
@@ -76,6 +77,7 @@ const (
 	TTLAlbumArt      = 1 * time.Hour
 	TTLSearchResults = 5 * time.Minute
 	TTLLibraryStats  = 5 * time.Minute
+	TTLAudioInfo     = 24 * time.Hour
 )
 
 // Get retrieves a value from cache
@@ -147,6 +149,17 @@ func (r *RedisClient) GetCachedSearchResults(ctx context.Context, query string,
 	return r.GetJSON(ctx, key, dest)
 }
 
+// CacheAudioInfo caches probed codec details under a version key that
+// changes whenever the file is rescanned
+func (r *RedisClient) CacheAudioInfo(ctx context.Context, version string, info interface{}) error {
+	return r.SetJSON(ctx, KeyPrefixAudioInfo+version, info, TTLAudioInfo)
+}
+
+// GetCachedAudioInfo retrieves cached codec details
+func (r *RedisClient) GetCachedAudioInfo(ctx context.Context, version string, dest interface{}) error {
+	return r.GetJSON(ctx, KeyPrefixAudioInfo+version, dest)
+}
+
 // InvalidateTrack removes a track from cache
 func (r *RedisClient) InvalidateTrack(ctx context.Context, trackID string) error {
 	return r.Delete(ctx, KeyPrefixTrack+trackID)
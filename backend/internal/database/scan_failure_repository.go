@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"harmony/internal/models"
+)
+
+type ScanFailureRepository struct {
+	db *gorm.DB
+}
+
+func NewScanFailureRepository(db *gorm.DB) *ScanFailureRepository {
+	return &ScanFailureRepository{db: db}
+}
+
+// GetAll returns every recorded failure keyed by file path
+func (r *ScanFailureRepository) GetAll(ctx context.Context) (map[string]models.ScanFailure, error) {
+	var failures []models.ScanFailure
+	if err := r.db.WithContext(ctx).Find(&failures).Error; err != nil {
+		return nil, fmt.Errorf("getting scan failures: %w", err)
+	}
+
+	byPath := make(map[string]models.ScanFailure, len(failures))
+	for _, failure := range failures {
+		byPath[failure.Path] = failure
+	}
+	return byPath, nil
+}
+
+// Record counts another failure of the file at path. The count starts over
+// when the file has changed since the last failure.
+func (r *ScanFailureRepository) Record(ctx context.Context, path string, modTime time.Time, message string) (*models.ScanFailure, error) {
+	var failure models.ScanFailure
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&failure, "path = ?", path).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("finding scan failure: %w", err)
+		}
+		if err != nil || !failure.ModTime.Equal(modTime) {
+			failure = models.ScanFailure{Path: path, ModTime: modTime}
+		}
+
+		failure.FailCount++
+		failure.LastError = message
+		failure.LastFailedAt = time.Now()
+		if err := tx.Save(&failure).Error; err != nil {
+			return fmt.Errorf("saving scan failure: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// Delete forgets the failures of the file at path
+func (r *ScanFailureRepository) Delete(ctx context.Context, path string) error {
+	if err := r.db.WithContext(ctx).Delete(&models.ScanFailure{}, "path = ?", path).Error; err != nil {
+		return fmt.Errorf("deleting scan failure: %w", err)
+	}
+	return nil
+}
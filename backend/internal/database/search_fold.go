@@ -0,0 +1,59 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// plainSearchSetting marks a handle whose searches use plain LIKE instead of
+// folded text (see Config.PlainSearch)
+const plainSearchSetting = "harmony:plain_search"
+
+// withPlainSearch returns db set up to search with plain LIKE when plain is
+// set. Like the track paths, the setting carries over to every query made
+// from the returned handle.
+func withPlainSearch(db *gorm.DB, plain bool) *gorm.DB {
+	if !plain {
+		return db
+	}
+	return db.Set(plainSearchSetting, true).Session(&gorm.Session{})
+}
+
+// searchPunctuationFolds maps typographic punctuation to its ASCII form
+var searchPunctuationFolds = map[rune]rune{
+	'‘': '\'', '’': '\'', '‚': '\'', '‛': '\'', '′': '\'',
+	'“': '"', '”': '"', '„': '"', '‟': '"', '″': '"',
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '―': '-',
+}
+
+// FoldSearchText normalizes text for matching: compatibility decomposition
+// (NFKD) with combining marks dropped, so "Beyoncé" and "Beyonce" match in
+// either unicode form, smart quotes and dashes folded to ASCII, lower case,
+// and runs of whitespace collapsed to one space
+func FoldSearchText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if folded, ok := searchPunctuationFolds[r]; ok {
+			r = folded
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// searchCondition returns a WHERE clause matching column against a search
+// query, with its argument. Folded matching runs both sides through the
+// search_fold SQL function unless db was set up for plain searches.
+func searchCondition(db *gorm.DB, column, query string) (string, string) {
+	if plain, _ := db.Get(plainSearchSetting); plain == true {
+		return column + " LIKE ?", "%" + query + "%"
+	}
+	return "search_fold(" + column + ") LIKE ?", "%" + FoldSearchText(query) + "%"
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchFolding(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('ar3', 'Beyoncé', datetime('now'), datetime('now')),
+			('ar4', 'Sigur Ros', datetime('now'), datetime('now'))`,
+		`UPDATE tracks SET title = 'Don’t  Stop' WHERE id = 't1'`,
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		folding bool
+		artist  string
+		track   string
+		want    string
+	}{
+		{"accent left out of query", true, "beyonce", "", "ar3"},
+		{"accent only in query", true, "Sigur Rós", "", "ar4"},
+		{"decomposed query", true, "Beyonce\u0301", "", "ar3"},
+		{"smart quote and spaces", true, "", "don't stop", "t1"},
+		{"folding disabled", false, "beyonce", "", ""},
+		{"exact match without folding", false, "Beyoncé", "", "ar3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := withPlainSearch(db.DB, !tt.folding)
+			artists := NewArtistRepository(handle)
+			tracks := NewTrackRepository(handle)
+
+			var found []string
+			if tt.artist != "" {
+				results, err := artists.Search(ctx, tt.artist, 10)
+				if err != nil {
+					t.Fatalf("searching artists: %v", err)
+				}
+				for _, artist := range results {
+					found = append(found, artist.ID)
+				}
+			} else {
+				results, err := tracks.Search(ctx, tt.track, 10)
+				if err != nil {
+					t.Fatalf("searching tracks: %v", err)
+				}
+				for _, track := range results {
+					found = append(found, track.ID)
+				}
+			}
+
+			if tt.want == "" {
+				if len(found) != 0 {
+					t.Errorf("found %v, want nothing", found)
+				}
+				return
+			}
+			if len(found) != 1 || found[0] != tt.want {
+				t.Errorf("found %v, want [%s]", found, tt.want)
+			}
+		})
+	}
+}
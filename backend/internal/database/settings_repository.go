@@ -145,3 +145,63 @@ func (r *SettingsRepository) GetMediaPaths(ctx context.Context) ([]string, error
 func (r *SettingsRepository) SetMediaPaths(ctx context.Context, paths []string) error {
 	return r.SetJSON(ctx, models.SettingMediaPaths, paths)
 }
+
+// GetViewPreferences retrieves a user's saved view preferences
+func (r *SettingsRepository) GetViewPreferences(ctx context.Context, userID string) (models.ViewPreferences, error) {
+	prefs := models.ViewPreferences{}
+	err := r.GetJSON(ctx, models.SettingViewPreferencesPrefix+userID, &prefs)
+	if err != nil {
+		if errors.Is(err, ErrSettingNotFound) {
+			return models.ViewPreferences{}, nil
+		}
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// SetViewPreferences replaces a user's saved view preferences
+func (r *SettingsRepository) SetViewPreferences(ctx context.Context, userID string, prefs models.ViewPreferences) error {
+	return r.SetJSON(ctx, models.SettingViewPreferencesPrefix+userID, prefs)
+}
+
+// GetTranscodeProfiles retrieves the custom transcode profiles
+func (r *SettingsRepository) GetTranscodeProfiles(ctx context.Context) ([]models.TranscodeProfile, error) {
+	var profiles []models.TranscodeProfile
+	err := r.GetJSON(ctx, models.SettingTranscodeProfiles, &profiles)
+	if err != nil {
+		if errors.Is(err, ErrSettingNotFound) {
+			return []models.TranscodeProfile{}, nil
+		}
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// SetTranscodeProfiles replaces the custom transcode profiles
+func (r *SettingsRepository) SetTranscodeProfiles(ctx context.Context, profiles []models.TranscodeProfile) error {
+	return r.SetJSON(ctx, models.SettingTranscodeProfiles, profiles)
+}
+
+// GetLastFMCredentials retrieves a user's Last.fm credentials, or nil when
+// the user hasn't linked an account
+func (r *SettingsRepository) GetLastFMCredentials(ctx context.Context, userID string) (*models.LastFMCredentials, error) {
+	var creds models.LastFMCredentials
+	err := r.GetJSON(ctx, models.SettingLastFMPrefix+userID, &creds)
+	if err != nil {
+		if errors.Is(err, ErrSettingNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// SetLastFMCredentials links a user to a Last.fm account
+func (r *SettingsRepository) SetLastFMCredentials(ctx context.Context, userID string, creds models.LastFMCredentials) error {
+	return r.SetJSON(ctx, models.SettingLastFMPrefix+userID, creds)
+}
+
+// DeleteLastFMCredentials unlinks a user's Last.fm account
+func (r *SettingsRepository) DeleteLastFMCredentials(ctx context.Context, userID string) error {
+	return r.Delete(ctx, models.SettingLastFMPrefix+userID)
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"harmony/internal/models"
+)
+
+func TestRelativeTrackPaths(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	// repoAt opens the tracks with the media root configured as given
+	repoAt := func(root string, relative bool) *TrackRepository {
+		return NewTrackRepository(models.WithTrackPaths(db.DB, models.NewTrackPaths("main", root, relative)))
+	}
+	execSQL(t, db,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES ('ar1', 'Band', datetime('now'), datetime('now'))`,
+		`INSERT INTO albums (id, title, artist_id, created_at, updated_at) VALUES ('al1', 'First', 'ar1', datetime('now'), datetime('now'))`,
+	)
+
+	// A library imported with absolute paths, one track outside the root
+	repo := repoAt("/old/music", false)
+	for _, track := range []models.Track{
+		{ID: "t1", Title: "One", FilePath: "/old/music/Band/First/01.mp3", FileSize: 1, Format: "mp3", AlbumID: "al1", ArtistID: "ar1"},
+		{ID: "t2", Title: "Two", FilePath: "/elsewhere/02.mp3", FileSize: 1, Format: "mp3", AlbumID: "al1", ArtistID: "ar1"},
+	} {
+		if err := repo.Create(ctx, &track); err != nil {
+			t.Fatalf("creating track: %v", err)
+		}
+	}
+
+	// stored returns a track's path and root as saved
+	stored := func(id string) (string, string) {
+		t.Helper()
+		var row trackPathRow
+		db.DB.Raw("SELECT id, file_path, root_id FROM tracks WHERE id = ?", id).Scan(&row)
+		return row.FilePath, row.RootID
+	}
+
+	// Switching to relative paths converts the tracks under the root
+	repo = repoAt("/old/music", true)
+	migrated, err := repo.MigratePaths(ctx)
+	if err != nil {
+		t.Fatalf("MigratePaths: %v", err)
+	}
+	if migrated != 1 {
+		t.Errorf("migrated %d tracks, want 1", migrated)
+	}
+	if path, root := stored("t1"); path != "Band/First/01.mp3" || root != "main" {
+		t.Errorf("t1 stored as %q under %q, want Band/First/01.mp3 under main", path, root)
+	}
+	if path, root := stored("t2"); path != "/elsewhere/02.mp3" || root != "" {
+		t.Errorf("t2 stored as %q under %q, want its absolute path", path, root)
+	}
+
+	// The root moves to a new mount point
+	repo = repoAt("/new/mount", true)
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"t1", "/new/mount/Band/First/01.mp3"},
+		{"t2", "/elsewhere/02.mp3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			track, err := repo.FindByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("FindByID: %v", err)
+			}
+			if track.FilePath != tt.want {
+				t.Errorf("path = %q, want %q", track.FilePath, tt.want)
+			}
+			byPath, err := repo.FindByFilePath(ctx, tt.want)
+			if err != nil || byPath.ID != tt.id {
+				t.Errorf("FindByFilePath(%q) = %v, %v; want %s", tt.want, byPath, err, tt.id)
+			}
+		})
+	}
+
+	paths, err := repo.GetAllFilePaths(ctx)
+	if err != nil {
+		t.Fatalf("GetAllFilePaths: %v", err)
+	}
+	slices.Sort(paths)
+	if want := []string{"/elsewhere/02.mp3", "/new/mount/Band/First/01.mp3"}; !slices.Equal(paths, want) {
+		t.Errorf("file paths = %v, want %v", paths, want)
+	}
+
+	// Switching back stores the paths under the new mount point
+	repo = repoAt("/new/mount", false)
+	if _, err := repo.MigratePaths(ctx); err != nil {
+		t.Fatalf("MigratePaths: %v", err)
+	}
+	if path, root := stored("t1"); path != "/new/mount/Band/First/01.mp3" || root != "" {
+		t.Errorf("t1 stored as %q under %q after switching back, want its absolute path", path, root)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -12,6 +14,7 @@ import (
 
 var (
 	ErrTrackNotFound = errors.New("track not found")
+	ErrDuplicatePlay = errors.New("play already recorded")
 )
 
 type TrackRepository struct {
@@ -23,19 +26,35 @@ func NewTrackRepository(db *gorm.DB) *TrackRepository {
 }
 
 type TrackFilter struct {
-	AlbumID  string
-	ArtistID string
-	Genre    string
-	Year     int
-	Query    string
+	AlbumID   string
+	ArtistID  string
+	Genre     string
+	Year      int
+	Query     string
+	MinRating int
+	MinBPM    int
+	MaxBPM    int
+	Key       string
+	// Technical attributes, for finding files worth upgrading
+	Format     string
+	MinBitrate int
+	SampleRate int
+	Lossless   *bool
 }
 
+// LosslessFormats are the file formats that always hold lossless audio.
+// Tracks in other formats are lossless when the scanner marked them so,
+// like Apple Lossless in m4a; these still match tracks scanned before it did.
+var LosslessFormats = []string{"flac", "wav"}
+
 type TrackListOptions struct {
 	Filter TrackFilter
 	Page   int
 	Limit  int
 	SortBy string
 	Order  string
+	// Locale sorts names under its collation (see SortLocales)
+	Locale string
 }
 
 func (r *TrackRepository) Create(ctx context.Context, track *models.Track) error {
@@ -73,7 +92,8 @@ func (r *TrackRepository) FindByID(ctx context.Context, id string) (*models.Trac
 
 func (r *TrackRepository) FindByFilePath(ctx context.Context, filePath string) (*models.Track, error) {
 	var track models.Track
-	result := r.db.WithContext(ctx).First(&track, "file_path = ?", filePath)
+	stored, _ := models.TrackPathsOf(r.db).Stored(filePath)
+	result := r.db.WithContext(ctx).Order("start_offset ASC").First(&track, "file_path = ?", stored)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -84,47 +104,73 @@ func (r *TrackRepository) FindByFilePath(ctx context.Context, filePath string) (
 	return &track, nil
 }
 
-func (r *TrackRepository) List(ctx context.Context, opts TrackListOptions) ([]models.Track, int64, error) {
-	var tracks []models.Track
-	var total int64
-
-	query := r.db.WithContext(ctx).Model(&models.Track{})
+// FindByFileSegment finds the track starting at startOffset (ms) within a file
+func (r *TrackRepository) FindByFileSegment(ctx context.Context, filePath string, startOffset int) (*models.Track, error) {
+	var track models.Track
+	stored, _ := models.TrackPathsOf(r.db).Stored(filePath)
+	result := r.db.WithContext(ctx).First(&track, "file_path = ? AND start_offset = ?", stored, startOffset)
 
-	// Apply filters
-	if opts.Filter.AlbumID != "" {
-		query = query.Where("album_id = ?", opts.Filter.AlbumID)
-	}
-	if opts.Filter.ArtistID != "" {
-		query = query.Where("artist_id = ?", opts.Filter.ArtistID)
-	}
-	if opts.Filter.Genre != "" {
-		query = query.Where("genre = ?", opts.Filter.Genre)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrTrackNotFound
+		}
+		return nil, fmt.Errorf("finding track by file segment: %w", result.Error)
 	}
-	if opts.Filter.Year > 0 {
-		query = query.Where("year = ?", opts.Filter.Year)
+	return &track, nil
+}
+
+// DeleteFileSegmentsExcept removes tracks of a file whose start offset isn't
+// in keep, e.g. after a cue sheet changed or was removed
+func (r *TrackRepository) DeleteFileSegmentsExcept(ctx context.Context, filePath string, keep []int) (int64, error) {
+	stored, _ := models.TrackPathsOf(r.db).Stored(filePath)
+	query := r.db.WithContext(ctx).Where("file_path = ?", stored)
+	if len(keep) > 0 {
+		query = query.Where("start_offset NOT IN ?", keep)
 	}
-	if opts.Filter.Query != "" {
-		searchQuery := "%" + opts.Filter.Query + "%"
-		query = query.Where("title LIKE ?", searchQuery)
+
+	result := query.Delete(&models.Track{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("deleting stale file segments: %w", result.Error)
 	}
+	return result.RowsAffected, nil
+}
+
+func (r *TrackRepository) List(ctx context.Context, opts TrackListOptions) ([]models.Track, int64, error) {
+	var tracks []models.Track
+	var total int64
+
+	query := applyTrackFilter(r.db.WithContext(ctx).Model(&models.Track{}), opts.Filter)
 
 	// Count total
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("counting tracks: %w", err)
 	}
 
-	// Apply sorting - map frontend field names to database columns
+	// Apply sorting - map frontend field names to database columns. Without an
+	// explicit sort, album listings follow the album's track order, as the
+	// album itself lists them, and others title.
 	sortBy := "title"
+	if opts.SortBy == "" && opts.Filter.AlbumID != "" {
+		sortBy = "album_order"
+	}
 	if opts.SortBy != "" {
 		// Map common field names to actual column names
 		sortMapping := map[string]string{
-			"name":        "title",
-			"title":       "title",
-			"duration":    "duration",
-			"trackNumber": "track_number",
-			"year":        "year",
-			"createdAt":   "created_at",
-			"updatedAt":   "updated_at",
+			"name":           "title",
+			"title":          "title",
+			"duration":       "duration",
+			"trackNumber":    "track_number",
+			"albumOrder":     "album_order",
+			"year":           "year",
+			"rating":         "rating",
+			"bpm":            "bpm",
+			"bitrate":        "bitrate",
+			"sampleRate":     "sample_rate",
+			"format":         "format",
+			"createdAt":      "created_at",
+			"updatedAt":      "updated_at",
+			"addedAt":        "created_at",
+			"fileModifiedAt": "mod_time",
 		}
 		if mapped, ok := sortMapping[opts.SortBy]; ok {
 			sortBy = mapped
@@ -135,7 +181,17 @@ func (r *TrackRepository) List(ctx context.Context, opts TrackListOptions) ([]mo
 	if opts.Order == "desc" {
 		order = "DESC"
 	}
-	query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
+	if sortBy == "title" {
+		sortBy = collatedColumn(sortBy, opts.Locale)
+	}
+	switch {
+	case sortBy == "album_order" && order == "DESC":
+		query = query.Order(albumTrackOrderDesc)
+	case sortBy == "album_order":
+		query = query.Order(albumTrackOrder)
+	default:
+		query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
+	}
 
 	// Apply pagination
 	if opts.Limit > 0 {
@@ -147,21 +203,139 @@ func (r *TrackRepository) List(ctx context.Context, opts TrackListOptions) ([]mo
 	}
 
 	// Execute query with preloads
-	if err := query.Preload("Album").Preload("Artist").Find(&tracks).Error; err != nil {
+	if err := query.Scopes(withoutLyrics).Preload("Album").Preload("Artist").Find(&tracks).Error; err != nil {
 		return nil, 0, fmt.Errorf("listing tracks: %w", err)
 	}
 
 	return tracks, total, nil
 }
 
+// applyTrackFilter adds the WHERE clauses for a track filter
+func applyTrackFilter(query *gorm.DB, filter TrackFilter) *gorm.DB {
+	if filter.AlbumID != "" {
+		query = query.Where("album_id = ?", filter.AlbumID)
+	}
+	if filter.ArtistID != "" {
+		query = query.Where("artist_id = ?", filter.ArtistID)
+	}
+	if filter.Genre != "" {
+		// Inferred genres are only set on tracks without a tagged one
+		query = query.Where("genre = ? OR inferred_genre = ?", filter.Genre, filter.Genre)
+	}
+	if filter.Year > 0 {
+		query = query.Where("year = ?", filter.Year)
+	}
+	if filter.Query != "" {
+		query = query.Where(searchCondition(query, "title", filter.Query))
+	}
+	if filter.MinRating > 0 {
+		query = query.Where("rating >= ?", filter.MinRating)
+	}
+	if filter.MinBPM > 0 {
+		query = query.Where("bpm >= ?", filter.MinBPM)
+	}
+	if filter.MaxBPM > 0 {
+		query = query.Where("bpm > 0 AND bpm <= ?", filter.MaxBPM)
+	}
+	if filter.Key != "" {
+		query = query.Where("musical_key = ?", filter.Key)
+	}
+	if filter.Format != "" {
+		query = query.Where("format = ?", filter.Format)
+	}
+	if filter.MinBitrate > 0 {
+		query = query.Where("bitrate >= ?", filter.MinBitrate)
+	}
+	if filter.SampleRate > 0 {
+		query = query.Where("sample_rate = ?", filter.SampleRate)
+	}
+	if filter.Lossless != nil {
+		if *filter.Lossless {
+			query = query.Where("(lossless = ? OR format IN ?)", true, LosslessFormats)
+		} else {
+			query = query.Where("lossless = ? AND format NOT IN ?", false, LosslessFormats)
+		}
+	}
+	return query
+}
+
+// Shuffle returns up to limit tracks matching the filter in a random order that
+// is reproducible for a given seed. IDs are streamed through a reservoir so the
+// full matching set is never held in memory.
+func (r *TrackRepository) Shuffle(ctx context.Context, filter TrackFilter, limit int, seed int64) ([]models.Track, error) {
+	rows, err := applyTrackFilter(r.db.WithContext(ctx).Model(&models.Track{}), filter).
+		Select("id").
+		Order("id ASC").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("querying track ids: %w", err)
+	}
+	defer rows.Close()
+
+	rng := rand.New(rand.NewSource(seed))
+	reservoir := make([]string, 0, limit)
+	seen := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning track id: %w", err)
+		}
+		seen++
+		if len(reservoir) < limit {
+			reservoir = append(reservoir, id)
+		} else if j := rng.Intn(seen); j < limit {
+			reservoir[j] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading track ids: %w", err)
+	}
+
+	rng.Shuffle(len(reservoir), func(i, j int) {
+		reservoir[i], reservoir[j] = reservoir[j], reservoir[i]
+	})
+
+	return r.FindByIDs(ctx, reservoir)
+}
+
+// FindByIDs returns the tracks with the given IDs in the same order
+func (r *TrackRepository) FindByIDs(ctx context.Context, ids []string) ([]models.Track, error) {
+	if len(ids) == 0 {
+		return []models.Track{}, nil
+	}
+
+	var found []models.Track
+	err := r.db.WithContext(ctx).
+		Preload("Album").
+		Preload("Artist").
+		Where("id IN ?", ids).
+		Find(&found).Error
+	if err != nil {
+		return nil, fmt.Errorf("finding tracks by ids: %w", err)
+	}
+
+	byID := make(map[string]models.Track, len(found))
+	for _, track := range found {
+		byID[track.ID] = track
+	}
+
+	tracks := make([]models.Track, 0, len(ids))
+	for _, id := range ids {
+		if track, ok := byID[id]; ok {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks, nil
+}
+
 func (r *TrackRepository) Search(ctx context.Context, query string, limit int) ([]models.Track, error) {
 	var tracks []models.Track
-	searchQuery := "%" + query + "%"
 
 	err := r.db.WithContext(ctx).
+		Scopes(withoutLyrics).
 		Preload("Album").
 		Preload("Artist").
-		Where("title LIKE ?", searchQuery).
+		Where(searchCondition(r.db, "title", query)).
 		Limit(limit).
 		Find(&tracks).Error
 
@@ -178,6 +352,183 @@ func (r *TrackRepository) Update(ctx context.Context, track *models.Track) error
 	return nil
 }
 
+// SetRating sets a track's star rating (0 clears it)
+func (r *TrackRepository) SetRating(ctx context.Context, id string, rating int) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Where("id = ?", id).
+		Update("rating", rating)
+
+	if result.Error != nil {
+		return fmt.Errorf("setting track rating: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTrackNotFound
+	}
+	return nil
+}
+
+// withoutLyrics leaves lyrics out of queries listing tracks; they can be
+// long, and only the lyrics endpoint reads them
+func withoutLyrics(db *gorm.DB) *gorm.DB {
+	return db.Omit("lyrics")
+}
+
+// RecordPlay counts a play of a track by userID, either as a full listen or
+// a skip, and adds it to the play history. A play of the same track by the
+// same user within window either side of one already recorded is taken as a
+// resubmission and refused with ErrDuplicatePlay; a zero window counts every
+// play. The check and the insert are one statement, so concurrent
+// submissions of a play can't both get through.
+func (r *TrackRepository) RecordPlay(ctx context.Context, id, userID string, completed bool, playedAt time.Time, window time.Duration) error {
+	updates := map[string]interface{}{
+		"last_played_at": playedAt,
+	}
+	if completed {
+		updates["play_count"] = gorm.Expr("play_count + 1")
+	} else {
+		updates["skip_count"] = gorm.Expr("skip_count + 1")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Track{}).
+			Where("id = ?", id).
+			UpdateColumns(updates)
+
+		if result.Error != nil {
+			return fmt.Errorf("recording play: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrTrackNotFound
+		}
+
+		if window <= 0 {
+			play := &models.Play{
+				TrackID:   id,
+				UserID:    userID,
+				Completed: completed,
+				PlayedAt:  playedAt,
+			}
+			if err := tx.Create(play).Error; err != nil {
+				return fmt.Errorf("recording play history: %w", err)
+			}
+			return nil
+		}
+
+		// Returning an error rolls back the counts updated above
+		result = tx.Exec(`INSERT INTO plays (track_id, user_id, completed, played_at)
+			SELECT ?, ?, ?, ?
+			WHERE NOT EXISTS (
+				SELECT 1 FROM plays
+				WHERE track_id = ? AND user_id = ? AND played_at > ? AND played_at < ?
+			)`,
+			id, userID, completed, playedAt,
+			id, userID, playedAt.Add(-window), playedAt.Add(window))
+		if result.Error != nil {
+			return fmt.Errorf("recording play history: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDuplicatePlay
+		}
+		return nil
+	})
+}
+
+// ListTitles returns the ID and title of every track
+func (r *TrackRepository) ListTitles(ctx context.Context) ([]models.Track, error) {
+	var tracks []models.Track
+	err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Select("id, title").
+		Order("id").
+		Find(&tracks).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("listing track titles: %w", err)
+	}
+	return tracks, nil
+}
+
+// UpdateTitle sets the title of a track
+func (r *TrackRepository) UpdateTitle(ctx context.Context, id, title string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Where("id = ?", id).
+		Update("title", title).Error
+
+	if err != nil {
+		return fmt.Errorf("updating track title: %w", err)
+	}
+	return nil
+}
+
+// FoldIntoSingles moves every track of an album into a Singles album,
+// recording the album's title on them so they can be moved back out.
+// Returns the number of tracks moved.
+func (r *TrackRepository) FoldIntoSingles(ctx context.Context, albumID, title, singlesID string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Where("album_id = ?", albumID).
+		Updates(map[string]interface{}{"album_id": singlesID, "folded_album": title, "album_order": 0})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("folding tracks into singles: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// UnfoldSingles moves the tracks folded into a Singles album from the album
+// titled title into albumID. Returns the number of tracks moved.
+func (r *TrackRepository) UnfoldSingles(ctx context.Context, singlesID, title, albumID string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Where("album_id = ? AND folded_album = ?", singlesID, title).
+		Updates(map[string]interface{}{"album_id": albumID, "folded_album": "", "album_order": 0})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("moving tracks out of singles: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// FoldedGroup is the set of tracks folded into a Singles album from one
+// tagged album
+type FoldedGroup struct {
+	SinglesID  string
+	ArtistID   string
+	Title      string
+	TrackCount int
+	Year       int
+	FilePath   string // one of the tracks' files, for finding artwork
+	RootID     string
+}
+
+// GetFoldedGroups returns the tracks folded into Singles albums, grouped by
+// the album they were tagged with
+func (r *TrackRepository) GetFoldedGroups(ctx context.Context) ([]FoldedGroup, error) {
+	var groups []FoldedGroup
+	err := r.db.WithContext(ctx).
+		Table("tracks").
+		Select(`tracks.album_id AS singles_id, albums.artist_id AS artist_id, tracks.folded_album AS title,
+			COUNT(*) AS track_count, MAX(tracks.year) AS year, MIN(tracks.file_path) AS file_path,
+			tracks.root_id AS root_id`).
+		Joins("JOIN albums ON albums.id = tracks.album_id").
+		Where("tracks.folded_album <> ''").
+		Group("tracks.album_id, albums.artist_id, tracks.folded_album").
+		Scan(&groups).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("getting folded tracks: %w", err)
+	}
+
+	// SQLite takes root_id from the row MIN picked the path from
+	paths := models.TrackPathsOf(r.db)
+	for i := range groups {
+		groups[i].FilePath = paths.Resolve(groups[i].FilePath, groups[i].RootID)
+	}
+	return groups, nil
+}
+
 func (r *TrackRepository) Delete(ctx context.Context, id string) error {
 	result := r.db.WithContext(ctx).Delete(&models.Track{}, "id = ?", id)
 	if result.Error != nil {
@@ -190,16 +541,20 @@ func (r *TrackRepository) Delete(ctx context.Context, id string) error {
 }
 
 func (r *TrackRepository) DeleteByFilePath(ctx context.Context, filePath string) error {
-	result := r.db.WithContext(ctx).Delete(&models.Track{}, "file_path = ?", filePath)
+	stored, _ := models.TrackPathsOf(r.db).Stored(filePath)
+	result := r.db.WithContext(ctx).Delete(&models.Track{}, "file_path = ?", stored)
 	if result.Error != nil {
 		return fmt.Errorf("deleting track by path: %w", result.Error)
 	}
 	return nil
 }
 
-func (r *TrackRepository) GetRecentlyAdded(ctx context.Context, limit int) ([]models.Track, error) {
+// GetRecentlyAdded returns the most recently added tracks matching filter,
+// newest first
+func (r *TrackRepository) GetRecentlyAdded(ctx context.Context, limit int, filter AddedFilter) ([]models.Track, error) {
 	var tracks []models.Track
-	err := r.db.WithContext(ctx).
+	err := filter.apply(r.db.WithContext(ctx)).
+		Scopes(withoutLyrics).
 		Preload("Album").
 		Preload("Artist").
 		Order("created_at DESC").
@@ -212,9 +567,93 @@ func (r *TrackRepository) GetRecentlyAdded(ctx context.Context, limit int) ([]mo
 	return tracks, nil
 }
 
+// TrackAddedGroup is the set of tracks added within one period
+type TrackAddedGroup struct {
+	Start  time.Time
+	Tracks []models.Track
+}
+
+// GetRecentlyAddedGrouped returns the most recently added tracks matching
+// filter bucketed by the day or week they were added, newest period first
+func (r *TrackRepository) GetRecentlyAddedGrouped(ctx context.Context, limit int, grouping DateGrouping, filter AddedFilter) ([]TrackAddedGroup, error) {
+	tracks, err := r.GetRecentlyAdded(ctx, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []TrackAddedGroup
+	for _, track := range tracks {
+		start := grouping.PeriodStart(track.CreatedAt)
+		if len(groups) == 0 || !groups[len(groups)-1].Start.Equal(start) {
+			groups = append(groups, TrackAddedGroup{Start: start})
+		}
+		last := &groups[len(groups)-1]
+		last.Tracks = append(last.Tracks, track)
+	}
+	return groups, nil
+}
+
+// GetRecentlyPlayed returns a user's most recent plays of tracks matching
+// filter, newest first, with their tracks. The filter's time range bounds
+// when the tracks were played rather than added.
+func (r *TrackRepository) GetRecentlyPlayed(ctx context.Context, userID string, limit int, filter AddedFilter) ([]models.Play, error) {
+	query := r.db.WithContext(ctx).
+		Joins("Track", r.db.Omit("lyrics")).
+		Where("plays.user_id = ?", userID)
+	if filter.ArtistID != "" {
+		query = query.Where("Track.artist_id = ?", filter.ArtistID)
+	}
+	if filter.AlbumID != "" {
+		query = query.Where("Track.album_id = ?", filter.AlbumID)
+	}
+	if !filter.After.IsZero() {
+		query = query.Where("plays.played_at >= ?", filter.After.Local())
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("plays.played_at < ?", filter.Before.Local())
+	}
+
+	var plays []models.Play
+	err := query.
+		Order("plays.played_at DESC, plays.id DESC").
+		Limit(limit).
+		Find(&plays).Error
+	if err != nil {
+		return nil, fmt.Errorf("getting recent plays: %w", err)
+	}
+	return plays, nil
+}
+
+// PlayedGroup is the set of plays within one period
+type PlayedGroup struct {
+	Start time.Time
+	Plays []models.Play
+}
+
+// GetRecentlyPlayedGrouped returns a user's most recent plays matching
+// filter bucketed by the day or week they were played, newest period first
+func (r *TrackRepository) GetRecentlyPlayedGrouped(ctx context.Context, userID string, limit int, grouping DateGrouping, filter AddedFilter) ([]PlayedGroup, error) {
+	plays, err := r.GetRecentlyPlayed(ctx, userID, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []PlayedGroup
+	for _, play := range plays {
+		start := grouping.PeriodStart(play.PlayedAt)
+		if len(groups) == 0 || !groups[len(groups)-1].Start.Equal(start) {
+			groups = append(groups, PlayedGroup{Start: start})
+		}
+		last := &groups[len(groups)-1]
+		last.Plays = append(last.Plays, play)
+	}
+	return groups, nil
+}
+
 func (r *TrackRepository) GetRandom(ctx context.Context, limit int) ([]models.Track, error) {
 	var tracks []models.Track
 	err := r.db.WithContext(ctx).
+		Scopes(withoutLyrics).
 		Preload("Album").
 		Preload("Artist").
 		Order("RANDOM()").
@@ -235,14 +674,222 @@ func (r *TrackRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// SumDuration returns the total duration of all tracks in seconds
+func (r *TrackRepository) SumDuration(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Track{}).Select("COALESCE(SUM(duration), 0)").Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("summing track durations: %w", err)
+	}
+	return total, nil
+}
+
+// SumSize returns the total size of all track files in bytes
+func (r *TrackRepository) SumSize(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Track{}).Select("COALESCE(SUM(file_size), 0)").Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("summing track sizes: %w", err)
+	}
+	return total, nil
+}
+
 func (r *TrackRepository) GetAllFilePaths(ctx context.Context) ([]string, error) {
-	var paths []string
+	var rows []trackPathRow
 	err := r.db.WithContext(ctx).
 		Model(&models.Track{}).
-		Pluck("file_path", &paths).Error
+		Select("id, file_path, root_id").
+		Scan(&rows).Error
 
 	if err != nil {
 		return nil, fmt.Errorf("getting file paths: %w", err)
 	}
+
+	trackPaths := models.TrackPathsOf(r.db)
+	paths := make([]string, len(rows))
+	for i, row := range rows {
+		paths[i] = trackPaths.Resolve(row.FilePath, row.RootID)
+	}
 	return paths, nil
 }
+
+// GetFileHashes returns the distinct content hashes stored for tracks
+func (r *TrackRepository) GetFileHashes(ctx context.Context) ([]string, error) {
+	var hashes []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Where("file_hash <> ''").
+		Distinct().
+		Pluck("file_hash", &hashes).Error
+	if err != nil {
+		return nil, fmt.Errorf("getting file hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// GetAllFilePathsWithModTime returns the modification time each track's file
+// had when it was last scanned, keyed by path. Files split into several
+// tracks report the oldest, and tracks scanned before mod times were stored
+// report the zero time.
+func (r *TrackRepository) GetAllFilePathsWithModTime(ctx context.Context) (map[string]time.Time, error) {
+	var rows []struct {
+		FilePath string
+		RootID   string
+		ModTime  *time.Time
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Select("file_path, root_id, mod_time").
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("getting file mod times: %w", err)
+	}
+
+	trackPaths := models.TrackPathsOf(r.db)
+	modTimes := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		path := trackPaths.Resolve(row.FilePath, row.RootID)
+		var modTime time.Time
+		if row.ModTime != nil {
+			modTime = *row.ModTime
+		}
+		if known, ok := modTimes[path]; !ok || modTime.Before(known) {
+			modTimes[path] = modTime
+		}
+	}
+	return modTimes, nil
+}
+
+// trackPathRow is the subset of track columns needed to rewrite paths
+type trackPathRow struct {
+	ID       string
+	FilePath string
+	RootID   string
+}
+
+// MigratePaths rewrites stored file paths to match the configured path mode,
+// converting absolute paths to root-relative ones or back. Returns the number
+// of tracks updated.
+func (r *TrackRepository) MigratePaths(ctx context.Context) (int, error) {
+	var rows []trackPathRow
+	err := r.db.WithContext(ctx).
+		Model(&models.Track{}).
+		Select("id, file_path, root_id").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, fmt.Errorf("loading track paths: %w", err)
+	}
+
+	paths := models.TrackPathsOf(r.db)
+	updated := 0
+	for _, row := range rows {
+		stored, rootID := paths.Stored(paths.Resolve(row.FilePath, row.RootID))
+		if stored == row.FilePath && rootID == row.RootID {
+			continue
+		}
+
+		err := r.db.WithContext(ctx).
+			Model(&models.Track{}).
+			Where("id = ?", row.ID).
+			UpdateColumns(map[string]interface{}{"file_path": stored, "root_id": rootID}).Error
+		if err != nil {
+			return updated, fmt.Errorf("migrating path for track %s: %w", row.ID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// Conditions matching tracks whose album or artist row no longer exists
+const (
+	trackMissingAlbum  = "album_id IS NOT NULL AND album_id <> '' AND album_id NOT IN (SELECT id FROM albums)"
+	trackMissingArtist = "artist_id IS NOT NULL AND artist_id <> '' AND artist_id NOT IN (SELECT id FROM artists)"
+)
+
+// TrackOrphans counts tracks that reference deleted albums or artists
+type TrackOrphans struct {
+	MissingAlbum  int64
+	MissingArtist int64
+}
+
+// CountOrphaned counts tracks that reference an album or artist that no
+// longer exists
+func (r *TrackRepository) CountOrphaned(ctx context.Context) (TrackOrphans, error) {
+	var orphans TrackOrphans
+	db := r.db.WithContext(ctx)
+
+	if err := db.Model(&models.Track{}).Where(trackMissingAlbum).Count(&orphans.MissingAlbum).Error; err != nil {
+		return orphans, fmt.Errorf("counting tracks with missing album: %w", err)
+	}
+	if err := db.Model(&models.Track{}).Where(trackMissingArtist).Count(&orphans.MissingArtist).Error; err != nil {
+		return orphans, fmt.Errorf("counting tracks with missing artist: %w", err)
+	}
+	return orphans, nil
+}
+
+// FixOrphaned repairs tracks that reference deleted rows. A missing artist is
+// replaced with the album's artist when that still exists; otherwise the
+// reference is cleared. Tracks with a missing album are detached from it.
+func (r *TrackRepository) FixOrphaned(ctx context.Context) (TrackOrphans, error) {
+	var fixed TrackOrphans
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Track{}).
+			Where(trackMissingArtist).
+			UpdateColumn("artist_id", gorm.Expr(`(
+				SELECT albums.artist_id FROM albums
+				JOIN artists ON artists.id = albums.artist_id
+				WHERE albums.id = tracks.album_id
+			)`))
+		if result.Error != nil {
+			return fmt.Errorf("fixing tracks with missing artist: %w", result.Error)
+		}
+		fixed.MissingArtist = result.RowsAffected
+
+		result = tx.Model(&models.Track{}).
+			Where(trackMissingAlbum).
+			UpdateColumn("album_id", nil)
+		if result.Error != nil {
+			return fmt.Errorf("fixing tracks with missing album: %w", result.Error)
+		}
+		fixed.MissingAlbum = result.RowsAffected
+		return nil
+	})
+	return fixed, err
+}
+
+// GetChapters returns a track's chapter markers in order
+func (r *TrackRepository) GetChapters(ctx context.Context, trackID string) ([]models.Chapter, error) {
+	var chapters []models.Chapter
+	err := r.db.WithContext(ctx).
+		Where("track_id = ?", trackID).
+		Order("position ASC").
+		Find(&chapters).Error
+	if err != nil {
+		return nil, fmt.Errorf("getting chapters: %w", err)
+	}
+	return chapters, nil
+}
+
+// ReplaceChapters swaps a track's chapter markers for the given ones,
+// numbering them in order
+func (r *TrackRepository) ReplaceChapters(ctx context.Context, trackID string, chapters []models.Chapter) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("track_id = ?", trackID).Delete(&models.Chapter{}).Error; err != nil {
+			return fmt.Errorf("clearing chapters: %w", err)
+		}
+		if len(chapters) == 0 {
+			return nil
+		}
+
+		for i := range chapters {
+			chapters[i].ID = 0
+			chapters[i].TrackID = trackID
+			chapters[i].Position = i + 1
+		}
+		if err := tx.Create(&chapters).Error; err != nil {
+			return fmt.Errorf("saving chapters: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"harmony/internal/models"
+)
+
+func TestRecordPlay(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	repo := NewTrackRepository(db.DB)
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Steps run in order against the same history
+	tests := []struct {
+		name      string
+		track     string
+		user      string
+		completed bool
+		at        time.Duration // after start
+		window    time.Duration
+		wantErr   error
+	}{
+		{"first play", "t1", "u1", true, 0, time.Minute, nil},
+		{"resubmitted", "t1", "u1", true, 30 * time.Second, time.Minute, ErrDuplicatePlay},
+		{"resubmitted earlier", "t1", "u1", true, -30 * time.Second, time.Minute, ErrDuplicatePlay},
+		{"skip resubmitted", "t1", "u1", false, 10 * time.Second, time.Minute, ErrDuplicatePlay},
+		{"other user", "t1", "u2", true, 30 * time.Second, time.Minute, nil},
+		{"other track", "t2", "u1", true, 30 * time.Second, time.Minute, nil},
+		{"after the window", "t1", "u1", true, 2 * time.Minute, time.Minute, nil},
+		{"no window", "t1", "u1", false, 2 * time.Minute, 0, nil},
+		{"missing track", "none", "u1", true, 0, time.Minute, ErrTrackNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.RecordPlay(ctx, tt.track, tt.user, tt.completed, start.Add(tt.at), tt.window)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordPlay error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Refused plays leave the counts alone
+	var counts struct{ PlayCount, SkipCount int }
+	db.DB.Raw("SELECT play_count, skip_count FROM tracks WHERE id = 't1'").Scan(&counts)
+	if counts.PlayCount != 3 || counts.SkipCount != 1 {
+		t.Errorf("t1 has %d plays and %d skips, want 3 and 1", counts.PlayCount, counts.SkipCount)
+	}
+	var plays int64
+	db.DB.Raw("SELECT COUNT(*) FROM plays").Scan(&plays)
+	if plays != 5 {
+		t.Errorf("%d plays in the history, want 5", plays)
+	}
+}
+
+func TestRecordPlayConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	repo := NewTrackRepository(db.DB)
+	playedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// The same play submitted several times at once is recorded once
+	const submissions = 8
+	errs := make([]error, submissions)
+	var wg sync.WaitGroup
+	for i := 0; i < submissions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.RecordPlay(context.Background(), "t1", "u1", true, playedAt, time.Minute)
+		}(i)
+	}
+	wg.Wait()
+
+	recorded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			recorded++
+		case !errors.Is(err, ErrDuplicatePlay):
+			t.Errorf("RecordPlay: %v", err)
+		}
+	}
+	if recorded != 1 {
+		t.Errorf("%d submissions recorded, want 1", recorded)
+	}
+	var playCount int
+	db.DB.Raw("SELECT play_count FROM tracks WHERE id = 't1'").Scan(&playCount)
+	if playCount != 1 {
+		t.Errorf("play_count = %d, want 1", playCount)
+	}
+}
+
+func TestListsLeaveOutLyrics(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db, `UPDATE tracks SET lyrics = 'la la la'`)
+	tracks := NewTrackRepository(db.DB)
+	albums := NewAlbumRepository(db.DB)
+	ctx := context.Background()
+
+	listed, _, err := tracks.List(ctx, TrackListOptions{Filter: TrackFilter{Genre: "Rock"}, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	recent, err := tracks.GetRecentlyAdded(ctx, 10, AddedFilter{})
+	if err != nil {
+		t.Fatalf("GetRecentlyAdded: %v", err)
+	}
+	album, err := albums.FindByIDWithTracks(ctx, "al1")
+	if err != nil {
+		t.Fatalf("FindByIDWithTracks: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tracks []models.Track
+		want   int
+	}{
+		{"list", listed, 2},
+		{"recently added", recent, 4},
+		{"album", album.Tracks, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.tracks) != tt.want {
+				t.Fatalf("%d tracks, want %d", len(tt.tracks), tt.want)
+			}
+			for _, track := range tt.tracks {
+				if track.Lyrics != "" || track.Title == "" || track.FilePath == "" {
+					t.Errorf("track %s loaded as %+v", track.ID, track)
+				}
+			}
+		})
+	}
+
+	// The track itself still has them
+	track, err := tracks.FindByID(ctx, "t1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if track.Lyrics != "la la la" {
+		t.Errorf("track lyrics = %q", track.Lyrics)
+	}
+}
+
+func TestLosslessFilter(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`UPDATE tracks SET format = 'flac' WHERE id = 't1'`,
+		`UPDATE tracks SET format = 'm4a', lossless = true WHERE id = 't2'`,
+		`UPDATE tracks SET format = 'm4a' WHERE id = 't3'`,
+	)
+	repo := NewTrackRepository(db.DB)
+
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		filter TrackFilter
+		want   []string
+	}{
+		{"lossless", TrackFilter{Lossless: &yes}, []string{"t1", "t2"}},
+		{"lossy", TrackFilter{Lossless: &no}, []string{"t3", "t4"}},
+		{"lossless m4a", TrackFilter{Format: "m4a", Lossless: &yes}, []string{"t2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, _, err := repo.List(context.Background(), TrackListOptions{Filter: tt.filter, Page: 1, Limit: 10})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var ids []string
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("tracks = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestListAlbumTrackOrder(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`INSERT INTO tracks (id, title, duration, track_number, disc_number, file_path, file_size,
+			format, album_id, artist_id, created_at, updated_at) VALUES
+			('t5', 'Encore', 200, 1, 2, '/a/5.mp3', 100, 'mp3', 'al1', 'ar1', datetime('now'), datetime('now'))`,
+		`UPDATE tracks SET album_order = 1 WHERE id = 't2'`,
+	)
+	repo := NewTrackRepository(db.DB)
+
+	tests := []struct {
+		name   string
+		sortBy string
+		order  string
+		want   []string
+	}{
+		{"default", "", "", []string{"t2", "t1", "t5"}},
+		{"default reversed", "", "desc", []string{"t5", "t1", "t2"}},
+		{"explicit album order", "albumOrder", "", []string{"t2", "t1", "t5"}},
+		{"explicit sort", "title", "", []string{"t5", "t1", "t2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, _, err := repo.List(context.Background(), TrackListOptions{
+				Filter: TrackFilter{AlbumID: "al1"},
+				SortBy: tt.sortBy,
+				Order:  tt.order,
+			})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var ids []string
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("tracks = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	// The album lists its tracks the same way
+	album, err := NewAlbumRepository(db.DB).FindByIDWithTracks(context.Background(), "al1")
+	if err != nil {
+		t.Fatalf("FindByIDWithTracks: %v", err)
+	}
+	var ids []string
+	for _, track := range album.Tracks {
+		ids = append(ids, track.ID)
+	}
+	if !slices.Equal(ids, tests[0].want) {
+		t.Errorf("album tracks = %v, want %v", ids, tests[0].want)
+	}
+}
+
+func TestGetRecentlyPlayedLeavesOutLyrics(t *testing.T) {
+	db := newTestDB(t)
+	seedLibrary(t, db)
+	execSQL(t, db,
+		`UPDATE tracks SET lyrics = 'la la la'`,
+		`INSERT INTO plays (track_id, user_id, completed, played_at) VALUES ('t1', 'u1', true, datetime('now'))`,
+	)
+
+	plays, err := NewTrackRepository(db.DB).GetRecentlyPlayed(context.Background(), "u1", 10, AddedFilter{})
+	if err != nil {
+		t.Fatalf("GetRecentlyPlayed: %v", err)
+	}
+	if len(plays) != 1 || plays[0].Track == nil {
+		t.Fatalf("plays = %+v, want one with its track", plays)
+	}
+	if track := plays[0].Track; track.Lyrics != "" || track.Title != "One" {
+		t.Errorf("track loaded as %+v", track)
+	}
+}
+
+func TestSumDurationAndSize(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTrackRepository(db.DB)
+	ctx := context.Background()
+
+	check := func(step string, wantDuration, wantSize int64) {
+		t.Helper()
+		duration, err := repo.SumDuration(ctx)
+		if err != nil {
+			t.Fatalf("%s: SumDuration: %v", step, err)
+		}
+		size, err := repo.SumSize(ctx)
+		if err != nil {
+			t.Fatalf("%s: SumSize: %v", step, err)
+		}
+		if duration != wantDuration || size != wantSize {
+			t.Errorf("%s: duration %d, size %d; want %d, %d", step, duration, size, wantDuration, wantSize)
+		}
+	}
+
+	check("empty", 0, 0)
+	seedLibrary(t, db)
+	check("seeded", 830, 400)
+	execSQL(t, db, `UPDATE tracks SET file_size = 5000000000 WHERE id = 't1'`)
+	check("large file", 830, 5000000300)
+}
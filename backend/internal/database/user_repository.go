@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/gorm"
 
 	"harmony/internal/models"
@@ -23,11 +24,26 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// Create adds a user, making them an administrator when they are the first
+// one. A username or email already taken returns ErrUserAlreadyExists.
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	if user.ID == "" {
 		user.ID = GenerateID()
 	}
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+	// Counting and inserting in one transaction means two sign-ups racing
+	// for the first account can't both become administrators
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		user.IsAdmin = user.IsAdmin || count == 0
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrUserAlreadyExists
+		}
 		return fmt.Errorf("creating user: %w", err)
 	}
 	return nil
@@ -123,3 +139,35 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	}
 	return count > 0, nil
 }
+
+// isUniqueViolation reports whether err is SQLite rejecting a duplicate in a
+// unique column
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// migrateAdmin makes the oldest user an administrator when there is none,
+// as in databases created before users had the flag
+func (d *Database) migrateAdmin() error {
+	err := d.DB.Exec(`UPDATE users SET is_admin = true
+		WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1)
+		AND NOT EXISTS (SELECT 1 FROM users WHERE is_admin)`).Error
+	if err != nil {
+		return fmt.Errorf("promoting first user to admin: %w", err)
+	}
+	return nil
+}
+
+// migratePlaylistOwners hands playlists saved under the default user, as
+// every playlist was before there were accounts, to the first administrator.
+// They stay where they are until someone has registered.
+func (d *Database) migratePlaylistOwners() error {
+	err := d.DB.Exec(`UPDATE playlists
+		SET user_id = (SELECT id FROM users WHERE is_admin ORDER BY created_at LIMIT 1)
+		WHERE user_id = 'default-user' AND EXISTS (SELECT 1 FROM users WHERE is_admin)`).Error
+	if err != nil {
+		return fmt.Errorf("assigning default user playlists: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/scanner"
+	"harmony/internal/services"
+	"harmony/internal/transcoder"
+)
+
+const backupPrefix = "harmony-backup-"
+
+// AdminHandler handles administrative endpoints
+type AdminHandler struct {
+	trackRepo    *database.TrackRepository
+	albumRepo    *database.AlbumRepository
+	playlistRepo *database.PlaylistRepository
+	db           *database.Database
+	service      *services.LibraryService
+	settings     *database.SettingsRepository
+	transcoder   *transcoder.Transcoder
+	backupDir    string
+	timeFormat   TimeFormat
+	// profilesMu serializes updates to the stored transcode profiles
+	profilesMu sync.Mutex
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(
+	trackRepo *database.TrackRepository,
+	albumRepo *database.AlbumRepository,
+	playlistRepo *database.PlaylistRepository,
+	db *database.Database,
+	service *services.LibraryService,
+	settings *database.SettingsRepository,
+	trans *transcoder.Transcoder,
+	backupDir string,
+	timeFormat TimeFormat,
+) *AdminHandler {
+	return &AdminHandler{
+		trackRepo:    trackRepo,
+		albumRepo:    albumRepo,
+		playlistRepo: playlistRepo,
+		db:           db,
+		service:      service,
+		settings:     settings,
+		transcoder:   trans,
+		backupDir:    backupDir,
+		timeFormat:   timeFormat,
+	}
+}
+
+// BackupInfo describes a database backup file
+type BackupInfo struct {
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Backup handles POST /api/v1/admin/backup
+func (h *AdminHandler) Backup(c *gin.Context) {
+	if err := os.MkdirAll(h.backupDir, 0755); err != nil {
+		InternalError(c, "failed to create backup directory")
+		return
+	}
+
+	now := time.Now().UTC()
+	filename := backupPrefix + now.Format("20060102T150405Z") + ".db"
+	path := filepath.Join(h.backupDir, filename)
+
+	if err := h.db.Backup(c.Request.Context(), path); err != nil {
+		InternalError(c, "failed to back up database")
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		InternalError(c, "failed to read backup")
+		return
+	}
+
+	Created(c, BackupInfo{
+		Filename:  filename,
+		Size:      info.Size(),
+		CreatedAt: h.timeFormat.Format(info.ModTime()),
+	})
+}
+
+// DownloadBackup handles GET /api/v1/admin/backup/download
+// Serves the most recent backup.
+func (h *AdminHandler) DownloadBackup(c *gin.Context) {
+	matches, err := filepath.Glob(filepath.Join(h.backupDir, backupPrefix+"*.db"))
+	if err != nil || len(matches) == 0 {
+		NotFound(c, "backup")
+		return
+	}
+
+	// Timestamped names sort chronologically
+	sort.Strings(matches)
+	latest := matches[len(matches)-1]
+
+	c.Header("Content-Disposition", `attachment; filename="`+filepath.Base(latest)+`"`)
+	c.File(latest)
+}
+
+// ReprocessArtwork handles POST /api/v1/admin/artwork/reprocess
+// Regenerates cached artwork for every album in the background.
+func (h *AdminHandler) ReprocessArtwork(c *gin.Context) {
+	if err := h.service.StartArtworkReprocess(); err != nil {
+		if errors.Is(err, services.ErrArtworkJobInProgress) {
+			Conflict(c, "artwork reprocessing already in progress")
+			return
+		}
+		InternalError(c, "failed to start artwork reprocessing")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "artwork reprocessing started",
+	})
+}
+
+// ArtworkStatus handles GET /api/v1/admin/artwork/status
+func (h *AdminHandler) ArtworkStatus(c *gin.Context) {
+	progress := h.service.GetArtworkProgress()
+
+	Success(c, gin.H{
+		"status":          progress.Status,
+		"totalAlbums":     progress.TotalAlbums,
+		"processedAlbums": progress.ProcessedAlbums,
+		"updatedAlbums":   progress.UpdatedAlbums,
+		"missingArtwork":  progress.MissingArtwork,
+		"errorCount":      progress.ErrorCount,
+		"currentAlbum":    progress.CurrentAlbum,
+		"startedAt":       h.timeFormat.Format(progress.StartedAt),
+		"completedAt":     h.timeFormat.Format(progress.CompletedAt),
+	})
+}
+
+// CancelArtwork handles POST /api/v1/admin/artwork/cancel
+func (h *AdminHandler) CancelArtwork(c *gin.Context) {
+	if err := h.service.CancelArtworkReprocess(); err != nil {
+		if errors.Is(err, services.ErrArtworkJobNotRunning) {
+			BadRequest(c, "no artwork reprocessing is running")
+			return
+		}
+		InternalError(c, "failed to cancel artwork reprocessing")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "artwork reprocessing cancellation requested",
+	})
+}
+
+// IntegrityReport counts rows that reference deleted records. The fix
+// endpoint returns the same shape with the number of rows repaired.
+type IntegrityReport struct {
+	TracksMissingAlbum     int64 `json:"tracksMissingAlbum"`
+	TracksMissingArtist    int64 `json:"tracksMissingArtist"`
+	AlbumsMissingArtist    int64 `json:"albumsMissingArtist"`
+	OrphanedPlaylistTracks int64 `json:"orphanedPlaylistTracks"`
+	Total                  int64 `json:"total"`
+}
+
+// Integrity handles GET /api/v1/admin/integrity
+func (h *AdminHandler) Integrity(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tracks, err := h.trackRepo.CountOrphaned(ctx)
+	if err != nil {
+		InternalError(c, "failed to check tracks")
+		return
+	}
+	albums, err := h.albumRepo.CountMissingArtist(ctx)
+	if err != nil {
+		InternalError(c, "failed to check albums")
+		return
+	}
+	playlistTracks, err := h.playlistRepo.CountOrphanedTracks(ctx)
+	if err != nil {
+		InternalError(c, "failed to check playlists")
+		return
+	}
+
+	Success(c, newIntegrityReport(tracks, albums, playlistTracks))
+}
+
+// FixIntegrity handles POST /api/v1/admin/integrity/fix
+// Tracks and albums pointing at deleted rows are reassigned or detached, and
+// playlist entries for deleted tracks are removed.
+func (h *AdminHandler) FixIntegrity(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Albums first, so tracks can inherit a reassigned album artist
+	albums, err := h.albumRepo.FixMissingArtist(ctx)
+	if err != nil {
+		InternalError(c, "failed to fix albums")
+		return
+	}
+	tracks, err := h.trackRepo.FixOrphaned(ctx)
+	if err != nil {
+		InternalError(c, "failed to fix tracks")
+		return
+	}
+	playlistTracks, err := h.playlistRepo.DeleteOrphanedTracks(ctx)
+	if err != nil {
+		InternalError(c, "failed to fix playlists")
+		return
+	}
+
+	report := newIntegrityReport(tracks, albums, playlistTracks)
+	if report.Total > 0 {
+		slog.Info("repaired orphaned rows",
+			"tracksMissingAlbum", report.TracksMissingAlbum,
+			"tracksMissingArtist", report.TracksMissingArtist,
+			"albumsMissingArtist", report.AlbumsMissingArtist,
+			"orphanedPlaylistTracks", report.OrphanedPlaylistTracks,
+		)
+	}
+
+	Success(c, report)
+}
+
+// newIntegrityReport assembles an IntegrityReport from per-table counts
+func newIntegrityReport(tracks database.TrackOrphans, albums, playlistTracks int64) IntegrityReport {
+	return IntegrityReport{
+		TracksMissingAlbum:     tracks.MissingAlbum,
+		TracksMissingArtist:    tracks.MissingArtist,
+		AlbumsMissingArtist:    albums,
+		OrphanedPlaylistTracks: playlistTracks,
+		Total:                  tracks.MissingAlbum + tracks.MissingArtist + albums + playlistTracks,
+	}
+}
+
+// NormalizeTagsRequest is the request body for POST /api/v1/admin/normalize-tags
+type NormalizeTagsRequest struct {
+	// DryRun reports the changes without writing them; defaults to true
+	DryRun *bool    `json:"dryRun"`
+	Rules  []string `json:"rules"`
+}
+
+// NormalizeTags handles POST /api/v1/admin/normalize-tags
+// Starts cleaning up existing track titles, album titles and artist names.
+// Without a rules list the rules configured for scans are applied. Progress
+// is reported by GET /api/v1/admin/normalize-tags/status.
+func (h *AdminHandler) NormalizeTags(c *gin.Context) {
+	var req NormalizeTagsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "invalid request body")
+			return
+		}
+	}
+
+	rules, err := scanner.ParseNormalizeRuleList(req.Rules)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+
+	if err := h.service.StartTagNormalize(rules, dryRun); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoNormalizeRules):
+			BadRequest(c, "no rules given and TAG_NORMALIZE_RULES is not set")
+		case errors.Is(err, services.ErrScanInProgress):
+			Conflict(c, "a scan is in progress")
+		case errors.Is(err, services.ErrTagNormalizeInProgress):
+			Conflict(c, "tag normalization already in progress")
+		default:
+			InternalError(c, "failed to start tag normalization")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "tag normalization started",
+	})
+}
+
+// NormalizeTagsStatus handles GET /api/v1/admin/normalize-tags/status
+func (h *AdminHandler) NormalizeTagsStatus(c *gin.Context) {
+	job := h.service.GetTagNormalizeJob()
+
+	Success(c, gin.H{
+		"status":      job.Status,
+		"result":      job.Result,
+		"error":       job.Error,
+		"startedAt":   h.timeFormat.Format(job.StartedAt),
+		"completedAt": h.timeFormat.Format(job.CompletedAt),
+	})
+}
+
+// MissingArtwork handles GET /api/v1/admin/missing-artwork
+// Lists albums without cached covers and artists without images, albums
+// first. type=album or type=artist limits the list to one kind; the counts
+// always cover both.
+func (h *AdminHandler) MissingArtwork(c *gin.Context) {
+	pagination := ParsePagination(c)
+
+	albums, artists, err := h.service.MissingArtwork(c.Request.Context())
+	if err != nil {
+		InternalError(c, "failed to check artwork")
+		return
+	}
+
+	var items []services.MissingArtworkItem
+	switch c.Query("type") {
+	case "":
+		items = append(albums, artists...)
+	case "album":
+		items = albums
+	case "artist":
+		items = artists
+	default:
+		BadRequest(c, "type must be album or artist")
+		return
+	}
+
+	start := min((pagination.Page-1)*pagination.Limit, len(items))
+	end := min(start+pagination.Limit, len(items))
+
+	SuccessWithPagination(c, gin.H{
+		"missingAlbums":  len(albums),
+		"missingArtists": len(artists),
+		"items":          items[start:end],
+	}, NewPagination(pagination.Page, pagination.Limit, int64(len(items))))
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"harmony/internal/database"
+	"harmony/internal/scanner"
+	"harmony/internal/services"
+)
+
+// testAdminToken opens the admin routes in tests without authentication
+const testAdminToken = "fedcba9876543210fedcba9876543210"
+
+// withAdminToken sets the admin token
+func withAdminToken(cfg *RouterConfig) {
+	cfg.AdminToken = testAdminToken
+}
+
+func TestAdminToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*RouterConfig)
+		headers   []string
+		want      int
+	}{
+		{"no admin token configured", nil, bearer(testAdminToken), http.StatusForbidden},
+		{"no token sent", withAdminToken, nil, http.StatusUnauthorized},
+		{"wrong token", withAdminToken, bearer("wrong"), http.StatusUnauthorized},
+		{"wrong scheme", withAdminToken, []string{"Authorization", "Basic " + testAdminToken}, http.StatusUnauthorized},
+		{"query token", withAdminToken, nil, http.StatusUnauthorized},
+		{"admin token", withAdminToken, bearer(testAdminToken), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.configure)
+			path := "/api/v1/admin/integrity"
+			if tt.name == "query token" {
+				path += "?token=" + testAdminToken
+			}
+			rec := env.do(http.MethodGet, path, nil, tt.headers...)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
+
+func TestBackupEndpoints(t *testing.T) {
+	env := newTestEnv(t, withAdminToken)
+	env.seedLibrary()
+	auth := bearer(testAdminToken)
+
+	rec := env.do(http.MethodGet, "/api/v1/admin/backup/download", nil, auth...)
+	expectStatus(t, rec, http.StatusNotFound)
+
+	rec = env.do(http.MethodPost, "/api/v1/admin/backup", nil, auth...)
+	expectStatus(t, rec, http.StatusCreated)
+	var info BackupInfo
+	decodeData(t, rec, &info)
+	if info.Size == 0 {
+		t.Errorf("backup size = 0")
+	}
+
+	rec = env.do(http.MethodGet, "/api/v1/admin/backup/download", nil, auth...)
+	expectStatus(t, rec, http.StatusOK)
+
+	// The download opens as a database holding the library
+	path := filepath.Join(t.TempDir(), info.Filename)
+	if err := os.WriteFile(path, rec.Body.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := database.New(database.Config{Path: path, MaxOpenConn: 1, MaxIdleConn: 1})
+	if err != nil {
+		t.Fatalf("opening backup: %v", err)
+	}
+	defer backup.Close()
+	var tracks int64
+	if err := backup.DB.Raw("SELECT COUNT(*) FROM tracks").Scan(&tracks).Error; err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if tracks != 4 {
+		t.Errorf("backup has %d tracks, want 4", tracks)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	env := newTestEnv(t, withAdminToken)
+	env.seedLibrary()
+	env.exec(
+		`UPDATE tracks SET title = '  Opener  ', updated_at = '2000-01-01 00:00:00' WHERE id = 't1'`,
+		`UPDATE albums SET title = 'first', updated_at = '2000-01-01 00:00:00' WHERE id = 'al1'`,
+	)
+	auth := bearer(testAdminToken)
+
+	// waitDone polls the status until the run ends
+	waitDone := func() (status services.ScanStatus, result services.TagNormalizeResult) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			rec := env.do(http.MethodGet, "/api/v1/admin/normalize-tags/status", nil, auth...)
+			expectStatus(t, rec, http.StatusOK)
+			var job struct {
+				Status services.ScanStatus          `json:"status"`
+				Result *services.TagNormalizeResult `json:"result"`
+			}
+			decodeData(t, rec, &job)
+			if job.Status != services.ScanStatusProcessing {
+				if job.Result != nil {
+					result = *job.Result
+				}
+				return job.Status, result
+			}
+		}
+		t.Fatal("tag normalization did not finish")
+		return
+	}
+
+	status, _ := waitDone()
+	if status != services.ScanStatusIdle {
+		t.Errorf("status before any run = %q, want idle", status)
+	}
+
+	// Nothing given and nothing configured
+	rec := env.do(http.MethodPost, "/api/v1/admin/normalize-tags", nil, auth...)
+	expectStatus(t, rec, http.StatusBadRequest)
+	rec = env.do(http.MethodPost, "/api/v1/admin/normalize-tags", map[string]any{"rules": []string{"shout"}}, auth...)
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	tests := []struct {
+		name       string
+		body       map[string]any
+		configured scanner.NormalizeRules
+		tracks     int
+		albums     int
+		title      string
+	}{
+		{"dry run", map[string]any{"rules": []string{"trim"}}, scanner.NormalizeRules{}, 1, 0, "  Opener  "},
+		{"configured rules", map[string]any{"dryRun": false}, scanner.NormalizeRules{Trim: true}, 1, 0, "Opener"},
+		{"given rules", map[string]any{"dryRun": false, "rules": []string{"case"}}, scanner.NormalizeRules{Trim: true}, 0, 1, "Opener"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.lib.SetOptions(services.LibraryOptions{NormalizeRules: tt.configured})
+			rec := env.do(http.MethodPost, "/api/v1/admin/normalize-tags", tt.body, auth...)
+			expectStatus(t, rec, http.StatusAccepted)
+
+			status, result := waitDone()
+			if status != services.ScanStatusCompleted {
+				t.Fatalf("status = %q, want completed", status)
+			}
+			if result.TracksChanged != tt.tracks || result.AlbumsChanged != tt.albums {
+				t.Errorf("changed %d tracks and %d albums, want %d and %d",
+					result.TracksChanged, result.AlbumsChanged, tt.tracks, tt.albums)
+			}
+			var title string
+			env.db.DB.Raw("SELECT title FROM tracks WHERE id = 't1'").Scan(&title)
+			if title != tt.title {
+				t.Errorf("track title = %q, want %q", title, tt.title)
+			}
+		})
+	}
+
+	// Written changes bump updated_at, so clients syncing by it see them
+	var stale int64
+	env.db.DB.Raw(`SELECT COUNT(*) FROM tracks WHERE id = 't1' AND updated_at < '2001-01-01'`).Scan(&stale)
+	if stale != 0 {
+		t.Errorf("renamed track kept its old updated_at")
+	}
+	env.db.DB.Raw(`SELECT COUNT(*) FROM albums WHERE id = 'al1' AND updated_at < '2001-01-01'`).Scan(&stale)
+	if stale != 0 {
+		t.Errorf("renamed album kept its old updated_at")
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	env := newTestEnv(t, withAdminToken)
+	env.seedLibrary()
+	// Rows left behind by deletes made without foreign keys enforced
+	env.exec(
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO albums (id, title, artist_id, created_at, updated_at) VALUES
+			('al4', 'Lost', 'gone', datetime('now'), datetime('now'))`,
+		`INSERT INTO tracks (id, title, duration, file_path, file_size, format, album_id, artist_id, created_at, updated_at) VALUES
+			('t5', 'Five', 200, '/a/5.mp3', 100, 'mp3', 'al1', 'gone', datetime('now'), datetime('now')),
+			('t6', 'Six', 200, '/a/6.mp3', 100, 'mp3', 'gone', 'ar1', datetime('now'), datetime('now')),
+			('t7', 'Seven', 200, '/a/7.mp3', 100, 'mp3', 'al4', 'ar1', datetime('now'), datetime('now'))`,
+		`INSERT INTO playlists (id, name, created_at, updated_at) VALUES ('p1', 'Mix', datetime('now'), datetime('now'))`,
+		`INSERT INTO playlist_tracks (playlist_id, track_id, position, added_at) VALUES
+			('p1', 't1', 1, datetime('now')),
+			('p1', 'gone', 2, datetime('now')),
+			('p1', 't2', 3, datetime('now'))`,
+		`PRAGMA foreign_keys = ON`,
+	)
+	auth := bearer(testAdminToken)
+
+	// Steps run in order: report, repair, then report again
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   IntegrityReport
+	}{
+		{"report", http.MethodGet, "/api/v1/admin/integrity", IntegrityReport{1, 1, 1, 1, 4}},
+		{"fix", http.MethodPost, "/api/v1/admin/integrity/fix", IntegrityReport{1, 1, 1, 1, 4}},
+		{"report after fixing", http.MethodGet, "/api/v1/admin/integrity", IntegrityReport{}},
+		{"fix again", http.MethodPost, "/api/v1/admin/integrity/fix", IntegrityReport{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(tt.method, tt.path, nil, auth...)
+			expectStatus(t, rec, http.StatusOK)
+			var report IntegrityReport
+			decodeData(t, rec, &report)
+			if report != tt.want {
+				t.Errorf("report = %+v, want %+v", report, tt.want)
+			}
+		})
+	}
+
+	// The repaired rows point at what's left
+	repaired := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT artist_id FROM tracks WHERE id = 't5'", "ar1"},
+		{"SELECT COALESCE(album_id, '') FROM tracks WHERE id = 't6'", ""},
+		{"SELECT artist_id FROM albums WHERE id = 'al4'", "ar1"},
+		{"SELECT group_concat(track_id || ':' || position, ' ') FROM (SELECT * FROM playlist_tracks ORDER BY position)", "t1:1 t2:2"},
+	}
+	for _, tt := range repaired {
+		var got string
+		env.db.DB.Raw(tt.query).Scan(&got)
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMissingArtwork(t *testing.T) {
+	cacheDir := t.TempDir()
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		withAdminToken(cfg)
+		cfg.CacheDir = cacheDir
+	})
+	env.seedLibrary()
+	// al1 has a cover and ar1 a cached image; ar2 links to an image and ar3
+	// has nothing
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	if _, err := processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, "al1"); err != nil {
+		t.Fatalf("caching artwork: %v", err)
+	}
+	if _, err := processor.CacheArtistImage(&scanner.ArtworkInfo{Data: grayPNG(t)}, "ar1"); err != nil {
+		t.Fatalf("caching artist image: %v", err)
+	}
+	env.exec(
+		`UPDATE artists SET image_path = 'https://example.com/various.jpg' WHERE id = 'ar2'`,
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES ('ar3', 'Another', datetime('now'), datetime('now'))`,
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		ids   []string
+		total int64
+	}{
+		{"everything", "", http.StatusOK, []string{"al3", "al2", "ar3"}, 3},
+		{"albums", "?type=album", http.StatusOK, []string{"al3", "al2"}, 2},
+		{"artists", "?type=artist", http.StatusOK, []string{"ar3"}, 1},
+		{"second page", "?limit=2&page=2", http.StatusOK, []string{"ar3"}, 3},
+		{"unknown type", "?type=track", http.StatusBadRequest, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/admin/missing-artwork"+tt.query, nil, bearer(testAdminToken)...)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var report struct {
+				MissingAlbums  int                           `json:"missingAlbums"`
+				MissingArtists int                           `json:"missingArtists"`
+				Items          []services.MissingArtworkItem `json:"items"`
+			}
+			decodeData(t, rec, &report)
+			if report.MissingAlbums != 2 || report.MissingArtists != 1 {
+				t.Errorf("missing %d albums and %d artists, want 2 and 1", report.MissingAlbums, report.MissingArtists)
+			}
+			var ids []string
+			for _, item := range report.Items {
+				ids = append(ids, item.ID)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("items = %v, want %v", ids, tt.ids)
+			}
+			var envelope Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Meta == nil || envelope.Meta.Pagination == nil || envelope.Meta.Pagination.Total != tt.total {
+				t.Errorf("meta = %+v, want a total of %d", envelope.Meta, tt.total)
+			}
+		})
+	}
+}
+
+func TestRecheckTranscoder(t *testing.T) {
+	trans, runs := fakeEncoder(t)
+	ffmpeg := filepath.Join(filepath.Dir(runs), "ffmpeg")
+	script, err := os.ReadFile(ffmpeg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := newTestEnvWith(t, withAdminToken, trans)
+	untranscoded := newTestEnv(t, withAdminToken)
+
+	// Steps run in order against the same transcoder
+	tests := []struct {
+		name      string
+		env       *testEnv
+		change    func()
+		want      int
+		code      string
+		available bool
+	}{
+		{"present", env, func() {}, http.StatusOK, "", true},
+		{"removed", env, func() { os.Remove(ffmpeg) }, http.StatusServiceUnavailable, "FFMPEG_UNAVAILABLE", false},
+		{"installed", env, func() {
+			if err := os.WriteFile(ffmpeg, script, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}, http.StatusOK, "", true},
+		{"no transcoder", untranscoded, func() {}, http.StatusServiceUnavailable, "TRANSCODER_UNAVAILABLE", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			rec := tt.env.do(http.MethodGet, "/api/v1/admin/transcode/recheck", nil, bearer(testAdminToken)...)
+			expectStatus(t, rec, tt.want)
+			if tt.code != "" {
+				if code := errorCode(t, rec); code != tt.code {
+					t.Errorf("error code = %s, want %s", code, tt.code)
+				}
+			}
+			if tt.env != env {
+				return
+			}
+			var envelope struct {
+				Data FFmpegStatusResponse `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			status := envelope.Data
+			if status.Available != tt.available || (tt.available && (status.Version != "6.0-test" || len(status.Encoders) != 2)) {
+				t.Errorf("status = %+v, want available %v", status, tt.available)
+			}
+			if trans.Status().Available != tt.available {
+				t.Errorf("transcoder available = %v, want %v", trans.Status().Available, tt.available)
+			}
+		})
+	}
+}
@@ -2,39 +2,59 @@ package handlers
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/models"
 )
 
 // AlbumHandler handles album-related endpoints
 type AlbumHandler struct {
-	repo    *database.AlbumRepository
-	baseURL string
+	repo       *database.AlbumRepository
+	cacheDir   string
+	baseURL    string
+	timeFormat TimeFormat
+	sortLocale string
 }
 
 // NewAlbumHandler creates a new AlbumHandler
-func NewAlbumHandler(repo *database.AlbumRepository, baseURL string) *AlbumHandler {
+func NewAlbumHandler(repo *database.AlbumRepository, cacheDir, baseURL string, timeFormat TimeFormat, sortLocale string) *AlbumHandler {
 	return &AlbumHandler{
-		repo:    repo,
-		baseURL: baseURL,
+		repo:       repo,
+		cacheDir:   cacheDir,
+		baseURL:    baseURL,
+		timeFormat: timeFormat,
+		sortLocale: sortLocale,
 	}
 }
 
 // List handles GET /api/v1/albums
 func (h *AlbumHandler) List(c *gin.Context) {
 	pagination := ParsePagination(c)
+	fields, err := parseFields(c, AlbumResponse{}.fields())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	opts := database.AlbumListOptions{
 		Page:  pagination.Page,
 		Limit: pagination.Limit,
 		Filter: database.AlbumFilter{
-			ArtistID: c.Query("artistId"),
-			Query:    c.Query("q"),
+			ArtistID:  c.Query("artistId"),
+			Query:     c.Query("q"),
+			AlbumType: c.Query("type"),
 		},
 		SortBy: c.DefaultQuery("sortBy", "title"),
 		Order:  c.DefaultQuery("order", "asc"),
+		Locale: requestSortLocale(c, h.sortLocale),
+	}
+
+	// Singles can be left to the singles view
+	if c.Query("hideSingles") == "true" {
+		opts.Filter.ExcludeType = models.AlbumTypeSingle
 	}
 
 	// Parse year filter
@@ -53,26 +73,45 @@ func (h *AlbumHandler) List(c *gin.Context) {
 	// Build response with links
 	response := make([]AlbumResponse, len(albums))
 	for i, album := range albums {
-		response[i] = AlbumResponse{
-			ID:          album.ID,
-			Title:       album.Title,
-			Year:        album.Year,
-			ArtistID:    album.ArtistID,
-			TrackCount:  album.TrackCount,
-			Duration:    album.Duration,
-			CoverArtURL: h.baseURL + "/api/v1/artwork/album/" + album.ID,
-			Links:       BuildAlbumLinks(h.baseURL, album.ID, album.ArtistID),
-		}
+		response[i] = h.listEntry(album)
+	}
 
-		// Include artist name if preloaded
-		if album.Artist != nil {
-			response[i].ArtistName = album.Artist.Name
+	if fields != nil {
+		sparse := make([]fieldSet, len(response))
+		for i, album := range response {
+			sparse[i] = album.fields().only(fields)
 		}
+		SuccessWithPagination(c, sparse, NewPagination(pagination.Page, pagination.Limit, total))
+		return
 	}
 
 	SuccessWithPagination(c, response, NewPagination(pagination.Page, pagination.Limit, total))
 }
 
+// listEntry builds the album response used in album lists
+func (h *AlbumHandler) listEntry(album models.Album) AlbumResponse {
+	response := AlbumResponse{
+		ID:          album.ID,
+		Title:       album.Title,
+		Edition:     album.Edition,
+		Year:        album.Year,
+		AlbumType:   album.AlbumType,
+		TotalTracks: album.TotalTracks,
+		TotalDiscs:  album.TotalDiscs,
+		ArtistID:    album.ArtistID,
+		TrackCount:  album.TrackCount,
+		Duration:    album.Duration,
+		CoverArtURL: h.baseURL + "/api/v1/artwork/album/" + album.ID,
+		Links:       BuildAlbumLinks(h.baseURL, album.ID, album.ArtistID),
+	}
+
+	// Include artist name if preloaded
+	if album.Artist != nil {
+		response.ArtistName = album.Artist.Name
+	}
+	return response
+}
+
 // Get handles GET /api/v1/albums/:id
 func (h *AlbumHandler) Get(c *gin.Context) {
 	id := c.Param("id")
@@ -92,39 +131,138 @@ func (h *AlbumHandler) Get(c *gin.Context) {
 		return
 	}
 
+	Success(c, h.albumDetail(album))
+}
+
+// UpdateAlbumRequest is the body of PATCH /api/v1/albums/:id
+type UpdateAlbumRequest struct {
+	Notes *string `json:"notes" binding:"omitempty,max=65536"`
+}
+
+// Update handles PATCH /api/v1/albums/:id
+// Edits an album's liner notes; an empty string clears them.
+func (h *AlbumHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req UpdateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if req.Notes == nil {
+		BadRequest(c, "no fields to update")
+		return
+	}
+
+	if _, err := h.repo.FindByID(ctx, id); err != nil {
+		if errors.Is(err, database.ErrAlbumNotFound) {
+			NotFound(c, "album")
+			return
+		}
+		InternalError(c, "failed to get album")
+		return
+	}
+
+	if err := h.repo.UpdateNotes(ctx, id, strings.TrimSpace(*req.Notes)); err != nil {
+		InternalError(c, "failed to update album")
+		return
+	}
+
+	album, err := h.repo.FindByIDWithTracks(ctx, id)
+	if err != nil {
+		InternalError(c, "failed to get album")
+		return
+	}
+
+	Success(c, h.albumDetail(album))
+}
+
+// TrackOrderRequest is the body of PUT /api/v1/albums/:id/track-order
+type TrackOrderRequest struct {
+	TrackIDs []string `json:"trackIds"`
+}
+
+// SetTrackOrder handles PUT /api/v1/albums/:id/track-order
+// Stores a manual track order for albums with wrong track numbers. Tracks
+// left out follow in tagged order; an empty list restores the tagged order.
+func (h *AlbumHandler) SetTrackOrder(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req TrackOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	seen := make(map[string]bool, len(req.TrackIDs))
+	for _, trackID := range req.TrackIDs {
+		if seen[trackID] {
+			BadRequest(c, "duplicate track ID: "+trackID)
+			return
+		}
+		seen[trackID] = true
+	}
+
+	if _, err := h.repo.FindByID(ctx, id); err != nil {
+		if errors.Is(err, database.ErrAlbumNotFound) {
+			NotFound(c, "album")
+			return
+		}
+		InternalError(c, "failed to get album")
+		return
+	}
+
+	if err := h.repo.SetTrackOrder(ctx, id, req.TrackIDs); err != nil {
+		if errors.Is(err, database.ErrTrackNotInAlbum) {
+			BadRequest(c, "all tracks must belong to the album")
+			return
+		}
+		InternalError(c, "failed to set track order")
+		return
+	}
+
+	album, err := h.repo.FindByIDWithTracks(ctx, id)
+	if err != nil {
+		InternalError(c, "failed to get album")
+		return
+	}
+
+	Success(c, h.albumDetail(album))
+}
+
+// albumDetailResponse is an album with its tracks
+type albumDetailResponse struct {
+	AlbumResponse
+	Notes  string          `json:"notes,omitempty"`
+	Tracks []TrackResponse `json:"tracks"`
+}
+
+// albumDetail builds the response for an album loaded with its tracks
+func (h *AlbumHandler) albumDetail(album *models.Album) albumDetailResponse {
 	// Build track responses
 	tracks := make([]TrackResponse, len(album.Tracks))
 	for i, track := range album.Tracks {
-		tracks[i] = TrackResponse{
-			ID:          track.ID,
-			Title:       track.Title,
-			Duration:    track.Duration,
-			TrackNumber: track.TrackNumber,
-			DiscNumber:  track.DiscNumber,
-			Format:      track.Format,
-			Bitrate:     track.Bitrate,
-			AlbumID:     track.AlbumID,
-			ArtistID:    track.ArtistID,
-			Genre:       track.Genre,
-			Year:        track.Year,
-			Links:       BuildTrackLinks(h.baseURL, track.ID, track.AlbumID),
-		}
+		tracks[i] = newTrackResponse(h.baseURL, h.timeFormat, track)
 	}
 
-	response := struct {
-		AlbumResponse
-		Tracks []TrackResponse `json:"tracks"`
-	}{
+	response := albumDetailResponse{
 		AlbumResponse: AlbumResponse{
 			ID:          album.ID,
 			Title:       album.Title,
+			Edition:     album.Edition,
 			Year:        album.Year,
+			AlbumType:   album.AlbumType,
+			TotalTracks: album.TotalTracks,
+			TotalDiscs:  album.TotalDiscs,
 			ArtistID:    album.ArtistID,
 			TrackCount:  album.TrackCount,
 			Duration:    album.Duration,
 			CoverArtURL: h.baseURL + "/api/v1/artwork/album/" + album.ID,
 			Links:       BuildAlbumLinks(h.baseURL, album.ID, album.ArtistID),
 		},
+		Notes:  album.Notes,
 		Tracks: tracks,
 	}
 
@@ -133,5 +271,5 @@ func (h *AlbumHandler) Get(c *gin.Context) {
 		response.ArtistName = album.Artist.Name
 	}
 
-	Success(c, response)
+	return response
 }
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestAlbumTrackOrder(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	env.exec(`INSERT INTO tracks (id, title, duration, track_number, disc_number, file_path, file_size,
+		format, album_id, artist_id, created_at, updated_at) VALUES
+		('t5', 'Three', 190, 3, 1, '/a/5.mp3', 100, 'mp3', 'al1', 'ar1', datetime('now'), datetime('now'))`)
+
+	// albumTracks returns the track IDs of the album detail in order
+	albumTracks := func() []string {
+		t.Helper()
+		rec := env.do(http.MethodGet, "/api/v1/albums/al1", nil)
+		expectStatus(t, rec, http.StatusOK)
+		var album albumDetailResponse
+		decodeData(t, rec, &album)
+		var ids []string
+		for _, track := range album.Tracks {
+			ids = append(ids, track.ID)
+		}
+		return ids
+	}
+
+	// Steps run in order, each starting from the order the last one left
+	tests := []struct {
+		name   string
+		album  string
+		tracks []string
+		status int
+		want   []string
+	}{
+		{"custom order", "al1", []string{"t5", "t1", "t2"}, http.StatusOK, []string{"t5", "t1", "t2"}},
+		{"partial order", "al1", []string{"t2"}, http.StatusOK, []string{"t2", "t1", "t5"}},
+		{"track from another album", "al1", []string{"t1", "t3"}, http.StatusBadRequest, []string{"t2", "t1", "t5"}},
+		{"duplicate track", "al1", []string{"t1", "t1"}, http.StatusBadRequest, []string{"t2", "t1", "t5"}},
+		{"unknown album", "al9", []string{"t1"}, http.StatusNotFound, []string{"t2", "t1", "t5"}},
+		{"tagged order restored", "al1", []string{}, http.StatusOK, []string{"t1", "t2", "t5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodPut, "/api/v1/albums/"+tt.album+"/track-order", map[string]any{"trackIds": tt.tracks})
+			expectStatus(t, rec, tt.status)
+			if got := albumTracks(); !slices.Equal(got, tt.want) {
+				t.Errorf("album tracks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+// SinglesGroupResponse is one artist's singles in the singles view
+type SinglesGroupResponse struct {
+	ArtistID   string          `json:"artistId"`
+	ArtistName string          `json:"artistName"`
+	Singles    []AlbumResponse `json:"singles"`
+}
+
+// Singles handles GET /api/v1/albums/singles
+// Groups every single by album artist, newest first within each artist, so
+// the albums grid can leave them out with ?hideSingles=true.
+func (h *AlbumHandler) Singles(c *gin.Context) {
+	albums, _, err := h.repo.List(c.Request.Context(), database.AlbumListOptions{
+		Filter: database.AlbumFilter{AlbumType: models.AlbumTypeSingle},
+		SortBy: "year",
+		Order:  "desc",
+	})
+	if err != nil {
+		InternalError(c, "failed to list singles")
+		return
+	}
+
+	groups := []SinglesGroupResponse{}
+	index := make(map[string]int)
+	for _, album := range albums {
+		entry := h.listEntry(album)
+		i, ok := index[album.ArtistID]
+		if !ok {
+			i = len(groups)
+			index[album.ArtistID] = i
+			groups = append(groups, SinglesGroupResponse{
+				ArtistID:   album.ArtistID,
+				ArtistName: entry.ArtistName,
+			})
+		}
+		groups[i].Singles = append(groups[i].Singles, entry)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].ArtistName) < strings.ToLower(groups[j].ArtistName)
+	})
+
+	Success(c, groups)
+}
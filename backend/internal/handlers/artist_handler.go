@@ -2,23 +2,37 @@ package handlers
 
 import (
 	"errors"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/models"
+	"harmony/internal/scanner"
 )
 
 // ArtistHandler handles artist-related endpoints
 type ArtistHandler struct {
-	repo    *database.ArtistRepository
-	baseURL string
+	repo       *database.ArtistRepository
+	trackRepo  *database.TrackRepository
+	cacheDir   string
+	baseURL    string
+	timeFormat TimeFormat
+	sortLocale string
 }
 
 // NewArtistHandler creates a new ArtistHandler
-func NewArtistHandler(repo *database.ArtistRepository, baseURL string) *ArtistHandler {
+func NewArtistHandler(repo *database.ArtistRepository, trackRepo *database.TrackRepository, cacheDir, baseURL string, timeFormat TimeFormat, sortLocale string) *ArtistHandler {
 	return &ArtistHandler{
-		repo:    repo,
-		baseURL: baseURL,
+		repo:       repo,
+		trackRepo:  trackRepo,
+		cacheDir:   cacheDir,
+		baseURL:    baseURL,
+		timeFormat: timeFormat,
+		sortLocale: sortLocale,
 	}
 }
 
@@ -34,6 +48,7 @@ func (h *ArtistHandler) List(c *gin.Context) {
 		},
 		SortBy: c.DefaultQuery("sortBy", "name"),
 		Order:  c.DefaultQuery("order", "asc"),
+		Locale: requestSortLocale(c, h.sortLocale),
 	}
 
 	artists, total, err := h.repo.List(c.Request.Context(), opts)
@@ -49,7 +64,7 @@ func (h *ArtistHandler) List(c *gin.Context) {
 			ID:       artist.ID,
 			Name:     artist.Name,
 			Bio:      artist.Bio,
-			ImageURL: artist.ImageURL,
+			ImageURL: BuildArtistImageURL(h.baseURL, artist),
 			Links:    BuildArtistLinks(h.baseURL, artist.ID),
 		}
 	}
@@ -82,7 +97,11 @@ func (h *ArtistHandler) Get(c *gin.Context) {
 		albums[i] = AlbumResponse{
 			ID:          album.ID,
 			Title:       album.Title,
+			Edition:     album.Edition,
 			Year:        album.Year,
+			AlbumType:   album.AlbumType,
+			TotalTracks: album.TotalTracks,
+			TotalDiscs:  album.TotalDiscs,
 			ArtistID:    album.ArtistID,
 			ArtistName:  artist.Name,
 			CoverArtURL: h.baseURL + "/api/v1/artwork/album/" + album.ID,
@@ -114,7 +133,7 @@ func (h *ArtistHandler) Get(c *gin.Context) {
 			ID:         artist.ID,
 			Name:       artist.Name,
 			Bio:        artist.Bio,
-			ImageURL:   artist.ImageURL,
+			ImageURL:   BuildArtistImageURL(h.baseURL, *artist),
 			AlbumCount: len(artist.Albums),
 			Links:      BuildArtistLinks(h.baseURL, artist.ID),
 		},
@@ -124,3 +143,279 @@ func (h *ArtistHandler) Get(c *gin.Context) {
 
 	Success(c, response)
 }
+
+// Tracks handles GET /api/v1/artists/:id/tracks
+func (h *ArtistHandler) Tracks(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "artist ID required")
+		return
+	}
+
+	if _, err := h.repo.FindByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, database.ErrArtistNotFound) {
+			NotFound(c, "artist")
+			return
+		}
+		InternalError(c, "failed to get artist")
+		return
+	}
+
+	pagination := ParsePagination(c)
+	filter, err := parseTrackFilter(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	filter.ArtistID = id
+
+	tracks, total, err := h.trackRepo.List(c.Request.Context(), database.TrackListOptions{
+		Page:   pagination.Page,
+		Limit:  pagination.Limit,
+		Filter: filter,
+		SortBy: c.Query("sortBy"),
+		Order:  c.DefaultQuery("order", "asc"),
+		Locale: requestSortLocale(c, h.sortLocale),
+	})
+	if err != nil {
+		InternalError(c, "failed to list artist tracks")
+		return
+	}
+
+	response := make([]TrackResponse, len(tracks))
+	for i, track := range tracks {
+		response[i] = newTrackResponse(h.baseURL, h.timeFormat, track)
+	}
+
+	SuccessWithPagination(c, response, NewPagination(pagination.Page, pagination.Limit, total))
+}
+
+// Listener limits for artist stats
+const (
+	defaultTopListeners = 5
+	maxTopListeners     = 50
+)
+
+// ArtistStatsResponse summarizes how much an artist's tracks are played
+type ArtistStatsResponse struct {
+	ArtistID        string                   `json:"artistId"`
+	TotalPlays      int64                    `json:"totalPlays"`
+	TotalSkips      int64                    `json:"totalSkips"`
+	PlayedTracks    int64                    `json:"playedTracks"`
+	MostPlayedTrack *TrackResponse           `json:"mostPlayedTrack,omitempty"`
+	TopListeners    []ArtistListenerResponse `json:"topListeners"`
+}
+
+// ArtistListenerResponse is a user and how many times they played an artist
+type ArtistListenerResponse struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username,omitempty"`
+	Plays    int64  `json:"plays"`
+}
+
+// Stats handles GET /api/v1/artists/:id/stats
+// Total plays and skips across the artist's tracks, the most played track
+// and the users who played the artist most (?listeners=, default 5).
+func (h *ArtistHandler) Stats(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "artist ID required")
+		return
+	}
+
+	listeners := defaultTopListeners
+	if value := c.Query("listeners"); value != "" {
+		n, err := parseInt(value)
+		if err != nil || n < 0 || n > maxTopListeners {
+			BadRequest(c, "listeners must be between 0 and 50")
+			return
+		}
+		listeners = n
+	}
+
+	if _, err := h.repo.FindByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, database.ErrArtistNotFound) {
+			NotFound(c, "artist")
+			return
+		}
+		InternalError(c, "failed to get artist")
+		return
+	}
+
+	stats, err := h.repo.GetStats(c.Request.Context(), id, listeners)
+	if err != nil {
+		InternalError(c, "failed to get artist stats")
+		return
+	}
+
+	response := ArtistStatsResponse{
+		ArtistID:     id,
+		TotalPlays:   stats.TotalPlays,
+		TotalSkips:   stats.TotalSkips,
+		PlayedTracks: stats.PlayedTracks,
+		TopListeners: make([]ArtistListenerResponse, len(stats.TopListeners)),
+	}
+	if stats.MostPlayedTrack != nil {
+		track := newTrackResponse(h.baseURL, h.timeFormat, *stats.MostPlayedTrack)
+		response.MostPlayedTrack = &track
+	}
+	for i, listener := range stats.TopListeners {
+		response.TopListeners[i] = ArtistListenerResponse{
+			UserID:   listener.UserID,
+			Username: listener.Username,
+			Plays:    listener.Plays,
+		}
+	}
+
+	Success(c, response)
+}
+
+// Discography handles GET /api/v1/artists/:id/discography
+func (h *ArtistHandler) Discography(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "artist ID required")
+		return
+	}
+
+	artist, err := h.repo.FindByIDWithAlbums(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrArtistNotFound) {
+			NotFound(c, "artist")
+			return
+		}
+		InternalError(c, "failed to get artist")
+		return
+	}
+
+	appearances, err := h.repo.GetAppearances(c.Request.Context(), id)
+	if err != nil {
+		InternalError(c, "failed to get artist appearances")
+		return
+	}
+
+	response := struct {
+		ArtistResponse
+		Albums       []AlbumResponse `json:"albums"`
+		EPs          []AlbumResponse `json:"eps"`
+		Singles      []AlbumResponse `json:"singles"`
+		Compilations []AlbumResponse `json:"compilations"`
+		AppearsOn    []AlbumResponse `json:"appearsOn"`
+	}{
+		ArtistResponse: ArtistResponse{
+			ID:         artist.ID,
+			Name:       artist.Name,
+			Bio:        artist.Bio,
+			ImageURL:   BuildArtistImageURL(h.baseURL, *artist),
+			AlbumCount: len(artist.Albums),
+			Links:      BuildArtistLinks(h.baseURL, artist.ID),
+		},
+		Albums:       []AlbumResponse{},
+		EPs:          []AlbumResponse{},
+		Singles:      []AlbumResponse{},
+		Compilations: []AlbumResponse{},
+		AppearsOn:    make([]AlbumResponse, 0, len(appearances)),
+	}
+
+	// Own releases, oldest first within each group
+	own := artist.Albums
+	sort.SliceStable(own, func(i, j int) bool {
+		if own[i].Year != own[j].Year {
+			return own[i].Year < own[j].Year
+		}
+		return own[i].Title < own[j].Title
+	})
+
+	for _, album := range own {
+		entry := h.discographyEntry(album, artist.Name)
+		switch album.AlbumType {
+		case models.AlbumTypeEP:
+			response.EPs = append(response.EPs, entry)
+		case models.AlbumTypeSingle:
+			response.Singles = append(response.Singles, entry)
+		case models.AlbumTypeCompilation:
+			response.Compilations = append(response.Compilations, entry)
+		default:
+			response.Albums = append(response.Albums, entry)
+		}
+	}
+
+	for _, album := range appearances {
+		artistName := ""
+		if album.Artist != nil {
+			artistName = album.Artist.Name
+		}
+		response.AppearsOn = append(response.AppearsOn, h.discographyEntry(album, artistName))
+	}
+
+	Success(c, response)
+}
+
+// discographyEntry builds the album response used in discography groups
+func (h *ArtistHandler) discographyEntry(album models.Album, artistName string) AlbumResponse {
+	return AlbumResponse{
+		ID:          album.ID,
+		Title:       album.Title,
+		Edition:     album.Edition,
+		Year:        album.Year,
+		AlbumType:   album.AlbumType,
+		TotalTracks: album.TotalTracks,
+		TotalDiscs:  album.TotalDiscs,
+		ArtistID:    album.ArtistID,
+		ArtistName:  artistName,
+		CoverArtURL: h.baseURL + "/api/v1/artwork/album/" + album.ID,
+		Links:       BuildAlbumLinks(h.baseURL, album.ID, album.ArtistID),
+	}
+}
+
+// SetArtistImageRequest is the body of PUT /api/v1/admin/artists/:id/image
+type SetArtistImageRequest struct {
+	URL string `json:"url" binding:"max=2048"`
+}
+
+// SetImage handles PUT /api/v1/admin/artists/:id/image
+// Sets the http(s) URL of an artist's image, which the artwork endpoint
+// fetches and caches on first request; an empty URL removes it. The
+// previously cached image is dropped either way.
+func (h *ArtistHandler) SetImage(c *gin.Context) {
+	id := c.Param("id")
+
+	var req SetArtistImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	imageURL := strings.TrimSpace(req.URL)
+	if imageURL != "" && !scanner.IsRemoteURL(imageURL) {
+		BadRequest(c, "url must be an http or https URL")
+		return
+	}
+
+	if err := h.repo.UpdateImagePath(c.Request.Context(), id, imageURL); err != nil {
+		if errors.Is(err, database.ErrArtistNotFound) {
+			NotFound(c, "artist")
+			return
+		}
+		InternalError(c, "failed to update artist")
+		return
+	}
+
+	dir := scanner.ArtworkCacheDir(h.cacheDir, scanner.ArtworkKindArtist, id)
+	if err := os.RemoveAll(dir); err != nil {
+		slog.Warn("removing cached artist image failed", "dir", dir, "error", err)
+	}
+
+	artist, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		InternalError(c, "failed to get artist")
+		return
+	}
+
+	Success(c, ArtistResponse{
+		ID:       artist.ID,
+		Name:     artist.Name,
+		Bio:      artist.Bio,
+		ImageURL: BuildArtistImageURL(h.baseURL, *artist),
+		Links:    BuildArtistLinks(h.baseURL, artist.ID),
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetArtistImage(t *testing.T) {
+	var pngData bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngData.Bytes())
+	}))
+	defer server.Close()
+
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		withAdminToken(cfg)
+		cfg.RemoteArtworkPrivateHosts = true
+	})
+	env.seedLibrary()
+	auth := bearer(testAdminToken)
+
+	tests := []struct {
+		name string
+		path string
+		url  string
+		want int
+	}{
+		{"not a URL", "/api/v1/admin/artists/ar1/image", "/etc/passwd", http.StatusBadRequest},
+		{"not http", "/api/v1/admin/artists/ar1/image", "file:///etc/passwd", http.StatusBadRequest},
+		{"missing artist", "/api/v1/admin/artists/none/image", server.URL + "/band.png", http.StatusNotFound},
+		{"remote image", "/api/v1/admin/artists/ar1/image", server.URL + "/band.png", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodPut, tt.path, map[string]string{"url": tt.url}, auth...)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+
+	var imagePath string
+	env.db.DB.Raw("SELECT image_path FROM artists WHERE id = 'ar1'").Scan(&imagePath)
+	if imagePath != server.URL+"/band.png" {
+		t.Fatalf("image_path = %q, want the URL set", imagePath)
+	}
+
+	// Fetched on the first request and served from the cache after that
+	for i := 0; i < 2; i++ {
+		rec := env.do(http.MethodGet, "/api/v1/artwork/artist/ar1", nil)
+		expectStatus(t, rec, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/jpeg" && ct != "image/png" {
+			t.Errorf("artist image content type = %q", ct)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("remote image fetched %d times, want 1", got)
+	}
+
+	// Setting it again drops the cached copy
+	rec := env.do(http.MethodPut, "/api/v1/admin/artists/ar1/image", map[string]string{"url": server.URL + "/new.png"}, auth...)
+	expectStatus(t, rec, http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/artwork/artist/ar1", nil)
+	if got := hits.Load(); got != 2 {
+		t.Errorf("remote image fetched %d times after changing it, want 2", got)
+	}
+
+	// Only administrators set images
+	rec = env.do(http.MethodPut, "/api/v1/admin/artists/ar1/image", map[string]string{"url": ""})
+	expectStatus(t, rec, http.StatusUnauthorized)
+}
+
+func TestArtistTracks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	tests := []struct {
+		name  string
+		path  string
+		want  int
+		ids   []string
+		total int64
+	}{
+		{"first page", "/api/v1/artists/ar1/tracks?limit=3&sortBy=title", http.StatusOK, []string{"t4", "t3", "t1"}, 4},
+		{"second page", "/api/v1/artists/ar1/tracks?limit=3&page=2&sortBy=title", http.StatusOK, []string{"t2"}, 4},
+		{"descending", "/api/v1/artists/ar1/tracks?limit=2&sortBy=title&order=desc", http.StatusOK, []string{"t2", "t1"}, 4},
+		{"filtered", "/api/v1/artists/ar1/tracks?genre=Rock&sortBy=title", http.StatusOK, []string{"t3", "t1"}, 2},
+		{"album artist without tracks", "/api/v1/artists/ar2/tracks", http.StatusOK, []string{}, 0},
+		{"missing artist", "/api/v1/artists/none/tracks", http.StatusNotFound, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var tracks []TrackResponse
+			decodeData(t, rec, &tracks)
+			ids := make([]string, len(tracks))
+			for i, track := range tracks {
+				ids[i] = track.ID
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("tracks = %v, want %v", ids, tt.ids)
+			}
+			var envelope Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Meta == nil || envelope.Meta.Pagination == nil || envelope.Meta.Pagination.Total != tt.total {
+				t.Errorf("meta = %+v, want a total of %d", envelope.Meta, tt.total)
+			}
+		})
+	}
+}
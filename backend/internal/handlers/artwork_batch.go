@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/scanner"
+)
+
+// maxArtworkBatchBytes caps the encoded images of a batch response. Images
+// past it are listed as truncated so the client can fetch them in a
+// follow-up batch.
+const maxArtworkBatchBytes = 4 << 20
+
+// ArtworkBatchRequest represents a request for several albums' artwork
+type ArtworkBatchRequest struct {
+	AlbumIDs []string `json:"albumIds" binding:"required,max=100"`
+	Size     string   `json:"size"`
+}
+
+// ArtworkBatchImage is one album's artwork, base64-encoded
+type ArtworkBatchImage struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// ArtworkBatchResponse carries the artwork of a batch keyed by album ID
+type ArtworkBatchResponse struct {
+	Size      string                       `json:"size"`
+	Images    map[string]ArtworkBatchImage `json:"images"`
+	Missing   []string                     `json:"missing,omitempty"`
+	Truncated []string                     `json:"truncated,omitempty"`
+}
+
+// Batch handles POST /api/v1/artwork/batch
+// Returns the cached artwork of up to 100 albums in one response, for
+// clients that prefetch covers on first load. Size is thumbnail (default),
+// small or medium; the encoded images are capped at 4MB in total.
+func (h *ArtworkHandler) Batch(c *gin.Context) {
+	var req ArtworkBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	size := req.Size
+	switch size {
+	case "":
+		size = scanner.ArtworkSizeThumbnail.Name
+	case scanner.ArtworkSizeThumbnail.Name, scanner.ArtworkSizeSmall.Name, scanner.ArtworkSizeMedium.Name:
+	default:
+		BadRequest(c, "size must be thumbnail, small or medium")
+		return
+	}
+
+	response := ArtworkBatchResponse{
+		Size:   size,
+		Images: make(map[string]ArtworkBatchImage, len(req.AlbumIDs)),
+	}
+	seen := make(map[string]bool, len(req.AlbumIDs))
+	total := 0
+	for _, id := range req.AlbumIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if id == "" || strings.Contains(id, "..") || strings.ContainsAny(id, `/\`) {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+		if total >= maxArtworkBatchBytes {
+			response.Truncated = append(response.Truncated, id)
+			continue
+		}
+
+		data, mimeType, err := h.processor.LoadArtwork(id, size)
+		if err != nil {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(data)
+		if total+len(encoded) > maxArtworkBatchBytes {
+			response.Truncated = append(response.Truncated, id)
+			continue
+		}
+		total += len(encoded)
+		response.Images[id] = ArtworkBatchImage{MIMEType: mimeType, Data: encoded}
+	}
+
+	Success(c, response)
+}
@@ -1,26 +1,66 @@
 package handlers
 
 import (
+	"errors"
+	"image/color"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"harmony/internal/database"
 	"harmony/internal/scanner"
 )
 
+// artworkPlaceholderSVG is served in place of missing artwork
+const artworkPlaceholderSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 300 300"><rect fill="#1a1a2e" width="300" height="300"/><text x="150" y="160" font-family="Arial" font-size="48" fill="#4a4a6a" text-anchor="middle">♪</text></svg>`
+
 // ArtworkHandler handles artwork serving endpoints
 type ArtworkHandler struct {
-	processor *scanner.ArtworkProcessor
-	cacheDir  string
+	artistRepo     *database.ArtistRepository
+	albumRepo      *database.AlbumRepository
+	trackRepo      *database.TrackRepository
+	processor      *scanner.ArtworkProcessor
+	fetcher        *scanner.RemoteArtworkFetcher
+	artistFallback bool
+	trackArtwork   bool
 }
 
-// NewArtworkHandler creates a new ArtworkHandler
-func NewArtworkHandler(cacheDir string) *ArtworkHandler {
+// NewArtworkHandler creates a new ArtworkHandler. With artistFallback set,
+// albums without a cover are shown with their artist's image; with
+// trackArtwork set, tracks are shown with their own embedded artwork. Remote
+// artist images are only fetched from private network addresses with
+// remotePrivateHosts set.
+func NewArtworkHandler(
+	artistRepo *database.ArtistRepository,
+	albumRepo *database.AlbumRepository,
+	trackRepo *database.TrackRepository,
+	cacheDir string,
+	maxDimension int,
+	maxPixels int,
+	thumbnailMode scanner.ThumbnailMode,
+	padColor color.Color,
+	artistFallback bool,
+	trackArtwork bool,
+	remotePrivateHosts bool,
+) *ArtworkHandler {
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	processor.SetMaxDimension(maxDimension)
+	processor.SetMaxPixels(maxPixels)
+	processor.SetThumbnailMode(thumbnailMode, padColor)
+	fetcher := scanner.NewRemoteArtworkFetcher(scanner.DefaultRemoteArtworkTimeout, scanner.DefaultRemoteArtworkMaxBytes)
+	fetcher.SetAllowPrivateHosts(remotePrivateHosts)
+
 	return &ArtworkHandler{
-		processor: scanner.NewArtworkProcessor(cacheDir),
-		cacheDir:  cacheDir,
+		artistRepo:     artistRepo,
+		albumRepo:      albumRepo,
+		trackRepo:      trackRepo,
+		processor:      processor,
+		fetcher:        fetcher,
+		artistFallback: artistFallback,
+		trackArtwork:   trackArtwork,
 	}
 }
 
@@ -34,7 +74,55 @@ func (h *ArtworkHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// Get size parameter (default to medium)
+	size := artworkSizeParam(c)
+
+	kind, ok := scanner.ParseArtworkKind(artType)
+	if !ok {
+		BadRequest(c, "invalid artwork type")
+		return
+	}
+	if strings.Contains(id, "..") || strings.ContainsAny(id, `/\`) {
+		BadRequest(c, "invalid "+artType+" ID")
+		return
+	}
+
+	artworkPath := h.processor.CachePath(kind, id, size)
+	cacheControl := "public, max-age=31536000, immutable"
+
+	switch kind {
+	case scanner.ArtworkKindAlbum:
+		if _, err := os.Stat(artworkPath); os.IsNotExist(err) && h.artistFallback {
+			if fallback := h.albumArtistImagePath(c, id, size); fallback != "" {
+				artworkPath = fallback
+				// The album may get its own cover on a later scan
+				cacheControl = "public, max-age=3600"
+			}
+		}
+	case scanner.ArtworkKindArtist:
+		// Artist images are proxied from their remote URL on first request
+		if _, err := os.Stat(artworkPath); os.IsNotExist(err) {
+			h.cacheRemoteArtistImage(c, id)
+		}
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(artworkPath); os.IsNotExist(err) {
+		// Return a placeholder to avoid 404 spam
+		// The frontend should handle this gracefully with CSS fallback
+		servePlaceholderArtwork(c)
+		return
+	}
+
+	// Set cache headers
+	c.Header("Cache-Control", cacheControl)
+	c.Header("Content-Type", "image/jpeg")
+
+	// Serve the file
+	c.File(artworkPath)
+}
+
+// artworkSizeParam reads the size query parameter, defaulting to medium
+func artworkSizeParam(c *gin.Context) string {
 	size := c.DefaultQuery("size", "medium")
 	validSizes := map[string]bool{
 		"thumbnail": true,
@@ -46,39 +134,102 @@ func (h *ArtworkHandler) Get(c *gin.Context) {
 	if !validSizes[size] {
 		size = "medium"
 	}
+	return size
+}
 
-	var artworkPath string
+// servePlaceholderArtwork responds with the placeholder image
+func servePlaceholderArtwork(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("Content-Type", "image/svg+xml")
+	c.String(200, artworkPlaceholderSVG)
+}
 
-	switch artType {
-	case "album":
-		artworkPath = h.processor.GetArtworkPath(id, size)
-	case "artist":
-		// Artist images stored differently
-		artworkPath = filepath.Join(h.cacheDir, "artists", id, size+".jpg")
-	case "playlist":
-		// Playlist cover images
-		artworkPath = filepath.Join(h.cacheDir, "playlists", id, size+".jpg")
-	default:
-		BadRequest(c, "invalid artwork type")
+// albumArtistImagePath returns the cached image of an album's artist, fetching
+// it first if needed, or an empty string when the artist has no image
+func (h *ArtworkHandler) albumArtistImagePath(c *gin.Context, albumID, size string) string {
+	if h.albumRepo == nil {
+		return ""
+	}
+
+	album, err := h.albumRepo.FindByID(c.Request.Context(), albumID)
+	if err != nil || album.ArtistID == "" {
+		return ""
+	}
+
+	path := h.processor.GetArtistImagePath(album.ArtistID, size)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		h.cacheRemoteArtistImage(c, album.ArtistID)
+		if _, err := os.Stat(path); err != nil {
+			return ""
+		}
+	}
+	return path
+}
+
+// cacheRemoteArtistImage fetches an artist's remote image and stores it in the
+// artwork cache. Failures are logged and leave the placeholder in place.
+func (h *ArtworkHandler) cacheRemoteArtistImage(c *gin.Context, artistID string) {
+	if h.artistRepo == nil {
 		return
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(artworkPath); os.IsNotExist(err) {
-		// Return a 1x1 transparent placeholder to avoid 404 spam
-		// The frontend should handle this gracefully with CSS fallback
-		c.Header("Cache-Control", "public, max-age=3600")
-		c.Header("Content-Type", "image/svg+xml")
-		c.String(200, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 300 300"><rect fill="#1a1a2e" width="300" height="300"/><text x="150" y="160" font-family="Arial" font-size="48" fill="#4a4a6a" text-anchor="middle">♪</text></svg>`)
+	artist, err := h.artistRepo.FindByID(c.Request.Context(), artistID)
+	if err != nil || !scanner.IsRemoteURL(artist.ImagePath) {
 		return
 	}
 
-	// Set cache headers
-	c.Header("Cache-Control", "public, max-age=31536000, immutable")
-	c.Header("Content-Type", "image/jpeg")
+	artwork, err := h.fetcher.Fetch(c.Request.Context(), artist.ImagePath)
+	if errors.Is(err, scanner.ErrRemoteArtworkRecentlyFailed) {
+		return
+	}
+	if err != nil {
+		slog.Warn("failed to fetch artist image", "artistID", artistID, "error", err)
+		return
+	}
 
-	// Serve the file
-	c.File(artworkPath)
+	if _, err := h.processor.CacheArtistImage(artwork, artistID); err != nil {
+		slog.Warn("failed to cache artist image", "artistID", artistID, "error", err)
+	}
+}
+
+// ArtworkStatusRequest represents a request for artwork availability
+type ArtworkStatusRequest struct {
+	AlbumIDs []string `json:"albumIds" binding:"required,max=200"`
+}
+
+// ArtworkStatus describes the cached artwork for a single album
+type ArtworkStatus struct {
+	Exists        bool     `json:"exists"`
+	Sizes         []string `json:"sizes,omitempty"`
+	DominantColor string   `json:"dominantColor,omitempty"`
+}
+
+// Status handles POST /api/v1/artwork/status
+func (h *ArtworkHandler) Status(c *gin.Context) {
+	var req ArtworkStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	statuses := make(map[string]ArtworkStatus, len(req.AlbumIDs))
+	for _, id := range req.AlbumIDs {
+		if !h.processor.ArtworkExists(id) {
+			statuses[id] = ArtworkStatus{Exists: false}
+			continue
+		}
+
+		status := ArtworkStatus{
+			Exists: true,
+			Sizes:  h.processor.AvailableSizes(id),
+		}
+		if color, err := h.processor.DominantColor(id); err == nil {
+			status.DominantColor = color
+		}
+		statuses[id] = status
+	}
+
+	Success(c, statuses)
 }
 
 // GetAlbumArtwork is a convenience method for album artwork
@@ -94,9 +245,7 @@ func (h *ArtworkHandler) GetAlbumArtwork(c *gin.Context) {
 
 	if _, err := os.Stat(artworkPath); os.IsNotExist(err) {
 		// Return SVG placeholder for missing artwork
-		c.Header("Cache-Control", "public, max-age=3600")
-		c.Header("Content-Type", "image/svg+xml")
-		c.String(200, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 300 300"><rect fill="#1a1a2e" width="300" height="300"/><text x="150" y="160" font-family="Arial" font-size="48" fill="#4a4a6a" text-anchor="middle">♪</text></svg>`)
+		servePlaceholderArtwork(c)
 		return
 	}
 
@@ -148,6 +297,10 @@ func (h *ArtworkHandler) Upload(c *gin.Context) {
 
 	// Save and process artwork
 	if err := h.processor.SaveArtworkFromReader(id, file, contentType); err != nil {
+		if errors.Is(err, scanner.ErrArtworkTooLarge) {
+			BadRequest(c, "image dimensions too large")
+			return
+		}
 		InternalError(c, "failed to save artwork")
 		return
 	}
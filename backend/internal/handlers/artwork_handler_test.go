@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"harmony/internal/scanner"
+)
+
+// grayPNG encodes a 64x64 mid-gray image
+func grayPNG(t *testing.T) []byte {
+	t.Helper()
+	var data bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+		}
+	}
+	if err := png.Encode(&data, img); err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+func TestArtworkStatus(t *testing.T) {
+	cacheDir := t.TempDir()
+	env := newTestEnv(t, func(cfg *RouterConfig) { cfg.CacheDir = cacheDir })
+	env.seedLibrary()
+
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	for _, id := range []string{"al1", "al2"} {
+		if _, err := processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, id); err != nil {
+			t.Fatalf("caching artwork: %v", err)
+		}
+	}
+	colorPath := func(id string) string {
+		return filepath.Join(scanner.ArtworkCacheDir(cacheDir, scanner.ArtworkKindAlbum, id), "color")
+	}
+	// The saved color is served as is rather than worked out again
+	if err := os.WriteFile(colorPath("al1"), []byte("#123456"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Artwork cached before colors were saved has its color saved on first use
+	if err := os.Remove(colorPath("al2")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := env.do(http.MethodPost, "/api/v1/artwork/status", map[string]any{"albumIds": []string{"al1", "al2", "al3"}})
+	expectStatus(t, rec, http.StatusOK)
+	var statuses map[string]ArtworkStatus
+	decodeData(t, rec, &statuses)
+
+	tests := []struct {
+		id     string
+		exists bool
+		color  string
+	}{
+		{"al1", true, "#123456"},
+		{"al2", true, "#808080"},
+		{"al3", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			status := statuses[tt.id]
+			if status.Exists != tt.exists || status.DominantColor != tt.color {
+				t.Errorf("status = %+v, want exists %v and color %q", status, tt.exists, tt.color)
+			}
+			if tt.exists && len(status.Sizes) != len(scanner.AllArtworkSizes)+1 {
+				t.Errorf("sizes = %v, want every size and the original", status.Sizes)
+			}
+		})
+	}
+	if data, err := os.ReadFile(colorPath("al2")); err != nil || string(data) != "#808080" {
+		t.Errorf("saved color = %q, %v; want #808080", data, err)
+	}
+}
+
+func TestArtworkCachePaths(t *testing.T) {
+	cacheDir := t.TempDir()
+	env := newTestEnv(t, func(cfg *RouterConfig) { cfg.CacheDir = cacheDir })
+	env.seedLibrary()
+
+	// Albums and artists are cached by the processor; other kinds are
+	// written where the processor resolves them
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	cached := map[scanner.ArtworkKind]map[string]string{}
+	var err error
+	if cached[scanner.ArtworkKindAlbum], err = processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, "al1"); err != nil {
+		t.Fatalf("caching album artwork: %v", err)
+	}
+	if cached[scanner.ArtworkKindArtist], err = processor.CacheArtistImage(&scanner.ArtworkInfo{Data: grayPNG(t)}, "ar1"); err != nil {
+		t.Fatalf("caching artist image: %v", err)
+	}
+	sizes := []string{"original"}
+	for _, size := range scanner.AllArtworkSizes {
+		sizes = append(sizes, size.Name)
+	}
+	cached[scanner.ArtworkKindPlaylist] = map[string]string{}
+	for _, size := range sizes {
+		path := processor.CachePath(scanner.ArtworkKindPlaylist, "p1", size)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("playlist "+size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cached[scanner.ArtworkKindPlaylist][size] = path
+	}
+
+	tests := []struct {
+		kind scanner.ArtworkKind
+		id   string
+	}{
+		{scanner.ArtworkKindAlbum, "al1"},
+		{scanner.ArtworkKindArtist, "ar1"},
+		{scanner.ArtworkKindPlaylist, "p1"},
+	}
+	for _, tt := range tests {
+		for _, size := range sizes {
+			t.Run(string(tt.kind)+"/"+size, func(t *testing.T) {
+				path := cached[tt.kind][size]
+				if path != scanner.ArtworkCachePath(cacheDir, tt.kind, tt.id, size) {
+					t.Errorf("processor cached %s, want %s", path, scanner.ArtworkCachePath(cacheDir, tt.kind, tt.id, size))
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("reading cached artwork: %v", err)
+				}
+
+				rec := env.do(http.MethodGet, "/api/v1/artwork/"+string(tt.kind)+"/"+tt.id+"?size="+size, nil)
+				expectStatus(t, rec, http.StatusOK)
+				if !bytes.Equal(rec.Body.Bytes(), want) {
+					t.Errorf("handler served %d bytes, not the %d cached at %s", rec.Body.Len(), len(want), path)
+				}
+			})
+		}
+	}
+}
+
+func TestArtworkArtistFallback(t *testing.T) {
+	cacheDir := t.TempDir()
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	if _, err := processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, "al1"); err != nil {
+		t.Fatalf("caching artwork: %v", err)
+	}
+	albumCover, err := os.ReadFile(processor.CachePath(scanner.ArtworkKindAlbum, "al1", "medium"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ar1 has an image; ar2, the artist of al3, has none
+	artistImage := processor.CachePath(scanner.ArtworkKindArtist, "ar1", "medium")
+	if err := os.MkdirAll(filepath.Dir(artistImage), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(artistImage, []byte("artist image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		fallback     bool
+		album        string
+		body         string
+		cacheControl string
+	}{
+		{"album cover", true, "al1", string(albumCover), "public, max-age=31536000, immutable"},
+		{"artist image", true, "al2", "artist image", "public, max-age=3600"},
+		{"artist without an image", true, "al3", artworkPlaceholderSVG, "public, max-age=3600"},
+		{"fallback disabled", false, "al2", artworkPlaceholderSVG, "public, max-age=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *RouterConfig) {
+				cfg.CacheDir = cacheDir
+				cfg.ArtworkArtistFallback = tt.fallback
+			})
+			env.seedLibrary()
+
+			rec := env.do(http.MethodGet, "/api/v1/artwork/album/"+tt.album+"?size=medium", nil)
+			expectStatus(t, rec, http.StatusOK)
+			if rec.Body.String() != tt.body {
+				t.Errorf("served %d bytes of %s, want %d bytes", rec.Body.Len(), rec.Header().Get("Content-Type"), len(tt.body))
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+		})
+	}
+}
+
+func TestArtworkBatch(t *testing.T) {
+	cacheDir := t.TempDir()
+	env := newTestEnv(t, func(cfg *RouterConfig) { cfg.CacheDir = cacheDir })
+	env.seedLibrary()
+
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	for _, id := range []string{"al1", "al2"} {
+		if _, err := processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, id); err != nil {
+			t.Fatalf("caching artwork: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		body    any
+		want    int
+		size    scanner.ArtworkSize
+		images  []string
+		missing []string
+	}{
+		{"thumbnails by default", map[string]any{"albumIds": []string{"al1", "al2"}},
+			http.StatusOK, scanner.ArtworkSizeThumbnail, []string{"al1", "al2"}, nil},
+		{"small", map[string]any{"albumIds": []string{"al1"}, "size": "small"},
+			http.StatusOK, scanner.ArtworkSizeSmall, []string{"al1"}, nil},
+		{"missing and repeated", map[string]any{"albumIds": []string{"al1", "al3", "al1", "../al1"}},
+			http.StatusOK, scanner.ArtworkSizeThumbnail, []string{"al1"}, []string{"al3", "../al1"}},
+		{"size too large", map[string]any{"albumIds": []string{"al1"}, "size": "large"},
+			http.StatusBadRequest, scanner.ArtworkSize{}, nil, nil},
+		{"no albums", map[string]any{"size": "small"},
+			http.StatusBadRequest, scanner.ArtworkSize{}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodPost, "/api/v1/artwork/batch", tt.body)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var batch ArtworkBatchResponse
+			decodeData(t, rec, &batch)
+			if batch.Size != tt.size.Name || !slices.Equal(batch.Missing, tt.missing) || len(batch.Truncated) != 0 {
+				t.Errorf("batch = size %q, missing %v, truncated %v; want %q and %v",
+					batch.Size, batch.Missing, batch.Truncated, tt.size.Name, tt.missing)
+			}
+			if len(batch.Images) != len(tt.images) {
+				t.Errorf("batch has %d images, want %d", len(batch.Images), len(tt.images))
+			}
+			for _, id := range tt.images {
+				img, ok := batch.Images[id]
+				if !ok {
+					t.Errorf("no image for %s", id)
+					continue
+				}
+				data, err := base64.StdEncoding.DecodeString(img.Data)
+				if err != nil {
+					t.Fatalf("decoding %s: %v", id, err)
+				}
+				config, format, err := image.DecodeConfig(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("%s is not an image: %v", id, err)
+				}
+				if img.MIMEType != "image/"+format || config.Width > tt.size.Width || config.Height > tt.size.Height {
+					t.Errorf("%s is a %dx%d %s served as %s, want at most %dx%d",
+						id, config.Width, config.Height, format, img.MIMEType, tt.size.Width, tt.size.Height)
+				}
+			}
+		})
+	}
+}
+
+// pictureMP3 builds an MP3-looking file whose ID3v2.3 tag embeds a PNG
+// front cover
+func pictureMP3(picture []byte) []byte {
+	body := append([]byte{0}, "image/png\x00"...)
+	body = append(body, 3, 0)
+	body = append(body, picture...)
+	frame := append([]byte("APIC"), byte(len(body)>>24), byte(len(body)>>16), byte(len(body)>>8), byte(len(body)), 0, 0)
+	frame = append(frame, body...)
+	size := len(frame)
+	data := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	data = append(data, frame...)
+	return append(data, make([]byte, 1024)...)
+}
+
+func TestTrackArtwork(t *testing.T) {
+	cacheDir := t.TempDir()
+	processor := scanner.NewArtworkProcessor(cacheDir)
+	if _, err := processor.ProcessAndCache(&scanner.ArtworkInfo{Data: grayPNG(t)}, "al1"); err != nil {
+		t.Fatalf("caching artwork: %v", err)
+	}
+	albumCover, err := os.ReadFile(processor.CachePath(scanner.ArtworkKindAlbum, "al1", "medium"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A red picture, unlike the album's gray cover
+	var red bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	if err := png.Encode(&red, img); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		track   string
+		want    int
+		// body is "track" for the track's own artwork
+		body string
+	}{
+		{"embedded artwork", true, "t1", http.StatusOK, "track"},
+		{"no embedded artwork", true, "t2", http.StatusOK, string(albumCover)},
+		{"track artwork disabled", false, "t1", http.StatusOK, string(albumCover)},
+		{"no album cover", true, "t3", http.StatusOK, artworkPlaceholderSVG},
+		{"unknown track", true, "t9", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *RouterConfig) {
+				cfg.CacheDir = cacheDir
+				cfg.TrackArtwork = tt.enabled
+			})
+			env.seedLibrary()
+			files := map[string][]byte{"t1": pictureMP3(red.Bytes()), "t2": []byte("no tags"), "t3": []byte("no tags")}
+			for id, data := range files {
+				path := filepath.Join(env.mediaRoot, id+".mp3")
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				env.exec(`UPDATE tracks SET file_path = '` + path + `' WHERE id = '` + id + `'`)
+			}
+
+			rec := env.do(http.MethodGet, "/api/v1/tracks/"+tt.track+"/artwork?size=medium", nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			want := tt.body
+			if want == "track" {
+				data, err := os.ReadFile(processor.CachePath(scanner.ArtworkKindTrack, tt.track, "medium"))
+				if err != nil {
+					t.Fatalf("track artwork not cached: %v", err)
+				}
+				if bytes.Equal(data, albumCover) {
+					t.Fatal("track artwork is the album cover")
+				}
+				want = string(data)
+			}
+			if rec.Body.String() != want {
+				t.Errorf("served %d bytes, not the expected %d", rec.Body.Len(), len(want))
+			}
+		})
+	}
+}
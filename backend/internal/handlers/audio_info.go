@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/transcoder"
+)
+
+// audioInfoCacheSize bounds the in-process cache used when Redis isn't configured
+const audioInfoCacheSize = 512
+
+// AudioInfoResponse describes the codec and container of a track's file
+type AudioInfoResponse struct {
+	TrackID       string  `json:"trackId"`
+	Container     string  `json:"container"`
+	ContainerName string  `json:"containerName,omitempty"`
+	Codec         string  `json:"codec"`
+	CodecName     string  `json:"codecName,omitempty"`
+	Profile       string  `json:"profile,omitempty"`
+	BitDepth      int     `json:"bitDepth,omitempty"`
+	SampleFormat  string  `json:"sampleFormat,omitempty"`
+	SampleRate    int     `json:"sampleRate"`
+	Channels      int     `json:"channels"`
+	ChannelLayout string  `json:"channelLayout,omitempty"`
+	Bitrate       int     `json:"bitrate"`
+	Duration      float64 `json:"duration"`
+	Lossless      bool    `json:"lossless"`
+	HiRes         bool    `json:"hiRes"`
+}
+
+// newAudioInfoResponse builds the response for probed audio details. Hi-res
+// means lossless audio beyond CD quality (16-bit/44.1kHz).
+func newAudioInfoResponse(trackID string, info *transcoder.AudioInfo) AudioInfoResponse {
+	lossless := transcoder.IsLosslessCodec(info.Codec)
+	return AudioInfoResponse{
+		TrackID:       trackID,
+		Container:     info.Format,
+		ContainerName: info.FormatName,
+		Codec:         info.Codec,
+		CodecName:     info.CodecName,
+		Profile:       info.Profile,
+		BitDepth:      info.BitDepth,
+		SampleFormat:  info.SampleFormat,
+		SampleRate:    info.SampleRate,
+		Channels:      info.Channels,
+		ChannelLayout: info.ChannelLayout,
+		Bitrate:       info.Bitrate,
+		Duration:      info.Duration,
+		Lossless:      lossless,
+		HiRes:         lossless && (info.BitDepth > 16 || info.SampleRate > 44100),
+	}
+}
+
+// AudioInfo handles GET /api/v1/tracks/:id/audioinfo
+// Results are cached until the track is rescanned.
+func (h *TrackHandler) AudioInfo(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	track, err := h.repo.FindByID(ctx, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	version := track.ID + ":" + strconv.FormatInt(track.UpdatedAt.UnixNano(), 10)
+	if response, ok := h.audioInfo.get(version); ok {
+		Success(c, response)
+		return
+	}
+	if h.redis != nil {
+		var cached AudioInfoResponse
+		if err := h.redis.GetCachedAudioInfo(ctx, version, &cached); err == nil {
+			h.audioInfo.put(version, cached)
+			Success(c, cached)
+			return
+		}
+	}
+
+	if !h.transcoder.IsAvailable() {
+		Error(c, http.StatusServiceUnavailable, "UNAVAILABLE", "ffprobe not available")
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	info, err := h.transcoder.ProbeAudio(probeCtx, track.FilePath)
+	if err != nil {
+		InternalError(c, "failed to probe audio")
+		return
+	}
+
+	response := newAudioInfoResponse(track.ID, info)
+	if track.StartOffset > 0 || track.EndOffset > 0 {
+		// A track cut from a cue sheet's image lasts its segment, not the file
+		response.Duration = segmentDuration(info.Duration, track.StartOffset, track.EndOffset)
+	}
+	h.audioInfo.put(version, response)
+	if h.redis != nil {
+		h.redis.CacheAudioInfo(ctx, version, response)
+	}
+
+	Success(c, response)
+}
+
+// segmentDuration returns the seconds between two offsets in milliseconds
+// within a file lasting fileDuration seconds; an end of 0 is the end of the file
+func segmentDuration(fileDuration float64, startMs, endMs int) float64 {
+	end := fileDuration
+	if endMs > 0 {
+		end = float64(endMs) / 1000
+	}
+	return max(end-float64(startMs)/1000, 0)
+}
+
+// audioInfoCache is a small LRU of probe results keyed by track version
+type audioInfoCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type audioInfoEntry struct {
+	key   string
+	value AudioInfoResponse
+}
+
+// newAudioInfoCache creates a cache holding at most size entries
+func newAudioInfoCache(size int) *audioInfoCache {
+	return &audioInfoCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns a cached response and marks it as recently used
+func (c *audioInfoCache) get(key string) (AudioInfoResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return AudioInfoResponse{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*audioInfoEntry).value, true
+}
+
+// put stores a response, evicting the least recently used entry when full
+func (c *audioInfoCache) put(key string, value AudioInfoResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*audioInfoEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&audioInfoEntry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*audioInfoEntry).key)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"harmony/internal/transcoder"
+)
+
+// flacProbe is ffprobe's output for a 24-bit/96kHz FLAC file of 300 seconds
+const flacProbe = `{
+	"format": {"format_name": "flac", "format_long_name": "raw FLAC", "duration": "300.000000", "bit_rate": "2900000"},
+	"streams": [{"codec_type": "audio", "codec_name": "flac", "codec_long_name": "FLAC (Free Lossless Audio Codec)",
+		"sample_fmt": "s32", "sample_rate": "96000", "channels": 2, "channel_layout": "stereo", "bits_per_raw_sample": "24"}]
+}`
+
+// fakeProbe creates a transcoder whose ffprobe prints output and counts its
+// runs in the returned file
+func fakeProbe(t *testing.T, output string) (*transcoder.Transcoder, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	scripts := map[string]string{
+		"ffmpeg":  "#!/bin/sh\necho 'ffmpeg version 6.0-test'\n",
+		"ffprobe": "#!/bin/sh\necho run >> " + runs + "\ncat <<'EOF'\n" + output + "\nEOF\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: filepath.Join(dir, "ffmpeg"), CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+	return trans, runs
+}
+
+// probeRuns counts the runs of a fakeProbe's ffprobe
+func probeRuns(t *testing.T, runs string) int {
+	t.Helper()
+	data, err := os.ReadFile(runs)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+func TestAudioInfo(t *testing.T) {
+	trans, runs := fakeProbe(t, flacProbe)
+	env := newTestEnvWith(t, nil, trans)
+	env.seedLibrary()
+	// t2 and t3 are cut from one image by a cue sheet
+	env.exec(
+		`UPDATE tracks SET start_offset = 0, end_offset = 120500 WHERE id = 't2'`,
+		`UPDATE tracks SET start_offset = 120500, end_offset = 0 WHERE id = 't3'`,
+	)
+
+	tests := []struct {
+		name     string
+		id       string
+		want     int
+		duration float64
+	}{
+		{"whole file", "t1", http.StatusOK, 300},
+		{"first segment", "t2", http.StatusOK, 120.5},
+		{"last segment", "t3", http.StatusOK, 179.5},
+		{"cached", "t1", http.StatusOK, 300},
+		{"missing", "none", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/tracks/"+tt.id+"/audioinfo", nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var info AudioInfoResponse
+			decodeData(t, rec, &info)
+			if info.Codec != "flac" || info.Container != "flac" || info.BitDepth != 24 || info.SampleRate != 96000 ||
+				info.Channels != 2 || info.ChannelLayout != "stereo" || !info.Lossless || !info.HiRes {
+				t.Errorf("info = %+v", info)
+			}
+			if math.Abs(info.Duration-tt.duration) > 0.001 {
+				t.Errorf("duration = %v, want %v", info.Duration, tt.duration)
+			}
+		})
+	}
+	if n := probeRuns(t, runs); n != 3 {
+		t.Errorf("ffprobe ran %d times, want 3", n)
+	}
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+// authUserKey is the gin context key holding the authenticated user's ID
+const authUserKey = "authUserID"
+
+// maxPasswordBytes is the longest password bcrypt uses all of
+const maxPasswordBytes = 72
+
+// AuthHandler handles sign-up and sign-in, issuing signed tokens that
+// requireAuth checks on the rest of the API
+type AuthHandler struct {
+	users        *database.UserRepository
+	secret       []byte
+	tokenTTL     time.Duration
+	registration bool
+	timeFormat   TimeFormat
+}
+
+// NewAuthHandler creates a new AuthHandler. Tokens are signed with secret
+// and expire after tokenTTL; registration allows new accounts to be created.
+func NewAuthHandler(users *database.UserRepository, secret string, tokenTTL time.Duration, registration bool, timeFormat TimeFormat) *AuthHandler {
+	return &AuthHandler{
+		users:        users,
+		secret:       []byte(secret),
+		tokenTTL:     tokenTTL,
+		registration: registration,
+		timeFormat:   timeFormat,
+	}
+}
+
+// RegisterRequest is the body of POST /api/v1/auth/register. bcrypt only
+// uses the first 72 bytes of a password, so longer ones are refused; the
+// binding counts characters, so Register checks the bytes.
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// LoginRequest is the body of POST /api/v1/auth/login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// AuthResponse is a signed-in user and their token
+type AuthResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt string       `json:"expiresAt"`
+	User      UserResponse `json:"user"`
+}
+
+// UserResponse describes a user account
+type UserResponse struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	IsAdmin   bool   `json:"isAdmin"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Register handles POST /api/v1/auth/register
+// Creates an account and signs it in. The first account is the
+// administrator.
+func (h *AuthHandler) Register(c *gin.Context) {
+	if !h.registration {
+		Forbidden(c, "registration is disabled")
+		return
+	}
+
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if len(req.Password) > maxPasswordBytes {
+		BadRequest(c, "password must be at most 72 bytes")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	ctx := c.Request.Context()
+	if exists, err := h.users.ExistsByUsername(ctx, req.Username); err != nil {
+		InternalError(c, "failed to check username")
+		return
+	} else if exists {
+		Conflict(c, "username already taken")
+		return
+	}
+	if exists, err := h.users.ExistsByEmail(ctx, req.Email); err != nil {
+		InternalError(c, "failed to check email")
+		return
+	} else if exists {
+		Conflict(c, "email already registered")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		InternalError(c, "failed to hash password")
+		return
+	}
+
+	user := &models.User{
+		Username:     req.Username,
+		Email:        req.Email,
+		PasswordHash: string(hash),
+	}
+	if err := h.users.Create(ctx, user); err != nil {
+		// Someone else took the name or email since the checks above
+		if errors.Is(err, database.ErrUserAlreadyExists) {
+			Conflict(c, "username or email already registered")
+			return
+		}
+		InternalError(c, "failed to create user")
+		return
+	}
+
+	response, err := h.signIn(user)
+	if err != nil {
+		InternalError(c, "failed to issue token")
+		return
+	}
+	Created(c, response)
+}
+
+// Login handles POST /api/v1/auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	user, err := h.users.FindByUsername(c.Request.Context(), strings.TrimSpace(req.Username))
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			Unauthorized(c, "invalid username or password")
+			return
+		}
+		InternalError(c, "failed to get user")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		Unauthorized(c, "invalid username or password")
+		return
+	}
+
+	response, err := h.signIn(user)
+	if err != nil {
+		InternalError(c, "failed to issue token")
+		return
+	}
+	Success(c, response)
+}
+
+// signIn issues a token for user
+func (h *AuthHandler) signIn(user *models.User) (AuthResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(h.tokenTTL)
+	token, err := signToken(h.secret, tokenClaims{
+		Subject:   user.ID,
+		Username:  user.Username,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	return AuthResponse{
+		Token:     token,
+		ExpiresAt: h.timeFormat.Format(expiresAt),
+		User: UserResponse{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			IsAdmin:   user.IsAdmin,
+			CreatedAt: h.timeFormat.Format(user.CreatedAt),
+		},
+	}, nil
+}
+
+// requireAuth returns a middleware that rejects requests without a valid
+// token and records the user it was issued to for requestUserID. The token
+// is sent as "Authorization: Bearer <token>", or in the token query
+// parameter by clients that can't set headers, such as audio and image tags.
+func (h *AuthHandler) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if header := c.GetHeader("Authorization"); header != "" {
+			scheme, value, _ := strings.Cut(header, " ")
+			if !strings.EqualFold(scheme, "Bearer") {
+				Unauthorized(c, "unsupported authorization scheme")
+				c.Abort()
+				return
+			}
+			token = strings.TrimSpace(value)
+		}
+		if token == "" {
+			Unauthorized(c, "authentication required")
+			c.Abort()
+			return
+		}
+
+		claims, err := parseToken(h.secret, token, time.Now())
+		if err != nil {
+			if errors.Is(err, errTokenExpired) {
+				Unauthorized(c, "token expired")
+			} else {
+				Unauthorized(c, "invalid token")
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(authUserKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// requireAdmin returns a middleware that only lets administrators through.
+// It runs after requireAuth and looks the user up each time, so taking the
+// flag away applies to tokens already issued. With authentication disabled
+// there are no users: the request must carry adminToken as a bearer token,
+// and nothing gets through when it's empty.
+func (h *AuthHandler) requireAdmin(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := authenticatedUserID(c)
+		if !ok {
+			if adminToken == "" {
+				Forbidden(c, "admin routes require authentication or an admin token")
+				c.Abort()
+				return
+			}
+			scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") ||
+				subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(adminToken)) != 1 {
+				Unauthorized(c, "admin token required")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		user, err := h.users.FindByID(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, database.ErrUserNotFound) {
+				Unauthorized(c, "user no longer exists")
+			} else {
+				InternalError(c, "failed to get user")
+			}
+			c.Abort()
+			return
+		}
+		if !user.IsAdmin {
+			Forbidden(c, "admin access required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireUser returns a middleware refusing requests that aren't signed in.
+// Routes keeping per-user settings and secrets use it, since without
+// authentication the user is whoever the X-User-ID header names.
+func requireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authenticatedUserID(c); !ok {
+			Forbidden(c, "user settings require authentication")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticatedUserID returns the ID of the user a request's token was
+// issued to, if authentication is enabled
+func authenticatedUserID(c *gin.Context) (string, bool) {
+	id := c.GetString(authUserKey)
+	return id, id != ""
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	errTokenMalformed = errors.New("malformed token")
+	errTokenSignature = errors.New("invalid token signature")
+	errTokenExpired   = errors.New("token expired")
+)
+
+// tokenHeader is the encoded JOSE header of every token issued: HMAC-SHA256
+// signed JWTs
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// tokenClaims are the JWT claims identifying a signed-in user
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	Username  string `json:"name"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signToken issues a JWT carrying claims, signed with secret
+func signToken(secret []byte, claims tokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + tokenSignature(secret, unsigned), nil
+}
+
+// parseToken verifies a JWT issued by signToken and returns its claims.
+// Tokens signed with another algorithm or key, or expired at now, are
+// rejected.
+func parseToken(secret []byte, token string, now time.Time) (tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tokenClaims{}, errTokenMalformed
+	}
+	// Only the header signToken writes is accepted, which rules out
+	// "alg":"none" and algorithm confusion
+	if parts[0] != tokenHeader {
+		return tokenClaims{}, errTokenMalformed
+	}
+	expected := tokenSignature(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return tokenClaims{}, errTokenSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return tokenClaims{}, errTokenMalformed
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return tokenClaims{}, errTokenMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return tokenClaims{}, errTokenExpired
+	}
+	return claims, nil
+}
+
+// tokenSignature returns the encoded HMAC-SHA256 of a token's header and
+// payload
+func tokenSignature(secret []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
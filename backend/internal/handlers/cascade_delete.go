@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/scanner"
+)
+
+// DeletionResponse counts what deleting an album or artist removed
+type DeletionResponse struct {
+	DeletedAlbums int `json:"deletedAlbums"`
+	DeletedTracks int `json:"deletedTracks"`
+}
+
+// parseCascade reads ?cascade=, which defaults to refusing deletions that
+// would take tracks or albums with them
+func parseCascade(c *gin.Context) (bool, error) {
+	value := c.Query("cascade")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// removeDeletedArtwork removes the cached artwork of deleted entities. The
+// deletion is already committed, so failures are only logged.
+func removeDeletedArtwork(cacheDir string, deletion *database.Deletion, artistID string) {
+	dirs := make([]string, 0, len(deletion.AlbumIDs)+len(deletion.TrackIDs)+1)
+	if artistID != "" {
+		dirs = append(dirs, scanner.ArtworkCacheDir(cacheDir, scanner.ArtworkKindArtist, artistID))
+	}
+	for _, id := range deletion.AlbumIDs {
+		dirs = append(dirs, scanner.ArtworkCacheDir(cacheDir, scanner.ArtworkKindAlbum, id))
+	}
+	for _, id := range deletion.TrackIDs {
+		dirs = append(dirs, scanner.ArtworkCacheDir(cacheDir, scanner.ArtworkKindTrack, id))
+	}
+
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("removing deleted artwork failed", "dir", dir, "error", err)
+		}
+	}
+}
+
+// Delete handles DELETE /api/v1/admin/albums/:id
+// An album with tracks is refused with 409 unless ?cascade=true, which
+// deletes the tracks too and removes them from playlists. Files stay on
+// disk, so a later scan adds them back.
+func (h *AlbumHandler) Delete(c *gin.Context) {
+	cascade, err := parseCascade(c)
+	if err != nil {
+		BadRequest(c, "cascade must be true or false")
+		return
+	}
+
+	id := c.Param("id")
+	deletion, err := h.repo.DeleteCascade(c.Request.Context(), id, cascade)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrAlbumNotFound):
+			NotFound(c, "album")
+		case errors.Is(err, database.ErrAlbumHasTracks):
+			Conflict(c, "album has tracks; delete with cascade=true to remove them too")
+		default:
+			InternalError(c, "failed to delete album")
+		}
+		return
+	}
+	removeDeletedArtwork(h.cacheDir, deletion, "")
+
+	Success(c, DeletionResponse{
+		DeletedAlbums: len(deletion.AlbumIDs),
+		DeletedTracks: len(deletion.TrackIDs),
+	})
+}
+
+// Delete handles DELETE /api/v1/admin/artists/:id
+// An artist with albums or tracks is refused with 409 unless
+// ?cascade=true, which deletes their albums, every track on those albums
+// and the artist's tracks on other albums, removing the tracks from
+// playlists. Files stay on disk, so a later scan adds them back.
+func (h *ArtistHandler) Delete(c *gin.Context) {
+	cascade, err := parseCascade(c)
+	if err != nil {
+		BadRequest(c, "cascade must be true or false")
+		return
+	}
+
+	id := c.Param("id")
+	deletion, err := h.repo.DeleteCascade(c.Request.Context(), id, cascade)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrArtistNotFound):
+			NotFound(c, "artist")
+		case errors.Is(err, database.ErrArtistHasMusic):
+			Conflict(c, "artist has albums or tracks; delete with cascade=true to remove them too")
+		default:
+			InternalError(c, "failed to delete artist")
+		}
+		return
+	}
+	removeDeletedArtwork(h.cacheDir, deletion, id)
+
+	Success(c, DeletionResponse{
+		DeletedAlbums: len(deletion.AlbumIDs),
+		DeletedTracks: len(deletion.TrackIDs),
+	})
+}
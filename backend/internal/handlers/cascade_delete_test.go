@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCascadeDelete(t *testing.T) {
+	env := newTestEnv(t, withAuth)
+	admin := env.register("alice")
+	user := env.register("bob")
+	env.seedLibrary()
+	env.exec(
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES ('ar3', 'Nobody', datetime('now'), datetime('now'))`,
+		`INSERT INTO playlists (id, name, created_at, updated_at) VALUES ('p1', 'Mix', datetime('now'), datetime('now'))`,
+		`INSERT INTO playlist_tracks (playlist_id, track_id, position, added_at) VALUES ('p1', 't1', 0, datetime('now'))`,
+	)
+
+	// Steps run in order: the refused deletions leave everything in place
+	// for the cascading ones after them
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		want   int
+		albums int
+		tracks int
+	}{
+		{"not an administrator", "/api/v1/admin/albums/al1?cascade=true", user, http.StatusForbidden, 0, 0},
+		{"invalid cascade", "/api/v1/admin/albums/al1?cascade=maybe", admin, http.StatusBadRequest, 0, 0},
+		{"missing album", "/api/v1/admin/albums/none", admin, http.StatusNotFound, 0, 0},
+		{"album with tracks", "/api/v1/admin/albums/al1", admin, http.StatusConflict, 0, 0},
+		{"album cascade", "/api/v1/admin/albums/al1?cascade=true", admin, http.StatusOK, 1, 2},
+		{"missing artist", "/api/v1/admin/artists/none", admin, http.StatusNotFound, 0, 0},
+		{"artist with albums", "/api/v1/admin/artists/ar2", admin, http.StatusConflict, 0, 0},
+		{"artist cascade", "/api/v1/admin/artists/ar2?cascade=true", admin, http.StatusOK, 1, 1},
+		{"artist without music", "/api/v1/admin/artists/ar3", admin, http.StatusOK, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodDelete, tt.path, nil, bearer(tt.token)...)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var deletion DeletionResponse
+			decodeData(t, rec, &deletion)
+			if deletion.DeletedAlbums != tt.albums || deletion.DeletedTracks != tt.tracks {
+				t.Errorf("deleted %d albums and %d tracks, want %d and %d",
+					deletion.DeletedAlbums, deletion.DeletedTracks, tt.albums, tt.tracks)
+			}
+		})
+	}
+
+	var remaining []string
+	env.db.DB.Raw("SELECT id FROM tracks ORDER BY id").Scan(&remaining)
+	if len(remaining) != 1 || remaining[0] != "t3" {
+		t.Errorf("remaining tracks = %v, want [t3]", remaining)
+	}
+	var entries int64
+	env.db.DB.Raw("SELECT COUNT(*) FROM playlist_tracks").Scan(&entries)
+	if entries != 0 {
+		t.Errorf("%d playlist entries left for deleted tracks", entries)
+	}
+
+	// The old routes are gone
+	rec := env.do(http.MethodDelete, "/api/v1/albums/al2", nil, bearer(admin)...)
+	expectStatus(t, rec, http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressWriter buffers compressible responses until the size threshold is
+// reached, then switches to the negotiated encoding. Anything that isn't JSON
+// or text (audio streams, artwork) is passed straight through.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding    string
+	minSize     int
+	buf         bytes.Buffer
+	encoder     io.WriteCloser
+	passthrough bool
+}
+
+// Write implements io.Writer
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+
+	// First write decides whether this response is a candidate at all
+	if w.buf.Len() == 0 {
+		if w.Written() || !isCompressible(w.Header().Get("Content-Type")) {
+			w.passthrough = true
+			return w.ResponseWriter.Write(data)
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if w.encoding == "" {
+			w.passthrough = true
+			return w.ResponseWriter.Write(data)
+		}
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minSize {
+		if err := w.startEncoding(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString implements io.StringWriter
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends any buffered data to the client
+func (w *compressWriter) Flush() {
+	switch {
+	case w.encoder != nil:
+		if f, ok := w.encoder.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	case w.buf.Len() > 0:
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+		w.passthrough = true
+	}
+	w.ResponseWriter.Flush()
+}
+
+// startEncoding sets the encoding headers and drains the buffer into the encoder
+func (w *compressWriter) startEncoding() error {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", w.encoding)
+
+	switch w.encoding {
+	case "gzip":
+		w.encoder = gzip.NewWriter(w.ResponseWriter)
+	case "deflate":
+		fw, err := flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		w.encoder = fw
+	}
+
+	_, err := w.encoder.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish writes whatever is left once the handler chain has returned
+func (w *compressWriter) finish() {
+	if w.encoder != nil {
+		w.encoder.Close()
+		return
+	}
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// compressResponses returns a middleware that gzip/deflate encodes JSON and
+// text responses of at least minSize bytes based on Accept-Encoding
+func compressResponses(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "HEAD" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       negotiateEncoding(c.GetHeader("Accept-Encoding")),
+			minSize:        minSize,
+		}
+		c.Writer = writer
+		defer writer.finish()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q-values and preferring gzip when both are equally acceptable
+func negotiateEncoding(header string) string {
+	best := ""
+	bestQ := 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		switch name {
+		case "gzip", "deflate":
+			if q > bestQ || (q == bestQ && name == "gzip") {
+				best, bestQ = name, q
+			}
+		}
+	}
+
+	return best
+}
+
+// isCompressible reports whether a content type benefits from compression.
+// Event streams are excluded since buffering would delay delivery.
+func isCompressible(contentType string) bool {
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	return strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, "text/")
+}
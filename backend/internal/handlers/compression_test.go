@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressResponses(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	// Big enough to be compressed if it were JSON
+	audio := bytes.Repeat([]byte("ID3 audio frame "), 256)
+	path := filepath.Join(env.mediaRoot, "song.mp3")
+	if err := os.WriteFile(path, audio, 0644); err != nil {
+		t.Fatal(err)
+	}
+	env.exec(`UPDATE tracks SET file_path = '` + path + `' WHERE id = 't1'`)
+
+	t.Run("json list", func(t *testing.T) {
+		rec := env.do(http.MethodGet, "/api/v1/tracks", nil, "Accept-Encoding", "gzip")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+
+		reader, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("reading gzip body: %v", err)
+		}
+		var response Response
+		if err := json.NewDecoder(reader).Decode(&response); err != nil {
+			t.Fatalf("decoding gunzipped body: %v", err)
+		}
+		if tracks, _ := response.Data.([]any); len(tracks) != 4 {
+			t.Errorf("got %d tracks, want 4", len(tracks))
+		}
+	})
+
+	t.Run("json without gzip", func(t *testing.T) {
+		rec := env.do(http.MethodGet, "/api/v1/tracks", nil)
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if !json.Valid(rec.Body.Bytes()) {
+			t.Errorf("body is not plain JSON")
+		}
+	})
+
+	t.Run("audio stream", func(t *testing.T) {
+		rec := env.do(http.MethodGet, "/api/v1/tracks/t1/stream", nil, "Accept-Encoding", "gzip")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		body, _ := io.ReadAll(rec.Body)
+		if !bytes.Equal(body, audio) {
+			t.Errorf("streamed %d bytes, want the %d byte file unchanged", len(body), len(audio))
+		}
+	})
+}
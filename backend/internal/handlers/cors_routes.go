@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"path"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// publicMediaRoutes are the media endpoints that players and pages on other
+// sites load directly, such as cast receivers and embedded audio and image
+// tags. They can be opened to more origins than the rest of the API.
+// Patterns are matched with path.Match, so * stands for one path segment.
+var publicMediaRoutes = []string{
+	"/api/v1/tracks/*/stream",
+	"/api/v1/tracks/*/artwork",
+	"/api/v1/artwork/*/*",
+}
+
+// isPublicMediaRoute reports whether a request path is one of publicMediaRoutes
+func isPublicMediaRoute(requestPath string) bool {
+	for _, pattern := range publicMediaRoutes {
+		if ok, _ := path.Match(pattern, requestPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// configurePublicCORS returns the CORS policy of the public media routes.
+// They are read-only and the policy doesn't allow credentialed requests, so
+// browsers send other origins no cookies or Authorization headers. With
+// authentication on, a request still needs a token in the URL, which grants
+// access to whoever holds it: "*" exposes no more than a leaked link would.
+func configurePublicCORS(allowedOrigins []string) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:  allowedOrigins,
+		AllowMethods:  []string{"GET", "HEAD", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Accept", "Range"},
+		ExposeHeaders: []string{"Content-Length", "Content-Range", "Accept-Ranges", "X-Stream-Offset", placeholderHeader},
+		MaxAge:        12 * time.Hour,
+	})
+}
+
+// routeCORS applies the public policy to the public media routes and the
+// API policy to everything else. It runs for unmatched routes as well, so
+// preflight requests get the policy of the route they ask about.
+func routeCORS(api, public gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isPublicMediaRoute(c.Request.URL.Path) {
+			public(c)
+			return
+		}
+		api(c)
+	}
+}
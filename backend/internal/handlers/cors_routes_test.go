@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsPublicMediaRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/api/v1/tracks/t1/stream", true},
+		{"/api/v1/tracks/t1/artwork", true},
+		{"/api/v1/artwork/album/al1", true},
+		{"/api/v1/tracks/t1", false},
+		{"/api/v1/tracks/t1/stream/extra", false},
+		{"/api/v1/artwork/batch", false},
+		{"/api/v1/playlists", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isPublicMediaRoute(tt.path); got != tt.want {
+				t.Errorf("isPublicMediaRoute(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteCORS(t *testing.T) {
+	const app, other = "https://app.example.com", "https://cast.example.net"
+	withPublicOrigins := func(origins ...string) func(*RouterConfig) {
+		return func(cfg *RouterConfig) {
+			cfg.AllowedOrigins = []string{app}
+			cfg.PublicOrigins = origins
+		}
+	}
+	public := newTestEnv(t, withPublicOrigins("*"))
+	public.seedLibrary()
+	// Without public origins every route has the API policy
+	locked := newTestEnv(t, withPublicOrigins())
+
+	tests := []struct {
+		name        string
+		env         *testEnv
+		method      string
+		path        string
+		origin      string
+		allowOrigin string
+		credentials bool
+	}{
+		{"stream preflight from another site", public, http.MethodOptions, "/api/v1/tracks/t1/stream", other, "*", false},
+		{"artwork from another site", public, http.MethodGet, "/api/v1/artwork/album/al1", other, "*", false},
+		{"playlists from another site", public, http.MethodGet, "/api/v1/playlists", other, "", false},
+		{"playlists preflight from another site", public, http.MethodOptions, "/api/v1/playlists", other, "", false},
+		{"playlists from the app", public, http.MethodGet, "/api/v1/playlists", app, app, true},
+		{"stream from another site when locked", locked, http.MethodOptions, "/api/v1/tracks/t1/stream", other, "", false},
+		{"stream from the app when locked", locked, http.MethodOptions, "/api/v1/tracks/t1/stream", app, app, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := []string{"Origin", tt.origin}
+			if tt.method == http.MethodOptions {
+				headers = append(headers, "Access-Control-Request-Method", http.MethodGet)
+			}
+			rec := tt.env.do(tt.method, tt.path, nil, headers...)
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.credentials)
+			}
+		})
+	}
+}
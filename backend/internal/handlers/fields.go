@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/models"
+)
+
+// fieldSet is a response object keyed by JSON field name. Its keys double as
+// the whitelist of fields a client may select with ?fields=.
+type fieldSet map[string]interface{}
+
+// fields returns every selectable field of the track
+func (t TrackResponse) fields() fieldSet {
+	return fieldSet{
+		"id":             t.ID,
+		"title":          t.Title,
+		"duration":       t.Duration,
+		"trackNumber":    t.TrackNumber,
+		"discNumber":     t.DiscNumber,
+		"totalTracks":    t.TotalTracks,
+		"totalDiscs":     t.TotalDiscs,
+		"format":         t.Format,
+		"lossless":       t.Lossless,
+		"bitrate":        t.Bitrate,
+		"albumId":        t.AlbumID,
+		"artistId":       t.ArtistID,
+		"artistName":     t.ArtistName,
+		"albumTitle":     t.AlbumTitle,
+		"genre":          t.Genre,
+		"inferredGenre":  t.InferredGenre,
+		"year":           t.Year,
+		"rating":         t.Rating,
+		"bpm":            t.BPM,
+		"musicalKey":     t.MusicalKey,
+		"trackGain":      t.TrackGain,
+		"albumGain":      t.AlbumGain,
+		"encoderDelay":   t.EncoderDelay,
+		"encoderPadding": t.EncoderPadding,
+		"playCount":      t.PlayCount,
+		"skipCount":      t.SkipCount,
+		"lastPlayedAt":   t.LastPlayed,
+		"addedAt":        t.AddedAt,
+		"fileModifiedAt": t.FileModifiedAt,
+		"links":          t.Links,
+	}
+}
+
+// fields returns every selectable field of the album
+func (a AlbumResponse) fields() fieldSet {
+	return fieldSet{
+		"id":          a.ID,
+		"title":       a.Title,
+		"edition":     a.Edition,
+		"year":        a.Year,
+		"albumType":   a.AlbumType,
+		"totalTracks": a.TotalTracks,
+		"totalDiscs":  a.TotalDiscs,
+		"artistId":    a.ArtistID,
+		"artistName":  a.ArtistName,
+		"trackCount":  a.TrackCount,
+		"duration":    a.Duration,
+		"coverArtUrl": a.CoverArtURL,
+		"links":       a.Links,
+	}
+}
+
+// only returns the named fields. Requested fields are always present, even
+// when their value is empty.
+func (f fieldSet) only(names []string) fieldSet {
+	selected := make(fieldSet, len(names))
+	for _, name := range names {
+		selected[name] = f[name]
+	}
+	return selected
+}
+
+// parseFields reads the comma-separated fields query parameter, checking each
+// name against allowed. Returns nil when the parameter is absent or empty,
+// meaning the full object should be returned.
+func parseFields(c *gin.Context, allowed fieldSet) ([]string, error) {
+	raw := strings.TrimSpace(c.Query("fields"))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// trackExpansions are the related names a track response inlines
+type trackExpansions struct {
+	artist bool
+	album  bool
+}
+
+// parseTrackExpand reads the comma-separated expand query parameter
+// ("artist", "album"). The names come from the relations the track queries
+// already preload, so expanding costs no extra queries.
+func parseTrackExpand(c *gin.Context) (trackExpansions, error) {
+	var expand trackExpansions
+	raw := strings.TrimSpace(c.Query("expand"))
+	if raw == "" {
+		return expand, nil
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "artist":
+			expand.artist = true
+		case "album":
+			expand.album = true
+		default:
+			return expand, fmt.Errorf("unknown expansion: %s", strings.TrimSpace(name))
+		}
+	}
+	return expand, nil
+}
+
+// apply fills the expanded names of a track response
+func (e trackExpansions) apply(response *TrackResponse, track models.Track) {
+	if e.artist && track.Artist != nil {
+		response.ArtistName = track.Artist.Name
+	}
+	if e.album && track.Album != nil {
+		response.AlbumTitle = track.Album.Title
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	tests := []struct {
+		name  string
+		path  string
+		want  int
+		items int
+		// keys are the fields every item must have exactly; nil expects the
+		// full object
+		keys []string
+	}{
+		{"track fields", "/api/v1/tracks?fields=id,title,duration", http.StatusOK, 4, []string{"duration", "id", "title"}},
+		{"repeated and blank", "/api/v1/tracks?fields=id,+title,,id", http.StatusOK, 4, []string{"id", "title"}},
+		{"unknown track field", "/api/v1/tracks?fields=id,filePath", http.StatusBadRequest, 0, nil},
+		{"all track fields", "/api/v1/tracks", http.StatusOK, 4, nil},
+		{"album fields", "/api/v1/albums?fields=id,artistName", http.StatusOK, 3, []string{"artistName", "id"}},
+		{"empty album field", "/api/v1/albums?fields=edition", http.StatusOK, 3, []string{"edition"}},
+		{"unknown album field", "/api/v1/albums?fields=tracks", http.StatusBadRequest, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var items []map[string]any
+			decodeData(t, rec, &items)
+			if len(items) != tt.items {
+				t.Fatalf("got %d items, want %d", len(items), tt.items)
+			}
+			for _, item := range items {
+				var keys []string
+				for key := range item {
+					keys = append(keys, key)
+				}
+				slices.Sort(keys)
+				if tt.keys == nil {
+					if !slices.Contains(keys, "title") || !slices.Contains(keys, "links") {
+						t.Errorf("fields = %v, want the full object", keys)
+					}
+					continue
+				}
+				if !slices.Equal(keys, tt.keys) {
+					t.Errorf("fields = %v, want %v", keys, tt.keys)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackExpand(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	albums := map[string]string{"t1": "First", "t2": "First", "t3": "Hit", "t4": "Comp"}
+	tests := []struct {
+		name   string
+		query  string
+		want   int
+		artist bool
+		album  bool
+	}{
+		{"not expanded", "", http.StatusOK, false, false},
+		{"artist", "?expand=artist", http.StatusOK, true, false},
+		{"album", "?expand=album", http.StatusOK, false, true},
+		{"both", "?expand=artist,+album", http.StatusOK, true, true},
+		{"with fields", "?expand=artist&fields=id,artistName,albumTitle", http.StatusOK, true, false},
+		{"unknown", "?expand=genre", http.StatusBadRequest, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/tracks"+tt.query, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var tracks []map[string]any
+			decodeData(t, rec, &tracks)
+			if len(tracks) != 4 {
+				t.Fatalf("got %d tracks, want 4", len(tracks))
+			}
+			for _, track := range tracks {
+				id, _ := track["id"].(string)
+				// Requested fields are listed even when empty
+				artist, _ := track["artistName"].(string)
+				album, _ := track["albumTitle"].(string)
+				wantArtist, wantAlbum := "", ""
+				if tt.artist {
+					wantArtist = "Band"
+				}
+				if tt.album {
+					wantAlbum = albums[id]
+				}
+				if artist != wantArtist || album != wantAlbum {
+					t.Errorf("%s names = %q, %q; want %q, %q", id, artist, album, wantArtist, wantAlbum)
+				}
+				if tt.query == "" {
+					_, hasArtist := track["artistName"]
+					_, hasAlbum := track["albumTitle"]
+					if hasArtist || hasAlbum {
+						t.Errorf("%s lists names without expand", id)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/services"
+	"harmony/internal/transcoder"
+)
+
+// testSecret signs tokens in tests with authentication enabled
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// testEnv is a router over a fresh SQLite database
+type testEnv struct {
+	t         *testing.T
+	db        *database.Database
+	lib       *services.LibraryService
+	router    *gin.Engine
+	mediaRoot string
+}
+
+func TestMain(m *testing.M) {
+	// Keep the request log out of test output
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// newTestDB opens and migrates a SQLite database in a temp directory. A
+// single connection keeps PRAGMA foreign_keys in effect for every query.
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(database.Config{
+		Path:        filepath.Join(t.TempDir(), "harmony.db"),
+		MaxOpenConn: 1,
+		MaxIdleConn: 1,
+	})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	return db
+}
+
+// newTestEnv builds a router with the default configuration, changed by
+// configure when it isn't nil
+func newTestEnv(t *testing.T, configure func(*RouterConfig)) *testEnv {
+	t.Helper()
+	return newTestEnvWith(t, configure, nil)
+}
+
+// newTestEnvWith builds a router like newTestEnv using trans for transcoding
+// and probing
+func newTestEnvWith(t *testing.T, configure func(*RouterConfig), trans *transcoder.Transcoder) *testEnv {
+	t.Helper()
+	db := newTestDB(t)
+	mediaRoot := t.TempDir()
+
+	cfg := DefaultRouterConfig()
+	cfg.MediaRoot = mediaRoot
+	cfg.CacheDir = t.TempDir()
+	cfg.BackupDir = t.TempDir()
+	cfg.JWTSecret = testSecret
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	// The library caches artwork where the router serves it from, as in main
+	lib := services.NewLibraryService(mediaRoot, cfg.CacheDir,
+		database.NewTrackRepository(db.DB),
+		database.NewAlbumRepository(db.DB),
+		database.NewArtistRepository(db.DB),
+		database.NewScanFailureRepository(db.DB))
+	lib.SetSettings(database.NewSettingsRepository(db.DB))
+
+	return &testEnv{t: t, db: db, lib: lib, router: NewRouter(cfg, db, nil, trans, lib), mediaRoot: mediaRoot}
+}
+
+// withAuth enables authentication
+func withAuth(cfg *RouterConfig) {
+	cfg.AuthEnabled = true
+}
+
+// exec runs SQL statements against the test database
+func (e *testEnv) exec(statements ...string) {
+	e.t.Helper()
+	for _, stmt := range statements {
+		if err := e.db.DB.Exec(stmt).Error; err != nil {
+			e.t.Fatalf("executing %q: %v", stmt, err)
+		}
+	}
+}
+
+// seedLibrary adds two artists, three albums and four tracks:
+//
+//	ar1 "Band": al1 "First" (album, t1 and t2), al2 "Hit" (single, t3)
+//	ar2 "Various Artists": al3 "Comp" (compilation, t4 by ar1)
+func (e *testEnv) seedLibrary() {
+	e.t.Helper()
+	e.exec(
+		`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('ar1', 'Band', datetime('now'), datetime('now')),
+			('ar2', 'Various Artists', datetime('now'), datetime('now'))`,
+		`INSERT INTO albums (id, title, year, album_type, artist_id, created_at, updated_at) VALUES
+			('al1', 'First', 2001, 'album', 'ar1', datetime('now'), datetime('now')),
+			('al2', 'Hit', 2003, 'single', 'ar1', datetime('now'), datetime('now')),
+			('al3', 'Comp', 2005, 'compilation', 'ar2', datetime('now'), datetime('now'))`,
+		`INSERT INTO tracks (id, title, duration, track_number, disc_number, file_path, file_size,
+			format, album_id, artist_id, genre, year, created_at, updated_at) VALUES
+			('t1', 'One', 200, 1, 1, '/a/1.mp3', 100, 'mp3', 'al1', 'ar1', 'Rock', 2001, datetime('now'), datetime('now')),
+			('t2', 'Two', 180, 2, 1, '/a/2.mp3', 100, 'mp3', 'al1', 'ar1', 'Jazz', 2001, datetime('now'), datetime('now')),
+			('t3', 'Hit', 210, 1, 1, '/a/3.mp3', 100, 'mp3', 'al2', 'ar1', 'Rock', 2003, datetime('now'), datetime('now')),
+			('t4', 'Cover', 240, 1, 1, '/a/4.mp3', 100, 'mp3', 'al3', 'ar1', 'Pop', 2005, datetime('now'), datetime('now'))`,
+	)
+}
+
+// do sends a request, JSON-encoding body unless it's nil, with headers
+// given as name, value pairs
+func (e *testEnv) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatalf("encoding body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// register creates an account and returns its token
+func (e *testEnv) register(username string) string {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "correct horse",
+	})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("registering %s: status %d: %s", username, rec.Code, rec.Body)
+	}
+	var auth AuthResponse
+	decodeData(e.t, rec, &auth)
+	return auth.Token
+}
+
+// bearer returns the header pair authenticating with token
+func bearer(token string) []string {
+	return []string{"Authorization", "Bearer " + token}
+}
+
+// decodeData decodes the data of a success envelope into v
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body, err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("decoding data %q: %v", envelope.Data, err)
+	}
+}
+
+// errorCode returns the code of an error envelope
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body, err)
+	}
+	if response.Error == nil {
+		return ""
+	}
+	return response.Error.Code
+}
+
+// expectStatus fails the test when rec has another status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, want, strings.TrimSpace(rec.Body.String()))
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+// IndexHandler serves the compact catalog index
+type IndexHandler struct {
+	artistRepo *database.ArtistRepository
+	trackRepo  *database.TrackRepository
+}
+
+// NewIndexHandler creates a new IndexHandler
+func NewIndexHandler(artistRepo *database.ArtistRepository, trackRepo *database.TrackRepository) *IndexHandler {
+	return &IndexHandler{
+		artistRepo: artistRepo,
+		trackRepo:  trackRepo,
+	}
+}
+
+// IndexTrack is a track in the catalog index
+type IndexTrack struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	DiscNumber  int    `json:"discNumber"`
+	TrackNumber int    `json:"trackNumber"`
+	Duration    int    `json:"duration"`
+}
+
+// IndexAlbum is an album in the catalog index
+type IndexAlbum struct {
+	ID     string       `json:"id"`
+	Title  string       `json:"title"`
+	Year   int          `json:"year,omitempty"`
+	Tracks []IndexTrack `json:"tracks"`
+}
+
+// IndexArtist is an artist in the catalog index, with the albums filed
+// under it as album artist and its tracks that aren't on any album
+type IndexArtist struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Albums []IndexAlbum `json:"albums"`
+	Tracks []IndexTrack `json:"tracks,omitempty"`
+}
+
+// LibraryIndexResponse is one page of the catalog index. Version identifies
+// the state of the whole library, not just the page.
+type LibraryIndexResponse struct {
+	Version int64         `json:"version"`
+	Artists []IndexArtist `json:"artists"`
+}
+
+// Index handles GET /api/v1/index
+// Returns the catalog as artist → albums → tracks with minimal fields, paged
+// by artist, for clients mirroring the library. The version is also sent as
+// the ETag, so a client can revalidate with If-None-Match and get 304 Not
+// Modified while nothing changed.
+func (h *IndexHandler) Index(c *gin.Context) {
+	pagination := ParsePagination(c)
+
+	version, err := h.trackRepo.LibraryVersion(c.Request.Context())
+	if err != nil {
+		InternalError(c, "failed to get library version")
+		return
+	}
+
+	etag := `"` + strconv.FormatInt(version, 10) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	artists, total, err := h.artistRepo.ListCatalog(c.Request.Context(), pagination.Page, pagination.Limit)
+	if err != nil {
+		InternalError(c, "failed to build library index")
+		return
+	}
+
+	response := LibraryIndexResponse{
+		Version: version,
+		Artists: make([]IndexArtist, len(artists)),
+	}
+	for i, artist := range artists {
+		entry := IndexArtist{
+			ID:     artist.ID,
+			Name:   artist.Name,
+			Albums: make([]IndexAlbum, len(artist.Albums)),
+			Tracks: newIndexTracks(artist.Tracks),
+		}
+		for j, album := range artist.Albums {
+			entry.Albums[j] = IndexAlbum{
+				ID:     album.ID,
+				Title:  album.Title,
+				Year:   album.Year,
+				Tracks: newIndexTracks(album.Tracks),
+			}
+		}
+		response.Artists[i] = entry
+	}
+
+	SuccessWithPagination(c, response, NewPagination(pagination.Page, pagination.Limit, total))
+}
+
+// newIndexTracks converts tracks to their index entries
+func newIndexTracks(tracks []models.Track) []IndexTrack {
+	entries := make([]IndexTrack, len(tracks))
+	for i, track := range tracks {
+		entries[i] = IndexTrack{
+			ID:          track.ID,
+			Title:       track.Title,
+			DiscNumber:  track.DiscNumber,
+			TrackNumber: track.TrackNumber,
+			Duration:    track.Duration,
+		}
+	}
+	return entries
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	env.exec(`INSERT INTO tracks (id, title, duration, file_path, file_size, format, artist_id, created_at, updated_at)
+		VALUES ('t5', 'Loose', 100, '/a/5.mp3', 100, 'mp3', 'ar1', datetime('now'), datetime('now'))`)
+
+	rec := env.do(http.MethodGet, "/api/v1/index", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var index LibraryIndexResponse
+	decodeData(t, rec, &index)
+
+	if len(index.Artists) != 2 {
+		t.Fatalf("index has %d artists, want 2", len(index.Artists))
+	}
+	band := index.Artists[0]
+	if band.ID != "ar1" || len(band.Albums) != 2 || len(band.Albums[0].Tracks) != 2 {
+		t.Errorf("artist ar1 = %+v, want albums al1 (two tracks) and al2", band)
+	}
+	if len(band.Tracks) != 1 || band.Tracks[0].ID != "t5" {
+		t.Errorf("loose tracks of ar1 = %+v, want t5", band.Tracks)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	// Each step runs against the state the previous one left
+	tests := []struct {
+		name     string
+		mutation string
+		want     int
+	}{
+		{"unchanged", "", http.StatusNotModified},
+		// Column updates that leave updated_at alone still count
+		{"play recorded", `UPDATE tracks SET play_count = play_count + 1 WHERE id = 't1'`, http.StatusOK},
+		{"track deleted", `DELETE FROM tracks WHERE id = 't5'`, http.StatusOK},
+		{"album renamed", `UPDATE albums SET title = 'Second' WHERE id = 'al1'`, http.StatusOK},
+		{"playlist created", `INSERT INTO playlists (id, name, created_at, updated_at) VALUES ('p1', 'Mix', datetime('now'), datetime('now'))`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mutation != "" {
+				env.exec(tt.mutation)
+			}
+			rec := env.do(http.MethodGet, "/api/v1/index", nil, "If-None-Match", etag)
+			expectStatus(t, rec, tt.want)
+			if tt.want == http.StatusOK {
+				var changed LibraryIndexResponse
+				decodeData(t, rec, &changed)
+				if changed.Version <= index.Version {
+					t.Errorf("version = %d, want more than %d", changed.Version, index.Version)
+				}
+				index.Version = changed.Version
+				etag = rec.Header().Get("ETag")
+			}
+		})
+	}
+}
+
+func TestIndexPages(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	// shape writes an artist as id[album(tracks) ...] with loose tracks last
+	shape := func(artists []IndexArtist) []string {
+		var shapes []string
+		for _, artist := range artists {
+			var albums []string
+			for _, album := range artist.Albums {
+				var tracks []string
+				for _, track := range album.Tracks {
+					tracks = append(tracks, track.ID)
+				}
+				albums = append(albums, album.ID+"("+strings.Join(tracks, ",")+")")
+			}
+			for _, track := range artist.Tracks {
+				albums = append(albums, track.ID)
+			}
+			shapes = append(shapes, artist.ID+"["+strings.Join(albums, " ")+"]")
+		}
+		return shapes
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		artists []string
+		hasMore bool
+	}{
+		{"everything", "", []string{"ar1[al1(t1,t2) al2(t3)]", "ar2[al3(t4)]"}, false},
+		{"first page", "?limit=1", []string{"ar1[al1(t1,t2) al2(t3)]"}, true},
+		{"second page", "?limit=1&page=2", []string{"ar2[al3(t4)]"}, false},
+		{"past the end", "?limit=1&page=3", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/index"+tt.query, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var index LibraryIndexResponse
+			decodeData(t, rec, &index)
+			if got := shape(index.Artists); !slices.Equal(got, tt.artists) {
+				t.Errorf("artists = %v, want %v", got, tt.artists)
+			}
+			if index.Version == 0 {
+				t.Error("no version")
+			}
+
+			var envelope Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Meta == nil || envelope.Meta.Pagination == nil ||
+				envelope.Meta.Pagination.Total != 2 || envelope.Meta.Pagination.HasMore != tt.hasMore {
+				t.Errorf("meta = %+v, want a total of 2 and more %v", envelope.Meta, tt.hasMore)
+			}
+		})
+	}
+}
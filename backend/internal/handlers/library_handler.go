@@ -1,28 +1,45 @@
 package handlers
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"harmony/internal/database"
 	"harmony/internal/services"
 )
 
 // LibraryHandler handles library management endpoints
 type LibraryHandler struct {
-	service *services.LibraryService
+	service        *services.LibraryService
+	baseURL        string
+	maxUploadSize  int64
+	allowedOrigins []string
+	timeFormat     TimeFormat
 }
 
-// NewLibraryHandler creates a new LibraryHandler
-func NewLibraryHandler(service *services.LibraryService) *LibraryHandler {
-	return &LibraryHandler{service: service}
+// NewLibraryHandler creates a new LibraryHandler. allowedOrigins are the
+// cross-origin pages that may open the scan event socket.
+func NewLibraryHandler(service *services.LibraryService, baseURL string, maxUploadSize int64, allowedOrigins []string, timeFormat TimeFormat) *LibraryHandler {
+	return &LibraryHandler{
+		service:        service,
+		baseURL:        baseURL,
+		maxUploadSize:  maxUploadSize,
+		allowedOrigins: allowedOrigins,
+		timeFormat:     timeFormat,
+	}
 }
 
 // ScanRequest represents a scan request
 type ScanRequest struct {
 	Incremental bool `json:"incremental"`
+	// Force retries files skipped after repeated failures
+	Force bool `json:"force"`
 }
 
 // Scan handles POST /api/v1/library/scan
@@ -34,30 +51,35 @@ func (h *LibraryHandler) Scan(c *gin.Context) {
 	if c.Query("type") == "incremental" {
 		req.Incremental = true
 	}
-
-	// Check if scan is already in progress
-	if h.service.IsScanning() {
-		Conflict(c, "scan already in progress")
-		return
+	if c.Query("force") == "true" {
+		req.Force = true
 	}
 
-	// Start scan in background
-	// Use background context since the HTTP request context will be cancelled
-	// when the response is sent, but we want the scan to continue
-	go func() {
-		ctx := context.Background()
-		if req.Incremental {
-			h.service.IncrementalScan(ctx)
-		} else {
-			h.service.FullScan(ctx)
+	// Start the scan in the background, or queue it behind a running one
+	status, position, err := h.service.StartScan(req.Incremental, req.Force)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrScanInProgress):
+			Conflict(c, "scan already in progress")
+		case errors.Is(err, services.ErrScanQueueFull):
+			Conflict(c, "scan queue is full")
+		default:
+			InternalError(c, "failed to start scan")
 		}
-	}()
+		return
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{
+	body := gin.H{
 		"success": true,
 		"message": "scan started",
+		"status":  status,
 		"type":    map[bool]string{true: "incremental", false: "full"}[req.Incremental],
-	})
+	}
+	if status == services.ScanStatusQueued {
+		body["message"] = "scan queued"
+		body["position"] = position
+	}
+	c.JSON(http.StatusAccepted, body)
 }
 
 // ScanStatus handles GET /api/v1/library/scan/status
@@ -65,17 +87,22 @@ func (h *LibraryHandler) ScanStatus(c *gin.Context) {
 	progress := h.service.GetProgress()
 
 	Success(c, gin.H{
-		"status":         progress.Status,
-		"totalFiles":     progress.TotalFiles,
-		"processedFiles": progress.ProcessedFiles,
-		"newTracks":      progress.NewTracks,
-		"updatedTracks":  progress.UpdatedTracks,
-		"deletedTracks":  progress.DeletedTracks,
-		"errorCount":     progress.ErrorCount,
-		"currentFile":    progress.CurrentFile,
-		"startedAt":      progress.StartedAt,
-		"completedAt":    progress.CompletedAt,
-		"duration":       progress.Duration,
+		"status":           progress.Status,
+		"totalFiles":       progress.TotalFiles,
+		"processedFiles":   progress.ProcessedFiles,
+		"newTracks":        progress.NewTracks,
+		"updatedTracks":    progress.UpdatedTracks,
+		"deletedTracks":    progress.DeletedTracks,
+		"skippedFiles":     progress.SkippedFiles,
+		"errorCount":       progress.ErrorCount,
+		"errors":           progress.Errors,
+		"errorsByCategory": progress.ErrorsByCategory,
+		"currentFile":      progress.CurrentFile,
+		"startedAt":        h.timeFormat.Format(progress.StartedAt),
+		"completedAt":      h.timeFormat.Format(progress.CompletedAt),
+		"duration":         progress.Duration,
+		"queuedScans":      progress.QueuedScans,
+		"queue":            progress.Queue,
 	})
 }
 
@@ -110,6 +137,180 @@ func (h *LibraryHandler) Stats(c *gin.Context) {
 		"totalArtists":  stats.TotalArtists,
 		"totalDuration": stats.TotalDuration,
 		"totalSize":     stats.TotalSize,
-		"lastScanAt":    stats.LastScanAt,
+		"lastScanAt":    h.timeFormat.Format(stats.LastScanAt),
 	})
 }
+
+// Upload handles POST /api/v1/library/upload
+func (h *LibraryHandler) Upload(c *gin.Context) {
+	// Leave room for the multipart envelope around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize+1<<20)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			BadRequest(c, fmt.Sprintf("file too large (max %dMB)", h.maxUploadSize>>20))
+			return
+		}
+		BadRequest(c, "audio file required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxUploadSize {
+		BadRequest(c, fmt.Sprintf("file too large (max %dMB)", h.maxUploadSize>>20))
+		return
+	}
+
+	track, err := h.service.ImportFile(c.Request.Context(), file, header.Filename)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnsupportedFormat):
+			BadRequest(c, "unsupported audio format")
+		case errors.Is(err, services.ErrScanInProgress):
+			Conflict(c, "scan in progress, try again when it ends")
+		default:
+			InternalError(c, "failed to import file")
+		}
+		return
+	}
+
+	Created(c, newTrackResponse(h.baseURL, h.timeFormat, *track))
+}
+
+// FilePreviewResponse is the metadata a scan would import from a file
+type FilePreviewResponse struct {
+	Path               string `json:"path"`
+	Title              string `json:"title"`
+	Artist             string `json:"artist"`
+	Album              string `json:"album"`
+	AlbumArtist        string `json:"albumArtist"`
+	Edition            string `json:"edition,omitempty"`
+	Year               int    `json:"year,omitempty"`
+	TrackNumber        int    `json:"trackNumber,omitempty"`
+	TotalTracks        int    `json:"totalTracks,omitempty"`
+	DiscNumber         int    `json:"discNumber,omitempty"`
+	TotalDiscs         int    `json:"totalDiscs,omitempty"`
+	Genre              string `json:"genre,omitempty"`
+	Duration           int    `json:"duration"`
+	Format             string `json:"format"`
+	Bitrate            int    `json:"bitrate,omitempty"`
+	SampleRate         int    `json:"sampleRate,omitempty"`
+	Channels           int    `json:"channels,omitempty"`
+	BPM                int    `json:"bpm,omitempty"`
+	MusicalKey         string `json:"musicalKey,omitempty"`
+	HasEmbeddedArtwork bool   `json:"hasEmbeddedArtwork"`
+	ArtworkFound       bool   `json:"artworkFound"`
+	ArtworkSource      string `json:"artworkSource,omitempty"`
+	ArtworkPath        string `json:"artworkPath,omitempty"`
+	ArtworkMIMEType    string `json:"artworkMimeType,omitempty"`
+	ArtworkSize        int    `json:"artworkSize,omitempty"`
+	ArtworkError       string `json:"artworkError,omitempty"`
+}
+
+// Preview handles GET /api/v1/library/preview?path=...
+// Reads a file's metadata and artwork the way a scan would, without
+// importing it. Relative paths are resolved against the media root.
+func (h *LibraryHandler) Preview(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		BadRequest(c, "path is required")
+		return
+	}
+
+	preview, err := h.service.PreviewFile(c.Request.Context(), path)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPathOutsideRoot):
+			BadRequest(c, "path outside media root")
+		case errors.Is(err, os.ErrNotExist):
+			NotFound(c, "file")
+		case errors.Is(err, services.ErrUnsupportedFormat):
+			BadRequest(c, "unsupported audio format")
+		case errors.Is(err, services.ErrUnreadableMetadata):
+			Error(c, http.StatusUnprocessableEntity, "UNREADABLE_FILE", err.Error())
+		default:
+			InternalError(c, "failed to preview file")
+		}
+		return
+	}
+
+	meta := preview.Metadata
+	response := FilePreviewResponse{
+		Path:               preview.Path,
+		Title:              meta.Title,
+		Artist:             meta.Artist,
+		Album:              meta.Album,
+		AlbumArtist:        meta.AlbumArtist,
+		Edition:            meta.Edition,
+		Year:               meta.Year,
+		TrackNumber:        meta.TrackNumber,
+		TotalTracks:        meta.TotalTracks,
+		DiscNumber:         meta.DiscNumber,
+		TotalDiscs:         meta.TotalDiscs,
+		Genre:              meta.Genre,
+		Duration:           meta.Duration,
+		Format:             meta.Format,
+		Bitrate:            meta.Bitrate,
+		SampleRate:         meta.SampleRate,
+		Channels:           meta.Channels,
+		BPM:                meta.BPM,
+		MusicalKey:         meta.MusicalKey,
+		HasEmbeddedArtwork: meta.HasArtwork,
+		ArtworkError:       preview.ArtworkError,
+	}
+	if artwork := preview.Artwork; artwork != nil {
+		response.ArtworkFound = true
+		response.ArtworkSource = artwork.Source
+		response.ArtworkPath = artwork.Path
+		response.ArtworkMIMEType = artwork.MIMEType
+		response.ArtworkSize = len(artwork.Data)
+	}
+
+	Success(c, response)
+}
+
+// IncompleteTrackResponse is a track along with the metadata it's missing
+type IncompleteTrackResponse struct {
+	TrackResponse
+	Missing []string `json:"missing"`
+}
+
+// IncompleteMetadata handles GET /api/v1/library/incomplete-metadata
+// Lists tracks missing a title, artist, album, year or genre, for triaging
+// tagging work. ?missing=year,genre limits the check to those fields.
+func (h *LibraryHandler) IncompleteMetadata(c *gin.Context) {
+	pagination := ParsePagination(c)
+
+	var missing []string
+	for _, field := range strings.Split(c.Query("missing"), ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(database.MetadataFields, field) {
+			BadRequest(c, "missing must list title, artist, album, year or genre")
+			return
+		}
+		missing = append(missing, field)
+	}
+
+	tracks, total, err := h.service.IncompleteMetadata(c.Request.Context(), missing, pagination.Page, pagination.Limit)
+	if err != nil {
+		InternalError(c, "failed to list tracks with incomplete metadata")
+		return
+	}
+
+	expand := trackExpansions{artist: true, album: true}
+	response := make([]IncompleteTrackResponse, len(tracks))
+	for i, incomplete := range tracks {
+		response[i] = IncompleteTrackResponse{
+			TrackResponse: newTrackResponse(h.baseURL, h.timeFormat, incomplete.Track),
+			Missing:       incomplete.Missing,
+		}
+		expand.apply(&response[i].TrackResponse, incomplete.Track)
+	}
+
+	SuccessWithPagination(c, response, NewPagination(pagination.Page, pagination.Limit, total))
+}
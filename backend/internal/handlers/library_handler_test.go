@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpload(t *testing.T) {
+	mp3 := "ID3\x03\x00\x00\x00\x00\x00\x00not really audio"
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		maxSize  int64
+		want     int
+	}{
+		{"audio file", "01 - First.mp3", mp3, 0, http.StatusCreated},
+		{"not audio", "01 - First.mp3", "<html>", 0, http.StatusBadRequest},
+		{"unsupported extension", "notes.txt", mp3, 0, http.StatusBadRequest},
+		{"too large", "01 - First.mp3", mp3 + strings.Repeat("x", 2<<20), 1 << 20, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *RouterConfig) {
+				if tt.maxSize > 0 {
+					cfg.UploadMaxSize = tt.maxSize
+				}
+			})
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("file", tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(tt.content))
+			form.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/library/upload", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			rec := httptest.NewRecorder()
+			env.router.ServeHTTP(rec, req)
+			expectStatus(t, rec, tt.want)
+
+			var tracks []string
+			env.db.DB.Raw("SELECT title FROM tracks").Scan(&tracks)
+			if tt.want == http.StatusCreated {
+				var track TrackResponse
+				decodeData(t, rec, &track)
+				if len(tracks) != 1 || track.Title != "First" {
+					t.Errorf("tracks = %v, response title %q; want one track titled First", tracks, track.Title)
+				}
+			} else if len(tracks) != 0 {
+				t.Errorf("refused upload imported %v", tracks)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	env := newTestEnv(t, nil)
+	write := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(env.mediaRoot, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Band/First/01 - One.mp3", "not really audio")
+	write("Band/Second/02 - Two.mp3", "not really audio")
+	write("Band/Second/cover.jpg", "not really an image")
+	write("Band/notes.txt", "notes")
+	outside := filepath.Join(t.TempDir(), "01 - Elsewhere.mp3")
+	os.WriteFile(outside, []byte("not really audio"), 0644)
+
+	tests := []struct {
+		name        string
+		path        string
+		want        int
+		wantTitle   string
+		wantArtwork string
+	}{
+		{"relative path", "Band/First/01 - One.mp3", http.StatusOK, "One", ""},
+		{"absolute path", filepath.Join(env.mediaRoot, "Band/First/01 - One.mp3"), http.StatusOK, "One", ""},
+		{"folder artwork", "Band/Second/02 - Two.mp3", http.StatusOK, "Two", "external"},
+		{"missing path", "", http.StatusBadRequest, "", ""},
+		{"outside the media root", outside, http.StatusBadRequest, "", ""},
+		{"escaping the media root", "../01 - Elsewhere.mp3", http.StatusBadRequest, "", ""},
+		{"missing file", "Band/First/03 - Gone.mp3", http.StatusNotFound, "", ""},
+		{"not audio", "Band/notes.txt", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/library/preview?path="+url.QueryEscape(tt.path), nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var preview FilePreviewResponse
+			decodeData(t, rec, &preview)
+			if preview.Title != tt.wantTitle || preview.Artist != "Band" {
+				t.Errorf("preview = %q by %q, want %q by Band", preview.Title, preview.Artist, tt.wantTitle)
+			}
+			if preview.ArtworkFound != (tt.wantArtwork != "") || preview.ArtworkSource != tt.wantArtwork {
+				t.Errorf("artwork found %v from %q, want %q", preview.ArtworkFound, preview.ArtworkSource, tt.wantArtwork)
+			}
+		})
+	}
+
+	// Nothing was imported
+	var tracks int64
+	env.db.DB.Raw("SELECT COUNT(*) FROM tracks").Scan(&tracks)
+	if tracks != 0 {
+		t.Errorf("preview imported %d tracks", tracks)
+	}
+}
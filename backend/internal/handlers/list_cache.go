@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// cacheList returns a middleware adding an ETag to list responses, derived
+// from the library version the catalog index reports, the request URL, the
+// requesting user and their sort locale (defaultSortLocale when the request
+// names none), and answering 304 Not Modified when the client already has
+// it. With maxAge clients may reuse a response that long without asking;
+// otherwise they revalidate each time.
+func cacheList(trackRepo *database.TrackRepository, maxAge time.Duration, defaultSortLocale string) gin.HandlerFunc {
+	cacheControl := "private, no-cache"
+	if maxAge > 0 {
+		cacheControl = "private, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		// The version is read before the handler runs, so a change made
+		// while it does only makes the tag older than the response
+		version, err := trackRepo.LibraryVersion(c.Request.Context())
+		if err != nil {
+			slog.Warn("failed to get library version", "error", err)
+			c.Next()
+			return
+		}
+		token := strconv.FormatInt(version, 10)
+		// Names sort by the locale the client asks for
+		sum := sha256.Sum256([]byte(token + "|" + c.Request.URL.RequestURI() + "|" + requestUserID(c) + "|" + requestSortLocale(c, defaultSortLocale)))
+		// Weak, since compression changes the bytes but not the content
+		etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", cacheControl)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		c.Next()
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for it
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
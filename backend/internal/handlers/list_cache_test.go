@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListCache(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	rec := env.do(http.MethodGet, "/api/v1/tracks", nil)
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", got)
+	}
+
+	// Each step runs against the state the previous one left
+	tests := []struct {
+		name   string
+		change func()
+		path   string
+		want   int
+	}{
+		{"unchanged", nil, "/api/v1/tracks", http.StatusNotModified},
+		{"other list", nil, "/api/v1/albums", http.StatusOK},
+		{"other query", nil, "/api/v1/tracks?genre=Rock", http.StatusOK},
+		{"still unchanged", nil, "/api/v1/tracks", http.StatusNotModified},
+		{"rated through the API", func() {
+			rec := env.do(http.MethodPut, "/api/v1/tracks/t1/rating", map[string]int{"rating": 4})
+			expectStatus(t, rec, http.StatusOK)
+		}, "/api/v1/tracks", http.StatusOK},
+		{"changed by a scan", func() {
+			env.exec(`UPDATE tracks SET title = 'Uno' WHERE id = 't1'`)
+		}, "/api/v1/tracks", http.StatusOK},
+		{"failed request", func() {
+			rec := env.do(http.MethodPut, "/api/v1/tracks/missing/rating", map[string]int{"rating": 4})
+			expectStatus(t, rec, http.StatusNotFound)
+		}, "/api/v1/tracks", http.StatusNotModified},
+		{"unlisted column", func() {
+			env.exec(`UPDATE tracks SET lyrics = 'La la la' WHERE id = 't1'`)
+		}, "/api/v1/tracks", http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				tt.change()
+			}
+			rec := env.do(http.MethodGet, tt.path, nil, "If-None-Match", etag)
+			expectStatus(t, rec, tt.want)
+			if tt.want == http.StatusOK && tt.path == "/api/v1/tracks" {
+				etag = rec.Header().Get("ETag")
+			}
+		})
+	}
+}
+
+func TestListCacheLocale(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	rec := env.do(http.MethodGet, "/api/v1/artists", nil, "Accept-Language", "en")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Values("Vary"); !slices.Contains(got, "Accept-Language") {
+		t.Errorf("Vary = %q, want it to include Accept-Language", got)
+	}
+	etag := rec.Header().Get("ETag")
+
+	tests := []struct {
+		language string
+		want     int
+	}{
+		{"en", http.StatusNotModified},
+		{"sv", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/artists", nil, "Accept-Language", tt.language, "If-None-Match", etag)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
+
+func TestListCacheMaxAge(t *testing.T) {
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		cfg.ListCacheMaxAge = 90 * time.Second
+	})
+
+	rec := env.do(http.MethodGet, "/api/v1/artists", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Cache-Control"); got != "private, max-age=90" {
+		t.Errorf("Cache-Control = %q, want private, max-age=90", got)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"other", W/"abc"`, true},
+		{`*`, true},
+		{`"other"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `W/"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
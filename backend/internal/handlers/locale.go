@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// requestSortLocale picks the collation for name sorting from the
+// Accept-Language header, falling back to the configured default when it
+// matches no sort locale. An empty default sorts by byte order.
+func requestSortLocale(c *gin.Context, fallback string) string {
+	return database.MatchSortLocale(c.GetHeader("Accept-Language"), fallback)
+}
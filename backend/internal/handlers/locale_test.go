@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestDefaultSortLocale(t *testing.T) {
+	// newEnv builds a library whose artists sort differently by locale
+	newEnv := func(locale string) *testEnv {
+		env := newTestEnv(t, func(cfg *RouterConfig) {
+			cfg.SortLocale = locale
+		})
+		env.exec(`INSERT INTO artists (id, name, created_at, updated_at) VALUES
+			('a1', 'Zulu', datetime('now'), datetime('now')),
+			('a2', 'Ärtist', datetime('now'), datetime('now')),
+			('a3', 'Bravo', datetime('now'), datetime('now')),
+			('a4', 'Artist', datetime('now'), datetime('now'))`)
+		return env
+	}
+	// Both routers are live at once, each with its own default
+	byteOrder := newEnv("")
+	german := newEnv("de")
+
+	tests := []struct {
+		name     string
+		env      *testEnv
+		language string
+		want     []string
+	}{
+		{"no default", byteOrder, "", []string{"Artist", "Bravo", "Zulu", "Ärtist"}},
+		{"default", german, "", []string{"Artist", "Ärtist", "Bravo", "Zulu"}},
+		{"unmatched language", german, "tlh", []string{"Artist", "Ärtist", "Bravo", "Zulu"}},
+		{"requested locale", byteOrder, "de-DE", []string{"Artist", "Ärtist", "Bravo", "Zulu"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.language != "" {
+				headers = []string{"Accept-Language", tt.language}
+			}
+			rec := tt.env.do(http.MethodGet, "/api/v1/artists?sortBy=name", nil, headers...)
+			expectStatus(t, rec, http.StatusOK)
+			var artists []ArtistResponse
+			decodeData(t, rec, &artists)
+			var names []string
+			for _, artist := range artists {
+				names = append(names, artist.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	// The same request is tagged differently under each default
+	byteTag := byteOrder.do(http.MethodGet, "/api/v1/artists", nil).Header().Get("ETag")
+	germanTag := german.do(http.MethodGet, "/api/v1/artists", nil).Header().Get("ETag")
+	if byteTag == "" || byteTag == germanTag {
+		t.Errorf("ETags %q and %q, want them to differ by default locale", byteTag, germanTag)
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,19 +14,25 @@ import (
 
 // PlaylistHandler handles playlist-related endpoints
 type PlaylistHandler struct {
-	repo *database.PlaylistRepository
+	repo          *database.PlaylistRepository
+	defaultPublic bool
+	maxTracks     int
+	timeFormat    TimeFormat
+	sortLocale    string
 }
 
-// NewPlaylistHandler creates a new PlaylistHandler
-func NewPlaylistHandler(repo *database.PlaylistRepository) *PlaylistHandler {
-	return &PlaylistHandler{repo: repo}
+// NewPlaylistHandler creates a new PlaylistHandler. defaultPublic is the
+// visibility of playlists created without an explicit isPublic; maxTracks
+// caps the tracks per playlist, with 0 meaning unlimited.
+func NewPlaylistHandler(repo *database.PlaylistRepository, defaultPublic bool, maxTracks int, timeFormat TimeFormat, sortLocale string) *PlaylistHandler {
+	return &PlaylistHandler{repo: repo, defaultPublic: defaultPublic, maxTracks: maxTracks, timeFormat: timeFormat, sortLocale: sortLocale}
 }
 
 // CreatePlaylistRequest represents a playlist creation request
 type CreatePlaylistRequest struct {
 	Name        string `json:"name" binding:"required,min=1,max=100"`
 	Description string `json:"description" binding:"max=500"`
-	IsPublic    bool   `json:"isPublic"`
+	IsPublic    *bool  `json:"isPublic"`
 }
 
 // UpdatePlaylistRequest represents a playlist update request
@@ -39,6 +47,11 @@ type AddTrackRequest struct {
 	TrackID string `json:"trackId" binding:"required"`
 }
 
+// MergePlaylistRequest names the playlist whose tracks are merged in
+type MergePlaylistRequest struct {
+	SourceID string `json:"sourceId" binding:"required"`
+}
+
 // PlaylistResponse represents a playlist in API responses
 type PlaylistResponse struct {
 	ID          string          `json:"id"`
@@ -53,12 +66,33 @@ type PlaylistResponse struct {
 	Tracks      []TrackResponse `json:"tracks,omitempty"`
 }
 
+// PlaylistStatsResponse summarizes the tracks of a playlist
+type PlaylistStatsResponse struct {
+	PlaylistID     string               `json:"playlistId"`
+	TrackCount     int                  `json:"trackCount"`
+	Duration       int                  `json:"duration"`
+	ArtistCount    int                  `json:"artistCount"`
+	AlbumCount     int                  `json:"albumCount"`
+	AverageBitrate int                  `json:"averageBitrate"`
+	Genres         []GenreCountResponse `json:"genres"`
+}
+
+// GenreCountResponse is the number of playlist tracks in a genre
+type GenreCountResponse struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
+}
+
 // List handles GET /api/v1/playlists
 func (h *PlaylistHandler) List(c *gin.Context) {
 	pagination := ParsePagination(c)
 
-	// Filter by user ID (auth not implemented - uses query param for now)
+	// Signed-in users see their own playlists; without authentication the
+	// userId query parameter filters by owner
 	userID := c.Query("userId")
+	if id, ok := authenticatedUserID(c); ok {
+		userID = id
+	}
 
 	opts := database.PlaylistListOptions{
 		Page:  pagination.Page,
@@ -69,6 +103,7 @@ func (h *PlaylistHandler) List(c *gin.Context) {
 		},
 		SortBy: c.DefaultQuery("sortBy", "name"),
 		Order:  c.DefaultQuery("order", "asc"),
+		Locale: requestSortLocale(c, h.sortLocale),
 	}
 
 	playlists, total, err := h.repo.List(c.Request.Context(), opts)
@@ -87,8 +122,8 @@ func (h *PlaylistHandler) List(c *gin.Context) {
 			TrackCount:  playlist.TrackCount,
 			Duration:    playlist.Duration,
 			UserID:      playlist.UserID,
-			CreatedAt:   playlist.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt:   playlist.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			CreatedAt:   h.timeFormat.Format(playlist.CreatedAt),
+			UpdatedAt:   h.timeFormat.Format(playlist.UpdatedAt),
 		}
 	}
 
@@ -103,13 +138,17 @@ func (h *PlaylistHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Use default user (auth not implemented yet)
-	userID := "default-user"
+	userID := requestUserID(c)
+
+	isPublic := h.defaultPublic
+	if req.IsPublic != nil {
+		isPublic = *req.IsPublic
+	}
 
 	playlist := &models.Playlist{
 		Name:        req.Name,
 		Description: req.Description,
-		IsPublic:    req.IsPublic,
+		IsPublic:    isPublic,
 		UserID:      userID,
 	}
 
@@ -126,8 +165,8 @@ func (h *PlaylistHandler) Create(c *gin.Context) {
 		TrackCount:  0,
 		Duration:    0,
 		UserID:      playlist.UserID,
-		CreatedAt:   playlist.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:   playlist.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		CreatedAt:   h.timeFormat.Format(playlist.CreatedAt),
+		UpdatedAt:   h.timeFormat.Format(playlist.UpdatedAt),
 	}
 
 	Created(c, response)
@@ -150,6 +189,10 @@ func (h *PlaylistHandler) Get(c *gin.Context) {
 		InternalError(c, "failed to get playlist")
 		return
 	}
+	if !canSeePlaylist(c, playlist) {
+		NotFound(c, "playlist")
+		return
+	}
 
 	// Build track responses
 	tracks := make([]TrackResponse, len(playlist.Tracks))
@@ -173,8 +216,8 @@ func (h *PlaylistHandler) Get(c *gin.Context) {
 		TrackCount:  playlist.TrackCount,
 		Duration:    playlist.Duration,
 		UserID:      playlist.UserID,
-		CreatedAt:   playlist.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:   playlist.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		CreatedAt:   h.timeFormat.Format(playlist.CreatedAt),
+		UpdatedAt:   h.timeFormat.Format(playlist.UpdatedAt),
 		Tracks:      tracks,
 	}
 
@@ -195,13 +238,8 @@ func (h *PlaylistHandler) Update(c *gin.Context) {
 		return
 	}
 
-	playlist, err := h.repo.FindByID(c.Request.Context(), id)
-	if err != nil {
-		if errors.Is(err, database.ErrPlaylistNotFound) {
-			NotFound(c, "playlist")
-			return
-		}
-		InternalError(c, "failed to get playlist")
+	playlist, ok := h.ownPlaylist(c, id)
+	if !ok {
 		return
 	}
 
@@ -227,8 +265,8 @@ func (h *PlaylistHandler) Update(c *gin.Context) {
 		Description: playlist.Description,
 		IsPublic:    playlist.IsPublic,
 		UserID:      playlist.UserID,
-		CreatedAt:   playlist.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:   playlist.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		CreatedAt:   h.timeFormat.Format(playlist.CreatedAt),
+		UpdatedAt:   h.timeFormat.Format(playlist.UpdatedAt),
 	}
 
 	Success(c, response)
@@ -242,6 +280,10 @@ func (h *PlaylistHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if _, ok := h.ownPlaylist(c, id); !ok {
+		return
+	}
+
 	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
 		if errors.Is(err, database.ErrPlaylistNotFound) {
 			NotFound(c, "playlist")
@@ -268,14 +310,7 @@ func (h *PlaylistHandler) AddTrack(c *gin.Context) {
 		return
 	}
 
-	// Check if playlist exists
-	_, err := h.repo.FindByID(c.Request.Context(), id)
-	if err != nil {
-		if errors.Is(err, database.ErrPlaylistNotFound) {
-			NotFound(c, "playlist")
-			return
-		}
-		InternalError(c, "failed to get playlist")
+	if _, ok := h.ownPlaylist(c, id); !ok {
 		return
 	}
 
@@ -286,7 +321,11 @@ func (h *PlaylistHandler) AddTrack(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.AddTrack(c.Request.Context(), id, req.TrackID); err != nil {
+	if err := h.repo.AddTrack(c.Request.Context(), id, req.TrackID, h.maxTracks); err != nil {
+		if errors.Is(err, database.ErrPlaylistFull) {
+			Conflict(c, h.playlistFullMessage())
+			return
+		}
 		InternalError(c, "failed to add track to playlist")
 		return
 	}
@@ -307,6 +346,10 @@ func (h *PlaylistHandler) RemoveTrack(c *gin.Context) {
 		return
 	}
 
+	if _, ok := h.ownPlaylist(c, playlistID); !ok {
+		return
+	}
+
 	if err := h.repo.RemoveTrack(c.Request.Context(), playlistID, trackID); err != nil {
 		if errors.Is(err, database.ErrTrackNotInPlaylist) {
 			NotFound(c, "track in playlist")
@@ -338,14 +381,7 @@ func (h *PlaylistHandler) ReorderTracks(c *gin.Context) {
 		return
 	}
 
-	// Check if playlist exists
-	_, err := h.repo.FindByID(c.Request.Context(), playlistID)
-	if err != nil {
-		if errors.Is(err, database.ErrPlaylistNotFound) {
-			NotFound(c, "playlist")
-			return
-		}
-		InternalError(c, "failed to get playlist")
+	if _, ok := h.ownPlaylist(c, playlistID); !ok {
 		return
 	}
 
@@ -359,3 +395,129 @@ func (h *PlaylistHandler) ReorderTracks(c *gin.Context) {
 		"message": "tracks reordered",
 	})
 }
+
+// Stats handles GET /api/v1/playlists/:id/stats
+func (h *PlaylistHandler) Stats(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	if _, ok := h.visiblePlaylist(c, id, "playlist"); !ok {
+		return
+	}
+
+	stats, err := h.repo.GetStats(ctx, id)
+	if err != nil {
+		InternalError(c, "failed to get playlist stats")
+		return
+	}
+
+	genres := make([]GenreCountResponse, len(stats.Genres))
+	for i, genre := range stats.Genres {
+		genres[i] = GenreCountResponse{Genre: genre.Genre, Count: genre.Count}
+	}
+
+	Success(c, PlaylistStatsResponse{
+		PlaylistID:     id,
+		TrackCount:     stats.TrackCount,
+		Duration:       stats.Duration,
+		ArtistCount:    stats.ArtistCount,
+		AlbumCount:     stats.AlbumCount,
+		AverageBitrate: int(math.Round(stats.AverageBitrate)),
+		Genres:         genres,
+	})
+}
+
+// Merge handles POST /api/v1/playlists/:id/merge
+// Appends the tracks of another playlist, skipping ones already present. The
+// source must be public or belong to the requesting user too.
+func (h *PlaylistHandler) Merge(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req MergePlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if req.SourceID == id {
+		BadRequest(c, "cannot merge a playlist into itself")
+		return
+	}
+
+	target, ok := h.ownPlaylist(c, id)
+	if !ok {
+		return
+	}
+	source, ok := h.visiblePlaylist(c, req.SourceID, "source playlist")
+	if !ok {
+		return
+	}
+
+	added, err := h.repo.MergeTracks(ctx, target.ID, source.ID, h.maxTracks)
+	if err != nil {
+		if errors.Is(err, database.ErrPlaylistFull) {
+			Conflict(c, h.playlistFullMessage())
+			return
+		}
+		InternalError(c, "failed to merge playlists")
+		return
+	}
+
+	Success(c, gin.H{
+		"playlistId":  target.ID,
+		"sourceId":    source.ID,
+		"addedTracks": added,
+	})
+}
+
+// visiblePlaylist fetches a playlist the requesting user may see, writing
+// the error response when there is none. what names it in a 404.
+func (h *PlaylistHandler) visiblePlaylist(c *gin.Context, id, what string) (*models.Playlist, bool) {
+	playlist, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrPlaylistNotFound) {
+			NotFound(c, what)
+			return nil, false
+		}
+		InternalError(c, "failed to get playlist")
+		return nil, false
+	}
+	if !canSeePlaylist(c, playlist) {
+		NotFound(c, what)
+		return nil, false
+	}
+	return playlist, true
+}
+
+// ownPlaylist fetches a playlist to change, which only its owner may do
+func (h *PlaylistHandler) ownPlaylist(c *gin.Context, id string) (*models.Playlist, bool) {
+	playlist, ok := h.visiblePlaylist(c, id, "playlist")
+	if !ok {
+		return nil, false
+	}
+	if !ownsPlaylist(c, playlist) {
+		Forbidden(c, "playlist belongs to another user")
+		return nil, false
+	}
+	return playlist, true
+}
+
+// ownsPlaylist reports whether the requesting user owns playlist. Playlists
+// without an owner belong to everyone; those saved under the default user
+// before there were accounts are handed to the first administrator when the
+// database is migrated.
+func ownsPlaylist(c *gin.Context, playlist *models.Playlist) bool {
+	return playlist.UserID == "" || playlist.UserID == requestUserID(c)
+}
+
+// canSeePlaylist reports whether the requesting user may read playlist.
+// Other users' private playlists are answered as not found, so their IDs
+// reveal nothing.
+func canSeePlaylist(c *gin.Context, playlist *models.Playlist) bool {
+	return playlist.IsPublic || ownsPlaylist(c, playlist)
+}
+
+// playlistFullMessage explains a rejected addition to a full playlist
+func (h *PlaylistHandler) playlistFullMessage() string {
+	return fmt.Sprintf("playlist is limited to %d tracks", h.maxTracks)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPlaylistOwnership(t *testing.T) {
+	env := newTestEnv(t, withAuth)
+	alice := env.register("alice")
+	bob := env.register("bob")
+
+	create := func(public bool) string {
+		t.Helper()
+		rec := env.do(http.MethodPost, "/api/v1/playlists",
+			map[string]any{"name": "Mix", "isPublic": public}, bearer(alice)...)
+		expectStatus(t, rec, http.StatusCreated)
+		var playlist PlaylistResponse
+		decodeData(t, rec, &playlist)
+		return playlist.ID
+	}
+	private := create(false)
+	public := create(true)
+	env.seedLibrary()
+	// Playlists from before accounts were saved under a default user
+	// without an account, and go to the first user, an administrator, on
+	// the next migration
+	const legacy = "p-legacy"
+	env.exec(`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO playlists (id, name, user_id, is_public, created_at, updated_at)
+			VALUES ('p-legacy', 'Old', 'default-user', false, datetime('now'), datetime('now'))`,
+		`PRAGMA foreign_keys = ON`)
+	if err := env.db.Migrate(); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		token  string
+		want   int
+	}{
+		{"owner reads private", http.MethodGet, "/api/v1/playlists/" + private, nil, alice, http.StatusOK},
+		{"other reads private", http.MethodGet, "/api/v1/playlists/" + private, nil, bob, http.StatusNotFound},
+		{"other stats of private", http.MethodGet, "/api/v1/playlists/" + private + "/stats", nil, bob, http.StatusNotFound},
+		{"other updates private", http.MethodPut, "/api/v1/playlists/" + private, map[string]string{"name": "Mine"}, bob, http.StatusNotFound},
+		{"other reads public", http.MethodGet, "/api/v1/playlists/" + public, nil, bob, http.StatusOK},
+		{"other updates public", http.MethodPut, "/api/v1/playlists/" + public, map[string]string{"name": "Mine"}, bob, http.StatusForbidden},
+		{"other adds to public", http.MethodPost, "/api/v1/playlists/" + public + "/tracks", map[string]string{"trackId": "t1"}, bob, http.StatusForbidden},
+		{"other reorders public", http.MethodPut, "/api/v1/playlists/" + public + "/tracks/reorder", map[string]any{"trackIds": []string{}}, bob, http.StatusForbidden},
+		{"other deletes public", http.MethodDelete, "/api/v1/playlists/" + public, nil, bob, http.StatusForbidden},
+		{"other merges private source", http.MethodPost, "/api/v1/playlists/" + public + "/merge", map[string]string{"sourceId": private}, bob, http.StatusForbidden},
+		{"owner adds track", http.MethodPost, "/api/v1/playlists/" + public + "/tracks", map[string]string{"trackId": "t1"}, alice, http.StatusOK},
+		{"other removes track", http.MethodDelete, "/api/v1/playlists/" + public + "/tracks/t1", nil, bob, http.StatusForbidden},
+		{"admin reads legacy", http.MethodGet, "/api/v1/playlists/" + legacy, nil, alice, http.StatusOK},
+		{"admin updates legacy", http.MethodPut, "/api/v1/playlists/" + legacy, map[string]string{"name": "Older"}, alice, http.StatusOK},
+		{"other reads legacy", http.MethodGet, "/api/v1/playlists/" + legacy, nil, bob, http.StatusNotFound},
+		{"owner updates", http.MethodPut, "/api/v1/playlists/" + private, map[string]string{"name": "Renamed"}, alice, http.StatusOK},
+		{"owner deletes", http.MethodDelete, "/api/v1/playlists/" + private, nil, alice, http.StatusNoContent},
+		{"missing", http.MethodGet, "/api/v1/playlists/missing", nil, alice, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(tt.method, tt.path, tt.body, bearer(tt.token)...)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
+
+func TestPlaylistTrackLimit(t *testing.T) {
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		withAuth(cfg)
+		cfg.MaxPlaylistTracks = 2
+	})
+	alice := env.register("alice")
+	env.seedLibrary()
+
+	create := func(trackIDs ...string) string {
+		t.Helper()
+		rec := env.do(http.MethodPost, "/api/v1/playlists", map[string]string{"name": "Mix"}, bearer(alice)...)
+		expectStatus(t, rec, http.StatusCreated)
+		var playlist PlaylistResponse
+		decodeData(t, rec, &playlist)
+		for _, trackID := range trackIDs {
+			rec := env.do(http.MethodPost, "/api/v1/playlists/"+playlist.ID+"/tracks",
+				map[string]string{"trackId": trackID}, bearer(alice)...)
+			expectStatus(t, rec, http.StatusOK)
+		}
+		return playlist.ID
+	}
+	mix := create()
+	pair := create("t3", "t4")
+
+	// Steps run in order against the same playlist
+	tests := []struct {
+		name string
+		path string
+		body map[string]string
+		want int
+	}{
+		{"first track", "/tracks", map[string]string{"trackId": "t1"}, http.StatusOK},
+		{"up to the limit", "/tracks", map[string]string{"trackId": "t2"}, http.StatusOK},
+		{"past the limit", "/tracks", map[string]string{"trackId": "t3"}, http.StatusConflict},
+		{"already in the playlist", "/tracks", map[string]string{"trackId": "t1"}, http.StatusConflict},
+		{"merge past the limit", "/merge", map[string]string{"sourceId": pair}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodPost, "/api/v1/playlists/"+mix+tt.path, tt.body, bearer(alice)...)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+
+	rec := env.do(http.MethodGet, "/api/v1/playlists/"+mix, nil, bearer(alice)...)
+	expectStatus(t, rec, http.StatusOK)
+	var playlist PlaylistResponse
+	decodeData(t, rec, &playlist)
+	var got []string
+	for _, track := range playlist.Tracks {
+		got = append(got, track.ID)
+	}
+	if !slices.Equal(got, []string{"t1", "t2"}) {
+		t.Errorf("playlist holds %v, want [t1 t2]", got)
+	}
+}
+
+func TestPlaylistStats(t *testing.T) {
+	env := newTestEnv(t, withAuth)
+	alice := env.register("alice")
+	bob := env.register("bob")
+	env.seedLibrary()
+	// The cover is credited to another artist, and only some bitrates are known
+	env.exec(
+		`UPDATE tracks SET artist_id = 'ar2' WHERE id = 't4'`,
+		`UPDATE tracks SET bitrate = 320 WHERE id = 't1'`,
+		`UPDATE tracks SET bitrate = 257 WHERE id = 't2'`,
+	)
+
+	create := func(trackIDs ...string) string {
+		t.Helper()
+		rec := env.do(http.MethodPost, "/api/v1/playlists", map[string]string{"name": "Mix"}, bearer(alice)...)
+		expectStatus(t, rec, http.StatusCreated)
+		var playlist PlaylistResponse
+		decodeData(t, rec, &playlist)
+		for _, trackID := range trackIDs {
+			rec := env.do(http.MethodPost, "/api/v1/playlists/"+playlist.ID+"/tracks",
+				map[string]string{"trackId": trackID}, bearer(alice)...)
+			expectStatus(t, rec, http.StatusOK)
+		}
+		return playlist.ID
+	}
+	mix := create("t1", "t2", "t3", "t4")
+	empty := create()
+
+	tests := []struct {
+		name     string
+		playlist string
+		want     PlaylistStatsResponse
+	}{
+		{"mix", mix, PlaylistStatsResponse{
+			PlaylistID:     mix,
+			TrackCount:     4,
+			Duration:       830,
+			ArtistCount:    2,
+			AlbumCount:     3,
+			AverageBitrate: 289,
+			Genres:         []GenreCountResponse{{"Rock", 2}, {"Jazz", 1}, {"Pop", 1}},
+		}},
+		{"empty", empty, PlaylistStatsResponse{PlaylistID: empty, Genres: []GenreCountResponse{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/playlists/"+tt.playlist+"/stats", nil, bearer(alice)...)
+			expectStatus(t, rec, http.StatusOK)
+			var got PlaylistStatsResponse
+			decodeData(t, rec, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stats = %+v\nwant    %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Someone else's private playlist has no stats to show
+	rec := env.do(http.MethodGet, "/api/v1/playlists/"+mix+"/stats", nil, bearer(bob)...)
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+func TestPlaylistVisibility(t *testing.T) {
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		withAuth(cfg)
+		cfg.PlaylistDefaultPublic = true
+	})
+	alice := env.register("alice")
+
+	// Steps run in order, each creating a playlist or updating the last one
+	var id string
+	tests := []struct {
+		name   string
+		method string
+		body   map[string]any
+		want   bool
+	}{
+		{"created without isPublic", http.MethodPost, map[string]any{"name": "Mix"}, true},
+		{"created private", http.MethodPost, map[string]any{"name": "Mix", "isPublic": false}, false},
+		{"made public", http.MethodPut, map[string]any{"isPublic": true}, true},
+		{"renamed", http.MethodPut, map[string]any{"name": "Renamed"}, true},
+		{"made private", http.MethodPut, map[string]any{"isPublic": false}, false},
+		{"described", http.MethodPut, map[string]any{"description": "Quiet"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/playlists"
+			want := http.StatusCreated
+			if tt.method == http.MethodPut {
+				path += "/" + id
+				want = http.StatusOK
+			}
+			rec := env.do(tt.method, path, tt.body, bearer(alice)...)
+			expectStatus(t, rec, want)
+			var playlist PlaylistResponse
+			decodeData(t, rec, &playlist)
+			id = playlist.ID
+
+			if playlist.IsPublic != tt.want {
+				t.Errorf("isPublic = %v, want %v", playlist.IsPublic, tt.want)
+			}
+			rec = env.do(http.MethodGet, "/api/v1/playlists/"+id, nil, bearer(alice)...)
+			decodeData(t, rec, &playlist)
+			if playlist.IsPublic != tt.want {
+				t.Errorf("stored isPublic = %v, want %v", playlist.IsPublic, tt.want)
+			}
+		})
+	}
+}
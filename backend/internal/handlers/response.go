@@ -2,8 +2,14 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
 )
 
 // Response is the standard API response wrapper
@@ -164,11 +170,45 @@ func Forbidden(c *gin.Context, message string) {
 	Error(c, http.StatusForbidden, "FORBIDDEN", message)
 }
 
+// MethodNotAllowed sends a 405 Method Not Allowed error
+func MethodNotAllowed(c *gin.Context, message string) {
+	Error(c, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", message)
+}
+
 // Conflict sends a 409 Conflict error
 func Conflict(c *gin.Context, message string) {
 	Error(c, http.StatusConflict, "CONFLICT", message)
 }
 
+// GatewayTimeout sends a 504 Gateway Timeout error
+func GatewayTimeout(c *gin.Context, message string) {
+	Error(c, http.StatusGatewayTimeout, "TIMEOUT", message)
+}
+
+// TimeFormat is the layout used for every timestamp in API responses
+type TimeFormat string
+
+// NewTimeFormat returns the TimeFormat named by the TIME_FORMAT setting,
+// "rfc3339" or "rfc3339nano"
+func NewTimeFormat(name string) TimeFormat {
+	if strings.EqualFold(name, "rfc3339nano") {
+		return TimeFormat(time.RFC3339Nano)
+	}
+	return TimeFormat(time.RFC3339)
+}
+
+// Format serializes a timestamp in UTC. Zero times are returned as an empty
+// string; the zero TimeFormat is RFC 3339.
+func (f TimeFormat) Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if f == "" {
+		f = TimeFormat(time.RFC3339)
+	}
+	return t.UTC().Format(string(f))
+}
+
 // parseInt parses a string to int
 func parseInt(s string) (int, error) {
 	var result int
@@ -193,31 +233,53 @@ type Link struct {
 
 // TrackResponse extends track data with links
 type TrackResponse struct {
-	ID          string  `json:"id"`
-	Title       string  `json:"title"`
-	Duration    int     `json:"duration"`
-	TrackNumber int     `json:"trackNumber"`
-	DiscNumber  int     `json:"discNumber"`
-	Format      string  `json:"format"`
-	Bitrate     int     `json:"bitrate,omitempty"`
-	AlbumID     string  `json:"albumId,omitempty"`
-	ArtistID    string  `json:"artistId,omitempty"`
-	Genre       string  `json:"genre,omitempty"`
-	Year        int     `json:"year,omitempty"`
-	Links       []Link  `json:"links,omitempty"`
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	Duration       int      `json:"duration"`
+	TrackNumber    int      `json:"trackNumber"`
+	DiscNumber     int      `json:"discNumber"`
+	TotalTracks    int      `json:"totalTracks,omitempty"`
+	TotalDiscs     int      `json:"totalDiscs,omitempty"`
+	Format         string   `json:"format"`
+	Lossless       bool     `json:"lossless"`
+	Bitrate        int      `json:"bitrate,omitempty"`
+	AlbumID        string   `json:"albumId,omitempty"`
+	ArtistID       string   `json:"artistId,omitempty"`
+	ArtistName     string   `json:"artistName,omitempty"`
+	AlbumTitle     string   `json:"albumTitle,omitempty"`
+	Genre          string   `json:"genre,omitempty"`
+	InferredGenre  string   `json:"inferredGenre,omitempty"`
+	Year           int      `json:"year,omitempty"`
+	Rating         int      `json:"rating"`
+	BPM            int      `json:"bpm,omitempty"`
+	MusicalKey     string   `json:"musicalKey,omitempty"`
+	TrackGain      *float64 `json:"trackGain,omitempty"`
+	AlbumGain      *float64 `json:"albumGain,omitempty"`
+	EncoderDelay   int      `json:"encoderDelay,omitempty"`
+	EncoderPadding int      `json:"encoderPadding,omitempty"`
+	PlayCount      int      `json:"playCount"`
+	SkipCount      int      `json:"skipCount"`
+	LastPlayed     string   `json:"lastPlayedAt,omitempty"`
+	AddedAt        string   `json:"addedAt"`
+	FileModifiedAt string   `json:"fileModifiedAt,omitempty"`
+	Links          []Link   `json:"links,omitempty"`
 }
 
 // AlbumResponse extends album data with links
 type AlbumResponse struct {
-	ID          string  `json:"id"`
-	Title       string  `json:"title"`
-	Year        int     `json:"year,omitempty"`
-	ArtistID    string  `json:"artistId"`
-	ArtistName  string  `json:"artistName,omitempty"`
-	TrackCount  int     `json:"trackCount,omitempty"`
-	Duration    int     `json:"duration,omitempty"`
-	CoverArtURL string  `json:"coverArtUrl,omitempty"`
-	Links       []Link  `json:"links,omitempty"`
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Edition     string `json:"edition,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	AlbumType   string `json:"albumType,omitempty"`
+	TotalTracks int    `json:"totalTracks,omitempty"`
+	TotalDiscs  int    `json:"totalDiscs,omitempty"`
+	ArtistID    string `json:"artistId"`
+	ArtistName  string `json:"artistName,omitempty"`
+	TrackCount  int    `json:"trackCount,omitempty"`
+	Duration    int    `json:"duration,omitempty"`
+	CoverArtURL string `json:"coverArtUrl,omitempty"`
+	Links       []Link `json:"links,omitempty"`
 }
 
 // ArtistResponse extends artist data with links
@@ -231,6 +293,49 @@ type ArtistResponse struct {
 	Links      []Link `json:"links,omitempty"`
 }
 
+// newTrackResponse builds the full track response for a track
+func newTrackResponse(baseURL string, timeFormat TimeFormat, track models.Track) TrackResponse {
+	lastPlayed := ""
+	if track.LastPlayedAt != nil {
+		lastPlayed = timeFormat.Format(*track.LastPlayedAt)
+	}
+	fileModified := ""
+	if !track.ModTime.IsZero() {
+		fileModified = timeFormat.Format(track.ModTime)
+	}
+
+	return TrackResponse{
+		ID:             track.ID,
+		Title:          track.Title,
+		Duration:       track.Duration,
+		TrackNumber:    track.TrackNumber,
+		DiscNumber:     track.DiscNumber,
+		TotalTracks:    track.TotalTracks,
+		TotalDiscs:     track.TotalDiscs,
+		Format:         track.Format,
+		Lossless:       track.Lossless || slices.Contains(database.LosslessFormats, track.Format),
+		Bitrate:        track.Bitrate,
+		AlbumID:        track.AlbumID,
+		ArtistID:       track.ArtistID,
+		Genre:          track.Genre,
+		InferredGenre:  track.InferredGenre,
+		Year:           track.Year,
+		Rating:         track.Rating,
+		BPM:            track.BPM,
+		MusicalKey:     track.MusicalKey,
+		TrackGain:      track.TrackGain,
+		AlbumGain:      track.AlbumGain,
+		EncoderDelay:   track.EncoderDelay,
+		EncoderPadding: track.EncoderPad,
+		PlayCount:      track.PlayCount,
+		SkipCount:      track.SkipCount,
+		LastPlayed:     lastPlayed,
+		AddedAt:        timeFormat.Format(track.CreatedAt),
+		FileModifiedAt: fileModified,
+		Links:          BuildTrackLinks(baseURL, track.ID, track.AlbumID),
+	}
+}
+
 // BuildTrackLinks generates hypermedia links for a track
 func BuildTrackLinks(baseURL, trackID, albumID string) []Link {
 	links := []Link{
@@ -255,6 +360,15 @@ func BuildAlbumLinks(baseURL, albumID, artistID string) []Link {
 	return links
 }
 
+// BuildArtistImageURL returns the locally served image URL for an artist, so
+// clients never contact the remote image host directly
+func BuildArtistImageURL(baseURL string, artist models.Artist) string {
+	if artist.ImagePath == "" {
+		return artist.ImageURL
+	}
+	return baseURL + "/api/v1/artwork/artist/" + artist.ID
+}
+
 // BuildArtistLinks generates hypermedia links for an artist
 func BuildArtistLinks(baseURL, artistID string) []Link {
 	return []Link{
@@ -1,33 +1,100 @@
 package handlers
 
 import (
+	"context"
+	"image/color"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/scanner"
 	"harmony/internal/services"
 	"harmony/internal/transcoder"
 )
 
 // RouterConfig holds router configuration
 type RouterConfig struct {
-	AllowedOrigins []string
-	MediaRoot      string
-	CacheDir       string
-	BaseURL        string
+	AllowedOrigins      []string
+	MediaRoot           string
+	CacheDir            string
+	BaseURL             string
+	ArtworkMaxDimension int
+	ArtworkMaxPixels    int
+	ThumbnailMode       scanner.ThumbnailMode
+	ThumbnailPadColor   color.Color
+	CompressionMinSize  int
+	TimeFormat          string
+	SortLocale          string
+	SearchTimeout       time.Duration
+	LibraryTimeout      time.Duration
+	UploadMaxSize       int64
+	BackupDir           string
+	MaxStreamsPerUser   int
+	UserStreamLimits    map[string]int
+	StreamBufferSize    int
+	MaxPlaylistTracks   int
+	// ListCacheMaxAge lets clients reuse list responses that long without
+	// revalidating them (0 revalidates every time)
+	ListCacheMaxAge time.Duration
+	// PlayDedupeWindow ignores repeat plays of a track by the same user
+	// within it (0 counts every play)
+	PlayDedupeWindow time.Duration
+	// ArtworkArtistFallback serves the artist image for albums without a cover
+	ArtworkArtistFallback bool
+	// PlaylistDefaultPublic is the visibility of playlists created without isPublic
+	PlaylistDefaultPublic bool
+	// MissingPlaceholder streams a silent or tone clip (PlaceholderSilence,
+	// PlaceholderTone) for tracks whose file is missing; empty responds 404
+	MissingPlaceholder string
+	// TrackArtwork serves a track's own embedded artwork, when it has some,
+	// instead of its album's cover
+	TrackArtwork bool
+	// RemoteArtworkPrivateHosts lets remote artist images be fetched from
+	// private, loopback and link-local addresses
+	RemoteArtworkPrivateHosts bool
+	// PublicOrigins are the origins allowed on the public media routes
+	// (see publicMediaRoutes); nil applies AllowedOrigins to them too
+	PublicOrigins []string
+	// AuthEnabled requires a token from /api/v1/auth on every other API
+	// route; disabled, all requests act as one default user
+	AuthEnabled bool
+	// JWTSecret signs the issued tokens, which expire after AuthTokenTTL
+	JWTSecret    string
+	AuthTokenTTL time.Duration
+	// AuthRegistration lets anyone create an account
+	AuthRegistration bool
+	// AdminToken opens the admin routes while AuthEnabled is off, sent as
+	// a bearer token; without either they refuse every request
+	AdminToken string
 }
 
 // DefaultRouterConfig returns default router configuration
 func DefaultRouterConfig() RouterConfig {
 	return RouterConfig{
-		AllowedOrigins: []string{"http://localhost:3000", "http://localhost:5173"},
-		MediaRoot:      "./media",
-		CacheDir:       "./data/cache",
-		BaseURL:        "http://localhost:8080",
+		AllowedOrigins:      []string{"http://localhost:3000", "http://localhost:5173"},
+		MediaRoot:           "./media",
+		CacheDir:            "./data/cache",
+		BaseURL:             "http://localhost:8080",
+		ArtworkMaxDimension: scanner.DefaultMaxArtworkDimension,
+		ArtworkMaxPixels:    scanner.DefaultMaxArtworkPixels,
+		ThumbnailMode:       scanner.ThumbnailFit,
+		ThumbnailPadColor:   color.Black,
+		CompressionMinSize:  1024,
+		TimeFormat:          "rfc3339",
+		SearchTimeout:       5 * time.Second,
+		LibraryTimeout:      30 * time.Second,
+		UploadMaxSize:       200 << 20,
+		BackupDir:           "./data/backups",
+		AuthTokenTTL:        7 * 24 * time.Hour,
+		AuthRegistration:    true,
+		PlayDedupeWindow:    10 * time.Second,
 	}
 }
 
@@ -42,6 +109,10 @@ type Handlers struct {
 	Stream   *StreamHandler
 	Artwork  *ArtworkHandler
 	Setup    *SetupHandler
+	Admin    *AdminHandler
+	User     *UserHandler
+	Index    *IndexHandler
+	Auth     *AuthHandler
 }
 
 // NewRouter creates and configures the Gin router
@@ -57,10 +128,27 @@ func NewRouter(
 
 	router := gin.New()
 
+	// Unknown routes and methods get the JSON error envelope instead of
+	// Gin's plain text. Gin fills in the Allow header of 405 responses.
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		NotFound(c, "route")
+	})
+	router.NoMethod(func(c *gin.Context) {
+		MethodNotAllowed(c, c.Request.Method+" not allowed on "+c.Request.URL.Path)
+	})
+
 	// Middleware
 	router.Use(gin.Recovery())
 	router.Use(requestLogger())
-	router.Use(configureCORS(cfg.AllowedOrigins))
+	if cfg.PublicOrigins != nil {
+		router.Use(routeCORS(configureCORS(cfg.AllowedOrigins), configurePublicCORS(cfg.PublicOrigins)))
+	} else {
+		router.Use(configureCORS(cfg.AllowedOrigins))
+	}
+	if cfg.CompressionMinSize > 0 {
+		router.Use(compressResponses(cfg.CompressionMinSize))
+	}
 
 	// Create repositories
 	trackRepo := database.NewTrackRepository(db.DB)
@@ -68,80 +156,151 @@ func NewRouter(
 	artistRepo := database.NewArtistRepository(db.DB)
 	playlistRepo := database.NewPlaylistRepository(db.DB)
 	settingsRepo := database.NewSettingsRepository(db.DB)
+	userRepo := database.NewUserRepository(db.DB)
+	restoreTranscodeProfiles(context.Background(), settingsRepo)
+	restoreMediaPaths(context.Background(), settingsRepo, libService)
 
 	// Create handlers
+	timeFormat := NewTimeFormat(cfg.TimeFormat)
 	handlers := &Handlers{
-		Track:    NewTrackHandler(trackRepo, cfg.BaseURL),
-		Album:    NewAlbumHandler(albumRepo, cfg.BaseURL),
-		Artist:   NewArtistHandler(artistRepo, cfg.BaseURL),
-		Playlist: NewPlaylistHandler(playlistRepo),
-		Search:   NewSearchHandler(trackRepo, albumRepo, artistRepo, redis),
-		Library:  NewLibraryHandler(libService),
-		Stream:   NewStreamHandler(trackRepo, trans, cfg.MediaRoot),
-		Artwork:  NewArtworkHandler(cfg.CacheDir),
+		Track:    NewTrackHandler(trackRepo, trans, redis, services.NewScrobbler(settingsRepo), cfg.CacheDir, cfg.BaseURL, cfg.PlayDedupeWindow, timeFormat, cfg.SortLocale),
+		Album:    NewAlbumHandler(albumRepo, cfg.CacheDir, cfg.BaseURL, timeFormat, cfg.SortLocale),
+		Artist:   NewArtistHandler(artistRepo, trackRepo, cfg.CacheDir, cfg.BaseURL, timeFormat, cfg.SortLocale),
+		Playlist: NewPlaylistHandler(playlistRepo, cfg.PlaylistDefaultPublic, cfg.MaxPlaylistTracks, timeFormat, cfg.SortLocale),
+		Search:   NewSearchHandler(trackRepo, albumRepo, artistRepo, redis, cfg.SearchTimeout, timeFormat),
+		Library:  NewLibraryHandler(libService, cfg.BaseURL, cfg.UploadMaxSize, cfg.AllowedOrigins, timeFormat),
+		Stream:   NewStreamHandler(trackRepo, trans, cfg.MediaRoot, cfg.StreamBufferSize, cfg.MissingPlaceholder, libService),
+		Artwork:  NewArtworkHandler(artistRepo, albumRepo, trackRepo, cfg.CacheDir, cfg.ArtworkMaxDimension, cfg.ArtworkMaxPixels, cfg.ThumbnailMode, cfg.ThumbnailPadColor, cfg.ArtworkArtistFallback, cfg.TrackArtwork, cfg.RemoteArtworkPrivateHosts),
 		Setup:    NewSetupHandler(settingsRepo, libService, cfg.MediaRoot),
+		Admin:    NewAdminHandler(trackRepo, albumRepo, playlistRepo, db, libService, settingsRepo, trans, cfg.BackupDir, timeFormat),
+		User:     NewUserHandler(settingsRepo),
+		Index:    NewIndexHandler(artistRepo, trackRepo),
+		Auth:     NewAuthHandler(userRepo, cfg.JWTSecret, cfg.AuthTokenTTL, cfg.AuthRegistration, timeFormat),
 	}
 
-	// Health check endpoint
+	streamLimiter := newStreamLimiter(cfg.MaxStreamsPerUser, cfg.UserStreamLimits)
+
+	// List responses are tagged with the library version, so clients can
+	// revalidate them cheaply
+	listCache := cacheList(trackRepo, cfg.ListCacheMaxAge, cfg.SortLocale)
+
+	// Health check endpoint; an unavailable media root reports "degraded"
+	// without failing the check so the container isn't restarted over it
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
+		body := gin.H{
 			"status": "healthy",
-			"time":   time.Now().UTC().Format(time.RFC3339),
-		})
+			"time":   timeFormat.Format(time.Now()),
+		}
+
+		if root := libService.MediaRootStatus(); !root.CheckedAt.IsZero() {
+			if !root.Available {
+				body["status"] = "degraded"
+			}
+			body["mediaRoot"] = gin.H{
+				"path":      root.Path,
+				"available": root.Available,
+				"error":     root.Error,
+				"checkedAt": timeFormat.Format(root.CheckedAt),
+			}
+		}
+
+		c.JSON(http.StatusOK, body)
 	})
 
+	// Sign-up and sign-in stay open when the rest of the API requires a token
+	auth := router.Group("/api/v1/auth")
+	{
+		auth.POST("/register", handlers.Auth.Register)
+		auth.POST("/login", handlers.Auth.Login)
+	}
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
+	if cfg.AuthEnabled {
+		v1.Use(handlers.Auth.requireAuth())
+	}
 	{
 		// Track routes
 		tracks := v1.Group("/tracks")
 		{
-			tracks.GET("", handlers.Track.List)
+			tracks.GET("", listCache, handlers.Track.List)
+			tracks.GET("/shuffle", handlers.Track.Shuffle)
 			tracks.GET("/:id", handlers.Track.Get)
-			tracks.GET("/:id/stream", handlers.Stream.Stream)
+			tracks.GET("/:id/stream", limitStreams(streamLimiter), handlers.Stream.Stream)
+			tracks.GET("/:id/analysis", handlers.Track.Analysis)
+			tracks.GET("/:id/now-playing", handlers.Track.NowPlaying)
+			tracks.GET("/:id/artwork", handlers.Artwork.Track)
+			tracks.GET("/:id/audioinfo", handlers.Track.AudioInfo)
+			tracks.GET("/:id/chapters", handlers.Track.Chapters)
+			tracks.GET("/:id/lyrics", handlers.Track.Lyrics)
+			tracks.GET("/:id/gapless", handlers.Track.Gapless)
+			tracks.PUT("/:id/rating", handlers.Track.SetRating)
+			tracks.POST("/:id/play", handlers.Track.RecordPlay)
+			tracks.POST("/:id/scrobble", handlers.Track.Scrobble)
 		}
 
 		// Album routes
 		albums := v1.Group("/albums")
 		{
-			albums.GET("", handlers.Album.List)
+			albums.GET("", listCache, handlers.Album.List)
+			albums.GET("/singles", listCache, handlers.Album.Singles)
 			albums.GET("/:id", handlers.Album.Get)
+			albums.PATCH("/:id", handlers.Album.Update)
+			albums.PUT("/:id/track-order", handlers.Album.SetTrackOrder)
 		}
 
 		// Artist routes
 		artists := v1.Group("/artists")
 		{
-			artists.GET("", handlers.Artist.List)
+			artists.GET("", listCache, handlers.Artist.List)
 			artists.GET("/:id", handlers.Artist.Get)
+			artists.GET("/:id/discography", handlers.Artist.Discography)
+			artists.GET("/:id/tracks", handlers.Artist.Tracks)
+			artists.GET("/:id/stats", handlers.Artist.Stats)
 		}
 
 		// Playlist routes
 		playlists := v1.Group("/playlists")
 		{
-			playlists.GET("", handlers.Playlist.List)
+			playlists.GET("", listCache, handlers.Playlist.List)
 			playlists.POST("", handlers.Playlist.Create)
 			playlists.GET("/:id", handlers.Playlist.Get)
+			playlists.GET("/:id/stats", handlers.Playlist.Stats)
 			playlists.PUT("/:id", handlers.Playlist.Update)
 			playlists.DELETE("/:id", handlers.Playlist.Delete)
 			playlists.POST("/:id/tracks", handlers.Playlist.AddTrack)
+			playlists.POST("/:id/merge", handlers.Playlist.Merge)
 			playlists.PUT("/:id/tracks/reorder", handlers.Playlist.ReorderTracks)
 			playlists.DELETE("/:id/tracks/:trackId", handlers.Playlist.RemoveTrack)
 		}
 
 		// Search & Discovery routes
-		v1.GET("/search", handlers.Search.Search)
-		v1.GET("/recent", handlers.Search.Recent)
-		v1.GET("/random", handlers.Search.Random)
+		searchTimeout := requestTimeout(cfg.SearchTimeout)
+		v1.GET("/search", searchTimeout, handlers.Search.Search)
+		v1.GET("/search/stream", handlers.Search.SearchStream)
+		v1.GET("/recent", searchTimeout, handlers.Search.Recent)
+		v1.GET("/random", searchTimeout, handlers.Search.Random)
+
+		// Catalog index for clients mirroring the library
+		v1.GET("/index", handlers.Index.Index)
 
 		// Library management routes
 		library := v1.Group("/library")
+		library.Use(requestTimeout(cfg.LibraryTimeout))
 		{
 			library.POST("/scan", handlers.Library.Scan)
 			library.GET("/scan/status", handlers.Library.ScanStatus)
 			library.POST("/scan/cancel", handlers.Library.CancelScan)
 			library.GET("/stats", handlers.Library.Stats)
+			library.GET("/preview", handlers.Library.Preview)
+			library.GET("/incomplete-metadata", handlers.Library.IncompleteMetadata)
 		}
 
+		// Uploads can take longer than the library timeout allows, and the
+		// scan event socket stays open
+		v1.POST("/library/upload", handlers.Library.Upload)
+		v1.GET("/library/scan/events", handlers.Library.ScanEvents)
+
 		// Setup/onboarding routes
 		setup := v1.Group("/setup")
 		{
@@ -152,8 +311,45 @@ func NewRouter(
 			setup.POST("/complete", handlers.Setup.Complete)
 		}
 
+		// User routes, only for signed-in users
+		users := v1.Group("/users")
+		users.Use(requireUser())
+		{
+			users.GET("/me/view-preferences", handlers.User.GetViewPreferences)
+			users.PUT("/me/view-preferences", handlers.User.SetViewPreferences)
+			users.GET("/me/lastfm", handlers.User.GetLastFM)
+			users.PUT("/me/lastfm", handlers.User.SetLastFM)
+			users.DELETE("/me/lastfm", handlers.User.DeleteLastFM)
+		}
+
+		// Admin routes, for the administrator account or, without
+		// authentication, the admin token
+		admin := v1.Group("/admin")
+		admin.Use(handlers.Auth.requireAdmin(cfg.AdminToken))
+		{
+			admin.POST("/backup", handlers.Admin.Backup)
+			admin.GET("/backup/download", handlers.Admin.DownloadBackup)
+			admin.POST("/artwork/reprocess", handlers.Admin.ReprocessArtwork)
+			admin.GET("/artwork/status", handlers.Admin.ArtworkStatus)
+			admin.POST("/artwork/cancel", handlers.Admin.CancelArtwork)
+			admin.GET("/integrity", handlers.Admin.Integrity)
+			admin.POST("/integrity/fix", handlers.Admin.FixIntegrity)
+			admin.POST("/normalize-tags", handlers.Admin.NormalizeTags)
+			admin.GET("/normalize-tags/status", handlers.Admin.NormalizeTagsStatus)
+			admin.GET("/missing-artwork", handlers.Admin.MissingArtwork)
+			admin.GET("/transcode/profiles", handlers.Admin.ListTranscodeProfiles)
+			admin.POST("/transcode/profiles", handlers.Admin.SaveTranscodeProfile)
+			admin.GET("/transcode/active", handlers.Admin.ActiveTranscodes)
+			admin.GET("/transcode/recheck", handlers.Admin.RecheckTranscoder)
+			admin.DELETE("/albums/:id", handlers.Album.Delete)
+			admin.DELETE("/artists/:id", handlers.Artist.Delete)
+			admin.PUT("/artists/:id/image", handlers.Artist.SetImage)
+		}
+
 		// Artwork routes
 		v1.GET("/artwork/:type/:id", handlers.Artwork.Get)
+		v1.POST("/artwork/status", handlers.Artwork.Status)
+		v1.POST("/artwork/batch", handlers.Artwork.Batch)
 	}
 
 	return router
@@ -172,7 +368,7 @@ func requestLogger() gin.HandlerFunc {
 		status := c.Writer.Status()
 
 		if query != "" {
-			path = path + "?" + query
+			path = path + "?" + redactQuery(query)
 		}
 
 		slog.Info("request",
@@ -185,13 +381,32 @@ func requestLogger() gin.HandlerFunc {
 	}
 }
 
+// redactedParams are query parameters whose values are kept out of the logs
+var redactedParams = []string{"token"}
+
+// redactQuery returns a raw query string with the values of redactedParams
+// replaced, keeping the order and encoding of everything else
+func redactQuery(query string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		if !hasValue {
+			continue
+		}
+		if name, err := url.QueryUnescape(key); err == nil && slices.Contains(redactedParams, name) {
+			params[i] = key + "=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
 // configureCORS returns CORS middleware configuration
 func configureCORS(allowedOrigins []string) gin.HandlerFunc {
 	config := cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Range"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Accept-Ranges"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Accept-Ranges", "X-Stream-Offset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"harmony/internal/services"
+)
+
+func TestUnmatchedRoutes(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+		code   string
+		allow  string
+	}{
+		{"unknown API path", http.MethodGet, "/api/v1/nothing", http.StatusNotFound, "NOT_FOUND", ""},
+		{"unknown path", http.MethodGet, "/nothing", http.StatusNotFound, "NOT_FOUND", ""},
+		{"wrong method", http.MethodDelete, "/api/v1/tracks", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", http.MethodGet},
+		{"wrong method on health", http.MethodPut, "/health", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(tt.method, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", ct)
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+			}
+			if resp.Success || resp.Error == nil || resp.Error.Code != tt.code || resp.Error.Message == "" {
+				t.Errorf("response = %s, want a %s error", rec.Body.String(), tt.code)
+			}
+			if tt.allow != "" && !strings.Contains(rec.Header().Get("Allow"), tt.allow) {
+				t.Errorf("Allow = %q, want it to list %s", rec.Header().Get("Allow"), tt.allow)
+			}
+		})
+	}
+}
+
+func TestHealthMediaRoot(t *testing.T) {
+	env := newTestEnv(t, nil)
+	events := make(chan string, 10)
+	env.lib.OnScanEvent(func(event services.ScanEvent) {
+		if event.MediaRoot != nil {
+			events <- event.Type
+		}
+	})
+	unmounted := env.mediaRoot + ".unmounted"
+
+	// health reads the status and, when a check was made, the media root
+	health := func() (string, *bool) {
+		t.Helper()
+		rec := env.do(http.MethodGet, "/health", nil)
+		expectStatus(t, rec, http.StatusOK)
+		var body struct {
+			Status    string `json:"status"`
+			MediaRoot *struct {
+				Available bool   `json:"available"`
+				Error     string `json:"error"`
+			} `json:"mediaRoot"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.MediaRoot == nil {
+			return body.Status, nil
+		}
+		return body.Status, &body.MediaRoot.Available
+	}
+
+	if status, available := health(); status != "healthy" || available != nil {
+		t.Fatalf("health before any check = %q with root %v, want healthy without a root", status, available)
+	}
+
+	// Steps run in order, each changing the root before checking it
+	tests := []struct {
+		name      string
+		change    func()
+		status    string
+		available bool
+		event     string
+	}{
+		{"mounted", func() {
+			if err := os.WriteFile(filepath.Join(env.mediaRoot, "song.mp3"), []byte("audio"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "healthy", true, ""},
+		{"unmounted", func() {
+			if err := os.Rename(env.mediaRoot, unmounted); err != nil {
+				t.Fatal(err)
+			}
+		}, "degraded", false, "media_root_unavailable"},
+		{"still unmounted", func() {}, "degraded", false, ""},
+		{"remounted", func() {
+			if err := os.Rename(unmounted, env.mediaRoot); err != nil {
+				t.Fatal(err)
+			}
+		}, "healthy", true, "media_root_available"},
+		{"empty mount point", func() {
+			env.seedLibrary()
+			if err := os.Remove(filepath.Join(env.mediaRoot, "song.mp3")); err != nil {
+				t.Fatal(err)
+			}
+		}, "degraded", false, "media_root_unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			env.lib.CheckMediaRoot(context.Background())
+
+			status, available := health()
+			if status != tt.status || available == nil || *available != tt.available {
+				t.Errorf("health = %q with root available %v, want %q and %v", status, available, tt.status, tt.available)
+			}
+
+			select {
+			case event := <-events:
+				if event != tt.event {
+					t.Errorf("event = %q, want %q", event, tt.event)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.event != "" {
+					t.Errorf("no %s event", tt.event)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"harmony/internal/services"
+)
+
+// errOriginNotAllowed rejects WebSocket handshakes from other sites
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+const (
+	// scanEventStatus is the type of the event carrying the current progress
+	// sent when a client connects
+	scanEventStatus = "scan_status"
+	// scanEventWriteTimeout drops clients that stop reading
+	scanEventWriteTimeout = 10 * time.Second
+)
+
+// ScanEvents handles GET /api/v1/library/scan/events
+// Upgrades to a WebSocket and sends every scan event as a JSON message,
+// starting with a scan_status event holding the current progress so clients
+// joining mid-scan aren't blank. Clients aren't expected to send anything;
+// closing the socket unsubscribes them.
+func (h *LibraryHandler) ScanEvents(c *gin.Context) {
+	server := websocket.Server{
+		Handshake: checkOrigin(h.allowedOrigins),
+		Handler:   h.streamScanEvents,
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin returns a WebSocket handshake check standing in for CORS,
+// which browsers don't apply to WebSockets. Without it any page could open
+// the socket and read the file paths the events carry. Pages from the
+// server's own host and from allowed are accepted, as is any origin when
+// allowed is empty or holds "*" like the CORS policy. Clients other than
+// browsers send no Origin and are let through.
+func checkOrigin(allowed []string) func(*websocket.Config, *http.Request) error {
+	return func(_ *websocket.Config, req *http.Request) error {
+		origin := req.Header.Get("Origin")
+		if origin == "" || len(allowed) == 0 || slices.Contains(allowed, "*") || slices.Contains(allowed, origin) {
+			return nil
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, req.Host) {
+			return nil
+		}
+		return errOriginNotAllowed
+	}
+}
+
+// streamScanEvents relays scan events to one WebSocket client until it
+// disconnects or stops reading
+func (h *LibraryHandler) streamScanEvents(ws *websocket.Conn) {
+	defer ws.Close()
+
+	// The server's write timeout would otherwise cut the socket off
+	ws.SetDeadline(time.Time{})
+
+	done := make(chan struct{})
+	defer close(done)
+
+	events := make(chan services.ScanEvent)
+	unsubscribe := h.service.OnScanEvent(func(event services.ScanEvent) {
+		select {
+		case events <- event:
+		case <-done:
+		}
+	})
+	defer unsubscribe()
+
+	// Reads only fail once the client has gone
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	if !h.sendScanEvent(ws, services.ScanEvent{Type: scanEventStatus, Progress: h.service.GetProgress()}) {
+		return
+	}
+	for {
+		select {
+		case event := <-events:
+			if !h.sendScanEvent(ws, event) {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// sendScanEvent writes an event to the client, reporting whether it could
+func (h *LibraryHandler) sendScanEvent(ws *websocket.Conn, event services.ScanEvent) bool {
+	ws.SetWriteDeadline(time.Now().Add(scanEventWriteTimeout))
+	return websocket.JSON.Send(ws, event) == nil
+}
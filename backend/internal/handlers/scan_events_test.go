@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+
+	"harmony/internal/services"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		ok      bool
+	}{
+		{"no origin", []string{"https://app.example.com"}, "", true},
+		{"allowed", []string{"https://app.example.com"}, "https://app.example.com", true},
+		{"same host", []string{"https://app.example.com"}, "http://harmony.local:8080", true},
+		{"other site", []string{"https://app.example.com"}, "https://evil.example.com", false},
+		{"wildcard", []string{"*"}, "https://evil.example.com", true},
+		{"none configured", nil, "https://evil.example.com", true},
+		{"malformed", []string{"https://app.example.com"}, "://", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://harmony.local:8080/api/v1/library/scan/events", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			err := checkOrigin(tt.allowed)(nil, req)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkOrigin = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestScanEventsOrigin(t *testing.T) {
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		cfg.AllowedOrigins = []string{"https://app.example.com"}
+	})
+	server := httptest.NewServer(env.router)
+	defer server.Close()
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/library/scan/events"
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"https://app.example.com", true},
+		{server.URL, true},
+		{"https://evil.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			ws, err := websocket.Dial(endpoint, "", tt.origin)
+			if !tt.ok {
+				if err == nil {
+					ws.Close()
+					t.Fatal("handshake from a disallowed origin succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dialing: %v", err)
+			}
+			defer ws.Close()
+
+			var event services.ScanEvent
+			if err := websocket.JSON.Receive(ws, &event); err != nil {
+				t.Fatalf("receiving: %v", err)
+			}
+			if event.Type != scanEventStatus {
+				t.Errorf("first event = %q, want %q", event.Type, scanEventStatus)
+			}
+		})
+	}
+}
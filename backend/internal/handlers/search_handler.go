@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/models"
 )
 
 // SearchHandler handles search and discovery endpoints
@@ -12,6 +17,10 @@ type SearchHandler struct {
 	albumRepo  *database.AlbumRepository
 	artistRepo *database.ArtistRepository
 	redis      *database.RedisClient
+	// timeout bounds streaming searches, which can't use the request
+	// timeout middleware
+	timeout    time.Duration
+	timeFormat TimeFormat
 }
 
 // NewSearchHandler creates a new SearchHandler
@@ -20,12 +29,16 @@ func NewSearchHandler(
 	albumRepo *database.AlbumRepository,
 	artistRepo *database.ArtistRepository,
 	redis *database.RedisClient,
+	timeout time.Duration,
+	timeFormat TimeFormat,
 ) *SearchHandler {
 	return &SearchHandler{
 		trackRepo:  trackRepo,
 		albumRepo:  albumRepo,
 		artistRepo: artistRepo,
 		redis:      redis,
+		timeout:    timeout,
+		timeFormat: timeFormat,
 	}
 }
 
@@ -45,12 +58,7 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		return
 	}
 
-	limit := 10
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := parseInt(limitStr); err == nil && l > 0 && l <= 50 {
-			limit = l
-		}
-	}
+	limit := parseSearchLimit(c)
 
 	ctx := c.Request.Context()
 
@@ -65,48 +73,18 @@ func (h *SearchHandler) Search(c *gin.Context) {
 
 	// Search tracks
 	tracks, _ := h.trackRepo.Search(ctx, query, limit)
-	trackResponses := make([]TrackResponse, len(tracks))
-	for i, track := range tracks {
-		trackResponses[i] = TrackResponse{
-			ID:       track.ID,
-			Title:    track.Title,
-			Duration: track.Duration,
-			Format:   track.Format,
-			AlbumID:  track.AlbumID,
-			ArtistID: track.ArtistID,
-		}
-	}
 
 	// Search albums
 	albums, _ := h.albumRepo.Search(ctx, query, limit)
-	albumResponses := make([]AlbumResponse, len(albums))
-	for i, album := range albums {
-		albumResponses[i] = AlbumResponse{
-			ID:       album.ID,
-			Title:    album.Title,
-			Year:     album.Year,
-			ArtistID: album.ArtistID,
-		}
-		if album.Artist != nil {
-			albumResponses[i].ArtistName = album.Artist.Name
-		}
-	}
 
 	// Search artists
 	artists, _ := h.artistRepo.Search(ctx, query, limit)
-	artistResponses := make([]ArtistResponse, len(artists))
-	for i, artist := range artists {
-		artistResponses[i] = ArtistResponse{
-			ID:   artist.ID,
-			Name: artist.Name,
-		}
-	}
 
 	response := SearchResponse{
 		Query:   query,
-		Tracks:  trackResponses,
-		Albums:  albumResponses,
-		Artists: artistResponses,
+		Tracks:  recentTrackResponses(tracks),
+		Albums:  recentAlbumResponses(albums),
+		Artists: searchArtistResponses(artists),
 	}
 
 	// Cache results
@@ -117,7 +95,43 @@ func (h *SearchHandler) Search(c *gin.Context) {
 	Success(c, response)
 }
 
+// parseSearchLimit reads the per-category result limit of a search
+func parseSearchLimit(c *gin.Context) int {
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parseInt(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// searchArtistResponses builds the summary responses used by search
+func searchArtistResponses(artists []models.Artist) []ArtistResponse {
+	response := make([]ArtistResponse, len(artists))
+	for i, artist := range artists {
+		response[i] = ArtistResponse{
+			ID:   artist.ID,
+			Name: artist.Name,
+		}
+	}
+	return response
+}
+
+// RecentSection is one period of a grouped recently-added feed
+type RecentSection struct {
+	Period string      `json:"period"`
+	Start  string      `json:"start"`
+	Count  int         `json:"count"`
+	Items  interface{} `json:"items"`
+}
+
 // Recent handles GET /api/v1/recent
+// With ?groupBy=day or ?groupBy=week the results are returned as sections,
+// newest period first. ?artistId= keeps one artist's tracks or albums,
+// ?albumId= one album's tracks, and ?addedAfter=/?addedBefore= those added
+// within a time range. ?type=played lists the user's play history instead,
+// the time range bounding when tracks were played.
 func (h *SearchHandler) Recent(c *gin.Context) {
 	limit := 20
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -126,51 +140,200 @@ func (h *SearchHandler) Recent(c *gin.Context) {
 		}
 	}
 
+	var grouping database.DateGrouping
+	if groupBy := c.Query("groupBy"); groupBy != "" {
+		g, err := database.ParseDateGrouping(groupBy)
+		if err != nil {
+			BadRequest(c, "groupBy must be day or week")
+			return
+		}
+		grouping = g
+	}
+
+	filter, err := parseAddedFilter(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
 	ctx := c.Request.Context()
 	resourceType := c.DefaultQuery("type", "tracks")
+	if resourceType == "albums" && filter.AlbumID != "" {
+		BadRequest(c, "albumId only filters tracks")
+		return
+	}
 
 	switch resourceType {
+	case "played":
+		userID := requestUserID(c)
+		if grouping != "" {
+			groups, err := h.trackRepo.GetRecentlyPlayedGrouped(ctx, userID, limit, grouping, filter)
+			if err != nil {
+				InternalError(c, "failed to get recent plays")
+				return
+			}
+
+			sections := make([]RecentSection, len(groups))
+			for i, group := range groups {
+				sections[i] = newRecentSection(h.timeFormat, group.Start, len(group.Plays), recentPlayResponses(h.timeFormat, group.Plays))
+			}
+			Success(c, sections)
+			return
+		}
+
+		plays, err := h.trackRepo.GetRecentlyPlayed(ctx, userID, limit, filter)
+		if err != nil {
+			InternalError(c, "failed to get recent plays")
+			return
+		}
+		Success(c, recentPlayResponses(h.timeFormat, plays))
+
 	case "albums":
-		albums, err := h.albumRepo.GetRecentlyAdded(ctx, limit)
+		if grouping != "" {
+			groups, err := h.albumRepo.GetRecentlyAddedGrouped(ctx, limit, grouping, filter)
+			if err != nil {
+				InternalError(c, "failed to get recent albums")
+				return
+			}
+
+			sections := make([]RecentSection, len(groups))
+			for i, group := range groups {
+				sections[i] = newRecentSection(h.timeFormat, group.Start, len(group.Albums), recentAlbumResponses(group.Albums))
+			}
+			Success(c, sections)
+			return
+		}
+
+		albums, err := h.albumRepo.GetRecentlyAdded(ctx, limit, filter)
 		if err != nil {
 			InternalError(c, "failed to get recent albums")
 			return
 		}
+		Success(c, recentAlbumResponses(albums))
 
-		response := make([]AlbumResponse, len(albums))
-		for i, album := range albums {
-			response[i] = AlbumResponse{
-				ID:       album.ID,
-				Title:    album.Title,
-				Year:     album.Year,
-				ArtistID: album.ArtistID,
+	default: // tracks
+		if grouping != "" {
+			groups, err := h.trackRepo.GetRecentlyAddedGrouped(ctx, limit, grouping, filter)
+			if err != nil {
+				InternalError(c, "failed to get recent tracks")
+				return
 			}
-			if album.Artist != nil {
-				response[i].ArtistName = album.Artist.Name
+
+			sections := make([]RecentSection, len(groups))
+			for i, group := range groups {
+				sections[i] = newRecentSection(h.timeFormat, group.Start, len(group.Tracks), recentTrackResponses(group.Tracks))
 			}
+			Success(c, sections)
+			return
 		}
-		Success(c, response)
 
-	default: // tracks
-		tracks, err := h.trackRepo.GetRecentlyAdded(ctx, limit)
+		tracks, err := h.trackRepo.GetRecentlyAdded(ctx, limit, filter)
 		if err != nil {
 			InternalError(c, "failed to get recent tracks")
 			return
 		}
+		Success(c, recentTrackResponses(tracks))
+	}
+}
 
-		response := make([]TrackResponse, len(tracks))
-		for i, track := range tracks {
-			response[i] = TrackResponse{
-				ID:       track.ID,
-				Title:    track.Title,
-				Duration: track.Duration,
-				Format:   track.Format,
-				AlbumID:  track.AlbumID,
-				ArtistID: track.ArtistID,
-			}
+// parseAddedFilter reads the artistId, albumId, addedAfter and addedBefore
+// filters of the recently-added feed; the times are RFC 3339
+func parseAddedFilter(c *gin.Context) (database.AddedFilter, error) {
+	filter := database.AddedFilter{ArtistID: c.Query("artistId"), AlbumID: c.Query("albumId")}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"addedAfter", &filter.After},
+		{"addedBefore", &filter.Before},
+	} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
 		}
-		Success(c, response)
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 time", param.name)
+		}
+		*param.dest = t
+	}
+	if !filter.After.IsZero() && !filter.Before.IsZero() && !filter.After.Before(filter.Before) {
+		return filter, errors.New("addedAfter must be before addedBefore")
+	}
+	return filter, nil
+}
+
+// newRecentSection builds a section of the grouped recently-added feed
+func newRecentSection(timeFormat TimeFormat, start time.Time, count int, items interface{}) RecentSection {
+	return RecentSection{
+		Period: start.Format("2006-01-02"),
+		Start:  timeFormat.Format(start),
+		Count:  count,
+		Items:  items,
+	}
+}
+
+// recentAlbumResponses builds the summary responses used by the recent feed
+// and search
+func recentAlbumResponses(albums []models.Album) []AlbumResponse {
+	response := make([]AlbumResponse, len(albums))
+	for i, album := range albums {
+		response[i] = AlbumResponse{
+			ID:       album.ID,
+			Title:    album.Title,
+			Year:     album.Year,
+			ArtistID: album.ArtistID,
+		}
+		if album.Artist != nil {
+			response[i].ArtistName = album.Artist.Name
+		}
+	}
+	return response
+}
+
+// recentTrackResponses builds the summary responses used by the recent feed
+// and search
+func recentTrackResponses(tracks []models.Track) []TrackResponse {
+	response := make([]TrackResponse, len(tracks))
+	for i, track := range tracks {
+		response[i] = recentTrackResponse(track)
+	}
+	return response
+}
+
+// recentTrackResponse builds the short track response of the recent feeds
+func recentTrackResponse(track models.Track) TrackResponse {
+	return TrackResponse{
+		ID:       track.ID,
+		Title:    track.Title,
+		Duration: track.Duration,
+		Format:   track.Format,
+		AlbumID:  track.AlbumID,
+		ArtistID: track.ArtistID,
+	}
+}
+
+// PlayedTrackResponse is a track in the play history with when it was played
+type PlayedTrackResponse struct {
+	TrackResponse
+	PlayedAt  string `json:"playedAt"`
+	Completed bool   `json:"completed"`
+}
+
+// recentPlayResponses builds the play history responses
+func recentPlayResponses(timeFormat TimeFormat, plays []models.Play) []PlayedTrackResponse {
+	response := make([]PlayedTrackResponse, 0, len(plays))
+	for _, play := range plays {
+		if play.Track == nil {
+			continue
+		}
+		response = append(response, PlayedTrackResponse{
+			TrackResponse: recentTrackResponse(*play.Track),
+			PlayedAt:      timeFormat.Format(play.PlayedAt),
+			Completed:     play.Completed,
+		})
 	}
+	return response
 }
 
 // Random handles GET /api/v1/random
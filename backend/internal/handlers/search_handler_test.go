@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	env.exec(
+		`UPDATE tracks SET created_at = '2024-01-05 10:00:00' WHERE id IN ('t1', 't2')`,
+		`UPDATE tracks SET created_at = '2024-02-05 10:00:00' WHERE id IN ('t3', 't4')`,
+		`INSERT INTO plays (track_id, user_id, completed, played_at) VALUES
+			('t1', 'default-user', true, '2024-03-01 09:00:00'),
+			('t3', 'default-user', false, '2024-03-02 09:00:00'),
+			('t2', 'default-user', true, '2024-03-02 10:00:00'),
+			('t4', 'someone-else', true, '2024-03-03 09:00:00')`,
+	)
+
+	// Tracks added together come back in either order, so those are sorted;
+	// plays are newest first
+	tests := []struct {
+		name   string
+		path   string
+		want   int
+		ids    []string
+		sorted bool
+	}{
+		{"added to an album", "/api/v1/recent?albumId=al1", http.StatusOK, []string{"t1", "t2"}, true},
+		{"added by an artist since", "/api/v1/recent?artistId=ar1&addedAfter=2024-02-01T00:00:00Z", http.StatusOK, []string{"t3", "t4"}, true},
+		{"albums by album", "/api/v1/recent?type=albums&albumId=al1", http.StatusBadRequest, nil, false},
+		{"played", "/api/v1/recent?type=played", http.StatusOK, []string{"t2", "t3", "t1"}, false},
+		{"played from an album", "/api/v1/recent?type=played&albumId=al1", http.StatusOK, []string{"t2", "t1"}, false},
+		{"played in a range", "/api/v1/recent?type=played&addedAfter=2024-03-02T00:00:00Z&addedBefore=2024-03-02T09:30:00Z", http.StatusOK, []string{"t3"}, false},
+		{"played by another user", "/api/v1/recent?type=played&userId=someone-else", http.StatusOK, []string{"t4"}, false},
+		{"invalid range", "/api/v1/recent?type=played&addedAfter=yesterday", http.StatusBadRequest, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var tracks []PlayedTrackResponse
+			decodeData(t, rec, &tracks)
+			var ids []string
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+			}
+			if tt.sorted {
+				slices.Sort(ids)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("tracks = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	// Play history grouped by the day played
+	rec := env.do(http.MethodGet, "/api/v1/recent?type=played&groupBy=day", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var sections []struct {
+		Period string                `json:"period"`
+		Items  []PlayedTrackResponse `json:"items"`
+	}
+	decodeData(t, rec, &sections)
+	if len(sections) != 2 || sections[0].Period != "2024-03-02" || len(sections[0].Items) != 2 {
+		t.Errorf("sections = %+v, want 2024-03-02 with 2 plays first", sections)
+	}
+	if len(sections) > 0 && len(sections[0].Items) > 0 && (sections[0].Items[0].PlayedAt == "" || !sections[0].Items[0].Completed) {
+		t.Errorf("latest play = %+v, want its time and completion", sections[0].Items[0])
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Categories of the streaming search, also used as the SSE event names
+const (
+	searchCategoryTracks  = "tracks"
+	searchCategoryAlbums  = "albums"
+	searchCategoryArtists = "artists"
+)
+
+// SearchCategoryEvent carries the results of one category of a streaming search
+type SearchCategoryEvent struct {
+	Query    string      `json:"query"`
+	Category string      `json:"category"`
+	Results  interface{} `json:"results"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// searchResult is a finished category query waiting to be sent
+type searchResult struct {
+	category string
+	results  interface{}
+	err      error
+}
+
+// SearchStream handles GET /api/v1/search/stream
+// Runs the track, album and artist searches concurrently and sends each
+// category as a server-sent event as soon as its query completes, followed
+// by a done event. Instant-search UIs can render the fast categories without
+// waiting for the slowest one.
+func (h *SearchHandler) SearchStream(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		BadRequest(c, "search query required")
+		return
+	}
+	limit := parseSearchLimit(c)
+
+	ctx := c.Request.Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	// A cached full search can be sent straight away
+	if h.redis != nil {
+		var cached SearchResponse
+		if err := h.redis.GetCachedSearchResults(ctx, query, &cached); err == nil {
+			h.sendSearchEvent(c, query, searchResult{category: searchCategoryTracks, results: cached.Tracks})
+			h.sendSearchEvent(c, query, searchResult{category: searchCategoryAlbums, results: cached.Albums})
+			h.sendSearchEvent(c, query, searchResult{category: searchCategoryArtists, results: cached.Artists})
+			c.SSEvent("done", gin.H{"query": query})
+			return
+		}
+	}
+
+	results := make(chan searchResult, 3)
+	go func() {
+		tracks, err := h.trackRepo.Search(ctx, query, limit)
+		results <- searchResult{category: searchCategoryTracks, results: recentTrackResponses(tracks), err: err}
+	}()
+	go func() {
+		albums, err := h.albumRepo.Search(ctx, query, limit)
+		results <- searchResult{category: searchCategoryAlbums, results: recentAlbumResponses(albums), err: err}
+	}()
+	go func() {
+		artists, err := h.artistRepo.Search(ctx, query, limit)
+		results <- searchResult{category: searchCategoryArtists, results: searchArtistResponses(artists), err: err}
+	}()
+
+	response := SearchResponse{Query: query}
+	failed := false
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-results:
+			h.sendSearchEvent(c, query, result)
+			if result.err != nil {
+				failed = true
+				continue
+			}
+			switch result.category {
+			case searchCategoryTracks:
+				response.Tracks = result.results.([]TrackResponse)
+			case searchCategoryAlbums:
+				response.Albums = result.results.([]AlbumResponse)
+			case searchCategoryArtists:
+				response.Artists = result.results.([]ArtistResponse)
+			}
+		case <-ctx.Done():
+			c.SSEvent("error", gin.H{"query": query, "error": "search timed out"})
+			return
+		}
+	}
+
+	// Only complete results are cached, so a later search can't miss a category
+	if h.redis != nil && !failed {
+		h.redis.CacheSearchResults(ctx, query, response)
+	}
+
+	c.SSEvent("done", gin.H{"query": query})
+}
+
+// sendSearchEvent writes one category's results and flushes them to the client
+func (h *SearchHandler) sendSearchEvent(c *gin.Context, query string, result searchResult) {
+	event := SearchCategoryEvent{
+		Query:    query,
+		Category: result.category,
+		Results:  result.results,
+	}
+	if result.err != nil {
+		event.Error = "search failed"
+	}
+
+	c.SSEvent(result.category, event)
+	c.Writer.Flush()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sseFrame is one server-sent event of a response body
+type sseFrame struct {
+	event string
+	data  string
+}
+
+// parseSSE splits a server-sent event stream into its frames
+func parseSSE(t *testing.T, body string) []sseFrame {
+	t.Helper()
+	var frames []sseFrame
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var frame sseFrame
+		for _, line := range strings.Split(block, "\n") {
+			name, value, _ := strings.Cut(line, ":")
+			switch name {
+			case "event":
+				frame.event = value
+			case "data":
+				frame.data = value
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestSearchStream(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string][]string
+	}{
+		{"one of each", "Hit", map[string][]string{
+			searchCategoryTracks:  {"t3"},
+			searchCategoryAlbums:  {"al2"},
+			searchCategoryArtists: nil,
+		}},
+		{"several matches", "o", map[string][]string{
+			searchCategoryTracks:  {"t1", "t2", "t4"},
+			searchCategoryAlbums:  {"al3"},
+			searchCategoryArtists: {"ar2"},
+		}},
+		{"no matches", "zzz", map[string][]string{
+			searchCategoryTracks:  nil,
+			searchCategoryAlbums:  nil,
+			searchCategoryArtists: nil,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/search/stream?q="+tt.query, nil)
+			expectStatus(t, rec, http.StatusOK)
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+				t.Errorf("Content-Type = %q, want text/event-stream", ct)
+			}
+
+			// Each category arrives in its own frame, in whatever order the
+			// queries finish, and done comes last
+			frames := parseSSE(t, rec.Body.String())
+			if len(frames) != 4 || frames[3].event != "done" {
+				t.Fatalf("frames = %+v, want three categories then done", frames)
+			}
+			got := map[string][]string{}
+			for _, frame := range frames[:3] {
+				var event struct {
+					SearchCategoryEvent
+					Results []struct {
+						ID string `json:"id"`
+					} `json:"results"`
+				}
+				if err := json.Unmarshal([]byte(frame.data), &event); err != nil {
+					t.Fatalf("decoding %s frame: %v", frame.event, err)
+				}
+				if event.Category != frame.event || event.Query != tt.query || event.Error != "" {
+					t.Errorf("%s frame = %+v", frame.event, event.SearchCategoryEvent)
+				}
+				if _, seen := got[frame.event]; seen {
+					t.Errorf("%s sent twice", frame.event)
+				}
+				var ids []string
+				for _, result := range event.Results {
+					ids = append(ids, result.ID)
+				}
+				slices.Sort(ids)
+				got[frame.event] = ids
+			}
+			for category, want := range tt.want {
+				ids, ok := got[category]
+				if !ok {
+					t.Errorf("no %s frame", category)
+					continue
+				}
+				if !slices.Equal(ids, want) {
+					t.Errorf("%s = %v, want %v", category, ids, want)
+				}
+			}
+		})
+	}
+
+	rec := env.do(http.MethodGet, "/api/v1/search/stream", nil)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
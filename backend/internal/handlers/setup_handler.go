@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -166,6 +168,7 @@ func (h *SetupHandler) SetSelectedFolders(c *gin.Context) {
 		InternalError(c, "failed to save selected folders")
 		return
 	}
+	h.libraryService.SetMediaPaths(req.Paths)
 
 	Success(c, gin.H{
 		"message": "folders saved",
@@ -173,6 +176,16 @@ func (h *SetupHandler) SetSelectedFolders(c *gin.Context) {
 	})
 }
 
+// restoreMediaPaths limits scans to the folders selected during setup
+func restoreMediaPaths(ctx context.Context, settings *database.SettingsRepository, library *services.LibraryService) {
+	paths, err := settings.GetMediaPaths(ctx)
+	if err != nil {
+		slog.Warn("failed to load media paths", "error", err)
+		return
+	}
+	library.SetMediaPaths(paths)
+}
+
 // Complete handles POST /api/v1/setup/complete
 func (h *SetupHandler) Complete(c *gin.Context) {
 	var req struct {
@@ -5,16 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/models"
+	"harmony/internal/services"
 	"harmony/internal/transcoder"
 )
 
@@ -26,6 +30,7 @@ var audioMIMETypes = map[string]string{
 	"ogg":  "audio/ogg",
 	"m4a":  "audio/mp4",
 	"aac":  "audio/aac",
+	"adts": "audio/aac",
 	"opus": "audio/opus",
 	"wma":  "audio/x-ms-wma",
 }
@@ -35,6 +40,18 @@ type StreamHandler struct {
 	trackRepo   *database.TrackRepository
 	transcoder  *transcoder.Transcoder
 	mediaRoot   string
+	// bufferSize is the copy buffer used when serving files; 0 leaves
+	// buffering to io.Copy
+	bufferSize int
+	buffers    sync.Pool
+	// placeholder is the clip streamed for tracks whose file is missing;
+	// nil responds with 404
+	placeholder []byte
+	// placeholderClips holds the clip transcoded to each profile asked for
+	placeholderClips map[string][]byte
+	placeholderMu    sync.Mutex
+	// library is told about missing files so it can verify and remove them
+	library *services.LibraryService
 }
 
 // NewStreamHandler creates a new StreamHandler
@@ -42,11 +59,23 @@ func NewStreamHandler(
 	trackRepo *database.TrackRepository,
 	transcoder *transcoder.Transcoder,
 	mediaRoot string,
+	bufferSize int,
+	placeholder string,
+	library *services.LibraryService,
 ) *StreamHandler {
 	return &StreamHandler{
-		trackRepo:  trackRepo,
-		transcoder: transcoder,
-		mediaRoot:  mediaRoot,
+		trackRepo:   trackRepo,
+		transcoder:  transcoder,
+		mediaRoot:   mediaRoot,
+		bufferSize:  bufferSize,
+		placeholder: placeholderWAV(placeholder),
+		library:     library,
+		buffers: sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, bufferSize)
+				return &buf
+			},
+		},
 	}
 }
 
@@ -80,6 +109,12 @@ func (h *StreamHandler) Stream(c *gin.Context) {
 	fileInfo, err := os.Stat(track.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if h.library != nil {
+				h.library.VerifyMissingFile(track.FilePath)
+			}
+			if h.servePlaceholder(c) {
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
 			return
 		}
@@ -87,15 +122,68 @@ func (h *StreamHandler) Stream(c *gin.Context) {
 		return
 	}
 
-	// Get quality parameter
+	segmented := track.StartOffset > 0 || track.EndOffset > 0
+
+	// ReplayGain (?gain=track or album) is applied while transcoding
+	gain, err := replayGain(track, c.Query("gain"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid gain mode"})
+		return
+	}
+
+	// A time offset (?t=seconds) seeks by time, for streams where byte
+	// ranges can't be mapped to a position
+	offset, err := parseStreamOffset(c.Query("t"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time offset"})
+		return
+	}
+
+	// Get quality parameter; without one, pick a quality from client hints
+	// and switch to a format listed in the Accept header if needed
 	quality := c.Query("quality")
 	if quality == "" {
 		quality = h.detectQuality(c)
+
+		c.Header("Vary", "Accept")
+		quality, err = negotiateStreamQuality(c.GetHeader("Accept"), streamOutputFormat(track.Format, quality, segmented || gain != 0), quality)
+		if err != nil {
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": "no acceptable audio format"})
+			return
+		}
+	}
+
+	// Tracks cut from a larger file by a cue sheet are always extracted with ffmpeg
+	if segmented {
+		segment := transcoder.Segment{
+			Start: time.Duration(track.StartOffset)*time.Millisecond + offset,
+			End:   time.Duration(track.EndOffset) * time.Millisecond,
+		}
+		if segment.End > 0 && segment.Start >= segment.End {
+			c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "offset beyond end of track"})
+			return
+		}
+		setStreamOffset(c, offset)
+		h.streamSegment(c, track.FilePath, track.Bitrate, quality, segment, gain)
+		return
 	}
 
 	// Handle transcoding if requested
 	if quality != "" && quality != "original" {
-		h.streamTranscoded(c, track.FilePath, track.Format, quality)
+		// A hash stored before the file last changed no longer describes it
+		contentHash := track.FileHash
+		if fileInfo.ModTime().After(track.UpdatedAt) {
+			contentHash = ""
+		}
+		h.streamTranscoded(c, track.FilePath, track.Bitrate, contentHash, quality, offset, gain)
+		return
+	}
+
+	// Seeking into the original by time, or changing its volume, means
+	// re-encoding it with ffmpeg
+	if offset > 0 || gain != 0 {
+		setStreamOffset(c, offset)
+		h.streamSegment(c, track.FilePath, track.Bitrate, quality, transcoder.Segment{Start: offset}, gain)
 		return
 	}
 
@@ -136,12 +224,15 @@ func (h *StreamHandler) streamOriginal(c *gin.Context, filePath, format string,
 	// Serve entire file
 	c.Header("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
 	c.Status(http.StatusOK)
-	io.Copy(c.Writer, file)
+	h.copyStream(c.Writer, file)
 }
 
-// streamTranscoded streams a transcoded version of the file
-func (h *StreamHandler) streamTranscoded(c *gin.Context, filePath, format, quality string) {
-	if h.transcoder == nil {
+// streamTranscoded streams a transcoded version of the file, starting offset
+// into the track and with its volume changed by gain dB. contentHash, when
+// known, lets the cached transcode of an identical file be used; bitrate is
+// the source's, to cap the transcode at.
+func (h *StreamHandler) streamTranscoded(c *gin.Context, filePath string, bitrate int, contentHash, quality string, offset time.Duration, gain float64) {
+	if !h.transcoder.IsAvailable() {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "transcoding not available"})
 		return
 	}
@@ -151,31 +242,182 @@ func (h *StreamHandler) streamTranscoded(c *gin.Context, filePath, format, quali
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quality"})
 		return
 	}
+	if !h.transcoder.SupportsEncoder(profile.Codec) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "encoder not available"})
+		return
+	}
+	profile = h.transcoder.EffectiveProfile(c.Request.Context(), filePath, bitrate, profile)
+	profile.Gain = gain
 
 	// Check if cached version exists
-	cachedPath := h.transcoder.GetCachedPath(filePath, profile)
+	cachedPath := h.transcoder.GetCachedPath(filePath, contentHash, profile)
 	if cachedPath != "" {
 		if fileInfo, err := os.Stat(cachedPath); err == nil {
-			h.streamOriginal(c, cachedPath, profile.Format, fileInfo)
-			return
+			switch {
+			case offset == 0:
+				h.streamOriginal(c, cachedPath, profile.Format, fileInfo)
+				return
+			case profile.Format == "ogg":
+				h.streamOggFrom(c, cachedPath, fileInfo, offset)
+				return
+			}
 		}
 	}
 
-	// Set headers for streaming transcoded content
-	c.Header("Content-Type", getMIMEType(profile.Format))
-	c.Header("Transfer-Encoding", "chunked")
-	c.Header("Cache-Control", "no-cache")
-	c.Status(http.StatusOK)
+	if offset > 0 {
+		setStreamOffset(c, offset)
+		h.streamSegment(c, filePath, bitrate, quality, transcoder.Segment{Start: offset}, gain)
+		return
+	}
 
-	// Stream transcoded content
+	// Stream transcoded content, which is cached as it's written. Requests
+	// arriving while the same transcode runs read it from the start as it
+	// grows. Delivery is paced by the client, so the server's write timeout
+	// is lifted for it, up to the transcode limit.
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
 	defer cancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		// Writers that can't have their deadline changed keep the timeout
+		http.NewResponseController(c.Writer).SetWriteDeadline(deadline)
+	}
 
-	err = h.transcoder.TranscodeToWriter(ctx, filePath, profile, c.Writer)
+	cachedPath, err = h.transcoder.StreamTranscode(ctx, filePath, contentHash, profile, c.Writer, func() {
+		c.Header("Content-Type", getMIMEType(profile.Format))
+		c.Header("Transfer-Encoding", "chunked")
+		c.Header("Cache-Control", "no-cache")
+		c.Status(http.StatusOK)
+	})
 	if err != nil {
 		// Can't send error response after streaming started
+		if !c.Writer.Written() && ctx.Err() == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "transcoding failed"})
+		}
+		return
+	}
+	if cachedPath != "" {
+		fileInfo, err := os.Stat(cachedPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "transcoding failed"})
+			return
+		}
+		h.streamOriginal(c, cachedPath, profile.Format, fileInfo)
+	}
+}
+
+// replayGain returns the volume change in dB a ?gain= mode asks for: the
+// track's or album's ReplayGain, with the track gain standing in for a
+// missing album gain. No mode, "off" and untagged tracks get 0.
+func replayGain(track *models.Track, mode string) (float64, error) {
+	var gain *float64
+	switch mode {
+	case "", "off":
+	case "track":
+		gain = track.TrackGain
+	case "album":
+		gain = track.AlbumGain
+		if gain == nil {
+			gain = track.TrackGain
+		}
+	default:
+		return 0, fmt.Errorf("unknown gain mode %q", mode)
+	}
+	if gain == nil {
+		return 0, nil
+	}
+	return *gain, nil
+}
+
+// streamOggFrom serves a cached Ogg transcode from the page holding offset,
+// preceded by the codec headers so it plays as a standalone stream. The
+// actual start, which may be slightly before offset, is reported in the
+// X-Stream-Offset header.
+func (h *StreamHandler) streamOggFrom(c *gin.Context, path string, fileInfo os.FileInfo, offset time.Duration) {
+	file, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
 		return
 	}
+	defer file.Close()
+
+	point, err := transcoder.SeekOgg(file, offset)
+	if err != nil {
+		if errors.Is(err, transcoder.ErrSeekBeyondEnd) {
+			c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "offset beyond end of track"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to seek stream"})
+		return
+	}
+	if _, err := file.Seek(point.Offset, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to seek stream"})
+		return
+	}
+
+	setStreamOffset(c, point.Start)
+	c.Header("Content-Type", getMIMEType("ogg"))
+	c.Header("Content-Length", strconv.FormatInt(int64(len(point.Header))+fileInfo.Size()-point.Offset, 10))
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	c.Writer.Write(point.Header)
+	h.copyStream(c.Writer, file)
+}
+
+// parseStreamOffset parses a time offset in seconds; empty means 0
+func parseStreamOffset(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0, fmt.Errorf("invalid offset %q", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// setStreamOffset reports where in the track the response starts
+func setStreamOffset(c *gin.Context, offset time.Duration) {
+	if offset > 0 {
+		c.Header("X-Stream-Offset", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64))
+	}
+}
+
+// streamSegment streams part of a file with its volume changed by gain dB.
+// Segments aren't cached and don't support range requests since their size
+// isn't known up front.
+func (h *StreamHandler) streamSegment(c *gin.Context, filePath string, bitrate int, quality string, segment transcoder.Segment, gain float64) {
+	if !h.transcoder.IsAvailable() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "transcoding not available"})
+		return
+	}
+
+	if quality == "" {
+		quality = "original"
+	}
+	profile, err := transcoder.GetProfile(quality)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quality"})
+		return
+	}
+	if profile.Name == transcoder.ProfileOriginal.Name {
+		profile = transcoder.ProfileLossless
+	}
+	if !h.transcoder.SupportsEncoder(profile.Codec) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "encoder not available"})
+		return
+	}
+	profile = h.transcoder.EffectiveProfile(c.Request.Context(), filePath, bitrate, profile)
+	profile.Gain = gain
+
+	c.Header("Content-Type", getMIMEType(profile.Format))
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
+	defer cancel()
+
+	// Can't send an error response after streaming started
+	h.transcoder.TranscodeSegmentToWriter(ctx, filePath, profile, segment, c.Writer)
 }
 
 // serveRange handles HTTP range requests for seeking
@@ -206,7 +448,21 @@ func (h *StreamHandler) serveRange(c *gin.Context, file *os.File, fileInfo os.Fi
 	c.Status(http.StatusPartialContent)
 
 	// Copy the requested range
-	io.CopyN(c.Writer, file, contentLength)
+	h.copyStream(c.Writer, io.LimitReader(file, contentLength))
+}
+
+// copyStream copies a file to the response through the configured buffer.
+// Without one it uses io.Copy, which lets the response use sendfile.
+func (h *StreamHandler) copyStream(w io.Writer, r io.Reader) (int64, error) {
+	if h.bufferSize <= 0 {
+		return io.Copy(w, r)
+	}
+
+	buf := h.buffers.Get().(*[]byte)
+	defer h.buffers.Put(buf)
+
+	// Hide ReaderFrom/WriterTo, which would bypass the buffer
+	return io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{r}, *buf)
 }
 
 // handleConditional handles If-Modified-Since and If-Range headers
@@ -258,6 +514,117 @@ func (h *StreamHandler) detectQuality(c *gin.Context) string {
 	return "original"
 }
 
+// errNotAcceptable is returned when none of the formats a client accepts can be produced
+var errNotAcceptable = errors.New("no acceptable audio format")
+
+// audioMIMEAliases maps nonstandard audio MIME types to the ones we serve
+var audioMIMEAliases = map[string]string{
+	"audio/mp3":       "audio/mpeg",
+	"audio/mpeg3":     "audio/mpeg",
+	"audio/x-mpeg":    "audio/mpeg",
+	"audio/x-flac":    "audio/flac",
+	"audio/vorbis":    "audio/ogg",
+	"application/ogg": "audio/ogg",
+	"audio/x-wav":     "audio/wav",
+	"audio/wave":      "audio/wav",
+	"audio/x-m4a":     "audio/mp4",
+	"audio/m4a":       "audio/mp4",
+}
+
+// streamOutputFormat returns the format a track is served in at a quality
+func streamOutputFormat(sourceFormat, quality string, segmented bool) string {
+	if quality == "" || quality == transcoder.ProfileOriginal.Name {
+		if segmented {
+			return transcoder.ProfileLossless.Format
+		}
+		return sourceFormat
+	}
+	if profile, err := transcoder.GetProfile(quality); err == nil {
+		return profile.Format
+	}
+	return sourceFormat
+}
+
+// negotiateStreamQuality checks that a quality's output format is listed in
+// an Accept header. If it isn't, the MP3 or OGG profile of the same bitrate
+// tier with the highest q-value is chosen instead. Headers that don't mention
+// any audio type leave the quality unchanged.
+func negotiateStreamQuality(accept, outputFormat, quality string) (string, error) {
+	accepted := parseAudioAccept(accept)
+	if accepted == nil || acceptQuality(accepted, getMIMEType(outputFormat)) > 0 {
+		return quality, nil
+	}
+
+	tier := strings.TrimSuffix(quality, "-ogg")
+	if tier == "" || tier == transcoder.ProfileOriginal.Name {
+		tier = transcoder.ProfileHigh.Name
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range []string{tier, tier + "-ogg"} {
+		profile, err := transcoder.GetProfile(name)
+		if err != nil {
+			continue
+		}
+		if q := acceptQuality(accepted, getMIMEType(profile.Format)); q > bestQ {
+			best, bestQ = profile.Name, q
+		}
+	}
+	if best == "" {
+		return "", errNotAcceptable
+	}
+	return best, nil
+}
+
+// parseAudioAccept returns the q-value of each media range in an Accept
+// header, or nil when no audio type is mentioned
+func parseAudioAccept(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	mentionsAudio := false
+
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		if alias, ok := audioMIMEAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		if strings.HasPrefix(mediaType, "audio/") {
+			mentionsAudio = true
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if prev, ok := accepted[mediaType]; !ok || q > prev {
+			accepted[mediaType] = q
+		}
+	}
+
+	if !mentionsAudio {
+		return nil
+	}
+	return accepted
+}
+
+// acceptQuality returns the q-value an Accept header gives a MIME type,
+// preferring exact matches over audio/* and */*
+func acceptQuality(accepted map[string]float64, mimeType string) float64 {
+	for _, key := range []string{mimeType, "audio/*", "*/*"} {
+		if q, ok := accepted[key]; ok {
+			return q
+		}
+	}
+	return 0
+}
+
 // parseRangeHeader parses the Range header and returns start and end positions
 func parseRangeHeader(rangeHeader string, fileSize int64) (int64, int64, error) {
 	// Format: "bytes=start-end" or "bytes=start-" or "bytes=-suffix"
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStreamAccept(t *testing.T) {
+	trans, _ := fakeEncoder(t)
+	env := newTestEnvWith(t, nil, trans)
+	env.seedLibrary()
+	path := filepath.Join(env.mediaRoot, "one.flac")
+	if err := os.WriteFile(path, []byte("fLaC original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env.exec(`UPDATE tracks SET file_path = '` + path + `', format = 'flac', lossless = 1 WHERE id = 't1'`)
+
+	tests := []struct {
+		name        string
+		accept      string
+		want        int
+		contentType string
+		body        string
+	}{
+		{"mp3 only", "audio/mpeg", http.StatusOK, "audio/mpeg", "encoded"},
+		{"mp3 alias", "audio/mp3", http.StatusOK, "audio/mpeg", "encoded"},
+		{"ogg preferred", "audio/mpeg;q=0.5, audio/ogg", http.StatusOK, "audio/ogg", "encoded"},
+		{"flac refused", "audio/flac;q=0, audio/*;q=0.8", http.StatusOK, "audio/mpeg", "encoded"},
+		{"flac accepted", "audio/flac, audio/mpeg", http.StatusOK, "audio/flac", "fLaC original"},
+		{"no audio types", "*/*", http.StatusOK, "audio/flac", "fLaC original"},
+		{"nothing playable", "audio/wav", http.StatusNotAcceptable, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/tracks/t1/stream", nil, "Accept", tt.accept)
+			expectStatus(t, rec, tt.want)
+			if vary := rec.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept") {
+				t.Errorf("Vary = %v, want Accept listed", vary)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := rec.Body.String(); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+
+	// An explicit quality isn't second-guessed by the Accept header
+	rec := env.do(http.MethodGet, "/api/v1/tracks/t1/stream?quality=original", nil, "Accept", "audio/mpeg")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "audio/flac" {
+		t.Errorf("with a quality, Content-Type = %q, want audio/flac", got)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// readSizes records the size of every read from a reader
+type readSizes struct {
+	r     io.Reader
+	sizes []int
+}
+
+func (rs *readSizes) Read(p []byte) (int, error) {
+	rs.sizes = append(rs.sizes, len(p))
+	return rs.r.Read(p)
+}
+
+func TestStreamCopyBuffer(t *testing.T) {
+	audio := bytes.Repeat([]byte("audio data "), 20000)
+
+	tests := []struct {
+		name       string
+		bufferSize int
+		limit      int64
+	}{
+		{"default", 0, 0},
+		{"small", 1024, 0},
+		{"large", 64 * 1024, 0},
+		{"range", 4096, 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStreamHandler(nil, nil, "", tt.bufferSize, "", nil)
+			want := audio
+			if tt.limit > 0 {
+				want = audio[:tt.limit]
+			}
+
+			// Copy twice so the second copy reuses a pooled buffer
+			for i := 0; i < 2; i++ {
+				source := &readSizes{r: bytes.NewReader(audio)}
+				var r io.Reader = source
+				if tt.limit > 0 {
+					r = io.LimitReader(source, tt.limit)
+				}
+				var out bytes.Buffer
+				n, err := h.copyStream(&out, r)
+				if err != nil || n != int64(len(want)) || !bytes.Equal(out.Bytes(), want) {
+					t.Fatalf("copied %d bytes, %v; want %d bytes intact", n, err, len(want))
+				}
+				if tt.bufferSize == 0 {
+					continue
+				}
+				for _, size := range source.sizes {
+					if size > tt.bufferSize {
+						t.Errorf("read %d bytes at once, want at most %d", size, tt.bufferSize)
+						break
+					}
+				}
+				if source.sizes[0] != tt.bufferSize {
+					t.Errorf("first read was %d bytes, want the %d byte buffer", source.sizes[0], tt.bufferSize)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultUserID identifies clients while authentication is disabled
+const defaultUserID = "default-user"
+
+// streamLimiter counts active streams per user and enforces a maximum.
+// A limit of 0 means unlimited.
+type streamLimiter struct {
+	mu           sync.Mutex
+	active       map[string]int
+	defaultLimit int
+	userLimits   map[string]int
+}
+
+// newStreamLimiter creates a limiter with a global default and per-user overrides
+func newStreamLimiter(defaultLimit int, userLimits map[string]int) *streamLimiter {
+	return &streamLimiter{
+		active:       make(map[string]int),
+		defaultLimit: defaultLimit,
+		userLimits:   userLimits,
+	}
+}
+
+// limitFor returns the stream limit that applies to a user
+func (l *streamLimiter) limitFor(userID string) int {
+	if limit, ok := l.userLimits[userID]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// acquire reserves a stream slot, reporting false if the user is at their limit
+func (l *streamLimiter) acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitFor(userID)
+	if limit > 0 && l.active[userID] >= limit {
+		return false
+	}
+	l.active[userID]++
+	return true
+}
+
+// release frees a slot reserved by acquire
+func (l *streamLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[userID] <= 1 {
+		delete(l.active, userID)
+		return
+	}
+	l.active[userID]--
+}
+
+// requestUserID identifies the client as the user its token was issued to.
+// Without authentication it's taken from the X-User-ID header or userId
+// query parameter, falling back to the default user.
+func requestUserID(c *gin.Context) string {
+	if id, ok := authenticatedUserID(c); ok {
+		return id
+	}
+	if id := c.GetHeader("X-User-ID"); id != "" {
+		return id
+	}
+	if id := c.Query("userId"); id != "" {
+		return id
+	}
+	return defaultUserID
+}
+
+// streamClient identifies whose streams a request counts against: the user
+// its token was issued to or, without authentication, the client's address.
+// Headers and query parameters naming a user are not trusted here, since any
+// client could pick a fresh one for every stream.
+func streamClient(c *gin.Context) string {
+	if id, ok := authenticatedUserID(c); ok {
+		return id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// limitStreams returns a middleware that holds a stream slot for the
+// duration of the request and rejects requests over the client's limit
+func limitStreams(limiter *streamLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := streamClient(c)
+		if !limiter.acquire(client) {
+			c.Header("Retry-After", "30")
+			Error(c, http.StatusTooManyRequests, "TOO_MANY_STREAMS", "concurrent stream limit reached")
+			c.Abort()
+			return
+		}
+		defer limiter.release(client)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitStreams(t *testing.T) {
+	const limit = 2
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	router := gin.New()
+	router.GET("/stream", limitStreams(newStreamLimiter(limit, nil)), func(c *gin.Context) {
+		select {
+		case started <- struct{}{}:
+		case <-finish:
+		}
+		<-finish
+		c.Status(http.StatusOK)
+	})
+
+	// open requests /stream from addr and returns once the stream is
+	// playing, holding its slot until finish is closed, or has been answered
+	var streams []chan struct{}
+	open := func(addr string, headers map[string]string) (rec *httptest.ResponseRecorder, playing bool) {
+		req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+		req.RemoteAddr = addr + ":40000"
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec = httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			router.ServeHTTP(rec, req)
+			close(done)
+		}()
+		select {
+		case <-started:
+			streams = append(streams, done)
+			return nil, true
+		case <-done:
+			return rec, false
+		}
+	}
+
+	for i := 0; i < limit; i++ {
+		if rec, playing := open("10.0.0.1", nil); !playing {
+			t.Fatalf("stream %d: status = %d, want it playing", i, rec.Code)
+		}
+	}
+
+	// Without authentication, naming a different user doesn't get around
+	// the limit of the client's address
+	for name, headers := range map[string]map[string]string{
+		"same client":     nil,
+		"claimed user ID": {"X-User-ID": "someone-else"},
+	} {
+		rec, playing := open("10.0.0.1", headers)
+		if playing {
+			t.Errorf("%s: stream over the limit is playing", name)
+			continue
+		}
+		if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "TOO_MANY_STREAMS" {
+			t.Errorf("%s: status = %d (%s), want %d TOO_MANY_STREAMS", name, rec.Code, rec.Body, http.StatusTooManyRequests)
+		}
+	}
+
+	// Another client has its own slots
+	if rec, playing := open("10.0.0.2", nil); !playing {
+		t.Errorf("other client: status = %d, want it playing", rec.Code)
+	}
+
+	// Finished streams free their slots
+	close(finish)
+	for _, done := range streams {
+		<-done
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("after streams finished: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
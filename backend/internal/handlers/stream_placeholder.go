@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/transcoder"
+)
+
+// Placeholders streamed in place of a track whose file is missing
+const (
+	PlaceholderSilence = "silence"
+	PlaceholderTone    = "tone"
+)
+
+// placeholderHeader flags a response as a stand-in for a missing file, so
+// clients can mark the track and move on
+const placeholderHeader = "X-Harmony-Placeholder"
+
+// Placeholder clip format: one second of 16-bit mono PCM
+const (
+	placeholderSampleRate = 22050
+	placeholderDuration   = time.Second
+	placeholderToneHz     = 440
+	placeholderToneLength = 300 * time.Millisecond
+)
+
+// placeholderWAV builds the clip for a placeholder mode, or nil when
+// placeholders are disabled
+func placeholderWAV(mode string) []byte {
+	if mode != PlaceholderSilence && mode != PlaceholderTone {
+		return nil
+	}
+
+	samples := make([]int16, int(placeholderDuration.Seconds()*placeholderSampleRate))
+	if mode == PlaceholderTone {
+		toneSamples := int(placeholderToneLength.Seconds() * placeholderSampleRate)
+		fade := placeholderSampleRate / 100 // 10ms ramps avoid clicks
+		for i := 0; i < toneSamples; i++ {
+			gain := 0.25
+			if i < fade {
+				gain *= float64(i) / float64(fade)
+			} else if i > toneSamples-fade {
+				gain *= float64(toneSamples-i) / float64(fade)
+			}
+			phase := 2 * math.Pi * placeholderToneHz * float64(i) / placeholderSampleRate
+			samples[i] = int16(gain * math.MaxInt16 * math.Sin(phase))
+		}
+	}
+
+	dataSize := uint32(len(samples) * 2)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	for _, field := range []interface{}{
+		uint32(16),                        // fmt chunk size
+		uint16(1),                         // PCM
+		uint16(1),                         // mono
+		uint32(placeholderSampleRate),     // sample rate
+		uint32(placeholderSampleRate * 2), // byte rate
+		uint16(2),                         // block align
+		uint16(16),                        // bits per sample
+	} {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// servePlaceholder streams the placeholder clip in place of a missing file,
+// encoded in the quality the request asks for like the track would have
+// been. The WAV clip is served when no quality is asked for, or when it
+// can't be transcoded, so playback still moves on. It reports false when
+// placeholders are disabled.
+func (h *StreamHandler) servePlaceholder(c *gin.Context) bool {
+	if h.placeholder == nil {
+		return false
+	}
+
+	quality := c.Query("quality")
+	if quality == "" {
+		quality = h.detectQuality(c)
+		c.Header("Vary", "Accept")
+		negotiated, err := negotiateStreamQuality(c.GetHeader("Accept"), streamOutputFormat("wav", quality, false), quality)
+		if err == nil {
+			quality = negotiated
+		}
+	}
+
+	clip, format := h.placeholder, "wav"
+	if quality != "" && quality != transcoder.ProfileOriginal.Name {
+		if encoded, profile, err := h.encodedPlaceholder(c.Request.Context(), quality); err == nil {
+			clip, format = encoded, profile.Format
+		} else {
+			slog.Warn("failed to encode placeholder clip", "quality", quality, "error", err)
+		}
+	}
+
+	c.Header(placeholderHeader, "missing-file")
+	c.Header("Content-Type", getMIMEType(format))
+	c.Header("Cache-Control", "no-store")
+	http.ServeContent(c.Writer, c.Request, "placeholder."+format, time.Time{}, bytes.NewReader(clip))
+	return true
+}
+
+// encodedPlaceholder returns the placeholder clip transcoded to a quality's
+// profile, encoding it the first time it's asked for
+func (h *StreamHandler) encodedPlaceholder(ctx context.Context, quality string) ([]byte, transcoder.Profile, error) {
+	profile, err := transcoder.GetProfile(quality)
+	if err != nil {
+		return nil, profile, err
+	}
+	if !h.transcoder.IsAvailable() || !h.transcoder.SupportsEncoder(profile.Codec) {
+		return nil, profile, errors.New("transcoding not available")
+	}
+
+	h.placeholderMu.Lock()
+	defer h.placeholderMu.Unlock()
+	if clip, ok := h.placeholderClips[profile.Name]; ok {
+		return clip, profile, nil
+	}
+
+	// ffmpeg reads the clip from a file like any other source
+	source, err := os.CreateTemp("", "harmony-placeholder-*.wav")
+	if err != nil {
+		return nil, profile, fmt.Errorf("creating placeholder source: %w", err)
+	}
+	defer os.Remove(source.Name())
+	_, err = source.Write(h.placeholder)
+	if closeErr := source.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, profile, fmt.Errorf("writing placeholder source: %w", err)
+	}
+
+	var encoded bytes.Buffer
+	if err := h.transcoder.TranscodeToWriter(ctx, source.Name(), profile, &encoded); err != nil {
+		return nil, profile, fmt.Errorf("transcoding placeholder: %w", err)
+	}
+	if h.placeholderClips == nil {
+		h.placeholderClips = make(map[string][]byte)
+	}
+	h.placeholderClips[profile.Name] = encoded.Bytes()
+	return encoded.Bytes(), profile, nil
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"harmony/internal/services"
+	"harmony/internal/transcoder"
+)
+
+// fakeEncoder creates a transcoder whose ffmpeg writes "encoded" as its
+// output and counts its runs in the returned file
+func fakeEncoder(t *testing.T) (*transcoder.Transcoder, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+if [ "$2" = "-encoders" ]; then
+	printf ' ------\n A..... libmp3lame MP3\n A..... libvorbis Vorbis\n'
+	exit 0
+fi
+echo run >> ` + runs + `
+printf encoded
+`
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: path, CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+	return trans, runs
+}
+
+func TestMissingFilePlaceholder(t *testing.T) {
+	trans, runs := fakeEncoder(t)
+	withPlaceholder := func(cfg *RouterConfig) {
+		cfg.MissingPlaceholder = PlaceholderTone
+	}
+	// newEnv builds a library whose first track's file is missing
+	newEnv := func(configure func(*RouterConfig), trans *transcoder.Transcoder) *testEnv {
+		env := newTestEnvWith(t, configure, trans)
+		env.seedLibrary()
+		env.exec(`UPDATE tracks SET file_path = '` + filepath.Join(env.mediaRoot, "gone.mp3") + `' WHERE id = 't1'`)
+		return env
+	}
+	disabled := newEnv(nil, trans)
+	enabled := newEnv(withPlaceholder, trans)
+	untranscoded := newEnv(withPlaceholder, nil)
+
+	tests := []struct {
+		name        string
+		env         *testEnv
+		query       string
+		headers     []string
+		want        int
+		contentType string
+		body        string
+	}{
+		{"disabled", disabled, "", nil, http.StatusNotFound, "", ""},
+		{"no quality", enabled, "", nil, http.StatusOK, "audio/wav", "RIFF"},
+		{"quality", enabled, "?quality=high", nil, http.StatusOK, "audio/mpeg", "encoded"},
+		{"cached quality", enabled, "?quality=high", nil, http.StatusOK, "audio/mpeg", "encoded"},
+		{"ogg quality", enabled, "?quality=high-ogg", nil, http.StatusOK, "audio/ogg", "encoded"},
+		{"accepted format", enabled, "", []string{"Accept", "audio/mpeg"}, http.StatusOK, "audio/mpeg", "encoded"},
+		{"no transcoding", untranscoded, "?quality=high", nil, http.StatusOK, "audio/wav", "RIFF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.env.do(http.MethodGet, "/api/v1/tracks/t1/stream"+tt.query, nil, tt.headers...)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get(placeholderHeader); got != "missing-file" {
+				t.Errorf("%s = %q, want missing-file", placeholderHeader, got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.HasPrefix(rec.Body.String(), tt.body) {
+				t.Errorf("body starts with %.8q, want %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+
+	// Each profile's clip is encoded once
+	data, _ := os.ReadFile(runs)
+	if n := strings.Count(string(data), "run\n"); n != 2 {
+		t.Errorf("ffmpeg ran %d times, want 2", n)
+	}
+}
+
+func TestStreamMissingFileVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		verify    bool
+		scheduled bool
+	}{
+		{"verification off", false, false},
+		{"verification on", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			env.lib.SetOptions(services.LibraryOptions{VerifyMissingFiles: tt.verify})
+			t.Cleanup(env.lib.Close)
+			env.seedLibrary()
+			kept := filepath.Join(env.mediaRoot, "kept.mp3")
+			if err := os.WriteFile(kept, []byte("audio"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			gone := filepath.Join(env.mediaRoot, "gone.mp3")
+			env.exec(
+				`UPDATE tracks SET file_path = '`+gone+`' WHERE id = 't1'`,
+				`UPDATE tracks SET file_path = '`+kept+`' WHERE id = 't2'`,
+			)
+
+			rec := env.do(http.MethodGet, "/api/v1/tracks/t1/stream", nil)
+			expectStatus(t, rec, http.StatusNotFound)
+
+			// The track stays until the scheduled check confirms the file is gone
+			var tracks int64
+			env.db.DB.Raw("SELECT COUNT(*) FROM tracks WHERE id = 't1'").Scan(&tracks)
+			if tracks != 1 {
+				t.Error("track removed as soon as its file was missed")
+			}
+			// A pending check refuses another one for the same file
+			env.lib.SetOptions(services.LibraryOptions{VerifyMissingFiles: true})
+			if scheduled := !env.lib.VerifyMissingFile(gone); scheduled != tt.scheduled {
+				t.Errorf("check scheduled by the stream = %v, want %v", scheduled, tt.scheduled)
+			}
+		})
+	}
+}
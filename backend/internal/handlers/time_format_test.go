@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+	stored := time.Date(2024, 5, 6, 7, 8, 9, 123_000_000, time.UTC)
+
+	tests := []struct {
+		format string
+		want   string
+		// precision is what survives a round trip through the API
+		precision time.Duration
+	}{
+		{"", "2024-05-06T07:08:09Z", time.Second},
+		{"rfc3339", "2024-05-06T07:08:09Z", time.Second},
+		{"rfc3339nano", "2024-05-06T07:08:09.123Z", time.Nanosecond},
+		{"RFC3339Nano", "2024-05-06T07:08:09.123Z", time.Nanosecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *RouterConfig) {
+				withAuth(cfg)
+				cfg.TimeFormat = tt.format
+			})
+			alice := env.register("alice")
+			env.seedLibrary()
+
+			rec := env.do(http.MethodPost, "/api/v1/playlists", map[string]string{"name": "Mix"}, bearer(alice)...)
+			expectStatus(t, rec, http.StatusCreated)
+			var playlist PlaylistResponse
+			decodeData(t, rec, &playlist)
+			const at = "'2024-05-06 07:08:09.123'"
+			env.exec(
+				`UPDATE playlists SET created_at = `+at+`, updated_at = `+at+` WHERE id = '`+playlist.ID+`'`,
+				`UPDATE tracks SET created_at = `+at+`, last_played_at = `+at+`, mod_time = `+at+` WHERE id = 't1'`,
+			)
+
+			rec = env.do(http.MethodGet, "/api/v1/playlists/"+playlist.ID, nil, bearer(alice)...)
+			expectStatus(t, rec, http.StatusOK)
+			decodeData(t, rec, &playlist)
+			rec = env.do(http.MethodGet, "/api/v1/tracks/t1", nil, bearer(alice)...)
+			expectStatus(t, rec, http.StatusOK)
+			var track TrackResponse
+			decodeData(t, rec, &track)
+
+			for name, got := range map[string]string{
+				"playlist createdAt":   playlist.CreatedAt,
+				"playlist updatedAt":   playlist.UpdatedAt,
+				"track addedAt":        track.AddedAt,
+				"track lastPlayed":     track.LastPlayed,
+				"track fileModifiedAt": track.FileModifiedAt,
+			} {
+				if got != tt.want {
+					t.Errorf("%s = %q, want %q", name, got, tt.want)
+					continue
+				}
+				parsed, err := time.Parse(time.RFC3339Nano, got)
+				if err != nil {
+					t.Errorf("%s: parsing %q: %v", name, got, err)
+					continue
+				}
+				if want := stored.Truncate(tt.precision); !parsed.Equal(want) {
+					t.Errorf("%s parses as %v, want %v", name, parsed, want)
+				}
+			}
+		})
+	}
+}
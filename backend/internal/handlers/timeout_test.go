@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	router := gin.New()
+	// slow stands in for a search waiting on a slow database
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+		}
+		Success(c, "late")
+	}
+	router.GET("/slow", requestTimeout(20*time.Millisecond), slow)
+	router.GET("/fast", requestTimeout(time.Second), func(c *gin.Context) { Success(c, "fast") })
+	router.GET("/stream", func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		c.String(http.StatusOK, "audio")
+	})
+	router.GET("/unlimited", requestTimeout(0), func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		Success(c, "done")
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/slow", http.StatusGatewayTimeout},
+		{"/fast", http.StatusOK},
+		{"/stream", http.StatusOK},
+		{"/unlimited", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			expectStatus(t, rec, tt.want)
+			if tt.want == http.StatusGatewayTimeout && time.Since(start) > 500*time.Millisecond {
+				t.Errorf("timed out after %v, want about 20ms", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestRouteGroupTimeouts(t *testing.T) {
+	env := newTestEnv(t, func(cfg *RouterConfig) {
+		cfg.SearchTimeout = time.Nanosecond
+		cfg.LibraryTimeout = time.Nanosecond
+	})
+	env.seedLibrary()
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"search", "/api/v1/search?q=one", http.StatusGatewayTimeout},
+		{"library", "/api/v1/library/stats", http.StatusGatewayTimeout},
+		// Streams aren't bounded; this one is refused for its path instead
+		{"stream", "/api/v1/tracks/t1/stream", http.StatusForbidden},
+		{"outside the groups", "/api/v1/tracks/t1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
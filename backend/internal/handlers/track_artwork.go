@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// Track handles GET /api/v1/tracks/:id/artwork
+// With track artwork enabled, a track whose file embeds its own artwork is
+// shown with it; other tracks fall back to their album's cover. Neither URL
+// is versioned, so responses are only cached briefly.
+func (h *ArtworkHandler) Track(c *gin.Context) {
+	size := artworkSizeParam(c)
+
+	track, err := h.trackRepo.FindByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	artworkPath := ""
+	if h.trackArtwork {
+		artworkPath, err = h.processor.TrackArtworkPath(track.FilePath, track.ID, size)
+		if err != nil {
+			slog.Debug("no track artwork", "trackID", track.ID, "error", err)
+		}
+	}
+	if artworkPath == "" && track.AlbumID != "" {
+		artworkPath = h.processor.GetArtworkPath(track.AlbumID, size)
+	}
+
+	if _, err := os.Stat(artworkPath); artworkPath == "" || err != nil {
+		servePlaceholderArtwork(c)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("Content-Type", "image/jpeg")
+	c.File(artworkPath)
+}
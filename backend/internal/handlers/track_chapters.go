@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// ChapterResponse is a chapter marker within a track. Times are in seconds,
+// matching the t parameter of the stream endpoint.
+type ChapterResponse struct {
+	Position  int     `json:"position"`
+	Title     string  `json:"title"`
+	Start     float64 `json:"start"`
+	End       float64 `json:"end,omitempty"`
+	StreamURL string  `json:"streamUrl"`
+}
+
+// Chapters handles GET /api/v1/tracks/:id/chapters
+// Each chapter links to a stream starting at its start time.
+func (h *TrackHandler) Chapters(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	track, err := h.repo.FindByID(ctx, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	chapters, err := h.repo.GetChapters(ctx, track.ID)
+	if err != nil {
+		InternalError(c, "failed to get chapters")
+		return
+	}
+
+	response := make([]ChapterResponse, len(chapters))
+	for i, chapter := range chapters {
+		start := float64(chapter.StartTime) / 1000
+		streamURL := h.baseURL + "/api/v1/tracks/" + track.ID + "/stream"
+		if chapter.StartTime > 0 {
+			streamURL += "?t=" + strconv.FormatFloat(start, 'f', -1, 64)
+		}
+
+		response[i] = ChapterResponse{
+			Position:  chapter.Position,
+			Title:     chapter.Title,
+			Start:     start,
+			End:       float64(chapter.EndTime) / 1000,
+			StreamURL: streamURL,
+		}
+	}
+
+	Success(c, response)
+}
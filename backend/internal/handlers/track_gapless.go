@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// GaplessResponse holds what a player needs to trim a track's encoder
+// padding. Delay and padding are in samples at SampleRate; both are 0 when
+// the file doesn't record them.
+type GaplessResponse struct {
+	TrackID        string `json:"trackId"`
+	EncoderDelay   int    `json:"encoderDelay"`
+	EncoderPadding int    `json:"encoderPadding"`
+	SampleRate     int    `json:"sampleRate,omitempty"`
+}
+
+// Gapless handles GET /api/v1/tracks/:id/gapless
+// Values come from the iTunSMPB tag or, for MP3s, the LAME header, as read
+// by the last scan.
+func (h *TrackHandler) Gapless(c *gin.Context) {
+	track, err := h.repo.FindByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	Success(c, GaplessResponse{
+		TrackID:        track.ID,
+		EncoderDelay:   track.EncoderDelay,
+		EncoderPadding: track.EncoderPad,
+		SampleRate:     track.SampleRate,
+	})
+}
@@ -2,48 +2,88 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"harmony/internal/database"
+	"harmony/internal/models"
+	"harmony/internal/scanner"
+	"harmony/internal/services"
+	"harmony/internal/transcoder"
 )
 
 // TrackHandler handles track-related endpoints
 type TrackHandler struct {
-	repo    *database.TrackRepository
-	baseURL string
+	repo       *database.TrackRepository
+	transcoder *transcoder.Transcoder
+	redis      *database.RedisClient
+	artwork    *scanner.ArtworkProcessor
+	audioInfo  *audioInfoCache
+	scrobbler  *services.Scrobbler
+	baseURL    string
+	// playWindow is how long after a recorded play another of the same
+	// track by the same user is ignored as a resubmission
+	playWindow time.Duration
+	timeFormat TimeFormat
+	sortLocale string
 }
 
 // NewTrackHandler creates a new TrackHandler
-func NewTrackHandler(repo *database.TrackRepository, baseURL string) *TrackHandler {
+func NewTrackHandler(
+	repo *database.TrackRepository,
+	trans *transcoder.Transcoder,
+	redis *database.RedisClient,
+	scrobbler *services.Scrobbler,
+	cacheDir string,
+	baseURL string,
+	playWindow time.Duration,
+	timeFormat TimeFormat,
+	sortLocale string,
+) *TrackHandler {
 	return &TrackHandler{
-		repo:    repo,
-		baseURL: baseURL,
+		repo:       repo,
+		transcoder: trans,
+		redis:      redis,
+		artwork:    scanner.NewArtworkProcessor(cacheDir),
+		audioInfo:  newAudioInfoCache(audioInfoCacheSize),
+		scrobbler:  scrobbler,
+		baseURL:    baseURL,
+		playWindow: playWindow,
+		timeFormat: timeFormat,
+		sortLocale: sortLocale,
 	}
 }
 
 // List handles GET /api/v1/tracks
 func (h *TrackHandler) List(c *gin.Context) {
 	pagination := ParsePagination(c)
+	fields, err := parseFields(c, TrackResponse{}.fields())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	expand, err := parseTrackExpand(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	filter, err := parseTrackFilter(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	opts := database.TrackListOptions{
-		Page:  pagination.Page,
-		Limit: pagination.Limit,
-		Filter: database.TrackFilter{
-			AlbumID:  c.Query("albumId"),
-			ArtistID: c.Query("artistId"),
-			Genre:    c.Query("genre"),
-			Query:    c.Query("q"),
-		},
-		SortBy: c.DefaultQuery("sortBy", "title"),
+		Page:   pagination.Page,
+		Limit:  pagination.Limit,
+		Filter: filter,
+		SortBy: c.Query("sortBy"),
 		Order:  c.DefaultQuery("order", "asc"),
-	}
-
-	// Parse year filter
-	if yearStr := c.Query("year"); yearStr != "" {
-		if year, err := parseInt(yearStr); err == nil {
-			opts.Filter.Year = year
-		}
+		Locale: requestSortLocale(c, h.sortLocale),
 	}
 
 	tracks, total, err := h.repo.List(c.Request.Context(), opts)
@@ -55,25 +95,133 @@ func (h *TrackHandler) List(c *gin.Context) {
 	// Build response with links
 	response := make([]TrackResponse, len(tracks))
 	for i, track := range tracks {
-		response[i] = TrackResponse{
-			ID:          track.ID,
-			Title:       track.Title,
-			Duration:    track.Duration,
-			TrackNumber: track.TrackNumber,
-			DiscNumber:  track.DiscNumber,
-			Format:      track.Format,
-			Bitrate:     track.Bitrate,
-			AlbumID:     track.AlbumID,
-			ArtistID:    track.ArtistID,
-			Genre:       track.Genre,
-			Year:        track.Year,
-			Links:       BuildTrackLinks(h.baseURL, track.ID, track.AlbumID),
+		response[i] = newTrackResponse(h.baseURL, h.timeFormat, track)
+		expand.apply(&response[i], track)
+	}
+
+	if fields != nil {
+		sparse := make([]fieldSet, len(response))
+		for i, track := range response {
+			sparse[i] = track.fields().only(fields)
 		}
+		SuccessWithPagination(c, sparse, NewPagination(pagination.Page, pagination.Limit, total))
+		return
 	}
 
 	SuccessWithPagination(c, response, NewPagination(pagination.Page, pagination.Limit, total))
 }
 
+// Shuffle handles GET /api/v1/tracks/shuffle
+func (h *TrackHandler) Shuffle(c *gin.Context) {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parseInt(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	// A seed makes the order reproducible; without one we pick and return it
+	seed := time.Now().UnixNano()
+	if seedStr := c.Query("seed"); seedStr != "" {
+		s, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			BadRequest(c, "invalid seed")
+			return
+		}
+		seed = s
+	}
+
+	filter, err := parseTrackFilter(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	tracks, err := h.repo.Shuffle(c.Request.Context(), filter, limit, seed)
+	if err != nil {
+		InternalError(c, "failed to shuffle tracks")
+		return
+	}
+
+	response := make([]TrackResponse, len(tracks))
+	for i, track := range tracks {
+		response[i] = newTrackResponse(h.baseURL, h.timeFormat, track)
+	}
+
+	Success(c, gin.H{
+		"seed":   seed,
+		"tracks": response,
+	})
+}
+
+// parseTrackFilter reads the common track filter query parameters. Unknown
+// formats and lossless values that aren't booleans are errors, since
+// ignoring them would list every track.
+func parseTrackFilter(c *gin.Context) (database.TrackFilter, error) {
+	filter := database.TrackFilter{
+		AlbumID:  c.Query("albumId"),
+		ArtistID: c.Query("artistId"),
+		Genre:    c.Query("genre"),
+		Query:    c.Query("q"),
+	}
+
+	// Parse year filter
+	if yearStr := c.Query("year"); yearStr != "" {
+		if year, err := parseInt(yearStr); err == nil {
+			filter.Year = year
+		}
+	}
+
+	// Parse minimum rating filter
+	if ratingStr := c.Query("minRating"); ratingStr != "" {
+		if rating, err := parseInt(ratingStr); err == nil {
+			filter.MinRating = rating
+		}
+	}
+
+	// Parse tempo range and key filters
+	if bpmStr := c.Query("minBpm"); bpmStr != "" {
+		if bpm, err := parseInt(bpmStr); err == nil {
+			filter.MinBPM = bpm
+		}
+	}
+	if bpmStr := c.Query("maxBpm"); bpmStr != "" {
+		if bpm, err := parseInt(bpmStr); err == nil {
+			filter.MaxBPM = bpm
+		}
+	}
+	if key := c.Query("key"); key != "" {
+		filter.Key = scanner.NormalizeKey(key)
+	}
+
+	// Parse technical attribute filters
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		if !scanner.SupportedFormats["."+format] {
+			return filter, fmt.Errorf("unknown format: %s", format)
+		}
+		filter.Format = format
+	}
+	if bitrateStr := c.Query("minBitrate"); bitrateStr != "" {
+		if bitrate, err := parseInt(bitrateStr); err == nil {
+			filter.MinBitrate = bitrate
+		}
+	}
+	if rateStr := c.Query("sampleRate"); rateStr != "" {
+		if rate, err := parseInt(rateStr); err == nil {
+			filter.SampleRate = rate
+		}
+	}
+	if losslessStr := c.Query("lossless"); losslessStr != "" {
+		lossless, err := strconv.ParseBool(losslessStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid lossless: %s", losslessStr)
+		}
+		filter.Lossless = &lossless
+	}
+
+	return filter, nil
+}
+
 // Get handles GET /api/v1/tracks/:id
 func (h *TrackHandler) Get(c *gin.Context) {
 	id := c.Param("id")
@@ -92,20 +240,7 @@ func (h *TrackHandler) Get(c *gin.Context) {
 		return
 	}
 
-	response := TrackResponse{
-		ID:          track.ID,
-		Title:       track.Title,
-		Duration:    track.Duration,
-		TrackNumber: track.TrackNumber,
-		DiscNumber:  track.DiscNumber,
-		Format:      track.Format,
-		Bitrate:     track.Bitrate,
-		AlbumID:     track.AlbumID,
-		ArtistID:    track.ArtistID,
-		Genre:       track.Genre,
-		Year:        track.Year,
-		Links:       BuildTrackLinks(h.baseURL, track.ID, track.AlbumID),
-	}
+	response := newTrackResponse(h.baseURL, h.timeFormat, *track)
 
 	// Include album info if preloaded
 	if track.Album != nil {
@@ -125,3 +260,203 @@ func (h *TrackHandler) Get(c *gin.Context) {
 
 	Success(c, response)
 }
+
+// SetRatingRequest represents a track rating update
+type SetRatingRequest struct {
+	Rating *int `json:"rating" binding:"required,min=0,max=5"`
+}
+
+// TrackAnalysis is the detected tempo and key of a track
+type TrackAnalysis struct {
+	TrackID  string `json:"trackId"`
+	Analyzed bool   `json:"analyzed"`
+	BPM      int    `json:"bpm,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Camelot  string `json:"camelot,omitempty"`
+}
+
+// Analysis handles GET /api/v1/tracks/:id/analysis
+func (h *TrackHandler) Analysis(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "track ID required")
+		return
+	}
+
+	track, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	Success(c, TrackAnalysis{
+		TrackID:  track.ID,
+		Analyzed: track.BPM > 0 || track.MusicalKey != "",
+		BPM:      track.BPM,
+		Key:      track.MusicalKey,
+		Camelot:  scanner.CamelotKey(track.MusicalKey),
+	})
+}
+
+// SetRating handles PUT /api/v1/tracks/:id/rating
+func (h *TrackHandler) SetRating(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "track ID required")
+		return
+	}
+
+	var req SetRatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "rating must be between 0 and 5")
+		return
+	}
+
+	if err := h.repo.SetRating(c.Request.Context(), id, *req.Rating); err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to set rating")
+		return
+	}
+
+	track, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	Success(c, newTrackResponse(h.baseURL, h.timeFormat, *track))
+}
+
+// RecordPlayRequest reports how a track was played. Either flag completion
+// explicitly or send the seconds listened and let the server decide.
+type RecordPlayRequest struct {
+	Completed     *bool `json:"completed"`
+	PlayedSeconds *int  `json:"playedSeconds" binding:"omitempty,min=0"`
+}
+
+// A play counts as complete once half the track, or four minutes, was heard
+const completedPlaySeconds = 240
+
+// playCompleted reports whether playing a track for played seconds counts as
+// a complete play
+func playCompleted(track *models.Track, played int) bool {
+	return played >= completedPlaySeconds || (track.Duration > 0 && played*2 >= track.Duration)
+}
+
+// RecordPlay handles POST /api/v1/tracks/:id/play
+// A repeat of the user's last play of the track within the play window,
+// such as a client retry, is answered like any other but isn't counted.
+func (h *TrackHandler) RecordPlay(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		BadRequest(c, "track ID required")
+		return
+	}
+
+	var req RecordPlayRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "invalid request body")
+			return
+		}
+	}
+
+	track, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	completed := true
+	switch {
+	case req.Completed != nil:
+		completed = *req.Completed
+	case req.PlayedSeconds != nil:
+		completed = playCompleted(track, *req.PlayedSeconds)
+	}
+
+	err = h.repo.RecordPlay(c.Request.Context(), id, requestUserID(c), completed, time.Now(), h.playWindow)
+	if err != nil && !errors.Is(err, database.ErrDuplicatePlay) {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to record play")
+		return
+	}
+
+	track, err = h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	Success(c, newTrackResponse(h.baseURL, h.timeFormat, *track))
+}
+
+// NowPlayingResponse carries what a client needs for lock-screen and media
+// session display in a single request
+type NowPlayingResponse struct {
+	Track      TrackResponse `json:"track"`
+	Title      string        `json:"title"`
+	Artist     string        `json:"artist,omitempty"`
+	Album      string        `json:"album,omitempty"`
+	Artwork    string        `json:"artwork,omitempty"`
+	ArtworkURL string        `json:"artworkUrl,omitempty"`
+}
+
+// NowPlaying handles GET /api/v1/tracks/:id/now-playing
+// The album thumbnail is inlined as a data URI; ?artwork=small inlines the
+// larger size and ?artwork=none leaves it out.
+func (h *TrackHandler) NowPlaying(c *gin.Context) {
+	size := c.DefaultQuery("artwork", scanner.ArtworkSizeThumbnail.Name)
+	switch size {
+	case scanner.ArtworkSizeThumbnail.Name, scanner.ArtworkSizeSmall.Name, "none":
+	default:
+		BadRequest(c, "artwork must be thumbnail, small or none")
+		return
+	}
+
+	track, err := h.repo.FindByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	response := NowPlayingResponse{
+		Track: newTrackResponse(h.baseURL, h.timeFormat, *track),
+		Title: track.Title,
+	}
+	if track.Artist != nil {
+		response.Artist = track.Artist.Name
+	}
+	if track.Album != nil {
+		response.Album = track.Album.Title
+	}
+
+	if track.AlbumID != "" && h.artwork.ArtworkExists(track.AlbumID) {
+		response.ArtworkURL = h.baseURL + "/api/v1/artwork/album/" + track.AlbumID
+		if size != "none" {
+			if uri, err := h.artwork.ArtworkDataURI(track.AlbumID, size); err == nil {
+				response.Artwork = uri
+			}
+		}
+	}
+
+	Success(c, response)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestTrackFilterParams(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	env.exec(
+		`UPDATE tracks SET format = 'flac' WHERE id = 't1'`,
+		`UPDATE tracks SET format = 'm4a', lossless = true WHERE id = 't2'`,
+	)
+
+	tests := []struct {
+		name  string
+		path  string
+		want  int
+		count int
+	}{
+		{"lossless", "/api/v1/tracks?lossless=true", http.StatusOK, 2},
+		{"lossy", "/api/v1/tracks?lossless=false", http.StatusOK, 2},
+		{"format", "/api/v1/tracks?format=M4A", http.StatusOK, 1},
+		{"unknown format", "/api/v1/tracks?format=xyz", http.StatusBadRequest, 0},
+		{"invalid lossless", "/api/v1/tracks?lossless=maybe", http.StatusBadRequest, 0},
+		{"shuffle unknown format", "/api/v1/tracks/shuffle?format=xyz", http.StatusBadRequest, 0},
+		{"artist unknown format", "/api/v1/artists/ar1/tracks?format=xyz", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, tt.path, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+			var tracks []TrackResponse
+			decodeData(t, rec, &tracks)
+			if len(tracks) != tt.count {
+				t.Errorf("%d tracks, want %d", len(tracks), tt.count)
+			}
+		})
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	for i := 5; i <= 40; i++ {
+		env.exec(fmt.Sprintf(`INSERT INTO tracks (id, title, duration, file_path, file_size, format, artist_id, genre, created_at, updated_at)
+			VALUES ('t%d', 'Track %d', 100, '/a/%d.mp3', 100, 'mp3', 'ar1', 'Ambient', datetime('now'), datetime('now'))`, i, i, i))
+	}
+
+	// shuffle returns the IDs a request lists, in order, and the seed used
+	shuffle := func(query string) ([]string, int64) {
+		t.Helper()
+		rec := env.do(http.MethodGet, "/api/v1/tracks/shuffle?"+query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var result struct {
+			Seed   int64           `json:"seed"`
+			Tracks []TrackResponse `json:"tracks"`
+		}
+		decodeData(t, rec, &result)
+		ids := make([]string, len(result.Tracks))
+		for i, track := range result.Tracks {
+			ids[i] = track.ID
+		}
+		return ids, result.Seed
+	}
+
+	first, seed := shuffle("seed=42&limit=10")
+	if seed != 42 || len(first) != 10 {
+		t.Fatalf("shuffle gave %d tracks with seed %d, want 10 with seed 42", len(first), seed)
+	}
+	again, _ := shuffle("seed=42&limit=10")
+	if !slices.Equal(first, again) {
+		t.Errorf("same seed gave %v, then %v", first, again)
+	}
+	other, _ := shuffle("seed=43&limit=10")
+	if slices.Equal(first, other) {
+		t.Errorf("seeds 42 and 43 both gave %v", first)
+	}
+
+	// Without a seed one is picked and returned, so the order can be replayed
+	picked, seed := shuffle("limit=10")
+	replayed, _ := shuffle(fmt.Sprintf("seed=%d&limit=10", seed))
+	if !slices.Equal(picked, replayed) {
+		t.Errorf("replaying seed %d gave %v, want %v", seed, replayed, picked)
+	}
+
+	// Filters narrow the set shuffled
+	rock, _ := shuffle("seed=42&genre=Rock")
+	slices.Sort(rock)
+	if !slices.Equal(rock, []string{"t1", "t3"}) {
+		t.Errorf("rock shuffle = %v, want t1 and t3", rock)
+	}
+
+	rec := env.do(http.MethodGet, "/api/v1/tracks/shuffle?seed=abc", nil)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestTrackDates(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+	// Tracks were added in order but their files were last changed in the
+	// opposite order; t4's file date isn't known
+	env.exec(
+		`UPDATE tracks SET created_at = '2024-01-01 10:00:00', mod_time = '2020-04-01 08:00:00' WHERE id = 't1'`,
+		`UPDATE tracks SET created_at = '2024-01-02 10:00:00', mod_time = '2020-03-01 08:00:00' WHERE id = 't2'`,
+		`UPDATE tracks SET created_at = '2024-01-03 10:00:00', mod_time = '2020-02-01 08:00:00' WHERE id = 't3'`,
+		`UPDATE tracks SET created_at = '2024-01-04 10:00:00', mod_time = NULL WHERE id = 't4'`,
+	)
+	added := map[string]string{
+		"t1": "2024-01-01T10:00:00Z", "t2": "2024-01-02T10:00:00Z",
+		"t3": "2024-01-03T10:00:00Z", "t4": "2024-01-04T10:00:00Z",
+	}
+	modified := map[string]string{
+		"t1": "2020-04-01T08:00:00Z", "t2": "2020-03-01T08:00:00Z", "t3": "2020-02-01T08:00:00Z",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		ids   []string
+	}{
+		{"added first", "?sortBy=addedAt", []string{"t1", "t2", "t3", "t4"}},
+		{"added last", "?sortBy=addedAt&order=desc", []string{"t4", "t3", "t2", "t1"}},
+		{"file changed last", "?sortBy=fileModifiedAt&order=desc", []string{"t1", "t2", "t3", "t4"}},
+		{"file changed first", "?sortBy=fileModifiedAt", []string{"t4", "t3", "t2", "t1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/tracks"+tt.query, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var tracks []TrackResponse
+			decodeData(t, rec, &tracks)
+			var ids []string
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+				if track.AddedAt != added[track.ID] || track.FileModifiedAt != modified[track.ID] {
+					t.Errorf("%s added %q, file modified %q; want %q and %q",
+						track.ID, track.AddedAt, track.FileModifiedAt, added[track.ID], modified[track.ID])
+				}
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("tracks = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestTrackRating(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.seedLibrary()
+
+	rate := func(id string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		return env.do(http.MethodPut, "/api/v1/tracks/"+id+"/rating", body)
+	}
+	// rated returns the IDs of the tracks listed at path
+	rated := func(path string) []string {
+		t.Helper()
+		rec := env.do(http.MethodGet, path, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var tracks []TrackResponse
+		decodeData(t, rec, &tracks)
+		ids := make([]string, len(tracks))
+		for i, track := range tracks {
+			ids[i] = track.ID
+		}
+		return ids
+	}
+
+	for id, rating := range map[string]int{"t1": 4, "t2": 2, "t3": 5} {
+		rec := rate(id, map[string]int{"rating": rating})
+		expectStatus(t, rec, http.StatusOK)
+		var track TrackResponse
+		decodeData(t, rec, &track)
+		if track.Rating != rating {
+			t.Errorf("%s rated %d, want %d", id, track.Rating, rating)
+		}
+	}
+	if got, want := rated("/api/v1/tracks?minRating=4&sortBy=rating&order=desc"), []string{"t3", "t1"}; !slices.Equal(got, want) {
+		t.Errorf("rated 4 and up = %v, want %v", got, want)
+	}
+
+	// Rating 0 clears a rating
+	expectStatus(t, rate("t1", map[string]int{"rating": 0}), http.StatusOK)
+	if got, want := rated("/api/v1/tracks?minRating=1&sortBy=rating"), []string{"t2", "t3"}; !slices.Equal(got, want) {
+		t.Errorf("rated tracks after clearing t1 = %v, want %v", got, want)
+	}
+
+	invalid := []struct {
+		name string
+		body any
+	}{
+		{"above five", map[string]int{"rating": 6}},
+		{"negative", map[string]int{"rating": -1}},
+		{"missing", map[string]int{}},
+		{"not a number", map[string]string{"rating": "five"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, rate("t2", tt.body), http.StatusBadRequest)
+		})
+	}
+	if got, want := rated("/api/v1/tracks?minRating=2&sortBy=rating"), []string{"t2", "t3"}; !slices.Equal(got, want) {
+		t.Errorf("rated tracks after invalid ratings = %v, want %v", got, want)
+	}
+
+	expectStatus(t, rate("missing", map[string]int{"rating": 3}), http.StatusNotFound)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/scanner"
+)
+
+// LyricLineResponse is one line of synced lyrics; Time is in seconds,
+// matching the t parameter of the stream endpoint
+type LyricLineResponse struct {
+	Time float64 `json:"time"`
+	Text string  `json:"text"`
+}
+
+// LyricsResponse holds a track's lyrics. Lines is only present for synced
+// (LRC) lyrics.
+type LyricsResponse struct {
+	TrackID string              `json:"trackId"`
+	Synced  bool                `json:"synced"`
+	Text    string              `json:"text"`
+	Lines   []LyricLineResponse `json:"lines,omitempty"`
+}
+
+// Lyrics handles GET /api/v1/tracks/:id/lyrics
+// Lyrics come from a sidecar .lrc file or the file's tags, as read by the
+// last scan.
+func (h *TrackHandler) Lyrics(c *gin.Context) {
+	track, err := h.repo.FindByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+
+	if strings.TrimSpace(track.Lyrics) == "" {
+		NotFound(c, "lyrics")
+		return
+	}
+
+	lines, text := scanner.ParseLRC(track.Lyrics)
+	response := LyricsResponse{
+		TrackID: track.ID,
+		Synced:  len(lines) > 0,
+		Text:    text,
+	}
+	for _, line := range lines {
+		response.Lines = append(response.Lines, LyricLineResponse{
+			Time: line.Time.Seconds(),
+			Text: line.Text,
+		})
+	}
+
+	Success(c, response)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+)
+
+// ScrobbleRequest reports a track starting, with nowPlaying, or a play
+// ending. playedSeconds may be left out of the latter when the start was
+// reported, and the time since then is used.
+type ScrobbleRequest struct {
+	NowPlaying    bool `json:"nowPlaying"`
+	PlayedSeconds *int `json:"playedSeconds" binding:"omitempty,min=0"`
+}
+
+// ScrobbleResponse reports what became of a scrobble. Completed is whether
+// the play passed the scrobbling threshold, LastFM whether it was sent to
+// Last.fm and Duplicate whether it was ignored as a repeat of one just
+// recorded.
+type ScrobbleResponse struct {
+	TrackID    string `json:"trackId"`
+	NowPlaying bool   `json:"nowPlaying"`
+	Completed  bool   `json:"completed"`
+	LastFM     bool   `json:"lastfm"`
+	Duplicate  bool   `json:"duplicate,omitempty"`
+	PlayCount  int    `json:"playCount"`
+}
+
+// Scrobble handles POST /api/v1/tracks/:id/scrobble
+// With nowPlaying the track is marked as started and sent to Last.fm as now
+// playing. Otherwise the play is recorded like POST /play, and scrobbled to
+// Last.fm once half the track, or four minutes, was played. Repeats within
+// the play window are ignored, neither counted nor scrobbled. Last.fm is only
+// contacted for users who linked an account; failures there are logged
+// rather than failing the request.
+func (h *TrackHandler) Scrobble(c *gin.Context) {
+	var req ScrobbleRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "invalid request body")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	track, err := h.repo.FindByID(ctx, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to get track")
+		return
+	}
+	userID := requestUserID(c)
+
+	if req.NowPlaying {
+		sent, err := h.scrobbler.NowPlaying(ctx, userID, track)
+		if err != nil {
+			slog.Warn("updating last.fm now playing failed", "track", track.ID, "user", userID, "error", err)
+		}
+		Success(c, ScrobbleResponse{
+			TrackID:    track.ID,
+			NowPlaying: true,
+			LastFM:     sent,
+			PlayCount:  track.PlayCount,
+		})
+		return
+	}
+
+	var played int
+	if req.PlayedSeconds != nil {
+		played = *req.PlayedSeconds
+	} else if elapsed, ok := h.scrobbler.PlayedSeconds(userID, track.ID); ok {
+		played = elapsed
+	} else {
+		BadRequest(c, "playedSeconds required unless the track was reported as now playing")
+		return
+	}
+
+	now := time.Now()
+	completed := playCompleted(track, played)
+	err = h.repo.RecordPlay(ctx, track.ID, userID, completed, now, h.playWindow)
+	if errors.Is(err, database.ErrDuplicatePlay) {
+		h.scrobbler.Finish(userID, track.ID)
+		Success(c, ScrobbleResponse{
+			TrackID:   track.ID,
+			Duplicate: true,
+			PlayCount: track.PlayCount,
+		})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			NotFound(c, "track")
+			return
+		}
+		InternalError(c, "failed to record play")
+		return
+	}
+	h.scrobbler.Finish(userID, track.ID)
+
+	response := ScrobbleResponse{
+		TrackID:   track.ID,
+		Completed: completed,
+		PlayCount: track.PlayCount,
+	}
+	if completed {
+		response.PlayCount++
+		startedAt := now.Add(-time.Duration(played) * time.Second)
+		response.LastFM, err = h.scrobbler.Scrobble(ctx, userID, track, startedAt)
+		if err != nil {
+			slog.Warn("scrobbling to last.fm failed", "track", track.ID, "user", userID, "error", err)
+		}
+	}
+
+	Success(c, response)
+}
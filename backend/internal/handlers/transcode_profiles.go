@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+	"harmony/internal/transcoder"
+)
+
+// TranscodeProfileRequest defines a custom transcode profile
+type TranscodeProfileRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Codec   string `json:"codec" binding:"required"`
+	Format  string `json:"format"`
+	Bitrate int    `json:"bitrate"`
+}
+
+// TranscodeProfileResponse describes a transcode profile usable as a stream quality
+type TranscodeProfileResponse struct {
+	Name    string `json:"name"`
+	Codec   string `json:"codec,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Bitrate int    `json:"bitrate,omitempty"`
+	BuiltIn bool   `json:"builtIn"`
+}
+
+func newTranscodeProfileResponse(p transcoder.Profile, builtIn bool) TranscodeProfileResponse {
+	return TranscodeProfileResponse{
+		Name:    p.Name,
+		Codec:   p.Codec,
+		Format:  p.Format,
+		Bitrate: p.Bitrate,
+		BuiltIn: builtIn,
+	}
+}
+
+// ListTranscodeProfiles handles GET /api/v1/admin/transcode/profiles
+// Built-in profiles come first, followed by custom ones.
+func (h *AdminHandler) ListTranscodeProfiles(c *gin.Context) {
+	response := []TranscodeProfileResponse{}
+	for _, p := range transcoder.GetAllProfiles() {
+		response = append(response, newTranscodeProfileResponse(p, true))
+	}
+	for _, p := range transcoder.CustomProfiles() {
+		response = append(response, newTranscodeProfileResponse(p, false))
+	}
+
+	Success(c, response)
+}
+
+// SaveTranscodeProfile handles POST /api/v1/admin/transcode/profiles
+// Creates a custom profile, or replaces the custom profile of the same name.
+// The profile can then be requested by name with ?quality= on stream URLs.
+func (h *AdminHandler) SaveTranscodeProfile(c *gin.Context) {
+	var req TranscodeProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "name and codec are required")
+		return
+	}
+
+	profile, err := transcoder.NewCustomProfile(req.Name, req.Codec, req.Format, req.Bitrate)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	h.profilesMu.Lock()
+	defer h.profilesMu.Unlock()
+
+	ctx := c.Request.Context()
+	stored, err := h.settings.GetTranscodeProfiles(ctx)
+	if err != nil {
+		InternalError(c, "failed to load transcode profiles")
+		return
+	}
+
+	entry := models.TranscodeProfile{
+		Name:    profile.Name,
+		Codec:   profile.Codec,
+		Format:  profile.Format,
+		Bitrate: profile.Bitrate,
+	}
+	replaced := false
+	for i := range stored {
+		if stored[i].Name == entry.Name {
+			stored[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		if len(stored) >= transcoder.MaxCustomProfiles {
+			BadRequest(c, "too many custom transcode profiles")
+			return
+		}
+		stored = append(stored, entry)
+	}
+
+	if err := h.settings.SetTranscodeProfiles(ctx, stored); err != nil {
+		InternalError(c, "failed to save transcode profiles")
+		return
+	}
+	transcoder.SetCustomProfiles(customTranscodeProfiles(stored))
+
+	response := newTranscodeProfileResponse(profile, false)
+	if replaced {
+		Success(c, response)
+		return
+	}
+	Created(c, response)
+}
+
+// restoreTranscodeProfiles registers the custom transcode profiles kept in
+// settings. Stored profiles that no longer validate are skipped.
+func restoreTranscodeProfiles(ctx context.Context, settings *database.SettingsRepository) {
+	stored, err := settings.GetTranscodeProfiles(ctx)
+	if err != nil {
+		slog.Warn("failed to load transcode profiles", "error", err)
+		return
+	}
+	transcoder.SetCustomProfiles(customTranscodeProfiles(stored))
+}
+
+// customTranscodeProfiles converts stored profiles, dropping invalid ones
+func customTranscodeProfiles(stored []models.TranscodeProfile) []transcoder.Profile {
+	list := make([]transcoder.Profile, 0, len(stored))
+	for _, p := range stored {
+		profile, err := transcoder.NewCustomProfile(p.Name, p.Codec, p.Format, p.Bitrate)
+		if err != nil {
+			slog.Warn("skipping invalid transcode profile", "name", p.Name, "error", err)
+			continue
+		}
+		list = append(list, profile)
+	}
+	return list
+}
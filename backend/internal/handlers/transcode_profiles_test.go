@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"harmony/internal/database"
+	"harmony/internal/transcoder"
+)
+
+func TestTranscodeProfiles(t *testing.T) {
+	t.Cleanup(func() { transcoder.SetCustomProfiles(nil) })
+
+	// The fake ffmpeg logs its arguments and writes "encoded" to its output
+	dir := t.TempDir()
+	argsLog := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+[ "$2" = "-encoders" ] && exit 0
+echo "$*" >> ` + argsLog + `
+for out; do :; done
+if [ "$out" = "-" ] || [ "$out" = "pipe:1" ]; then printf encoded; else printf encoded > "$out"; fi
+`
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: ffmpeg, CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+
+	env := newTestEnvWith(t, withAdminToken, trans)
+	env.seedLibrary()
+	song := filepath.Join(env.mediaRoot, "song.mp3")
+	if err := os.WriteFile(song, []byte("original audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env.exec(`UPDATE tracks SET file_path = '` + song + `' WHERE id = 't1'`)
+	auth := bearer(testAdminToken)
+
+	// Steps run in order; later ones see the profiles earlier ones saved
+	tests := []struct {
+		name    string
+		profile map[string]any
+		want    int
+	}{
+		{"new profile", map[string]any{"name": "aac-256", "codec": "aac", "bitrate": 256}, http.StatusCreated},
+		{"replaced profile", map[string]any{"name": "AAC-256", "codec": "aac", "bitrate": 192}, http.StatusOK},
+		{"built-in name", map[string]any{"name": "high", "codec": "aac", "bitrate": 128}, http.StatusBadRequest},
+		{"unknown codec", map[string]any{"name": "wav", "codec": "pcm_s16le"}, http.StatusBadRequest},
+		{"bitrate out of range", map[string]any{"name": "opus", "codec": "libopus", "bitrate": 1000}, http.StatusBadRequest},
+		{"wrong format", map[string]any{"name": "mp3", "codec": "libmp3lame", "format": "ogg", "bitrate": 192}, http.StatusBadRequest},
+		{"no codec", map[string]any{"name": "empty"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodPost, "/api/v1/admin/transcode/profiles", tt.profile, auth...)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+
+	rec := env.do(http.MethodGet, "/api/v1/admin/transcode/profiles", nil, auth...)
+	expectStatus(t, rec, http.StatusOK)
+	var profiles []TranscodeProfileResponse
+	decodeData(t, rec, &profiles)
+	var custom []TranscodeProfileResponse
+	for _, profile := range profiles {
+		if !profile.BuiltIn {
+			custom = append(custom, profile)
+		}
+	}
+	want := TranscodeProfileResponse{Name: "aac-256", Codec: "aac", Format: "adts", Bitrate: 192}
+	if len(custom) != 1 || custom[0] != want {
+		t.Errorf("custom profiles = %+v, want only %+v", custom, want)
+	}
+
+	// The profile streams by name
+	rec = env.do(http.MethodGet, "/api/v1/tracks/t1/stream?quality=aac-256", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/aac" || rec.Body.String() != "encoded" {
+		t.Errorf("stream = %q as %s, want the encoded audio as audio/aac", rec.Body.String(), ct)
+	}
+	args, _ := os.ReadFile(argsLog)
+	if !strings.Contains(string(args), "-acodec aac -b:a 192k") || !strings.Contains(string(args), "-f adts") {
+		t.Errorf("ffmpeg ran with %q, want the custom profile", args)
+	}
+
+	// Saved profiles come back after a restart
+	transcoder.SetCustomProfiles(nil)
+	restoreTranscodeProfiles(context.Background(), database.NewSettingsRepository(env.db.DB))
+	if restored := transcoder.CustomProfiles(); len(restored) != 1 || restored[0].Bitrate != 192 {
+		t.Errorf("restored profiles = %+v, want aac-256 at 192 kbps", restored)
+	}
+}
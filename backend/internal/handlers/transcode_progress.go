@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranscodeProgressResponse describes a running transcode
+type TranscodeProgressResponse struct {
+	ID        uint64  `json:"id"`
+	Input     string  `json:"input"`
+	Profile   string  `json:"profile"`
+	StartedAt string  `json:"startedAt"`
+	Duration  float64 `json:"duration,omitempty"` // seconds; omitted until known
+	OutTime   float64 `json:"outTime"`            // seconds transcoded so far
+	TotalSize int64   `json:"totalSize"`
+	Percent   float64 `json:"percent"`
+}
+
+// ActiveTranscodes handles GET /api/v1/admin/transcode/active
+// Lists running transcodes with their progress as reported by ffmpeg, so
+// clients can show progress for long transcodes. Percent stays 0 until
+// ffmpeg has reported the length of the input.
+func (h *AdminHandler) ActiveTranscodes(c *gin.Context) {
+	response := []TranscodeProgressResponse{}
+	for _, progress := range h.transcoder.ActiveTranscodes() {
+		response = append(response, TranscodeProgressResponse{
+			ID:        progress.ID,
+			Input:     progress.Input,
+			Profile:   progress.Profile,
+			StartedAt: h.timeFormat.Format(progress.StartedAt),
+			Duration:  progress.Duration.Seconds(),
+			OutTime:   progress.OutTime.Seconds(),
+			TotalSize: progress.TotalSize,
+			Percent:   math.Round(progress.Percent*10) / 10,
+		})
+	}
+
+	Success(c, response)
+}
+
+// FFmpegStatusResponse describes the ffmpeg used for transcoding
+type FFmpegStatusResponse struct {
+	Available bool     `json:"available"`
+	Path      string   `json:"path,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Encoders  []string `json:"encoders"`
+}
+
+// RecheckTranscoder handles GET /api/v1/admin/transcode/recheck
+// Looks for ffmpeg again and re-lists its encoders, enabling transcoding
+// when ffmpeg was installed after startup (or disabling it when removed).
+func (h *AdminHandler) RecheckTranscoder(c *gin.Context) {
+	if h.transcoder == nil {
+		Error(c, http.StatusServiceUnavailable, "TRANSCODER_UNAVAILABLE", "transcoder not configured")
+		return
+	}
+
+	status, err := h.transcoder.Recheck(c.Request.Context())
+	response := FFmpegStatusResponse{
+		Available: status.Available,
+		Path:      status.Path,
+		Version:   status.Version,
+		Encoders:  status.Encoders,
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, Response{Success: false, Data: response, Error: &ErrorInfo{
+			Code:    "FFMPEG_UNAVAILABLE",
+			Message: err.Error(),
+		}})
+		return
+	}
+
+	Success(c, response)
+}
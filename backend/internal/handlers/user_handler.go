@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+// Limits on stored view preferences, which are kept in a single settings row
+const (
+	maxPreferenceViews   = 50
+	maxPreferenceFilters = 20
+	maxPreferenceLength  = 100
+)
+
+// UserHandler handles per-user endpoints
+type UserHandler struct {
+	settings *database.SettingsRepository
+}
+
+// NewUserHandler creates a new UserHandler
+func NewUserHandler(settings *database.SettingsRepository) *UserHandler {
+	return &UserHandler{settings: settings}
+}
+
+// GetViewPreferences handles GET /api/v1/users/me/view-preferences
+func (h *UserHandler) GetViewPreferences(c *gin.Context) {
+	prefs, err := h.settings.GetViewPreferences(c.Request.Context(), requestUserID(c))
+	if err != nil {
+		InternalError(c, "failed to get view preferences")
+		return
+	}
+
+	Success(c, prefs)
+}
+
+// SetViewPreferences handles PUT /api/v1/users/me/view-preferences
+// Replaces all saved preferences for the user.
+func (h *UserHandler) SetViewPreferences(c *gin.Context) {
+	var prefs models.ViewPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if msg := validateViewPreferences(prefs); msg != "" {
+		BadRequest(c, msg)
+		return
+	}
+	if prefs == nil {
+		prefs = models.ViewPreferences{}
+	}
+
+	if err := h.settings.SetViewPreferences(c.Request.Context(), requestUserID(c), prefs); err != nil {
+		InternalError(c, "failed to save view preferences")
+		return
+	}
+
+	Success(c, prefs)
+}
+
+// validateViewPreferences returns why prefs can't be stored, or an empty string
+func validateViewPreferences(prefs models.ViewPreferences) string {
+	if len(prefs) > maxPreferenceViews {
+		return "too many views"
+	}
+
+	for view, pref := range prefs {
+		if view == "" || len(view) > maxPreferenceLength {
+			return "invalid view name"
+		}
+		if pref.Order != "" && pref.Order != "asc" && pref.Order != "desc" {
+			return "order must be asc or desc"
+		}
+		if len(pref.SortBy) > maxPreferenceLength {
+			return "sortBy is too long"
+		}
+		if len(pref.Filters) > maxPreferenceFilters {
+			return "too many filters"
+		}
+		for key, value := range pref.Filters {
+			if key == "" || len(key) > maxPreferenceLength || len(value) > maxPreferenceLength {
+				return "invalid filter"
+			}
+		}
+	}
+
+	return ""
+}
+
+// LastFMResponse reports whether the user linked a Last.fm account; the
+// credentials themselves are never sent back
+type LastFMResponse struct {
+	Linked   bool   `json:"linked"`
+	Username string `json:"username,omitempty"`
+}
+
+// GetLastFM handles GET /api/v1/users/me/lastfm
+func (h *UserHandler) GetLastFM(c *gin.Context) {
+	creds, err := h.settings.GetLastFMCredentials(c.Request.Context(), requestUserID(c))
+	if err != nil {
+		InternalError(c, "failed to get last.fm account")
+		return
+	}
+	if creds == nil {
+		Success(c, LastFMResponse{})
+		return
+	}
+
+	Success(c, LastFMResponse{Linked: true, Username: creds.Username})
+}
+
+// SetLastFMRequest links a Last.fm account. The session key is obtained
+// through Last.fm's authentication flow with the same API account.
+type SetLastFMRequest struct {
+	Username   string `json:"username" binding:"max=100"`
+	APIKey     string `json:"apiKey" binding:"required,max=100"`
+	APISecret  string `json:"apiSecret" binding:"required,max=100"`
+	SessionKey string `json:"sessionKey" binding:"required,max=100"`
+}
+
+// SetLastFM handles PUT /api/v1/users/me/lastfm
+// Plays reported through POST /tracks/:id/scrobble are then scrobbled to the
+// account.
+func (h *UserHandler) SetLastFM(c *gin.Context) {
+	var req SetLastFMRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "apiKey, apiSecret and sessionKey are required")
+		return
+	}
+
+	creds := models.LastFMCredentials{
+		Username:   req.Username,
+		APIKey:     req.APIKey,
+		APISecret:  req.APISecret,
+		SessionKey: req.SessionKey,
+	}
+	if err := h.settings.SetLastFMCredentials(c.Request.Context(), requestUserID(c), creds); err != nil {
+		InternalError(c, "failed to save last.fm account")
+		return
+	}
+
+	Success(c, LastFMResponse{Linked: true, Username: creds.Username})
+}
+
+// DeleteLastFM handles DELETE /api/v1/users/me/lastfm
+func (h *UserHandler) DeleteLastFM(c *gin.Context) {
+	if err := h.settings.DeleteLastFMCredentials(c.Request.Context(), requestUserID(c)); err != nil {
+		InternalError(c, "failed to unlink last.fm account")
+		return
+	}
+
+	Success(c, LastFMResponse{})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"harmony/internal/models"
+)
+
+func TestUserRoutesRequireAuth(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodGet, "/api/v1/users/me/view-preferences", nil},
+		{http.MethodPut, "/api/v1/users/me/view-preferences", map[string]any{}},
+		{http.MethodGet, "/api/v1/users/me/lastfm", nil},
+		{http.MethodPut, "/api/v1/users/me/lastfm", map[string]string{"apiKey": "k", "apiSecret": "s", "sessionKey": "sk"}},
+		{http.MethodDelete, "/api/v1/users/me/lastfm", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			// Naming a user doesn't stand in for signing in
+			rec := env.do(tt.method, tt.path, tt.body, "X-User-ID", "victim")
+			expectStatus(t, rec, http.StatusForbidden)
+		})
+	}
+}
+
+func TestLastFMPerUser(t *testing.T) {
+	env := newTestEnv(t, withAuth)
+	alice := env.register("alice")
+	bob := env.register("bob")
+
+	rec := env.do(http.MethodPut, "/api/v1/users/me/lastfm", map[string]string{
+		"username":   "alice_fm",
+		"apiKey":     "key",
+		"apiSecret":  "secret",
+		"sessionKey": "session",
+	}, bearer(alice)...)
+	expectStatus(t, rec, http.StatusOK)
+
+	tests := []struct {
+		name   string
+		token  string
+		linked bool
+	}{
+		{"owner", alice, true},
+		{"other user", bob, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(http.MethodGet, "/api/v1/users/me/lastfm", nil, bearer(tt.token)...)
+			expectStatus(t, rec, http.StatusOK)
+			var got LastFMResponse
+			decodeData(t, rec, &got)
+			if got.Linked != tt.linked {
+				t.Errorf("linked = %v, want %v", got.Linked, tt.linked)
+			}
+		})
+	}
+
+	// Preferences are kept per user as well
+	prefs := models.ViewPreferences{"albums": {SortBy: "year", Order: "desc"}}
+	rec = env.do(http.MethodPut, "/api/v1/users/me/view-preferences", prefs, bearer(alice)...)
+	expectStatus(t, rec, http.StatusOK)
+	rec = env.do(http.MethodGet, "/api/v1/users/me/view-preferences", nil, bearer(bob)...)
+	expectStatus(t, rec, http.StatusOK)
+	var got models.ViewPreferences
+	decodeData(t, rec, &got)
+	if len(got) != 0 {
+		t.Errorf("other user's preferences = %v, want none", got)
+	}
+}
@@ -7,7 +7,13 @@ import (
 type Album struct {
 	ID           string    `gorm:"primaryKey;type:text" json:"id"`
 	Title        string    `gorm:"not null;index" json:"title"`
+	Edition      string    `gorm:"type:text" json:"edition,omitempty"`
 	Year         int       `gorm:"index" json:"year,omitempty"`
+	AlbumType    string    `gorm:"index;type:text" json:"albumType,omitempty"`
+	TotalTracks  int       `gorm:"default:0" json:"totalTracks,omitempty"` // largest track total tagged on its tracks, recounted after each scan
+	TotalDiscs   int       `gorm:"default:0" json:"totalDiscs,omitempty"`  // largest disc total tagged on its tracks
+	Notes        string    `gorm:"type:text" json:"notes,omitempty"`       // liner notes, edited by users or read from a sidecar file
+	NotesEdited  bool      `gorm:"default:false" json:"-"`                 // set once a user edits Notes, so scans stop filling them
 	CoverArtPath string    `gorm:"type:text" json:"-"`
 	CoverArtURL  string    `gorm:"-" json:"coverArtUrl,omitempty"`
 	ArtistID     string    `gorm:"index;type:text" json:"artistId"`
@@ -15,10 +21,18 @@ type Album struct {
 	Tracks       []Track   `gorm:"foreignKey:AlbumID" json:"tracks,omitempty"`
 	TrackCount   int       `gorm:"-" json:"trackCount,omitempty"`
 	Duration     int       `gorm:"-" json:"duration,omitempty"`
-	CreatedAt    time.Time `json:"createdAt"`
+	CreatedAt    time.Time `gorm:"index" json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
 func (Album) TableName() string {
 	return "albums"
 }
+
+// Album types, inferred after each scan
+const (
+	AlbumTypeAlbum       = "album"
+	AlbumTypeEP          = "ep"
+	AlbumTypeSingle      = "single"
+	AlbumTypeCompilation = "compilation"
+)
@@ -7,6 +7,7 @@ import (
 type Artist struct {
 	ID        string    `gorm:"primaryKey;type:text" json:"id"`
 	Name      string    `gorm:"not null;index" json:"name"`
+	NameKey   *string   `gorm:"uniqueIndex;type:text" json:"-"` // normalized name matching variants; nil unless artists are consolidated
 	Bio       string    `gorm:"type:text" json:"bio,omitempty"`
 	ImagePath string    `gorm:"type:text" json:"-"`
 	ImageURL  string    `gorm:"-" json:"imageUrl,omitempty"`
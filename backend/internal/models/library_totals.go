@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// LibraryTotals is a single row of running library totals, kept current by
+// triggers on the tracks, albums and artists tables so stats don't need to
+// scan them. RecountedAt is when the totals were last rebuilt from a full
+// count.
+type LibraryTotals struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	TrackCount    int64     `gorm:"not null;default:0" json:"trackCount"`
+	AlbumCount    int64     `gorm:"not null;default:0" json:"albumCount"`
+	ArtistCount   int64     `gorm:"not null;default:0" json:"artistCount"`
+	TotalDuration int64     `gorm:"not null;default:0" json:"totalDuration"`
+	TotalSize     int64     `gorm:"not null;default:0" json:"totalSize"`
+	RecountedAt   time.Time `json:"recountedAt"`
+}
+
+func (LibraryTotals) TableName() string {
+	return "library_totals"
+}
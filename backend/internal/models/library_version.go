@@ -0,0 +1,13 @@
+package models
+
+// LibraryVersion is a single row holding a counter that triggers bump on
+// every write to the tables clients mirror or list, so they can tell
+// whether anything changed without comparing the data
+type LibraryVersion struct {
+	ID      uint  `gorm:"primaryKey" json:"-"`
+	Version int64 `gorm:"not null;default:0" json:"version"`
+}
+
+func (LibraryVersion) TableName() string {
+	return "library_version"
+}
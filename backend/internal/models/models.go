@@ -7,8 +7,13 @@ func AllModels() []interface{} {
 		&Artist{},
 		&Album{},
 		&Track{},
+		&Chapter{},
+		&Play{},
 		&Playlist{},
 		&PlaylistTrack{},
 		&Settings{},
+		&ScanFailure{},
+		&LibraryTotals{},
+		&LibraryVersion{},
 	}
 }
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TrackPaths controls how Track.FilePath is persisted. In relative mode paths
+// under the media root are stored relative to it, tagged with the root's ID,
+// so the library survives the root moving (e.g. a new Docker mount point).
+// A nil TrackPaths stores paths as they are.
+type TrackPaths struct {
+	rootID   string
+	root     string
+	relative bool
+}
+
+// NewTrackPaths describes the media root used to store and resolve track paths
+func NewTrackPaths(rootID, root string, relative bool) *TrackPaths {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &TrackPaths{rootID: rootID, root: root, relative: relative}
+}
+
+// Stored converts an absolute path to the form saved in the database,
+// returning the root ID for relative paths
+func (p *TrackPaths) Stored(path string) (string, string) {
+	if p == nil || !p.relative || !filepath.IsAbs(path) {
+		return path, ""
+	}
+
+	rel, err := filepath.Rel(p.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, ""
+	}
+	return filepath.ToSlash(rel), p.rootID
+}
+
+// Resolve converts a stored path back to an absolute path
+func (p *TrackPaths) Resolve(path, rootID string) string {
+	if p == nil || rootID == "" || rootID != p.rootID || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.root, filepath.FromSlash(path))
+}
+
+// trackPathsSetting is the gorm setting holding a database's TrackPaths
+const trackPathsSetting = "harmony:track_paths"
+
+// WithTrackPaths returns db set up to store and resolve track paths with
+// paths. The setting carries over to every query, transaction and preload
+// made from the returned handle.
+func WithTrackPaths(db *gorm.DB, paths *TrackPaths) *gorm.DB {
+	return db.Set(trackPathsSetting, paths).Session(&gorm.Session{})
+}
+
+// TrackPathsOf returns the TrackPaths db was set up with, or nil
+func TrackPathsOf(db *gorm.DB) *TrackPaths {
+	value, _ := db.Get(trackPathsSetting)
+	paths, _ := value.(*TrackPaths)
+	return paths
+}
+
+// BeforeSave stores the file path in its configured form
+func (t *Track) BeforeSave(tx *gorm.DB) error {
+	if t.FilePath != "" {
+		t.FilePath, t.RootID = TrackPathsOf(tx).Stored(t.FilePath)
+	}
+	return nil
+}
+
+// AfterSave restores the absolute path on the caller's struct
+func (t *Track) AfterSave(tx *gorm.DB) error {
+	t.FilePath = TrackPathsOf(tx).Resolve(t.FilePath, t.RootID)
+	return nil
+}
+
+// AfterFind resolves stored paths to absolute paths
+func (t *Track) AfterFind(tx *gorm.DB) error {
+	t.FilePath = TrackPathsOf(tx).Resolve(t.FilePath, t.RootID)
+	return nil
+}
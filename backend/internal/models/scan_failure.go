@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// ScanFailure records a file that failed to import, so scans can stop
+// retrying it until it changes. FailCount counts consecutive failures of the
+// file as it was at ModTime.
+type ScanFailure struct {
+	Path         string    `gorm:"primaryKey;type:text" json:"path"`
+	ModTime      time.Time `gorm:"not null" json:"modTime"`
+	FailCount    int       `gorm:"not null;default:0" json:"failCount"`
+	LastError    string    `gorm:"type:text" json:"lastError"`
+	LastFailedAt time.Time `json:"lastFailedAt"`
+}
+
+func (ScanFailure) TableName() string {
+	return "scan_failures"
+}
@@ -22,4 +22,44 @@ const (
 	SettingMediaPaths     = "media_paths"
 	SettingAppName        = "app_name"
 	SettingTheme          = "theme"
+
+	// SettingTranscodeProfiles holds custom transcode profiles as JSON
+	SettingTranscodeProfiles = "transcode_profiles"
+
+	// SettingViewPreferencesPrefix is followed by the user ID
+	SettingViewPreferencesPrefix = "view_preferences:"
+
+	// SettingLastFMPrefix is followed by the user ID
+	SettingLastFMPrefix = "lastfm:"
+
+	// SettingLastScanAt is when the last scan completed, as RFC 3339
+	SettingLastScanAt = "last_scan_at"
 )
+
+// ViewPreference is the sort and filter state a client keeps for one view
+type ViewPreference struct {
+	SortBy  string            `json:"sortBy,omitempty"`
+	Order   string            `json:"order,omitempty"`
+	Filters map[string]string `json:"filters,omitempty"`
+}
+
+// ViewPreferences maps view names ("albums", "tracks", ...) to their preference
+type ViewPreferences map[string]ViewPreference
+
+// TranscodeProfile is a custom transcode profile defined through the admin API
+type TranscodeProfile struct {
+	Name    string `json:"name"`
+	Codec   string `json:"codec"`
+	Format  string `json:"format"`
+	Bitrate int    `json:"bitrate"`
+}
+
+// LastFMCredentials link a user to their Last.fm account. The API key and
+// secret are those of a Last.fm API account; the session key authorizes
+// scrobbling as the user.
+type LastFMCredentials struct {
+	Username   string `json:"username,omitempty"`
+	APIKey     string `json:"apiKey"`
+	APISecret  string `json:"apiSecret"`
+	SessionKey string `json:"sessionKey"`
+}
@@ -4,28 +4,89 @@ import (
 	"time"
 )
 
+// Track is a playable audio track. Tracks split from a single file by a cue
+// sheet share FilePath and are told apart by their offsets (in milliseconds);
+// an EndOffset of 0 means the track runs to the end of the file. TrackGain
+// and AlbumGain are ReplayGain adjustments in dB, nil when untagged;
+// EncoderDelay and EncoderPad are the samples an encoder added before and
+// after the audio, which gapless players trim.
 type Track struct {
-	ID          string    `gorm:"primaryKey;type:text" json:"id"`
-	Title       string    `gorm:"not null;index" json:"title"`
-	Duration    int       `gorm:"not null" json:"duration"`
-	TrackNumber int       `gorm:"default:0" json:"trackNumber"`
-	DiscNumber  int       `gorm:"default:1" json:"discNumber"`
-	FilePath    string    `gorm:"not null;uniqueIndex;type:text" json:"-"`
-	FileSize    int64     `gorm:"not null" json:"fileSize"`
-	Format      string    `gorm:"not null;type:text" json:"format"`
-	Bitrate     int       `gorm:"default:0" json:"bitrate,omitempty"`
-	SampleRate  int       `gorm:"default:0" json:"sampleRate,omitempty"`
-	Channels    int       `gorm:"default:2" json:"channels,omitempty"`
-	AlbumID     string    `gorm:"index;type:text" json:"albumId,omitempty"`
-	Album       *Album    `gorm:"foreignKey:AlbumID" json:"album,omitempty"`
-	ArtistID    string    `gorm:"index;type:text" json:"artistId,omitempty"`
-	Artist      *Artist   `gorm:"foreignKey:ArtistID" json:"artist,omitempty"`
-	Genre       string    `gorm:"index;type:text" json:"genre,omitempty"`
-	Year        int       `gorm:"index" json:"year,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID            string     `gorm:"primaryKey;type:text" json:"id"`
+	Title         string     `gorm:"not null;index" json:"title"`
+	Duration      int        `gorm:"not null" json:"duration"`
+	TrackNumber   int        `gorm:"default:0" json:"trackNumber"`
+	DiscNumber    int        `gorm:"default:1" json:"discNumber"`
+	TotalTracks   int        `gorm:"default:0" json:"totalTracks,omitempty"` // tagged track count of the disc; 0 when untagged
+	TotalDiscs    int        `gorm:"default:0" json:"totalDiscs,omitempty"`  // tagged disc count; 0 when untagged
+	AlbumOrder    int        `gorm:"default:0" json:"albumOrder,omitempty"`  // manual position in the album; 0 uses disc/track number
+	FilePath      string     `gorm:"not null;uniqueIndex:idx_tracks_file_segment;type:text" json:"-"`
+	StartOffset   int        `gorm:"default:0;uniqueIndex:idx_tracks_file_segment" json:"startOffset,omitempty"`
+	EndOffset     int        `gorm:"default:0" json:"endOffset,omitempty"`
+	RootID        string     `gorm:"type:text" json:"-"`
+	FileSize      int64      `gorm:"not null" json:"fileSize"`
+	FileHash      string     `gorm:"index;type:text" json:"-"` // content hash keying cached transcodes; empty unless files are hashed
+	ModTime       time.Time  `gorm:"index" json:"-"`           // file modification time when last scanned; zero rescans the file
+	Format        string     `gorm:"not null;type:text" json:"format"`
+	Lossless      bool       `gorm:"default:false;index" json:"lossless"` // lossless audio, whatever the container
+	Bitrate       int        `gorm:"default:0" json:"bitrate,omitempty"`
+	SampleRate    int        `gorm:"default:0" json:"sampleRate,omitempty"`
+	Channels      int        `gorm:"default:2" json:"channels,omitempty"`
+	AlbumID       string     `gorm:"index;type:text" json:"albumId,omitempty"`
+	Album         *Album     `gorm:"foreignKey:AlbumID" json:"album,omitempty"`
+	FoldedAlbum   string     `gorm:"type:text" json:"-"` // tagged album of a track folded into its artist's Singles album
+	ArtistID      string     `gorm:"index;type:text" json:"artistId,omitempty"`
+	Artist        *Artist    `gorm:"foreignKey:ArtistID" json:"artist,omitempty"`
+	Genre         string     `gorm:"index;type:text" json:"genre,omitempty"`
+	InferredGenre string     `gorm:"index;type:text" json:"inferredGenre,omitempty"` // artist's dominant genre for untagged tracks; never written to Genre
+	Year          int        `gorm:"index" json:"year,omitempty"`
+	Rating        int        `gorm:"default:0;index" json:"rating"`
+	BPM           int        `gorm:"column:bpm;default:0;index" json:"bpm,omitempty"`
+	MusicalKey    string     `gorm:"index;type:text" json:"musicalKey,omitempty"`
+	AnalyzedAt    time.Time  `json:"-"`                  // when BPM and key detection ran on this version of the file; zero when it didn't
+	Lyrics        string     `gorm:"type:text" json:"-"` // plain or LRC; served by the lyrics endpoint
+	TrackGain     *float64   `json:"trackGain,omitempty"`
+	AlbumGain     *float64   `json:"albumGain,omitempty"`
+	EncoderDelay  int        `gorm:"default:0" json:"encoderDelay,omitempty"`
+	EncoderPad    int        `gorm:"default:0" json:"encoderPadding,omitempty"`
+	PlayCount     int        `gorm:"default:0" json:"playCount"`
+	SkipCount     int        `gorm:"default:0" json:"skipCount"`
+	LastPlayedAt  *time.Time `json:"lastPlayedAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
 }
 
 func (Track) TableName() string {
 	return "tracks"
 }
+
+// Chapter is a marker within a long-form track such as an audiobook or DJ
+// mix. Times are in milliseconds; an EndTime of 0 means the chapter runs to
+// the end of the track.
+type Chapter struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	TrackID   string `gorm:"not null;index;type:text" json:"trackId"`
+	Track     *Track `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"-"`
+	Position  int    `gorm:"not null" json:"position"`
+	Title     string `gorm:"type:text" json:"title"`
+	StartTime int    `gorm:"not null" json:"startTime"`
+	EndTime   int    `gorm:"default:0" json:"endTime"`
+}
+
+func (Chapter) TableName() string {
+	return "chapters"
+}
+
+// Play records one listen of a track, so plays can be attributed to users.
+// Skips are recorded too, with Completed false.
+type Play struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	TrackID   string    `gorm:"not null;index;type:text" json:"trackId"`
+	Track     *Track    `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID    string    `gorm:"index;type:text" json:"userId"`
+	Completed bool      `gorm:"not null" json:"completed"`
+	PlayedAt  time.Time `gorm:"not null;index" json:"playedAt"`
+}
+
+func (Play) TableName() string {
+	return "plays"
+}
@@ -9,6 +9,7 @@ type User struct {
 	Username     string     `gorm:"not null;uniqueIndex;type:text" json:"username"`
 	Email        string     `gorm:"not null;uniqueIndex;type:text" json:"email"`
 	PasswordHash string     `gorm:"not null;type:text" json:"-"`
+	IsAdmin      bool       `gorm:"not null;default:false" json:"isAdmin"`
 	Playlists    []Playlist `gorm:"foreignKey:UserID" json:"playlists,omitempty"`
 	CreatedAt    time.Time  `json:"createdAt"`
 	UpdatedAt    time.Time  `json:"updatedAt"`
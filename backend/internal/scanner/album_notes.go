@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxAlbumNotesSize bounds how much of a notes file is read
+const maxAlbumNotesSize = 64 << 10
+
+// albumNotesNames are the file names, compared case-insensitively, that
+// hold an album's liner notes, in order of preference
+var albumNotesNames = []string{
+	"notes.txt",
+	"liner notes.txt",
+	"linernotes.txt",
+	"description.txt",
+	"notes",
+}
+
+// FindAlbumNotes returns the liner notes kept in a text file next to an
+// album's audio file, or an empty string when there are none. Files that
+// aren't valid UTF-8 text are ignored.
+func FindAlbumNotes(audioPath string) string {
+	dir := filepath.Dir(audioPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names[strings.ToLower(entry.Name())] = entry.Name()
+		}
+	}
+
+	for _, candidate := range albumNotesNames {
+		name, ok := names[candidate]
+		if !ok {
+			continue
+		}
+		if notes := readAlbumNotes(filepath.Join(dir, name)); notes != "" {
+			return notes
+		}
+	}
+	return ""
+}
+
+// readAlbumNotes reads a notes file, trimmed, or an empty string if it
+// can't be read or isn't text
+func readAlbumNotes(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAlbumNotesSize))
+	if err != nil || !utf8.Valid(data) {
+		return ""
+	}
+	notes := strings.TrimPrefix(string(data), "\uFEFF") // byte order mark
+	return strings.TrimSpace(strings.ReplaceAll(notes, "\r\n", "\n"))
+}
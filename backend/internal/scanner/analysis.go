@@ -0,0 +1,270 @@
+package scanner
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// AnalysisSampleRate is the mono sample rate audio is decoded at for analysis
+const AnalysisSampleRate = 11025
+
+// Tempo range considered by BPM detection
+const (
+	minDetectedBPM = 60
+	maxDetectedBPM = 200
+)
+
+// AudioAnalysis holds the tempo and key detected for a track
+type AudioAnalysis struct {
+	BPM float64
+	Key string
+}
+
+// pitchClasses names the twelve semitones, using sharps
+var pitchClasses = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// flatAliases maps flat spellings to their sharp equivalent
+var flatAliases = map[string]string{
+	"DB": "C#", "EB": "D#", "GB": "F#", "AB": "G#", "BB": "A#", "CB": "B", "FB": "E",
+}
+
+// camelotMajor maps Camelot wheel numbers (B side) to major keys; the A side
+// of the same number is the relative minor, three semitones lower
+var camelotMajor = [13]int{0, 11, 6, 1, 8, 3, 10, 5, 0, 7, 2, 9, 4}
+
+// Krumhansl-Kessler key profiles, starting at the tonic
+var (
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// NormalizeKey converts key notations such as "A minor", "Amin", "Bbm" or the
+// Camelot "8A" into the short sharp form used for storage ("Am", "A#m", "C").
+// Unrecognised values are returned as an empty string.
+func NormalizeKey(s string) string {
+	key := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	if key == "" {
+		return ""
+	}
+
+	// Camelot notation: 1-12 followed by A (minor) or B (major)
+	if n, err := strconv.Atoi(key[:len(key)-1]); err == nil && n >= 1 && n <= 12 {
+		switch key[len(key)-1] {
+		case 'B':
+			return pitchClasses[camelotMajor[n]]
+		case 'A':
+			return pitchClasses[(camelotMajor[n]+9)%12] + "m"
+		}
+		return ""
+	}
+
+	root := key[:1]
+	rest := key[1:]
+	// No mode suffix starts with "B", so one after the root is always a flat
+	if len(rest) > 0 && (rest[0] == '#' || rest[0] == 'B') {
+		root += rest[:1]
+		rest = rest[1:]
+	}
+	if alias, ok := flatAliases[root]; ok {
+		root = alias
+	}
+
+	valid := false
+	for _, pc := range pitchClasses {
+		if pc == root {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ""
+	}
+
+	switch rest {
+	case "", "MAJ", "MAJOR":
+		return root
+	case "M", "MIN", "MINOR":
+		return root + "m"
+	}
+	return ""
+}
+
+// CamelotKey returns the Camelot wheel code ("8A") for a normalized key
+func CamelotKey(key string) string {
+	minor := strings.HasSuffix(key, "m")
+	root := strings.TrimSuffix(key, "m")
+
+	for n := 1; n <= 12; n++ {
+		if minor && pitchClasses[(camelotMajor[n]+9)%12] == root {
+			return strconv.Itoa(n) + "A"
+		}
+		if !minor && pitchClasses[camelotMajor[n]] == root {
+			return strconv.Itoa(n) + "B"
+		}
+	}
+	return ""
+}
+
+// Analyze detects the tempo and key of mono audio sampled at sampleRate
+func Analyze(samples []float32, sampleRate int) AudioAnalysis {
+	return AudioAnalysis{
+		BPM: DetectBPM(samples, sampleRate),
+		Key: DetectKey(samples, sampleRate),
+	}
+}
+
+// DetectBPM estimates tempo by autocorrelating an onset-strength envelope.
+// Returns 0 when the audio is too short or has no clear pulse.
+func DetectBPM(samples []float32, sampleRate int) float64 {
+	const hop = 128
+	if sampleRate <= 0 || len(samples) < sampleRate*4 {
+		return 0
+	}
+
+	// Energy per hop, then half-wave rectified energy rise as onset strength
+	frames := len(samples) / hop
+	energy := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		var sum float64
+		for _, v := range samples[i*hop : (i+1)*hop] {
+			sum += float64(v) * float64(v)
+		}
+		energy[i] = math.Sqrt(sum / hop)
+	}
+
+	onset := make([]float64, frames)
+	var mean float64
+	for i := 1; i < frames; i++ {
+		if d := energy[i] - energy[i-1]; d > 0 {
+			onset[i] = d
+		}
+		mean += onset[i]
+	}
+	mean /= float64(frames)
+	if mean == 0 {
+		return 0
+	}
+	for i := range onset {
+		onset[i] -= mean
+	}
+
+	framesPerSecond := float64(sampleRate) / hop
+	minLag := int(framesPerSecond * 60 / maxDetectedBPM)
+	maxLag := int(framesPerSecond*60/minDetectedBPM) + 1
+	if maxLag >= frames/2 {
+		return 0
+	}
+
+	acf := make([]float64, maxLag+2)
+	for lag := minLag - 1; lag <= maxLag+1; lag++ {
+		var sum float64
+		for i := lag; i < frames; i++ {
+			sum += onset[i] * onset[i-lag]
+		}
+		acf[lag] = sum / float64(frames-lag)
+	}
+
+	// Pick the strongest period, weighting toward common tempos so that
+	// half- and double-time peaks don't win on near ties
+	bestLag, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		if acf[lag] <= 0 || acf[lag] < acf[lag-1] || acf[lag] < acf[lag+1] {
+			continue
+		}
+		bpm := framesPerSecond * 60 / float64(lag)
+		weight := math.Exp(-0.5 * math.Pow(math.Log2(bpm/120), 2))
+		if score := acf[lag] * weight; score > bestScore {
+			bestLag, bestScore = lag, score
+		}
+	}
+	if bestLag == 0 {
+		return 0
+	}
+
+	// Parabolic interpolation around the peak for sub-frame precision
+	lag := float64(bestLag)
+	a, b, c := acf[bestLag-1], acf[bestLag], acf[bestLag+1]
+	if denom := a - 2*b + c; denom != 0 {
+		lag += 0.5 * (a - c) / denom
+	}
+
+	return math.Round(framesPerSecond*60/lag*10) / 10
+}
+
+// DetectKey estimates the musical key by building a chromagram with Goertzel
+// filters and correlating it against the major and minor key profiles.
+// Returns an empty string when the audio has no tonal content.
+func DetectKey(samples []float32, sampleRate int) string {
+	const frameSize = 4096
+	if sampleRate <= 0 || len(samples) < frameSize {
+		return ""
+	}
+
+	// Notes from C2 to B5, kept well below Nyquist at the analysis rate
+	const firstNote, lastNote = 36, 83
+	coeffs := make([]float64, 0, lastNote-firstNote+1)
+	for note := firstNote; note <= lastNote; note++ {
+		freq := 440 * math.Pow(2, float64(note-69)/12)
+		coeffs = append(coeffs, 2*math.Cos(2*math.Pi*freq/float64(sampleRate)))
+	}
+
+	var chroma [12]float64
+	for start := 0; start+frameSize <= len(samples); start += frameSize {
+		frame := samples[start : start+frameSize]
+		for i, coeff := range coeffs {
+			var s1, s2 float64
+			for _, v := range frame {
+				s0 := float64(v) + coeff*s1 - s2
+				s2, s1 = s1, s0
+			}
+			power := s1*s1 + s2*s2 - coeff*s1*s2
+			chroma[(firstNote+i)%12] += math.Sqrt(math.Max(power, 0))
+		}
+	}
+
+	var total float64
+	for _, v := range chroma {
+		total += v
+	}
+	if total == 0 {
+		return ""
+	}
+
+	bestKey, bestCorr := "", math.Inf(-1)
+	for tonic := 0; tonic < 12; tonic++ {
+		if corr := profileCorrelation(chroma, majorProfile, tonic); corr > bestCorr {
+			bestKey, bestCorr = pitchClasses[tonic], corr
+		}
+		if corr := profileCorrelation(chroma, minorProfile, tonic); corr > bestCorr {
+			bestKey, bestCorr = pitchClasses[tonic]+"m", corr
+		}
+	}
+
+	return bestKey
+}
+
+// profileCorrelation returns the Pearson correlation between a chromagram and
+// a key profile rotated to start at tonic
+func profileCorrelation(chroma, profile [12]float64, tonic int) float64 {
+	var meanC, meanP float64
+	for i := 0; i < 12; i++ {
+		meanC += chroma[i]
+		meanP += profile[i]
+	}
+	meanC /= 12
+	meanP /= 12
+
+	var num, denC, denP float64
+	for i := 0; i < 12; i++ {
+		dc := chroma[(tonic+i)%12] - meanC
+		dp := profile[i] - meanP
+		num += dc * dp
+		denC += dc * dc
+		denP += dp * dp
+	}
+	if denC == 0 || denP == 0 {
+		return 0
+	}
+	return num / math.Sqrt(denC*denP)
+}
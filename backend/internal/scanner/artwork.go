@@ -2,20 +2,35 @@ package scanner
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	_ "image/gif"  // GIF support
+	"golang.org/x/image/draw"
 	_ "golang.org/x/image/webp" // WebP support (if available)
 )
 
+// ErrArtworkTooLarge is returned when an image exceeds the configured dimension or pixel limit
+var ErrArtworkTooLarge = errors.New("artwork dimensions too large")
+
+// DefaultMaxArtworkDimension bounds the width and height of images we are willing to decode
+const DefaultMaxArtworkDimension = 8192
+
+// DefaultMaxArtworkPixels bounds the pixel count of images we are willing to
+// decode, which sizes the memory decoding takes (4 bytes a pixel or more)
+const DefaultMaxArtworkPixels = 4096 * 4096
+
 // ArtworkSize represents a predefined artwork size
 type ArtworkSize struct {
 	Name   string
@@ -61,20 +76,104 @@ var ExternalArtworkFiles = []string{
 type ArtworkInfo struct {
 	Data     []byte
 	MIMEType string
-	Source   string // "embedded" or "external"
+	Source   string // "embedded", "external" or "video"
 	Path     string // For external artwork, the file path
 }
 
+// ThumbnailMode controls how artwork is fitted to the predefined sizes
+type ThumbnailMode string
+
+const (
+	// ThumbnailFit scales to fit within the size, preserving aspect ratio
+	ThumbnailFit ThumbnailMode = "fit"
+	// ThumbnailCrop center-crops to the size's aspect ratio, then scales
+	ThumbnailCrop ThumbnailMode = "crop"
+	// ThumbnailPad fits the image, then centers it on a background of the exact size
+	ThumbnailPad ThumbnailMode = "pad"
+)
+
+// ParseThumbnailMode validates a thumbnail mode name
+func ParseThumbnailMode(s string) (ThumbnailMode, error) {
+	switch mode := ThumbnailMode(strings.ToLower(s)); mode {
+	case ThumbnailFit, ThumbnailCrop, ThumbnailPad:
+		return mode, nil
+	case "":
+		return ThumbnailFit, nil
+	}
+	return "", fmt.Errorf("unknown thumbnail mode: %s", s)
+}
+
+// ParseHexColor parses a "#rrggbb" color
+func ParseHexColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(strings.ToLower(s), "#%02x%02x%02x", &r, &g, &b); err != nil || len(s) != 7 {
+		return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}, nil
+}
+
 // ArtworkProcessor handles artwork extraction and processing
 type ArtworkProcessor struct {
-	cacheDir string
+	cacheDir      string
+	maxDimension  int
+	maxPixels     int
+	thumbnailMode ThumbnailMode
+	padColor      color.Color
+	trackLocks    keyedLocks
 }
 
 // NewArtworkProcessor creates a new ArtworkProcessor
 func NewArtworkProcessor(cacheDir string) *ArtworkProcessor {
 	return &ArtworkProcessor{
-		cacheDir: cacheDir,
+		cacheDir:      cacheDir,
+		maxDimension:  DefaultMaxArtworkDimension,
+		maxPixels:     DefaultMaxArtworkPixels,
+		thumbnailMode: ThumbnailFit,
+		padColor:      color.Black,
+	}
+}
+
+// SetThumbnailMode sets how resized versions are produced; background is
+// only used by ThumbnailPad and defaults to black when nil
+func (p *ArtworkProcessor) SetThumbnailMode(mode ThumbnailMode, background color.Color) {
+	if mode == "" {
+		mode = ThumbnailFit
+	}
+	p.thumbnailMode = mode
+	if background != nil {
+		p.padColor = background
+	}
+}
+
+// SetMaxDimension sets the largest width or height accepted before decoding
+func (p *ArtworkProcessor) SetMaxDimension(max int) {
+	if max > 0 {
+		p.maxDimension = max
+	}
+}
+
+// SetMaxPixels sets the largest width times height accepted before decoding
+func (p *ArtworkProcessor) SetMaxPixels(max int) {
+	if max > 0 {
+		p.maxPixels = max
+	}
+}
+
+// checkDimensions reads only the image header and rejects oversized images,
+// so decompression bombs are refused before any pixel data is allocated
+func (p *ArtworkProcessor) checkDimensions(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("reading image header: %w", err)
+	}
+
+	if cfg.Width > p.maxDimension || cfg.Height > p.maxDimension {
+		return fmt.Errorf("%w: %dx%d exceeds %d", ErrArtworkTooLarge, cfg.Width, cfg.Height, p.maxDimension)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > int64(p.maxPixels) {
+		return fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrArtworkTooLarge, cfg.Width, cfg.Height, p.maxPixels)
 	}
+	return nil
 }
 
 // FindArtwork looks for artwork for an audio file
@@ -152,45 +251,123 @@ func (p *ArtworkProcessor) findExternalArtwork(dir string) (*ArtworkInfo, error)
 
 // ProcessAndCache processes artwork and caches it in multiple sizes
 func (p *ArtworkProcessor) ProcessAndCache(artwork *ArtworkInfo, albumID string) (map[string]string, error) {
+	return p.processInto(artwork, ArtworkKindAlbum, albumID)
+}
+
+// CacheArtistImage processes an artist image and caches it in multiple sizes
+func (p *ArtworkProcessor) CacheArtistImage(artwork *ArtworkInfo, artistID string) (map[string]string, error) {
+	return p.processInto(artwork, ArtworkKindArtist, artistID)
+}
+
+// CachePath returns the cached image path for any kind of entity and size
+func (p *ArtworkProcessor) CachePath(kind ArtworkKind, id string, size string) string {
+	return ArtworkCachePath(p.cacheDir, kind, id, size)
+}
+
+// GetArtistImagePath returns the cached image path for an artist and size
+func (p *ArtworkProcessor) GetArtistImagePath(artistID string, size string) string {
+	return ArtworkCachePath(p.cacheDir, ArtworkKindArtist, artistID, size)
+}
+
+// processInto decodes artwork and writes the original plus all predefined
+// sizes to the entity's cache directory
+func (p *ArtworkProcessor) processInto(artwork *ArtworkInfo, kind ArtworkKind, id string) (map[string]string, error) {
 	if artwork == nil || len(artwork.Data) == 0 {
 		return nil, nil
 	}
 
+	// Check dimensions before decoding
+	if err := p.checkDimensions(artwork.Data); err != nil {
+		return nil, err
+	}
+
 	// Decode the image
 	img, _, err := image.Decode(bytes.NewReader(artwork.Data))
 	if err != nil {
 		return nil, fmt.Errorf("decoding image: %w", err)
 	}
 
-	// Create cache directory for this album
-	albumCacheDir := filepath.Join(p.cacheDir, "artwork", albumID)
-	if err := os.MkdirAll(albumCacheDir, 0755); err != nil {
+	// Create cache directory
+	if err := os.MkdirAll(ArtworkCacheDir(p.cacheDir, kind, id), 0755); err != nil {
 		return nil, fmt.Errorf("creating cache directory: %w", err)
 	}
 
 	paths := make(map[string]string)
 
 	// Save original
-	originalPath := filepath.Join(albumCacheDir, "original.jpg")
+	originalPath := ArtworkCachePath(p.cacheDir, kind, id, "original")
 	if err := p.saveImage(img, originalPath); err != nil {
 		return nil, fmt.Errorf("saving original: %w", err)
 	}
 	paths["original"] = originalPath
 
 	// Create resized versions
+	colorPath := filepath.Join(ArtworkCacheDir(p.cacheDir, kind, id), dominantColorFile)
+	os.Remove(colorPath)
 	for _, size := range AllArtworkSizes {
-		resized := p.resize(img, size.Width, size.Height)
-		path := filepath.Join(albumCacheDir, fmt.Sprintf("%s.jpg", size.Name))
+		resized := p.thumbnail(img, size)
+		path := ArtworkCachePath(p.cacheDir, kind, id, size.Name)
 		if err := p.saveImage(resized, path); err != nil {
 			slog.Warn("failed to save resized image", "size", size.Name, "error", err)
 			continue
 		}
 		paths[size.Name] = path
+
+		// An album thumbnail's color is worked out once, for the status endpoint
+		if kind == ArtworkKindAlbum && size.Name == ArtworkSizeThumbnail.Name {
+			if hex, ok := averageColor(resized); ok {
+				os.WriteFile(colorPath, []byte(hex), 0644)
+			}
+		}
 	}
 
 	return paths, nil
 }
 
+// thumbnail produces one predefined size using the configured mode
+func (p *ArtworkProcessor) thumbnail(img image.Image, size ArtworkSize) image.Image {
+	switch p.thumbnailMode {
+	case ThumbnailCrop:
+		return p.scale(cropToAspect(img, size.Width, size.Height), size.Width, size.Height)
+	case ThumbnailPad:
+		fitted := p.resize(img, size.Width, size.Height)
+		dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(p.padColor), image.Point{}, draw.Src)
+		offset := image.Pt((size.Width-fitted.Bounds().Dx())/2, (size.Height-fitted.Bounds().Dy())/2)
+		draw.Draw(dst, fitted.Bounds().Add(offset), fitted, fitted.Bounds().Min, draw.Over)
+		return dst
+	default:
+		return p.resize(img, size.Width, size.Height)
+	}
+}
+
+// cropToAspect returns the largest centered region of img with the aspect
+// ratio width:height
+func cropToAspect(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	cropW, cropH := bounds.Dx(), bounds.Dy()
+
+	if cropW*height > cropH*width {
+		cropW = cropH * width / height
+	} else {
+		cropH = cropW * height / width
+	}
+
+	x0 := bounds.Min.X + (bounds.Dx()-cropW)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-cropH)/2
+	rect := image.Rect(x0, y0, x0+cropW, y0+cropH)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, cropW, cropH))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
+
 // resize resizes an image to fit within the given dimensions while maintaining aspect ratio
 func (p *ArtworkProcessor) resize(img image.Image, maxWidth, maxHeight int) image.Image {
 	bounds := img.Bounds()
@@ -221,62 +398,149 @@ func (p *ArtworkProcessor) resize(img image.Image, maxWidth, maxHeight int) imag
 		newWidth = int(float64(newHeight) * ratio)
 	}
 
+	return p.scale(img, newWidth, newHeight)
+}
+
+// scale resizes an image to exactly the given dimensions
+func (p *ArtworkProcessor) scale(img image.Image, newWidth, newHeight int) image.Image {
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
 	// Create new image with calculated dimensions
 	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
 
-	// Simple nearest-neighbor scaling (for better quality, use a dedicated imaging library)
-	scaleX := float64(srcWidth) / float64(newWidth)
-	scaleY := float64(srcHeight) / float64(newHeight)
-
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			srcX := int(float64(x) * scaleX)
-			srcY := int(float64(y) * scaleY)
-			if srcX >= srcWidth {
-				srcX = srcWidth - 1
-			}
-			if srcY >= srcHeight {
-				srcY = srcHeight - 1
-			}
-			dst.Set(x, y, img.At(srcX+bounds.Min.X, srcY+bounds.Min.Y))
-		}
-	}
+	// Catmull-Rom resampling averages over every source pixel a target pixel
+	// covers, keeping small thumbnails smooth instead of blocky
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
 
 	return dst
 }
 
-// saveImage saves an image as JPEG
+// saveImage saves an image as JPEG. It's written to a temporary file moved
+// into place when complete, so readers never see a partial image.
 func (p *ArtworkProcessor) saveImage(img image.Image, path string) error {
-	file, err := os.Create(path)
+	file, err := os.CreateTemp(filepath.Dir(path), ".artwork-*")
 	if err != nil {
 		return fmt.Errorf("creating file: %w", err)
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
 
 	opts := &jpeg.Options{Quality: 85}
 	if err := jpeg.Encode(file, img, opts); err != nil {
 		return fmt.Errorf("encoding jpeg: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("setting file mode: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("moving file: %w", err)
+	}
 
 	return nil
 }
 
 // GetArtworkPath returns the cached artwork path for an album and size
 func (p *ArtworkProcessor) GetArtworkPath(albumID string, size string) string {
-	return filepath.Join(p.cacheDir, "artwork", albumID, fmt.Sprintf("%s.jpg", size))
+	return ArtworkCachePath(p.cacheDir, ArtworkKindAlbum, albumID, size)
 }
 
 // ArtworkExists checks if artwork exists for an album
 func (p *ArtworkProcessor) ArtworkExists(albumID string) bool {
-	path := filepath.Join(p.cacheDir, "artwork", albumID, "original.jpg")
-	_, err := os.Stat(path)
+	_, err := os.Stat(p.GetArtworkPath(albumID, "original"))
 	return err == nil
 }
 
+// AvailableSizes returns the names of the cached sizes for an album
+func (p *ArtworkProcessor) AvailableSizes(albumID string) []string {
+	var sizes []string
+	for _, size := range AllArtworkSizes {
+		if _, err := os.Stat(p.GetArtworkPath(albumID, size.Name)); err == nil {
+			sizes = append(sizes, size.Name)
+		}
+	}
+	if p.ArtworkExists(albumID) {
+		sizes = append(sizes, "original")
+	}
+	return sizes
+}
+
+// ArtworkDataURI returns an album's cached artwork of the given size as a
+// base64 data URI, for embedding in responses. The media type is sniffed from
+// the file, since artwork saved as is keeps its own format.
+func (p *ArtworkProcessor) ArtworkDataURI(albumID string, size string) (string, error) {
+	data, err := os.ReadFile(p.GetArtworkPath(albumID, size))
+	if err != nil {
+		return "", err
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("cached artwork is not an image: %s", mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// dominantColorFile holds the average color of an entity's thumbnail, as
+// a hex string, next to its cached images
+const dominantColorFile = "color"
+
+// DominantColor returns the average color of an album's cached thumbnail as
+// a hex string. It's saved when the artwork is cached; thumbnails cached
+// before that have it worked out and saved on first use.
+func (p *ArtworkProcessor) DominantColor(albumID string) (string, error) {
+	colorPath := filepath.Join(ArtworkCacheDir(p.cacheDir, ArtworkKindAlbum, albumID), dominantColorFile)
+	if data, err := os.ReadFile(colorPath); err == nil {
+		return string(data), nil
+	}
+
+	file, err := os.Open(p.GetArtworkPath(albumID, ArtworkSizeThumbnail.Name))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("decoding thumbnail: %w", err)
+	}
+	hex, ok := averageColor(img)
+	if !ok {
+		return "", fmt.Errorf("empty thumbnail")
+	}
+	os.WriteFile(colorPath, []byte(hex), 0644)
+	return hex, nil
+}
+
+// averageColor returns the average color of an image as a hex string, or
+// false for an empty image
+func averageColor(img image.Image) (string, bool) {
+	bounds := img.Bounds()
+	var r, g, b, count uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pr, pg, pb, _ := img.At(x, y).RGBA()
+			r += uint64(pr >> 8)
+			g += uint64(pg >> 8)
+			b += uint64(pb >> 8)
+			count++
+		}
+	}
+	if count == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r/count, g/count, b/count), true
+}
+
 // DeleteArtwork removes cached artwork for an album
 func (p *ArtworkProcessor) DeleteArtwork(albumID string) error {
-	path := filepath.Join(p.cacheDir, "artwork", albumID)
-	return os.RemoveAll(path)
+	return os.RemoveAll(ArtworkCacheDir(p.cacheDir, ArtworkKindAlbum, albumID))
 }
 
 // getMIMETypeFromFilename returns MIME type based on file extension
@@ -339,7 +603,7 @@ func (p *ArtworkProcessor) SaveArtworkFromReader(albumID string, r io.Reader, mi
 
 // SaveRawArtwork saves raw artwork data without processing
 func (p *ArtworkProcessor) SaveRawArtwork(albumID string, data []byte, filename string) error {
-	albumCacheDir := filepath.Join(p.cacheDir, "artwork", albumID)
+	albumCacheDir := ArtworkCacheDir(p.cacheDir, ArtworkKindAlbum, albumID)
 	if err := os.MkdirAll(albumCacheDir, 0755); err != nil {
 		return fmt.Errorf("creating cache directory: %w", err)
 	}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns the start of a PNG claiming the given dimensions, with
+// no pixel data, so only a header read can succeed on it
+func pngHeader(width, height uint32) []byte {
+	var ihdr bytes.Buffer
+	ihdr.WriteString("IHDR")
+	binary.Write(&ihdr, binary.BigEndian, width)
+	binary.Write(&ihdr, binary.BigEndian, height)
+	ihdr.Write([]byte{8, 6, 0, 0, 0}) // 8-bit RGBA, no interlacing
+
+	var data bytes.Buffer
+	data.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&data, binary.BigEndian, uint32(ihdr.Len()-4))
+	data.Write(ihdr.Bytes())
+	binary.Write(&data, binary.BigEndian, crc32.ChecksumIEEE(ihdr.Bytes()))
+	return data.Bytes()
+}
+
+func TestCheckDimensions(t *testing.T) {
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		wantErr   error
+	}{
+		{"within the limits", small.Bytes(), 0, nil},
+		{"too wide", pngHeader(9000, 10), 0, ErrArtworkTooLarge},
+		{"too many pixels", pngHeader(5000, 5000), 0, ErrArtworkTooLarge},
+		{"configured pixel limit", small.Bytes(), 32 * 32, ErrArtworkTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewArtworkProcessor(t.TempDir())
+			p.SetMaxPixels(tt.maxPixels)
+			err := p.checkDimensions(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkDimensions = %v, want %v", err, tt.wantErr)
+			}
+
+			// Refused images never reach the decoder
+			if tt.wantErr != nil {
+				_, err := p.ProcessAndCache(&ArtworkInfo{Data: tt.data}, "al1")
+				if !errors.Is(err, ErrArtworkTooLarge) {
+					t.Errorf("ProcessAndCache = %v, want %v", err, ErrArtworkTooLarge)
+				}
+			}
+		})
+	}
+}
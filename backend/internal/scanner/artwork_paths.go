@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"path/filepath"
+)
+
+// ArtworkKind names the kind of entity cached artwork belongs to
+type ArtworkKind string
+
+const (
+	ArtworkKindAlbum    ArtworkKind = "album"
+	ArtworkKindArtist   ArtworkKind = "artist"
+	ArtworkKindPlaylist ArtworkKind = "playlist"
+	ArtworkKindTrack    ArtworkKind = "track"
+)
+
+// artworkKindDirs maps each kind to its directory under the cache root. The
+// album directory predates the others, hence the odd one out.
+var artworkKindDirs = map[ArtworkKind]string{
+	ArtworkKindAlbum:    "artwork",
+	ArtworkKindArtist:   "artists",
+	ArtworkKindPlaylist: "playlists",
+	ArtworkKindTrack:    "tracks",
+}
+
+// ParseArtworkKind validates an artwork kind, as used in artwork URLs
+func ParseArtworkKind(s string) (ArtworkKind, bool) {
+	kind := ArtworkKind(s)
+	_, ok := artworkKindDirs[kind]
+	return kind, ok
+}
+
+// ArtworkCacheDir returns the directory holding an entity's cached artwork:
+// cacheDir/<kind dir>/<id>
+func ArtworkCacheDir(cacheDir string, kind ArtworkKind, id string) string {
+	return filepath.Join(cacheDir, artworkKindDirs[kind], id)
+}
+
+// ArtworkCachePath returns the cached image of an entity at a size name
+// ("original" or one of AllArtworkSizes)
+func ArtworkCachePath(cacheDir string, kind ArtworkKind, id, size string) string {
+	return filepath.Join(ArtworkCacheDir(cacheDir, kind, id), size+".jpg")
+}
@@ -0,0 +1,201 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Limits applied when fetching remote images
+const (
+	DefaultRemoteArtworkTimeout  = 10 * time.Second
+	DefaultRemoteArtworkMaxBytes = 10 << 20
+	// remoteArtworkRetryAfter is how long a URL that failed isn't fetched again
+	remoteArtworkRetryAfter = 15 * time.Minute
+)
+
+var (
+	ErrInvalidArtworkURL           = errors.New("invalid artwork URL")
+	ErrRemoteArtworkFetch          = errors.New("fetching remote artwork failed")
+	ErrRemoteArtworkAddress        = errors.New("remote artwork host resolves to a private address")
+	ErrRemoteArtworkRecentlyFailed = errors.New("remote artwork failed recently")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice
+// though netip doesn't count it as such
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// RemoteArtworkFetcher downloads images from remote URLs with size and time
+// limits. Hosts on loopback, private or link-local addresses are refused,
+// whatever name or redirect leads to them, and a URL that failed isn't
+// fetched again for a while.
+type RemoteArtworkFetcher struct {
+	client   *http.Client
+	maxBytes int64
+
+	mu           sync.Mutex
+	allowPrivate bool
+	failures     map[string]time.Time // URL -> when it last failed
+}
+
+// NewRemoteArtworkFetcher creates a fetcher with the given limits
+func NewRemoteArtworkFetcher(timeout time.Duration, maxBytes int64) *RemoteArtworkFetcher {
+	if timeout <= 0 {
+		timeout = DefaultRemoteArtworkTimeout
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultRemoteArtworkMaxBytes
+	}
+
+	f := &RemoteArtworkFetcher{
+		maxBytes: maxBytes,
+		failures: make(map[string]time.Time),
+	}
+	// The address is checked as the connection is made, after resolution,
+	// so DNS answers and redirects can't lead around it. No proxy is used,
+	// since only the proxy's address would be checked.
+	dialer := &net.Dialer{Timeout: timeout, Control: f.checkAddress}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+	return f
+}
+
+// SetAllowPrivateHosts sets whether hosts on private, loopback and
+// link-local addresses may be fetched from
+func (f *RemoteArtworkFetcher) SetAllowPrivateHosts(allow bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allowPrivate = allow
+}
+
+// checkAddress refuses connections to addresses that aren't public
+func (f *RemoteArtworkFetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	f.mu.Lock()
+	allow := f.allowPrivate
+	f.mu.Unlock()
+	if allow {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrRemoteArtworkAddress, addr)
+	}
+	return nil
+}
+
+// isPublicAddress reports whether addr is a routable unicast address outside
+// the private, loopback and link-local ranges
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// IsRemoteURL reports whether s is an http(s) URL
+func IsRemoteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Fetch downloads an image, rejecting non-image responses and bodies over the
+// size limit. Failures are remembered, so fetching the URL again fails
+// straight away until remoteArtworkRetryAfter has passed.
+func (f *RemoteArtworkFetcher) Fetch(ctx context.Context, rawURL string) (*ArtworkInfo, error) {
+	if !IsRemoteURL(rawURL) {
+		return nil, ErrInvalidArtworkURL
+	}
+	if f.failedRecently(rawURL) {
+		return nil, ErrRemoteArtworkRecentlyFailed
+	}
+
+	artwork, err := f.fetch(ctx, rawURL)
+	// A request cut short by its client says nothing about the URL
+	if err != nil && ctx.Err() == nil {
+		f.recordFailure(rawURL)
+	}
+	return artwork, err
+}
+
+func (f *RemoteArtworkFetcher) fetch(ctx context.Context, rawURL string) (*ArtworkInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArtworkURL, err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRemoteArtworkFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrRemoteArtworkFetch, resp.StatusCode)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("%w: unexpected content type %q", ErrRemoteArtworkFetch, mimeType)
+	}
+	if resp.ContentLength > f.maxBytes {
+		return nil, fmt.Errorf("%w: image too large (%d bytes)", ErrRemoteArtworkFetch, resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteArtworkFetch, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("%w: image exceeds %d bytes", ErrRemoteArtworkFetch, f.maxBytes)
+	}
+
+	return &ArtworkInfo{
+		Data:     data,
+		MIMEType: mimeType,
+		Source:   "remote",
+		Path:     rawURL,
+	}, nil
+}
+
+// failedRecently reports whether fetching rawURL failed within
+// remoteArtworkRetryAfter
+func (f *RemoteArtworkFetcher) failedRecently(rawURL string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failedAt, ok := f.failures[rawURL]
+	return ok && time.Since(failedAt) < remoteArtworkRetryAfter
+}
+
+// recordFailure remembers that fetching rawURL failed, dropping failures old
+// enough to be retried
+func (f *RemoteArtworkFetcher) recordFailure(rawURL string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for failedURL, failedAt := range f.failures {
+		if time.Since(failedAt) >= remoteArtworkRetryAfter {
+			delete(f.failures, failedURL)
+		}
+	}
+	f.failures[rawURL] = time.Now()
+}
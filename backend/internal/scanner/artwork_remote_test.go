@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteArtworkFetch(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		allowPrivate bool
+		wantErr      error
+		wantHits     int32
+	}{
+		{"loopback refused", server.URL + "/image.png", false, ErrRemoteArtworkAddress, 0},
+		{"loopback allowed", server.URL + "/image.png", true, nil, 1},
+		{"not a URL", "file:///etc/passwd", true, ErrInvalidArtworkURL, 0},
+		{"not an image", server.URL + "/page", true, ErrRemoteArtworkFetch, 1},
+		{"missing", server.URL + "/missing.png", true, ErrRemoteArtworkFetch, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			fetcher := NewRemoteArtworkFetcher(0, 0)
+			fetcher.SetAllowPrivateHosts(tt.allowPrivate)
+
+			artwork, err := fetcher.Fetch(context.Background(), tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fetch error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(artwork.Data) != "png" {
+				t.Errorf("fetched %q, want the image", artwork.Data)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server hit %d times, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRemoteArtworkFailureIsRemembered(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	fetcher := NewRemoteArtworkFetcher(0, 0)
+	fetcher.SetAllowPrivateHosts(true)
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/a.png"); !errors.Is(err, ErrRemoteArtworkFetch) {
+		t.Fatalf("first Fetch error = %v, want %v", err, ErrRemoteArtworkFetch)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/a.png"); !errors.Is(err, ErrRemoteArtworkRecentlyFailed) {
+		t.Fatalf("second Fetch error = %v, want %v", err, ErrRemoteArtworkRecentlyFailed)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server hit %d times, want 1", got)
+	}
+
+	// Other URLs are unaffected
+	fetcher.Fetch(context.Background(), server.URL+"/b.png")
+	if got := hits.Load(); got != 2 {
+		t.Errorf("server hit %d times, want 2", got)
+	}
+
+	// A request its client gave up on isn't held against the URL
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetcher.Fetch(ctx, server.URL+"/c.png")
+	if fetcher.failedRecently(server.URL + "/c.png") {
+		t.Errorf("canceled fetch recorded as a failure")
+	}
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtworkDataURI(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	p := NewArtworkProcessor(t.TempDir())
+	if _, err := p.ProcessAndCache(&ArtworkInfo{Data: pngData.Bytes()}, "al1"); err != nil {
+		t.Fatalf("ProcessAndCache: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte // written over the cached thumbnail when not nil
+		want    string
+		wantErr bool
+	}{
+		{"processed artwork", nil, "data:image/jpeg;base64,", false},
+		{"artwork saved as is", pngData.Bytes(), "data:image/png;base64,", false},
+		{"not an image", []byte("<html></html>"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.data != nil {
+				name := filepath.Base(p.GetArtworkPath("al1", ArtworkSizeThumbnail.Name))
+				if err := p.SaveRawArtwork("al1", tt.data, name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			uri, err := p.ArtworkDataURI("al1", ArtworkSizeThumbnail.Name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ArtworkDataURI error = %v, want error %v", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(uri, tt.want) {
+				t.Errorf("ArtworkDataURI = %.40q, want prefix %q", uri, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dhowden/tag"
+)
+
+// maxChapters bounds how many chapter markers are kept for one file
+const maxChapters = 1000
+
+// Chapter is a named section of a long-form track such as an audiobook or DJ
+// mix. End is 0 when the chapter runs to the end of the file.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// chaptersFromTags reads chapter markers from ID3v2 CHAP frames or from
+// Vorbis CHAPTERxxx comments, sorted by start time. Chapters without a
+// title are numbered.
+func chaptersFromTags(format tag.Format, raw map[string]interface{}) []Chapter {
+	var chapters []Chapter
+	switch format {
+	case tag.ID3v2_3, tag.ID3v2_4:
+		chapters = id3Chapters(format, raw)
+	case tag.VORBIS:
+		chapters = vorbisChapters(raw)
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	if len(chapters) > maxChapters {
+		chapters = chapters[:maxChapters]
+	}
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = fmt.Sprintf("Chapter %d", i+1)
+		}
+	}
+	return chapters
+}
+
+// id3Chapters parses the CHAP frames, which the tag reader leaves as raw
+// bytes under "CHAP", "CHAP_0", "CHAP_1", ...
+func id3Chapters(format tag.Format, raw map[string]interface{}) []Chapter {
+	var chapters []Chapter
+	for name, value := range raw {
+		if name != "CHAP" && !strings.HasPrefix(name, "CHAP_") {
+			continue
+		}
+		data, ok := value.([]byte)
+		if !ok {
+			continue
+		}
+		if chapter, err := parseCHAPFrame(data, format == tag.ID3v2_4); err == nil {
+			chapters = append(chapters, chapter)
+		}
+	}
+	return chapters
+}
+
+// parseCHAPFrame decodes a CHAP frame body: a null-terminated element ID,
+// start and end times in milliseconds, byte offsets (unused here) and
+// embedded sub-frames, of which TIT2 carries the title. Sub-frame sizes are
+// synchsafe integers in ID3v2.4.
+func parseCHAPFrame(data []byte, synchsafe bool) (Chapter, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 || len(data) < end+1+16 {
+		return Chapter{}, fmt.Errorf("chapter frame too short")
+	}
+	data = data[end+1:]
+
+	startMs := binary.BigEndian.Uint32(data[0:4])
+	endMs := binary.BigEndian.Uint32(data[4:8])
+	if endMs < startMs {
+		endMs = 0
+	}
+	chapter := Chapter{
+		Start: time.Duration(startMs) * time.Millisecond,
+		End:   time.Duration(endMs) * time.Millisecond,
+	}
+
+	frames := data[16:]
+	for len(frames) >= 10 {
+		id := string(frames[0:4])
+		size := int(binary.BigEndian.Uint32(frames[4:8]))
+		if synchsafe {
+			size = int(frames[4])<<21 | int(frames[5])<<14 | int(frames[6])<<7 | int(frames[7])
+		}
+		if id[0] == 0 || size <= 0 || 10+size > len(frames) {
+			break
+		}
+		if id == "TIT2" {
+			chapter.Title = decodeID3Text(frames[10 : 10+size])
+			break
+		}
+		frames = frames[10+size:]
+	}
+
+	return chapter, nil
+}
+
+// decodeID3Text decodes a text frame body, whose first byte names the
+// encoding: ISO-8859-1, UTF-16 with BOM, UTF-16BE or UTF-8
+func decodeID3Text(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	encoding, text := b[0], b[1:]
+	switch encoding {
+	case 0:
+		runes := make([]rune, len(text))
+		for i, c := range text {
+			runes[i] = rune(c)
+		}
+		return strings.TrimSpace(strings.TrimRight(string(runes), "\x00"))
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xFF && text[1] == 0xFE:
+				bigEndian, text = false, text[2:]
+			case text[0] == 0xFE && text[1] == 0xFF:
+				bigEndian, text = true, text[2:]
+			}
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(text[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(text[i:]))
+			}
+		}
+		return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+	default:
+		return strings.TrimSpace(strings.TrimRight(string(text), "\x00"))
+	}
+}
+
+// vorbisChapters reads CHAPTERxxx=HH:MM:SS.mmm and CHAPTERxxxNAME=title
+// comments. Each chapter ends where the next one starts.
+func vorbisChapters(raw map[string]interface{}) []Chapter {
+	var chapters []Chapter
+	for name, value := range raw {
+		number := strings.TrimPrefix(name, "chapter")
+		if number == name || number == "" || strings.HasSuffix(number, "name") {
+			continue
+		}
+		if _, err := strconv.Atoi(number); err != nil {
+			continue
+		}
+		timestamp, _ := value.(string)
+		start, err := parseChapterTime(timestamp)
+		if err != nil {
+			continue
+		}
+		title, _ := raw[name+"name"].(string)
+		chapters = append(chapters, Chapter{Title: strings.TrimSpace(title), Start: start})
+	}
+
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	for i := 0; i+1 < len(chapters); i++ {
+		chapters[i].End = chapters[i+1].Start
+	}
+	return chapters
+}
+
+// parseChapterTime parses an HH:MM:SS(.mmm) timestamp
+func parseChapterTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes >= 60 {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || seconds < 0 || seconds >= 60 {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), nil
+}
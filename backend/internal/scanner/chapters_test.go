@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+// chapFrame builds an ID3v2.3 CHAP frame, with a TIT2 sub-frame holding
+// title (an encoding byte followed by text) unless it's nil
+func chapFrame(element string, start, end uint32, title []byte) []byte {
+	body := append([]byte(element), 0)
+	body = binary.BigEndian.AppendUint32(body, start)
+	body = binary.BigEndian.AppendUint32(body, end)
+	body = append(body, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	if title != nil {
+		body = append(body, id3Frame("TIT2", title)...)
+	}
+	return id3Frame("CHAP", body)
+}
+
+func TestExtractChapters(t *testing.T) {
+	latin1 := func(s string) []byte { return append([]byte{0}, s...) }
+	// "Café" as UTF-16 with a little-endian BOM
+	utf16 := []byte{1, 0xFF, 0xFE, 'C', 0, 'a', 0, 'f', 0, 0xE9, 0}
+
+	tests := []struct {
+		name     string
+		frames   [][]byte
+		chapters []Chapter
+	}{
+		{"none", nil, nil},
+		{"sorted by start", [][]byte{
+			chapFrame("ch2", 60000, 125500, latin1("Second")),
+			chapFrame("ch1", 0, 60000, latin1("First")),
+		}, []Chapter{
+			{"First", 0, time.Minute},
+			{"Second", time.Minute, 125500 * time.Millisecond},
+		}},
+		{"untitled", [][]byte{
+			chapFrame("ch1", 0, 1000, nil),
+			chapFrame("ch2", 1000, 2000, latin1("Named")),
+		}, []Chapter{
+			{"Chapter 1", 0, time.Second},
+			{"Named", time.Second, 2 * time.Second},
+		}},
+		{"UTF-16 title", [][]byte{chapFrame("ch1", 0, 1000, utf16)}, []Chapter{{"Café", 0, time.Second}}},
+		{"end before start", [][]byte{chapFrame("ch1", 5000, 1000, latin1("Open"))}, []Chapter{{"Open", 5 * time.Second, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "book.mp3")
+			// Taggers leave padding after the frames; the tag reader drops
+			// frames it doesn't know, like CHAP, that end a tag without any
+			frames := append([][]byte{id3Frame("TIT2", latin1("Book"))}, tt.frames...)
+			frames = append(frames, make([]byte, 64))
+			if err := os.WriteFile(path, id3File(frames...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			meta, err := NewMetadataExtractor().Extract(path)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if !slices.Equal(meta.Chapters, tt.chapters) {
+				t.Errorf("chapters = %+v, want %+v", meta.Chapters, tt.chapters)
+			}
+		})
+	}
+}
+
+func TestVorbisChapters(t *testing.T) {
+	raw := map[string]interface{}{
+		"title":          "Mix",
+		"chapter001":     "00:00:00.000",
+		"chapter001name": "Intro",
+		"chapter003":     "01:02:03",
+		"chapter002":     "00:01:30.500",
+		"chapter002name": " Main ",
+		"chapter004":     "not a time",
+		"chapter004name": "Broken",
+	}
+	want := []Chapter{
+		{"Intro", 0, 90500 * time.Millisecond},
+		{"Main", 90500 * time.Millisecond, time.Hour + 2*time.Minute + 3*time.Second},
+		{"Chapter 3", time.Hour + 2*time.Minute + 3*time.Second, 0},
+	}
+	if got := chaptersFromTags(tag.VORBIS, raw); !slices.Equal(got, want) {
+		t.Errorf("chapters = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseChapterTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"00:00:00", 0, false},
+		{"00:01:30.5", 90500 * time.Millisecond, false},
+		{"12:59:59.999", 12*time.Hour + 59*time.Minute + 59999*time.Millisecond, false},
+		{" 01:00:00 ", time.Hour, false},
+		{"01:60:00", 0, true},
+		{"01:00:60", 0, true},
+		{"-1:00:00", 0, true},
+		{"01:00", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseChapterTime(tt.value)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("parseChapterTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
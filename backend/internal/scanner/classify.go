@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"regexp"
+	"strings"
+
+	"harmony/internal/models"
+)
+
+// Duration limits (seconds) used when inferring release types: singles and
+// EPs run under half an hour, and a short release with a track of ten
+// minutes or more is an EP rather than a single
+const (
+	maxEPDuration          = 30 * 60
+	maxSingleTrackDuration = 10 * 60
+)
+
+var epTitlePattern = regexp.MustCompile(`(?i)(\s|-|\(|\[)EP(\)|\])?$`)
+
+// AlbumTrackSummary holds the per-album figures used for classification
+type AlbumTrackSummary struct {
+	Title        string
+	ArtistName   string
+	TrackCount   int
+	Duration     int
+	LongestTrack int
+	ArtistCount  int
+}
+
+// ClassifyOptions adjusts how release types are inferred
+type ClassifyOptions struct {
+	// SingleTrackSingles classifies every album of exactly one track as a
+	// single, however long the track is
+	SingleTrackSingles bool
+}
+
+// ClassifyAlbum infers the release type of an album from its tracks
+func ClassifyAlbum(summary AlbumTrackSummary, opts ClassifyOptions) string {
+	if IsVariousArtists(summary.ArtistName) || summary.ArtistCount >= 3 {
+		return models.AlbumTypeCompilation
+	}
+
+	if epTitlePattern.MatchString(strings.TrimSpace(summary.Title)) {
+		return models.AlbumTypeEP
+	}
+
+	if summary.TrackCount == 1 && opts.SingleTrackSingles {
+		return models.AlbumTypeSingle
+	}
+
+	if summary.TrackCount <= 3 && summary.Duration < maxEPDuration && summary.LongestTrack < maxSingleTrackDuration {
+		return models.AlbumTypeSingle
+	}
+
+	if summary.TrackCount <= 6 && summary.Duration < maxEPDuration {
+		return models.AlbumTypeEP
+	}
+
+	return models.AlbumTypeAlbum
+}
+
+// IsVariousArtists checks if an album artist name denotes a compilation
+func IsVariousArtists(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "various artists", "various", "va", "v.a.":
+		return true
+	}
+	return false
+}
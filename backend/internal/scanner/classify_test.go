@@ -0,0 +1,32 @@
+package scanner
+
+import (
+	"testing"
+
+	"harmony/internal/models"
+)
+
+func TestClassifyAlbum(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary AlbumTrackSummary
+		opts    ClassifyOptions
+		want    string
+	}{
+		{"single", AlbumTrackSummary{Title: "Hit", TrackCount: 2, Duration: 420, LongestTrack: 240}, ClassifyOptions{}, models.AlbumTypeSingle},
+		{"short release with a long track", AlbumTrackSummary{Title: "Suite", TrackCount: 2, Duration: 900, LongestTrack: 720}, ClassifyOptions{}, models.AlbumTypeEP},
+		{"long track as a single", AlbumTrackSummary{Title: "Suite", TrackCount: 1, Duration: 720, LongestTrack: 720}, ClassifyOptions{SingleTrackSingles: true}, models.AlbumTypeSingle},
+		{"ep by track count", AlbumTrackSummary{Title: "Four", TrackCount: 5, Duration: 1200, LongestTrack: 300}, ClassifyOptions{}, models.AlbumTypeEP},
+		{"ep by title", AlbumTrackSummary{Title: "Demos EP", TrackCount: 9, Duration: 2400, LongestTrack: 300}, ClassifyOptions{}, models.AlbumTypeEP},
+		{"short release over half an hour", AlbumTrackSummary{Title: "Epic", TrackCount: 3, Duration: 1900, LongestTrack: 900}, ClassifyOptions{}, models.AlbumTypeAlbum},
+		{"album", AlbumTrackSummary{Title: "Full", TrackCount: 10, Duration: 2400, LongestTrack: 300}, ClassifyOptions{}, models.AlbumTypeAlbum},
+		{"compilation", AlbumTrackSummary{Title: "Hits", ArtistName: "Various Artists", TrackCount: 2, Duration: 400, LongestTrack: 200}, ClassifyOptions{}, models.AlbumTypeCompilation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyAlbum(tt.summary, tt.opts); got != tt.want {
+				t.Errorf("ClassifyAlbum = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package scanner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCueSheet is returned for cue sheets that can't be parsed
+var ErrInvalidCueSheet = errors.New("invalid cue sheet")
+
+// cueFramesPerSecond is the CD frame rate used by INDEX timestamps
+const cueFramesPerSecond = 75
+
+// CueSheet describes how a single audio file is split into tracks
+type CueSheet struct {
+	Performer string
+	Title     string
+	Genre     string
+	Year      int
+	Tracks    []CueTrack
+}
+
+// CueTrack is one track within a cue sheet
+type CueTrack struct {
+	Number    int
+	Title     string
+	Performer string
+	File      string
+	// Start is the INDEX 01 position within File
+	Start time.Duration
+	// End is where the next track in the same file starts; 0 means end of file
+	End time.Duration
+}
+
+// ParseCueSheet parses a cue sheet. End offsets are filled in from the start
+// of the following track in the same file.
+func ParseCueSheet(r io.Reader) (*CueSheet, error) {
+	sheet := &CueSheet{}
+	var current *CueTrack
+	file := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		command, args, _ := strings.Cut(line, " ")
+		args = strings.TrimSpace(args)
+
+		switch strings.ToUpper(command) {
+		case "REM":
+			field, value, _ := strings.Cut(args, " ")
+			switch strings.ToUpper(field) {
+			case "GENRE":
+				sheet.Genre = unquoteCue(value)
+			case "DATE":
+				sheet.Year = extractYearFromString(value)
+			}
+		case "PERFORMER":
+			if current != nil {
+				current.Performer = unquoteCue(args)
+			} else {
+				sheet.Performer = unquoteCue(args)
+			}
+		case "TITLE":
+			if current != nil {
+				current.Title = unquoteCue(args)
+			} else {
+				sheet.Title = unquoteCue(args)
+			}
+		case "FILE":
+			file = unquoteCue(cueFileName(args))
+			current = nil
+		case "TRACK":
+			fields := strings.Fields(args)
+			if len(fields) < 1 {
+				return nil, fmt.Errorf("%w: line %d: missing track number", ErrInvalidCueSheet, lineNum)
+			}
+			number, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad track number %q", ErrInvalidCueSheet, lineNum, fields[0])
+			}
+			if file == "" {
+				return nil, fmt.Errorf("%w: line %d: TRACK before FILE", ErrInvalidCueSheet, lineNum)
+			}
+			sheet.Tracks = append(sheet.Tracks, CueTrack{Number: number, File: file, Start: -1})
+			current = &sheet.Tracks[len(sheet.Tracks)-1]
+		case "INDEX":
+			fields := strings.Fields(args)
+			if current == nil || len(fields) != 2 || fields[0] != "01" {
+				continue
+			}
+			start, err := parseCueTime(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCueSheet, lineNum, err)
+			}
+			current.Start = start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading cue sheet: %w", err)
+	}
+
+	for i := range sheet.Tracks {
+		if sheet.Tracks[i].Start < 0 {
+			return nil, fmt.Errorf("%w: track %d has no INDEX 01", ErrInvalidCueSheet, sheet.Tracks[i].Number)
+		}
+		if i > 0 && sheet.Tracks[i-1].File == sheet.Tracks[i].File {
+			sheet.Tracks[i-1].End = sheet.Tracks[i].Start
+		}
+	}
+
+	return sheet, nil
+}
+
+// TracksForFile returns the tracks stored in the given audio file. Cue sheets
+// often name the original rip (album.wav) after it has been converted, so a
+// file with the same base name but another extension also matches.
+func (s *CueSheet) TracksForFile(audioPath string) []CueTrack {
+	name := filepath.Base(audioPath)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	var tracks []CueTrack
+	for _, t := range s.Tracks {
+		cueName := filepath.Base(filepath.ToSlash(t.File))
+		if strings.EqualFold(cueName, name) ||
+			strings.EqualFold(strings.TrimSuffix(cueName, filepath.Ext(cueName)), stem) {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// FindCueSheet looks for a cue sheet describing audioPath: first "album.cue"
+// or "album.flac.cue" beside it, then any cue sheet in the directory that
+// references the file. Returns nil when there is none; unreadable sheets are
+// logged and skipped.
+func FindCueSheet(audioPath string) *CueSheet {
+	return loadCueDir(filepath.Dir(audioPath)).find(audioPath)
+}
+
+// CueSheetCache remembers the cue sheets of each directory, so a scan reads
+// them once per album folder rather than once per file. It's safe for
+// concurrent use; a nil cache reads them every time.
+type CueSheetCache struct {
+	mu   sync.Mutex
+	dirs map[string]*cueDir
+}
+
+// NewCueSheetCache creates an empty CueSheetCache
+func NewCueSheetCache() *CueSheetCache {
+	return &CueSheetCache{dirs: make(map[string]*cueDir)}
+}
+
+// Find is FindCueSheet reading each directory's cue sheets once
+func (c *CueSheetCache) Find(audioPath string) *CueSheet {
+	if c == nil {
+		return FindCueSheet(audioPath)
+	}
+
+	dir := filepath.Dir(audioPath)
+	c.mu.Lock()
+	entry, ok := c.dirs[dir]
+	if !ok {
+		entry = &cueDir{}
+		c.dirs[dir] = entry
+	}
+	c.mu.Unlock()
+
+	// Files of the same folder wait for the first to read its sheets
+	entry.once.Do(func() {
+		loaded := loadCueDir(dir)
+		entry.paths, entry.sheets = loaded.paths, loaded.sheets
+	})
+	return entry.find(audioPath)
+}
+
+// cueDir is the parsed cue sheets of a directory, by path
+type cueDir struct {
+	once   sync.Once
+	paths  []string
+	sheets map[string]*CueSheet
+}
+
+// loadCueDir reads every cue sheet in dir
+func loadCueDir(dir string) *cueDir {
+	entry := &cueDir{sheets: make(map[string]*CueSheet)}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.cue"))
+	if err != nil {
+		return entry
+	}
+	for _, path := range paths {
+		sheet, err := readCueSheet(path)
+		if err != nil {
+			slog.Warn("skipping unreadable cue sheet", "path", path, "error", err)
+			continue
+		}
+		entry.paths = append(entry.paths, path)
+		entry.sheets[path] = sheet
+	}
+	return entry
+}
+
+// find returns the sheet describing audioPath, preferring those named after it
+func (d *cueDir) find(audioPath string) *CueSheet {
+	dir := filepath.Dir(audioPath)
+	name := filepath.Base(audioPath)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	candidates := append([]string{
+		filepath.Join(dir, stem+".cue"),
+		filepath.Join(dir, name+".cue"),
+	}, d.paths...)
+	for _, path := range candidates {
+		if sheet := d.sheets[path]; sheet != nil && len(sheet.TracksForFile(audioPath)) > 0 {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// readCueSheet opens and parses a cue sheet file
+func readCueSheet(path string) (*CueSheet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheet, err := ParseCueSheet(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sheet, nil
+}
+
+// parseCueTime parses an mm:ss:ff timestamp, where ff is in 1/75ths of a second
+func parseCueTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+
+	values := make([]int, 3)
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("bad timestamp %q", s)
+		}
+		values[i] = v
+	}
+	if values[1] >= 60 || values[2] >= cueFramesPerSecond {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+
+	frames := (values[0]*60+values[1])*cueFramesPerSecond + values[2]
+	return time.Duration(frames) * time.Second / cueFramesPerSecond, nil
+}
+
+// cueFileName strips the trailing file type (WAVE, MP3, ...) from a FILE line
+func cueFileName(args string) string {
+	if strings.HasPrefix(args, `"`) {
+		if end := strings.Index(args[1:], `"`); end >= 0 {
+			return args[:end+2]
+		}
+		return args
+	}
+	if i := strings.LastIndex(args, " "); i > 0 {
+		return args[:i]
+	}
+	return args
+}
+
+// unquoteCue removes surrounding double quotes from a cue value
+func unquoteCue(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
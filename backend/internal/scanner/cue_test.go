@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCueSheet = `REM GENRE Rock
+REM DATE 1999
+PERFORMER "Band"
+TITLE "Live"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Song"
+    PERFORMER "Guest"
+    INDEX 00 03:58:00
+    INDEX 01 04:00:37
+  TRACK 03 AUDIO
+    TITLE "Outro"
+    INDEX 01 09:30:00
+`
+
+func TestParseCueSheet(t *testing.T) {
+	sheet, err := ParseCueSheet(strings.NewReader(testCueSheet))
+	if err != nil {
+		t.Fatalf("ParseCueSheet: %v", err)
+	}
+	if sheet.Performer != "Band" || sheet.Title != "Live" || sheet.Genre != "Rock" || sheet.Year != 1999 {
+		t.Errorf("sheet = %+v", sheet)
+	}
+
+	tests := []struct {
+		title     string
+		performer string
+		start     time.Duration
+		end       time.Duration
+	}{
+		{"Intro", "", 0, 4*time.Minute + 37*time.Second/75},
+		{"Song", "Guest", 4*time.Minute + 37*time.Second/75, 9*time.Minute + 30*time.Second},
+		{"Outro", "", 9*time.Minute + 30*time.Second, 0},
+	}
+	if len(sheet.Tracks) != len(tests) {
+		t.Fatalf("%d tracks, want %d", len(sheet.Tracks), len(tests))
+	}
+	for i, tt := range tests {
+		track := sheet.Tracks[i]
+		if track.Title != tt.title || track.Performer != tt.performer || track.Start != tt.start || track.End != tt.end {
+			t.Errorf("track %d = %+v, want %s by %q from %s to %s", i+1, track, tt.title, tt.performer, tt.start, tt.end)
+		}
+	}
+
+	for _, invalid := range []string{
+		"TRACK 01 AUDIO\n  INDEX 01 00:00:00\n",
+		"FILE \"a.wav\" WAVE\n  TRACK 01 AUDIO\n",
+		"FILE \"a.wav\" WAVE\n  TRACK xx AUDIO\n",
+	} {
+		if _, err := ParseCueSheet(strings.NewReader(invalid)); err == nil {
+			t.Errorf("ParseCueSheet(%q) accepted", invalid)
+		}
+	}
+}
+
+func TestFindCueSheet(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	audio := write("album.flac", "audio")
+	other := write("other.flac", "audio")
+	write("notes.cue", strings.ReplaceAll(testCueSheet, `TITLE "Live"`, `TITLE "Notes"`))
+	write("album.cue", testCueSheet)
+	write("broken.cue", "TRACK 01 AUDIO\n")
+
+	tests := []struct {
+		name  string
+		find  func(string) *CueSheet
+		path  string
+		title string
+	}{
+		{"named after the file", FindCueSheet, audio, "Live"},
+		{"not referenced", FindCueSheet, other, ""},
+		{"cached", NewCueSheetCache().Find, audio, "Live"},
+		{"nil cache", (*CueSheetCache)(nil).Find, audio, "Live"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := tt.find(tt.path)
+			title := ""
+			if sheet != nil {
+				title = sheet.Title
+			}
+			if title != tt.title {
+				t.Errorf("found sheet %q, want %q", title, tt.title)
+			}
+		})
+	}
+
+	// The cache reads a folder's sheets once, for its first file
+	cache := NewCueSheetCache()
+	if cache.Find(audio) == nil {
+		t.Fatal("cached Find found nothing")
+	}
+	if err := os.Remove(filepath.Join(dir, "album.cue")); err != nil {
+		t.Fatal(err)
+	}
+	if sheet := cache.Find(audio); sheet == nil || sheet.Title != "Live" {
+		t.Errorf("cached Find read the folder again")
+	}
+	if sheet := FindCueSheet(audio); sheet == nil || sheet.Title != "Notes" {
+		t.Errorf("FindCueSheet = %v, want the remaining sheet referencing the file", sheet)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// fileIdentity identifies the file behind info by its path with symlinks
+// resolved
+func fileIdentity(info fs.FileInfo, canonical string) string {
+	return canonical
+}
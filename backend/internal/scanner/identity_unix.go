@@ -0,0 +1,18 @@
+//go:build unix
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// fileIdentity identifies the file behind info by its device and inode, so
+// hard links and paths through bind mounts match as well as symlinks
+func fileIdentity(info fs.FileInfo, canonical string) string {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+	}
+	return canonical
+}
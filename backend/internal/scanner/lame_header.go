@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	// maxFrameSearch bounds how far past the ID3v2 tag the first MP3 frame
+	// is looked for
+	maxFrameSearch = 64 << 10
+	// lameDelayOffset is where the 12-bit delay and padding fields start,
+	// counted from the encoder name that opens the LAME extension
+	lameDelayOffset = 21
+)
+
+// Xing/Info header flags marking which optional fields follow them
+const (
+	xingFrames  = 0x1
+	xingBytes   = 0x2
+	xingTOC     = 0x4
+	xingQuality = 0x8
+)
+
+// lameEncoders are the encoder names that open a LAME extension; ffmpeg
+// writes one too, named after its libraries
+var lameEncoders = [][]byte{[]byte("LAME"), []byte("L3.9"), []byte("Lavc"), []byte("Lavf")}
+
+// readLAMEGapless reads the encoder delay and padding, in samples, from the
+// LAME extension of the Xing/Info header in an MP3's first frame. ok is
+// false when the file has no such header.
+func readLAMEGapless(r io.ReadSeeker) (delay, padding int, ok bool) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, false
+	}
+	start, err := id3v2Size(r)
+	if err != nil {
+		return 0, 0, false
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, false
+	}
+
+	buf := make([]byte, maxFrameSearch)
+	n, _ := io.ReadFull(r, buf)
+	buf = buf[:n]
+
+	frame := findFrameSync(buf)
+	if frame < 0 {
+		return 0, 0, false
+	}
+	header := buf[frame:]
+	offset := 4 + sideInfoSize(header)
+	if header[1]&0x01 == 0 {
+		// A CRC follows the frame header
+		offset += 2
+	}
+	if len(header) < offset+8 {
+		return 0, 0, false
+	}
+	tag := header[offset:]
+	if !bytes.HasPrefix(tag, []byte("Xing")) && !bytes.HasPrefix(tag, []byte("Info")) {
+		return 0, 0, false
+	}
+
+	flags := binary.BigEndian.Uint32(tag[4:8])
+	pos := 8
+	if flags&xingFrames != 0 {
+		pos += 4
+	}
+	if flags&xingBytes != 0 {
+		pos += 4
+	}
+	if flags&xingTOC != 0 {
+		pos += 100
+	}
+	if flags&xingQuality != 0 {
+		pos += 4
+	}
+	if len(tag) < pos+lameDelayOffset+3 {
+		return 0, 0, false
+	}
+	lame := tag[pos:]
+	known := false
+	for _, encoder := range lameEncoders {
+		if bytes.HasPrefix(lame, encoder) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return 0, 0, false
+	}
+
+	b := lame[lameDelayOffset : lameDelayOffset+3]
+	delay = int(b[0])<<4 | int(b[1])>>4
+	padding = int(b[1]&0x0f)<<8 | int(b[2])
+	return delay, padding, true
+}
+
+// id3v2Size returns the length of the ID3v2 tag at the start of r, footer
+// included, or 0 when there is none
+func id3v2Size(r io.Reader) (int64, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(header, []byte("ID3")) {
+		return 0, nil
+	}
+	// The size is syncsafe: 7 bits per byte
+	size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 |
+		int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size, nil
+}
+
+// findFrameSync returns the offset of the first valid MPEG audio layer III
+// frame header in buf, or -1
+func findFrameSync(buf []byte) int {
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (buf[i+1] >> 3) & 0x03
+		layer := (buf[i+1] >> 1) & 0x03
+		bitrate := buf[i+2] >> 4
+		sampleRate := (buf[i+2] >> 2) & 0x03
+		if version == 1 || layer != 1 || bitrate == 0x0f || sampleRate == 0x03 {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// sideInfoSize is the length of the side information following a layer III
+// frame header, which the Xing/Info header comes after
+func sideInfoSize(header []byte) int {
+	mpeg1 := (header[1]>>3)&0x03 == 3
+	mono := header[3]>>6 == 3
+	switch {
+	case mpeg1 && mono:
+		return 17
+	case mpeg1:
+		return 32
+	case mono:
+		return 9
+	default:
+		return 17
+	}
+}
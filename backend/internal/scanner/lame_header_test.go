@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// lameFrame builds the first frame of an MP3 as LAME writes it: a layer III
+// frame header, empty side information and an Info header with all optional
+// fields, followed by a LAME extension from encoder with delay and padding.
+// header is the 4-byte frame header; a CRC is added when it asks for one.
+func lameFrame(header []byte, encoder string, delay, padding int) []byte {
+	frame := append([]byte(nil), header...)
+	if header[1]&0x01 == 0 {
+		frame = append(frame, 0, 0)
+	}
+	frame = append(frame, make([]byte, sideInfoSize(header))...)
+	frame = append(frame, "Info"...)
+	frame = append(frame, 0, 0, 0, xingFrames|xingBytes|xingTOC|xingQuality)
+	frame = append(frame, make([]byte, 4+4+100+4)...)
+
+	// Encoder name and version (9 bytes), revision, lowpass, ReplayGain
+	// (8 bytes), encoding flags and bitrate come before the delay
+	lame := append([]byte(encoder), make([]byte, lameDelayOffset-len(encoder))...)
+	lame = append(lame, byte(delay>>4), byte(delay<<4)|byte(padding>>8), byte(padding))
+	frame = append(frame, lame...)
+	return append(frame, make([]byte, 200)...)
+}
+
+// MPEG frame headers: MPEG-1 128kbps 44.1kHz joint stereo, the same with a
+// CRC, and MPEG-2 64kbps 22.05kHz mono
+var (
+	mpeg1Stereo = []byte{0xff, 0xfb, 0x90, 0x64}
+	mpeg1CRC    = []byte{0xff, 0xfa, 0x90, 0x64}
+	mpeg2Mono   = []byte{0xff, 0xf3, 0x80, 0xc4}
+)
+
+func TestReadLAMEGapless(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		delay   int
+		padding int
+		ok      bool
+	}{
+		{"LAME", lameFrame(mpeg1Stereo, "LAME3.100", 576, 1234), 576, 1234, true},
+		{"after ID3 tag", append(id3File(id3Frame("TIT2", []byte("\x00Song"))), lameFrame(mpeg1Stereo, "LAME3.100", 1105, 96)...), 1105, 96, true},
+		{"with CRC", lameFrame(mpeg1CRC, "LAME3.99r", 576, 4095), 576, 4095, true},
+		{"MPEG-2 mono", lameFrame(mpeg2Mono, "LAME3.100", 1152, 0), 1152, 0, true},
+		{"ffmpeg", lameFrame(mpeg1Stereo, "Lavc60.31", 1105, 777), 1105, 777, true},
+		{"unknown encoder", lameFrame(mpeg1Stereo, "FhG", 576, 1234), 0, 0, false},
+		{"no Info header", append(append([]byte(nil), mpeg1Stereo...), make([]byte, 400)...), 0, 0, false},
+		{"not audio", []byte("not really audio"), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, padding, ok := readLAMEGapless(bytes.NewReader(tt.data))
+			if ok != tt.ok || delay != tt.delay || padding != tt.padding {
+				t.Errorf("readLAMEGapless = %d, %d, %v; want %d, %d, %v",
+					delay, padding, ok, tt.delay, tt.padding, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtractLAMEGapless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "01 - Song.mp3")
+	data := append(id3File(id3Frame("TIT2", []byte("\x00Song"))), lameFrame(mpeg1Stereo, "LAME3.100", 576, 1234)...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := NewMetadataExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if meta.Gain.EncoderDelay != 576 || meta.Gain.EncoderPadding != 1234 {
+		t.Errorf("encoder delay and padding = %d and %d, want 576 and 1234",
+			meta.Gain.EncoderDelay, meta.Gain.EncoderPadding)
+	}
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLyricsSize bounds how much of a sidecar lyrics file is read
+const maxLyricsSize = 256 << 10
+
+// LyricLine is one timestamped line of synced lyrics
+type LyricLine struct {
+	Time time.Duration
+	Text string
+}
+
+// sidecarLyricsNames returns the paths a sidecar .lrc file of an audio file
+// may have
+func sidecarLyricsNames(audioPath string) []string {
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	return []string{base + ".lrc", base + ".LRC"}
+}
+
+// sidecarLyricsModTime returns when an audio file's sidecar .lrc file was
+// last modified, or the zero time when it has none
+func sidecarLyricsModTime(audioPath string) time.Time {
+	var modTime time.Time
+	for _, name := range sidecarLyricsNames(audioPath) {
+		if info, err := os.Stat(name); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
+
+// sidecarLyrics reads the .lrc file next to an audio file, sharing its base
+// name ("01 - Song.mp3" and "01 - Song.lrc"). It returns an empty string
+// when there is none.
+func sidecarLyrics(audioPath string) string {
+	for _, name := range sidecarLyricsNames(audioPath) {
+		file, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxLyricsSize))
+		file.Close()
+		if err != nil {
+			continue
+		}
+		if lyrics := strings.TrimSpace(strings.ToValidUTF8(string(data), "")); lyrics != "" {
+			return lyrics
+		}
+	}
+	return ""
+}
+
+var (
+	lrcTimestamp = regexp.MustCompile(`^\[(\d{1,3}):(\d{1,2}(?:[.:]\d{1,3})?)\]`)
+	lrcOffset    = regexp.MustCompile(`^\[offset:\s*([+-]?\d+)\s*\]$`)
+)
+
+// ParseLRC splits lyrics in LRC format into timestamped lines, sorted by
+// time, and the plain text of those lines in the same order. A line can
+// carry several timestamps when it repeats, and an [offset:ms] tag shifts
+// every timestamp. Lyrics without any timestamps return no lines and the
+// text unchanged.
+func ParseLRC(lyrics string) ([]LyricLine, string) {
+	var lines []LyricLine
+	var offset time.Duration
+
+	for _, raw := range strings.Split(strings.ReplaceAll(lyrics, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+
+		if m := lrcOffset.FindStringSubmatch(line); m != nil {
+			if ms, err := strconv.Atoi(m[1]); err == nil {
+				// A positive offset makes the lyrics appear sooner
+				offset = -time.Duration(ms) * time.Millisecond
+			}
+			continue
+		}
+
+		var times []time.Duration
+		for {
+			m := lrcTimestamp.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			minutes, _ := strconv.Atoi(m[1])
+			seconds, err := strconv.ParseFloat(strings.Replace(m[2], ":", ".", 1), 64)
+			if err != nil {
+				break
+			}
+			times = append(times, time.Duration(minutes)*time.Minute+time.Duration(seconds*float64(time.Second)))
+			line = strings.TrimSpace(line[len(m[0]):])
+		}
+
+		// Untimed lines are metadata tags such as [ar:...] or [ti:...]
+		for _, t := range times {
+			lines = append(lines, LyricLine{Time: t, Text: line})
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, lyrics
+	}
+
+	for i := range lines {
+		lines[i].Time = max(lines[i].Time+offset, 0)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+
+	plain := make([]string, len(lines))
+	for i, line := range lines {
+		plain[i] = line.Text
+	}
+	return lines, strings.TrimSpace(strings.Join(plain, "\n"))
+}
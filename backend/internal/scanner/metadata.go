@@ -2,7 +2,9 @@ package scanner
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,16 +20,25 @@ type TrackMetadata struct {
 	Artist      string
 	Album       string
 	AlbumArtist string
+	Edition     string // album edition split off by tag normalization
 	Year        int
 	TrackNumber int
+	TotalTracks int // 0 when the tags don't say
 	DiscNumber  int
+	TotalDiscs  int // 0 when the tags don't say
 	Genre       string
 	Duration    int // in seconds
 	Bitrate     int
 	SampleRate  int
 	Channels    int
 	Format      string
+	Lossless    bool // lossless audio, from the format or the codec
 	HasArtwork  bool
+	BPM         int
+	MusicalKey  string
+	Chapters    []Chapter
+	Lyrics      string // from a sidecar .lrc file, else the tags
+	Gain        GainInfo
 }
 
 // MetadataExtractor handles metadata extraction from audio files
@@ -50,7 +61,9 @@ func (e *MetadataExtractor) Extract(path string) (*TrackMetadata, error) {
 	if err != nil {
 		// If tag reading fails, try to extract from filename
 		slog.Debug("tag reading failed, using filename fallback", "path", path, "error", err)
-		return e.extractFromFilename(path), nil
+		trackMeta := e.extractFromFilename(path)
+		trackMeta.Lyrics = sidecarLyrics(path)
+		return trackMeta, nil
 	}
 
 	trackMeta := &TrackMetadata{
@@ -62,18 +75,44 @@ func (e *MetadataExtractor) Extract(path string) (*TrackMetadata, error) {
 		Genre:       metadata.Genre(),
 		Format:      GetFormatFromPath(path),
 	}
+	trackMeta.Lossless = isLossless(trackMeta.Format, file)
+
+	// Extract track and disc numbers. The tag library only reads plain
+	// integers from text tags, so "03/12" Vorbis comments and Roman numerals
+	// are parsed from the raw tags; MP4 and ID3v1 store them as numbers.
+	trackMeta.TrackNumber, trackMeta.TotalTracks = positionFromTags(metadata.Raw(), trackNumberTags, trackTotalTags)
+	if trackMeta.TrackNumber == 0 {
+		trackMeta.TrackNumber, trackMeta.TotalTracks = metadata.Track()
+	}
 
-	// Extract track and disc numbers
-	trackNum, totalTracks := metadata.Track()
-	trackMeta.TrackNumber = trackNum
-	_ = totalTracks // Not used currently
-
-	discNum, totalDiscs := metadata.Disc()
-	trackMeta.DiscNumber = discNum
+	trackMeta.DiscNumber, trackMeta.TotalDiscs = positionFromTags(metadata.Raw(), discNumberTags, discTotalTags)
+	if trackMeta.DiscNumber == 0 {
+		trackMeta.DiscNumber, trackMeta.TotalDiscs = metadata.Disc()
+	}
 	if trackMeta.DiscNumber == 0 {
 		trackMeta.DiscNumber = 1
 	}
-	_ = totalDiscs // Not used currently
+
+	// Tempo and key from DJ software or taggers, when present
+	trackMeta.BPM, trackMeta.MusicalKey = tempoAndKeyFromTags(metadata.Raw())
+
+	// Chapter markers in audiobooks and mixes
+	trackMeta.Chapters = chaptersFromTags(metadata.Format(), metadata.Raw())
+
+	// Loudness and encoder padding for gapless, level-matched playback
+	trackMeta.Gain = gainFromTags(metadata.Raw())
+	if trackMeta.Format == "mp3" && trackMeta.Gain.EncoderDelay == 0 && trackMeta.Gain.EncoderPadding == 0 {
+		// MP3 encoders record them in the LAME header rather than a tag
+		if delay, padding, ok := readLAMEGapless(file); ok {
+			trackMeta.Gain.EncoderDelay, trackMeta.Gain.EncoderPadding = delay, padding
+		}
+	}
+
+	// Synced lyrics in a sidecar .lrc file win over unsynced ones in the tags
+	trackMeta.Lyrics = sidecarLyrics(path)
+	if trackMeta.Lyrics == "" {
+		trackMeta.Lyrics = strings.TrimSpace(metadata.Lyrics())
+	}
 
 	// Check for embedded artwork
 	if metadata.Picture() != nil {
@@ -86,12 +125,59 @@ func (e *MetadataExtractor) Extract(path string) (*TrackMetadata, error) {
 	return trackMeta, nil
 }
 
+// isLossless reports whether a file holds lossless audio: FLAC and WAV
+// always do, and MP4 files do when their codec is Apple Lossless. Other
+// lossless codecs are found when the file is probed.
+func isLossless(format string, r io.ReadSeeker) bool {
+	switch format {
+	case "flac", "wav":
+		return true
+	case "m4a":
+		codec, ok := readMP4Codec(r)
+		return ok && codec == "alac"
+	}
+	return false
+}
+
+// tempoAndKeyFromTags reads BPM and initial key from ID3, Vorbis or MP4 tags
+func tempoAndKeyFromTags(raw map[string]interface{}) (int, string) {
+	bpm := 0
+	for _, name := range []string{"TBPM", "TBP", "bpm", "tempo"} {
+		switch v := raw[name].(type) {
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && f > 0 {
+				bpm = int(math.Round(f))
+			}
+		case int:
+			bpm = v
+		}
+		if bpm > 0 {
+			break
+		}
+	}
+
+	key := ""
+	for _, name := range []string{"TKEY", "TKE", "initialkey", "key"} {
+		if v, ok := raw[name].(string); ok {
+			if key = NormalizeKey(v); key != "" {
+				break
+			}
+		}
+	}
+
+	return bpm, key
+}
+
 // extractFromFilename creates metadata from the filename when tags are unavailable
 func (e *MetadataExtractor) extractFromFilename(path string) *TrackMetadata {
 	meta := &TrackMetadata{
 		Format:     GetFormatFromPath(path),
 		DiscNumber: 1,
 	}
+	if file, err := os.Open(path); err == nil {
+		meta.Lossless = isLossless(meta.Format, file)
+		file.Close()
+	}
 
 	filename := filepath.Base(path)
 	filename = strings.TrimSuffix(filename, filepath.Ext(filename))
@@ -143,13 +229,10 @@ func (e *MetadataExtractor) applyFallbacks(meta *TrackMetadata, path string) {
 		meta.Album = cleanAlbumName(dirName)
 	}
 
-	// Fallback artist to parent directory name
-	if meta.Artist == "" {
+	// Fallback artist to parent directory name. Generic names are left empty
+	// for the library to file under its unknown artist.
+	if meta.Artist == "" && !isGenericName(parentDirName) {
 		meta.Artist = parentDirName
-		// If parent is something generic, use "Unknown Artist"
-		if isGenericName(meta.Artist) {
-			meta.Artist = "Unknown Artist"
-		}
 	}
 
 	// Set album artist if empty
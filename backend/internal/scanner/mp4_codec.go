@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// maxMP4Boxes bounds how many boxes are read looking for the sample
+// description, so a corrupt file can't keep the scanner busy
+const maxMP4Boxes = 256
+
+// mp4Containers are the boxes on the path to a track's sample description
+var mp4Containers = map[string]bool{"moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true}
+
+// readMP4Codec returns the type of the first sample entry of an MP4 file's
+// tracks, such as "mp4a" for AAC or "alac" for Apple Lossless, which the tag
+// library can't tell apart. ok is false when the file has no such entry.
+func readMP4Codec(r io.ReadSeeker) (codec string, ok bool) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", false
+	}
+	boxes := 0
+	return findSampleEntry(r, 0, end, &boxes)
+}
+
+// findSampleEntry walks the boxes between start and end, descending into
+// the containers leading to an stsd box
+func findSampleEntry(r io.ReadSeeker, start, end int64, boxes *int) (string, bool) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if *boxes++; *boxes > maxMP4Boxes {
+			return "", false
+		}
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return "", false
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return "", false
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of its parent
+			size = end - offset
+		case 1:
+			// A 64-bit size follows the type
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return "", false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return "", false
+		}
+
+		switch {
+		case kind == "stsd":
+			// Version and flags, the entry count, then the first entry's
+			// size and type
+			entry := make([]byte, 16)
+			if size < headerSize+int64(len(entry)) {
+				return "", false
+			}
+			if _, err := io.ReadFull(r, entry); err != nil {
+				return "", false
+			}
+			if binary.BigEndian.Uint32(entry[4:8]) == 0 {
+				return "", false
+			}
+			return string(entry[12:16]), true
+		case mp4Containers[kind]:
+			if codec, ok := findSampleEntry(r, offset+headerSize, offset+size, boxes); ok {
+				return codec, true
+			}
+		}
+		offset += size
+	}
+	return "", false
+}
@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// mp4Box builds an MP4 box of the given type around its body
+func mp4Box(kind string, body ...[]byte) []byte {
+	content := bytes.Join(body, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(content)))
+	return append(append(box, kind...), content...)
+}
+
+// mp4File builds an MP4 file whose one track has a sample entry of the given type
+func mp4File(codec string) []byte {
+	entry := mp4Box(codec, make([]byte, 28))
+	stsd := mp4Box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, entry)
+	trak := mp4Box("trak", mp4Box("tkhd", make([]byte, 84)),
+		mp4Box("mdia", mp4Box("minf", mp4Box("stbl", stsd))))
+	return append(mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4Box("moov", mp4Box("mvhd", make([]byte, 100)), trak)...)
+}
+
+func TestReadMP4Codec(t *testing.T) {
+	truncated := mp4File("alac")
+	truncated = truncated[:len(truncated)-20]
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   string
+		wantOK bool
+	}{
+		{"apple lossless", mp4File("alac"), "alac", true},
+		{"aac", mp4File("mp4a"), "mp4a", true},
+		{"no movie", mp4Box("ftyp", []byte("M4A ")), "", false},
+		{"truncated", truncated, "", false},
+		{"empty", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, ok := readMP4Codec(bytes.NewReader(tt.data))
+			if codec != tt.want || ok != tt.wantOK {
+				t.Errorf("readMP4Codec = %q, %v; want %q, %v", codec, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsLossless(t *testing.T) {
+	tests := []struct {
+		format string
+		data   []byte
+		want   bool
+	}{
+		{"flac", nil, true},
+		{"wav", nil, true},
+		{"m4a", mp4File("alac"), true},
+		{"m4a", mp4File("mp4a"), false},
+		{"mp3", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isLossless(tt.format, bytes.NewReader(tt.data)); got != tt.want {
+			t.Errorf("isLossless(%s) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Tag normalization rule names
+const (
+	NormalizeTrim    = "trim"
+	NormalizeCase    = "case"
+	NormalizeFeat    = "feat"
+	NormalizeEdition = "edition"
+)
+
+// NormalizeRules selects which normalizations a Normalizer applies
+type NormalizeRules struct {
+	// Trim removes surrounding whitespace and collapses runs of spaces
+	Trim bool
+	// Case title-cases text that is entirely lower case, or entirely upper
+	// case with more than one word (single words like "ABBA" are kept)
+	Case bool
+	// Feat rewrites "ft", "ft.", "feat" and "featuring" as "feat."
+	Feat bool
+	// Edition moves suffixes like "(Remastered)" or "- Deluxe Edition" out
+	// of album titles into a separate edition
+	Edition bool
+}
+
+// AllNormalizeRules enables every rule
+var AllNormalizeRules = NormalizeRules{Trim: true, Case: true, Feat: true, Edition: true}
+
+// ParseNormalizeRules parses a comma-separated list of rule names; "all"
+// enables every rule and an empty list none
+func ParseNormalizeRules(s string) (NormalizeRules, error) {
+	return ParseNormalizeRuleList(strings.Split(s, ","))
+}
+
+// ParseNormalizeRuleList parses rule names
+func ParseNormalizeRuleList(names []string) (NormalizeRules, error) {
+	var rules NormalizeRules
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "all":
+			rules = AllNormalizeRules
+		case NormalizeTrim:
+			rules.Trim = true
+		case NormalizeCase:
+			rules.Case = true
+		case NormalizeFeat:
+			rules.Feat = true
+		case NormalizeEdition:
+			rules.Edition = true
+		default:
+			return NormalizeRules{}, fmt.Errorf("unknown normalization rule: %s", name)
+		}
+	}
+	return rules, nil
+}
+
+// Any reports whether any rule is enabled
+func (r NormalizeRules) Any() bool {
+	return r.Trim || r.Case || r.Feat || r.Edition
+}
+
+// Names lists the enabled rules
+func (r NormalizeRules) Names() []string {
+	names := []string{}
+	for _, rule := range []struct {
+		name    string
+		enabled bool
+	}{
+		{NormalizeTrim, r.Trim},
+		{NormalizeCase, r.Case},
+		{NormalizeFeat, r.Feat},
+		{NormalizeEdition, r.Edition},
+	} {
+		if rule.enabled {
+			names = append(names, rule.name)
+		}
+	}
+	return names
+}
+
+var (
+	featPattern = regexp.MustCompile(`(?i)(^|[\s(\[])(?:featuring|feat|ft)\.?(\s)`)
+
+	editionWords = `remaster(?:ed)?|deluxe|expanded|anniversary|special edition|collector'?s edition|bonus tracks?`
+	// "Title (2011 Remastered)" or "Title [Deluxe Edition]"
+	editionBracketPattern = regexp.MustCompile(`(?i)^(.*?)\s*[(\[]([^()\[\]]*\b(?:` + editionWords + `)\b[^()\[\]]*)[)\]]$`)
+	// "Title - Remastered 2011"
+	editionDashPattern = regexp.MustCompile(`(?i)^(.*?)\s+-\s+([^-]*\b(?:` + editionWords + `)\b[^-]*)$`)
+
+	// Words kept lower case inside titles
+	smallWords = map[string]bool{
+		"a": true, "an": true, "and": true, "as": true, "at": true, "but": true,
+		"by": true, "for": true, "in": true, "nor": true, "of": true, "on": true,
+		"or": true, "the": true, "to": true, "vs": true, "vs.": true, "with": true,
+		"feat.": true,
+	}
+)
+
+// Normalizer cleans up titles and names according to a set of rules
+type Normalizer struct {
+	rules NormalizeRules
+}
+
+// NewNormalizer creates a Normalizer applying rules
+func NewNormalizer(rules NormalizeRules) *Normalizer {
+	return &Normalizer{rules: rules}
+}
+
+// Rules returns the rules the normalizer applies
+func (n *Normalizer) Rules() NormalizeRules {
+	return n.rules
+}
+
+// Title normalizes a track title or artist name
+func (n *Normalizer) Title(s string) string {
+	if n.rules.Trim {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if n.rules.Case {
+		s = fixCase(s)
+	}
+	if n.rules.Feat {
+		s = featPattern.ReplaceAllString(s, "${1}feat.${2}")
+	}
+	return s
+}
+
+// AlbumTitle normalizes an album title, splitting off its edition when the
+// edition rule is enabled. Several suffixes ("(Deluxe) (Remastered)") are
+// joined into one edition; edition is empty when there is none.
+func (n *Normalizer) AlbumTitle(s string) (title, edition string) {
+	if !n.rules.Edition {
+		return n.Title(s), ""
+	}
+
+	// Split first so the edition doesn't affect case fixing of the title
+	title = strings.TrimSpace(s)
+	var editions []string
+	for {
+		rest, suffix, ok := splitEdition(title)
+		if !ok {
+			break
+		}
+		title = rest
+		editions = append([]string{suffix}, editions...)
+	}
+	return n.Title(title), strings.Join(editions, ", ")
+}
+
+// Apply normalizes the names in extracted metadata. A split off album
+// edition is stored in Edition.
+func (n *Normalizer) Apply(meta *TrackMetadata) {
+	meta.Title = n.Title(meta.Title)
+	meta.Artist = n.Title(meta.Artist)
+	meta.AlbumArtist = n.Title(meta.AlbumArtist)
+	meta.Album, meta.Edition = n.AlbumTitle(meta.Album)
+}
+
+// splitEdition removes one trailing edition suffix from an album title. A
+// title that is nothing but an edition is left alone.
+func splitEdition(title string) (rest, edition string, ok bool) {
+	for _, pattern := range []*regexp.Regexp{editionBracketPattern, editionDashPattern} {
+		if m := pattern.FindStringSubmatch(title); m != nil && strings.TrimSpace(m[1]) != "" {
+			return strings.TrimSpace(m[1]), strings.TrimSpace(m[2]), true
+		}
+	}
+	return title, "", false
+}
+
+// fixCase title-cases text that is all lower case, or all upper case with
+// more than one word. Mixed case is assumed to be deliberate.
+func fixCase(s string) string {
+	hasUpper := strings.IndexFunc(s, unicode.IsUpper) >= 0
+	hasLower := strings.IndexFunc(s, unicode.IsLower) >= 0
+	words := strings.Split(s, " ")
+
+	switch {
+	case hasUpper && hasLower, !hasUpper && !hasLower:
+		return s
+	case hasUpper && len(words) < 2:
+		return s
+	}
+
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if i > 0 && i < len(words)-1 && smallWords[lower] {
+			words[i] = lower
+			continue
+		}
+		words[i] = capitalize(lower)
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalize upper-cases the first letter of a word, skipping leading
+// punctuation such as an opening parenthesis
+func capitalize(word string) string {
+	runes := []rune(word)
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(runes)
+}
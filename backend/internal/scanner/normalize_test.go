@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNormalizeRules(t *testing.T) {
+	tests := []struct {
+		value string
+		want  NormalizeRules
+		err   bool
+	}{
+		{"", NormalizeRules{}, false},
+		{"trim", NormalizeRules{Trim: true}, false},
+		{"trim,case", NormalizeRules{Trim: true, Case: true}, false},
+		{" Feat , EDITION ", NormalizeRules{Feat: true, Edition: true}, false},
+		{"all", AllNormalizeRules, false},
+		{"trim,,", NormalizeRules{Trim: true}, false},
+		{"trim,shout", NormalizeRules{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rules, err := ParseNormalizeRules(tt.value)
+			if (err != nil) != tt.err || rules != tt.want {
+				t.Errorf("ParseNormalizeRules(%q) = %+v, %v; want %+v", tt.value, rules, err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			// The enabled names parse back to the same rules
+			again, err := ParseNormalizeRuleList(rules.Names())
+			if err != nil || again != rules {
+				t.Errorf("rules from names %v = %+v, %v; want %+v", rules.Names(), again, err, rules)
+			}
+			if rules.Any() != (rules != NormalizeRules{}) {
+				t.Errorf("Any() = %v for %+v", rules.Any(), rules)
+			}
+		})
+	}
+}
+
+func TestNormalizerTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules NormalizeRules
+		value string
+		want  string
+	}{
+		{"no rules", NormalizeRules{}, "  hello   world FT someone ", "  hello   world FT someone "},
+
+		{"trim", NormalizeRules{Trim: true}, "  Hello   World  ", "Hello World"},
+		{"trim tabs", NormalizeRules{Trim: true}, "Hello\t World\n", "Hello World"},
+
+		{"case lower", NormalizeRules{Case: true}, "hello world of the night", "Hello World of the Night"},
+		{"case upper", NormalizeRules{Case: true}, "THE END OF IT", "The End of It"},
+		{"case small word first and last", NormalizeRules{Case: true}, "the one to", "The One To"},
+		{"case single upper word", NormalizeRules{Case: true}, "ABBA", "ABBA"},
+		{"case single lower word", NormalizeRules{Case: true}, "yesterday", "Yesterday"},
+		{"case mixed", NormalizeRules{Case: true}, "iPhone song", "iPhone song"},
+		{"case brackets", NormalizeRules{Case: true}, "song (live version)", "Song (Live Version)"},
+		{"case no letters", NormalizeRules{Case: true}, "1999", "1999"},
+
+		{"feat ft.", NormalizeRules{Feat: true}, "Song ft. Someone", "Song feat. Someone"},
+		{"feat ft", NormalizeRules{Feat: true}, "Song Ft Someone", "Song feat. Someone"},
+		{"feat featuring", NormalizeRules{Feat: true}, "Song (featuring Someone)", "Song (feat. Someone)"},
+		{"feat bracket", NormalizeRules{Feat: true}, "Song [Feat. Someone]", "Song [feat. Someone]"},
+		{"feat already", NormalizeRules{Feat: true}, "Song feat. Someone", "Song feat. Someone"},
+		{"feat inside a word", NormalizeRules{Feat: true}, "Raft Soft ft", "Raft Soft ft"},
+
+		{"all rules", AllNormalizeRules, "  song   ft someone ", "Song feat. Someone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewNormalizer(tt.rules).Title(tt.value); got != tt.want {
+				t.Errorf("Title(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizerAlbumTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   NormalizeRules
+		value   string
+		title   string
+		edition string
+	}{
+		{"bracketed", NormalizeRules{Edition: true}, "Abbey Road (Remastered)", "Abbey Road", "Remastered"},
+		{"bracketed with year", NormalizeRules{Edition: true}, "Abbey Road (2009 Remaster)", "Abbey Road", "2009 Remaster"},
+		{"square brackets", NormalizeRules{Edition: true}, "Title [Deluxe Edition]", "Title", "Deluxe Edition"},
+		{"dash", NormalizeRules{Edition: true}, "Title - Remastered 2011", "Title", "Remastered 2011"},
+		{"several", NormalizeRules{Edition: true}, "Title (Deluxe) [Remastered]", "Title", "Deluxe, Remastered"},
+		{"collector's edition", NormalizeRules{Edition: true}, "Title (Collector's Edition)", "Title", "Collector's Edition"},
+		{"bonus tracks", NormalizeRules{Edition: true}, "Title (With Bonus Tracks)", "Title", "With Bonus Tracks"},
+		{"only an edition", NormalizeRules{Edition: true}, "(Remastered)", "(Remastered)", ""},
+		{"other brackets", NormalizeRules{Edition: true}, "Live (At Wembley)", "Live (At Wembley)", ""},
+		{"edition word in the title", NormalizeRules{Edition: true}, "Remastered Hits", "Remastered Hits", ""},
+		{"edition disabled", NormalizeRules{Trim: true}, " Abbey Road (Remastered) ", "Abbey Road (Remastered)", ""},
+		{"with other rules", AllNormalizeRules, "abbey  road (remastered)", "Abbey Road", "remastered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, edition := NewNormalizer(tt.rules).AlbumTitle(tt.value)
+			if title != tt.title || edition != tt.edition {
+				t.Errorf("AlbumTitle(%q) = %q, %q; want %q, %q", tt.value, title, edition, tt.title, tt.edition)
+			}
+		})
+	}
+}
+
+func TestNormalizerApply(t *testing.T) {
+	meta := &TrackMetadata{
+		Title:       " one  ft two ",
+		Artist:      "THE BAND",
+		AlbumArtist: "the band",
+		Album:       "First [Deluxe Edition]",
+	}
+	NewNormalizer(AllNormalizeRules).Apply(meta)
+
+	got := []string{meta.Title, meta.Artist, meta.AlbumArtist, meta.Album, meta.Edition}
+	if want := []string{"One feat. Two", "The Band", "The Band", "First", "Deluxe Edition"}; !slices.Equal(got, want) {
+		t.Errorf("normalized metadata = %q, want %q", got, want)
+	}
+}
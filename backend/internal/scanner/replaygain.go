@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// r128Offset converts Opus R128 gains, relative to -23 LUFS, to the -18 LUFS
+// reference ReplayGain uses
+const r128Offset = 5.0
+
+// GainInfo holds the loudness and gapless playback data found in a file's
+// tags. Gains are in dB and nil when the file has none; delay and padding
+// are the priming and trailing samples the encoder added, 0 when unknown.
+type GainInfo struct {
+	TrackGain      *float64
+	AlbumGain      *float64
+	EncoderDelay   int
+	EncoderPadding int
+}
+
+// gainFromTags reads ReplayGain from ID3 TXXX frames, Vorbis comments or MP4
+// freeform atoms (falling back to the R128 gains of Opus files), and encoder
+// delay and padding from the iTunSMPB tag iTunes and most AAC encoders write
+func gainFromTags(raw map[string]interface{}) GainInfo {
+	// Tag names are case-insensitive in every format that carries them
+	tags := make(map[string]string)
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			tags[strings.ToLower(name)] = v
+		case *tag.Comm:
+			// TXXX and COMM frames keep the tag name as their description
+			if name == "TXXX" || strings.HasPrefix(name, "TXXX_") ||
+				name == "COMM" || strings.HasPrefix(name, "COMM_") {
+				tags[strings.ToLower(v.Description)] = v.Text
+			}
+		}
+	}
+
+	var info GainInfo
+	info.TrackGain = parseGain(tags["replaygain_track_gain"])
+	info.AlbumGain = parseGain(tags["replaygain_album_gain"])
+	if info.TrackGain == nil {
+		info.TrackGain = parseR128Gain(tags["r128_track_gain"])
+	}
+	if info.AlbumGain == nil {
+		info.AlbumGain = parseR128Gain(tags["r128_album_gain"])
+	}
+	info.EncoderDelay, info.EncoderPadding = parseITunSMPB(tags["itunsmpb"])
+	return info
+}
+
+// parseGain parses a ReplayGain value such as "-6.54 dB"
+func parseGain(value string) *float64 {
+	value = strings.TrimSpace(value)
+	if fields := strings.Fields(value); len(fields) == 2 && strings.EqualFold(fields[1], "dB") {
+		value = fields[0]
+	}
+	gain, err := strconv.ParseFloat(strings.TrimPrefix(value, "+"), 64)
+	if err != nil || gain < -64 || gain > 64 {
+		return nil
+	}
+	return &gain
+}
+
+// parseR128Gain parses an Opus R128 gain, a Q7.8 fixed point number of dB
+func parseR128Gain(value string) *float64 {
+	q, err := strconv.ParseInt(strings.TrimSpace(value), 10, 16)
+	if err != nil {
+		return nil
+	}
+	gain := float64(q)/256 + r128Offset
+	return &gain
+}
+
+// parseITunSMPB reads the encoder delay and padding from an iTunSMPB value:
+// space-separated hex fields of which the second is the delay and the third
+// the padding, in samples
+func parseITunSMPB(value string) (delay, padding int) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	d, errDelay := strconv.ParseUint(fields[1], 16, 32)
+	p, errPadding := strconv.ParseUint(fields[2], 16, 32)
+	if errDelay != nil || errPadding != nil {
+		return 0, 0
+	}
+	return int(d), int(p)
+}
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -10,6 +11,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -31,7 +33,7 @@ var SupportedFormats = map[string]bool{
 type FileInfo struct {
 	Path         string
 	Size         int64
-	ModTime      time.Time
+	ModTime      time.Time // of the file or its sidecar lyrics, whichever is newer
 	Format       string
 	Hash         string
 	IsNew        bool
@@ -58,11 +60,16 @@ type ScanProgress struct {
 
 // Scanner handles file discovery in media directories
 type Scanner struct {
-	mediaRoot     string
-	knownFiles    map[string]time.Time // path -> modTime
-	mu            sync.RWMutex
-	progressChan  chan ScanProgress
-	workerCount   int
+	mediaRoot    string
+	roots        []string             // directories walked instead of mediaRoot, if any
+	knownFiles   map[string]time.Time // path -> modTime
+	mu           sync.RWMutex
+	progressChan chan ScanProgress
+	workerCount  int
+
+	followSymlinks bool
+	dedupePaths    bool
+	maxDepth       int // deepest directory walked below a root; 0 is unlimited
 }
 
 // NewScanner creates a new Scanner instance
@@ -77,6 +84,46 @@ func NewScanner(mediaRoot string, workerCount int) *Scanner {
 	}
 }
 
+// SetRoots sets the directories discovery walks instead of the media root.
+// Roots inside another root are dropped, since walking the outer one finds
+// their files; with no roots the media root is walked.
+func (s *Scanner) SetRoots(roots []string) {
+	cleaned := make([]string, len(roots))
+	for i, root := range roots {
+		cleaned[i] = filepath.Clean(root)
+	}
+	// A parent sorts before the directories inside it
+	sort.Strings(cleaned)
+
+	var kept []string
+	for _, root := range cleaned {
+		nested := false
+		for _, parent := range kept {
+			if IsWithinDir(parent, root) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			kept = append(kept, root)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots = kept
+}
+
+// IsWithinDir reports whether path is dir or inside it. Both are compared as
+// given; resolve symlinks first where they matter.
+func IsWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
 // SetKnownFiles sets the map of known files and their modification times
 func (s *Scanner) SetKnownFiles(files map[string]time.Time) {
 	s.mu.Lock()
@@ -84,19 +131,145 @@ func (s *Scanner) SetKnownFiles(files map[string]time.Time) {
 	s.knownFiles = files
 }
 
+// SetFollowSymlinks sets whether symlinked directories are walked during
+// discovery. Symlinked files are always followed.
+func (s *Scanner) SetFollowSymlinks(follow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.followSymlinks = follow
+}
+
+// SetDedupePaths sets whether a file reachable through several paths (via
+// symlinks, hard links or bind mounts) is discovered only once
+func (s *Scanner) SetDedupePaths(dedupe bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dedupePaths = dedupe
+}
+
+// SetMaxDepth sets how many directory levels below a root discovery walks.
+// Directories deeper than that are skipped with a warning; 0 walks the whole
+// tree.
+func (s *Scanner) SetMaxDepth(depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDepth = depth
+}
+
 // SetProgressChannel sets the channel for progress updates
 func (s *Scanner) SetProgressChannel(ch chan ScanProgress) {
 	s.progressChan = ch
 }
 
-// DiscoverFiles walks the media directory and returns all audio files
+// DiscoverFiles walks the media directory, or the roots set in its place,
+// and returns all audio files. Symlinked files are resolved to their
+// targets; symlinked directories are only walked when following symlinks is
+// enabled. With path deduplication on, a file reachable through several
+// paths is returned once, preferring a path already in the library (see
+// discovery.add).
 func (s *Scanner) DiscoverFiles(ctx context.Context) ([]FileInfo, error) {
-	var files []FileInfo
-	var mu sync.Mutex
+	s.mu.RLock()
+	roots := s.roots
+	if len(roots) == 0 {
+		roots = []string{s.mediaRoot}
+	}
+	d := &discovery{
+		followSymlinks: s.followSymlinks,
+		dedupePaths:    s.dedupePaths,
+		maxDepth:       s.maxDepth,
+		seen:           make(map[string]int),
+		walked:         make(map[string]bool),
+	}
+	s.mu.RUnlock()
 
-	slog.Info("starting file discovery", "root", s.mediaRoot)
+	slog.Info("starting file discovery", "roots", roots)
+	for _, root := range roots {
+		if err := s.walk(ctx, d, root, root, 0); err != nil {
+			return nil, fmt.Errorf("walking directory: %w", err)
+		}
+	}
+
+	if d.duplicates > 0 {
+		slog.Info("skipped duplicate paths", "count", d.duplicates)
+	}
+	if d.tooDeep > 0 {
+		slog.Info("skipped directories beyond the maximum depth", "count", d.tooDeep, "maxDepth", d.maxDepth)
+	}
+	slog.Info("file discovery complete", "totalFiles", len(d.files))
+	return d.files, nil
+}
 
-	err := filepath.WalkDir(s.mediaRoot, func(path string, d fs.DirEntry, err error) error {
+// discovery is the state of one DiscoverFiles pass
+type discovery struct {
+	followSymlinks bool
+	dedupePaths    bool
+	maxDepth       int
+	files          []FileInfo
+	seen           map[string]int  // file identity -> index in files
+	walked         map[string]bool // canonical directories already walked
+	duplicates     int             // paths skipped as another path's duplicate
+	tooDeep        int             // directories skipped for being beyond maxDepth
+}
+
+// add records a discovered file. With path deduplication on, a new path to
+// a file found under another path already is skipped. Paths already in the
+// library are never skipped, so their tracks keep their ratings and plays:
+// one wins over a new path found before it, and several are all kept.
+func (d *discovery) add(file FileInfo, identity string) {
+	if !d.dedupePaths {
+		d.files = append(d.files, file)
+		return
+	}
+
+	index, ok := d.seen[identity]
+	if !ok {
+		d.seen[identity] = len(d.files)
+		d.files = append(d.files, file)
+		return
+	}
+
+	first := d.files[index]
+	switch {
+	case file.IsNew:
+		slog.Debug("skipping duplicate path", "path", file.Path, "duplicateOf", first.Path)
+	case first.IsNew:
+		slog.Debug("skipping duplicate path", "path", first.Path, "duplicateOf", file.Path)
+		d.files[index] = file
+	default:
+		d.files = append(d.files, file)
+		return
+	}
+	d.duplicates++
+}
+
+// beyondMaxDepth reports whether a directory depth levels below the root is
+// too deep to walk, warning about it if so
+func (d *discovery) beyondMaxDepth(shown string, depth int) bool {
+	if d.maxDepth <= 0 || depth <= d.maxDepth {
+		return false
+	}
+	slog.Warn("skipping directory beyond the maximum depth", "path", shown, "maxDepth", d.maxDepth)
+	d.tooDeep++
+	return true
+}
+
+// walk discovers the files under root, reporting them under shownRoot. The
+// two differ when walking the target of a symlinked directory, so files keep
+// the path they were reached through. depth is how many directories root is
+// below the walked root it was reached from.
+func (s *Scanner) walk(ctx context.Context, d *discovery, root, shownRoot string, depth int) error {
+	canonicalRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		canonicalRoot = root // The walk reports the error
+	}
+	// Stops symlink loops and directories linked into the tree twice
+	if d.walked[canonicalRoot] {
+		slog.Debug("skipping directory already walked", "path", shownRoot, "target", canonicalRoot)
+		return nil
+	}
+	d.walked[canonicalRoot] = true
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			slog.Warn("error accessing path", "path", path, "error", err)
 			return nil // Continue walking
@@ -109,59 +282,90 @@ func (s *Scanner) DiscoverFiles(ctx context.Context) ([]FileInfo, error) {
 		default:
 		}
 
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		shown := filepath.Join(shownRoot, rel)
+		canonical := filepath.Join(canonicalRoot, rel)
+
+		// Levels below the walked root, 1 for entries directly in it
+		entryDepth := depth + strings.Count(rel, string(filepath.Separator)) + 1
+
 		// Skip directories
-		if d.IsDir() {
+		if entry.IsDir() {
 			// Skip hidden directories
-			if strings.HasPrefix(d.Name(), ".") && path != s.mediaRoot {
+			if strings.HasPrefix(entry.Name(), ".") && path != root {
 				return filepath.SkipDir
 			}
+			if path != root && d.beyondMaxDepth(shown, entryDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Get file info
+		info, err := entry.Info()
+		if err != nil {
+			slog.Warn("error getting file info", "path", shown, "error", err)
 			return nil
 		}
 
+		if entry.Type()&fs.ModeSymlink != 0 {
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				slog.Warn("skipping broken symlink", "path", shown, "error", err)
+				return nil
+			}
+			if info, err = os.Stat(target); err != nil {
+				slog.Warn("error getting file info", "path", shown, "error", err)
+				return nil
+			}
+			if info.IsDir() {
+				if !d.followSymlinks || strings.HasPrefix(entry.Name(), ".") {
+					return nil
+				}
+				if d.beyondMaxDepth(shown, entryDepth) {
+					return nil
+				}
+				return s.walk(ctx, d, target, shown, entryDepth)
+			}
+			canonical = target
+		}
+
 		// Check if file is a supported audio format
-		ext := strings.ToLower(filepath.Ext(path))
+		ext := strings.ToLower(filepath.Ext(shown))
 		if !SupportedFormats[ext] {
 			return nil
 		}
 
-		// Get file info
-		info, err := d.Info()
-		if err != nil {
-			slog.Warn("error getting file info", "path", path, "error", err)
-			return nil
+		// Lyrics are read from a sidecar file, so changing it changes the track
+		modTime := info.ModTime()
+		if lyricsModTime := sidecarLyricsModTime(shown); lyricsModTime.After(modTime) {
+			modTime = lyricsModTime
 		}
 
 		fileInfo := FileInfo{
-			Path:    path,
+			Path:    shown,
 			Size:    info.Size(),
-			ModTime: info.ModTime(),
+			ModTime: modTime,
 			Format:  ext[1:], // Remove leading dot
 		}
 
 		// Check if file is new or modified
 		s.mu.RLock()
-		knownModTime, exists := s.knownFiles[path]
+		knownModTime, exists := s.knownFiles[shown]
 		s.mu.RUnlock()
 
 		if !exists {
 			fileInfo.IsNew = true
-		} else if info.ModTime().After(knownModTime) {
+		} else if modTime.After(knownModTime) {
 			fileInfo.IsModified = true
 		}
 
-		mu.Lock()
-		files = append(files, fileInfo)
-		mu.Unlock()
-
+		d.add(fileInfo, fileIdentity(info, canonical))
 		return nil
 	})
-
-	if err != nil {
-		return nil, fmt.Errorf("walking directory: %w", err)
-	}
-
-	slog.Info("file discovery complete", "totalFiles", len(files))
-	return files, nil
 }
 
 // DiscoverNewAndModified returns only new or modified files
@@ -182,7 +386,9 @@ func (s *Scanner) DiscoverNewAndModified(ctx context.Context) ([]FileInfo, error
 	return filtered, nil
 }
 
-// ComputeFileHash generates a SHA256 hash of the file content
+// ComputeFileHash generates a SHA256 hash of the whole file content. It
+// keys shared transcodes, so sampling parts of the file could serve one
+// file's transcode for another.
 func (s *Scanner) ComputeFileHash(path string) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -190,43 +396,10 @@ func (s *Scanner) ComputeFileHash(path string) (string, error) {
 	}
 	defer file.Close()
 
-	// For large files, only hash the first and last 1MB
-	info, err := file.Stat()
-	if err != nil {
-		return "", fmt.Errorf("getting file stat: %w", err)
-	}
-
 	hasher := sha256.New()
-
-	if info.Size() > 2*1024*1024 {
-		// Hash first 1MB
-		buf := make([]byte, 1024*1024)
-		n, err := file.Read(buf)
-		if err != nil && err != io.EOF {
-			return "", fmt.Errorf("reading file start: %w", err)
-		}
-		hasher.Write(buf[:n])
-
-		// Hash last 1MB
-		_, err = file.Seek(-1024*1024, io.SeekEnd)
-		if err != nil {
-			return "", fmt.Errorf("seeking file end: %w", err)
-		}
-		n, err = file.Read(buf)
-		if err != nil && err != io.EOF {
-			return "", fmt.Errorf("reading file end: %w", err)
-		}
-		hasher.Write(buf[:n])
-
-		// Include file size in hash
-		hasher.Write([]byte(fmt.Sprintf("%d", info.Size())))
-	} else {
-		// Hash entire file for small files
-		if _, err := io.Copy(hasher, file); err != nil {
-			return "", fmt.Errorf("hashing file: %w", err)
-		}
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("hashing file: %w", err)
 	}
-
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
@@ -259,6 +432,25 @@ func IsSupportedFormat(path string) bool {
 	return SupportedFormats[ext]
 }
 
+// IsAudioContent checks the leading bytes of a file for a known audio
+// container signature, so renamed non-audio files can be rejected
+func IsAudioContent(header []byte) bool {
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")),
+		bytes.HasPrefix(header, []byte("fLaC")),
+		bytes.HasPrefix(header, []byte("OggS")),
+		bytes.HasPrefix(header, []byte{0x30, 0x26, 0xB2, 0x75}): // ASF (WMA)
+		return true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return true
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")): // MP4/M4A
+		return true
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0: // MPEG/ADTS frame sync
+		return true
+	}
+	return false
+}
+
 // GetFormatFromPath extracts the format from a file path
 func GetFormatFromPath(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
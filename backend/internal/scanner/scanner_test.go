@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Keep discovery logging out of test output
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// writeFile creates a file and the directories leading to it
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// discoveredPaths runs discovery and returns the paths found, sorted
+func discoveredPaths(t *testing.T, s *Scanner) []string {
+	t.Helper()
+	files, err := s.DiscoverFiles(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverFiles: %v", err)
+	}
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestDiscoverDuplicatePaths(t *testing.T) {
+	base := t.TempDir()
+	first := filepath.Join(base, "a")
+	second := filepath.Join(base, "b")
+	original := filepath.Join(first, "song.mp3")
+	linked := filepath.Join(second, "song.mp3")
+	writeFile(t, original)
+	if err := os.MkdirAll(second, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		link   func(oldname, newname string) error
+		dedupe bool
+		known  []string
+		want   []string
+	}{
+		{"symlink without dedupe", os.Symlink, false, nil, []string{original, linked}},
+		{"symlink", os.Symlink, true, nil, []string{original}},
+		{"hard link", os.Link, true, nil, []string{original}},
+		{"second path imported", os.Symlink, true, []string{linked}, []string{linked}},
+		{"both paths imported", os.Symlink, true, []string{original, linked}, []string{original, linked}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(linked)
+			if err := tt.link(original, linked); err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewScanner(base, 1)
+			s.SetRoots([]string{first, second})
+			s.SetDedupePaths(tt.dedupe)
+			known := make(map[string]time.Time)
+			for _, path := range tt.known {
+				known[path] = time.Now()
+			}
+			s.SetKnownFiles(known)
+
+			got := discoveredPaths(t, s)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("discovered %v, want %v", got, tt.want)
+			}
+
+			// Known paths skipped or not, none of them count as deleted
+			deleted, err := s.FindDeletedFiles(context.Background())
+			if err != nil {
+				t.Fatalf("FindDeletedFiles: %v", err)
+			}
+			if len(deleted) != 0 {
+				t.Errorf("deleted %v, want none", deleted)
+			}
+		})
+	}
+}
+
+func TestDiscoverMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, path := range []string{
+		"top.mp3",
+		"a/one.mp3",
+		"a/b/two.mp3",
+		"a/b/c/three.mp3",
+		"a/b/c/d/four.mp3",
+	} {
+		writeFile(t, filepath.Join(root, path))
+	}
+	writeFile(t, filepath.Join(outside, "e/linked.mp3"))
+	// Reached one level down, the link's own directory is two levels deep
+	if err := os.Symlink(outside, filepath.Join(root, "a", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	in := func(paths ...string) []string {
+		for i, path := range paths {
+			paths[i] = filepath.Join(root, path)
+		}
+		slices.Sort(paths)
+		return paths
+	}
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     []string
+	}{
+		{"unlimited", 0, in("top.mp3", "a/one.mp3", "a/b/two.mp3", "a/b/c/three.mp3", "a/b/c/d/four.mp3", "a/link/e/linked.mp3")},
+		{"one level", 1, in("top.mp3", "a/one.mp3")},
+		{"two levels", 2, in("top.mp3", "a/one.mp3", "a/b/two.mp3")},
+		{"three levels", 3, in("top.mp3", "a/one.mp3", "a/b/two.mp3", "a/b/c/three.mp3", "a/link/e/linked.mp3")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner(root, 1)
+			s.SetFollowSymlinks(true)
+			s.SetMaxDepth(tt.maxDepth)
+			got := discoveredPaths(t, s)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("discovered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsWithinDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want bool
+	}{
+		{"/music", "/music", true},
+		{"/music", "/music/a/b.mp3", true},
+		{"/music", "/music/../music/a.mp3", true},
+		{"/music", "/musical/a.mp3", false},
+		{"/music", "/", false},
+		{"/music", "/music/../etc/passwd", false},
+		{"/music", "/music/..foo/a.mp3", true},
+		{"/music/a", "/music", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := IsWithinDir(tt.dir, tt.path); got != tt.want {
+				t.Errorf("IsWithinDir(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeFileHash(t *testing.T) {
+	dir := t.TempDir()
+	// Files that differ only in the middle, past where sampling the start
+	// and end of large files would look
+	data := make([]byte, 3<<20)
+	first := filepath.Join(dir, "first.flac")
+	if err := os.WriteFile(first, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] = 1
+	second := filepath.Join(dir, "second.flac")
+	if err := os.WriteFile(second, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	copied := filepath.Join(dir, "copy.flac")
+	if err := os.WriteFile(copied, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(dir, 1)
+	hash := func(path string) string {
+		t.Helper()
+		h, err := s.ComputeFileHash(path)
+		if err != nil {
+			t.Fatalf("ComputeFileHash: %v", err)
+		}
+		return h
+	}
+	if hash(first) == hash(second) {
+		t.Errorf("files differing in the middle hash the same")
+	}
+	if hash(second) != hash(copied) {
+		t.Errorf("identical files hash differently")
+	}
+}
@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestThumbnailModes(t *testing.T) {
+	// A landscape source twice as wide as it's tall
+	orange := color.RGBA{R: 240, G: 120, B: 0, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	draw.Draw(src, src.Bounds(), image.NewUniform(orange), image.Point{}, draw.Src)
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	tests := []struct {
+		mode ThumbnailMode
+		// size returns the expected output for a square size
+		size func(side int) image.Point
+		// padded reports whether the corners are the background
+		padded bool
+	}{
+		{ThumbnailFit, func(side int) image.Point { return image.Pt(side, side/2) }, false},
+		{ThumbnailCrop, func(side int) image.Point { return image.Pt(side, side) }, false},
+		{ThumbnailPad, func(side int) image.Point { return image.Pt(side, side) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			p := NewArtworkProcessor(t.TempDir())
+			p.SetThumbnailMode(tt.mode, white)
+
+			for _, size := range AllArtworkSizes {
+				img := p.thumbnail(src, size)
+				bounds := img.Bounds()
+				if got, want := bounds.Size(), tt.size(size.Width); got != want {
+					t.Errorf("%s: size = %v, want %v", size.Name, got, want)
+					continue
+				}
+
+				corner := color.RGBAModel.Convert(img.At(bounds.Min.X, bounds.Min.Y)).(color.RGBA)
+				center := color.RGBAModel.Convert(img.At(bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2)).(color.RGBA)
+				if !closeColor(center, orange) {
+					t.Errorf("%s: center = %v, want the artwork's %v", size.Name, center, orange)
+				}
+				if tt.padded && corner != white {
+					t.Errorf("%s: corner = %v, want the %v background", size.Name, corner, white)
+				}
+				if !tt.padded && !closeColor(corner, orange) {
+					t.Errorf("%s: corner = %v, want the artwork's %v", size.Name, corner, orange)
+				}
+			}
+		})
+	}
+}
+
+// closeColor reports whether two colors differ by at most a little per
+// channel, allowing for resampling
+func closeColor(a, b color.RGBA) bool {
+	near := func(x, y uint8) bool { return int(x)-int(y) <= 2 && int(y)-int(x) <= 2 }
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B)
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// noTrackArtworkMarker is left in a track's cache directory when its file
+// has no embedded artwork, so the file isn't read again until it changes
+const noTrackArtworkMarker = "none"
+
+// keyedLocks hands out a mutex per key, dropping it once nobody holds or
+// waits for it
+type keyedLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks key and returns the function unlocking it
+func (k *keyedLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// TrackArtworkPath returns the cached embedded artwork of a track at a size,
+// extracting it from audioPath first when nothing is cached yet or the file
+// changed since. Returns an empty path when the file has no embedded artwork.
+// Requests for the same track wait for the one extracting its artwork.
+func (p *ArtworkProcessor) TrackArtworkPath(audioPath, trackID, size string) (string, error) {
+	defer p.trackLocks.lock(trackID)()
+
+	audioInfo, err := os.Stat(audioPath)
+	if err != nil {
+		return "", err
+	}
+
+	dir := ArtworkCacheDir(p.cacheDir, ArtworkKindTrack, trackID)
+	path := ArtworkCachePath(p.cacheDir, ArtworkKindTrack, trackID, size)
+	marker := filepath.Join(dir, noTrackArtworkMarker)
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Before(audioInfo.ModTime()) {
+		return path, nil
+	}
+	if info, err := os.Stat(marker); err == nil && !info.ModTime().Before(audioInfo.ModTime()) {
+		return "", nil
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("clearing track artwork: %w", err)
+	}
+
+	// Files without tags are treated like files without a picture
+	data, mimeType, _ := NewMetadataExtractor().ExtractEmbeddedArtwork(audioPath)
+	if data == nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("creating cache directory: %w", err)
+		}
+		return "", os.WriteFile(marker, nil, 0644)
+	}
+
+	artwork := &ArtworkInfo{
+		Data:     data,
+		MIMEType: mimeType,
+		Source:   "embedded",
+	}
+	if _, err := p.processInto(artwork, ArtworkKindTrack, trackID); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// id3Frame encodes an ID3v2.3 frame
+func id3Frame(id string, body []byte) []byte {
+	frame := []byte(id)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	frame = append(frame, 0, 0)
+	return append(frame, body...)
+}
+
+// id3File builds an MP3-looking file whose ID3v2.3 tag holds frames, followed
+// by audio that is only padding
+func id3File(frames ...[]byte) []byte {
+	var body []byte
+	for _, frame := range frames {
+		body = append(body, frame...)
+	}
+	size := len(body)
+	data := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	data = append(data, body...)
+	return append(data, make([]byte, 1024)...)
+}
+
+// apicFrame is a front cover picture frame holding a PNG
+func apicFrame(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	body := append([]byte{0}, "image/png\x00"...)
+	body = append(body, 3, 0)
+	return id3Frame("APIC", append(body, buf.Bytes()...))
+}
+
+func TestTrackArtworkPath(t *testing.T) {
+	dir := t.TempDir()
+	withPicture := filepath.Join(dir, "picture.mp3")
+	if err := os.WriteFile(withPicture, id3File(apicFrame(t)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withoutPicture := filepath.Join(dir, "plain.mp3")
+	if err := os.WriteFile(withoutPicture, id3File(id3Frame("TIT2", []byte("\x00Plain"))), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantPath bool
+	}{
+		{"embedded picture", withPicture, true},
+		{"no picture", withoutPicture, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewArtworkProcessor(t.TempDir())
+
+			// Concurrent requests extract the artwork once between them and
+			// all get a complete image
+			const requests = 8
+			paths := make([]string, requests)
+			errs := make([]error, requests)
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					paths[i], errs[i] = p.TrackArtworkPath(tt.path, "t1", "small")
+				}(i)
+			}
+			wg.Wait()
+
+			for i := 0; i < requests; i++ {
+				if errs[i] != nil {
+					t.Fatalf("TrackArtworkPath: %v", errs[i])
+				}
+				if (paths[i] != "") != tt.wantPath {
+					t.Fatalf("TrackArtworkPath = %q, want a path: %v", paths[i], tt.wantPath)
+				}
+				if paths[i] == "" {
+					continue
+				}
+				data, err := os.ReadFile(paths[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+					t.Errorf("cached artwork doesn't decode: %v", err)
+				}
+			}
+
+			// Nothing is left behind: no temporary files, no locks
+			entries, _ := os.ReadDir(ArtworkCacheDir(p.cacheDir, ArtworkKindTrack, "t1"))
+			for _, entry := range entries {
+				if filepath.Ext(entry.Name()) != ".jpg" && entry.Name() != noTrackArtworkMarker {
+					t.Errorf("unexpected file %s in the cache", entry.Name())
+				}
+			}
+			if len(p.trackLocks.locks) != 0 {
+				t.Errorf("%d track locks left", len(p.trackLocks.locks))
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Tags holding track and disc positions, as ID3v2.3/2.4, ID3v2.2 and Vorbis
+// names. Totals may be part of the position ("3/12") or a tag of their own.
+var (
+	trackNumberTags = []string{"TRCK", "TRK", "tracknumber"}
+	trackTotalTags  = []string{"tracktotal", "totaltracks"}
+	discNumberTags  = []string{"TPOS", "TPA", "discnumber"}
+	discTotalTags   = []string{"disctotal", "totaldiscs"}
+)
+
+// maxPosition bounds track and disc numbers; anything larger is junk.
+// Roman numerals are held to maxRomanPosition, since words such as "MIX" or
+// "CD" are valid numerals far past any real track number.
+const (
+	maxPosition      = 9999
+	maxRomanPosition = 50
+)
+
+// positionFromTags reads a track or disc number and total from the first
+// number tag that parses, taking the total from a total tag when the number
+// doesn't include one. It returns zeros when no tag is usable.
+func positionFromTags(raw map[string]interface{}, numberTags, totalTags []string) (number, total int) {
+	for _, name := range numberTags {
+		if value, ok := raw[name].(string); ok {
+			if number, total = parsePosition(value); number > 0 {
+				break
+			}
+		}
+	}
+	if number == 0 {
+		return 0, 0
+	}
+	if total == 0 {
+		for _, name := range totalTags {
+			if value, ok := raw[name].(string); ok {
+				if total = parsePositionNumber(value); total > 0 {
+					break
+				}
+			}
+		}
+	}
+	if total < number {
+		total = 0
+	}
+	return number, total
+}
+
+// parsePosition parses a track or disc position such as "3", "03", "3/12",
+// "03 / 12" or "III" into its number and total; either is 0 when missing or
+// unparseable
+func parsePosition(s string) (number, total int) {
+	numberPart, totalPart, _ := strings.Cut(s, "/")
+	return parsePositionNumber(numberPart), parsePositionNumber(totalPart)
+}
+
+// parsePositionNumber parses a positive decimal number, leading zeros
+// allowed, or a Roman numeral
+func parsePositionNumber(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n > 0 && n <= maxPosition {
+			return n
+		}
+		return 0
+	}
+	return parseRoman(s)
+}
+
+// romanValues are the values of Roman numeral digits
+var romanValues = map[rune]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// parseRoman parses a Roman numeral up to maxRomanPosition, all upper or all
+// lower case, returning 0 for anything that isn't one written in the
+// standard subtractive form
+func parseRoman(s string) int {
+	upper := strings.ToUpper(s)
+	if s != upper && s != strings.ToLower(s) {
+		return 0
+	}
+	s = upper
+	total := 0
+	for i, r := range s {
+		value, ok := romanValues[r]
+		if !ok {
+			return 0
+		}
+		if i+1 < len(s) && value < romanValues[rune(s[i+1])] {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+	// Round-tripping rejects malformed numerals such as "IIII" or "IC"
+	if total <= 0 || total > maxRomanPosition || formatRoman(total) != s {
+		return 0
+	}
+	return total
+}
+
+// formatRoman writes n as a Roman numeral in the standard subtractive form
+func formatRoman(n int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var b strings.Builder
+	for _, numeral := range numerals {
+		for n >= numeral.value {
+			b.WriteString(numeral.symbol)
+			n -= numeral.value
+		}
+	}
+	return b.String()
+}
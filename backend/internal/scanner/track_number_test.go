@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		value  string
+		number int
+		total  int
+	}{
+		{"3", 3, 0},
+		{"03", 3, 0},
+		{"03/12", 3, 12},
+		{"03 / 12", 3, 12},
+		{"3/", 3, 0},
+		{"/12", 0, 12},
+		{"III", 3, 0},
+		{"iii", 3, 0},
+		{"IV/XII", 4, 12},
+		{"L", 50, 0},
+		{"0", 0, 0},
+		{"-1", 0, 0},
+		{"10000", 0, 0},
+		{"Iv", 0, 0},
+		{"IIII", 0, 0},
+		{"IC", 0, 0},
+		{"VX", 0, 0},
+		{"LI", 0, 0},
+		{"MIX", 0, 0},
+		{"CD", 0, 0},
+		{"DJ", 0, 0},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			number, total := parsePosition(tt.value)
+			if number != tt.number || total != tt.total {
+				t.Errorf("parsePosition(%q) = %d, %d, want %d, %d", tt.value, number, total, tt.number, tt.total)
+			}
+		})
+	}
+}
+
+func TestExtractPositions(t *testing.T) {
+	text := func(id, value string) []byte { return id3Frame(id, append([]byte{0}, value...)) }
+
+	tests := []struct {
+		name        string
+		frames      [][]byte
+		track       int
+		totalTracks int
+		disc        int
+		totalDiscs  int
+	}{
+		{"with totals", [][]byte{text("TRCK", "03/12"), text("TPOS", "1/2")}, 3, 12, 1, 2},
+		{"roman", [][]byte{text("TRCK", "III"), text("TPOS", "II")}, 3, 0, 2, 0},
+		{"total below number", [][]byte{text("TRCK", "12/3")}, 12, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			frames := append([][]byte{text("TIT2", "Song")}, tt.frames...)
+			if err := os.WriteFile(path, id3File(frames...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			meta, err := NewMetadataExtractor().Extract(path)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if meta.TrackNumber != tt.track || meta.TotalTracks != tt.totalTracks {
+				t.Errorf("track %d of %d, want %d of %d", meta.TrackNumber, meta.TotalTracks, tt.track, tt.totalTracks)
+			}
+			if meta.DiscNumber != tt.disc || meta.TotalDiscs != tt.totalDiscs {
+				t.Errorf("disc %d of %d, want %d of %d", meta.DiscNumber, meta.TotalDiscs, tt.disc, tt.totalDiscs)
+			}
+		})
+	}
+}
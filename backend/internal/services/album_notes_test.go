@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAlbumNotesFromFiles(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{AlbumNotesFromFiles: true})
+	lib.addFile("Band/First/01 - One.mp3", nil)
+	lib.addFile("Band/First/02 - Two.mp3", nil)
+	lib.addFile("Band/First/notes.txt", []byte("Recorded live.\r\n"))
+	lib.addFile("Band/Second/01 - Three.mp3", nil)
+
+	notes := func(album string) string {
+		t.Helper()
+		var notes string
+		lib.db.DB.Raw("SELECT notes FROM albums WHERE title = ?", album).Scan(&notes)
+		return notes
+	}
+	albumID := func(album string) string {
+		t.Helper()
+		var id string
+		lib.db.DB.Raw("SELECT id FROM albums WHERE title = ?", album).Scan(&id)
+		return id
+	}
+	albums := lib.service.albumRepo
+
+	// Steps run in order against the same library
+	tests := []struct {
+		name   string
+		change func()
+		first  string
+		second string
+	}{
+		{"read from the folder", func() {}, "Recorded live.", ""},
+		{"added to an existing album", func() {
+			lib.addFile("Band/Second/notes", []byte("Demo tapes."))
+		}, "Recorded live.", "Demo tapes."},
+		{"cleared by a user", func() {
+			if err := albums.UpdateNotes(context.Background(), albumID("First"), ""); err != nil {
+				t.Fatal(err)
+			}
+		}, "", "Demo tapes."},
+		{"edited by a user", func() {
+			if err := albums.UpdateNotes(context.Background(), albumID("Second"), "Mine."); err != nil {
+				t.Fatal(err)
+			}
+		}, "", "Mine."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			lib.scan(false)
+			if got := notes("First"); got != tt.first {
+				t.Errorf("First notes = %q, want %q", got, tt.first)
+			}
+			if got := notes("Second"); got != tt.second {
+				t.Errorf("Second notes = %q, want %q", got, tt.second)
+			}
+		})
+	}
+
+	// Each album's folder is searched once per scan
+	lib.service.mu.Lock()
+	lib.service.notesChecked = make(map[string]bool)
+	lib.service.mu.Unlock()
+	id := albumID("Second")
+	if !lib.service.claimNotesCheck(id) || lib.service.claimNotesCheck(id) {
+		t.Errorf("album folder claimed for a notes search more than once")
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"harmony/internal/transcoder"
+)
+
+func TestAnalysisOncePerFileVersion(t *testing.T) {
+	// A stand-in for ffmpeg that decodes to silence and counts its decodes
+	dir := t.TempDir()
+	decodes := filepath.Join(dir, "decodes")
+	script := `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+if [ "$2" = "-encoders" ]; then exit 0; fi
+case "$*" in *f32le*) echo decode >> ` + decodes + `;; esac
+`
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: ffmpeg, CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+
+	lib := newTestLibrary(t, LibraryOptions{AnalyzeAudio: true})
+	lib.service.SetTranscoder(trans)
+	path := lib.addFile("Artist/Album/01 - Ambient.mp3", nil)
+
+	// Steps run in order; silence has no tempo or key, which is remembered
+	tests := []struct {
+		name        string
+		touch       bool
+		wantDecodes int
+	}{
+		{"new file is analyzed", false, 1},
+		{"unchanged file isn't analyzed again", false, 1},
+		{"changed file is analyzed again", true, 2},
+		{"and only once", false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.touch {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(path, later, later); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lib.scan(false)
+			data, _ := os.ReadFile(decodes)
+			if n := strings.Count(string(data), "decode\n"); n != tt.wantDecodes {
+				t.Errorf("audio decoded %d times, want %d", n, tt.wantDecodes)
+			}
+		})
+	}
+}
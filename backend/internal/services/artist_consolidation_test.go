@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestConsolidateArtists(t *testing.T) {
+	// counts returns how many artists and albums the library has
+	counts := func(lib *testLibrary) (artists, albums int64) {
+		t.Helper()
+		lib.db.DB.Raw("SELECT COUNT(*) FROM artists").Scan(&artists)
+		lib.db.DB.Raw("SELECT COUNT(*) FROM albums").Scan(&albums)
+		return artists, albums
+	}
+	addVariants := func(lib *testLibrary) {
+		lib.addFile("The Beatles/Help/01 - Help.mp3", nil)
+		lib.addFile("the beatles/Help/02 - Yesterday.mp3", nil)
+		lib.addFile("Beatles/Abbey Road/01 - Something.mp3", nil)
+		lib.addFile("The  BEATLES/Let It Be/01 - Get Back.mp3", nil)
+	}
+
+	tests := []struct {
+		name        string
+		enableLater bool
+		incremental bool
+	}{
+		{"consolidated while scanning", false, false},
+		{"existing duplicates, full scan", true, false},
+		{"existing duplicates, incremental scan", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newTestLibrary(t, LibraryOptions{ConsolidateArtists: !tt.enableLater})
+			addVariants(lib)
+			lib.scan(false)
+
+			if tt.enableLater {
+				if artists, _ := counts(lib); artists != 4 {
+					t.Fatalf("%d artists before consolidation, want 4", artists)
+				}
+				lib.service.SetOptions(LibraryOptions{ConsolidateArtists: true})
+				lib.scan(tt.incremental)
+			}
+
+			artists, albums := counts(lib)
+			if artists != 1 {
+				t.Errorf("%d artists, want 1", artists)
+			}
+			// Both Help tracks end up on one album
+			if albums != 3 {
+				t.Errorf("%d albums, want 3", albums)
+			}
+			if lib.albumOf("Help") != "Help" || lib.albumOf("Yesterday") != "Help" {
+				t.Errorf("Help tracks are on %q and %q", lib.albumOf("Help"), lib.albumOf("Yesterday"))
+			}
+			var tracks int64
+			lib.db.DB.Raw("SELECT COUNT(*) FROM tracks JOIN artists ON artists.id = tracks.artist_id").Scan(&tracks)
+			if tracks != 4 {
+				t.Errorf("%d tracks kept their artist, want 4", tracks)
+			}
+		})
+	}
+}
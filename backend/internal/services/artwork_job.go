@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrArtworkJobInProgress = errors.New("artwork reprocessing already in progress")
+	ErrArtworkJobNotRunning = errors.New("no artwork reprocessing is running")
+)
+
+// ArtworkJobProgress reports on a bulk artwork reprocessing run
+type ArtworkJobProgress struct {
+	Status          ScanStatus `json:"status"`
+	TotalAlbums     int        `json:"totalAlbums"`
+	ProcessedAlbums int        `json:"processedAlbums"`
+	UpdatedAlbums   int        `json:"updatedAlbums"`
+	MissingArtwork  int        `json:"missingArtwork"`
+	ErrorCount      int        `json:"errorCount"`
+	CurrentAlbum    string     `json:"currentAlbum,omitempty"`
+	StartedAt       time.Time  `json:"startedAt,omitempty"`
+	CompletedAt     time.Time  `json:"completedAt,omitempty"`
+}
+
+// artworkProgressInterval is how many albums pass between artwork_progress events
+const artworkProgressInterval = 25
+
+// StartArtworkReprocess regenerates cached artwork for every album in the
+// background. Progress is available from GetArtworkProgress and is announced
+// as artwork_started, artwork_progress and artwork_completed events.
+func (s *LibraryService) StartArtworkReprocess() error {
+	ctx, err := s.beginArtworkJob(context.Background())
+	if err != nil {
+		return err
+	}
+
+	go s.runArtworkJob(ctx)
+	return nil
+}
+
+// ReprocessArtwork regenerates cached artwork for every album and waits for
+// the run to finish
+func (s *LibraryService) ReprocessArtwork(ctx context.Context) error {
+	ctx, err := s.beginArtworkJob(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.runArtworkJob(ctx)
+}
+
+// CancelArtworkReprocess stops a running artwork reprocessing job. Albums
+// already processed keep their regenerated artwork.
+func (s *LibraryService) CancelArtworkReprocess() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.artworkCancel == nil {
+		return ErrArtworkJobNotRunning
+	}
+
+	s.artworkCancel()
+	return nil
+}
+
+// GetArtworkProgress returns the state of the current or last artwork job
+func (s *LibraryService) GetArtworkProgress() ArtworkJobProgress {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	progress := s.artworkJob
+	if progress.Status == "" {
+		progress.Status = ScanStatusIdle
+	}
+	return progress
+}
+
+// beginArtworkJob marks an artwork job as running, deriving its
+// cancellable context from parent
+func (s *LibraryService) beginArtworkJob(parent context.Context) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.artworkCancel != nil {
+		return nil, ErrArtworkJobInProgress
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	s.artworkCancel = cancel
+	s.artworkJob = ArtworkJobProgress{
+		Status:    ScanStatusProcessing,
+		StartedAt: time.Now(),
+	}
+	return ctx, nil
+}
+
+// runArtworkJob processes every album and records the final status
+func (s *LibraryService) runArtworkJob(ctx context.Context) error {
+	defer func() {
+		s.mu.Lock()
+		if s.artworkCancel != nil {
+			s.artworkCancel()
+			s.artworkCancel = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	sources, err := s.albumRepo.GetArtworkSources(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			s.finishArtworkJob(ScanStatusCancelled)
+		} else {
+			s.finishArtworkJob(ScanStatusFailed)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	s.artworkJob.TotalAlbums = len(sources)
+	s.mu.Unlock()
+
+	slog.Info("artwork reprocessing started", "albums", len(sources))
+	s.emitArtworkEvent("artwork_started")
+
+	for i, source := range sources {
+		if ctx.Err() != nil {
+			slog.Info("artwork reprocessing cancelled", "processed", i, "total", len(sources))
+			s.finishArtworkJob(ScanStatusCancelled)
+			return ctx.Err()
+		}
+
+		s.mu.Lock()
+		s.artworkJob.CurrentAlbum = source.Title
+		s.mu.Unlock()
+
+		found, err := s.reprocessAlbumArtwork(ctx, source.AlbumID, source.FilePath)
+
+		s.mu.Lock()
+		s.artworkJob.ProcessedAlbums++
+		switch {
+		case err != nil:
+			s.artworkJob.ErrorCount++
+		case found:
+			s.artworkJob.UpdatedAlbums++
+		default:
+			s.artworkJob.MissingArtwork++
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			slog.Warn("failed to reprocess artwork", "album", source.Title, "error", err)
+		}
+		if (i+1)%artworkProgressInterval == 0 {
+			s.emitArtworkEvent("artwork_progress")
+		}
+	}
+
+	s.finishArtworkJob(ScanStatusCompleted)
+	progress := s.GetArtworkProgress()
+	slog.Info("artwork reprocessing completed",
+		"albums", progress.ProcessedAlbums,
+		"updated", progress.UpdatedAlbums,
+		"missing", progress.MissingArtwork,
+		"errors", progress.ErrorCount,
+	)
+	return nil
+}
+
+// reprocessAlbumArtwork reloads an album and regenerates its cached artwork
+func (s *LibraryService) reprocessAlbumArtwork(ctx context.Context, albumID, audioPath string) (bool, error) {
+	album, err := s.albumRepo.FindByID(ctx, albumID)
+	if err != nil {
+		return false, err
+	}
+	return s.cacheAlbumArtwork(ctx, album, audioPath)
+}
+
+// finishArtworkJob records the final job status and emits artwork_completed
+func (s *LibraryService) finishArtworkJob(status ScanStatus) {
+	s.mu.Lock()
+	s.artworkJob.Status = status
+	s.artworkJob.CurrentAlbum = ""
+	s.artworkJob.CompletedAt = time.Now()
+	s.mu.Unlock()
+
+	s.emitArtworkEvent("artwork_completed")
+}
+
+// emitArtworkEvent notifies event handlers about artwork job progress
+func (s *LibraryService) emitArtworkEvent(eventType string) {
+	s.mu.RLock()
+	progress := s.progress.snapshot()
+	artwork := s.artworkJob
+	s.mu.RUnlock()
+
+	event := ScanEvent{
+		Type:     eventType,
+		Progress: progress,
+		Artwork:  &artwork,
+	}
+
+	s.dispatch(event)
+}
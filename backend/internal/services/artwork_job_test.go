@@ -0,0 +1,115 @@
+//go:build unix
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestArtworkReprocess(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{})
+	var cover bytes.Buffer
+	if err := png.Encode(&cover, image.NewGray(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 30; i++ {
+		lib.addFile(fmt.Sprintf("Band/A%02d/01 - Song.mp3", i), nil)
+	}
+	lib.addFile("Band/A01/cover.png", cover.Bytes())
+	lib.addFile("Band/A02/cover.png", cover.Bytes())
+	lib.scan(false)
+
+	events := make(chan ArtworkJobProgress, 100)
+	lib.service.OnScanEvent(func(event ScanEvent) {
+		if event.Artwork != nil {
+			events <- *event.Artwork
+		}
+	})
+
+	// A full run reports its progress along the way
+	if err := lib.service.ReprocessArtwork(context.Background()); err != nil {
+		t.Fatalf("ReprocessArtwork: %v", err)
+	}
+	progress := lib.service.GetArtworkProgress()
+	if progress.Status != ScanStatusCompleted || progress.TotalAlbums != 30 || progress.ProcessedAlbums != 30 ||
+		progress.UpdatedAlbums != 2 || progress.MissingArtwork != 28 {
+		t.Errorf("progress = %+v, want 30 albums processed, 2 updated", progress)
+	}
+	var processed []int
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			processed = append(processed, event.ProcessedAlbums)
+			done = event.Status == ScanStatusCompleted
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no artwork_completed event; saw progress %v", processed)
+		}
+	}
+	if want := []int{0, artworkProgressInterval, 30}; fmt.Sprint(processed) != fmt.Sprint(want) {
+		t.Errorf("events reported %v albums processed, want %v", processed, want)
+	}
+
+	// A third album's cover that can't be read until the test allows it
+	// holds the next run there
+	fifo := filepath.Join(lib.mediaRoot, "Band", "A03", "cover.png")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.service.StartArtworkReprocess(); err != nil {
+		t.Fatalf("StartArtworkReprocess: %v", err)
+	}
+	// Opening the other end returns once the run is reading the cover
+	opened := make(chan *os.File)
+	go func() {
+		writer, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+		if err != nil {
+			t.Error(err)
+		}
+		opened <- writer
+	}()
+	var writer *os.File
+	select {
+	case writer = <-opened:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("run didn't reach A03: %+v", lib.service.GetArtworkProgress())
+	}
+	if writer == nil {
+		t.FailNow()
+	}
+	if progress := lib.service.GetArtworkProgress(); progress.Status != ScanStatusProcessing ||
+		progress.CurrentAlbum != "A03" || progress.ProcessedAlbums != 2 {
+		t.Errorf("progress mid-run = %+v, want A03 after 2 albums", progress)
+	}
+	if err := lib.service.StartArtworkReprocess(); !errors.Is(err, ErrArtworkJobInProgress) {
+		t.Errorf("second start = %v, want %v", err, ErrArtworkJobInProgress)
+	}
+
+	if err := lib.service.CancelArtworkReprocess(); err != nil {
+		t.Fatalf("CancelArtworkReprocess: %v", err)
+	}
+	// Release the cover; the run stops before the next album
+	writer.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for lib.service.GetArtworkProgress().Status == ScanStatusProcessing {
+		if time.Now().After(deadline) {
+			t.Fatal("run didn't stop after cancelling")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if progress := lib.service.GetArtworkProgress(); progress.Status != ScanStatusCancelled || progress.ProcessedAlbums != 3 {
+		t.Errorf("progress after cancelling = %+v, want cancelled after 3 albums", progress)
+	}
+	if err := lib.service.CancelArtworkReprocess(); !errors.Is(err, ErrArtworkJobNotRunning) {
+		t.Errorf("cancelling again = %v, want %v", err, ErrArtworkJobNotRunning)
+	}
+}
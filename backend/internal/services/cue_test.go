@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestCueSheetTracks(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{})
+	lib.addFile("Band/Live/album.wav", nil)
+	lib.addFile("Band/Live/album.cue", []byte(`PERFORMER "Band"
+TITLE "Live"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Song"
+    INDEX 01 04:00:37
+  TRACK 03 AUDIO
+    TITLE "Outro"
+    INDEX 01 09:30:00
+`))
+	lib.scan(false)
+
+	var tracks []struct {
+		Title       string
+		TrackNumber int
+		StartOffset int
+		EndOffset   int
+		Duration    int
+	}
+	lib.db.DB.Raw(`SELECT title, track_number, start_offset, end_offset, duration
+		FROM tracks ORDER BY track_number`).Scan(&tracks)
+
+	tests := []struct {
+		title    string
+		start    int
+		end      int
+		duration int
+	}{
+		// 37 frames are 493ms, which rounds the first two durations
+		{"Intro", 0, 240493, 240},
+		{"Song", 240493, 570000, 330},
+		{"Outro", 570000, 0, 0},
+	}
+	if len(tracks) != len(tests) {
+		t.Fatalf("%d tracks, want %d", len(tracks), len(tests))
+	}
+	for i, tt := range tests {
+		got := tracks[i]
+		if got.Title != tt.title || got.StartOffset != tt.start || got.EndOffset != tt.end || got.Duration != tt.duration {
+			t.Errorf("track %d = %+v, want %s from %d to %d lasting %ds", i+1, got, tt.title, tt.start, tt.end, tt.duration)
+		}
+	}
+	if album := lib.albumOf("Song"); album != "Live" {
+		t.Errorf("Song is in %q, want Live", album)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import "sync"
+
+// DefaultEventBacklog is how many undelivered events are kept per handler
+// when no backlog is configured
+const DefaultEventBacklog = 16
+
+// coalescedEvents are intermediate updates that a newer event of the same
+// type supersedes. They may be merged or dropped for a handler that falls
+// behind; every other event is always delivered.
+var coalescedEvents = map[string]bool{
+	"scan_progress":    true,
+	"artwork_progress": true,
+}
+
+// eventSubscriber delivers events to one handler, in order, from at most one
+// goroutine at a time
+type eventSubscriber struct {
+	handler func(ScanEvent)
+
+	mu      sync.Mutex
+	queue   []ScanEvent
+	running bool
+	// closed drops queued and future events once the handler is removed
+	closed bool
+}
+
+// push queues an event and starts delivery if it isn't already running.
+// A progress update replaces one still waiting at the end of the queue, and
+// once the queue holds more than backlog events the oldest progress update
+// is dropped.
+func (sub *eventSubscriber) push(event ScanEvent, backlog int) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+
+	last := len(sub.queue) - 1
+	if coalescedEvents[event.Type] && last >= 0 && sub.queue[last].Type == event.Type {
+		sub.queue[last] = event
+	} else {
+		sub.queue = append(sub.queue, event)
+	}
+
+	if len(sub.queue) > backlog {
+		for i, queued := range sub.queue {
+			if coalescedEvents[queued.Type] {
+				sub.queue = append(sub.queue[:i], sub.queue[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if !sub.running {
+		sub.running = true
+		go sub.deliver()
+	}
+}
+
+// deliver hands queued events to the handler until the queue is empty
+func (sub *eventSubscriber) deliver() {
+	for {
+		sub.mu.Lock()
+		if len(sub.queue) == 0 || sub.closed {
+			sub.running = false
+			sub.mu.Unlock()
+			return
+		}
+		event := sub.queue[0]
+		sub.queue = sub.queue[1:]
+		sub.mu.Unlock()
+
+		sub.handler(event)
+	}
+}
+
+// close stops delivery to the handler; an event being handled completes
+func (sub *eventSubscriber) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.closed = true
+	sub.queue = nil
+}
+
+// OnScanEvent registers a handler for scan events. Each handler receives
+// events in order on its own goroutine; a slow handler only delays itself.
+// The returned function removes the handler again.
+func (s *LibraryService) OnScanEvent(handler func(ScanEvent)) func() {
+	sub := &eventSubscriber{handler: handler}
+
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sub.close()
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			// dispatch may be iterating the current slice, so build a new one
+			remaining := make([]*eventSubscriber, 0, len(s.subscribers))
+			for _, other := range s.subscribers {
+				if other != sub {
+					remaining = append(remaining, other)
+				}
+			}
+			s.subscribers = remaining
+		})
+	}
+}
+
+// dispatch queues an event for every registered handler
+func (s *LibraryService) dispatch(event ScanEvent) {
+	s.mu.RLock()
+	subscribers := s.subscribers
+	backlog := s.options.EventBacklog
+	s.mu.RUnlock()
+
+	if backlog <= 0 {
+		backlog = DefaultEventBacklog
+	}
+	for _, sub := range subscribers {
+		sub.push(event, backlog)
+	}
+}
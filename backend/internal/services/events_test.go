@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEventDelivery(t *testing.T) {
+	// event builds an event whose progress numbers it among its type
+	event := func(eventType string, n int) ScanEvent {
+		return ScanEvent{Type: eventType, Progress: ScanProgress{ProcessedFiles: n}}
+	}
+	var progress, alternating, terminal []ScanEvent
+	for i := 1; i <= 1000; i++ {
+		progress = append(progress, event("scan_progress", i))
+	}
+	progress = append(progress, event("scan_completed", 1000))
+	for i := 1; i <= 10; i++ {
+		alternating = append(alternating, event("scan_progress", i), event("artwork_progress", i))
+	}
+	alternating = append(alternating, event("scan_completed", 10))
+	for i := 1; i <= 5; i++ {
+		terminal = append(terminal, event("artwork_completed", i))
+	}
+
+	tests := []struct {
+		name    string
+		backlog int
+		events  []ScanEvent
+		want    []string
+	}{
+		{"progress coalesces", 0, progress,
+			[]string{"scan_progress:1000", "scan_completed:1000"}},
+		{"oldest progress dropped past the backlog", 4, alternating,
+			[]string{"artwork_progress:9", "scan_progress:10", "artwork_progress:10", "scan_completed:10"}},
+		{"other events kept past the backlog", 2, terminal,
+			[]string{"artwork_completed:1", "artwork_completed:2", "artwork_completed:3", "artwork_completed:4", "artwork_completed:5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newTestLibrary(t, LibraryOptions{EventBacklog: tt.backlog})
+			received := make(chan string, len(tt.events)+1)
+			release := make(chan struct{})
+			// The handler stalls on the first event while the rest are sent
+			lib.service.OnScanEvent(func(event ScanEvent) {
+				received <- fmt.Sprintf("%s:%d", event.Type, event.Progress.ProcessedFiles)
+				if event.Type == "scan_started" {
+					<-release
+				}
+			})
+			lib.service.dispatch(event("scan_started", 0))
+			if got := <-received; got != "scan_started:0" {
+				t.Fatalf("first event = %s, want scan_started:0", got)
+			}
+
+			before := runtime.NumGoroutine()
+			for _, event := range tt.events {
+				lib.service.dispatch(event)
+			}
+			if after := runtime.NumGoroutine(); after > before+2 {
+				t.Errorf("goroutines grew from %d to %d while the handler was stalled", before, after)
+			}
+			close(release)
+
+			var got []string
+			for len(got) < len(tt.want) {
+				select {
+				case label := <-received:
+					got = append(got, label)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("delivered %v, want %v", got, tt.want)
+				}
+			}
+			select {
+			case label := <-received:
+				got = append(got, label)
+			case <-time.After(50 * time.Millisecond):
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("delivered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+func TestMain(m *testing.M) {
+	// Keep scan logging out of test output
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// testLibrary is a library service over a fresh SQLite database and an
+// empty media root
+type testLibrary struct {
+	t         *testing.T
+	db        *database.Database
+	service   *LibraryService
+	mediaRoot string
+}
+
+// newTestLibrary opens and migrates a database in a temp directory and
+// builds a library service over it with opts
+func newTestLibrary(t *testing.T, opts LibraryOptions) *testLibrary {
+	t.Helper()
+	return openTestLibrary(t, opts, false)
+}
+
+// newRelativeTestLibrary is newTestLibrary storing track paths relative to
+// the media root
+func newRelativeTestLibrary(t *testing.T, opts LibraryOptions) *testLibrary {
+	t.Helper()
+	return openTestLibrary(t, opts, true)
+}
+
+func openTestLibrary(t *testing.T, opts LibraryOptions, relativePaths bool) *testLibrary {
+	t.Helper()
+	mediaRoot := t.TempDir()
+	db, err := database.New(database.Config{
+		Path:        filepath.Join(t.TempDir(), "harmony.db"),
+		MaxOpenConn: 1,
+		MaxIdleConn: 1,
+		TrackPaths:  models.NewTrackPaths("main", mediaRoot, relativePaths),
+	})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+
+	service := NewLibraryService(mediaRoot, t.TempDir(),
+		database.NewTrackRepository(db.DB),
+		database.NewAlbumRepository(db.DB),
+		database.NewArtistRepository(db.DB),
+		database.NewScanFailureRepository(db.DB))
+	service.SetOptions(opts)
+	return &testLibrary{t: t, db: db, service: service, mediaRoot: mediaRoot}
+}
+
+// addFile writes a file under the media root. Untagged files are named
+// after their tracks and sit in Artist/Album directories, which the
+// metadata extractor falls back to.
+func (l *testLibrary) addFile(path string, content []byte) string {
+	l.t.Helper()
+	full := filepath.Join(l.mediaRoot, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		l.t.Fatal(err)
+	}
+	if content == nil {
+		content = []byte("not really audio")
+	}
+	if err := os.WriteFile(full, content, 0644); err != nil {
+		l.t.Fatal(err)
+	}
+	return full
+}
+
+// scan runs a full or incremental scan to completion
+func (l *testLibrary) scan(incremental bool) {
+	l.t.Helper()
+	var err error
+	if incremental {
+		err = l.service.IncrementalScan(context.Background())
+	} else {
+		err = l.service.FullScan(context.Background())
+	}
+	if err != nil {
+		l.t.Fatalf("scan: %v", err)
+	}
+}
+
+// albumOf returns the title of the album a track is in
+func (l *testLibrary) albumOf(title string) string {
+	l.t.Helper()
+	var album string
+	l.db.DB.Raw(`SELECT albums.title FROM tracks JOIN albums ON albums.id = tracks.album_id
+		WHERE tracks.title = ?`, title).Scan(&album)
+	return album
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+// IncompleteTrack is a track missing some of its metadata
+type IncompleteTrack struct {
+	Track models.Track
+	// Missing lists the missing fields of database.MetadataFields
+	Missing []string
+}
+
+// IncompleteMetadata returns a page of tracks missing any of the given
+// metadata fields (all of database.MetadataFields when empty). Tracks filed
+// under the unknown artist or album count as missing those tags.
+func (s *LibraryService) IncompleteMetadata(ctx context.Context, missing []string, page, limit int) ([]IncompleteTrack, int64, error) {
+	unknownArtist, unknownAlbum := s.unknownNames()
+	tracks, total, err := s.trackRepo.ListIncompleteMetadata(ctx, database.IncompleteMetadataOptions{
+		Missing:       missing,
+		UnknownArtist: unknownArtist,
+		UnknownAlbum:  unknownAlbum,
+		Page:          page,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	incomplete := make([]IncompleteTrack, len(tracks))
+	for i, track := range tracks {
+		incomplete[i] = IncompleteTrack{
+			Track:   track,
+			Missing: database.MissingMetadata(track, unknownArtist, unknownAlbum),
+		}
+	}
+	return incomplete, total, nil
+}
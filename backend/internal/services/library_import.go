@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"harmony/internal/models"
+	"harmony/internal/scanner"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	ErrInvalidUploadDir  = errors.New("upload directory must be inside the media root")
+)
+
+// ImportFile stores an uploaded audio file under the upload directory,
+// organized as Artist/Album/NN - Title, and imports it into the library.
+// Imports take the scan slot, so they never run alongside a scan or each
+// other; ErrScanInProgress is returned while it's taken, and scans queued
+// during an import start when it ends.
+func (s *LibraryService) ImportFile(ctx context.Context, r io.Reader, filename string) (*models.Track, error) {
+	filename = sanitizePathComponent(filepath.Base(filename))
+	if !scanner.IsSupportedFormat(filename) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	s.mu.Lock()
+	if s.scanning {
+		s.mu.Unlock()
+		return nil, ErrScanInProgress
+	}
+	s.scanning = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.releaseScanSlot()
+	}()
+
+	uploadRoot, err := s.uploadRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	// Stage the upload so tags can be read before choosing its final location
+	stagingDir, err := os.MkdirTemp(uploadRoot, ".incoming-")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// Stage under the unknown artist/album names so untagged uploads fall back
+	// to them instead of the staging directory's name
+	unknownArtist, unknownAlbum := s.unknownNames()
+	stagedDir := filepath.Join(stagingDir, sanitizePathComponent(unknownArtist), sanitizePathComponent(unknownAlbum))
+	if err := os.MkdirAll(stagedDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	stagedPath := filepath.Join(stagedDir, filename)
+
+	if err := writeUpload(stagedPath, r); err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.metadataExtractor.Extract(stagedPath)
+	if err != nil {
+		return nil, fmt.Errorf("extracting metadata: %w", err)
+	}
+	s.normalizeMetadata(metadata)
+	s.fillUnknownNames(metadata)
+
+	destPath, err := uniquePath(filepath.Join(
+		uploadRoot,
+		sanitizePathComponent(metadata.AlbumArtist),
+		sanitizePathComponent(metadata.Album),
+		trackFilename(metadata, filepath.Ext(filename)),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if !scanner.IsWithinDir(uploadRoot, destPath) {
+		return nil, fmt.Errorf("resolved upload path escapes upload directory: %s", destPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return nil, fmt.Errorf("creating destination directory: %w", err)
+	}
+	if err := os.Rename(stagedPath, destPath); err != nil {
+		return nil, fmt.Errorf("moving upload into library: %w", err)
+	}
+
+	info, err := os.Stat(destPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading imported file: %w", err)
+	}
+
+	if _, err := s.processFile(ctx, scanner.FileInfo{
+		Path:    destPath,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Format:  scanner.GetFormatFromPath(destPath),
+		IsNew:   true,
+	}); err != nil {
+		os.Remove(destPath)
+		return nil, fmt.Errorf("importing file: %w", err)
+	}
+
+	if err := s.collectSingles(ctx); err != nil {
+		slog.Warn("collecting singles failed", "error", err)
+	}
+	if err := s.classifyAlbums(ctx); err != nil {
+		slog.Warn("album classification failed", "error", err)
+	}
+	if err := s.albumRepo.RecountTotals(ctx); err != nil {
+		slog.Warn("recounting album totals failed", "error", err)
+	}
+
+	track, err := s.trackRepo.FindByFilePath(ctx, destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("imported uploaded file", "path", destPath, "trackID", track.ID)
+	return track, nil
+}
+
+// uploadRoot resolves the configured upload directory, ensuring it exists
+// and stays inside the media root
+func (s *LibraryService) uploadRoot() (string, error) {
+	mediaRoot, err := filepath.Abs(s.mediaRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolving media root: %w", err)
+	}
+
+	dir := s.getOptions().UploadDir
+	if dir == "" {
+		dir = DefaultUploadDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(mediaRoot, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	if !scanner.IsWithinDir(mediaRoot, dir) {
+		return "", ErrInvalidUploadDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating upload directory: %w", err)
+	}
+	return dir, nil
+}
+
+// writeUpload copies the upload to disk, rejecting content that isn't audio
+func writeUpload(path string, r io.Reader) error {
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("reading upload: %w", err)
+	}
+	if !scanner.IsAudioContent(header[:n]) {
+		return ErrUnsupportedFormat
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(header[:n]); err != nil {
+		return fmt.Errorf("writing upload: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("writing upload: %w", err)
+	}
+	return f.Close()
+}
+
+// trackFilename builds "NN - Title.ext" from track metadata
+func trackFilename(metadata *scanner.TrackMetadata, ext string) string {
+	title := sanitizePathComponent(metadata.Title)
+	if metadata.TrackNumber > 0 {
+		return fmt.Sprintf("%02d - %s%s", metadata.TrackNumber, title, strings.ToLower(ext))
+	}
+	return title + strings.ToLower(ext)
+}
+
+// uniquePath appends a counter to the filename until it doesn't collide
+func uniquePath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	candidate := path
+	for i := 2; i < 1000; i++ {
+		if _, err := os.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	return "", fmt.Errorf("too many files named %s", filepath.Base(path))
+}
+
+// sanitizePathComponent makes a metadata value safe to use as a single path
+// element: no separators, control characters, or leading dots
+func sanitizePathComponent(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, r == 0x7f:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > 200 {
+		name = strings.TrimSpace(string(runes[:200]))
+	}
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
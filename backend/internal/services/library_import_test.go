@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// untaggedMP3 is an ID3 header with no frames, which passes the upload's
+// content check and is named after its file
+var untaggedMP3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x00not really audio")
+
+// readerFunc runs before its first read, while the import holds the scan slot
+type readerFunc struct {
+	r      io.Reader
+	before func()
+}
+
+func (r *readerFunc) Read(p []byte) (int, error) {
+	if r.before != nil {
+		r.before()
+		r.before = nil
+	}
+	return r.r.Read(p)
+}
+
+func TestImportFileTakesScanSlot(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{ScanQueueDepth: 1})
+
+	// Refused while a scan runs
+	lib.service.mu.Lock()
+	lib.service.scanning = true
+	lib.service.mu.Unlock()
+	_, err := lib.service.ImportFile(context.Background(), bytes.NewReader(untaggedMP3), "01 - First.mp3")
+	if !errors.Is(err, ErrScanInProgress) {
+		t.Fatalf("ImportFile during a scan = %v, want %v", err, ErrScanInProgress)
+	}
+	lib.service.mu.Lock()
+	lib.service.scanning = false
+	lib.service.mu.Unlock()
+
+	// A scan asked for during an import waits for it
+	var status ScanStatus
+	r := &readerFunc{r: bytes.NewReader(untaggedMP3), before: func() {
+		status, _, err = lib.service.StartScan(true, false)
+	}}
+	track, importErr := lib.service.ImportFile(context.Background(), r, "01 - First.mp3")
+	if importErr != nil {
+		t.Fatalf("ImportFile: %v", importErr)
+	}
+	if err != nil || status != ScanStatusQueued {
+		t.Errorf("StartScan during an import = %q, %v; want queued", status, err)
+	}
+	if track.Title != "First" {
+		t.Errorf("imported title = %q, want First", track.Title)
+	}
+
+	// The queued scan ran once the import ended
+	for deadline := time.Now().Add(5 * time.Second); lib.service.IsScanning(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("queued scan did not finish")
+		}
+	}
+	if progress := lib.service.GetProgress(); progress.Status != ScanStatusCompleted {
+		t.Errorf("scan status after the import = %q, want completed", progress.Status)
+	}
+}
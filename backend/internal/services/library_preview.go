@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"harmony/internal/scanner"
+)
+
+var (
+	ErrPathOutsideRoot    = errors.New("path is outside the media root")
+	ErrUnreadableMetadata = errors.New("failed to read metadata")
+)
+
+// FilePreview is what a scan would import from a single file
+type FilePreview struct {
+	Path     string
+	Metadata *scanner.TrackMetadata
+	// Artwork is nil when no artwork was found
+	Artwork *scanner.ArtworkInfo
+	// ArtworkError is why looking for artwork failed, which a scan takes as
+	// no artwork; empty when it didn't
+	ArtworkError string
+}
+
+// PreviewFile reads the metadata and artwork of a file inside the media root
+// the same way a scan would, without importing it. Relative paths are
+// resolved against the media root.
+func (s *LibraryService) PreviewFile(ctx context.Context, path string) (*FilePreview, error) {
+	resolved, err := s.resolveMediaPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() || !scanner.IsSupportedFormat(resolved) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	metadata, err := s.metadataExtractor.Extract(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnreadableMetadata, err)
+	}
+	s.normalizeMetadata(metadata)
+	s.fillUnknownNames(metadata)
+
+	preview := &FilePreview{Path: resolved, Metadata: metadata}
+	preview.Artwork, err = s.artworkProcessor.FindArtwork(resolved)
+	if err != nil {
+		preview.Artwork = nil
+		preview.ArtworkError = err.Error()
+	}
+	return preview, nil
+}
+
+// resolveMediaPath makes path absolute and checks that it, with symlinks
+// followed, stays inside the media root
+func (s *LibraryService) resolveMediaPath(path string) (string, error) {
+	mediaRoot, err := filepath.Abs(s.mediaRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolving media root: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(mediaRoot, path)
+	}
+	path = filepath.Clean(path)
+	if !scanner.IsWithinDir(mediaRoot, path) {
+		return "", ErrPathOutsideRoot
+	}
+
+	realRoot, err := filepath.EvalSymlinks(mediaRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolving media root: %w", err)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if !scanner.IsWithinDir(realRoot, realPath) {
+		return "", ErrPathOutsideRoot
+	}
+	return path, nil
+}
@@ -4,20 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image/color"
+	"io/fs"
 	"log/slog"
+	"math"
 	"runtime"
+	"strings"
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"time"
 
 	"harmony/internal/database"
 	"harmony/internal/models"
 	"harmony/internal/scanner"
+	"harmony/internal/transcoder"
 )
 
 var (
 	ErrScanInProgress = errors.New("scan already in progress")
 	ErrScanNotRunning = errors.New("no scan is running")
+	ErrScanQueueFull  = errors.New("scan queue is full")
+
+	errMetadata = errors.New("extracting metadata")
 )
 
 // ScanStatus represents the current scan status
@@ -25,6 +33,7 @@ type ScanStatus string
 
 const (
 	ScanStatusIdle       ScanStatus = "idle"
+	ScanStatusQueued     ScanStatus = "queued"
 	ScanStatusScanning   ScanStatus = "scanning"
 	ScanStatusProcessing ScanStatus = "processing"
 	ScanStatusCompleted  ScanStatus = "completed"
@@ -34,35 +43,185 @@ const (
 
 // ScanProgress represents scan progress information
 type ScanProgress struct {
-	Status         ScanStatus `json:"status"`
-	TotalFiles     int        `json:"totalFiles"`
-	ProcessedFiles int        `json:"processedFiles"`
-	NewTracks      int        `json:"newTracks"`
-	UpdatedTracks  int        `json:"updatedTracks"`
-	DeletedTracks  int        `json:"deletedTracks"`
-	ErrorCount     int        `json:"errorCount"`
-	CurrentFile    string     `json:"currentFile,omitempty"`
-	StartedAt      time.Time  `json:"startedAt,omitempty"`
-	CompletedAt    time.Time  `json:"completedAt,omitempty"`
-	Duration       string     `json:"duration,omitempty"`
+	Status         ScanStatus   `json:"status"`
+	TotalFiles     int          `json:"totalFiles"`
+	ProcessedFiles int          `json:"processedFiles"`
+	NewTracks      int          `json:"newTracks"`
+	UpdatedTracks  int          `json:"updatedTracks"`
+	DeletedTracks  int          `json:"deletedTracks"`
+	SkippedFiles   int          `json:"skippedFiles"`
+	ErrorCount     int          `json:"errorCount"`
+	CurrentFile    string       `json:"currentFile,omitempty"`
+	StartedAt      time.Time    `json:"startedAt,omitempty"`
+	CompletedAt    time.Time    `json:"completedAt,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+	QueuedScans    int          `json:"queuedScans,omitempty"`
+	Queue          []QueuedScan `json:"queue,omitempty"`
+
+	Errors           []ScanFileError `json:"errors,omitempty"`
+	ErrorsByCategory map[string]int  `json:"errorsByCategory,omitempty"`
+}
+
+// snapshot copies the progress so it can be read outside the lock
+func (p ScanProgress) snapshot() ScanProgress {
+	p.Errors = append([]ScanFileError(nil), p.Errors...)
+	if p.ErrorsByCategory != nil {
+		counts := make(map[string]int, len(p.ErrorsByCategory))
+		for k, v := range p.ErrorsByCategory {
+			counts[k] = v
+		}
+		p.ErrorsByCategory = counts
+	}
+	return p
+}
+
+// Scan error categories, so users can tell "fix your file permissions" from
+// "this file is corrupt"
+const (
+	ScanErrorPermission = "permission"
+	ScanErrorIO         = "io"
+	ScanErrorParse      = "parse"
+	ScanErrorDatabase   = "database"
+)
+
+// maxScanErrors caps how many individual file errors a scan keeps
+const maxScanErrors = 100
+
+// ScanFileError records a file that could not be imported
+type ScanFileError struct {
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// categorizeScanError maps a processFile error to a scan error category
+func categorizeScanError(err error) string {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return ScanErrorPermission
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, syscall.EIO), errors.As(err, &pathErr):
+		return ScanErrorIO
+	case errors.Is(err, errMetadata):
+		return ScanErrorParse
+	default:
+		return ScanErrorDatabase
+	}
 }
 
 // ScanEvent represents a scan event for WebSocket updates
 type ScanEvent struct {
 	Type     string       `json:"type"`
 	Progress ScanProgress `json:"progress"`
+	Summary  *ScanSummary `json:"summary,omitempty"`
+	// MediaRoot is set on media_root_available/unavailable events
+	MediaRoot *MediaRootStatus `json:"mediaRoot,omitempty"`
+	// Artwork is set on artwork_started/progress/completed events
+	Artwork *ArtworkJobProgress `json:"artwork,omitempty"`
+}
+
+// ScanSummary is the final tally attached to the scan_completed event
+type ScanSummary struct {
+	Incremental   bool   `json:"incremental"`
+	TotalFiles    int    `json:"totalFiles"`
+	NewTracks     int    `json:"newTracks"`
+	UpdatedTracks int    `json:"updatedTracks"`
+	DeletedTracks int    `json:"deletedTracks"`
+	SkippedFiles  int    `json:"skippedFiles"`
+	ErrorCount    int    `json:"errorCount"`
+	Duration      string `json:"duration"`
+	DurationMs    int64  `json:"durationMs"`
+	Message       string `json:"message"`
 }
 
 // LibraryStats contains library statistics
 type LibraryStats struct {
-	TotalTracks   int64  `json:"totalTracks"`
-	TotalAlbums   int64  `json:"totalAlbums"`
-	TotalArtists  int64  `json:"totalArtists"`
-	TotalDuration int64  `json:"totalDuration"`
-	TotalSize     int64  `json:"totalSize"`
-	LastScanAt    string `json:"lastScanAt,omitempty"`
+	TotalTracks   int64     `json:"totalTracks"`
+	TotalAlbums   int64     `json:"totalAlbums"`
+	TotalArtists  int64     `json:"totalArtists"`
+	TotalDuration int64     `json:"totalDuration"`
+	TotalSize     int64     `json:"totalSize"`
+	LastScanAt    time.Time `json:"lastScanAt"` // zero before the first completed scan
+}
+
+// LibraryOptions holds optional scan behaviour
+type LibraryOptions struct {
+	// ArtworkFromVideo grabs a video frame with ffmpeg when no other artwork exists
+	ArtworkFromVideo bool
+	// ArtworkMaxDimension rejects embedded/external artwork larger than this
+	ArtworkMaxDimension int
+	// ArtworkMaxPixels rejects embedded/external artwork with more pixels than this
+	ArtworkMaxPixels int
+	// ProbeDuringScan runs ffprobe to fill bitrate/sample rate/channels the tags lack
+	ProbeDuringScan bool
+	// AnalyzeAudio decodes tracks with ffmpeg to detect BPM and key the tags lack
+	AnalyzeAudio bool
+	// UploadDir is where uploaded files are stored, relative to the media root
+	UploadDir string
+	// EventBacklog is how many undelivered scan events are kept per handler
+	// before intermediate progress updates are dropped
+	EventBacklog int
+	// MinAlbumTracks folds albums with fewer tracks into the album artist's
+	// Singles album; 0 disables
+	MinAlbumTracks int
+	// UnknownArtist and UnknownAlbum name the shared artist and album that
+	// tracks without artist or album metadata are filed under
+	UnknownArtist string
+	UnknownAlbum  string
+	// NormalizeRules cleans up titles and names as files are scanned
+	NormalizeRules scanner.NormalizeRules
+	// ProgressInterval is the minimum time between scan_progress events;
+	// 0 only limits them by file count
+	ProgressInterval time.Duration
+	// MaxScanFailures skips files that failed to import this many scans in a
+	// row until they change; 0 retries them every scan
+	MaxScanFailures int
+	// PurgeTranscodes removes cached transcodes of files that changed or were
+	// deleted during a scan
+	PurgeTranscodes bool
+	// ThumbnailMode controls how resized artwork is fitted (fit, crop or pad)
+	ThumbnailMode scanner.ThumbnailMode
+	// ThumbnailPadColor is the background used by the pad mode
+	ThumbnailPadColor color.Color
+	// FollowSymlinks walks symlinked directories during discovery
+	FollowSymlinks bool
+	// DedupePaths imports a file reachable through several paths only once
+	DedupePaths bool
+	// MaxScanDepth is how many directory levels below the media root or a
+	// selected folder discovery walks; 0 is unlimited
+	MaxScanDepth int
+	// SingleTrackSingles classifies albums of exactly one track as singles
+	SingleTrackSingles bool
+	// ScanQueueDepth is how many scans StartScan queues behind a running
+	// one; 0 rejects scans while one runs
+	ScanQueueDepth int
+	// HashFiles stores a content hash of each scanned file, used to share
+	// cached transcodes between identical files
+	HashFiles bool
+	// StatsCounters serves GetStats from the running library totals instead
+	// of counting every table on each request
+	StatsCounters bool
+	// VerifyMissingFiles removes the tracks of a file a stream found missing
+	// once it is confirmed gone (see VerifyMissingFile)
+	VerifyMissingFiles bool
+	// InferGenres gives tracks without a genre tag the dominant genre of
+	// their artist's other tracks after each scan
+	InferGenres bool
+	// ConsolidateArtists matches artist names ignoring case, spacing and a
+	// leading "The" (see database.ArtistNameKey)
+	ConsolidateArtists bool
+	// AlbumNotesFromFiles fills in the notes of albums without any from a
+	// notes.txt (or similar) file in the album's folder
+	AlbumNotesFromFiles bool
 }
 
+// Defaults used when the corresponding options aren't configured
+const (
+	DefaultUploadDir     = "uploads"
+	DefaultUnknownArtist = "Unknown Artist"
+	DefaultUnknownAlbum  = "Unknown Album"
+)
+
 // LibraryService handles library scanning and management
 type LibraryService struct {
 	mediaRoot        string
@@ -70,17 +229,46 @@ type LibraryService struct {
 	trackRepo        *database.TrackRepository
 	albumRepo        *database.AlbumRepository
 	artistRepo       *database.ArtistRepository
+	failureRepo      *database.ScanFailureRepository
 	scanner          *scanner.Scanner
 	metadataExtractor *scanner.MetadataExtractor
 	artworkProcessor *scanner.ArtworkProcessor
+	transcoder       *transcoder.Transcoder
+	settings         *database.SettingsRepository
+	options          LibraryOptions
 
 	// Scan state
-	mu            sync.RWMutex
-	scanning      bool
-	cancelFunc    context.CancelFunc
-	progress      ScanProgress
-	progressChan  chan ScanProgress
-	eventHandlers []func(ScanEvent)
+	mu           sync.RWMutex
+	scanning     bool
+	cancelFunc   context.CancelFunc
+	progress     ScanProgress
+	counters     *scanCounters
+	progressChan chan ScanProgress
+	subscribers  []*eventSubscriber
+	scanQueue    []scanRequest
+
+	// Media root health
+	rootStatus  MediaRootStatus
+	stopMonitor chan struct{}
+
+	// Scheduled scans
+	stopSchedule chan struct{}
+
+	// Pending checks of files streams found missing, keyed by path
+	missingChecks map[string]*time.Timer
+
+	// Albums whose folder was searched for notes during the running scan
+	notesChecked map[string]bool
+
+	// Cue sheets read during the running scan, by folder
+	cueSheets *scanner.CueSheetCache
+
+	// Artwork reprocessing job
+	artworkJob    ArtworkJobProgress
+	artworkCancel context.CancelFunc
+
+	// Tag normalization run
+	tagJob TagNormalizeJob
 }
 
 // NewLibraryService creates a new LibraryService
@@ -90,6 +278,7 @@ func NewLibraryService(
 	trackRepo *database.TrackRepository,
 	albumRepo *database.AlbumRepository,
 	artistRepo *database.ArtistRepository,
+	failureRepo *database.ScanFailureRepository,
 ) *LibraryService {
 	workerCount := runtime.NumCPU()
 	if workerCount > 8 {
@@ -102,6 +291,7 @@ func NewLibraryService(
 		trackRepo:         trackRepo,
 		albumRepo:         albumRepo,
 		artistRepo:        artistRepo,
+		failureRepo:       failureRepo,
 		scanner:           scanner.NewScanner(mediaRoot, workerCount),
 		metadataExtractor: scanner.NewMetadataExtractor(),
 		artworkProcessor:  scanner.NewArtworkProcessor(cacheDir),
@@ -109,18 +299,79 @@ func NewLibraryService(
 	}
 }
 
-// OnScanEvent registers a handler for scan events
-func (s *LibraryService) OnScanEvent(handler func(ScanEvent)) {
+// SetOptions configures optional scan behaviour
+func (s *LibraryService) SetOptions(opts LibraryOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = opts
+	s.artworkProcessor.SetMaxDimension(opts.ArtworkMaxDimension)
+	s.artworkProcessor.SetMaxPixels(opts.ArtworkMaxPixels)
+	s.artworkProcessor.SetThumbnailMode(opts.ThumbnailMode, opts.ThumbnailPadColor)
+	s.scanner.SetFollowSymlinks(opts.FollowSymlinks)
+	s.scanner.SetDedupePaths(opts.DedupePaths)
+	s.scanner.SetMaxDepth(opts.MaxScanDepth)
+}
+
+// getOptions returns a snapshot of the current options
+func (s *LibraryService) getOptions() LibraryOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.options
+}
+
+// SetTranscoder sets the transcoder used for ffmpeg-backed scan steps
+func (s *LibraryService) SetTranscoder(t *transcoder.Transcoder) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.eventHandlers = append(s.eventHandlers, handler)
+	s.transcoder = t
+}
+
+// SetSettings sets the repository the last scan time is kept in
+func (s *LibraryService) SetSettings(settings *database.SettingsRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = settings
+}
+
+// recordScanCompleted saves when a scan completed, for the library stats
+func (s *LibraryService) recordScanCompleted(ctx context.Context, at time.Time) {
+	s.mu.RLock()
+	settings := s.settings
+	s.mu.RUnlock()
+	if settings == nil {
+		return
+	}
+	if err := settings.Set(ctx, models.SettingLastScanAt, at.UTC().Format(time.RFC3339)); err != nil {
+		slog.Warn("saving last scan time failed", "error", err)
+	}
+}
+
+// lastScanAt returns when the last scan completed, or the zero time
+func (s *LibraryService) lastScanAt(ctx context.Context) (time.Time, error) {
+	s.mu.RLock()
+	settings := s.settings
+	s.mu.RUnlock()
+	if settings == nil {
+		return time.Time{}, nil
+	}
+	value, err := settings.Get(ctx, models.SettingLastScanAt)
+	if errors.Is(err, database.ErrSettingNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing last scan time: %w", err)
+	}
+	return at, nil
 }
 
 // emitEvent sends an event to all registered handlers
 func (s *LibraryService) emitEvent(eventType string) {
 	s.mu.RLock()
-	handlers := s.eventHandlers
-	progress := s.progress
+	progress := s.progressSnapshot()
 	s.mu.RUnlock()
 
 	event := ScanEvent{
@@ -128,16 +379,47 @@ func (s *LibraryService) emitEvent(eventType string) {
 		Progress: progress,
 	}
 
-	for _, handler := range handlers {
-		go handler(event)
+	s.dispatch(event)
+}
+
+// emitCompletion sends the scan_completed event with a summary of the scan
+func (s *LibraryService) emitCompletion(incremental bool) {
+	s.mu.RLock()
+	progress := s.progressSnapshot()
+	s.mu.RUnlock()
+
+	elapsed := time.Since(progress.StartedAt).Round(time.Millisecond)
+	summary := &ScanSummary{
+		Incremental:   incremental,
+		TotalFiles:    progress.TotalFiles,
+		NewTracks:     progress.NewTracks,
+		UpdatedTracks: progress.UpdatedTracks,
+		DeletedTracks: progress.DeletedTracks,
+		SkippedFiles:  progress.SkippedFiles,
+		ErrorCount:    progress.ErrorCount,
+		Duration:      elapsed.String(),
+		DurationMs:    elapsed.Milliseconds(),
+	}
+	summary.Message = fmt.Sprintf("scan done: %d new, %d updated, %d removed",
+		summary.NewTracks, summary.UpdatedTracks, summary.DeletedTracks)
+	if summary.ErrorCount > 0 {
+		summary.Message += fmt.Sprintf(", %d errors", summary.ErrorCount)
+	}
+
+	event := ScanEvent{
+		Type:     "scan_completed",
+		Progress: progress,
+		Summary:  summary,
 	}
+
+	s.dispatch(event)
 }
 
 // GetProgress returns the current scan progress
 func (s *LibraryService) GetProgress() ScanProgress {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.progress
+	return s.progressSnapshot()
 }
 
 // IsScanning returns whether a scan is in progress
@@ -149,26 +431,41 @@ func (s *LibraryService) IsScanning() bool {
 
 // FullScan performs a full library scan
 func (s *LibraryService) FullScan(ctx context.Context) error {
-	return s.scan(ctx, false)
+	return s.scan(ctx, false, false)
 }
 
 // IncrementalScan performs an incremental library scan
 func (s *LibraryService) IncrementalScan(ctx context.Context) error {
-	return s.scan(ctx, true)
+	return s.scan(ctx, true, false)
+}
+
+// ForceScan performs a full or incremental scan that also retries files
+// skipped after repeated failures
+func (s *LibraryService) ForceScan(ctx context.Context, incremental bool) error {
+	return s.scan(ctx, incremental, true)
 }
 
 // scan performs the actual scan operation
-func (s *LibraryService) scan(ctx context.Context, incremental bool) error {
+func (s *LibraryService) scan(ctx context.Context, incremental, force bool) error {
 	s.mu.Lock()
 	if s.scanning {
 		s.mu.Unlock()
 		return ErrScanInProgress
 	}
+	s.scanning = true
+	s.mu.Unlock()
 
+	return s.runScan(ctx, incremental, force)
+}
+
+// runScan scans the library once scanning has been set. When it finishes,
+// the next queued scan, if any, takes over without clearing scanning, so no
+// other scan can start in between.
+func (s *LibraryService) runScan(ctx context.Context, incremental, force bool) error {
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
 	s.cancelFunc = cancel
-	s.scanning = true
 	s.progress = ScanProgress{
 		Status:    ScanStatusScanning,
 		StartedAt: time.Now(),
@@ -177,11 +474,12 @@ func (s *LibraryService) scan(ctx context.Context, incremental bool) error {
 
 	defer func() {
 		s.mu.Lock()
-		s.scanning = false
+		defer s.mu.Unlock()
+		cancel()
 		s.cancelFunc = nil
 		s.progress.CompletedAt = time.Now()
 		s.progress.Duration = s.progress.CompletedAt.Sub(s.progress.StartedAt).String()
-		s.mu.Unlock()
+		s.releaseScanSlot()
 	}()
 
 	scanType := "full"
@@ -191,11 +489,21 @@ func (s *LibraryService) scan(ctx context.Context, incremental bool) error {
 	slog.Info("starting library scan", "type", scanType, "mediaRoot", s.mediaRoot)
 	s.emitEvent("scan_started")
 
-	// Load known files for incremental scan
-	if incremental {
-		if err := s.loadKnownFiles(ctx); err != nil {
-			s.setStatus(ScanStatusFailed)
-			return fmt.Errorf("loading known files: %w", err)
+	// Load known files, which incremental scans compare against and full
+	// scans check for deleted files
+	if err := s.loadKnownFiles(ctx); err != nil {
+		s.setStatus(ScanStatusFailed)
+		return fmt.Errorf("loading known files: %w", err)
+	}
+
+	// Key artists created before consolidation was enabled, merging
+	// duplicates into the artist holding their key
+	if s.getOptions().ConsolidateArtists {
+		assigned, merged, err := s.artistRepo.AssignNameKeys(ctx)
+		if err != nil {
+			slog.Warn("assigning artist name keys failed", "error", err)
+		} else if assigned > 0 || merged > 0 {
+			slog.Info("assigned artist name keys", "artists", assigned, "merged", merged)
 		}
 	}
 
@@ -212,14 +520,19 @@ func (s *LibraryService) scan(ctx context.Context, incremental bool) error {
 		return fmt.Errorf("discovering files: %w", err)
 	}
 
+	// Leave out files that keep failing
+	failures := s.loadScanFailures(ctx, files, incremental)
+	files, skipped := s.skipFailedFiles(files, failures, force)
+
 	s.mu.Lock()
 	s.progress.TotalFiles = len(files)
+	s.progress.SkippedFiles = skipped
 	s.progress.Status = ScanStatusProcessing
 	s.mu.Unlock()
 	s.emitEvent("scan_progress")
 
 	// Process files concurrently
-	if err := s.processFiles(ctx, files); err != nil {
+	if err := s.processFiles(ctx, files, failures); err != nil {
 		if errors.Is(err, context.Canceled) {
 			s.setStatus(ScanStatusCancelled)
 			return err
@@ -235,20 +548,39 @@ func (s *LibraryService) scan(ctx context.Context, incremental bool) error {
 		}
 	}
 
+	if err := s.collectSingles(ctx); err != nil {
+		slog.Warn("collecting singles failed", "error", err)
+	}
+	if err := s.classifyAlbums(ctx); err != nil {
+		slog.Warn("album classification failed", "error", err)
+	}
+	if err := s.albumRepo.RecountTotals(ctx); err != nil {
+		slog.Warn("recounting album totals failed", "error", err)
+	}
+	if err := s.inferGenres(ctx); err != nil {
+		slog.Warn("genre inference failed", "error", err)
+	}
+	if err := s.trackRepo.RecountLibraryTotals(ctx); err != nil {
+		slog.Warn("recounting library totals failed", "error", err)
+	}
+	s.purgeContentTranscodes(ctx)
+
 	s.setStatus(ScanStatusCompleted)
+	s.recordScanCompleted(ctx, time.Now())
 	slog.Info("library scan completed",
 		"newTracks", s.progress.NewTracks,
 		"updatedTracks", s.progress.UpdatedTracks,
 		"deletedTracks", s.progress.DeletedTracks,
 		"errors", s.progress.ErrorCount,
 	)
-	s.emitEvent("scan_completed")
+	s.emitCompletion(incremental)
 
 	return nil
 }
 
-// processFiles processes discovered files concurrently
-func (s *LibraryService) processFiles(ctx context.Context, files []scanner.FileInfo) error {
+// processFiles processes discovered files concurrently. failures holds the
+// recorded failures of the files, which are updated as files fail or succeed.
+func (s *LibraryService) processFiles(ctx context.Context, files []scanner.FileInfo, failures map[string]models.ScanFailure) error {
 	if len(files) == 0 {
 		return nil
 	}
@@ -260,8 +592,25 @@ func (s *LibraryService) processFiles(ctx context.Context, files []scanner.FileI
 
 	fileChan := make(chan scanner.FileInfo, workerCount*2)
 	var wg sync.WaitGroup
-	var processedCount int64
-	var newCount, updatedCount, errorCount int64
+	total := int64(len(files))
+	interval := s.getOptions().ProgressInterval
+
+	counters := &scanCounters{}
+	s.mu.Lock()
+	s.counters = counters
+	s.notesChecked = make(map[string]bool)
+	s.cueSheets = scanner.NewCueSheetCache()
+	s.mu.Unlock()
+
+	// Record the final tallies and stop reading the live counters
+	defer func() {
+		s.mu.Lock()
+		counters.apply(&s.progress)
+		s.counters = nil
+		s.notesChecked = nil
+		s.cueSheets = nil
+		s.mu.Unlock()
+	}()
 
 	// Start workers
 	for i := 0; i < workerCount; i++ {
@@ -275,27 +624,30 @@ func (s *LibraryService) processFiles(ctx context.Context, files []scanner.FileI
 				default:
 				}
 
+				counters.currentFile.Store(&fileInfo.Path)
 				isNew, err := s.processFile(ctx, fileInfo)
 				if err != nil {
-					slog.Warn("failed to process file", "path", fileInfo.Path, "error", err)
-					atomic.AddInt64(&errorCount, 1)
-				} else if isNew {
-					atomic.AddInt64(&newCount, 1)
+					category := categorizeScanError(err)
+					slog.Warn("failed to process file", "path", fileInfo.Path, "category", category, "error", err)
+					counters.errors.Add(1)
+					s.recordScanError(fileInfo.Path, category, err)
+					// Database errors say nothing about the file itself
+					if category != ScanErrorDatabase {
+						s.recordScanFailure(ctx, fileInfo, err)
+					}
 				} else {
-					atomic.AddInt64(&updatedCount, 1)
+					if _, failed := failures[fileInfo.Path]; failed {
+						s.clearScanFailure(ctx, fileInfo.Path)
+					}
+					if isNew {
+						counters.newTracks.Add(1)
+					} else {
+						counters.updated.Add(1)
+					}
 				}
 
-				processed := atomic.AddInt64(&processedCount, 1)
-
-				// Update progress periodically
-				if processed%10 == 0 || processed == int64(len(files)) {
-					s.mu.Lock()
-					s.progress.ProcessedFiles = int(processed)
-					s.progress.NewTracks = int(atomic.LoadInt64(&newCount))
-					s.progress.UpdatedTracks = int(atomic.LoadInt64(&updatedCount))
-					s.progress.ErrorCount = int(atomic.LoadInt64(&errorCount))
-					s.progress.CurrentFile = fileInfo.Path
-					s.mu.Unlock()
+				processed := counters.processed.Add(1)
+				if counters.shouldEmit(processed, total, interval, time.Now()) {
 					s.emitEvent("scan_progress")
 				}
 			}
@@ -323,57 +675,498 @@ func (s *LibraryService) processFile(ctx context.Context, fileInfo scanner.FileI
 	// Extract metadata
 	metadata, err := s.metadataExtractor.Extract(fileInfo.Path)
 	if err != nil {
-		return false, fmt.Errorf("extracting metadata: %w", err)
+		return false, fmt.Errorf("%w: %w", errMetadata, err)
+	}
+	s.normalizeMetadata(metadata)
+	s.fillUnknownNames(metadata)
+
+	// Fill technical fields the tags couldn't provide
+	if s.getOptions().ProbeDuringScan {
+		s.fillFromProbe(ctx, fileInfo.Path, metadata)
+	}
+
+	if s.getOptions().HashFiles && fileInfo.Hash == "" {
+		hash, err := s.scanner.ComputeFileHash(fileInfo.Path)
+		if err != nil {
+			slog.Warn("failed to hash file", "path", fileInfo.Path, "error", err)
+		}
+		fileInfo.Hash = hash
+	}
+
+	// Single-file albums with a cue sheet become one track per cue entry
+	s.mu.RLock()
+	cueSheets := s.cueSheets
+	s.mu.RUnlock()
+	if sheet := cueSheets.Find(fileInfo.Path); sheet != nil {
+		if cueTracks := sheet.TracksForFile(fileInfo.Path); len(cueTracks) > 1 {
+			return s.processCueFile(ctx, fileInfo, metadata, sheet, cueTracks)
+		}
 	}
 
 	// Find or create artist
-	artist, err := s.artistRepo.FindOrCreate(ctx, metadata.Artist)
+	artist, err := s.findOrCreateArtist(ctx, metadata.Artist)
 	if err != nil {
 		return false, fmt.Errorf("finding/creating artist: %w", err)
 	}
 
-	// Find or create album
-	album, err := s.findOrCreateAlbum(ctx, metadata, artist.ID, fileInfo.Path)
-	if err != nil {
-		return false, fmt.Errorf("finding/creating album: %w", err)
+	// Albums belong to the album artist, which differs from the track
+	// artist on compilations and featured appearances
+	albumArtist := artist
+	if metadata.AlbumArtist != "" && metadata.AlbumArtist != metadata.Artist {
+		albumArtist, err = s.findOrCreateArtist(ctx, metadata.AlbumArtist)
+		if err != nil {
+			return false, fmt.Errorf("finding/creating album artist: %w", err)
+		}
 	}
 
 	// Check if track exists
 	existingTrack, err := s.trackRepo.FindByFilePath(ctx, fileInfo.Path)
-	isNew := errors.Is(err, database.ErrTrackNotFound)
+	if err != nil && !errors.Is(err, database.ErrTrackNotFound) {
+		return false, fmt.Errorf("finding track: %w", err)
+	}
+
+	// The file used to be split by a cue sheet that is gone now
+	if existingTrack != nil && (existingTrack.StartOffset > 0 || existingTrack.EndOffset > 0) {
+		if _, err := s.trackRepo.DeleteFileSegmentsExcept(ctx, fileInfo.Path, nil); err != nil {
+			return false, err
+		}
+		existingTrack = nil
+	}
+
+	// Find or create album, leaving tracks already folded into Singles there
+	album := s.retainedSinglesAlbum(ctx, existingTrack, metadata, albumArtist.ID)
+	if album == nil {
+		album, err = s.findOrCreateAlbum(ctx, metadata, albumArtist.ID, fileInfo.Path)
+		if err != nil {
+			return false, fmt.Errorf("finding/creating album: %w", err)
+		}
+	}
 
 	// Create or update track
 	track := &models.Track{
-		Title:       metadata.Title,
-		Duration:    metadata.Duration,
-		TrackNumber: metadata.TrackNumber,
-		DiscNumber:  metadata.DiscNumber,
-		FilePath:    fileInfo.Path,
-		FileSize:    fileInfo.Size,
-		Format:      metadata.Format,
-		Bitrate:     metadata.Bitrate,
-		SampleRate:  metadata.SampleRate,
-		Channels:    metadata.Channels,
-		AlbumID:     album.ID,
-		ArtistID:    artist.ID,
-		Genre:       metadata.Genre,
-		Year:        metadata.Year,
-	}
-
-	if isNew {
+		Title:        metadata.Title,
+		Duration:     metadata.Duration,
+		TrackNumber:  metadata.TrackNumber,
+		DiscNumber:   metadata.DiscNumber,
+		TotalTracks:  metadata.TotalTracks,
+		TotalDiscs:   metadata.TotalDiscs,
+		FilePath:     fileInfo.Path,
+		FileSize:     fileInfo.Size,
+		FileHash:     fileInfo.Hash,
+		ModTime:      fileInfo.ModTime,
+		Format:       metadata.Format,
+		Lossless:     metadata.Lossless,
+		Bitrate:      metadata.Bitrate,
+		SampleRate:   metadata.SampleRate,
+		Channels:     metadata.Channels,
+		AlbumID:      album.ID,
+		ArtistID:     artist.ID,
+		Genre:        metadata.Genre,
+		Year:         metadata.Year,
+		BPM:          metadata.BPM,
+		MusicalKey:   metadata.MusicalKey,
+		Lyrics:       metadata.Lyrics,
+		TrackGain:    metadata.Gain.TrackGain,
+		AlbumGain:    metadata.Gain.AlbumGain,
+		EncoderDelay: metadata.Gain.EncoderDelay,
+		EncoderPad:   metadata.Gain.EncoderPadding,
+	}
+
+	isNew, err := s.saveTrack(ctx, track, existingTrack)
+	if err != nil {
+		return false, err
+	}
+
+	if len(metadata.Chapters) > 0 || existingTrack != nil {
+		if err := s.trackRepo.ReplaceChapters(ctx, track.ID, chapterModels(metadata.Chapters)); err != nil {
+			return isNew, err
+		}
+	}
+	return isNew, nil
+}
+
+// chapterModels converts extracted chapter markers for storage
+func chapterModels(chapters []scanner.Chapter) []models.Chapter {
+	stored := make([]models.Chapter, len(chapters))
+	for i, chapter := range chapters {
+		stored[i] = models.Chapter{
+			Title:     chapter.Title,
+			StartTime: int(chapter.Start.Milliseconds()),
+			EndTime:   int(chapter.End.Milliseconds()),
+		}
+	}
+	return stored
+}
+
+// processCueFile imports each cue sheet entry of a single-file album as its
+// own track sharing the file, with start/end offsets into it
+func (s *LibraryService) processCueFile(ctx context.Context, fileInfo scanner.FileInfo, metadata *scanner.TrackMetadata, sheet *scanner.CueSheet, cueTracks []scanner.CueTrack) (bool, error) {
+	// The cue sheet is usually more complete than the rip's tags
+	albumMeta := *metadata
+	if sheet.Title != "" {
+		albumMeta.Album = sheet.Title
+		albumMeta.Edition = ""
+		s.normalizeMetadata(&albumMeta)
+	}
+	if sheet.Year > 0 {
+		albumMeta.Year = sheet.Year
+	}
+	if sheet.Genre != "" {
+		albumMeta.Genre = sheet.Genre
+	}
+	albumArtistName := sheet.Performer
+	if albumArtistName == "" {
+		albumArtistName = metadata.AlbumArtist
+	}
+	if albumArtistName == "" {
+		albumArtistName = metadata.Artist
+	}
+
+	albumArtist, err := s.findOrCreateArtist(ctx, albumArtistName)
+	if err != nil {
+		return false, fmt.Errorf("finding/creating album artist: %w", err)
+	}
+
+	album, err := s.findOrCreateAlbum(ctx, &albumMeta, albumArtist.ID, fileInfo.Path)
+	if err != nil {
+		return false, fmt.Errorf("finding/creating album: %w", err)
+	}
+
+	// The file's gain was measured over the whole image, which makes it the
+	// album gain of every track cut from it
+	albumGain := metadata.Gain.AlbumGain
+	if albumGain == nil {
+		albumGain = metadata.Gain.TrackGain
+	}
+
+	anyNew := false
+	starts := make([]int, 0, len(cueTracks))
+	for i, cueTrack := range cueTracks {
+		artist := albumArtist
+		if cueTrack.Performer != "" && cueTrack.Performer != albumArtist.Name {
+			artist, err = s.findOrCreateArtist(ctx, cueTrack.Performer)
+			if err != nil {
+				return false, fmt.Errorf("finding/creating artist: %w", err)
+			}
+		}
+
+		startMs := int(cueTrack.Start.Milliseconds())
+		endMs := int(cueTrack.End.Milliseconds())
+		durationMs := endMs - startMs
+		if endMs == 0 {
+			durationMs = metadata.Duration*1000 - startMs
+		}
+		duration := (durationMs + 500) / 1000
+
+		title := cueTrack.Title
+		if title == "" {
+			title = fmt.Sprintf("Track %d", i+1)
+		}
+
+		track := &models.Track{
+			Title:       title,
+			Duration:    max(duration, 0),
+			TrackNumber: cueTrack.Number,
+			DiscNumber:  metadata.DiscNumber,
+			TotalTracks: len(cueTracks),
+			TotalDiscs:  metadata.TotalDiscs,
+			FilePath:    fileInfo.Path,
+			StartOffset: startMs,
+			EndOffset:   endMs,
+			FileSize:    fileInfo.Size,
+			FileHash:    fileInfo.Hash,
+			ModTime:     fileInfo.ModTime,
+			Format:      metadata.Format,
+			Lossless:    metadata.Lossless,
+			Bitrate:     metadata.Bitrate,
+			SampleRate:  metadata.SampleRate,
+			Channels:    metadata.Channels,
+			AlbumID:     album.ID,
+			ArtistID:    artist.ID,
+			Genre:       albumMeta.Genre,
+			Year:        albumMeta.Year,
+			AlbumGain:   albumGain,
+		}
+
+		existingTrack, err := s.trackRepo.FindByFileSegment(ctx, fileInfo.Path, startMs)
+		if err != nil && !errors.Is(err, database.ErrTrackNotFound) {
+			return false, fmt.Errorf("finding track: %w", err)
+		}
+
+		isNew, err := s.saveTrack(ctx, track, existingTrack)
+		if err != nil {
+			return false, err
+		}
+		anyNew = anyNew || isNew
+		starts = append(starts, startMs)
+	}
+
+	// Drop segments from an earlier version of the cue sheet
+	if _, err := s.trackRepo.DeleteFileSegmentsExcept(ctx, fileInfo.Path, starts); err != nil {
+		return false, err
+	}
+
+	return anyNew, nil
+}
+
+// saveTrack creates track, or updates existing in place while keeping the
+// user data and analysis results a rescan can't recover from the file
+func (s *LibraryService) saveTrack(ctx context.Context, track *models.Track, existing *models.Track) (bool, error) {
+	if existing != nil {
+		track.ID = existing.ID
+		track.CreatedAt = existing.CreatedAt
+		track.Rating = existing.Rating
+		track.PlayCount = existing.PlayCount
+		track.SkipCount = existing.SkipCount
+		track.LastPlayedAt = existing.LastPlayedAt
+		if track.AlbumID == existing.AlbumID {
+			track.AlbumOrder = existing.AlbumOrder
+			track.FoldedAlbum = existing.FoldedAlbum
+		}
+		if track.Genre == "" {
+			track.InferredGenre = existing.InferredGenre
+		}
+
+		// Keep earlier analysis results; detection is too slow to repeat every scan
+		if track.BPM == 0 {
+			track.BPM = existing.BPM
+		}
+		if track.MusicalKey == "" {
+			track.MusicalKey = existing.MusicalKey
+		}
+		// Files are analyzed once for each version, even when nothing is found
+		if existing.ModTime.Equal(track.ModTime) {
+			track.AnalyzedAt = existing.AnalyzedAt
+		} else {
+			// Transcodes of the earlier version would never be served again.
+			// Full scans only see the change here, and a file put back with
+			// an older modification time has changed too.
+			s.purgeTranscodes(track.FilePath)
+		}
+	}
+	if (track.BPM == 0 || track.MusicalKey == "") && track.AnalyzedAt.IsZero() && s.getOptions().AnalyzeAudio {
+		s.fillFromAnalysis(ctx, track)
+	}
+
+	if existing == nil {
 		track.ID = database.GenerateID()
 		if err := s.trackRepo.Create(ctx, track); err != nil {
 			return false, fmt.Errorf("creating track: %w", err)
 		}
-	} else {
-		track.ID = existingTrack.ID
-		track.CreatedAt = existingTrack.CreatedAt
-		if err := s.trackRepo.Update(ctx, track); err != nil {
-			return false, fmt.Errorf("updating track: %w", err)
+		return true, nil
+	}
+
+	if err := s.trackRepo.Update(ctx, track); err != nil {
+		return false, fmt.Errorf("updating track: %w", err)
+	}
+	return false, nil
+}
+
+// loadScanFailures returns the recorded failures of the discovered files. A
+// full scan also forgets failures of files that no longer exist.
+func (s *LibraryService) loadScanFailures(ctx context.Context, files []scanner.FileInfo, incremental bool) map[string]models.ScanFailure {
+	if s.failureRepo == nil {
+		return nil
+	}
+
+	failures, err := s.failureRepo.GetAll(ctx)
+	if err != nil {
+		slog.Warn("failed to load scan failures", "error", err)
+		return nil
+	}
+	if incremental {
+		return failures
+	}
+
+	discovered := make(map[string]bool, len(files))
+	for _, file := range files {
+		discovered[file.Path] = true
+	}
+	for path := range failures {
+		if !discovered[path] {
+			s.clearScanFailure(ctx, path)
+			delete(failures, path)
 		}
 	}
+	return failures
+}
 
-	return isNew, nil
+// skipFailedFiles drops files that reached the failure limit and haven't
+// changed since their last failure, unless force is set. Returns the files
+// to process and how many were skipped.
+func (s *LibraryService) skipFailedFiles(files []scanner.FileInfo, failures map[string]models.ScanFailure, force bool) ([]scanner.FileInfo, int) {
+	limit := s.getOptions().MaxScanFailures
+	if force || limit <= 0 || len(failures) == 0 {
+		return files, 0
+	}
+
+	kept := files[:0:0]
+	for _, file := range files {
+		failure, ok := failures[file.Path]
+		if ok && failure.FailCount >= limit && failure.ModTime.Equal(file.ModTime) {
+			slog.Debug("skipping repeatedly failing file", "path", file.Path, "failures", failure.FailCount)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, len(files) - len(kept)
+}
+
+// recordScanFailure counts a failed import of a file
+func (s *LibraryService) recordScanFailure(ctx context.Context, file scanner.FileInfo, err error) {
+	if s.failureRepo == nil {
+		return
+	}
+
+	failure, recordErr := s.failureRepo.Record(ctx, file.Path, file.ModTime, err.Error())
+	if recordErr != nil {
+		slog.Warn("failed to record scan failure", "path", file.Path, "error", recordErr)
+		return
+	}
+	if limit := s.getOptions().MaxScanFailures; limit > 0 && failure.FailCount == limit {
+		slog.Info("file will be skipped until it changes", "path", file.Path, "failures", failure.FailCount)
+	}
+}
+
+// clearScanFailure forgets the failures of a file
+func (s *LibraryService) clearScanFailure(ctx context.Context, path string) {
+	if err := s.failureRepo.Delete(ctx, path); err != nil {
+		slog.Warn("failed to clear scan failure", "path", path, "error", err)
+	}
+}
+
+// purgeTranscodes drops cached transcodes of a changed or deleted file when
+// enabled
+func (s *LibraryService) purgeTranscodes(path string) {
+	s.mu.RLock()
+	trans := s.transcoder
+	enabled := s.options.PurgeTranscodes
+	s.mu.RUnlock()
+
+	if enabled {
+		trans.PurgeSource(path)
+	}
+}
+
+// purgeContentTranscodes drops content-keyed transcodes of files that
+// changed or were deleted when enabled. They're shared by every file with the
+// same content, so they go once no track has that content any more.
+func (s *LibraryService) purgeContentTranscodes(ctx context.Context) {
+	s.mu.RLock()
+	trans := s.transcoder
+	enabled := s.options.PurgeTranscodes && s.options.HashFiles
+	s.mu.RUnlock()
+
+	if !enabled {
+		return
+	}
+	hashes, err := s.trackRepo.GetFileHashes(ctx)
+	if err != nil {
+		slog.Warn("purging content-keyed transcodes failed", "error", err)
+		return
+	}
+	trans.PurgeContentExcept(hashes)
+}
+
+// recordScanError adds a file error to the scan progress
+func (s *LibraryService) recordScanError(path, category string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.progress.ErrorsByCategory == nil {
+		s.progress.ErrorsByCategory = make(map[string]int)
+	}
+	s.progress.ErrorsByCategory[category]++
+
+	if len(s.progress.Errors) < maxScanErrors {
+		s.progress.Errors = append(s.progress.Errors, ScanFileError{
+			Path:     path,
+			Category: category,
+			Message:  err.Error(),
+		})
+	}
+}
+
+// fillFromProbe fills missing technical metadata using ffprobe
+func (s *LibraryService) fillFromProbe(ctx context.Context, path string, metadata *scanner.TrackMetadata) {
+	if metadata.Bitrate > 0 && metadata.SampleRate > 0 && metadata.Channels > 0 && metadata.Duration > 0 {
+		return
+	}
+
+	s.mu.RLock()
+	trans := s.transcoder
+	s.mu.RUnlock()
+	if !trans.IsAvailable() {
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	info, err := trans.ProbeAudio(probeCtx, path)
+	if err != nil {
+		slog.Debug("probe failed", "path", path, "error", err)
+		return
+	}
+
+	if metadata.Bitrate == 0 {
+		metadata.Bitrate = info.Bitrate
+	}
+	if metadata.SampleRate == 0 {
+		metadata.SampleRate = info.SampleRate
+	}
+	if metadata.Channels == 0 {
+		metadata.Channels = info.Channels
+	}
+	if metadata.Duration == 0 {
+		metadata.Duration = int(info.Duration + 0.5)
+	}
+	if transcoder.IsLosslessCodec(info.Codec) {
+		metadata.Lossless = true
+	}
+}
+
+// unknownNames returns the configured names for missing artists and albums
+func (s *LibraryService) unknownNames() (artist, album string) {
+	opts := s.getOptions()
+	artist, album = opts.UnknownArtist, opts.UnknownAlbum
+	if artist == "" {
+		artist = DefaultUnknownArtist
+	}
+	if album == "" {
+		album = DefaultUnknownAlbum
+	}
+	return artist, album
+}
+
+// fillUnknownNames files tracks without artist or album metadata under the
+// unknown artist and album, so no artist or album is created with an empty
+// name. The unknown album is shared per album artist, like any other title.
+func (s *LibraryService) fillUnknownNames(metadata *scanner.TrackMetadata) {
+	unknownArtist, unknownAlbum := s.unknownNames()
+
+	metadata.Artist = strings.TrimSpace(metadata.Artist)
+	metadata.AlbumArtist = strings.TrimSpace(metadata.AlbumArtist)
+	metadata.Album = strings.TrimSpace(metadata.Album)
+
+	if metadata.Artist == "" {
+		metadata.Artist = unknownArtist
+	}
+	if metadata.AlbumArtist == "" {
+		metadata.AlbumArtist = metadata.Artist
+	}
+	if metadata.Album == "" {
+		metadata.Album = unknownAlbum
+	}
+}
+
+// findOrCreateArtist finds or creates the artist of a name, matching
+// variants of it when ConsolidateArtists is set
+func (s *LibraryService) findOrCreateArtist(ctx context.Context, name string) (*models.Artist, error) {
+	if s.getOptions().ConsolidateArtists {
+		return s.artistRepo.FindOrCreateConsolidated(ctx, name)
+	}
+	return s.artistRepo.FindOrCreate(ctx, name)
 }
 
 // findOrCreateAlbum finds or creates an album
@@ -381,6 +1174,17 @@ func (s *LibraryService) findOrCreateAlbum(ctx context.Context, metadata *scanne
 	// Try to find existing album
 	album, err := s.albumRepo.FindByTitleAndArtist(ctx, metadata.Album, artistID)
 	if err == nil {
+		if album.Notes == "" && !album.NotesEdited && s.claimNotesCheck(album.ID) {
+			if notes := s.albumNotes(audioPath); notes != "" {
+				filled, err := s.albumRepo.FillNotes(ctx, album.ID, notes)
+				if err != nil {
+					return nil, err
+				}
+				if filled {
+					album.Notes = notes
+				}
+			}
+		}
 		return album, nil
 	}
 	if !errors.Is(err, database.ErrAlbumNotFound) {
@@ -391,7 +1195,9 @@ func (s *LibraryService) findOrCreateAlbum(ctx context.Context, metadata *scanne
 	album = &models.Album{
 		ID:       database.GenerateID(),
 		Title:    metadata.Album,
+		Edition:  metadata.Edition,
 		Year:     metadata.Year,
+		Notes:    s.albumNotes(audioPath),
 		ArtistID: artistID,
 	}
 
@@ -401,57 +1207,162 @@ func (s *LibraryService) findOrCreateAlbum(ctx context.Context, metadata *scanne
 
 	// Process artwork for new album
 	go func() {
-		slog.Debug("looking for artwork", "album", album.Title, "albumID", album.ID, "audioPath", audioPath)
-
-		artwork, err := s.artworkProcessor.FindArtwork(audioPath)
-		if err != nil {
-			slog.Debug("artwork search failed", "album", album.Title, "error", err)
-			return
-		}
-		if artwork == nil {
-			slog.Debug("no artwork found for album", "album", album.Title, "albumID", album.ID)
-			return
+		if _, err := s.cacheAlbumArtwork(context.Background(), album, audioPath); err != nil {
+			slog.Warn("failed to process artwork", "album", album.Title, "error", err)
 		}
+	}()
 
-		slog.Debug("found artwork", "album", album.Title, "source", artwork.Source, "mimeType", artwork.MIMEType, "dataSize", len(artwork.Data))
+	return album, nil
+}
 
-		paths, err := s.artworkProcessor.ProcessAndCache(artwork, album.ID)
-		if err != nil {
-			slog.Warn("failed to process artwork", "album", album.Title, "error", err)
-			return
-		}
+// claimNotesCheck reports whether an album's folder is still to be searched
+// for notes in the running scan, so it's searched for its first track only
+func (s *LibraryService) claimNotesCheck(albumID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notesChecked == nil {
+		return true
+	}
+	if s.notesChecked[albumID] {
+		return false
+	}
+	s.notesChecked[albumID] = true
+	return true
+}
 
-		slog.Info("artwork cached", "album", album.Title, "albumID", album.ID, "paths", len(paths))
+// albumNotes reads an album's liner notes from a text file next to one of
+// its audio files when AlbumNotesFromFiles is set
+func (s *LibraryService) albumNotes(audioPath string) string {
+	if !s.getOptions().AlbumNotesFromFiles {
+		return ""
+	}
+	return scanner.FindAlbumNotes(audioPath)
+}
 
-		if originalPath, ok := paths["original"]; ok {
-			album.CoverArtPath = originalPath
-			s.albumRepo.Update(context.Background(), album)
+// cacheAlbumArtwork finds artwork for an album from one of its files and
+// writes all cached sizes. Reports false when no artwork was found.
+func (s *LibraryService) cacheAlbumArtwork(ctx context.Context, album *models.Album, audioPath string) (bool, error) {
+	slog.Debug("looking for artwork", "album", album.Title, "albumID", album.ID, "audioPath", audioPath)
+
+	artwork, err := s.artworkProcessor.FindArtwork(audioPath)
+	if err != nil {
+		slog.Debug("artwork search failed", "album", album.Title, "error", err)
+		return false, nil
+	}
+	if artwork == nil {
+		artwork = s.extractVideoArtwork(audioPath)
+	}
+	if artwork == nil {
+		slog.Debug("no artwork found for album", "album", album.Title, "albumID", album.ID)
+		return false, nil
+	}
+
+	slog.Debug("found artwork", "album", album.Title, "source", artwork.Source, "mimeType", artwork.MIMEType, "dataSize", len(artwork.Data))
+
+	paths, err := s.artworkProcessor.ProcessAndCache(artwork, album.ID)
+	if err != nil {
+		return false, err
+	}
+
+	slog.Info("artwork cached", "album", album.Title, "albumID", album.ID, "paths", len(paths))
+
+	if originalPath, ok := paths["original"]; ok && originalPath != album.CoverArtPath {
+		album.CoverArtPath = originalPath
+		if err := s.albumRepo.UpdateCoverArt(ctx, album.ID, originalPath); err != nil {
+			return true, err
 		}
-	}()
+	}
+	return true, nil
+}
 
-	return album, nil
+// fillFromAnalysis detects BPM and key from the decoded audio. Up to a minute
+// from the middle of the track (or cue segment) is analyzed to skip intros
+// and outros. AnalyzedAt is set unless the scan was stopped first.
+func (s *LibraryService) fillFromAnalysis(ctx context.Context, track *models.Track) {
+	s.mu.RLock()
+	trans := s.transcoder
+	s.mu.RUnlock()
+	if !trans.IsAvailable() {
+		return
+	}
+
+	const window = 60
+	offset := track.StartOffset / 1000
+	if track.Duration > window*3/2 {
+		offset += track.Duration/2 - window/2
+	}
+
+	decodeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	samples, err := trans.DecodePCM(decodeCtx, track.FilePath, scanner.AnalysisSampleRate, offset, window)
+	if ctx.Err() != nil {
+		return
+	}
+	track.AnalyzedAt = time.Now()
+	if err != nil {
+		slog.Debug("audio analysis decode failed", "path", track.FilePath, "error", err)
+		return
+	}
+
+	analysis := scanner.Analyze(samples, scanner.AnalysisSampleRate)
+	if track.BPM == 0 {
+		track.BPM = int(math.Round(analysis.BPM))
+	}
+	if track.MusicalKey == "" {
+		track.MusicalKey = analysis.Key
+	}
 }
 
-// loadKnownFiles loads existing file paths and mod times from the database
-func (s *LibraryService) loadKnownFiles(ctx context.Context) error {
-	paths, err := s.trackRepo.GetAllFilePaths(ctx)
+// extractVideoArtwork uses ffmpeg to grab a frame as artwork when enabled
+func (s *LibraryService) extractVideoArtwork(path string) *scanner.ArtworkInfo {
+	s.mu.RLock()
+	enabled := s.options.ArtworkFromVideo
+	trans := s.transcoder
+	s.mu.RUnlock()
+
+	if !enabled || !trans.IsAvailable() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, err := trans.ExtractThumbnail(ctx, path)
 	if err != nil {
-		return err
+		slog.Debug("no video frame for artwork", "path", path, "error", err)
+		return nil
 	}
 
-	knownFiles := make(map[string]time.Time)
-	for _, path := range paths {
-		// We don't have mod times stored, so use zero time
-		// This means all files will be considered "modified"
-		knownFiles[path] = time.Time{}
+	return &scanner.ArtworkInfo{
+		Data:     data,
+		MIMEType: "image/jpeg",
+		Source:   "video",
+		Path:     path,
+	}
+}
+
+// loadKnownFiles loads existing file paths and mod times from the database.
+// Tracks stored before mod times were recorded have a zero time, so their
+// files count as modified and are rescanned once, which records it.
+func (s *LibraryService) loadKnownFiles(ctx context.Context) error {
+	knownFiles, err := s.trackRepo.GetAllFilePathsWithModTime(ctx)
+	if err != nil {
+		return err
 	}
 
 	s.scanner.SetKnownFiles(knownFiles)
 	return nil
 }
 
-// cleanupDeletedFiles removes database entries for files that no longer exist
+// cleanupDeletedFiles removes database entries for files that no longer exist.
+// Nothing is removed while the media root is unavailable, since every file
+// would look deleted.
 func (s *LibraryService) cleanupDeletedFiles(ctx context.Context) error {
+	if status := s.CheckMediaRoot(ctx); !status.Available {
+		return fmt.Errorf("skipping cleanup: %s", status.Error)
+	}
+
 	deleted, err := s.scanner.FindDeletedFiles(ctx)
 	if err != nil {
 		return err
@@ -463,6 +1374,7 @@ func (s *LibraryService) cleanupDeletedFiles(ctx context.Context) error {
 			slog.Warn("failed to delete track", "path", path, "error", err)
 			continue
 		}
+		s.purgeTranscodes(path)
 		deletedCount++
 	}
 
@@ -470,26 +1382,223 @@ func (s *LibraryService) cleanupDeletedFiles(ctx context.Context) error {
 	s.progress.DeletedTracks = deletedCount
 	s.mu.Unlock()
 
-	// Clean up empty albums and artists
 	if deletedCount > 0 {
-		albumsDeleted, err := s.albumRepo.DeleteEmpty(ctx)
-		if err != nil {
-			slog.Warn("failed to clean up empty albums", "error", err)
-		} else if albumsDeleted > 0 {
-			slog.Info("cleaned up empty albums", "count", albumsDeleted)
+		s.deleteEmptyAlbumsAndArtists(ctx)
+	}
+
+	return nil
+}
+
+// deleteEmptyAlbumsAndArtists removes albums and artists left without tracks
+func (s *LibraryService) deleteEmptyAlbumsAndArtists(ctx context.Context) {
+	albumsDeleted, err := s.albumRepo.DeleteEmpty(ctx)
+	if err != nil {
+		slog.Warn("failed to clean up empty albums", "error", err)
+	} else if albumsDeleted > 0 {
+		slog.Info("cleaned up empty albums", "count", albumsDeleted)
+	}
+
+	artistsDeleted, err := s.artistRepo.DeleteEmpty(ctx)
+	if err != nil {
+		slog.Warn("failed to clean up empty artists", "error", err)
+	} else if artistsDeleted > 0 {
+		slog.Info("cleaned up empty artists", "count", artistsDeleted)
+	}
+}
+
+// classifyAlbums infers the release type of every album from its tracks
+func (s *LibraryService) classifyAlbums(ctx context.Context) error {
+	stats, err := s.albumRepo.GetTrackStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := s.getOptions()
+	classifyOpts := scanner.ClassifyOptions{SingleTrackSingles: opts.SingleTrackSingles}
+
+	updated := 0
+	for _, stat := range stats {
+		albumType := scanner.ClassifyAlbum(scanner.AlbumTrackSummary{
+			Title:        stat.Title,
+			ArtistName:   stat.ArtistName,
+			TrackCount:   stat.TrackCount,
+			Duration:     stat.Duration,
+			LongestTrack: stat.LongestTrack,
+			ArtistCount:  stat.ArtistCount,
+		}, classifyOpts)
+		if stat.Title == SinglesAlbumTitle && opts.MinAlbumTracks > 0 {
+			albumType = models.AlbumTypeSingle
+		}
+		if albumType == stat.AlbumType {
+			continue
 		}
+		if err := s.albumRepo.UpdateType(ctx, stat.AlbumID, albumType); err != nil {
+			return err
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		slog.Info("classified albums", "count", updated)
+	}
+	return nil
+}
+
+// inferGenres sets the inferred genre of untagged tracks from their artist
+// when InferGenres is set, and clears inferred genres otherwise. Inferred
+// genres are kept apart from tagged ones and worked out again after every
+// scan, so newly tagged tracks change them even when incremental scans skip
+// the untagged files.
+func (s *LibraryService) inferGenres(ctx context.Context) error {
+	if !s.getOptions().InferGenres {
+		return s.trackRepo.ClearInferredGenres(ctx)
+	}
+
+	unknownArtist, _ := s.unknownNames()
+	inferred, err := s.trackRepo.InferGenresFromArtists(ctx, unknownArtist)
+	if err != nil {
+		return err
+	}
+	if inferred > 0 {
+		slog.Info("inferred track genres from artists", "count", inferred)
+	}
+	return nil
+}
+
+// SinglesAlbumTitle names the per-artist album that collects tracks from
+// albums below the MinAlbumTracks threshold
+const SinglesAlbumTitle = "Singles"
 
-		artistsDeleted, err := s.artistRepo.DeleteEmpty(ctx)
+// collectSingles moves the tracks of albums with fewer than MinAlbumTracks
+// tracks into their album artist's Singles album and removes the emptied
+// albums. Tracks folded earlier are moved back into their own album once it
+// would reach the threshold, or when folding is turned off.
+func (s *LibraryService) collectSingles(ctx context.Context) error {
+	minTracks := s.getOptions().MinAlbumTracks
+
+	moved, err := s.unfoldSingles(ctx, minTracks)
+	if err != nil {
+		return err
+	}
+
+	if minTracks > 0 {
+		stats, err := s.albumRepo.GetTrackStats(ctx)
 		if err != nil {
-			slog.Warn("failed to clean up empty artists", "error", err)
-		} else if artistsDeleted > 0 {
-			slog.Info("cleaned up empty artists", "count", artistsDeleted)
+			return err
+		}
+		for _, stat := range stats {
+			// Tracks without an album artist have no Singles album to go to
+			if stat.TrackCount >= minTracks || stat.Title == SinglesAlbumTitle || stat.ArtistID == "" {
+				continue
+			}
+
+			singles, err := s.findOrCreateSinglesAlbum(ctx, stat.ArtistID)
+			if err != nil {
+				return err
+			}
+			count, err := s.trackRepo.FoldIntoSingles(ctx, stat.AlbumID, stat.Title, singles.ID)
+			if err != nil {
+				return err
+			}
+			moved += int(count)
 		}
 	}
 
+	if moved > 0 {
+		if _, err := s.albumRepo.DeleteEmpty(ctx); err != nil {
+			return err
+		}
+		slog.Info("moved tracks between albums and singles", "tracks", moved, "minAlbumTracks", minTracks)
+	}
 	return nil
 }
 
+// unfoldSingles moves folded tracks back into their tagged album when,
+// together with the tracks it has already, they reach minTracks. Returns the
+// number of tracks moved.
+func (s *LibraryService) unfoldSingles(ctx context.Context, minTracks int) (int, error) {
+	groups, err := s.trackRepo.GetFoldedGroups(ctx)
+	if err != nil || len(groups) == 0 {
+		return 0, err
+	}
+
+	stats, err := s.albumRepo.GetTrackStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	trackCounts := make(map[string]int, len(stats))
+	for _, stat := range stats {
+		trackCounts[stat.AlbumID] = stat.TrackCount
+	}
+
+	moved := 0
+	for _, group := range groups {
+		total := group.TrackCount
+		album, err := s.albumRepo.FindByTitleAndArtist(ctx, group.Title, group.ArtistID)
+		switch {
+		case err == nil:
+			total += trackCounts[album.ID]
+		case !errors.Is(err, database.ErrAlbumNotFound):
+			return moved, err
+		}
+		if minTracks > 0 && total < minTracks {
+			continue
+		}
+
+		if album == nil {
+			metadata := &scanner.TrackMetadata{Album: group.Title, Year: group.Year}
+			album, err = s.findOrCreateAlbum(ctx, metadata, group.ArtistID, group.FilePath)
+			if err != nil {
+				return moved, fmt.Errorf("finding/creating album: %w", err)
+			}
+		}
+		count, err := s.trackRepo.UnfoldSingles(ctx, group.SinglesID, group.Title, album.ID)
+		if err != nil {
+			return moved, err
+		}
+		moved += int(count)
+	}
+	return moved, nil
+}
+
+// findOrCreateSinglesAlbum returns an artist's Singles album, creating it if needed
+func (s *LibraryService) findOrCreateSinglesAlbum(ctx context.Context, artistID string) (*models.Album, error) {
+	album, err := s.albumRepo.FindByTitleAndArtist(ctx, SinglesAlbumTitle, artistID)
+	if err == nil {
+		return album, nil
+	}
+	if !errors.Is(err, database.ErrAlbumNotFound) {
+		return nil, err
+	}
+
+	album = &models.Album{
+		ID:        database.GenerateID(),
+		Title:     SinglesAlbumTitle,
+		AlbumType: models.AlbumTypeSingle,
+		ArtistID:  artistID,
+	}
+	if err := s.albumRepo.Create(ctx, album); err != nil {
+		return nil, fmt.Errorf("creating singles album: %w", err)
+	}
+	return album, nil
+}
+
+// retainedSinglesAlbum returns the Singles album an existing track was folded
+// into, as long as it's still tagged with the album it was folded from. This
+// keeps rescans from recreating the small album only to fold it again;
+// collectSingles moves the track back out once the album is big enough.
+func (s *LibraryService) retainedSinglesAlbum(ctx context.Context, existing *models.Track, metadata *scanner.TrackMetadata, artistID string) *models.Album {
+	if existing == nil || existing.FoldedAlbum == "" || existing.FoldedAlbum != metadata.Album {
+		return nil
+	}
+
+	album, err := s.albumRepo.FindByID(ctx, existing.AlbumID)
+	if err != nil || album.Title != SinglesAlbumTitle || album.ArtistID != artistID {
+		return nil
+	}
+	return album
+}
+
 // CancelScan cancels the current scan
 func (s *LibraryService) CancelScan() error {
 	s.mu.Lock()
@@ -510,26 +1619,30 @@ func (s *LibraryService) setStatus(status ScanStatus) {
 	s.mu.Unlock()
 }
 
-// GetStats returns library statistics
+// GetStats returns library statistics. With StatsCounters they come from the
+// running totals, otherwise they are counted from the tables.
 func (s *LibraryService) GetStats(ctx context.Context) (*LibraryStats, error) {
-	trackCount, err := s.trackRepo.Count(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("counting tracks: %w", err)
+	var totals *models.LibraryTotals
+	var err error
+	if s.getOptions().StatsCounters {
+		totals, err = s.trackRepo.LibraryTotals(ctx)
+	} else {
+		totals, err = s.trackRepo.CountLibraryTotals(ctx)
 	}
-
-	albumCount, err := s.albumRepo.Count(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("counting albums: %w", err)
+		return nil, err
 	}
-
-	artistCount, err := s.artistRepo.Count(ctx)
+	lastScanAt, err := s.lastScanAt(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("counting artists: %w", err)
+		return nil, err
 	}
 
 	return &LibraryStats{
-		TotalTracks:  trackCount,
-		TotalAlbums:  albumCount,
-		TotalArtists: artistCount,
+		TotalTracks:   totals.TrackCount,
+		TotalAlbums:   totals.AlbumCount,
+		TotalArtists:  totals.ArtistCount,
+		TotalDuration: totals.TotalDuration,
+		TotalSize:     totals.TotalSize,
+		LastScanAt:    lastScanAt,
 	}, nil
 }
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSidecarLyricsRescan(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{})
+	audio := lib.addFile("Band/First/01 - One.mp3", nil)
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(audio, past, past); err != nil {
+		t.Fatal(err)
+	}
+	lib.scan(false)
+
+	lyrics := func() string {
+		t.Helper()
+		var lyrics string
+		lib.db.DB.Raw("SELECT lyrics FROM tracks WHERE title = 'One'").Scan(&lyrics)
+		return lyrics
+	}
+
+	// Steps run in order; the audio file itself never changes
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"added", "[00:01.00]First words", "[00:01.00]First words"},
+		{"edited", "[00:01.00]Better words", "[00:01.00]Better words"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lrc := lib.addFile("Band/First/01 - One.lrc", []byte(tt.content))
+			modTime := past.Add(time.Duration(i+1) * time.Minute)
+			if err := os.Chtimes(lrc, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			lib.scan(true)
+			if got := lyrics(); got != tt.want {
+				t.Errorf("lyrics = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
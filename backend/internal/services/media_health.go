@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// ErrMediaRootUnavailable is returned when the media root is missing or unreadable
+var ErrMediaRootUnavailable = errors.New("media root unavailable")
+
+// MediaRootStatus describes whether the media root is mounted and readable
+type MediaRootStatus struct {
+	Path      string    `json:"path"`
+	Available bool      `json:"available"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// CheckMediaRoot verifies the media root is a readable directory and records
+// the result. An empty root is treated as unavailable while the library has
+// tracks, since that is what an unmounted network volume looks like.
+// Availability changes are announced as media_root_unavailable and
+// media_root_available events.
+func (s *LibraryService) CheckMediaRoot(ctx context.Context) MediaRootStatus {
+	status := MediaRootStatus{
+		Path:      s.mediaRoot,
+		Available: true,
+		CheckedAt: time.Now(),
+	}
+	if err := s.probeMediaRoot(ctx); err != nil {
+		status.Available = false
+		status.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	previous := s.rootStatus
+	s.rootStatus = status
+	s.mu.Unlock()
+
+	// The first check only establishes a baseline unless the root is missing
+	changed := previous.CheckedAt.IsZero() && !status.Available ||
+		!previous.CheckedAt.IsZero() && previous.Available != status.Available
+	if changed {
+		if status.Available {
+			slog.Info("media root available again", "path", status.Path)
+			s.emitMediaRootEvent("media_root_available", status)
+		} else {
+			slog.Warn("media root unavailable", "path", status.Path, "error", status.Error)
+			s.emitMediaRootEvent("media_root_unavailable", status)
+		}
+	}
+
+	return status
+}
+
+// MediaRootStatus returns the result of the most recent media root check
+func (s *LibraryService) MediaRootStatus() MediaRootStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rootStatus
+}
+
+// StartMediaMonitor checks the media root immediately and then every
+// interval until Close is called
+func (s *LibraryService) StartMediaMonitor(interval time.Duration) {
+	s.CheckMediaRoot(context.Background())
+	if interval <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stopMonitor != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stopMonitor = stop
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.CheckMediaRoot(context.Background())
+			}
+		}
+	}()
+}
+
+// Close stops background work started by the service
+func (s *LibraryService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scanQueue = nil
+	s.stopMissingChecks()
+	if s.stopMonitor != nil {
+		close(s.stopMonitor)
+		s.stopMonitor = nil
+	}
+	if s.stopSchedule != nil {
+		close(s.stopSchedule)
+		s.stopSchedule = nil
+	}
+}
+
+// probeMediaRoot returns why the media root can't be used, or nil
+func (s *LibraryService) probeMediaRoot(ctx context.Context) error {
+	dir, err := os.Open(s.mediaRoot)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMediaRootUnavailable, err)
+	}
+	defer dir.Close()
+
+	info, err := dir.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMediaRootUnavailable, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: not a directory", ErrMediaRootUnavailable)
+	}
+
+	if _, err := dir.Readdirnames(1); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: %v", ErrMediaRootUnavailable, err)
+		}
+		if count, err := s.trackRepo.Count(ctx); err == nil && count > 0 {
+			return fmt.Errorf("%w: directory is empty but the library has %d tracks", ErrMediaRootUnavailable, count)
+		}
+	}
+
+	return nil
+}
+
+// emitMediaRootEvent notifies event handlers about a media root change
+func (s *LibraryService) emitMediaRootEvent(eventType string, status MediaRootStatus) {
+	s.mu.RLock()
+	progress := s.progress.snapshot()
+	s.mu.RUnlock()
+
+	event := ScanEvent{
+		Type:      eventType,
+		Progress:  progress,
+		MediaRoot: &status,
+	}
+
+	s.dispatch(event)
+}
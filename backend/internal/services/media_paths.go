@@ -0,0 +1,40 @@
+package services
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"harmony/internal/scanner"
+)
+
+// SetMediaPaths limits scans to the folders picked during setup. Paths
+// outside the media root are ignored; with none left, scans cover the whole
+// media root again. Files found in several of the folders are scanned once.
+func (s *LibraryService) SetMediaPaths(paths []string) {
+	mediaRoot, err := filepath.Abs(s.mediaRoot)
+	if err != nil {
+		slog.Warn("resolving media root failed", "error", err)
+		return
+	}
+
+	roots := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil || !scanner.IsWithinDir(mediaRoot, absPath) {
+			slog.Warn("ignoring media path outside the media root", "path", path)
+			continue
+		}
+		// Keep discovered paths in the form a scan of the whole root gives,
+		// so tracks already stored under them are recognized
+		rel, err := filepath.Rel(mediaRoot, absPath)
+		if err != nil {
+			continue
+		}
+		roots = append(roots, filepath.Join(s.mediaRoot, rel))
+	}
+
+	s.scanner.SetRoots(roots)
+	if len(roots) > 0 {
+		slog.Info("scanning selected media paths", "paths", roots)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sort"
+)
+
+// MissingArtworkItem is an album without a cached cover or an artist without
+// an image
+type MissingArtworkItem struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ArtistID   string `json:"artistId,omitempty"`
+	ArtistName string `json:"artistName,omitempty"`
+}
+
+// MissingArtwork lists albums without cached cover art and artists with
+// neither an image URL nor a cached image, albums first, each sorted by name
+func (s *LibraryService) MissingArtwork(ctx context.Context) (albums, artists []MissingArtworkItem, err error) {
+	artistRows, err := s.artistRepo.ListNames(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	artistNames := make(map[string]string, len(artistRows))
+	for _, artist := range artistRows {
+		artistNames[artist.ID] = artist.Name
+	}
+
+	albumRows, err := s.albumRepo.ListTitles(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	albums = []MissingArtworkItem{}
+	for _, album := range albumRows {
+		if s.artworkProcessor.ArtworkExists(album.ID) {
+			continue
+		}
+		albums = append(albums, MissingArtworkItem{
+			Type:       "album",
+			ID:         album.ID,
+			Name:       album.Title,
+			ArtistID:   album.ArtistID,
+			ArtistName: artistNames[album.ArtistID],
+		})
+	}
+
+	imageless, err := s.artistRepo.ListWithoutImage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	artists = []MissingArtworkItem{}
+	for _, artist := range imageless {
+		if _, err := os.Stat(s.artworkProcessor.GetArtistImagePath(artist.ID, "original")); err == nil {
+			continue
+		}
+		artists = append(artists, MissingArtworkItem{
+			Type: "artist",
+			ID:   artist.ID,
+			Name: artist.Name,
+		})
+	}
+
+	sortMissingArtwork(albums)
+	sortMissingArtwork(artists)
+	return albums, artists, nil
+}
+
+// sortMissingArtwork orders items by name, then ID for a stable page order
+func sortMissingArtwork(items []MissingArtworkItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].ID < items[j].ID
+	})
+}
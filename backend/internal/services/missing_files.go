@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// missingFileVerifyDelay is how long a file must stay missing after a stream
+// found it gone before its tracks are removed, so a volume being remounted or
+// a file being replaced isn't taken for a deletion
+const missingFileVerifyDelay = time.Minute
+
+// VerifyMissingFile schedules a check of a file a stream couldn't find. When
+// the media root is available and the file is still missing after
+// missingFileVerifyDelay, its tracks are removed as a full scan would. It
+// reports whether a check was scheduled: nothing is done with
+// VerifyMissingFiles off, while the media root is known to be unavailable or
+// when a check of the file is already pending.
+func (s *LibraryService) VerifyMissingFile(path string) bool {
+	if !s.getOptions().VerifyMissingFiles {
+		return false
+	}
+	if status := s.MediaRootStatus(); !status.CheckedAt.IsZero() && !status.Available {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, pending := s.missingChecks[path]; pending {
+		return false
+	}
+	if s.missingChecks == nil {
+		s.missingChecks = make(map[string]*time.Timer)
+	}
+	s.missingChecks[path] = time.AfterFunc(missingFileVerifyDelay, func() {
+		s.verifyMissingFile(context.Background(), path)
+	})
+	slog.Info("scheduled missing file check", "path", path, "delay", missingFileVerifyDelay)
+	return true
+}
+
+// verifyMissingFile removes the tracks of a file that is still missing while
+// the media root is available
+func (s *LibraryService) verifyMissingFile(ctx context.Context, path string) {
+	s.mu.Lock()
+	delete(s.missingChecks, path)
+	s.mu.Unlock()
+
+	if status := s.CheckMediaRoot(ctx); !status.Available {
+		slog.Info("skipping missing file check, media root unavailable", "path", path)
+		return
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return
+	}
+
+	if err := s.trackRepo.DeleteByFilePath(ctx, path); err != nil {
+		slog.Warn("failed to delete track", "path", path, "error", err)
+		return
+	}
+	s.purgeTranscodes(path)
+	s.purgeContentTranscodes(ctx)
+	slog.Info("removed tracks of missing file", "path", path)
+
+	s.deleteEmptyAlbumsAndArtists(ctx)
+}
+
+// stopMissingChecks cancels the pending missing file checks. The caller must
+// hold s.mu.
+func (s *LibraryService) stopMissingChecks() {
+	for path, timer := range s.missingChecks {
+		timer.Stop()
+		delete(s.missingChecks, path)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyMissingFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		options LibraryOptions
+		// setup runs after the scan, with the path of the file a stream
+		// will find missing
+		setup     func(lib *testLibrary, gone string)
+		scheduled bool
+		tracks    int64
+	}{
+		{"disabled", LibraryOptions{}, func(lib *testLibrary, gone string) {
+			os.Remove(gone)
+		}, false, 2},
+		{"still missing", LibraryOptions{VerifyMissingFiles: true}, func(lib *testLibrary, gone string) {
+			os.Remove(gone)
+		}, true, 1},
+		{"back before the check", LibraryOptions{VerifyMissingFiles: true}, func(lib *testLibrary, gone string) {}, true, 2},
+		{"media root emptied", LibraryOptions{VerifyMissingFiles: true}, func(lib *testLibrary, gone string) {
+			os.RemoveAll(filepath.Join(lib.mediaRoot, "Band"))
+		}, true, 2},
+		{"media root known unavailable", LibraryOptions{VerifyMissingFiles: true}, func(lib *testLibrary, gone string) {
+			os.RemoveAll(filepath.Join(lib.mediaRoot, "Band"))
+			lib.service.CheckMediaRoot(context.Background())
+		}, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newTestLibrary(t, tt.options)
+			t.Cleanup(lib.service.Close)
+			lib.addFile("Band/Album/01 - Kept.mp3", nil)
+			gone := lib.addFile("Band/Album/02 - Gone.mp3", nil)
+			lib.scan(false)
+			tt.setup(lib, gone)
+
+			if scheduled := lib.service.VerifyMissingFile(gone); scheduled != tt.scheduled {
+				t.Fatalf("VerifyMissingFile = %v, want %v", scheduled, tt.scheduled)
+			}
+			if !tt.scheduled {
+				lib.service.mu.RLock()
+				pending := len(lib.service.missingChecks)
+				lib.service.mu.RUnlock()
+				if pending != 0 {
+					t.Errorf("%d checks pending, want none", pending)
+				}
+				return
+			}
+
+			// Nothing is removed until the check runs, and a file is only
+			// checked once at a time
+			if lib.service.VerifyMissingFile(gone) {
+				t.Error("a second check of the file was scheduled")
+			}
+			if tracks := countTracks(lib); tracks != 2 {
+				t.Fatalf("library has %d tracks before the check, want 2", tracks)
+			}
+
+			lib.service.verifyMissingFile(context.Background(), gone)
+			if tracks := countTracks(lib); tracks != tt.tracks {
+				t.Errorf("library has %d tracks after the check, want %d", tracks, tt.tracks)
+			}
+			lib.service.mu.RLock()
+			_, pending := lib.service.missingChecks[gone]
+			lib.service.mu.RUnlock()
+			if pending {
+				t.Error("check still pending after it ran")
+			}
+		})
+	}
+}
+
+// countTracks returns the number of tracks in the library
+func countTracks(lib *testLibrary) int64 {
+	var tracks int64
+	lib.db.DB.Raw("SELECT COUNT(*) FROM tracks").Scan(&tracks)
+	return tracks
+}
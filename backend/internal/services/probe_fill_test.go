@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"harmony/internal/scanner"
+	"harmony/internal/transcoder"
+)
+
+func TestFillFromProbe(t *testing.T) {
+	// Stand-ins for ffmpeg and the ffprobe next to it, which reports a FLAC
+	// stream and counts its runs
+	dir := t.TempDir()
+	probes := filepath.Join(dir, "probes")
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	ffprobe := filepath.Join(dir, "ffprobe")
+	scripts := map[string]string{
+		ffmpeg: `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+`,
+		ffprobe: `#!/bin/sh
+echo probe >> ` + probes + `
+cat <<'EOF'
+{"streams": [{"codec_type": "audio", "codec_name": "flac", "sample_rate": "48000", "channels": 2}],
+ "format": {"format_name": "flac", "duration": "100.400000", "bit_rate": "256000"}}
+EOF
+`,
+	}
+	for path, script := range scripts {
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: ffmpeg, CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+
+	lib := newTestLibrary(t, LibraryOptions{})
+	lib.service.SetTranscoder(trans)
+	path := lib.addFile("Artist/Album/01 - Song.flac", nil)
+
+	tests := []struct {
+		name      string
+		metadata  scanner.TrackMetadata
+		want      scanner.TrackMetadata
+		wantProbe bool
+	}{
+		{
+			name:      "missing bitrate",
+			metadata:  scanner.TrackMetadata{Title: "Song", Duration: 200, SampleRate: 44100, Channels: 1},
+			want:      scanner.TrackMetadata{Title: "Song", Duration: 200, Bitrate: 256, SampleRate: 44100, Channels: 1, Lossless: true},
+			wantProbe: true,
+		},
+		{
+			name:      "nothing from the tags",
+			metadata:  scanner.TrackMetadata{Title: "Song"},
+			want:      scanner.TrackMetadata{Title: "Song", Duration: 100, Bitrate: 256, SampleRate: 48000, Channels: 2, Lossless: true},
+			wantProbe: true,
+		},
+		{
+			name:     "complete tags",
+			metadata: scanner.TrackMetadata{Title: "Song", Duration: 200, Bitrate: 320, SampleRate: 44100, Channels: 2},
+			want:     scanner.TrackMetadata{Title: "Song", Duration: 200, Bitrate: 320, SampleRate: 44100, Channels: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(probes)
+			metadata := tt.metadata
+			lib.service.fillFromProbe(context.Background(), path, &metadata)
+
+			if !reflect.DeepEqual(metadata, tt.want) {
+				t.Errorf("metadata = %+v\nwant       %+v", metadata, tt.want)
+			}
+			data, _ := os.ReadFile(probes)
+			if probed := strings.Count(string(data), "probe\n") > 0; probed != tt.wantProbe {
+				t.Errorf("probed = %v, want %v", probed, tt.wantProbe)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"harmony/internal/transcoder"
+)
+
+func TestPurgeTranscodesOfChangedFiles(t *testing.T) {
+	// A stand-in for ffmpeg writing its output file, the last argument
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+if [ "$2" = "-encoders" ]; then printf ' ------\n A..... libmp3lame MP3\n'; exit 0; fi
+for out; do :; done
+echo transcoded > "$out"
+`
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	trans, err := transcoder.New(transcoder.Config{FFmpegPath: ffmpeg, CacheDir: t.TempDir(), MaxCacheGB: 1})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(trans.Close)
+
+	lib := newTestLibrary(t, LibraryOptions{PurgeTranscodes: true})
+	lib.service.SetTranscoder(trans)
+	path := lib.addFile("Artist/Album/01 - First.mp3", nil)
+	lib.scan(false)
+
+	// Steps run in order, each with a freshly cached transcode
+	tests := []struct {
+		name        string
+		modTime     time.Time // set on the file when not zero
+		incremental bool
+		wantPurged  bool
+	}{
+		{"unchanged file", time.Time{}, false, false},
+		{"newer file, full scan", time.Now().Add(time.Hour), false, true},
+		{"newer file, incremental scan", time.Now().Add(2 * time.Hour), true, true},
+		{"file put back with an older time", time.Now().Add(-time.Hour), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, err := trans.TranscodeAndCache(context.Background(), path, "", transcoder.ProfileHigh)
+			if err != nil {
+				t.Fatalf("TranscodeAndCache: %v", err)
+			}
+			if !tt.modTime.IsZero() {
+				if err := os.Chtimes(path, tt.modTime, tt.modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lib.scan(tt.incremental)
+
+			_, err = os.Stat(cached)
+			if purged := os.IsNotExist(err); purged != tt.wantPurged {
+				t.Errorf("transcode purged = %v, want %v", purged, tt.wantPurged)
+			}
+		})
+	}
+}
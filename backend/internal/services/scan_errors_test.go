@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestScanErrorPermission(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads files whatever their permissions")
+	}
+
+	lib := newTestLibrary(t, LibraryOptions{})
+	lib.addFile("Band/First/01 - One.mp3", nil)
+	locked := lib.addFile("Band/First/02 - Two.mp3", nil)
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0644) })
+	lib.scan(false)
+
+	progress := lib.service.GetProgress()
+	if got := progress.ErrorsByCategory[ScanErrorPermission]; got != 1 {
+		t.Errorf("permission errors = %d, want 1 (by category: %v)", got, progress.ErrorsByCategory)
+	}
+	if len(progress.Errors) != 1 || progress.Errors[0].Path != locked || progress.Errors[0].Category != ScanErrorPermission {
+		t.Errorf("errors = %+v, want one permission error for %s", progress.Errors, locked)
+	}
+	if got := countTracks(lib); got != 1 {
+		t.Errorf("%d tracks imported, want the readable one", got)
+	}
+}
+
+func TestCategorizeScanError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unreadable file", fmt.Errorf("%w: opening file: %w", errMetadata, &fs.PathError{Op: "open", Path: "/a.mp3", Err: syscall.EACCES}), ScanErrorPermission},
+		{"vanished file", fmt.Errorf("%w: opening file: %w", errMetadata, &fs.PathError{Op: "open", Path: "/a.mp3", Err: syscall.ENOENT}), ScanErrorIO},
+		{"read failure", fmt.Errorf("hashing: %w", syscall.EIO), ScanErrorIO},
+		{"bad tags", fmt.Errorf("%w: %w", errMetadata, errors.New("invalid frame")), ScanErrorParse},
+		{"database", errors.New("creating track: disk I/O error"), ScanErrorDatabase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorizeScanError(tt.err); got != tt.want {
+				t.Errorf("category = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+//go:build unix
+
+package services
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRepeatedScanFailures(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{MaxScanFailures: 2})
+	lib.addFile("Band/Album/01 - Good.mp3", nil)
+	// A socket is discovered like any file but can never be opened
+	bad := lib.addFile("Band/Album/02 - Bad.mp3", nil)
+	if err := os.Remove(bad); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	modTime := time.Now().Add(-time.Hour)
+	touch := func() {
+		modTime = modTime.Add(time.Minute)
+		if err := os.Chtimes(bad, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch()
+
+	// Steps run in order against the same library
+	tests := []struct {
+		name     string
+		before   func()
+		run      func(context.Context) error
+		errors   int
+		skipped  int
+		failures int
+	}{
+		{"first failure", nil, lib.service.FullScan, 1, 0, 1},
+		{"second failure", nil, lib.service.FullScan, 1, 0, 2},
+		{"skipped at the limit", nil, lib.service.FullScan, 0, 1, 2},
+		{"skipped by incremental scans", nil, lib.service.IncrementalScan, 0, 1, 2},
+		{"retried once changed", touch, lib.service.FullScan, 1, 0, 1},
+		{"counting again", nil, lib.service.FullScan, 1, 0, 2},
+		{"retried when forced", nil, func(ctx context.Context) error {
+			return lib.service.ForceScan(ctx, false)
+		}, 1, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			if err := tt.run(context.Background()); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			progress := lib.service.GetProgress()
+			if progress.ErrorCount != tt.errors || progress.SkippedFiles != tt.skipped {
+				t.Errorf("scan had %d errors and skipped %d files, want %d and %d",
+					progress.ErrorCount, progress.SkippedFiles, tt.errors, tt.skipped)
+			}
+			var failures int
+			lib.db.DB.Raw("SELECT fail_count FROM scan_failures WHERE path = ?", bad).Scan(&failures)
+			if failures != tt.failures {
+				t.Errorf("recorded %d failures, want %d", failures, tt.failures)
+			}
+			var tracks int64
+			lib.db.DB.Raw("SELECT COUNT(*) FROM tracks").Scan(&tracks)
+			if tracks != 1 {
+				t.Errorf("library has %d tracks, want 1", tracks)
+			}
+		})
+	}
+}
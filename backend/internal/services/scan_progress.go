@@ -0,0 +1,67 @@
+package services
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// progressEventFiles is how many processed files pass between scan_progress
+// events, before time-based throttling
+const progressEventFiles = 10
+
+// scanCounters holds the tallies workers update while files are processed.
+// They are atomic so workers and progress readers don't contend for the
+// service lock.
+type scanCounters struct {
+	processed   atomic.Int64
+	newTracks   atomic.Int64
+	updated     atomic.Int64
+	errors      atomic.Int64
+	currentFile atomic.Pointer[string]
+
+	// lastEvent is when the last scan_progress event was sent, in UnixNano
+	lastEvent atomic.Int64
+}
+
+// apply copies the counters into progress
+func (c *scanCounters) apply(p *ScanProgress) {
+	p.ProcessedFiles = int(c.processed.Load())
+	p.NewTracks = int(c.newTracks.Load())
+	p.UpdatedTracks = int(c.updated.Load())
+	p.ErrorCount = int(c.errors.Load())
+	if file := c.currentFile.Load(); file != nil {
+		p.CurrentFile = *file
+	}
+}
+
+// shouldEmit reports whether a scan_progress event is due after processed
+// files. Events go out every progressEventFiles files but no more often than
+// interval; the last file always gets one. Only one worker wins each slot.
+func (c *scanCounters) shouldEmit(processed, total int64, interval time.Duration, now time.Time) bool {
+	if processed == total {
+		c.lastEvent.Store(now.UnixNano())
+		return true
+	}
+	if processed%progressEventFiles != 0 {
+		return false
+	}
+
+	last := c.lastEvent.Load()
+	if last != 0 && now.UnixNano()-last < int64(interval) {
+		return false
+	}
+	return c.lastEvent.CompareAndSwap(last, now.UnixNano())
+}
+
+// progressSnapshot returns a copy of the progress including the live
+// counters of the files being processed and the scans queued after it.
+// Callers must hold s.mu.
+func (s *LibraryService) progressSnapshot() ScanProgress {
+	progress := s.progress.snapshot()
+	progress.QueuedScans = len(s.scanQueue)
+	progress.Queue = s.queuedScans()
+	if s.counters != nil {
+		s.counters.apply(&progress)
+	}
+	return progress
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProgressThrottle(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Files finish one a millisecond
+	tests := []struct {
+		name     string
+		interval time.Duration
+		total    int64
+		want     []int64
+	}{
+		{"count only", 0, 35, []int64{10, 20, 30, 35}},
+		{"throttled", 25 * time.Millisecond, 75, []int64{10, 40, 70, 75}},
+		{"slower than the scan", time.Hour, 75, []int64{10, 75}},
+		{"fewer files than a slot", time.Hour, 4, []int64{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counters scanCounters
+			var emitted []int64
+			for processed := int64(1); processed <= tt.total; processed++ {
+				now := start.Add(time.Duration(processed) * time.Millisecond)
+				if counters.shouldEmit(processed, tt.total, tt.interval, now) {
+					emitted = append(emitted, processed)
+				}
+			}
+			if !slices.Equal(emitted, tt.want) {
+				t.Errorf("events after %v files, want %v", emitted, tt.want)
+			}
+		})
+	}
+
+	t.Run("one worker per slot", func(t *testing.T) {
+		var counters scanCounters
+		var wins atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if counters.shouldEmit(10, 100, time.Hour, start) {
+					wins.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if wins.Load() != 1 {
+			t.Errorf("%d workers sent the event, want 1", wins.Load())
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// scanRequest is a scan waiting in the queue
+type scanRequest struct {
+	incremental bool
+	force       bool
+}
+
+// QueuedScan is a scan waiting in the queue, as shown in the scan status
+type QueuedScan struct {
+	Status   ScanStatus `json:"status"`
+	Type     string     `json:"type"`
+	Force    bool       `json:"force,omitempty"`
+	Position int        `json:"position"`
+}
+
+// queuedScans lists the scans waiting in the queue in the order they will
+// run. The caller must hold s.mu.
+func (s *LibraryService) queuedScans() []QueuedScan {
+	if len(s.scanQueue) == 0 {
+		return nil
+	}
+	queue := make([]QueuedScan, len(s.scanQueue))
+	for i, request := range s.scanQueue {
+		scanType := "full"
+		if request.incremental {
+			scanType = "incremental"
+		}
+		queue[i] = QueuedScan{
+			Status:   ScanStatusQueued,
+			Type:     scanType,
+			Force:    request.force,
+			Position: i + 1,
+		}
+	}
+	return queue
+}
+
+// StartScan starts a scan in the background. While another scan runs the
+// request is queued to run after it, as long as the queue (ScanQueueDepth)
+// has room; a request identical to one already queued shares its place. It
+// returns ScanStatusScanning or ScanStatusQueued along with the 1-based
+// queue position, or ErrScanInProgress/ErrScanQueueFull when the request
+// was rejected.
+func (s *LibraryService) StartScan(incremental, force bool) (ScanStatus, int, error) {
+	request := scanRequest{incremental: incremental, force: force}
+
+	s.mu.Lock()
+	if !s.scanning {
+		s.scanning = true
+		s.mu.Unlock()
+
+		// The scan outlives the request that started it
+		go s.runQueuedScan(request)
+		return ScanStatusScanning, 0, nil
+	}
+	defer s.mu.Unlock()
+
+	for i, queued := range s.scanQueue {
+		if queued == request {
+			return ScanStatusQueued, i + 1, nil
+		}
+	}
+
+	depth := s.options.ScanQueueDepth
+	if depth <= 0 {
+		return "", 0, ErrScanInProgress
+	}
+	if len(s.scanQueue) >= depth {
+		return "", 0, ErrScanQueueFull
+	}
+
+	s.scanQueue = append(s.scanQueue, request)
+	slog.Info("scan queued", "incremental", incremental, "force", force, "position", len(s.scanQueue))
+	return ScanStatusQueued, len(s.scanQueue), nil
+}
+
+// nextQueuedScan removes and returns the first queued scan. The caller must
+// hold s.mu.
+func (s *LibraryService) nextQueuedScan() (scanRequest, bool) {
+	if len(s.scanQueue) == 0 {
+		return scanRequest{}, false
+	}
+	next := s.scanQueue[0]
+	s.scanQueue = s.scanQueue[1:]
+	return next, true
+}
+
+// releaseScanSlot hands the slot claimed by setting scanning to the next
+// queued scan, or frees it when none is queued. s.mu must be held.
+func (s *LibraryService) releaseScanSlot() {
+	if next, ok := s.nextQueuedScan(); ok {
+		go s.runQueuedScan(next)
+		return
+	}
+	s.scanning = false
+}
+
+// runQueuedScan runs a scan whose slot was already claimed by setting
+// scanning
+func (s *LibraryService) runQueuedScan(request scanRequest) {
+	err := s.runScan(context.Background(), request.incremental, request.force)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		slog.Warn("scan failed", "incremental", request.incremental, "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanQueueProgress(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{ScanQueueDepth: 2})
+
+	// Hold the slot as a running scan would
+	lib.service.mu.Lock()
+	lib.service.scanning = true
+	lib.service.mu.Unlock()
+
+	tests := []struct {
+		name        string
+		incremental bool
+		force       bool
+		position    int
+		wantErr     error
+	}{
+		{"full", false, false, 1, nil},
+		{"incremental", true, true, 2, nil},
+		{"same as queued", false, false, 1, nil},
+		{"queue full", true, false, 0, ErrScanQueueFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, position, err := lib.service.StartScan(tt.incremental, tt.force)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StartScan error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if status != ScanStatusQueued || position != tt.position {
+				t.Errorf("StartScan = %q at %d, want queued at %d", status, position, tt.position)
+			}
+		})
+	}
+
+	progress := lib.service.GetProgress()
+	want := []QueuedScan{
+		{Status: ScanStatusQueued, Type: "full", Position: 1},
+		{Status: ScanStatusQueued, Type: "incremental", Force: true, Position: 2},
+	}
+	if progress.QueuedScans != len(want) || len(progress.Queue) != len(want) {
+		t.Fatalf("progress lists %d queued scans (%v), want %d", progress.QueuedScans, progress.Queue, len(want))
+	}
+	for i := range want {
+		if progress.Queue[i] != want[i] {
+			t.Errorf("queue[%d] = %+v, want %+v", i, progress.Queue[i], want[i])
+		}
+	}
+
+	// The queue drains as scans take the slot
+	lib.service.mu.Lock()
+	lib.service.nextQueuedScan()
+	lib.service.mu.Unlock()
+	progress = lib.service.GetProgress()
+	if len(progress.Queue) != 1 || progress.Queue[0].Type != "incremental" || progress.Queue[0].Position != 1 {
+		t.Errorf("queue after the first scan started = %+v, want the incremental scan at 1", progress.Queue)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScanSchedule decides when scheduled incremental scans run: either at a
+// fixed interval or at times of day in local time
+type ScanSchedule struct {
+	every time.Duration
+	// times are minutes after midnight, sorted
+	times []int
+}
+
+// minScanInterval keeps interval schedules from scanning back to back
+const minScanInterval = time.Minute
+
+// ParseScanSchedule parses a scan schedule: an interval such as "6h" or
+// "@every 6h", "@hourly", "@daily" (midnight), or a comma-separated list of
+// times of day such as "03:00" or "03:00,15:30". An empty spec returns nil.
+func ParseScanSchedule(spec string) (*ScanSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "":
+		return nil, nil
+	case "@hourly":
+		return &ScanSchedule{every: time.Hour}, nil
+	case "@daily", "@midnight":
+		return &ScanSchedule{times: []int{0}}, nil
+	}
+
+	if interval, ok := strings.CutPrefix(spec, "@every"); ok || !strings.Contains(spec, ":") {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan interval %q", spec)
+		}
+		if every < minScanInterval {
+			return nil, fmt.Errorf("scan interval %s is shorter than %s", every, minScanInterval)
+		}
+		return &ScanSchedule{every: every}, nil
+	}
+
+	schedule := &ScanSchedule{}
+	for _, part := range strings.Split(spec, ",") {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day %q (use HH:MM)", part)
+		}
+		schedule.times = append(schedule.times, t.Hour()*60+t.Minute())
+	}
+	sort.Ints(schedule.times)
+	return schedule, nil
+}
+
+// Next returns the first scheduled time after after
+func (s *ScanSchedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every)
+	}
+
+	// Building each candidate from the date keeps times of day right across
+	// daylight saving changes
+	year, month, day := after.Date()
+	for offset := 0; offset <= 1; offset++ {
+		for _, minutes := range s.times {
+			next := time.Date(year, month, day+offset, minutes/60, minutes%60, 0, 0, after.Location())
+			if next.After(after) {
+				return next
+			}
+		}
+	}
+	return time.Date(year, month, day+2, s.times[0]/60, s.times[0]%60, 0, 0, after.Location())
+}
+
+// String describes the schedule for logging
+func (s *ScanSchedule) String() string {
+	if s.every > 0 {
+		return "every " + s.every.String()
+	}
+	times := make([]string, len(s.times))
+	for i, minutes := range s.times {
+		times[i] = fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+	}
+	return "daily at " + strings.Join(times, ", ")
+}
+
+// StartScanSchedule runs incremental scans as schedule dictates until Close
+// is called. A run is skipped when another scan is still in progress.
+func (s *LibraryService) StartScanSchedule(schedule *ScanSchedule) {
+	if schedule == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stopSchedule != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stopSchedule = stop
+	s.mu.Unlock()
+
+	slog.Info("scheduled scans enabled", "schedule", schedule.String())
+	go runSchedule(schedule, time.Now, stop, s.runScheduledScan)
+}
+
+// runScheduledScan starts an incremental scan unless one is running
+func (s *LibraryService) runScheduledScan() {
+	if s.IsScanning() {
+		slog.Info("skipping scheduled scan, a scan is already in progress")
+		return
+	}
+
+	slog.Info("starting scheduled library scan")
+	if err := s.IncrementalScan(context.Background()); err != nil && !errors.Is(err, ErrScanInProgress) {
+		slog.Error("scheduled scan failed", "error", err)
+	}
+}
+
+// runSchedule calls run at each time schedule gives, using now as the clock,
+// until stop is closed. run is called synchronously, so a slow run delays
+// the next one instead of overlapping it.
+func runSchedule(schedule *ScanSchedule, now func() time.Time, stop <-chan struct{}, run func()) {
+	for {
+		current := now()
+		timer := time.NewTimer(schedule.Next(current).Sub(current))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			run()
+		}
+	}
+}
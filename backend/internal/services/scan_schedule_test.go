@@ -0,0 +1,83 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScanScheduleNext(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"6h", at(1, 0), at(7, 0)},
+		{"@every 90m", at(1, 0), at(2, 30)},
+		{"@hourly", at(1, 30), at(2, 30)},
+		{"@daily", at(1, 0), at(24, 0)},
+		{"03:00,15:30", at(1, 0), at(3, 0)},
+		{"15:30, 03:00", at(3, 0), at(15, 30)},
+		{"03:00,15:30", at(16, 0), at(27, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseScanSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseScanSchedule: %v", err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+
+	for _, spec := range []string{"nightly", "30s", "25:00", "03:00,later"} {
+		if _, err := ParseScanSchedule(spec); err == nil {
+			t.Errorf("ParseScanSchedule(%q) accepted", spec)
+		}
+	}
+	if schedule, err := ParseScanSchedule(""); schedule != nil || err != nil {
+		t.Errorf("ParseScanSchedule(\"\") = %v, %v; want nil, nil", schedule, err)
+	}
+}
+
+func TestRunSchedule(t *testing.T) {
+	// The clock starts just before 03:00, so a daily schedule fires within
+	// the test instead of hours later
+	start := time.Now()
+	base := time.Date(2024, 3, 10, 2, 59, 59, 900_000_000, time.UTC)
+	now := func() time.Time { return base.Add(time.Since(start)) }
+
+	tests := []struct {
+		name     string
+		schedule *ScanSchedule
+		wait     time.Duration
+		minRuns  int
+		maxRuns  int
+	}{
+		{"time of day", &ScanSchedule{times: []int{3 * 60}}, 300 * time.Millisecond, 1, 1},
+		{"interval", &ScanSchedule{every: 50 * time.Millisecond}, 275 * time.Millisecond, 3, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				runSchedule(tt.schedule, now, stop, func() { runs.Add(1) })
+				close(done)
+			}()
+
+			time.Sleep(tt.wait)
+			close(stop)
+			<-done
+			if got := int(runs.Load()); got < tt.minRuns || got > tt.maxRuns {
+				t.Errorf("ran %d times, want %d to %d", got, tt.minRuns, tt.maxRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+//go:build unix
+
+package services
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanCompletedSummary(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{})
+	completed := make(chan ScanEvent, 4)
+	lib.service.OnScanEvent(func(event ScanEvent) {
+		if event.Type == "scan_completed" {
+			completed <- event
+		}
+	})
+	// summary waits for the next scan_completed event's summary
+	summary := func() ScanSummary {
+		t.Helper()
+		select {
+		case event := <-completed:
+			if event.Summary == nil {
+				t.Fatal("scan_completed event has no summary")
+			}
+			return *event.Summary
+		case <-time.After(5 * time.Second):
+			t.Fatal("no scan_completed event")
+		}
+		return ScanSummary{}
+	}
+
+	// A socket is discovered like any file but can never be opened, and a
+	// file failing once is skipped until it changes
+	lib.service.SetOptions(LibraryOptions{MaxScanFailures: 1})
+	addSocket := func(path string) {
+		t.Helper()
+		listener, err := net.Listen("unix", filepath.Join(lib.mediaRoot, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+	}
+
+	lib.addFile("Band/First/01 - One.mp3", nil)
+	lib.addFile("Band/First/02 - Two.mp3", nil)
+	removed := lib.addFile("Band/First/03 - Three.mp3", nil)
+	addSocket("Band/First/04 - Broken.mp3")
+	lib.scan(false)
+	first := summary()
+	if first.Incremental || first.NewTracks != 3 || first.ErrorCount != 1 {
+		t.Errorf("first scan summary = %+v, want 3 new tracks and 1 error", first)
+	}
+
+	// Between scans one file is removed, one added and another socket
+	// added. A full scan updates every file it already knew.
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	lib.addFile("Band/First/05 - Five.mp3", nil)
+	addSocket("Band/First/06 - Broken Again.mp3")
+	lib.scan(false)
+	got := summary()
+
+	want := ScanSummary{NewTracks: 1, UpdatedTracks: 2, DeletedTracks: 1, SkippedFiles: 1, ErrorCount: 1}
+	if got.Incremental != want.Incremental || got.NewTracks != want.NewTracks || got.UpdatedTracks != want.UpdatedTracks ||
+		got.DeletedTracks != want.DeletedTracks || got.SkippedFiles != want.SkippedFiles || got.ErrorCount != want.ErrorCount {
+		t.Errorf("summary = %+v\nwant counts of %+v", got, want)
+	}
+
+	// The summary agrees with the progress the scan finished with
+	progress := lib.service.GetProgress()
+	if got.NewTracks != progress.NewTracks || got.UpdatedTracks != progress.UpdatedTracks ||
+		got.DeletedTracks != progress.DeletedTracks || got.SkippedFiles != progress.SkippedFiles ||
+		got.ErrorCount != progress.ErrorCount || got.TotalFiles != progress.TotalFiles {
+		t.Errorf("summary = %+v, progress = %+v", got, progress)
+	}
+	if got.Message != "scan done: 1 new, 2 updated, 1 removed, 1 errors" {
+		t.Errorf("message = %q", got.Message)
+	}
+}
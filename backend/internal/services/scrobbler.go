@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"harmony/internal/database"
+	"harmony/internal/models"
+)
+
+const (
+	// LastFMAPIURL is the Last.fm API endpoint scrobbles are sent to
+	LastFMAPIURL = "https://ws.audioscrobbler.com/2.0/"
+	// lastFMTimeout bounds each call to Last.fm
+	lastFMTimeout = 10 * time.Second
+	// maxLastFMResponse bounds the Last.fm responses read
+	maxLastFMResponse = 64 << 10
+)
+
+var ErrLastFMRequest = errors.New("last.fm request failed")
+
+// Scrobbler submits "now playing" updates and scrobbles to Last.fm for users
+// who linked their account, and remembers when each user started their
+// current track so a scrobble can tell how long it was played
+type Scrobbler struct {
+	settings *database.SettingsRepository
+	client   *http.Client
+	apiURL   string
+	now      func() time.Time
+
+	mu         sync.Mutex
+	nowPlaying map[string]nowPlayingEntry // by user ID
+}
+
+// nowPlayingEntry is the track a user reported as started and when
+type nowPlayingEntry struct {
+	trackID   string
+	startedAt time.Time
+}
+
+// NewScrobbler creates a Scrobbler reading credentials from settings
+func NewScrobbler(settings *database.SettingsRepository) *Scrobbler {
+	return &Scrobbler{
+		settings:   settings,
+		client:     &http.Client{Timeout: lastFMTimeout},
+		apiURL:     LastFMAPIURL,
+		now:        time.Now,
+		nowPlaying: make(map[string]nowPlayingEntry),
+	}
+}
+
+// NowPlaying remembers that a user started a track and tells Last.fm, if the
+// user linked an account. It reports whether Last.fm was updated.
+func (s *Scrobbler) NowPlaying(ctx context.Context, userID string, track *models.Track) (bool, error) {
+	s.mu.Lock()
+	s.nowPlaying[userID] = nowPlayingEntry{trackID: track.ID, startedAt: s.now()}
+	s.mu.Unlock()
+
+	creds, err := s.settings.GetLastFMCredentials(ctx, userID)
+	if err != nil || creds == nil {
+		return false, err
+	}
+	if err := s.call(ctx, creds, "track.updateNowPlaying", trackParams(track)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PlayedSeconds returns how long ago a user reported starting a track as now
+// playing, or false when their current track is another one
+func (s *Scrobbler) PlayedSeconds(userID, trackID string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.nowPlaying[userID]
+	if !ok || entry.trackID != trackID {
+		return 0, false
+	}
+	return int(s.now().Sub(entry.startedAt).Seconds()), true
+}
+
+// Finish forgets that a user is playing a track, once its play is recorded
+func (s *Scrobbler) Finish(userID, trackID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.nowPlaying[userID]; ok && entry.trackID == trackID {
+		delete(s.nowPlaying, userID)
+	}
+}
+
+// Scrobble sends a finished play to Last.fm, if the user linked an account.
+// startedAt is when playback began. It reports whether the scrobble was sent.
+func (s *Scrobbler) Scrobble(ctx context.Context, userID string, track *models.Track, startedAt time.Time) (bool, error) {
+	creds, err := s.settings.GetLastFMCredentials(ctx, userID)
+	if err != nil || creds == nil {
+		return false, err
+	}
+	params := trackParams(track)
+	params.Set("timestamp", strconv.FormatInt(startedAt.Unix(), 10))
+	if err := s.call(ctx, creds, "track.scrobble", params); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// trackParams describes a track the way the Last.fm track methods take it
+func trackParams(track *models.Track) url.Values {
+	params := url.Values{}
+	params.Set("track", track.Title)
+	if track.Artist != nil {
+		params.Set("artist", track.Artist.Name)
+	}
+	if track.Album != nil {
+		params.Set("album", track.Album.Title)
+	}
+	if track.TrackNumber > 0 {
+		params.Set("trackNumber", strconv.Itoa(track.TrackNumber))
+	}
+	if track.Duration > 0 {
+		params.Set("duration", strconv.Itoa(track.Duration))
+	}
+	return params
+}
+
+// call signs and posts a Last.fm API method call as the user
+func (s *Scrobbler) call(ctx context.Context, creds *models.LastFMCredentials, method string, params url.Values) error {
+	params.Set("method", method)
+	params.Set("api_key", creds.APIKey)
+	params.Set("sk", creds.SessionKey)
+	params.Set("api_sig", lastFMSignature(params, creds.APISecret))
+	// format isn't part of the signature
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLastFMRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLastFMRequest, err)
+	}
+	defer resp.Body.Close()
+
+	// Failures come back as {"error": code, "message": text}, usually but
+	// not always with an error status
+	var result struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLastFMResponse))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLastFMRequest, err)
+	}
+	_ = json.Unmarshal(body, &result)
+	if result.Error != 0 {
+		return fmt.Errorf("%w: %s (error %d)", ErrLastFMRequest, result.Message, result.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrLastFMRequest, resp.StatusCode)
+	}
+	return nil
+}
+
+// lastFMSignature computes the api_sig Last.fm requires: the md5 of every
+// parameter's name and value, sorted by name and concatenated, followed by
+// the API secret
+func lastFMSignature(params url.Values, secret string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(params.Get(name))
+	}
+	b.WriteString(secret)
+
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
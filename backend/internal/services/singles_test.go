@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollectSingles(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{MinAlbumTracks: 3})
+	lib.addFile("Band/Demo/01 - One.mp3", nil)
+	lib.addFile("Band/Demo/02 - Two.mp3", nil)
+	lib.addFile("Band/Record/01 - Alpha.mp3", nil)
+	lib.addFile("Band/Record/02 - Beta.mp3", nil)
+	lib.addFile("Band/Record/03 - Gamma.mp3", nil)
+	lib.scan(false)
+
+	check := func(step string, want map[string]string) {
+		t.Helper()
+		for title, album := range want {
+			if got := lib.albumOf(title); got != album {
+				t.Errorf("%s: %q is on %q, want %q", step, title, got, album)
+			}
+		}
+	}
+	check("first scan", map[string]string{
+		"One": SinglesAlbumTitle, "Two": SinglesAlbumTitle, "Alpha": "Record",
+	})
+
+	var demos int64
+	lib.db.DB.Raw(`SELECT COUNT(*) FROM albums WHERE title = 'Demo'`).Scan(&demos)
+	if demos != 0 {
+		t.Errorf("folded album Demo was left behind")
+	}
+
+	// Rescans leave the folded tracks where they are
+	lib.scan(false)
+	check("rescan", map[string]string{"One": SinglesAlbumTitle, "Two": SinglesAlbumTitle})
+
+	// A third track lifts Demo over the threshold, even when only the new
+	// file is scanned
+	lib.addFile("Band/Demo/03 - Three.mp3", nil)
+	lib.scan(true)
+	check("album complete", map[string]string{"One": "Demo", "Two": "Demo", "Three": "Demo"})
+
+	var singles int64
+	lib.db.DB.Raw(`SELECT COUNT(*) FROM albums WHERE title = ?`, SinglesAlbumTitle).Scan(&singles)
+	if singles != 0 {
+		t.Errorf("emptied Singles album was left behind")
+	}
+
+	// Turning folding off moves everything back
+	lib.addFile("Band/EP/01 - Solo.mp3", nil)
+	lib.scan(true)
+	check("small album", map[string]string{"Solo": SinglesAlbumTitle})
+	lib.service.SetOptions(LibraryOptions{})
+	lib.scan(true)
+	check("folding off", map[string]string{"Solo": "EP"})
+}
+
+func TestCollectSinglesWithoutArtist(t *testing.T) {
+	lib := newTestLibrary(t, LibraryOptions{})
+	lib.addFile("Band/Demo/01 - One.mp3", nil)
+	lib.scan(false)
+	lib.db.DB.Exec(`UPDATE albums SET artist_id = NULL`)
+	lib.service.SetOptions(LibraryOptions{MinAlbumTracks: 3})
+
+	if err := lib.service.collectSingles(context.Background()); err != nil {
+		t.Fatalf("collectSingles: %v", err)
+	}
+	if got := lib.albumOf("One"); got != "Demo" {
+		t.Errorf("track without an album artist is on %q, want Demo", got)
+	}
+}
+
+func TestUnfoldSinglesRelativePaths(t *testing.T) {
+	lib := newRelativeTestLibrary(t, LibraryOptions{MinAlbumTracks: 3})
+	lib.addFile("Band/Demo/01 - One.mp3", nil)
+	lib.addFile("Band/Demo/02 - Two.mp3", nil)
+	lib.addFile("Band/Demo/notes.txt", []byte("Recorded at home."))
+	lib.scan(false)
+
+	var stored string
+	lib.db.DB.Raw(`SELECT file_path FROM tracks WHERE title = 'One'`).Scan(&stored)
+	if stored != "Band/Demo/01 - One.mp3" {
+		t.Fatalf("track stored as %q, want a path relative to the media root", stored)
+	}
+
+	// Recreating the album reads its notes from the folded tracks' folder
+	lib.service.SetOptions(LibraryOptions{AlbumNotesFromFiles: true})
+	lib.scan(true)
+	if got := lib.albumOf("One"); got != "Demo" {
+		t.Fatalf("One is on %q, want Demo", got)
+	}
+
+	var notes string
+	lib.db.DB.Raw(`SELECT notes FROM albums WHERE title = 'Demo'`).Scan(&notes)
+	if notes != "Recorded at home." {
+		t.Errorf("unfolded album notes = %q, want the notes file's text", notes)
+	}
+}
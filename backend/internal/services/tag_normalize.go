@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"harmony/internal/database"
+	"harmony/internal/scanner"
+)
+
+var (
+	ErrTagNormalizeInProgress = errors.New("tag normalization already in progress")
+	ErrNoNormalizeRules       = errors.New("no normalization rules given or configured")
+)
+
+// maxTagChanges caps how many individual changes a normalization result lists
+const maxTagChanges = 500
+
+// TagChange is a single value rewritten by tag normalization
+type TagChange struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// TagNormalizeResult summarizes a tag normalization run. With DryRun nothing
+// was written and the counts describe what would change.
+type TagNormalizeResult struct {
+	DryRun         bool     `json:"dryRun"`
+	Rules          []string `json:"rules"`
+	TracksChanged  int      `json:"tracksChanged"`
+	AlbumsChanged  int      `json:"albumsChanged"`
+	ArtistsChanged int      `json:"artistsChanged"`
+	// Skipped counts renames that would collide with an existing album or
+	// artist; merging those is left to the user
+	Skipped   int         `json:"skipped"`
+	Changes   []TagChange `json:"changes"`
+	Truncated bool        `json:"truncated"`
+}
+
+// addChange records a change, keeping at most maxTagChanges of them
+func (r *TagNormalizeResult) addChange(change TagChange) {
+	if len(r.Changes) >= maxTagChanges {
+		r.Truncated = true
+		return
+	}
+	r.Changes = append(r.Changes, change)
+}
+
+// TagNormalizeJob reports on the current or last tag normalization run
+type TagNormalizeJob struct {
+	Status      ScanStatus          `json:"status"`
+	Result      *TagNormalizeResult `json:"result,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	StartedAt   time.Time           `json:"startedAt,omitempty"`
+	CompletedAt time.Time           `json:"completedAt,omitempty"`
+}
+
+// StartTagNormalize runs NormalizeTags in the background. Its outcome is
+// available from GetTagNormalizeJob.
+func (s *LibraryService) StartTagNormalize(rules scanner.NormalizeRules, dryRun bool) error {
+	rules, err := s.normalizeRules(rules)
+	if err != nil {
+		return err
+	}
+	if s.IsScanning() {
+		return ErrScanInProgress
+	}
+
+	s.mu.Lock()
+	if s.tagJob.Status == ScanStatusProcessing {
+		s.mu.Unlock()
+		return ErrTagNormalizeInProgress
+	}
+	s.tagJob = TagNormalizeJob{Status: ScanStatusProcessing, StartedAt: time.Now()}
+	s.mu.Unlock()
+
+	go func() {
+		result, err := s.NormalizeTags(context.Background(), rules, dryRun)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.tagJob.CompletedAt = time.Now()
+		if err != nil {
+			s.tagJob.Status = ScanStatusFailed
+			s.tagJob.Error = err.Error()
+			return
+		}
+		s.tagJob.Status = ScanStatusCompleted
+		s.tagJob.Result = result
+	}()
+	return nil
+}
+
+// GetTagNormalizeJob returns the state of the current or last tag
+// normalization run
+func (s *LibraryService) GetTagNormalizeJob() TagNormalizeJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job := s.tagJob
+	if job.Status == "" {
+		job.Status = ScanStatusIdle
+	}
+	return job
+}
+
+// normalizeRules returns the rules a normalization run applies: rules, or
+// when they enable nothing the ones configured for scans, so both agree
+func (s *LibraryService) normalizeRules(rules scanner.NormalizeRules) (scanner.NormalizeRules, error) {
+	if !rules.Any() {
+		rules = s.getOptions().NormalizeRules
+	}
+	if !rules.Any() {
+		return rules, ErrNoNormalizeRules
+	}
+	return rules, nil
+}
+
+// NormalizeTags applies rules to the titles and names already in the
+// library. When rules enables nothing, the rules configured for scans are
+// used. Artists and albums aren't renamed onto another existing artist or
+// album, since that would leave two rows with the same name.
+func (s *LibraryService) NormalizeTags(ctx context.Context, rules scanner.NormalizeRules, dryRun bool) (*TagNormalizeResult, error) {
+	if s.IsScanning() {
+		return nil, ErrScanInProgress
+	}
+	rules, err := s.normalizeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizer := scanner.NewNormalizer(rules)
+	result := &TagNormalizeResult{
+		DryRun:  dryRun,
+		Rules:   rules.Names(),
+		Changes: []TagChange{},
+	}
+
+	if err := s.normalizeArtists(ctx, normalizer, result); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeAlbums(ctx, normalizer, result); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeTracks(ctx, normalizer, result); err != nil {
+		return nil, err
+	}
+
+	slog.Info("tag normalization completed",
+		"dryRun", dryRun,
+		"tracks", result.TracksChanged,
+		"albums", result.AlbumsChanged,
+		"artists", result.ArtistsChanged,
+		"skipped", result.Skipped,
+	)
+	return result, nil
+}
+
+// normalizeArtists renames artists whose normalized name differs
+func (s *LibraryService) normalizeArtists(ctx context.Context, normalizer *scanner.Normalizer, result *TagNormalizeResult) error {
+	artists, err := s.artistRepo.ListNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, artist := range artists {
+		name := normalizer.Title(artist.Name)
+		if name == artist.Name || name == "" {
+			continue
+		}
+
+		existing, err := s.artistRepo.FindByName(ctx, name)
+		if err != nil && !errors.Is(err, database.ErrArtistNotFound) {
+			return err
+		}
+		if existing != nil && existing.ID != artist.ID {
+			result.Skipped++
+			continue
+		}
+
+		if !result.DryRun {
+			if err := s.artistRepo.UpdateName(ctx, artist.ID, name); err != nil {
+				return err
+			}
+		}
+		result.ArtistsChanged++
+		result.addChange(TagChange{Type: "artist", ID: artist.ID, Field: "name", From: artist.Name, To: name})
+	}
+	return nil
+}
+
+// normalizeAlbums retitles albums, moving edition suffixes into the edition
+func (s *LibraryService) normalizeAlbums(ctx context.Context, normalizer *scanner.Normalizer, result *TagNormalizeResult) error {
+	albums, err := s.albumRepo.ListTitles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, album := range albums {
+		title, edition := normalizer.AlbumTitle(album.Title)
+		if edition == "" {
+			edition = album.Edition
+		} else if album.Edition != "" && album.Edition != edition {
+			edition += ", " + album.Edition
+		}
+		if title == album.Title && edition == album.Edition || title == "" {
+			continue
+		}
+
+		if title != album.Title {
+			existing, err := s.albumRepo.FindByTitleAndArtist(ctx, title, album.ArtistID)
+			if err != nil && !errors.Is(err, database.ErrAlbumNotFound) {
+				return err
+			}
+			if existing != nil && existing.ID != album.ID {
+				result.Skipped++
+				continue
+			}
+		}
+
+		if !result.DryRun {
+			if err := s.albumRepo.UpdateTitle(ctx, album.ID, title, edition); err != nil {
+				return err
+			}
+		}
+		result.AlbumsChanged++
+		if title != album.Title {
+			result.addChange(TagChange{Type: "album", ID: album.ID, Field: "title", From: album.Title, To: title})
+		}
+		if edition != album.Edition {
+			result.addChange(TagChange{Type: "album", ID: album.ID, Field: "edition", From: album.Edition, To: edition})
+		}
+	}
+	return nil
+}
+
+// normalizeTracks retitles tracks whose normalized title differs
+func (s *LibraryService) normalizeTracks(ctx context.Context, normalizer *scanner.Normalizer, result *TagNormalizeResult) error {
+	tracks, err := s.trackRepo.ListTitles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		title := normalizer.Title(track.Title)
+		if title == track.Title || title == "" {
+			continue
+		}
+
+		if !result.DryRun {
+			if err := s.trackRepo.UpdateTitle(ctx, track.ID, title); err != nil {
+				return err
+			}
+		}
+		result.TracksChanged++
+		result.addChange(TagChange{Type: "track", ID: track.ID, Field: "title", From: track.Title, To: title})
+	}
+	return nil
+}
+
+// normalizeMetadata applies the configured normalization rules to extracted
+// tags, so rescans agree with values fixed by NormalizeTags
+func (s *LibraryService) normalizeMetadata(metadata *scanner.TrackMetadata) {
+	if rules := s.getOptions().NormalizeRules; rules.Any() {
+		scanner.NewNormalizer(rules).Apply(metadata)
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnknownNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       LibraryOptions
+		wantArtist string
+		wantAlbum  string
+	}{
+		{"defaults", LibraryOptions{}, DefaultUnknownArtist, DefaultUnknownAlbum},
+		{"configured", LibraryOptions{UnknownArtist: "Anonymous", UnknownAlbum: "Loose Tracks"}, "Anonymous", "Loose Tracks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newTestLibrary(t, tt.opts)
+			// A generic parent folder names no artist, and a folder holding
+			// only a year names no album
+			lib.addFile("music/(2020)/01 - First.mp3", nil)
+			lib.addFile("music/[2019]/02 - Second.mp3", nil)
+			lib.scan(false)
+			// Rescanning reuses the same artist and album
+			lib.scan(false)
+
+			var artists, albums []string
+			lib.db.DB.Raw("SELECT name FROM artists").Scan(&artists)
+			lib.db.DB.Raw("SELECT title FROM albums").Scan(&albums)
+			if !slices.Equal(artists, []string{tt.wantArtist}) {
+				t.Errorf("artists = %q, want [%q]", artists, tt.wantArtist)
+			}
+			if !slices.Equal(albums, []string{tt.wantAlbum}) {
+				t.Errorf("albums = %q, want [%q]", albums, tt.wantAlbum)
+			}
+			for _, title := range []string{"First", "Second"} {
+				if album := lib.albumOf(title); album != tt.wantAlbum {
+					t.Errorf("%s is in %q, want %q", title, album, tt.wantAlbum)
+				}
+			}
+		})
+	}
+}
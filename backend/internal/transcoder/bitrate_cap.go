@@ -0,0 +1,101 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxSourceBitrates bounds how many probed source bitrates are remembered
+const maxSourceBitrates = 4096
+
+// EffectiveProfile returns profile with its bitrate lowered to the ceiling
+// configured for its format and, with CapToSource, to the bitrate of the
+// source. sourceBitrate is the source's bitrate in kbps as stored by the
+// scanner; when it's unknown the file is probed, once for each version of
+// the file. Profiles without a target bitrate, such as original and
+// lossless, keep theirs. The low profiles also take the configured output
+// sample rate, when their codec supports it, and channel count, with their
+// bitrate scaled down to match.
+func (t *Transcoder) EffectiveProfile(ctx context.Context, inputPath string, sourceBitrate int, profile Profile) Profile {
+	if t == nil {
+		return profile
+	}
+
+	if profile.Name == ProfileLow.Name || profile.Name == ProfileLowOGG.Name {
+		if t.lowSampleRate > 0 && SupportsSampleRate(profile.Codec, t.lowSampleRate) {
+			profile.SampleRate = t.lowSampleRate
+		}
+		if t.lowChannels > 0 {
+			profile.Channels = t.lowChannels
+		}
+	}
+
+	if profile.Bitrate <= 0 {
+		return profile
+	}
+
+	bitrate := scaleBitrate(profile.Bitrate, profile)
+	if ceiling := t.maxBitrates[profile.Format]; ceiling > 0 && ceiling < bitrate {
+		bitrate = ceiling
+	}
+
+	if t.capToSource {
+		if sourceBitrate <= 0 {
+			sourceBitrate = t.probeSourceBitrate(ctx, inputPath)
+		}
+		if sourceBitrate > 0 && sourceBitrate < bitrate {
+			bitrate = sourceBitrate
+		}
+	}
+
+	profile.Bitrate = bitrate
+	return profile
+}
+
+// sourceBitrateCache holds the bitrates probed from sources without a
+// stored one, keyed by path, with the modification time they were probed at
+type sourceBitrateCache struct {
+	mu      sync.Mutex
+	entries map[string]sourceBitrate
+}
+
+type sourceBitrate struct {
+	modTime time.Time
+	bitrate int // 0 when the probe failed
+}
+
+// probeSourceBitrate returns a source's bitrate in kbps, probing it only
+// when it changed since it was last probed. Failed probes are remembered
+// too, so an unprobeable file isn't probed on every request.
+func (t *Transcoder) probeSourceBitrate(ctx context.Context, inputPath string) int {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return 0
+	}
+
+	cache := &t.sourceBitrates
+	cache.mu.Lock()
+	entry, ok := cache.entries[inputPath]
+	cache.mu.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.bitrate
+	}
+
+	bitrate := 0
+	if probed, err := t.ProbeAudio(ctx, inputPath); err == nil {
+		bitrate = probed.Bitrate
+	} else if ctx.Err() != nil {
+		// Cut short by the request rather than failed
+		return 0
+	}
+
+	cache.mu.Lock()
+	if cache.entries == nil || len(cache.entries) >= maxSourceBitrates {
+		cache.entries = make(map[string]sourceBitrate)
+	}
+	cache.entries[inputPath] = sourceBitrate{modTime: info.ModTime(), bitrate: bitrate}
+	cache.mu.Unlock()
+	return bitrate
+}
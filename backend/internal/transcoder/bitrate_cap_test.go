@@ -0,0 +1,125 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSupportsSampleRate(t *testing.T) {
+	tests := []struct {
+		codec string
+		rate  int
+		want  bool
+	}{
+		{"libmp3lame", 22050, true},
+		{"libmp3lame", 44100, true},
+		{"libmp3lame", 96000, false},
+		{"libmp3lame", 20000, false},
+		{"libvorbis", 20000, true},
+		{"libvorbis", 96000, true},
+		{"libvorbis", 4000, false},
+		{"libopus", 24000, true},
+		{"libopus", 22050, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			if got := SupportsSampleRate(tt.codec, tt.rate); got != tt.want {
+				t.Errorf("SupportsSampleRate(%s, %d) = %v, want %v", tt.codec, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveProfileLowSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        int
+		channels    int
+		maxBitrates map[string]int
+		profile     Profile
+		wantRate    int
+		wantCh      int
+		wantBitrate int
+	}{
+		{"unconfigured", 0, 0, nil, ProfileLow, 0, 0, 128},
+		{"resampled", 22050, 0, nil, ProfileLow, 22050, 0, 64},
+		{"mono", 0, 1, nil, ProfileLowOGG, 0, 1, 64},
+		{"resampled mono", 22050, 1, nil, ProfileLow, 22050, 1, 32},
+		{"floor", 8000, 1, nil, ProfileLowOGG, 8000, 1, minScaledBitrate},
+		{"rate mp3 can't take", 20000, 0, nil, ProfileLow, 0, 0, 128},
+		{"rate vorbis takes", 20000, 0, nil, ProfileLowOGG, 20000, 0, 58},
+		{"ceiling after scaling", 22050, 0, map[string]int{"mp3": 48}, ProfileLow, 22050, 0, 48},
+		{"other profiles untouched", 22050, 1, nil, ProfileMedium, 0, 0, ProfileMedium.Bitrate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transcoder{lowSampleRate: tt.rate, lowChannels: tt.channels, maxBitrates: tt.maxBitrates}
+			got := tr.EffectiveProfile(context.Background(), "in.flac", 0, tt.profile)
+			if got.SampleRate != tt.wantRate || got.Channels != tt.wantCh || got.Bitrate != tt.wantBitrate {
+				t.Errorf("EffectiveProfile = %d Hz, %d channels, %dk, want %d Hz, %d channels, %dk",
+					got.SampleRate, got.Channels, got.Bitrate, tt.wantRate, tt.wantCh, tt.wantBitrate)
+			}
+
+			args := strings.Join(tr.buildFFmpegArgs("in.flac", got, "out"), " ")
+			if tt.wantRate > 0 && !strings.Contains(args, fmt.Sprintf("-ar %d", tt.wantRate)) {
+				t.Errorf("ffmpeg args %q lack the sample rate", args)
+			}
+			if tt.wantCh > 0 && !strings.Contains(args, "-ac 1") {
+				t.Errorf("ffmpeg args %q lack the channel count", args)
+			}
+		})
+	}
+}
+
+func TestEffectiveProfileProbesOnce(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, "")
+	dir := filepath.Dir(ffmpeg)
+	// A stand-in for ffprobe reporting a 96k source and counting its runs
+	probe := `#!/bin/sh
+echo probe >> ` + filepath.Join(dir, "probes") + `
+echo '{"format":{"format_name":"mp3","bit_rate":"96000"}}'
+`
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(probe), 0755); err != nil {
+		t.Fatal(err)
+	}
+	probes := func() int {
+		data, _ := os.ReadFile(filepath.Join(dir, "probes"))
+		return strings.Count(string(data), "probe\n")
+	}
+
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	tr.capToSource = true
+	input := writeInput(t, "in.mp3")
+
+	tests := []struct {
+		name       string
+		touch      bool
+		wantProbes int
+	}{
+		{"first request probes", false, 1},
+		{"later request uses the probed bitrate", false, 1},
+		{"changed file is probed again", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.touch {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(input, later, later); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := tr.EffectiveProfile(context.Background(), input, 0, ProfileHigh)
+			if got.Bitrate != 96 {
+				t.Errorf("bitrate = %dk, want the source's 96k", got.Bitrate)
+			}
+			if n := probes(); n != tt.wantProbes {
+				t.Errorf("ffprobe ran %d times, want %d", n, tt.wantProbes)
+			}
+		})
+	}
+}
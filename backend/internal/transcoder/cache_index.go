@@ -0,0 +1,259 @@
+package transcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// cacheIndexFile holds the index of cached transcodes in the cache
+	// directory
+	cacheIndexFile = "index.json"
+	// cacheIndexVersion changes whenever the index format does
+	cacheIndexVersion = 1
+	// cacheIndexSaveInterval is how often a changed index is saved
+	cacheIndexSaveInterval = time.Minute
+)
+
+// cacheEntry is one cached transcode in the index
+type cacheEntry struct {
+	Size       int64     `json:"size"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+// cacheIndexData is the saved form of the index. Clean is only set when
+// the transcoder was closed normally; an index saved while it was running
+// may miss later transcodes, so it's rebuilt instead of trusted.
+type cacheIndexData struct {
+	Version int                   `json:"version"`
+	Clean   bool                  `json:"clean"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+// cacheIndex tracks cached transcodes by file name, so the cache size, file
+// count and eviction candidates are known without walking the cache
+// directory. Its fields other than saveMu are guarded by Transcoder.mu.
+type cacheIndex struct {
+	entries map[string]cacheEntry
+	dirty   bool
+
+	// saveMu serializes saves; closed stops them once the final save is made
+	saveMu sync.Mutex
+	closed bool
+}
+
+// loadCacheIndex reads the saved index, rebuilding it from the cache
+// directory when it's missing, unreadable or wasn't saved on a clean
+// shutdown. Entries recorded while it loads are kept.
+func (t *Transcoder) loadCacheIndex() {
+	entries, err := t.readCacheIndex()
+	if err != nil {
+		slog.Info("rebuilding transcode cache index", "reason", err)
+		entries = t.walkCache()
+	}
+
+	t.mu.Lock()
+	if t.index.entries == nil {
+		t.index.entries = make(map[string]cacheEntry)
+	}
+	for name, entry := range entries {
+		if _, ok := t.index.entries[name]; !ok {
+			t.index.entries[name] = entry
+		}
+	}
+	var size int64
+	for _, entry := range t.index.entries {
+		size += entry.Size
+	}
+	t.cacheSize = size
+	t.mu.Unlock()
+
+	// Mark the saved index as in use until Close saves it clean
+	if err := t.saveCacheIndex(false); err != nil {
+		slog.Warn("saving transcode cache index failed", "error", err)
+	}
+	slog.Debug("cache size calculated", "size", size, "sizeGB", float64(size)/(1024*1024*1024))
+}
+
+// readCacheIndex reads the saved index, failing unless it's current and
+// was saved clean
+func (t *Transcoder) readCacheIndex() (map[string]cacheEntry, error) {
+	data, err := os.ReadFile(filepath.Join(t.cacheDir, cacheIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %w", err)
+	}
+	var index cacheIndexData
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing index: %w", err)
+	}
+	switch {
+	case index.Version != cacheIndexVersion:
+		return nil, fmt.Errorf("index version %d", index.Version)
+	case !index.Clean:
+		return nil, fmt.Errorf("index not saved on shutdown")
+	}
+	return index.Entries, nil
+}
+
+// walkCache indexes the cache directory, taking each file's modification
+// time as its last access since touch keeps the two in step
+func (t *Transcoder) walkCache() map[string]cacheEntry {
+	entries := make(map[string]cacheEntry)
+	files, _ := os.ReadDir(t.cacheDir)
+	for _, file := range files {
+		if file.IsDir() || !isCachedTranscode(file.Name()) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries[file.Name()] = cacheEntry{Size: info.Size(), LastAccess: info.ModTime()}
+	}
+	return entries
+}
+
+// isCachedTranscode reports whether a file name in the cache directory is a
+// finished transcode, rather than the index or one in progress
+func isCachedTranscode(name string) bool {
+	return !strings.HasPrefix(name, cacheIndexFile) && !strings.HasSuffix(name, ".tmp")
+}
+
+// saveCacheIndex writes the index, replacing the saved one atomically
+func (t *Transcoder) saveCacheIndex(clean bool) error {
+	t.index.saveMu.Lock()
+	defer t.index.saveMu.Unlock()
+	if t.index.closed {
+		return nil
+	}
+
+	t.mu.Lock()
+	index := cacheIndexData{
+		Version: cacheIndexVersion,
+		Clean:   clean,
+		Entries: make(map[string]cacheEntry, len(t.index.entries)),
+	}
+	for name, entry := range t.index.entries {
+		index.Entries[name] = entry
+	}
+	t.index.dirty = false
+	t.mu.Unlock()
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("encoding cache index: %w", err)
+	}
+	path := filepath.Join(t.cacheDir, cacheIndexFile)
+	if err := os.WriteFile(path+".new", data, 0644); err != nil {
+		return fmt.Errorf("writing cache index: %w", err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		return fmt.Errorf("replacing cache index: %w", err)
+	}
+	if clean {
+		t.index.closed = true
+	}
+	return nil
+}
+
+// indexLoop saves the index when it changed, until Close is called
+func (t *Transcoder) indexLoop() {
+	ticker := time.NewTicker(cacheIndexSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopSweep:
+			return
+		case <-ticker.C:
+			t.mu.RLock()
+			dirty := t.index.dirty
+			t.mu.RUnlock()
+			if dirty {
+				if err := t.saveCacheIndex(false); err != nil {
+					slog.Warn("saving transcode cache index failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// indexFile records a cached file, or refreshes its entry, as just used.
+// The caller holds t.mu.
+func (t *Transcoder) indexFile(name string, size int64) {
+	if t.index.entries == nil {
+		t.index.entries = make(map[string]cacheEntry)
+	}
+	t.cacheSize += size - t.index.entries[name].Size
+	t.index.entries[name] = cacheEntry{Size: size, LastAccess: t.now()}
+	t.index.dirty = true
+}
+
+// unindexFile forgets a cached file. The caller holds t.mu.
+func (t *Transcoder) unindexFile(name string) {
+	entry, ok := t.index.entries[name]
+	if !ok {
+		return
+	}
+	delete(t.index.entries, name)
+	t.cacheSize -= entry.Size
+	if t.cacheSize < 0 {
+		t.cacheSize = 0
+	}
+	t.index.dirty = true
+}
+
+// removeCached deletes cached files by name, least recently used first,
+// until keep reports they can stop, and returns how many were removed and
+// the bytes freed. Files already gone are dropped from the index.
+func (t *Transcoder) removeCached(names []string, keep func(size int64) bool) (int, int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sort.Slice(names, func(i, j int) bool {
+		return t.index.entries[names[i]].LastAccess.Before(t.index.entries[names[j]].LastAccess)
+	})
+
+	var removed int
+	var freed int64
+	for _, name := range names {
+		if keep != nil && keep(t.cacheSize) {
+			break
+		}
+		entry, ok := t.index.entries[name]
+		if !ok {
+			continue
+		}
+		err := os.Remove(filepath.Join(t.cacheDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		t.unindexFile(name)
+		if err == nil {
+			removed++
+			freed += entry.Size
+		}
+	}
+	return removed, freed
+}
+
+// indexedNames returns the names of indexed files matching a filter
+func (t *Transcoder) indexedNames(match func(name string, entry cacheEntry) bool) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var names []string
+	for name, entry := range t.index.entries {
+		if match(name, entry) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
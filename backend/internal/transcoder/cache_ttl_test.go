@@ -0,0 +1,66 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepExpired(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		removed []string
+	}{
+		{"disabled", 0, nil},
+		{"hour", time.Hour, []string{"old.mp3"}},
+		{"ten minutes", 10 * time.Minute, []string{"old.mp3", "recent.mp3", "touched.mp3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := New(Config{FFmpegPath: fakeFFmpeg(t, ""), CacheDir: t.TempDir(), MaxCacheGB: 1, CacheTTL: tt.ttl})
+			if err != nil {
+				t.Fatalf("creating transcoder: %v", err)
+			}
+			t.Cleanup(tr.Close)
+			now := start
+			tr.now = func() time.Time { return now }
+
+			// cache writes a transcode at the current time
+			cache := func(name string) string {
+				path := filepath.Join(tr.cacheDir, name)
+				if err := os.WriteFile(path, []byte("transcoded"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				tr.updateCacheSize(path)
+				return path
+			}
+			cache("old.mp3")
+			touched := cache("touched.mp3")
+			now = start.Add(50 * time.Minute)
+			cache("recent.mp3")
+			tr.touch(touched)
+			now = start.Add(90 * time.Minute)
+
+			if removed := tr.sweepExpired(); removed != len(tt.removed) {
+				t.Errorf("sweep removed %d transcodes, want %d", removed, len(tt.removed))
+			}
+			gone := make(map[string]bool)
+			for _, name := range tt.removed {
+				gone[name] = true
+			}
+			for _, name := range []string{"old.mp3", "recent.mp3", "touched.mp3"} {
+				_, err := os.Stat(filepath.Join(tr.cacheDir, name))
+				if exists := err == nil; exists == gone[name] {
+					t.Errorf("%s exists = %v, want %v", name, exists, !gone[name])
+				}
+			}
+			if _, files, _ := tr.GetCacheStats(); files != 3-len(tt.removed) {
+				t.Errorf("index holds %d transcodes, want %d", files, 3-len(tt.removed))
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContentKeyedCache(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, `printf 'out' > "$out"`)
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	tr.contentKeys = true
+	ctx := context.Background()
+
+	// Two identical files at different paths share one transcode
+	first := writeInput(t, "song.flac")
+	second := filepath.Join(t.TempDir(), "copy.flac")
+	if err := os.WriteFile(second, []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const hash = "0123456789abcdef0123456789abcdef"
+	firstPath, err := tr.TranscodeAndCache(ctx, first, hash, ProfileHigh)
+	if err != nil {
+		t.Fatalf("TranscodeAndCache: %v", err)
+	}
+	secondPath, err := tr.TranscodeAndCache(ctx, second, hash, ProfileHigh)
+	if err != nil {
+		t.Fatalf("TranscodeAndCache: %v", err)
+	}
+	if firstPath != secondPath {
+		t.Errorf("cached paths differ: %s and %s", firstPath, secondPath)
+	}
+	if runs := ffmpegRuns(t, ffmpeg); runs != 1 {
+		t.Errorf("ffmpeg ran %d times, want 1", runs)
+	}
+	// Indexes the transcode, which the job does in the background
+	tr.GetCachedPath(first, hash, ProfileHigh)
+
+	tests := []struct {
+		name    string
+		hashes  []string
+		removed int
+	}{
+		{"content still in the library", []string{hash, "fedcba9876543210"}, 0},
+		{"content gone", []string{"fedcba9876543210"}, 1},
+		{"already removed", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if removed := tr.PurgeContentExcept(tt.hashes); removed != tt.removed {
+				t.Errorf("PurgeContentExcept removed %d, want %d", removed, tt.removed)
+			}
+		})
+	}
+	if _, err := os.Stat(firstPath); !os.IsNotExist(err) {
+		t.Errorf("purged transcode still cached")
+	}
+}
@@ -0,0 +1,134 @@
+package transcoder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limits on custom profiles defined at runtime
+const (
+	MaxCustomProfiles = 32
+	minCustomBitrate  = 32
+	maxCustomBitrate  = 512
+)
+
+// customCodec describes a codec custom profiles may use. The first format is
+// the default when a profile doesn't name one.
+type customCodec struct {
+	formats  []string
+	lossless bool
+}
+
+var (
+	customCodecs = map[string]customCodec{
+		"libmp3lame": {formats: []string{"mp3"}},
+		"libvorbis":  {formats: []string{"ogg"}},
+		"libopus":    {formats: []string{"opus", "ogg"}},
+		"aac":        {formats: []string{"adts"}},
+		"flac":       {formats: []string{"flac"}, lossless: true},
+	}
+
+	// formatExts maps ffmpeg output formats to file extensions
+	formatExts = map[string]string{
+		"mp3":  "mp3",
+		"ogg":  "ogg",
+		"opus": "opus",
+		"adts": "aac",
+		"flac": "flac",
+	}
+
+	profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+	customMu       sync.RWMutex
+	customProfiles = map[string]Profile{}
+)
+
+// NewCustomProfile validates a runtime-defined profile. Names are lowercased
+// and may not shadow a built-in profile. An empty format picks the codec's
+// default container; lossless codecs take no bitrate.
+func NewCustomProfile(name, codec, format string, bitrate int) (Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !profileNamePattern.MatchString(name) {
+		return Profile{}, fmt.Errorf("%w: name must be 1-32 lowercase letters, digits or dashes", ErrInvalidProfile)
+	}
+	if IsBuiltinProfile(name) {
+		return Profile{}, fmt.Errorf("%w: %q is a built-in profile", ErrInvalidProfile, name)
+	}
+
+	spec, ok := customCodecs[codec]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: unsupported codec %q", ErrInvalidProfile, codec)
+	}
+
+	if format == "" {
+		format = spec.formats[0]
+	}
+	supported := false
+	for _, f := range spec.formats {
+		if f == format {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return Profile{}, fmt.Errorf("%w: codec %s can't be written as %q", ErrInvalidProfile, codec, format)
+	}
+
+	switch {
+	case spec.lossless && bitrate != 0:
+		return Profile{}, fmt.Errorf("%w: codec %s is lossless and takes no bitrate", ErrInvalidProfile, codec)
+	case !spec.lossless && (bitrate < minCustomBitrate || bitrate > maxCustomBitrate):
+		return Profile{}, fmt.Errorf("%w: bitrate must be between %d and %d kbps", ErrInvalidProfile, minCustomBitrate, maxCustomBitrate)
+	}
+
+	return Profile{
+		Name:    name,
+		Format:  format,
+		Codec:   codec,
+		Bitrate: bitrate,
+		Ext:     formatExts[format],
+	}, nil
+}
+
+// IsBuiltinProfile reports whether name belongs to a predefined profile
+func IsBuiltinProfile(name string) bool {
+	name = strings.ToLower(name)
+	_, ok := profiles[name]
+	return ok || name == ProfileLossless.Name
+}
+
+// SetCustomProfiles replaces the set of runtime-defined profiles
+func SetCustomProfiles(list []Profile) {
+	set := make(map[string]Profile, len(list))
+	for _, p := range list {
+		set[p.Name] = p
+	}
+
+	customMu.Lock()
+	customProfiles = set
+	customMu.Unlock()
+}
+
+// CustomProfiles returns the runtime-defined profiles sorted by name
+func CustomProfiles() []Profile {
+	customMu.RLock()
+	list := make([]Profile, 0, len(customProfiles))
+	for _, p := range customProfiles {
+		list = append(list, p)
+	}
+	customMu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// customProfile looks up a runtime-defined profile
+func customProfile(name string) (Profile, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	p, ok := customProfiles[name]
+	return p, ok
+}
@@ -0,0 +1,76 @@
+package transcoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewCustomProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		codec   string
+		format  string
+		bitrate int
+		want    Profile
+		err     bool
+	}{
+		{"aac", "aac-256", "aac", "", 256, Profile{Name: "aac-256", Codec: "aac", Format: "adts", Bitrate: 256, Ext: "aac"}, false},
+		{"name lowercased", " Opus-Low ", "libopus", "ogg", 48, Profile{Name: "opus-low", Codec: "libopus", Format: "ogg", Bitrate: 48, Ext: "ogg"}, false},
+		{"lossless", "flac", "flac", "", 0, Profile{Name: "flac", Codec: "flac", Format: "flac", Ext: "flac"}, false},
+		{"built-in name", "High", "aac", "", 256, Profile{}, true},
+		{"invalid name", "my profile", "aac", "", 256, Profile{}, true},
+		{"unknown codec", "wav", "pcm_s16le", "", 0, Profile{}, true},
+		{"wrong format", "mp3-ogg", "libmp3lame", "ogg", 192, Profile{}, true},
+		{"bitrate too low", "tiny", "libopus", "", 16, Profile{}, true},
+		{"bitrate too high", "huge", "aac", "", 1024, Profile{}, true},
+		{"lossless bitrate", "flac-hi", "flac", "", 900, Profile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := NewCustomProfile(tt.profile, tt.codec, tt.format, tt.bitrate)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidProfile) {
+					t.Errorf("error = %v, want %v", err, ErrInvalidProfile)
+				}
+				return
+			}
+			if err != nil || profile != tt.want {
+				t.Errorf("profile = %+v, %v; want %+v", profile, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCustomProfile(t *testing.T) {
+	t.Cleanup(func() { SetCustomProfiles(nil) })
+	custom, err := NewCustomProfile("aac-256", "aac", "", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetCustomProfiles([]Profile{custom})
+
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{"high", "high", false},
+		{"aac-256", "aac-256", false},
+		{"AAC-256", "aac-256", false},
+		{"aac-128", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := GetProfile(tt.name)
+			if (err != nil) != tt.err || profile.Name != tt.want {
+				t.Errorf("GetProfile(%q) = %q, %v; want %q", tt.name, profile.Name, err, tt.want)
+			}
+		})
+	}
+
+	SetCustomProfiles(nil)
+	if _, err := GetProfile("aac-256"); err == nil {
+		t.Error("removed profile is still found")
+	}
+}
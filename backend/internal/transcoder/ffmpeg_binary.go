@@ -0,0 +1,184 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ffmpegCheckTimeout bounds each ffmpeg invocation made while locating it
+const ffmpegCheckTimeout = 10 * time.Second
+
+// ffmpegInfo describes a located ffmpeg binary. A zero value means ffmpeg
+// isn't available.
+type ffmpegInfo struct {
+	path    string
+	version string
+	// encoders holds the audio encoders ffmpeg was built with; nil if they
+	// couldn't be listed, in which case every encoder is assumed present
+	encoders map[string]bool
+}
+
+// ffmpegBinary holds the ffmpeg in use, which Recheck can replace while
+// transcodes run
+type ffmpegBinary struct {
+	mu      sync.RWMutex
+	current ffmpegInfo
+	// configured is the path ffmpeg was configured with; empty looks it up
+	// on PATH
+	configured string
+}
+
+func (b *ffmpegBinary) get() ffmpegInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.current
+}
+
+func (b *ffmpegBinary) set(info ffmpegInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = info
+}
+
+// locateFFmpeg finds ffmpeg at the configured path, or on PATH when none is
+// configured, checks that it runs and lists its audio encoders
+func locateFFmpeg(ctx context.Context, configured string) (ffmpegInfo, error) {
+	path := configured
+	if path == "" || path == "ffmpeg" {
+		found, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return ffmpegInfo{}, ErrFFmpegNotFound
+		}
+		path = found
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ffmpegCheckTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return ffmpegInfo{}, fmt.Errorf("ffmpeg check failed: %w", err)
+	}
+
+	info := ffmpegInfo{path: path, version: parseFFmpegVersion(output)}
+	if encoders, err := listAudioEncoders(ctx, path); err == nil {
+		info.encoders = encoders
+	} else {
+		slog.Debug("listing ffmpeg encoders failed", "error", err)
+	}
+	return info, nil
+}
+
+// parseFFmpegVersion reads the version from the first line of
+// `ffmpeg -version` ("ffmpeg version 6.1.1 Copyright ...")
+func parseFFmpegVersion(output []byte) string {
+	line, _, _ := bytes.Cut(output, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return ""
+}
+
+// listAudioEncoders parses `ffmpeg -encoders`, whose entries are a flags
+// column starting with A for audio encoders followed by the encoder name
+// (" A....D libmp3lame  libmp3lame MP3 ...")
+func listAudioEncoders(ctx context.Context, path string) (map[string]bool, error) {
+	output, err := exec.CommandContext(ctx, path, "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing encoders: %w", err)
+	}
+
+	encoders := make(map[string]bool)
+	listing := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// The legend above the list ends with a " ------" line
+		if len(fields) == 1 && strings.HasPrefix(fields[0], "---") {
+			listing = true
+			continue
+		}
+		if listing && len(fields) >= 2 && strings.HasPrefix(fields[0], "A") {
+			encoders[fields[1]] = true
+		}
+	}
+	if len(encoders) == 0 {
+		return nil, fmt.Errorf("listing encoders: no audio encoders found")
+	}
+	return encoders, nil
+}
+
+// FFmpegStatus describes the ffmpeg the transcoder uses
+type FFmpegStatus struct {
+	Available bool
+	Path      string
+	Version   string
+	// Encoders lists the audio encoders ffmpeg supports, sorted; empty if
+	// they couldn't be listed
+	Encoders []string
+}
+
+// Status reports the ffmpeg currently in use
+func (t *Transcoder) Status() FFmpegStatus {
+	if t == nil {
+		return FFmpegStatus{}
+	}
+
+	info := t.ffmpeg.get()
+	status := FFmpegStatus{
+		Available: info.path != "",
+		Path:      info.path,
+		Version:   info.version,
+		Encoders:  []string{},
+	}
+	for encoder := range info.encoders {
+		status.Encoders = append(status.Encoders, encoder)
+	}
+	sort.Strings(status.Encoders)
+	return status
+}
+
+// Recheck locates ffmpeg again and re-lists its encoders, so ffmpeg
+// installed or upgraded while the server runs is used without a restart.
+// When ffmpeg can't be found transcoding becomes unavailable and the error
+// is returned along with the new status. Transcodes already running keep
+// the binary they started with.
+func (t *Transcoder) Recheck(ctx context.Context) (FFmpegStatus, error) {
+	if t == nil {
+		return FFmpegStatus{}, ErrFFmpegNotFound
+	}
+
+	previous := t.ffmpeg.get()
+	info, err := locateFFmpeg(ctx, t.ffmpeg.configured)
+	t.ffmpeg.set(info)
+
+	switch {
+	case err != nil && previous.path != "":
+		slog.Warn("ffmpeg no longer available, transcoding disabled", "error", err)
+	case err == nil && (info.path != previous.path || info.version != previous.version):
+		slog.Info("ffmpeg changed", "ffmpeg", info.path, "version", info.version, "previousVersion", previous.version)
+	}
+	return t.Status(), err
+}
+
+// SupportsEncoder reports whether ffmpeg was built with an audio encoder.
+// It's assumed to be when the encoders couldn't be listed.
+func (t *Transcoder) SupportsEncoder(codec string) bool {
+	if codec == "" {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	encoders := t.ffmpeg.get().encoders
+	return encoders == nil || encoders[codec]
+}
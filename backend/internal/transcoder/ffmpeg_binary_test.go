@@ -0,0 +1,84 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFFmpegVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"release", "ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc", "6.1.1"},
+		{"static build", "ffmpeg version n7.0-static https://johnvansickle.com/ffmpeg/", "n7.0-static"},
+		{"no version", "ffmpeg\n", ""},
+		{"no output", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFFmpegVersion([]byte(tt.output)); got != tt.want {
+				t.Errorf("parseFFmpegVersion(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecheck(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, "")
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	original, err := os.ReadFile(ffmpeg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An upgrade to a build without the FLAC encoder
+	upgraded := strings.ReplaceAll(string(original), "6.0-test", "7.0-test")
+	upgraded = strings.ReplaceAll(upgraded, ` A..... flac FLAC\n`, "")
+
+	write := func(script string) func() {
+		return func() {
+			if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Steps run in order against the same transcoder
+	tests := []struct {
+		name     string
+		change   func()
+		err      error
+		version  string
+		encoders []string
+	}{
+		{"unchanged", func() {}, nil, "6.0-test", []string{"flac", "libmp3lame", "libvorbis"}},
+		{"removed", func() { os.Remove(ffmpeg) }, errors.New("ffmpeg check failed"), "", []string{}},
+		{"installed again", write(string(original)), nil, "6.0-test", []string{"flac", "libmp3lame", "libvorbis"}},
+		{"upgraded", write(upgraded), nil, "7.0-test", []string{"libmp3lame", "libvorbis"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			status, err := tr.Recheck(context.Background())
+			if (err != nil) != (tt.err != nil) || (err != nil && !strings.Contains(err.Error(), tt.err.Error())) {
+				t.Fatalf("Recheck error = %v, want %v", err, tt.err)
+			}
+			if current := tr.Status(); current.Available != status.Available || current.Version != status.Version {
+				t.Errorf("Recheck returned %+v, but the status is %+v", status, current)
+			}
+			available := tt.err == nil
+			if status.Available != available || status.Version != tt.version || !slices.Equal(status.Encoders, tt.encoders) {
+				t.Errorf("status = %+v, want available %v, version %q and encoders %v",
+					status, available, tt.version, tt.encoders)
+			}
+			if available && tr.SupportsEncoder("flac") != slices.Contains(tt.encoders, "flac") {
+				t.Errorf("SupportsEncoder(flac) = %v with encoders %v", tr.SupportsEncoder("flac"), tt.encoders)
+			}
+		})
+	}
+}
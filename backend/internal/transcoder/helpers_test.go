@@ -0,0 +1,71 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFFmpeg writes a stand-in for ffmpeg to a temp directory and returns
+// its path. It answers the version and encoder checks like ffmpeg; any
+// other run appends a line to the runs file next to it, then runs body
+// with $out set to the output argument (the last one).
+func fakeFFmpeg(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ffmpeg")
+	script := `#!/bin/sh
+case "$1" in -version) echo "ffmpeg version 6.0-test"; exit 0;; esac
+if [ "$2" = "-encoders" ]; then
+	printf ' ------\n A..... libmp3lame MP3\n A..... libvorbis Vorbis\n A..... flac FLAC\n'
+	exit 0
+fi
+echo run >> ` + filepath.Join(dir, "runs") + `
+for out; do :; done
+` + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ffmpegRuns counts the transcodes a fakeFFmpeg ran
+func ffmpegRuns(t *testing.T, ffmpegPath string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(ffmpegPath), "runs"))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+// newTestTranscoder creates a Transcoder running ffmpegPath with a temp
+// cache directory
+func newTestTranscoder(t *testing.T, ffmpegPath string, maxConcurrent int) *Transcoder {
+	t.Helper()
+	tr, err := New(Config{
+		FFmpegPath:    ffmpegPath,
+		CacheDir:      t.TempDir(),
+		MaxCacheGB:    1,
+		MaxConcurrent: maxConcurrent,
+	})
+	if err != nil {
+		t.Fatalf("creating transcoder: %v", err)
+	}
+	t.Cleanup(tr.Close)
+	return tr
+}
+
+// writeInput creates an input file in a temp directory
+func writeInput(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,230 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// transcodeJobTimeout bounds a cached transcode, which runs on after
+	// the requests waiting for it go away
+	transcodeJobTimeout = 30 * time.Minute
+	// tailPollInterval is how often readers catching up with a transcode
+	// check the cache file for more output
+	tailPollInterval = 50 * time.Millisecond
+)
+
+// transcodeJobs holds the cached transcodes being made, so requests for the
+// same output read one ffmpeg run instead of starting their own
+type transcodeJobs struct {
+	mu      sync.Mutex
+	running map[string]*transcodeJob // by cached path
+}
+
+// transcodeJob is a cached transcode being written to tempPath, which is
+// moved to the cached path when it succeeds. err is set before done is
+// closed.
+type transcodeJob struct {
+	tempPath string
+	done     chan struct{}
+	err      error
+}
+
+// acquireSlot waits until fewer than the configured number of ffmpeg
+// transcodes are running and returns the function releasing the slot taken
+func (t *Transcoder) acquireSlot(ctx context.Context) (func(), error) {
+	if t.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return func() { <-t.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startJob returns the transcode to cachedPath being made, starting it with
+// produce when there's none. produce writes the transcode to the temporary
+// path it's given as fast as ffmpeg goes, not at the pace of any client, so
+// it holds its transcode slot no longer than needed. It runs detached from
+// the requests reading it, which can come and go without cutting it short.
+func (t *Transcoder) startJob(cachedPath string, produce func(ctx context.Context, tempPath string) error) (*transcodeJob, error) {
+	t.jobs.mu.Lock()
+	defer t.jobs.mu.Unlock()
+
+	if t.jobs.running == nil {
+		t.jobs.running = make(map[string]*transcodeJob)
+	}
+	if job, ok := t.jobs.running[cachedPath]; ok {
+		return job, nil
+	}
+
+	// The file exists before the job is visible, so readers can open it
+	job := &transcodeJob{tempPath: cachedPath + ".tmp", done: make(chan struct{})}
+	file, err := os.Create(job.tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating cache file: %w", err)
+	}
+	file.Close()
+	t.jobs.running[cachedPath] = job
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), transcodeJobTimeout)
+		defer cancel()
+
+		err := produce(ctx, job.tempPath)
+		if err == nil {
+			if renameErr := os.Rename(job.tempPath, cachedPath); renameErr != nil {
+				err = fmt.Errorf("moving transcoded file: %w", renameErr)
+			}
+		}
+		if err != nil {
+			// Readers that have it open keep reading what was written
+			os.Remove(job.tempPath)
+		} else {
+			// Indexed before the job ends, so a purge right after it
+			// finds the file
+			t.updateCacheSize(cachedPath)
+		}
+		t.finishJob(cachedPath, job, err)
+	}()
+	return job, nil
+}
+
+// finishJob records the outcome of a transcode and wakes its readers
+func (t *Transcoder) finishJob(cachedPath string, job *transcodeJob, err error) {
+	t.jobs.mu.Lock()
+	delete(t.jobs.running, cachedPath)
+	t.jobs.mu.Unlock()
+
+	job.err = err
+	close(job.done)
+}
+
+// wait blocks until the job ends or ctx is done
+func (j *transcodeJob) wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// open opens the job's output: the temporary file while it's being written,
+// or the cached file it was moved to when it's done already
+func (j *transcodeJob) open(cachedPath string) (*os.File, error) {
+	file, err := os.Open(j.tempPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = os.Open(cachedPath)
+	}
+	if err == nil {
+		return file, nil
+	}
+	// Neither exists when the transcode failed before it could be opened
+	select {
+	case <-j.done:
+		if j.err != nil {
+			return nil, j.err
+		}
+	default:
+	}
+	return nil, fmt.Errorf("opening transcode: %w", err)
+}
+
+// tail copies the job's output to w as it's written, from the start, until
+// the transcode has ended and all of it was copied or ctx is done. Readers
+// joining late catch up at the speed of their connection.
+func (j *transcodeJob) tail(ctx context.Context, cachedPath string, w io.Writer) error {
+	file, err := j.open(cachedPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	flusher, _ := w.(interface{ Flush() })
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading transcode: %w", err)
+		}
+
+		// Caught up with the transcode; see whether there's more to come
+		select {
+		case <-j.done:
+			if j.err != nil {
+				return j.err
+			}
+			// Everything was written before done was closed
+			if _, err := io.Copy(w, file); err != nil {
+				return err
+			}
+			return nil
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// cachedTranscode returns the path of a finished cached transcode, or an
+// empty string when it isn't cached
+func (t *Transcoder) cachedTranscode(cachedPath string) string {
+	if _, err := os.Stat(cachedPath); err != nil {
+		return ""
+	}
+	t.touch(cachedPath)
+	return cachedPath
+}
+
+// StreamTranscode transcodes a file to w while saving it to the cache, so
+// later requests are served from there. begin is called before anything is
+// written to w. Requests for a transcode another one started read it as it's
+// written. When the transcode is cached already, w is left untouched and the
+// cached file's path is returned; otherwise the path is empty.
+func (t *Transcoder) StreamTranscode(ctx context.Context, inputPath, contentHash string, profile Profile, w io.Writer, begin func()) (string, error) {
+	cachedPath := filepath.Join(t.cacheDir, t.getCacheKey(inputPath, contentHash, profile)+"."+profile.Ext)
+	if path := t.cachedTranscode(cachedPath); path != "" {
+		return path, nil
+	}
+
+	job, err := t.startJob(cachedPath, func(ctx context.Context, tempPath string) error {
+		file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("opening cache file: %w", err)
+		}
+		defer file.Close()
+
+		if err := t.TranscodeToWriter(ctx, inputPath, profile, file); err != nil {
+			return err
+		}
+		return file.Close()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	begin()
+	return "", job.tail(ctx, cachedPath, w)
+}
@@ -0,0 +1,167 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowOutput writes two parts a moment apart, to stdout or the output file
+const slowOutput = `if [ "$out" = "pipe:1" ]; then
+	printf 'first-'; sleep 0.5; printf 'second'
+else
+	printf 'first-' > "$out"; sleep 0.5; printf 'second' >> "$out"
+fi`
+
+// signalWriter collects what's written and closes started on the first write
+type signalWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	once    sync.Once
+	started chan struct{}
+}
+
+func newSignalWriter() *signalWriter {
+	return &signalWriter{started: make(chan struct{})}
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.once.Do(func() { close(w.started) })
+	return w.buf.Write(p)
+}
+
+func (w *signalWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestStreamTranscodeShared(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, slowOutput)
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	input := writeInput(t, "song.flac")
+
+	const readers = 4
+	writers := make([]*signalWriter, readers)
+	errs := make([]error, readers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		writers[i] = newSignalWriter()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = tr.StreamTranscode(context.Background(), input, "", ProfileHigh, writers[i], func() {})
+		}(i)
+	}
+
+	// Every reader gets the first part while ffmpeg is still writing the
+	// second, rather than waiting for the whole transcode
+	for i, w := range writers {
+		select {
+		case <-w.started:
+		case <-time.After(400 * time.Millisecond):
+			t.Fatalf("reader %d got nothing while the transcode ran", i)
+		}
+	}
+	wg.Wait()
+
+	for i, w := range writers {
+		if errs[i] != nil {
+			t.Errorf("reader %d: %v", i, errs[i])
+		}
+		if got := w.String(); got != "first-second" {
+			t.Errorf("reader %d got %q, want %q", i, got, "first-second")
+		}
+	}
+	if runs := ffmpegRuns(t, ffmpeg); runs != 1 {
+		t.Errorf("ffmpeg ran %d times, want 1", runs)
+	}
+
+	// Later requests are served from the cache
+	path, err := tr.StreamTranscode(context.Background(), input, "", ProfileHigh, io.Discard, func() {})
+	if err != nil || path == "" {
+		t.Fatalf("cached StreamTranscode = %q, %v; want the cached path", path, err)
+	}
+}
+
+// stuckWriter is a client that stops reading: its first write signals
+// started and blocks until gone is closed, then fails
+type stuckWriter struct {
+	started chan struct{}
+	gone    chan struct{}
+	once    sync.Once
+}
+
+func (w *stuckWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gone
+	return 0, io.ErrClosedPipe
+}
+
+func TestStreamTranscodeSlowClient(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, slowOutput)
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	input := writeInput(t, "song.flac")
+	other := writeInput(t, "other.flac")
+
+	client := &stuckWriter{started: make(chan struct{}), gone: make(chan struct{})}
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := tr.StreamTranscode(context.Background(), input, "", ProfileHigh, client, func() {})
+		leaderDone <- err
+	}()
+	select {
+	case <-client.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("transcode produced nothing")
+	}
+
+	// The transcode runs on at ffmpeg's speed and frees the only slot, so
+	// another one can run while the client isn't reading
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := tr.TranscodeAndCache(ctx, other, "", ProfileHigh); err != nil {
+		t.Fatalf("second transcode blocked behind a slow client: %v", err)
+	}
+
+	close(client.gone)
+	if err := <-leaderDone; err == nil {
+		t.Errorf("StreamTranscode for a client that went away succeeded")
+	}
+
+	// The first transcode was cached even though its client left
+	if _, err := tr.TranscodeAndCache(context.Background(), input, "", ProfileHigh); err != nil {
+		t.Fatalf("TranscodeAndCache: %v", err)
+	}
+	if runs := ffmpegRuns(t, ffmpeg); runs != 2 {
+		t.Errorf("ffmpeg ran %d times, want 2", runs)
+	}
+}
+
+func TestStreamTranscodeFailure(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, `echo broken >&2; exit 1`)
+	tr := newTestTranscoder(t, ffmpeg, 1)
+	input := writeInput(t, "song.flac")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if _, err := tr.StreamTranscode(context.Background(), input, "", ProfileHigh, &buf, func() {}); err == nil {
+				t.Errorf("StreamTranscode of a failing transcode succeeded")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if path := tr.GetCachedPath(input, "", ProfileHigh); path != "" {
+		t.Errorf("failed transcode was cached at %s", path)
+	}
+}
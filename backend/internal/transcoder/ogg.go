@@ -0,0 +1,171 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrInvalidOgg    = errors.New("invalid ogg stream")
+	ErrSeekBeyondEnd = errors.New("seek position beyond end of stream")
+)
+
+// oggPageHeaderSize is the fixed part of an Ogg page header, before the
+// segment table
+const oggPageHeaderSize = 27
+
+// OggSeekPoint describes where to resume an Ogg Vorbis or Opus stream. A
+// playable stream is Header followed by the source from Offset onwards.
+type OggSeekPoint struct {
+	// Header holds the codec header pages every stream must start with
+	Header []byte
+	// Offset is the byte position of the page containing the seek target
+	Offset int64
+	// Start is the stream time at the beginning of that page, at or before
+	// the requested position
+	Start time.Duration
+}
+
+// oggPage is the part of an Ogg page header needed for seeking
+type oggPage struct {
+	// granule is the position after the last packet ending on the page, or
+	// -1 when no packet ends on it
+	granule int64
+	// packets counts the packets ending on the page
+	packets int
+	size    int64
+	data    []byte
+}
+
+// SeekOgg finds the page holding position in an Ogg Vorbis or Opus stream by
+// its granule positions, so a cached transcode can be served from the middle
+// without decoding it
+func SeekOgg(r io.Reader, position time.Duration) (*OggSeekPoint, error) {
+	br := bufio.NewReader(r)
+
+	var header bytes.Buffer
+	var offset int64
+	var rate, preSkip int64
+	var headerPackets, packets int
+
+	// The codec headers come first and end on a page boundary. They can span
+	// pages, so they're counted by packet rather than told apart by granule.
+	for headerPackets == 0 || packets < headerPackets {
+		page, raw, err := readOggPage(br)
+		if err != nil {
+			return nil, err
+		}
+		if headerPackets == 0 {
+			rate, preSkip, headerPackets, err = oggCodecRate(page.data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		header.Write(raw)
+		offset += page.size
+		packets += page.packets
+	}
+
+	// Nothing precedes the first audio page
+	if position <= 0 {
+		return &OggSeekPoint{Header: header.Bytes(), Offset: offset}, nil
+	}
+	target := preSkip + int64(position.Seconds()*float64(rate))
+	return seekOggAudio(br, header.Bytes(), offset, target, rate, preSkip)
+}
+
+// seekOggAudio scans the audio pages, the first at offset, for the first
+// page whose granule position reaches target. The stream resumes after the
+// last page before it with a known position, since pages where no packet
+// ends (granule -1) hold the start of the packets completed after them.
+func seekOggAudio(br *bufio.Reader, header []byte, offset, target, rate, preSkip int64) (*OggSeekPoint, error) {
+	resume := offset
+	var previous int64
+	for {
+		page, _, err := readOggPage(br)
+		if errors.Is(err, io.EOF) {
+			return nil, ErrSeekBeyondEnd
+		}
+		if err != nil {
+			return nil, err
+		}
+		if page.granule >= target {
+			break
+		}
+		offset += page.size
+		if page.granule >= 0 {
+			previous = page.granule
+			resume = offset
+		}
+	}
+
+	start := time.Duration(float64(max(previous-preSkip, 0)) / float64(rate) * float64(time.Second))
+	return &OggSeekPoint{Header: header, Offset: resume, Start: start}, nil
+}
+
+// readOggPage reads one page, returning it along with its raw bytes
+func readOggPage(br *bufio.Reader) (oggPage, []byte, error) {
+	fixed := make([]byte, oggPageHeaderSize)
+	if _, err := io.ReadFull(br, fixed); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return oggPage{}, nil, fmt.Errorf("%w: truncated page", ErrInvalidOgg)
+		}
+		return oggPage{}, nil, err
+	}
+	if !bytes.Equal(fixed[:4], []byte("OggS")) {
+		return oggPage{}, nil, fmt.Errorf("%w: missing capture pattern", ErrInvalidOgg)
+	}
+
+	segments := make([]byte, fixed[26])
+	if _, err := io.ReadFull(br, segments); err != nil {
+		return oggPage{}, nil, fmt.Errorf("%w: truncated page", ErrInvalidOgg)
+	}
+	var dataSize, packets int
+	for _, s := range segments {
+		dataSize += int(s)
+		// A lacing value under 255 ends a packet
+		if s < 255 {
+			packets++
+		}
+	}
+	data := make([]byte, dataSize)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return oggPage{}, nil, fmt.Errorf("%w: truncated page", ErrInvalidOgg)
+	}
+
+	raw := make([]byte, 0, len(fixed)+len(segments)+len(data))
+	raw = append(append(append(raw, fixed...), segments...), data...)
+	return oggPage{
+		granule: int64(binary.LittleEndian.Uint64(fixed[6:14])),
+		packets: packets,
+		size:    int64(len(raw)),
+		data:    data,
+	}, raw, nil
+}
+
+// oggCodecRate reads the granule rate and pre-skip from a Vorbis or Opus
+// identification header, along with how many header packets the codec has
+func oggCodecRate(packet []byte) (rate, preSkip int64, headerPackets int, err error) {
+	switch {
+	case len(packet) >= 16 && bytes.Equal(packet[:7], []byte("\x01vorbis")):
+		// Identification, comment and setup
+		rate = int64(binary.LittleEndian.Uint32(packet[12:16]))
+		headerPackets = 3
+	case len(packet) >= 12 && bytes.Equal(packet[:8], []byte("OpusHead")):
+		// Opus granules always count 48kHz samples. Identification and tags.
+		rate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+		headerPackets = 2
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: unsupported codec", ErrInvalidOgg)
+	}
+	if rate <= 0 {
+		return 0, 0, 0, fmt.Errorf("%w: bad sample rate", ErrInvalidOgg)
+	}
+	return rate, preSkip, headerPackets, nil
+}
@@ -0,0 +1,90 @@
+package transcoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// oggTestPage builds a page holding data. With complete set the last packet
+// on it ends there; otherwise data fills whole segments and runs on.
+func oggTestPage(granule int64, data []byte, complete bool) []byte {
+	var lacing []byte
+	for n := len(data); n >= 255; n -= 255 {
+		lacing = append(lacing, 255)
+	}
+	if complete {
+		lacing = append(lacing, byte(len(data)%255))
+	}
+
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = append(page, make([]byte, 12)...) // serial, sequence and checksum
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	return append(page, data...)
+}
+
+func TestSeekOgg(t *testing.T) {
+	const preSkip = 312
+	second := func(s int64) int64 { return preSkip + s*48000 }
+	opusHead := append([]byte("OpusHead\x01\x02"), binary.LittleEndian.AppendUint16(nil, preSkip)...)
+	opusHead = append(opusHead, make([]byte, 7)...)
+
+	// The tags and some audio packets span pages, which then have no
+	// granule position (-1)
+	pages := [][]byte{
+		oggTestPage(0, opusHead, true),
+		oggTestPage(-1, bytes.Repeat([]byte("t"), 510), false),
+		oggTestPage(0, []byte("tags end"), true),
+		oggTestPage(-1, bytes.Repeat([]byte("a"), 255), false),
+		oggTestPage(second(1), []byte("audio"), true),
+		oggTestPage(second(2), []byte("audio"), true),
+		oggTestPage(-1, bytes.Repeat([]byte("a"), 255), false),
+		oggTestPage(second(4), []byte("audio"), true),
+	}
+	var stream []byte
+	offsets := make([]int64, len(pages))
+	for i, page := range pages {
+		offsets[i] = int64(len(stream))
+		stream = append(stream, page...)
+	}
+	header := offsets[3]
+
+	tests := []struct {
+		name      string
+		position  time.Duration
+		wantPage  int
+		wantStart time.Duration
+		wantErr   error
+	}{
+		{"start", 0, 3, 0, nil},
+		{"within the first packets", 500 * time.Millisecond, 3, 0, nil},
+		{"between known positions", 1500 * time.Millisecond, 5, time.Second, nil},
+		{"before a page with no position", 3 * time.Second, 6, 2 * time.Second, nil},
+		{"beyond the end", 5 * time.Second, 0, 0, ErrSeekBeyondEnd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			point, err := SeekOgg(bytes.NewReader(stream), tt.position)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SeekOgg error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SeekOgg: %v", err)
+			}
+			if !bytes.Equal(point.Header, stream[:header]) {
+				t.Errorf("header is %d bytes, want the %d of the header pages", len(point.Header), header)
+			}
+			if point.Offset != offsets[tt.wantPage] || point.Start != tt.wantStart {
+				t.Errorf("seek point = offset %d at %v, want page %d (offset %d) at %v",
+					point.Offset, point.Start, tt.wantPage, offsets[tt.wantPage], tt.wantStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProbeAudio(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    AudioInfo
+		wantErr string
+	}{
+		{
+			name: "mp3",
+			output: `{
+				"streams": [{"codec_type": "audio", "codec_name": "mp3", "codec_long_name": "MP3 (MPEG audio layer 3)",
+					"sample_fmt": "fltp", "sample_rate": "44100", "channels": 2, "channel_layout": "stereo",
+					"bits_per_sample": 0, "bit_rate": "320000", "duration": "238.106122"}],
+				"format": {"format_name": "mp3", "format_long_name": "MP2/3 (MPEG audio layer 2/3)",
+					"duration": "238.123000", "bit_rate": "320517"}
+			}`,
+			want: AudioInfo{Duration: 238.123, Bitrate: 320, SampleRate: 44100, Channels: 2, ChannelLayout: "stereo",
+				SampleFormat: "fltp", Codec: "mp3", CodecName: "MP3 (MPEG audio layer 3)",
+				Format: "mp3", FormatName: "MP2/3 (MPEG audio layer 2/3)"},
+		},
+		{
+			// FLAC streams report no bit rate of their own, and their depth
+			// only as a raw sample size
+			name: "flac behind cover art",
+			output: `{
+				"streams": [
+					{"codec_type": "video", "codec_name": "mjpeg", "duration": "0.000000"},
+					{"codec_type": "audio", "codec_name": "flac", "codec_long_name": "FLAC (Free Lossless Audio Codec)",
+						"sample_fmt": "s32", "sample_rate": "96000", "channels": 2, "channel_layout": "stereo",
+						"bits_per_sample": 0, "bits_per_raw_sample": "24"}
+				],
+				"format": {"format_name": "flac", "format_long_name": "raw FLAC", "duration": "301.500000", "bit_rate": "3012345"}
+			}`,
+			want: AudioInfo{Duration: 301.5, Bitrate: 3012, SampleRate: 96000, Channels: 2, ChannelLayout: "stereo",
+				BitDepth: 24, SampleFormat: "s32", Codec: "flac", CodecName: "FLAC (Free Lossless Audio Codec)",
+				Format: "flac", FormatName: "raw FLAC"},
+		},
+		{
+			// WAV headers carry a fixed sample size, and some containers
+			// leave the duration to the stream
+			name: "wav with stream duration",
+			output: `{
+				"streams": [{"codec_type": "audio", "codec_name": "pcm_s16le", "sample_fmt": "s16", "sample_rate": "48000",
+					"channels": 1, "channel_layout": "mono", "bits_per_sample": 16, "bit_rate": "768000", "duration": "12.000000"}],
+				"format": {"format_name": "wav", "format_long_name": "WAV / WAVE (Waveform Audio)", "duration": "N/A"}
+			}`,
+			want: AudioInfo{Duration: 12, Bitrate: 768, SampleRate: 48000, Channels: 1, ChannelLayout: "mono",
+				BitDepth: 16, SampleFormat: "s16", Codec: "pcm_s16le",
+				Format: "wav", FormatName: "WAV / WAVE (Waveform Audio)"},
+		},
+		{
+			name:   "no audio stream",
+			output: `{"streams": [{"codec_type": "video", "codec_name": "h264"}], "format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "5.000000"}}`,
+			want:   AudioInfo{Duration: 5, Format: "mov,mp4,m4a,3gp,3g2,mj2"},
+		},
+		{
+			name:    "malformed output",
+			output:  `{"streams": [`,
+			wantErr: "parsing ffprobe output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ffprobe is looked up next to ffmpeg
+			ffmpeg := fakeFFmpeg(t, "")
+			canned := filepath.Join(t.TempDir(), "probe.json")
+			if err := os.WriteFile(canned, []byte(tt.output), 0644); err != nil {
+				t.Fatal(err)
+			}
+			script := "#!/bin/sh\ncat " + canned + "\n"
+			if err := os.WriteFile(filepath.Join(filepath.Dir(ffmpeg), "ffprobe"), []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+			tr := newTestTranscoder(t, ffmpeg, 1)
+
+			info, err := tr.ProbeAudio(context.Background(), writeInput(t, "input"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProbeAudio: %v", err)
+			}
+			if *info != tt.want {
+				t.Errorf("info = %+v\nwant   %+v", *info, tt.want)
+			}
+		})
+	}
+}
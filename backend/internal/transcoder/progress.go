@@ -0,0 +1,212 @@
+package transcoder
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscodeProgress reports how far a running transcode has got, as parsed
+// from ffmpeg's -progress output
+type TranscodeProgress struct {
+	ID        uint64
+	Input     string
+	Profile   string
+	StartedAt time.Time
+	// Duration is the length of audio being transcoded, or 0 until ffmpeg
+	// reports it
+	Duration time.Duration
+	// OutTime is how much audio has been written so far
+	OutTime   time.Duration
+	TotalSize int64
+	// Percent is OutTime as a percentage of Duration, or 0 while the
+	// duration is unknown
+	Percent float64
+	Done    bool
+}
+
+// progressArgs make ffmpeg write machine-readable progress to stderr,
+// alongside its log, in place of the interactive status line
+var progressArgs = []string{"-nostats", "-progress", "pipe:2"}
+
+// progressParser accumulates ffmpeg's -progress output. ffmpeg writes
+// key=value lines in blocks ending with a progress=continue or progress=end
+// line; the input's length is taken from the "Duration:" line of its log
+// unless it's known up front.
+type progressParser struct {
+	progress TranscodeProgress
+	// start is where a segment begins in the input, subtracted from the
+	// logged input duration
+	start time.Duration
+	// fixedDuration is set when the duration was known up front
+	fixedDuration bool
+}
+
+// parseLine applies one line of ffmpeg's stderr and reports whether it
+// ended a progress block
+func (p *progressParser) parseLine(line string) bool {
+	line = strings.TrimSpace(line)
+
+	if rest, ok := strings.CutPrefix(line, "Duration:"); ok {
+		if p.fixedDuration || p.progress.Duration > 0 {
+			return false
+		}
+		value, _, _ := strings.Cut(strings.TrimSpace(rest), ",")
+		if d, ok := parseProgressTime(value); ok && d > p.start {
+			p.progress.Duration = d - p.start
+		}
+		return false
+	}
+
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return false
+	}
+	switch key {
+	case "out_time_us", "out_time_ms":
+		// Despite its name ffmpeg reports out_time_ms in microseconds too
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			p.progress.OutTime = time.Duration(us) * time.Microsecond
+		}
+	case "total_size":
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+			p.progress.TotalSize = size
+		}
+	case "progress":
+		p.progress.Done = value == "end"
+		p.updatePercent()
+		return true
+	}
+	return false
+}
+
+// updatePercent derives Percent from OutTime and Duration
+func (p *progressParser) updatePercent() {
+	switch {
+	case p.progress.Done:
+		p.progress.Percent = 100
+	case p.progress.Duration > 0:
+		percent := float64(p.progress.OutTime) / float64(p.progress.Duration) * 100
+		p.progress.Percent = min(percent, 100)
+	}
+}
+
+// parseProgressTime parses an ffmpeg timestamp (HH:MM:SS.ss)
+func parseProgressTime(value string) (time.Duration, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
+
+// progressWriter receives ffmpeg's stderr and publishes each completed
+// progress block to the transcoder's list of active transcodes
+type progressWriter struct {
+	t       *Transcoder
+	parser  progressParser
+	partial []byte
+}
+
+func (w *progressWriter) Write(data []byte) (int, error) {
+	w.partial = append(w.partial, data...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if w.parser.parseLine(line) {
+			w.t.publishProgress(w.parser.progress)
+		}
+	}
+	return len(data), nil
+}
+
+// progressTracker holds the progress of running transcodes
+type progressTracker struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]TranscodeProgress
+}
+
+// trackProgress registers a transcode and returns the writer to attach to
+// ffmpeg's stderr, along with a function to call once ffmpeg has exited.
+// duration is the length of audio being transcoded if known, and start the
+// position a segment begins at in the input.
+func (t *Transcoder) trackProgress(inputPath string, profile Profile, duration, start time.Duration) (*progressWriter, func()) {
+	t.progress.mu.Lock()
+	defer t.progress.mu.Unlock()
+
+	if t.progress.active == nil {
+		t.progress.active = make(map[uint64]TranscodeProgress)
+	}
+	t.progress.nextID++
+	progress := TranscodeProgress{
+		ID:        t.progress.nextID,
+		Input:     inputPath,
+		Profile:   profile.Name,
+		StartedAt: t.now(),
+		Duration:  duration,
+	}
+	t.progress.active[progress.ID] = progress
+
+	w := &progressWriter{
+		t: t,
+		parser: progressParser{
+			progress:      progress,
+			start:         start,
+			fixedDuration: duration > 0,
+		},
+	}
+	finish := func() {
+		t.progress.mu.Lock()
+		defer t.progress.mu.Unlock()
+		delete(t.progress.active, progress.ID)
+	}
+	return w, finish
+}
+
+// publishProgress replaces the stored progress of a running transcode
+func (t *Transcoder) publishProgress(progress TranscodeProgress) {
+	t.progress.mu.Lock()
+	defer t.progress.mu.Unlock()
+	if _, ok := t.progress.active[progress.ID]; ok {
+		t.progress.active[progress.ID] = progress
+	}
+}
+
+// ActiveTranscodes returns the progress of the transcodes currently running,
+// oldest first
+func (t *Transcoder) ActiveTranscodes() []TranscodeProgress {
+	if t == nil {
+		return nil
+	}
+
+	t.progress.mu.Lock()
+	list := make([]TranscodeProgress, 0, len(t.progress.active))
+	for _, progress := range t.progress.active {
+		list = append(list, progress)
+	}
+	t.progress.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,149 @@
+package transcoder
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedProgress is ffmpeg's stderr for a four minute input with
+// -nostats -progress pipe:2, trimmed to three progress blocks
+const recordedProgress = `Input #0, mp3, from 'in.mp3':
+  Metadata:
+    title           : Song
+  Duration: 00:04:00.00, start: 0.025057, bitrate: 320 kb/s
+  Stream #0:0: Audio: mp3, 44100 Hz, stereo, fltp, 320 kb/s
+Stream mapping:
+  Stream #0:0 -> #0:0 (mp3 (mp3float) -> mp3 (libmp3lame))
+Output #0, mp3, to 'pipe:1':
+bitrate=N/A
+total_size=960000
+out_time_us=60000000
+out_time_ms=60000000
+out_time=00:01:00.000000
+speed=50.1x
+progress=continue
+bitrate=128.0kbits/s
+total_size=2880000
+out_time_us=180000000
+out_time_ms=180000000
+out_time=00:03:00.000000
+speed=49.8x
+progress=continue
+bitrate=128.0kbits/s
+total_size=3840000
+out_time_us=240000000
+out_time_ms=240000000
+out_time=00:04:00.000000
+speed=50.0x
+progress=end
+`
+
+func TestProgressParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		start    time.Duration
+		percents []float64
+		total    time.Duration
+	}{
+		{"logged duration", 0, 0, []float64{25, 75, 100}, 4 * time.Minute},
+		{"segment of the input", 0, time.Minute, []float64{33.33, 100, 100}, 3 * time.Minute},
+		{"known duration", 2 * time.Minute, 0, []float64{50, 100, 100}, 2 * time.Minute},
+		{"segment past the logged duration", 0, 5 * time.Minute, []float64{0, 0, 100}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := progressParser{
+				progress:      TranscodeProgress{Duration: tt.duration},
+				start:         tt.start,
+				fixedDuration: tt.duration > 0,
+			}
+			var percents []float64
+			for _, line := range strings.Split(recordedProgress, "\n") {
+				if p.parseLine(line) {
+					percents = append(percents, p.progress.Percent)
+				}
+			}
+			if len(percents) != len(tt.percents) {
+				t.Fatalf("percents = %v, want %v", percents, tt.percents)
+			}
+			for i := range percents {
+				if math.Abs(percents[i]-tt.percents[i]) > 0.01 {
+					t.Errorf("percents = %v, want %v", percents, tt.percents)
+					break
+				}
+			}
+			got := p.progress
+			if got.Duration != tt.total || got.OutTime != 4*time.Minute || got.TotalSize != 3840000 || !got.Done {
+				t.Errorf("final progress = %+v, want %v of %v, 3840000 bytes, done", got, 4*time.Minute, tt.total)
+			}
+		})
+	}
+}
+
+func TestParseProgressTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"00:04:00.00", 4 * time.Minute, true},
+		{" 01:02:03.50 ", time.Hour + 2*time.Minute + 3500*time.Millisecond, true},
+		{"N/A", 0, false},
+		{"04:00", 0, false},
+		{"00:xx:00", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseProgressTime(tt.value)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseProgressTime(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	tr := newTestTranscoder(t, fakeFFmpeg(t, ""), 1)
+	w, finish := tr.trackProgress("/music/in.mp3", Profile{Name: "high"}, 0, 0)
+
+	// ffmpeg's output arrives in chunks that split lines
+	blocks := strings.SplitAfter(recordedProgress, "progress=continue\n")
+	first := blocks[0]
+	logEnd := strings.Index(first, "bitrate=")
+	lineSplit := len(first) - len("continue\n")
+	tests := []struct {
+		name    string
+		output  string
+		percent float64
+		done    bool
+	}{
+		{"log only", first[:logEnd], 0, false},
+		{"half a line", first[logEnd:lineSplit], 0, false},
+		{"first block", first[lineSplit:], 25, false},
+		{"second block", blocks[1], 75, false},
+		{"last block", blocks[2], 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := w.Write([]byte(tt.output)); err != nil {
+				t.Fatal(err)
+			}
+			active := tr.ActiveTranscodes()
+			if len(active) != 1 {
+				t.Fatalf("%d active transcodes, want 1", len(active))
+			}
+			if got := active[0]; got.Input != "/music/in.mp3" || got.Profile != "high" ||
+				got.Percent != tt.percent || got.Done != tt.done {
+				t.Errorf("progress = %+v, want %v%% and done %v", got, tt.percent, tt.done)
+			}
+		})
+	}
+
+	finish()
+	if active := tr.ActiveTranscodes(); len(active) != 0 {
+		t.Errorf("%d active transcodes after finishing, want none", len(active))
+	}
+}
@@ -0,0 +1,56 @@
+package transcoder
+
+// codecSampleRates lists the output sample rates of encoders that only take
+// a fixed set; the others take any rate from minSampleRate to maxSampleRate
+var codecSampleRates = map[string][]int{
+	"libmp3lame": {8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000},
+	"libopus":    {8000, 12000, 16000, 24000, 48000},
+}
+
+const (
+	minSampleRate = 8000
+	maxSampleRate = 192000
+
+	// referenceSampleRate is the rate profile bitrates are chosen for
+	referenceSampleRate = 44100
+	// minScaledBitrate is the lowest bitrate (kbps) resampling or
+	// downmixing scales a profile to
+	minScaledBitrate = 16
+)
+
+// SupportsSampleRate reports whether codec can encode at rate Hz
+func SupportsSampleRate(codec string, rate int) bool {
+	rates, ok := codecSampleRates[codec]
+	if !ok {
+		return rate >= minSampleRate && rate <= maxSampleRate
+	}
+	for _, r := range rates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
+
+// LowProfiles returns the profiles the low-bandwidth sample rate and channel
+// settings apply to
+func LowProfiles() []Profile {
+	return []Profile{ProfileLow, ProfileLowOGG}
+}
+
+// scaleBitrate lowers a bitrate chosen for stereo at referenceSampleRate in
+// proportion to the profile's output sample rate and channel count, since
+// fewer samples need fewer bits for the same quality
+func scaleBitrate(bitrate int, profile Profile) int {
+	scaled := bitrate
+	if profile.SampleRate > 0 && profile.SampleRate < referenceSampleRate {
+		scaled = scaled * profile.SampleRate / referenceSampleRate
+	}
+	if profile.Channels == 1 {
+		scaled /= 2
+	}
+	if scaled == bitrate {
+		return bitrate
+	}
+	return max(scaled, min(bitrate, minScaledBitrate))
+}
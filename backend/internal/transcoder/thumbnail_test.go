@@ -0,0 +1,52 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractThumbnail(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		// A stand-in for ffmpeg writing a tiny JPEG, start and end of image
+		// markers around a marker segment, to stdout
+		{"frame", `printf '\377\330\377\340\000\020JFIF\377\331'`, ""},
+		{"no video stream", `echo "Stream map '0:v:0' matches no streams." >&2; exit 1`, "extracting thumbnail"},
+		{"no frame", `exit 0`, "no frame produced"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := filepath.Join(t.TempDir(), "args")
+			tr := newTestTranscoder(t, fakeFFmpeg(t, `echo "$@" > `+args+"\n"+tt.body), 1)
+			input := writeInput(t, "video.mkv")
+
+			data, err := tr.ExtractThumbnail(context.Background(), input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractThumbnail: %v", err)
+			}
+			if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+				t.Errorf("thumbnail starts % X, want the JPEG start of image marker FF D8", data[:min(len(data), 4)])
+			}
+
+			recorded, _ := os.ReadFile(args)
+			for _, want := range []string{"-i " + input, "-map 0:v:0", "-frames:v 1", "-vcodec mjpeg pipe:1"} {
+				if !strings.Contains(string(recorded), want) {
+					t.Errorf("ffmpeg arguments %q lack %q", recorded, want)
+				}
+			}
+		})
+	}
+}
@@ -3,14 +3,19 @@ package transcoder
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,11 +30,14 @@ var (
 
 // Profile represents a transcoding profile
 type Profile struct {
-	Name    string
-	Format  string
-	Codec   string
-	Bitrate int    // kbps
-	Ext     string // file extension
+	Name       string
+	Format     string
+	Codec      string
+	Bitrate    int     // kbps
+	SampleRate int     // output sample rate in Hz; 0 keeps the source's
+	Channels   int     // output channel count, 1 for mono; 0 keeps the source's
+	Ext        string  // file extension
+	Gain       float64 // volume change in dB, such as a track's ReplayGain; 0 leaves it
 }
 
 // Predefined transcoding profiles
@@ -44,6 +52,10 @@ var (
 	ProfileMediumOGG = Profile{Name: "medium-ogg", Format: "ogg", Codec: "libvorbis", Bitrate: 192, Ext: "ogg"}
 	ProfileLowOGG    = Profile{Name: "low-ogg", Format: "ogg", Codec: "libvorbis", Bitrate: 128, Ext: "ogg"}
 
+	// ProfileLossless re-encodes to FLAC; used where the original can't be
+	// served byte for byte, such as tracks cut from a larger file
+	ProfileLossless = Profile{Name: "lossless", Format: "flac", Codec: "flac", Bitrate: 0, Ext: "flac"}
+
 	// All profiles map
 	profiles = map[string]Profile{
 		"original":   ProfileOriginal,
@@ -56,12 +68,15 @@ var (
 	}
 )
 
-// GetProfile returns a profile by name
+// GetProfile returns a built-in or custom profile by name
 func GetProfile(name string) (Profile, error) {
 	name = strings.ToLower(name)
 	if profile, ok := profiles[name]; ok {
 		return profile, nil
 	}
+	if profile, ok := customProfile(name); ok {
+		return profile, nil
+	}
 	return Profile{}, ErrInvalidProfile
 }
 
@@ -80,11 +95,39 @@ func GetAllProfiles() []Profile {
 
 // Transcoder handles audio transcoding using ffmpeg
 type Transcoder struct {
-	ffmpegPath string
 	cacheDir   string
 	maxCacheGB float64
+	cacheTTL   time.Duration
+	now        func() time.Time
 	mu         sync.RWMutex
 	cacheSize  int64
+	index      cacheIndex
+	stopSweep  chan struct{}
+	closeOnce  sync.Once
+
+	// contentKeys keys cached transcodes on the source's content hash
+	contentKeys bool
+
+	// capToSource keeps transcodes at or below the source's bitrate
+	capToSource bool
+	// sourceBitrates remembers probed source bitrates for capToSource
+	sourceBitrates sourceBitrateCache
+	// maxBitrates caps the bitrate of transcodes by output format
+	maxBitrates map[string]int
+	// lowSampleRate and lowChannels are applied to the low profiles
+	lowSampleRate int
+	lowChannels   int
+
+	// progress follows running transcodes (see ActiveTranscodes)
+	progress progressTracker
+
+	// slots bounds how many transcodes run at once; nil is unbounded
+	slots chan struct{}
+	// jobs lets requests for the same cached transcode share one run
+	jobs transcodeJobs
+
+	// ffmpeg is the located ffmpeg binary, replaced by Recheck
+	ffmpeg ffmpegBinary
 }
 
 // Config holds transcoder configuration
@@ -92,6 +135,28 @@ type Config struct {
 	FFmpegPath string
 	CacheDir   string
 	MaxCacheGB float64
+	// CacheTTL expires transcodes not used for this long; 0 keeps them until
+	// size-based eviction
+	CacheTTL time.Duration
+	// ContentKeys keys cached transcodes on the content hash of the source
+	// when one is given, so renamed and identical files share entries
+	ContentKeys bool
+	// CapToSource lowers a profile's bitrate to the source's, so low-bitrate
+	// files aren't transcoded up to a larger size with no gain in quality
+	CapToSource bool
+	// MaxBitrates caps the bitrate of transcodes by output format (kbps)
+	MaxBitrates map[string]int
+	// LowSampleRate resamples the low profiles to this rate in Hz and
+	// LowChannels downmixes them to this many channels, trading more
+	// fidelity for bandwidth; 0 keeps the source's
+	LowSampleRate int
+	LowChannels   int
+	// MaxConcurrent bounds how many ffmpeg transcodes run at once, making
+	// further requests wait for a free slot; 0 uses the number of CPUs
+	MaxConcurrent int
+	// Optional creates the transcoder even when ffmpeg can't be found. It
+	// stays unavailable until Recheck finds ffmpeg.
+	Optional bool
 }
 
 // DefaultConfig returns default transcoder configuration
@@ -105,20 +170,12 @@ func DefaultConfig() Config {
 
 // New creates a new Transcoder
 func New(cfg Config) (*Transcoder, error) {
-	// Find ffmpeg
-	ffmpegPath := cfg.FFmpegPath
-	if ffmpegPath == "" || ffmpegPath == "ffmpeg" {
-		path, err := exec.LookPath("ffmpeg")
-		if err != nil {
-			return nil, ErrFFmpegNotFound
+	binary, err := locateFFmpeg(context.Background(), cfg.FFmpegPath)
+	if err != nil {
+		if !cfg.Optional {
+			return nil, err
 		}
-		ffmpegPath = path
-	}
-
-	// Verify ffmpeg works
-	cmd := exec.Command(ffmpegPath, "-version")
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("ffmpeg check failed: %w", err)
+		slog.Warn("ffmpeg not available, transcoding disabled until it is rechecked", "error", err)
 	}
 
 	// Create cache directory
@@ -127,15 +184,36 @@ func New(cfg Config) (*Transcoder, error) {
 	}
 
 	t := &Transcoder{
-		ffmpegPath: ffmpegPath,
-		cacheDir:   cfg.CacheDir,
-		maxCacheGB: cfg.MaxCacheGB,
+		cacheDir:    cfg.CacheDir,
+		maxCacheGB:  cfg.MaxCacheGB,
+		cacheTTL:    cfg.CacheTTL,
+		contentKeys: cfg.ContentKeys,
+		capToSource: cfg.CapToSource,
+		maxBitrates: cfg.MaxBitrates,
+		now:         time.Now,
+
+		lowSampleRate: cfg.LowSampleRate,
+		lowChannels:   cfg.LowChannels,
 	}
+	t.ffmpeg.configured = cfg.FFmpegPath
+	t.ffmpeg.set(binary)
 
-	// Calculate initial cache size
-	go t.calculateCacheSize()
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = runtime.NumCPU()
+	}
+	t.slots = make(chan struct{}, maxConcurrent)
+
+	// Load the cache index, which holds the cache size
+	go t.loadCacheIndex()
+
+	t.stopSweep = make(chan struct{})
+	go t.indexLoop()
+	if t.cacheTTL > 0 {
+		go t.sweepLoop()
+	}
 
-	slog.Info("transcoder initialized", "ffmpeg", ffmpegPath, "cacheDir", cfg.CacheDir)
+	slog.Info("transcoder initialized", "ffmpeg", binary.path, "version", binary.version, "cacheDir", cfg.CacheDir)
 	return t, nil
 }
 
@@ -143,8 +221,17 @@ func New(cfg Config) (*Transcoder, error) {
 func (t *Transcoder) TranscodeToFile(ctx context.Context, inputPath string, profile Profile, outputPath string) error {
 	args := t.buildFFmpegArgs(inputPath, profile, outputPath)
 
-	cmd := exec.CommandContext(ctx, t.ffmpegPath, args...)
-	cmd.Stderr = io.Discard // Suppress ffmpeg output
+	release, err := t.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	progress, finish := t.trackProgress(inputPath, profile, 0, 0)
+	defer finish()
+
+	cmd := exec.CommandContext(ctx, t.GetFFmpegPath(), append(progressArgs, args...)...)
+	cmd.Stderr = progress // Only progress is kept from ffmpeg output
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("%w: %v", ErrTranscodeFailed, err)
@@ -157,9 +244,18 @@ func (t *Transcoder) TranscodeToFile(ctx context.Context, inputPath string, prof
 func (t *Transcoder) TranscodeToWriter(ctx context.Context, inputPath string, profile Profile, w io.Writer) error {
 	args := t.buildFFmpegArgs(inputPath, profile, "pipe:1")
 
-	cmd := exec.CommandContext(ctx, t.ffmpegPath, args...)
+	release, err := t.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	progress, finish := t.trackProgress(inputPath, profile, 0, 0)
+	defer finish()
+
+	cmd := exec.CommandContext(ctx, t.GetFFmpegPath(), append(progressArgs, args...)...)
 	cmd.Stdout = w
-	cmd.Stderr = io.Discard
+	cmd.Stderr = progress
 
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("starting ffmpeg: %w", err)
@@ -183,46 +279,155 @@ func (t *Transcoder) TranscodeToWriter(ctx context.Context, inputPath string, pr
 	}
 }
 
-// TranscodeAndCache transcodes and caches the result
-func (t *Transcoder) TranscodeAndCache(ctx context.Context, inputPath string, profile Profile) (string, error) {
-	cacheKey := t.getCacheKey(inputPath, profile)
-	cachedPath := filepath.Join(t.cacheDir, cacheKey+"."+profile.Ext)
+// Segment selects part of an input file; an End of 0 means the end of the file
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+}
 
-	// Check if already cached
-	if _, err := os.Stat(cachedPath); err == nil {
-		return cachedPath, nil
+// TranscodeSegmentToWriter transcodes part of an audio file and writes it to w.
+// The original profile is encoded losslessly since a cut can't be copied as is.
+func (t *Transcoder) TranscodeSegmentToWriter(ctx context.Context, inputPath string, profile Profile, segment Segment, w io.Writer) error {
+	if profile.Name == ProfileOriginal.Name {
+		profile = ProfileLossless
 	}
 
-	// Create temp file for transcoding
-	tempPath := cachedPath + ".tmp"
-	defer os.Remove(tempPath)
+	release, err := t.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	// Transcode to temp file
-	if err := t.TranscodeToFile(ctx, inputPath, profile, tempPath); err != nil {
-		return "", err
+	var length time.Duration
+	if segment.End > segment.Start {
+		length = segment.End - segment.Start
+	}
+	progress, finish := t.trackProgress(inputPath, profile, length, segment.Start)
+	defer finish()
+
+	args := buildSegmentArgs(t.buildFFmpegArgs(inputPath, profile, "pipe:1"), segment)
+	cmd := exec.CommandContext(ctx, t.GetFFmpegPath(), append(progressArgs, args...)...)
+	cmd.Stdout = w
+	cmd.Stderr = progress
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrTranscodeFailed, err)
 	}
+	return nil
+}
+
+// buildSegmentArgs adds input seeking and a duration limit to ffmpeg arguments
+func buildSegmentArgs(args []string, segment Segment) []string {
+	seek := []string{"-ss", formatSeconds(segment.Start)}
+	if segment.End > segment.Start {
+		// Seeking before -i resets timestamps, so the length is relative
+		seek = append(seek, "-t", formatSeconds(segment.End-segment.Start))
+	}
+	return append(seek, args...)
+}
+
+// formatSeconds formats a duration as fractional seconds for ffmpeg
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
 
-	// Rename to final path
-	if err := os.Rename(tempPath, cachedPath); err != nil {
-		return "", fmt.Errorf("moving transcoded file: %w", err)
+// TranscodeAndCache transcodes and caches the result, or waits for the same
+// transcode when another request is already making it. contentHash is the
+// stored hash of the file, or empty if unknown.
+func (t *Transcoder) TranscodeAndCache(ctx context.Context, inputPath, contentHash string, profile Profile) (string, error) {
+	cacheKey := t.getCacheKey(inputPath, contentHash, profile)
+	cachedPath := filepath.Join(t.cacheDir, cacheKey+"."+profile.Ext)
+
+	if path := t.cachedTranscode(cachedPath); path != "" {
+		return path, nil
 	}
 
-	// Update cache size
-	go t.updateCacheSize(cachedPath)
+	// Concurrent requests for the same transcode share one ffmpeg run
+	job, err := t.startJob(cachedPath, func(ctx context.Context, tempPath string) error {
+		return t.TranscodeToFile(ctx, inputPath, profile, tempPath)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := job.wait(ctx); err != nil {
+		return "", err
+	}
 
 	return cachedPath, nil
 }
 
-// GetCachedPath returns the cached file path if it exists
-func (t *Transcoder) GetCachedPath(inputPath string, profile Profile) string {
+// ExtractThumbnail grabs a representative video frame from a file as JPEG data.
+// Works for music videos and for audio files carrying an attached picture stream.
+func (t *Transcoder) ExtractThumbnail(ctx context.Context, inputPath string) ([]byte, error) {
+	args := []string{
+		"-i", inputPath,
+		"-an",           // No audio
+		"-map", "0:v:0", // First video stream
+		"-vf", "thumbnail", // Pick a representative frame
+		"-frames:v", "1",
+		"-f", "image2pipe",
+		"-vcodec", "mjpeg",
+		"pipe:1",
+	}
+
+	cmd := exec.CommandContext(ctx, t.GetFFmpegPath(), args...)
+	cmd.Stderr = io.Discard
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("extracting thumbnail: %w", err)
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("extracting thumbnail: no frame produced")
+	}
+
+	return output, nil
+}
+
+// DecodePCM decodes up to maxSeconds of audio, starting offset seconds in, to
+// mono 32-bit float samples at sampleRate
+func (t *Transcoder) DecodePCM(ctx context.Context, inputPath string, sampleRate, offset, maxSeconds int) ([]float32, error) {
+	args := []string{
+		"-ss", strconv.Itoa(offset),
+		"-i", inputPath,
+		"-t", strconv.Itoa(maxSeconds),
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "f32le",
+		"pipe:1",
+	}
+
+	cmd := exec.CommandContext(ctx, t.GetFFmpegPath(), args...)
+	cmd.Stderr = io.Discard
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("decoding audio: %w", err)
+	}
+
+	samples := make([]float32, len(output)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(output[i*4:]))
+	}
+	return samples, nil
+}
+
+// GetCachedPath returns the cached file path if it exists. contentHash is
+// the stored hash of the file, or empty if unknown.
+func (t *Transcoder) GetCachedPath(inputPath, contentHash string, profile Profile) string {
 	if profile.Name == "original" {
 		return inputPath
 	}
 
-	cacheKey := t.getCacheKey(inputPath, profile)
+	cacheKey := t.getCacheKey(inputPath, contentHash, profile)
 	cachedPath := filepath.Join(t.cacheDir, cacheKey+"."+profile.Ext)
 
 	if _, err := os.Stat(cachedPath); err == nil {
+		t.touch(cachedPath)
 		return cachedPath
 	}
 	return ""
@@ -244,10 +449,22 @@ func (t *Transcoder) buildFFmpegArgs(inputPath string, profile Profile, outputPa
 		args = append(args, "-b:a", fmt.Sprintf("%dk", profile.Bitrate))
 	}
 
+	if profile.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(profile.SampleRate))
+	}
+
+	if profile.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(profile.Channels))
+	}
+
 	if profile.Format != "" {
 		args = append(args, "-f", profile.Format)
 	}
 
+	if profile.Gain != 0 {
+		args = append(args, "-af", fmt.Sprintf("volume=%.2fdB", profile.Gain))
+	}
+
 	// Add quality settings
 	switch profile.Codec {
 	case "libmp3lame":
@@ -260,8 +477,36 @@ func (t *Transcoder) buildFFmpegArgs(inputPath string, profile Profile, outputPa
 	return args
 }
 
+// contentKeyPrefix starts the cache keys of content-keyed transcodes, which
+// aren't tied to one source path and so aren't removed by PurgeSource. The
+// start of the content hash follows, for PurgeContentExcept.
+const contentKeyPrefix = "content-"
+
 // getCacheKey generates a unique cache key for a file and profile
-func (t *Transcoder) getCacheKey(inputPath string, profile Profile) string {
+func (t *Transcoder) getCacheKey(inputPath, contentHash string, profile Profile) string {
+	name := profile.Name
+	if !IsBuiltinProfile(name) {
+		// Custom profiles can be redefined under the same name
+		name = fmt.Sprintf("%s:%s:%s:%d", name, profile.Codec, profile.Format, profile.Bitrate)
+	} else if builtin, ok := profiles[name]; ok && builtin.Bitrate != profile.Bitrate {
+		// Capped by EffectiveProfile
+		name = fmt.Sprintf("%s@%dk", name, profile.Bitrate)
+	}
+	if profile.SampleRate > 0 {
+		name = fmt.Sprintf("%s@%dHz", name, profile.SampleRate)
+	}
+	if profile.Channels > 0 {
+		name = fmt.Sprintf("%s@%dch", name, profile.Channels)
+	}
+	if profile.Gain != 0 {
+		name = fmt.Sprintf("%s%+.2fdB", name, profile.Gain)
+	}
+
+	if t.contentKeys && contentHash != "" {
+		hash := sha256.Sum256([]byte(contentHash + "|" + name))
+		return contentKeyPrefix + contentID(contentHash) + "-" + hex.EncodeToString(hash[:16])
+	}
+
 	// Include file path, profile name, and file modification time
 	info, _ := os.Stat(inputPath)
 	modTime := ""
@@ -269,30 +514,79 @@ func (t *Transcoder) getCacheKey(inputPath string, profile Profile) string {
 		modTime = info.ModTime().Format(time.RFC3339)
 	}
 
-	data := fmt.Sprintf("%s|%s|%s", inputPath, profile.Name, modTime)
+	data := fmt.Sprintf("%s|%s|%s", inputPath, name, modTime)
 	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:16])
+	return sourceKey(inputPath) + "-" + hex.EncodeToString(hash[:16])
+}
+
+// sourceKey prefixes the cache keys of every transcode made from a file, so
+// they can be found again after the file changes
+func sourceKey(inputPath string) string {
+	hash := sha256.Sum256([]byte(inputPath))
+	return hex.EncodeToString(hash[:8])
 }
 
-// calculateCacheSize calculates the total cache size
-func (t *Transcoder) calculateCacheSize() {
-	var size int64
-	filepath.Walk(t.cacheDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
+// contentID is the part of a content hash that content-keyed transcodes of
+// the file start with
+func contentID(contentHash string) string {
+	if len(contentHash) > 16 {
+		return contentHash[:16]
+	}
+	return contentHash
+}
+
+// PurgeContentExcept removes the content-keyed transcodes of every content
+// hash but the given ones and returns how many were removed. Used after
+// scans, since no file deletion or change names the transcodes of a hash
+// no file has any more.
+func (t *Transcoder) PurgeContentExcept(contentHashes []string) int {
+	if t == nil {
+		return 0
+	}
+
+	live := make(map[string]bool, len(contentHashes))
+	for _, hash := range contentHashes {
+		live[contentID(hash)] = true
+	}
+	matches := t.indexedNames(func(name string, _ cacheEntry) bool {
+		rest, ok := strings.CutPrefix(name, contentKeyPrefix)
+		if !ok {
+			return false
 		}
-		size += info.Size()
-		return nil
+		// Keys made before the hash was part of them have no second dash
+		id, _, ok := strings.Cut(rest, "-")
+		return !ok || !live[id]
 	})
+	removed, _ := t.removeCached(matches, nil)
 
-	t.mu.Lock()
-	t.cacheSize = size
-	t.mu.Unlock()
+	if removed > 0 {
+		slog.Debug("purged transcodes of content no longer in the library", "filesRemoved", removed)
+	}
+	return removed
+}
+
+// PurgeSource removes every cached transcode made from inputPath and returns
+// how many were removed. Used when the source file changes or is deleted,
+// since the old entries would otherwise linger until size-based eviction.
+func (t *Transcoder) PurgeSource(inputPath string) int {
+	if t == nil {
+		return 0
+	}
 
-	slog.Debug("cache size calculated", "size", size, "sizeGB", float64(size)/(1024*1024*1024))
+	prefix := sourceKey(inputPath) + "-"
+	matches := t.indexedNames(func(name string, _ cacheEntry) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+	removed, _ := t.removeCached(matches, nil)
+
+	if removed > 0 {
+		slog.Debug("purged transcodes for source", "path", inputPath, "filesRemoved", removed)
+	}
+	return removed
 }
 
-// updateCacheSize updates cache size after adding a file
+// updateCacheSize indexes a file just added to the cache, evicting the
+// least recently used transcodes if the cache grew past its limit
 func (t *Transcoder) updateCacheSize(path string) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -300,7 +594,7 @@ func (t *Transcoder) updateCacheSize(path string) {
 	}
 
 	t.mu.Lock()
-	t.cacheSize += info.Size()
+	t.indexFile(filepath.Base(path), info.Size())
 	currentSize := t.cacheSize
 	t.mu.Unlock()
 
@@ -311,59 +605,89 @@ func (t *Transcoder) updateCacheSize(path string) {
 	}
 }
 
-// cleanupCache removes old cached files to stay under the size limit
+// cleanupCache removes the least recently used cached files to stay under
+// the size limit
 func (t *Transcoder) cleanupCache(targetSize int64) {
-	type fileEntry struct {
-		path    string
-		size    int64
-		modTime time.Time
+	names := t.indexedNames(func(string, cacheEntry) bool { return true })
+	removed, _ := t.removeCached(names, func(size int64) bool { return size <= targetSize })
+
+	if removed > 0 {
+		t.mu.RLock()
+		currentSize := t.cacheSize
+		t.mu.RUnlock()
+		slog.Info("cache cleanup completed", "filesRemoved", removed, "newSizeGB", float64(currentSize)/(1024*1024*1024))
 	}
+}
 
-	var files []fileEntry
-	filepath.Walk(t.cacheDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		files = append(files, fileEntry{
-			path:    path,
-			size:    info.Size(),
-			modTime: info.ModTime(),
-		})
-		return nil
-	})
+// touch marks a cached file as recently used, so both the TTL sweep and
+// size-based eviction measure age from the last access. The file's
+// modification time is kept in step for when the index is rebuilt.
+func (t *Transcoder) touch(path string) {
+	now := t.now()
+	os.Chtimes(path, now, now)
 
-	// Sort by modification time (oldest first)
-	for i := 0; i < len(files)-1; i++ {
-		for j := i + 1; j < len(files); j++ {
-			if files[j].modTime.Before(files[i].modTime) {
-				files[i], files[j] = files[j], files[i]
-			}
-		}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
 	}
-
-	// Remove oldest files until we're under target size
 	t.mu.Lock()
-	currentSize := t.cacheSize
+	t.indexFile(filepath.Base(path), info.Size())
 	t.mu.Unlock()
+}
 
-	var removed int
-	for _, f := range files {
-		if currentSize <= targetSize {
-			break
-		}
-		if err := os.Remove(f.path); err == nil {
-			currentSize -= f.size
-			removed++
+// sweepLoop periodically removes expired transcodes until Close is called
+func (t *Transcoder) sweepLoop() {
+	interval := t.cacheTTL / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopSweep:
+			return
+		case <-ticker.C:
+			t.sweepExpired()
 		}
 	}
+}
 
-	t.mu.Lock()
-	t.cacheSize = currentSize
-	t.mu.Unlock()
+// sweepExpired removes cached transcodes that haven't been used within the
+// TTL and returns how many were removed
+func (t *Transcoder) sweepExpired() int {
+	if t.cacheTTL <= 0 {
+		return 0
+	}
+
+	cutoff := t.now().Add(-t.cacheTTL)
+	expired := t.indexedNames(func(_ string, entry cacheEntry) bool {
+		return entry.LastAccess.Before(cutoff)
+	})
+	removed, freed := t.removeCached(expired, nil)
 
 	if removed > 0 {
-		slog.Info("cache cleanup completed", "filesRemoved", removed, "newSizeGB", float64(currentSize)/(1024*1024*1024))
+		slog.Info("expired transcodes removed", "filesRemoved", removed, "freedMB", freed/(1024*1024))
+	}
+	return removed
+}
+
+// Close stops the background cache sweep and saves the cache index
+func (t *Transcoder) Close() {
+	if t == nil || t.stopSweep == nil {
+		return
 	}
+	t.closeOnce.Do(func() {
+		close(t.stopSweep)
+		if err := t.saveCacheIndex(true); err != nil {
+			slog.Warn("saving transcode cache index failed", "error", err)
+		}
+	})
 }
 
 // ClearCache removes all cached files
@@ -380,6 +704,8 @@ func (t *Transcoder) ClearCache() error {
 
 	t.mu.Lock()
 	t.cacheSize = 0
+	t.index.entries = make(map[string]cacheEntry)
+	t.index.dirty = true
 	t.mu.Unlock()
 
 	return nil
@@ -388,37 +714,28 @@ func (t *Transcoder) ClearCache() error {
 // GetCacheStats returns cache statistics
 func (t *Transcoder) GetCacheStats() (int64, int, error) {
 	t.mu.RLock()
-	size := t.cacheSize
-	t.mu.RUnlock()
+	defer t.mu.RUnlock()
 
-	var count int
-	filepath.Walk(t.cacheDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		count++
-		return nil
-	})
-
-	return size, count, nil
+	return t.cacheSize, len(t.index.entries), nil
 }
 
 // IsAvailable checks if the transcoder is available
 func (t *Transcoder) IsAvailable() bool {
-	return t != nil && t.ffmpegPath != ""
+	return t.GetFFmpegPath() != ""
 }
 
-// GetFFmpegPath returns the path to ffmpeg
+// GetFFmpegPath returns the path to ffmpeg, or an empty string while it
+// isn't available
 func (t *Transcoder) GetFFmpegPath() string {
 	if t == nil {
 		return ""
 	}
-	return t.ffmpegPath
+	return t.ffmpeg.get().path
 }
 
 // ProbeAudio gets audio information using ffprobe
 func (t *Transcoder) ProbeAudio(ctx context.Context, inputPath string) (*AudioInfo, error) {
-	ffprobePath := strings.Replace(t.ffmpegPath, "ffmpeg", "ffprobe", 1)
+	ffprobePath := strings.Replace(t.GetFFmpegPath(), "ffmpeg", "ffprobe", 1)
 
 	args := []string{
 		"-v", "quiet",
@@ -434,17 +751,107 @@ func (t *Transcoder) ProbeAudio(ctx context.Context, inputPath string) (*AudioIn
 		return nil, fmt.Errorf("ffprobe failed: %w", err)
 	}
 
-	// Parse JSON output (simplified - in production use encoding/json)
-	_ = output
-	return &AudioInfo{}, nil
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("parsing ffprobe output: %w", err)
+	}
+
+	info := &AudioInfo{
+		Format:     probe.Format.FormatName,
+		FormatName: probe.Format.FormatLongName,
+		Bitrate:    parseBitrate(probe.Format.BitRate),
+	}
+	if d, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		info.Duration = d
+	}
+
+	// Use the first audio stream for codec details
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		info.Codec = stream.CodecName
+		info.CodecName = stream.CodecLongName
+		info.Profile = stream.Profile
+		info.Channels = stream.Channels
+		info.ChannelLayout = stream.ChannelLayout
+		info.SampleFormat = stream.SampleFmt
+		if bits, err := strconv.Atoi(stream.BitsPerRawSample); err == nil && bits > 0 {
+			info.BitDepth = bits
+		} else {
+			info.BitDepth = stream.BitsPerSample
+		}
+		if rate, err := strconv.Atoi(stream.SampleRate); err == nil {
+			info.SampleRate = rate
+		}
+		if bitrate := parseBitrate(stream.BitRate); bitrate > 0 {
+			info.Bitrate = bitrate
+		}
+		if info.Duration == 0 {
+			if d, err := strconv.ParseFloat(stream.Duration, 64); err == nil {
+				info.Duration = d
+			}
+		}
+		break
+	}
+
+	return info, nil
+}
+
+// parseBitrate converts an ffprobe bits/sec string to kbps
+func parseBitrate(s string) int {
+	bps, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return bps / 1000
+}
+
+// ffprobeOutput mirrors the parts of ffprobe's JSON output we use
+type ffprobeOutput struct {
+	Format struct {
+		FormatName     string `json:"format_name"`
+		FormatLongName string `json:"format_long_name"`
+		Duration       string `json:"duration"`
+		BitRate        string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType        string `json:"codec_type"`
+		CodecName        string `json:"codec_name"`
+		CodecLongName    string `json:"codec_long_name"`
+		Profile          string `json:"profile"`
+		SampleFmt        string `json:"sample_fmt"`
+		SampleRate       string `json:"sample_rate"`
+		Channels         int    `json:"channels"`
+		ChannelLayout    string `json:"channel_layout"`
+		BitsPerSample    int    `json:"bits_per_sample"`
+		BitsPerRawSample string `json:"bits_per_raw_sample"`
+		BitRate          string `json:"bit_rate"`
+		Duration         string `json:"duration"`
+	} `json:"streams"`
 }
 
 // AudioInfo contains audio file information
 type AudioInfo struct {
-	Duration   float64
-	Bitrate    int
-	SampleRate int
-	Channels   int
-	Codec      string
-	Format     string
+	Duration      float64 // seconds
+	Bitrate       int     // kbps
+	SampleRate    int
+	Channels      int
+	ChannelLayout string // e.g. "stereo", "5.1"
+	BitDepth      int    // 0 when the codec has no fixed bit depth
+	SampleFormat  string // e.g. "s16", "s32", "fltp"
+	Codec         string
+	CodecName     string // descriptive codec name
+	Profile       string
+	Format        string
+	FormatName    string // descriptive container name
+}
+
+// IsLosslessCodec reports whether an ffprobe codec name is a lossless codec
+func IsLosslessCodec(codec string) bool {
+	switch codec {
+	case "flac", "alac", "wavpack", "ape", "tta", "mlp", "truehd", "wmalossless":
+		return true
+	}
+	return strings.HasPrefix(codec, "pcm_")
 }